@@ -0,0 +1,179 @@
+package appstoreconnect
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/network"
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const (
+	baseURL       = "https://api.appstoreconnect.apple.com/v1"
+	tokenAudience = "appstoreconnect-v1"
+	// tokenLifetime is the maximum lifetime of an App Store Connect API token
+	tokenLifetime = 20 * time.Minute
+)
+
+// Client is an App Store Connect API client authenticated with an API key
+type Client struct {
+	keyID      string
+	issuerID   string
+	privateKey *ecdsa.PrivateKey
+	httpClient *http.Client
+
+	token          string
+	tokenExpiresAt time.Time
+}
+
+// NewClient creates a client with the API key ID, the issuer ID and the content of the API key (.p8) file
+func NewClient(keyID, issuerID string, privateKeyPEM []byte) (*Client, error) {
+	privateKey, err := parsePrivateKey(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient, err := network.NewHTTPClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client, error: %s", err)
+	}
+
+	return &Client{keyID: keyID, issuerID: issuerID, privateKey: privateKey, httpClient: httpClient}, nil
+}
+
+func parsePrivateKey(privateKeyPEM []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, errors.New("failed to decode API key: not a PEM file")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse API key, error: %s", err)
+	}
+	ecdsaKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("failed to parse API key: not an ECDSA key")
+	}
+	return ecdsaKey, nil
+}
+
+// signedToken returns a cached ES256 JWT, a new one is created shortly before the previous expires
+func (client *Client) signedToken(now time.Time) (string, error) {
+	if client.token != "" && now.Add(time.Minute).Before(client.tokenExpiresAt) {
+		return client.token, nil
+	}
+
+	expiresAt := now.Add(tokenLifetime)
+	token, err := newToken(client.keyID, client.issuerID, client.privateKey, now, expiresAt)
+	if err != nil {
+		return "", err
+	}
+	client.token, client.tokenExpiresAt = token, expiresAt
+	return token, nil
+}
+
+func newToken(keyID, issuerID string, privateKey *ecdsa.PrivateKey, issuedAt, expiresAt time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "ES256", "kid": keyID, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": issuerID,
+		"iat": issuedAt.Unix(),
+		"exp": expiresAt.Unix(),
+		"aud": tokenAudience,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign API token, error: %s", err)
+	}
+
+	// ES256 signatures are the concatenated, zero padded R and S values
+	size := (privateKey.Curve.Params().BitSize + 7) / 8
+	signature := make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	s.FillBytes(signature[size:])
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// errorResponse is the error format of the App Store Connect API
+type errorResponse struct {
+	Errors []struct {
+		Status string `json:"status"`
+		Code   string `json:"code"`
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+	} `json:"errors"`
+}
+
+// get performs a GET request on the endpoint and decodes the JSON response into v
+func (client *Client) get(endpoint string, query url.Values, v interface{}) error {
+	token, err := client.signedToken(time.Now())
+	if err != nil {
+		return err
+	}
+
+	requestURL := baseURL + endpoint
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+	log.Debugf("Request URL: %s", requestURL)
+
+	request, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Accept", "application/json")
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return network.DescribeError(err)
+	}
+	defer func() {
+		if err := response.Body.Close(); err != nil {
+			log.Warnf("Failed to close response body: %s", err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body, error: %s", err)
+	}
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		var errResponse errorResponse
+		if err := json.Unmarshal(body, &errResponse); err == nil && len(errResponse.Errors) > 0 {
+			var messages []string
+			for _, e := range errResponse.Errors {
+				messages = append(messages, fmt.Sprintf("%s: %s", e.Title, e.Detail))
+			}
+			return fmt.Errorf("App Store Connect request (%s) failed with status %d: %s", endpoint, response.StatusCode, strings.Join(messages, ", "))
+		}
+		return fmt.Errorf("App Store Connect request (%s) failed with status %d: %s", endpoint, response.StatusCode, body)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse App Store Connect response, error: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package appstoreconnect
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// Mismatch is a local code signing file which is out of sync with the developer portal
+type Mismatch struct {
+	// Name is the common name of the certificate or the name of the profile
+	Name        string
+	Description string
+}
+
+// CrossCheck compares the installed certificates and profiles against the ones registered on the developer portal.
+// The API key belongs to a single team, so only the local files of the teams having any file on the portal are checked.
+func CrossCheck(certificates []Certificate, profiles []Profile, installedCertificates []certificateutil.CertificateInfoModel, installedProfiles []profileutil.ProvisioningProfileInfoModel) (certificateMismatches []Mismatch, profileMismatches []Mismatch) {
+	remoteSerials := map[string]bool{}
+	for _, certificate := range certificates {
+		remoteSerials[DecimalSerial(certificate.SerialNumber)] = true
+	}
+	remoteByUUID := map[string]Profile{}
+	remoteByName := map[string][]Profile{}
+	for _, profile := range profiles {
+		remoteByUUID[strings.ToUpper(profile.UUID)] = profile
+		remoteByName[profile.Name] = append(remoteByName[profile.Name], profile)
+	}
+
+	teams := map[string]bool{}
+	for _, certificate := range installedCertificates {
+		if remoteSerials[certificate.Serial] {
+			teams[certificate.TeamID] = true
+		}
+	}
+	for _, profile := range installedProfiles {
+		if _, ok := remoteByUUID[strings.ToUpper(profile.UUID)]; ok {
+			teams[profile.TeamID] = true
+		}
+	}
+
+	for _, certificate := range installedCertificates {
+		if teams[certificate.TeamID] && !remoteSerials[certificate.Serial] {
+			certificateMismatches = append(certificateMismatches, Mismatch{
+				Name:        certificate.CommonName,
+				Description: fmt.Sprintf("serial %s is revoked or deleted on the developer portal", certificate.Serial),
+			})
+		}
+	}
+
+	for _, profile := range installedProfiles {
+		if !teams[profile.TeamID] {
+			continue
+		}
+		remote, ok := remoteByUUID[strings.ToUpper(profile.UUID)]
+		switch {
+		case ok && remote.ProfileState != "" && remote.ProfileState != "ACTIVE":
+			profileMismatches = append(profileMismatches, Mismatch{
+				Name:        profile.Name,
+				Description: fmt.Sprintf("%s is %s on the developer portal, regenerate it", profile.UUID, strings.ToLower(remote.ProfileState)),
+			})
+		case ok:
+		case len(remoteByName[profile.Name]) > 0:
+			var uuids []string
+			for _, newer := range remoteByName[profile.Name] {
+				uuids = append(uuids, newer.UUID)
+			}
+			sort.Strings(uuids)
+			profileMismatches = append(profileMismatches, Mismatch{
+				Name:        profile.Name,
+				Description: fmt.Sprintf("%s is out of date, the developer portal has: %s", profile.UUID, strings.Join(uuids, ", ")),
+			})
+		default:
+			profileMismatches = append(profileMismatches, Mismatch{
+				Name:        profile.Name,
+				Description: fmt.Sprintf("%s is deleted from the developer portal", profile.UUID),
+			})
+		}
+	}
+	return certificateMismatches, profileMismatches
+}
@@ -0,0 +1,39 @@
+package appstoreconnect
+
+import (
+	"testing"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCrossCheck(t *testing.T) {
+	certificates := []Certificate{{ID: "cert", SerialNumber: "0A1B"}}
+	profiles := []Profile{
+		{ID: "active", Name: "App Store", UUID: "new-uuid", ProfileState: "ACTIVE"},
+		{ID: "invalid", Name: "Ad Hoc", UUID: "adhoc-uuid", ProfileState: "INVALID"},
+	}
+	installedCertificates := []certificateutil.CertificateInfoModel{
+		{CommonName: "iPhone Distribution: Team", Serial: "2587", TeamID: "TEAM"},
+		{CommonName: "iPhone Developer: Team", Serial: "1", TeamID: "TEAM"},
+		{CommonName: "iPhone Developer: Other", Serial: "2", TeamID: "OTHER"},
+	}
+	installedProfiles := []profileutil.ProvisioningProfileInfoModel{
+		{Name: "App Store", UUID: "NEW-UUID", TeamID: "TEAM"},
+		{Name: "App Store", UUID: "old-uuid", TeamID: "TEAM"},
+		{Name: "Ad Hoc", UUID: "adhoc-uuid", TeamID: "TEAM"},
+		{Name: "Development", UUID: "dev-uuid", TeamID: "TEAM"},
+		{Name: "Other", UUID: "other-uuid", TeamID: "OTHER"},
+	}
+
+	certificateMismatches, profileMismatches := CrossCheck(certificates, profiles, installedCertificates, installedProfiles)
+	require.Equal(t, []Mismatch{
+		{Name: "iPhone Developer: Team", Description: "serial 1 is revoked or deleted on the developer portal"},
+	}, certificateMismatches)
+	require.Equal(t, []Mismatch{
+		{Name: "App Store", Description: "old-uuid is out of date, the developer portal has: new-uuid"},
+		{Name: "Ad Hoc", Description: "adhoc-uuid is invalid on the developer portal, regenerate it"},
+		{Name: "Development", Description: "dev-uuid is deleted from the developer portal"},
+	}, profileMismatches)
+}
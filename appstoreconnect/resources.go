@@ -0,0 +1,219 @@
+package appstoreconnect
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// App is an App Store Connect app
+type App struct {
+	ID       string
+	Name     string
+	BundleID string
+}
+
+// Build is an uploaded build of an app
+type Build struct {
+	ID              string
+	Version         string
+	UploadedDate    time.Time
+	ProcessingState string
+	Expired         bool
+}
+
+// Profile is a provisioning profile of the developer portal
+type Profile struct {
+	ID             string
+	Name           string
+	UUID           string
+	ProfileType    string
+	ProfileState   string
+	CreatedDate    time.Time
+	ExpirationDate time.Time
+}
+
+// Certificate is a signing certificate of the developer portal
+type Certificate struct {
+	ID              string
+	Name            string
+	CertificateType string
+	// SerialNumber is the hex encoded serial number
+	SerialNumber   string
+	ExpirationDate time.Time
+}
+
+type resource struct {
+	ID         string          `json:"id"`
+	Attributes json.RawMessage `json:"attributes"`
+}
+
+type listResponse struct {
+	Data []resource `json:"data"`
+}
+
+// FindApp returns the app with the given bundle ID
+func (client *Client) FindApp(bundleID string) (App, error) {
+	var response listResponse
+	if err := client.get("/apps", url.Values{"filter[bundleId]": {bundleID}}, &response); err != nil {
+		return App{}, err
+	}
+
+	for _, data := range response.Data {
+		var attributes struct {
+			Name     string `json:"name"`
+			BundleID string `json:"bundleId"`
+		}
+		if err := json.Unmarshal(data.Attributes, &attributes); err != nil {
+			return App{}, err
+		}
+		// the filter matches bundle ID prefixes too
+		if attributes.BundleID == bundleID {
+			return App{ID: data.ID, Name: attributes.Name, BundleID: attributes.BundleID}, nil
+		}
+	}
+	return App{}, fmt.Errorf("no App Store Connect app found with bundle ID: %s", bundleID)
+}
+
+// LatestBuild returns the most recently uploaded build of the app
+func (client *Client) LatestBuild(appID string) (Build, error) {
+	var response listResponse
+	query := url.Values{"filter[app]": {appID}, "sort": {"-uploadedDate"}, "limit": {"1"}}
+	if err := client.get("/builds", query, &response); err != nil {
+		return Build{}, err
+	}
+	if len(response.Data) == 0 {
+		return Build{}, fmt.Errorf("the app has no uploaded build")
+	}
+
+	var attributes struct {
+		Version         string    `json:"version"`
+		UploadedDate    time.Time `json:"uploadedDate"`
+		ProcessingState string    `json:"processingState"`
+		Expired         bool      `json:"expired"`
+	}
+	if err := json.Unmarshal(response.Data[0].Attributes, &attributes); err != nil {
+		return Build{}, err
+	}
+	return Build{
+		ID:              response.Data[0].ID,
+		Version:         attributes.Version,
+		UploadedDate:    attributes.UploadedDate,
+		ProcessingState: attributes.ProcessingState,
+		Expired:         attributes.Expired,
+	}, nil
+}
+
+// BundleIDProfiles returns the provisioning profiles of the bundle ID registered on the developer portal
+func (client *Client) BundleIDProfiles(bundleID string) ([]Profile, error) {
+	var bundleIDs listResponse
+	if err := client.get("/bundleIds", url.Values{"filter[identifier]": {bundleID}}, &bundleIDs); err != nil {
+		return nil, err
+	}
+
+	var bundleIDResourceID string
+	for _, data := range bundleIDs.Data {
+		var attributes struct {
+			Identifier string `json:"identifier"`
+		}
+		if err := json.Unmarshal(data.Attributes, &attributes); err != nil {
+			return nil, err
+		}
+		if attributes.Identifier == bundleID {
+			bundleIDResourceID = data.ID
+			break
+		}
+	}
+	if bundleIDResourceID == "" {
+		return nil, fmt.Errorf("bundle ID (%s) is not registered on the developer portal", bundleID)
+	}
+
+	var response listResponse
+	if err := client.get("/bundleIds/"+bundleIDResourceID+"/profiles", url.Values{"limit": {"200"}}, &response); err != nil {
+		return nil, err
+	}
+	return parseProfiles(response)
+}
+
+// Profiles returns the provisioning profiles registered on the developer portal
+func (client *Client) Profiles() ([]Profile, error) {
+	var response listResponse
+	if err := client.get("/profiles", url.Values{"limit": {"200"}}, &response); err != nil {
+		return nil, err
+	}
+	return parseProfiles(response)
+}
+
+func parseProfiles(response listResponse) ([]Profile, error) {
+	var profiles []Profile
+	for _, data := range response.Data {
+		var attributes struct {
+			Name           string    `json:"name"`
+			UUID           string    `json:"uuid"`
+			ProfileType    string    `json:"profileType"`
+			ProfileState   string    `json:"profileState"`
+			CreatedDate    time.Time `json:"createdDate"`
+			ExpirationDate time.Time `json:"expirationDate"`
+		}
+		if err := json.Unmarshal(data.Attributes, &attributes); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, Profile{
+			ID:             data.ID,
+			Name:           attributes.Name,
+			UUID:           attributes.UUID,
+			ProfileType:    attributes.ProfileType,
+			ProfileState:   attributes.ProfileState,
+			CreatedDate:    attributes.CreatedDate,
+			ExpirationDate: attributes.ExpirationDate,
+		})
+	}
+	return profiles, nil
+}
+
+// ProfileCertificates returns the certificates included in the profile
+func (client *Client) ProfileCertificates(profileID string) ([]Certificate, error) {
+	var response listResponse
+	if err := client.get("/profiles/"+profileID+"/certificates", url.Values{"limit": {"200"}}, &response); err != nil {
+		return nil, err
+	}
+	return parseCertificates(response)
+}
+
+// Certificates returns the signing certificates registered on the developer portal, the revoked ones are not listed
+func (client *Client) Certificates() ([]Certificate, error) {
+	var response listResponse
+	if err := client.get("/certificates", url.Values{"limit": {"200"}}, &response); err != nil {
+		return nil, err
+	}
+	return parseCertificates(response)
+}
+
+func parseCertificates(response listResponse) ([]Certificate, error) {
+	var certificates []Certificate
+	for _, data := range response.Data {
+		var attributes struct {
+			Name            string    `json:"name"`
+			DisplayName     string    `json:"displayName"`
+			CertificateType string    `json:"certificateType"`
+			SerialNumber    string    `json:"serialNumber"`
+			ExpirationDate  time.Time `json:"expirationDate"`
+		}
+		if err := json.Unmarshal(data.Attributes, &attributes); err != nil {
+			return nil, err
+		}
+		name := attributes.Name
+		if attributes.DisplayName != "" {
+			name = fmt.Sprintf("%s: %s", attributes.Name, attributes.DisplayName)
+		}
+		certificates = append(certificates, Certificate{
+			ID:              data.ID,
+			Name:            name,
+			CertificateType: attributes.CertificateType,
+			SerialNumber:    attributes.SerialNumber,
+			ExpirationDate:  attributes.ExpirationDate,
+		})
+	}
+	return certificates, nil
+}
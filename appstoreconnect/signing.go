@@ -0,0 +1,97 @@
+package appstoreconnect
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// appStoreProfileTypes are the profile types an App Store Connect build can be signed with
+var appStoreProfileTypes = []string{"IOS_APP_STORE", "TVOS_APP_STORE", "MAC_APP_STORE", "MAC_CATALYST_APP_STORE"}
+
+// Candidate is a signing configuration the build may have been signed with, and its local availability
+type Candidate struct {
+	Profile      Profile
+	Certificates []Certificate
+	// ProfileInstalled is true if a provisioning profile with the same UUID is installed
+	ProfileInstalled bool
+	// InstalledCertificates are the certificates of the profile installed with their private key
+	InstalledCertificates []Certificate
+}
+
+// Reproducible returns true if the signing configuration can be reproduced on this machine
+func (candidate Candidate) Reproducible() bool {
+	return candidate.ProfileInstalled && len(candidate.InstalledCertificates) > 0
+}
+
+// SigningCandidates returns the App Store profiles which were valid when the build was uploaded, newest first.
+// App Store Connect does not expose the certificate and profile a build was signed with,
+// the candidates are the profiles the build could have been signed with.
+func SigningCandidates(build Build, profiles []Profile) []Profile {
+	var candidates []Profile
+	for _, profile := range profiles {
+		isAppStore := false
+		for _, profileType := range appStoreProfileTypes {
+			if profile.ProfileType == profileType {
+				isAppStore = true
+			}
+		}
+		if !isAppStore {
+			continue
+		}
+
+		if !profile.CreatedDate.IsZero() && profile.CreatedDate.After(build.UploadedDate) {
+			continue
+		}
+		if !profile.ExpirationDate.IsZero() && profile.ExpirationDate.Before(build.UploadedDate) {
+			continue
+		}
+		candidates = append(candidates, profile)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].CreatedDate.After(candidates[j].CreatedDate)
+	})
+	return candidates
+}
+
+// CompareLocal checks which candidate profiles and their certificates are installed,
+// certificates contains the certificates of each profile by the profile ID.
+func CompareLocal(profiles []Profile, certificates map[string][]Certificate, installedCertificates []certificateutil.CertificateInfoModel, installedProfiles []profileutil.ProvisioningProfileInfoModel) []Candidate {
+	installedSerials := map[string]bool{}
+	for _, certificate := range installedCertificates {
+		installedSerials[certificate.Serial] = true
+	}
+	installedUUIDs := map[string]bool{}
+	for _, profile := range installedProfiles {
+		installedUUIDs[strings.ToUpper(profile.UUID)] = true
+	}
+
+	var candidates []Candidate
+	for _, profile := range profiles {
+		candidate := Candidate{
+			Profile:          profile,
+			Certificates:     certificates[profile.ID],
+			ProfileInstalled: profile.UUID != "" && installedUUIDs[strings.ToUpper(profile.UUID)],
+		}
+		for _, certificate := range candidate.Certificates {
+			if installedSerials[DecimalSerial(certificate.SerialNumber)] {
+				candidate.InstalledCertificates = append(candidate.InstalledCertificates, certificate)
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+// DecimalSerial converts the hex serial number of the API to the decimal format used by the local certificate infos
+func DecimalSerial(hexSerial string) string {
+	serial, ok := new(big.Int).SetString(strings.Replace(hexSerial, ":", "", -1), 16)
+	if !ok {
+		return hexSerial
+	}
+	return serial.String()
+}
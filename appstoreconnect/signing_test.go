@@ -0,0 +1,73 @@
+package appstoreconnect
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewToken(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	now := time.Now()
+	token, err := newToken("KEY123", "issuer", key, now, now.Add(tokenLifetime))
+	require.NoError(t, err)
+
+	parts := strings.Split(token, ".")
+	require.Equal(t, 3, len(parts))
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	require.Equal(t, 64, len(signature))
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	require.True(t, ecdsa.Verify(&key.PublicKey, digest[:], r, s))
+}
+
+func TestSigningCandidates(t *testing.T) {
+	uploaded := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	build := Build{ID: "build", UploadedDate: uploaded}
+	profiles := []Profile{
+		{ID: "development", ProfileType: "IOS_APP_DEVELOPMENT", CreatedDate: uploaded.AddDate(0, -1, 0), ExpirationDate: uploaded.AddDate(1, 0, 0)},
+		{ID: "old", ProfileType: "IOS_APP_STORE", CreatedDate: uploaded.AddDate(-1, 0, 0), ExpirationDate: uploaded.AddDate(0, 1, 0)},
+		{ID: "expired", ProfileType: "IOS_APP_STORE", CreatedDate: uploaded.AddDate(-2, 0, 0), ExpirationDate: uploaded.AddDate(0, -1, 0)},
+		{ID: "new", ProfileType: "IOS_APP_STORE", CreatedDate: uploaded.AddDate(0, -1, 0), ExpirationDate: uploaded.AddDate(1, 0, 0)},
+		{ID: "later", ProfileType: "IOS_APP_STORE", CreatedDate: uploaded.AddDate(0, 1, 0), ExpirationDate: uploaded.AddDate(1, 0, 0)},
+	}
+
+	candidates := SigningCandidates(build, profiles)
+	require.Equal(t, 2, len(candidates))
+	require.Equal(t, "new", candidates[0].ID)
+	require.Equal(t, "old", candidates[1].ID)
+
+	compared := CompareLocal(candidates,
+		map[string][]Certificate{
+			"new": {{ID: "cert", SerialNumber: "0A1B"}, {ID: "other", SerialNumber: "FF"}},
+			"old": {{ID: "cert", SerialNumber: "0A1B"}},
+		},
+		[]certificateutil.CertificateInfoModel{{Serial: "2587"}},
+		[]profileutil.ProvisioningProfileInfoModel{{UUID: ""}},
+	)
+	require.Equal(t, 2, len(compared))
+	require.Equal(t, 1, len(compared[0].InstalledCertificates))
+	require.Equal(t, "cert", compared[0].InstalledCertificates[0].ID)
+	require.False(t, compared[0].Reproducible())
+
+	candidates[1].UUID = "abcd-1234"
+	compared = CompareLocal(candidates[1:], map[string][]Certificate{"old": {{SerialNumber: "0a1b"}}},
+		[]certificateutil.CertificateInfoModel{{Serial: "2587"}},
+		[]profileutil.ProvisioningProfileInfoModel{{UUID: "ABCD-1234"}},
+	)
+	require.True(t, compared[0].Reproducible())
+}
@@ -0,0 +1,137 @@
+package bitriseconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	bitriseModels "github.com/bitrise-io/bitrise/models"
+	"gopkg.in/yaml.v2"
+)
+
+// Step is a single step of a workflow with its resolved inputs
+type Step struct {
+	ID     string
+	Inputs map[string]string
+}
+
+// Input returns the first non empty input value for the given keys
+func (step Step) Input(keys ...string) string {
+	for _, key := range keys {
+		if value := strings.TrimSpace(step.Inputs[key]); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Config wraps a parsed bitrise.yml
+type Config struct {
+	Data bitriseModels.BitriseDataModel
+}
+
+// NewConfigFromFile parses the bitrise.yml at the given path
+func NewConfigFromFile(pth string) (Config, error) {
+	content, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read bitrise config (%s), error: %s", pth, err)
+	}
+	return NewConfig(content)
+}
+
+// NewConfig parses the given bitrise.yml content
+func NewConfig(content []byte) (Config, error) {
+	var data bitriseModels.BitriseDataModel
+	if err := yaml.Unmarshal(content, &data); err != nil {
+		return Config{}, fmt.Errorf("failed to parse bitrise config, error: %s", err)
+	}
+	if len(data.Workflows) == 0 {
+		return Config{}, fmt.Errorf("no workflows found in the bitrise config")
+	}
+	return Config{Data: data}, nil
+}
+
+// WorkflowIDs returns the IDs of the workflows defined in the config
+func (config Config) WorkflowIDs() []string {
+	var ids []string
+	for id := range config.Data.Workflows {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Steps returns the steps of the given workflow including the steps of its before_run and after_run workflows, in run order
+func (config Config) Steps(workflowID string) ([]Step, error) {
+	return config.steps(workflowID, []string{})
+}
+
+func (config Config) steps(workflowID string, workflowStack []string) ([]Step, error) {
+	for _, id := range workflowStack {
+		if id == workflowID {
+			return nil, fmt.Errorf("workflow reference cycle found: %s -> %s", strings.Join(workflowStack, " -> "), workflowID)
+		}
+	}
+	workflowStack = append(workflowStack, workflowID)
+
+	workflow, ok := config.Data.Workflows[workflowID]
+	if !ok {
+		return nil, fmt.Errorf("workflow (%s) not found in the bitrise config", workflowID)
+	}
+
+	var steps []Step
+	for _, beforeID := range workflow.BeforeRun {
+		beforeSteps, err := config.steps(beforeID, workflowStack)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, beforeSteps...)
+	}
+
+	for _, stepListItem := range workflow.Steps {
+		compositeID, stepModel, err := bitriseModels.GetStepIDStepDataPair(stepListItem)
+		if err != nil {
+			return nil, fmt.Errorf("invalid step in workflow (%s), error: %s", workflowID, err)
+		}
+
+		inputs := map[string]string{}
+		for _, input := range stepModel.Inputs {
+			key, value, err := input.GetKeyValuePair()
+			if err != nil {
+				return nil, fmt.Errorf("invalid input of step (%s), error: %s", compositeID, err)
+			}
+			inputs[key] = value
+		}
+
+		steps = append(steps, Step{
+			ID:     stepID(compositeID),
+			Inputs: inputs,
+		})
+	}
+
+	for _, afterID := range workflow.AfterRun {
+		afterSteps, err := config.steps(afterID, workflowStack)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, afterSteps...)
+	}
+
+	return steps, nil
+}
+
+// stepID returns the plain step id of a composite step id, e.g.: https://github.com/bitrise-io/bitrise-steplib.git::xcode-archive@2 -> xcode-archive
+func stepID(compositeID string) string {
+	id := compositeID
+	if split := strings.Split(id, "::"); len(split) == 2 {
+		id = split[1]
+	}
+	if idx := strings.LastIndex(id, "@"); idx > 0 {
+		id = id[:idx]
+	}
+	if strings.Contains(id, "/") {
+		// direct git url, e.g.: https://github.com/bitrise-steplib/steps-xcode-archive.git
+		id = strings.TrimSuffix(id[strings.LastIndex(id, "/")+1:], ".git")
+		id = strings.TrimPrefix(id, "steps-")
+	}
+	return id
+}
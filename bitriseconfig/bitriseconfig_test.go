@@ -0,0 +1,42 @@
+package bitriseconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `format_version: "8"
+default_step_lib_source: https://github.com/bitrise-io/bitrise-steplib.git
+workflows:
+  _setup:
+    steps:
+    - certificate-and-profile-installer@1: {}
+  deploy:
+    before_run:
+    - _setup
+    steps:
+    - https://github.com/bitrise-io/bitrise-steplib.git::xcode-archive@2:
+        inputs:
+        - export_method: app-store
+        - team_id: ABCD1234
+    - git::https://github.com/bitrise-steplib/steps-deploy-to-bitrise-io.git@master: {}
+`
+
+func TestConfigSteps(t *testing.T) {
+	config, err := NewConfig([]byte(testConfig))
+	require.NoError(t, err)
+
+	steps, err := config.Steps("deploy")
+	require.NoError(t, err)
+	require.Equal(t, 3, len(steps))
+
+	require.Equal(t, "certificate-and-profile-installer", steps[0].ID)
+	require.Equal(t, "xcode-archive", steps[1].ID)
+	require.Equal(t, "app-store", steps[1].Input("distribution_method", "export_method"))
+	require.Equal(t, "ABCD1234", steps[1].Input("team_id"))
+	require.Equal(t, "deploy-to-bitrise-io", steps[2].ID)
+
+	_, err = config.Steps("missing")
+	require.Error(t, err)
+}
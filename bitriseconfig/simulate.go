@@ -0,0 +1,255 @@
+package bitriseconfig
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// Severity describes how serious a simulation finding is
+type Severity string
+
+const (
+	// SeverityOK means the step will find exactly one matching signing file
+	SeverityOK Severity = "ok"
+	// SeverityAmbiguous means the step will find more than one matching signing file and picks one of them
+	SeverityAmbiguous Severity = "ambiguous"
+	// SeverityGap means the step will not find any matching signing file
+	SeverityGap Severity = "gap"
+)
+
+// Finding is the result of simulating a single signing decision of a step
+type Finding struct {
+	WorkflowID string
+	StepID     string
+	Severity   Severity
+	Message    string
+}
+
+// Assets are the signing files available for the simulated steps
+type Assets struct {
+	Certificates []certificateutil.CertificateInfoModel
+	Profiles     []profileutil.ProvisioningProfileInfoModel
+}
+
+// LoadAssetsFromDir reads the .p12 and provisioning profile files of the given directory (e.g. the codesigndoc export directory)
+func LoadAssetsFromDir(dir, p12Passphrase string) (Assets, error) {
+	var assets Assets
+
+	p12Pths, err := filepath.Glob(filepath.Join(dir, "*.p12"))
+	if err != nil {
+		return Assets{}, err
+	}
+	for _, pth := range p12Pths {
+		certificates, err := certificateutil.CertificatesFromPKCS12File(pth, p12Passphrase)
+		if err != nil {
+			return Assets{}, fmt.Errorf("failed to read certificates from %s, error: %s", pth, err)
+		}
+		assets.Certificates = append(assets.Certificates, certificates...)
+	}
+
+	for _, ext := range []string{"*.mobileprovision", "*.provisionprofile"} {
+		profilePths, err := filepath.Glob(filepath.Join(dir, ext))
+		if err != nil {
+			return Assets{}, err
+		}
+		for _, pth := range profilePths {
+			profile, err := profileutil.NewProvisioningProfileInfoFromFile(pth)
+			if err != nil {
+				return Assets{}, fmt.Errorf("failed to read provisioning profile %s, error: %s", pth, err)
+			}
+			assets.Profiles = append(assets.Profiles, profile)
+		}
+	}
+
+	return assets, nil
+}
+
+// installerStepIDs are the steps downloading and installing the uploaded signing files
+var installerStepIDs = []string{
+	"certificate-and-profile-installer",
+	"manage-ios-code-signing",
+	"ios-auto-provision",
+	"ios-auto-provision-appstoreconnect",
+}
+
+// consumerStepIDs are the steps selecting signing files from the installed ones, mapped to their export method input keys
+var consumerStepIDs = map[string][]string{
+	"xcode-archive":        {"distribution_method", "export_method"},
+	"xcode-archive-mac":    {"export_method"},
+	"export-xcarchive":     {"distribution_method", "export_method"},
+	"export-xcarchive-mac": {"export_method"},
+	"xcode-build-for-test": {},
+	"xamarin-archive":      {"export_method"},
+}
+
+func isInstallerStep(id string) bool {
+	for _, installerID := range installerStepIDs {
+		if id == installerID {
+			return true
+		}
+	}
+	return false
+}
+
+// Simulate walks the given workflows (all workflows if none given) and reports which signing files
+// the signing steps would select from the given assets
+func Simulate(config Config, assets Assets, workflowIDs ...string) ([]Finding, error) {
+	if len(workflowIDs) == 0 {
+		workflowIDs = config.WorkflowIDs()
+	}
+	sort.Strings(workflowIDs)
+
+	var findings []Finding
+	for _, workflowID := range workflowIDs {
+		steps, err := config.Steps(workflowID)
+		if err != nil {
+			return nil, err
+		}
+
+		installed := false
+		for _, step := range steps {
+			if isInstallerStep(step.ID) {
+				installed = true
+				continue
+			}
+
+			methodKeys, ok := consumerStepIDs[step.ID]
+			if !ok {
+				continue
+			}
+
+			if !installed {
+				findings = append(findings, Finding{
+					WorkflowID: workflowID,
+					StepID:     step.ID,
+					Severity:   SeverityGap,
+					Message:    "no code signing file installer step runs before this step, uploaded signing files will not be available",
+				})
+			}
+
+			for _, finding := range simulateStep(step, methodKeys, assets) {
+				finding.WorkflowID = workflowID
+				finding.StepID = step.ID
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings, nil
+}
+
+func simulateStep(step Step, methodKeys []string, assets Assets) []Finding {
+	exportMethod := exportoptions.MethodDevelopment
+	if len(methodKeys) > 0 {
+		if method := step.Input(methodKeys...); method != "" && !strings.HasPrefix(method, "$") {
+			exportMethod = exportoptions.Method(method)
+		}
+	}
+	teamID := step.Input("team_id", "export_development_team")
+	forcedIdentity := step.Input("force_code_sign_identity")
+	forcedProfile := step.Input("force_provisioning_profile", "force_provisioning_profile_specifier")
+
+	var findings []Finding
+
+	// Certificates
+	var certificates []certificateutil.CertificateInfoModel
+	for _, certificate := range certificateutil.FilterValidCertificateInfos(assets.Certificates).ValidCertificates {
+		if teamID != "" && certificate.TeamID != teamID {
+			continue
+		}
+		if forcedIdentity != "" {
+			if certificate.CommonName != forcedIdentity && !strings.HasPrefix(certificate.CommonName, forcedIdentity+":") && !strings.EqualFold(certificate.SHA1Fingerprint, forcedIdentity) {
+				continue
+			}
+		} else if codesign.IsDistributionCertificate(certificate) != (exportMethod != exportoptions.MethodDevelopment) {
+			continue
+		}
+		certificates = append(certificates, certificate)
+	}
+
+	switch len(certificates) {
+	case 0:
+		msg := fmt.Sprintf("no valid certificate found for the %s export method", exportMethod)
+		if forcedIdentity != "" {
+			msg = fmt.Sprintf("the forced code sign identity (%s) is not among the signing files", forcedIdentity)
+		}
+		if teamID != "" {
+			msg += fmt.Sprintf(" (team: %s)", teamID)
+		}
+		findings = append(findings, Finding{Severity: SeverityGap, Message: msg})
+	case 1:
+		findings = append(findings, Finding{Severity: SeverityOK, Message: fmt.Sprintf("certificate: %s [%s]", certificates[0].CommonName, certificates[0].Serial)})
+	default:
+		var names []string
+		for _, certificate := range certificates {
+			names = append(names, fmt.Sprintf("%s [%s]", certificate.CommonName, certificate.Serial))
+		}
+		findings = append(findings, Finding{
+			Severity: SeverityAmbiguous,
+			Message:  fmt.Sprintf("multiple certificates match the %s export method: %s, set the force_code_sign_identity or team_id input to pin one", exportMethod, strings.Join(names, ", ")),
+		})
+	}
+
+	if len(certificates) == 0 {
+		return findings
+	}
+
+	// Profiles
+	profilesByBundleID := map[string][]profileutil.ProvisioningProfileInfoModel{}
+	for _, profile := range assets.Profiles {
+		if err := profile.CheckValidity(); err != nil {
+			continue
+		}
+		if forcedProfile != "" {
+			if profile.UUID != forcedProfile && profile.Name != forcedProfile {
+				continue
+			}
+		} else if profile.ExportType != exportMethod {
+			continue
+		}
+		if !profile.HasInstalledCertificate(certificates) {
+			continue
+		}
+		profilesByBundleID[profile.BundleID] = append(profilesByBundleID[profile.BundleID], profile)
+	}
+
+	if len(profilesByBundleID) == 0 {
+		msg := fmt.Sprintf("no valid %s provisioning profile found for the matching certificates", exportMethod)
+		if forcedProfile != "" {
+			msg = fmt.Sprintf("the forced provisioning profile (%s) is not among the signing files, or it does not contain the matching certificates", forcedProfile)
+		}
+		return append(findings, Finding{Severity: SeverityGap, Message: msg})
+	}
+
+	var bundleIDs []string
+	for bundleID := range profilesByBundleID {
+		bundleIDs = append(bundleIDs, bundleID)
+	}
+	sort.Strings(bundleIDs)
+
+	for _, bundleID := range bundleIDs {
+		profiles := codesign.FilterLatestProfiles(profilesByBundleID[bundleID])
+		if len(profiles) == 1 {
+			findings = append(findings, Finding{Severity: SeverityOK, Message: fmt.Sprintf("profile for %s: %s (%s)", bundleID, profiles[0].Name, profiles[0].UUID)})
+			continue
+		}
+
+		var names []string
+		for _, profile := range profiles {
+			names = append(names, fmt.Sprintf("%s (%s)", profile.Name, profile.UUID))
+		}
+		sort.Strings(names)
+		findings = append(findings, Finding{
+			Severity: SeverityAmbiguous,
+			Message:  fmt.Sprintf("multiple %s profiles match %s: %s", exportMethod, bundleID, strings.Join(names, ", ")),
+		})
+	}
+
+	return findings
+}
@@ -0,0 +1,155 @@
+package bitriseconfig
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+const testSimulateConfig = `format_version: "8"
+workflows:
+  _install:
+    steps:
+    - certificate-and-profile-installer@1: {}
+  app-store:
+    before_run: [_install]
+    steps:
+    - xcode-archive@4:
+        inputs:
+        - distribution_method: app-store
+        - team_id: ABCD123456
+  no-installer:
+    steps:
+    - xcode-archive@4:
+        inputs:
+        - distribution_method: app-store
+  ad-hoc:
+    before_run: [_install]
+    steps:
+    - xcode-archive@4:
+        inputs:
+        - distribution_method: ad-hoc
+  other-team:
+    before_run: [_install]
+    steps:
+    - xcode-archive@4:
+        inputs:
+        - distribution_method: app-store
+        - team_id: EFGH123456
+  forced-identity:
+    before_run: [_install]
+    steps:
+    - xcode-archive@4:
+        inputs:
+        - distribution_method: app-store
+        - force_code_sign_identity: "iPhone Distribution: Other"
+  forced-profile:
+    before_run: [_install]
+    steps:
+    - xcode-archive@4:
+        inputs:
+        - distribution_method: app-store
+        - force_provisioning_profile: missing-uuid
+  development:
+    before_run: [_install]
+    steps:
+    - xcode-build-for-test@1: {}
+  env-method:
+    before_run: [_install]
+    steps:
+    - export-xcarchive@4:
+        inputs:
+        - distribution_method: $BITRISE_DISTRIBUTION_METHOD
+  no-signing:
+    steps:
+    - script@1: {}
+`
+
+func testSimulateCertificate(commonName, serial, teamID string, notAfter time.Time) certificateutil.CertificateInfoModel {
+	return certificateutil.CertificateInfoModel{
+		CommonName:  commonName,
+		Serial:      serial,
+		TeamID:      teamID,
+		EndDate:     notAfter,
+		Certificate: x509.Certificate{NotBefore: notAfter.AddDate(-1, 0, 0), NotAfter: notAfter},
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	config, err := NewConfig([]byte(testSimulateConfig))
+	require.NoError(t, err)
+
+	expire := time.Now().AddDate(0, 6, 0)
+	distribution := testSimulateCertificate("iPhone Distribution: Company", "1", "ABCD123456", expire)
+	development := testSimulateCertificate("iPhone Developer: Company", "2", "ABCD123456", expire)
+	otherDevelopment := testSimulateCertificate("iPhone Developer: Other", "3", "ABCD123456", expire)
+	expired := testSimulateCertificate("iPhone Distribution: Expired", "4", "ABCD123456", time.Now().Add(-time.Hour))
+	assets := Assets{
+		Certificates: []certificateutil.CertificateInfoModel{distribution, development, otherDevelopment, expired},
+		Profiles: []profileutil.ProvisioningProfileInfoModel{
+			{UUID: "as", Name: "App Store", BundleID: "com.company.app", ExportType: exportoptions.MethodAppStore, ExpirationDate: expire, DeveloperCertificates: []certificateutil.CertificateInfoModel{distribution}},
+			{UUID: "as-old", Name: "App Store", BundleID: "com.company.app", ExportType: exportoptions.MethodAppStore, ExpirationDate: expire.AddDate(0, -1, 0), DeveloperCertificates: []certificateutil.CertificateInfoModel{distribution}},
+			{UUID: "as-expired", Name: "App Store expired", BundleID: "com.company.app", ExportType: exportoptions.MethodAppStore, ExpirationDate: time.Now().Add(-time.Hour), DeveloperCertificates: []certificateutil.CertificateInfoModel{distribution}},
+			{UUID: "dev", Name: "Development", BundleID: "com.company.app", ExportType: exportoptions.MethodDevelopment, ExpirationDate: expire, DeveloperCertificates: []certificateutil.CertificateInfoModel{development}},
+			{UUID: "dev-2", Name: "Development 2", BundleID: "com.company.app", ExportType: exportoptions.MethodDevelopment, ExpirationDate: expire, DeveloperCertificates: []certificateutil.CertificateInfoModel{otherDevelopment}},
+		},
+	}
+
+	for workflowID, want := range map[string][]Finding{
+		"app-store": {
+			{Severity: SeverityOK, Message: "certificate: iPhone Distribution: Company [1]"},
+			{Severity: SeverityOK, Message: "profile for com.company.app: App Store (as)"},
+		},
+		"no-installer": {
+			{Severity: SeverityGap, Message: "no code signing file installer step runs before this step, uploaded signing files will not be available"},
+			{Severity: SeverityOK, Message: "certificate: iPhone Distribution: Company [1]"},
+			{Severity: SeverityOK, Message: "profile for com.company.app: App Store (as)"},
+		},
+		"ad-hoc": {
+			{Severity: SeverityOK, Message: "certificate: iPhone Distribution: Company [1]"},
+			{Severity: SeverityGap, Message: "no valid ad-hoc provisioning profile found for the matching certificates"},
+		},
+		"other-team": {
+			{Severity: SeverityGap, Message: "no valid certificate found for the app-store export method (team: EFGH123456)"},
+		},
+		"forced-identity": {
+			{Severity: SeverityGap, Message: "the forced code sign identity (iPhone Distribution: Other) is not among the signing files"},
+		},
+		"forced-profile": {
+			{Severity: SeverityOK, Message: "certificate: iPhone Distribution: Company [1]"},
+			{Severity: SeverityGap, Message: "the forced provisioning profile (missing-uuid) is not among the signing files, or it does not contain the matching certificates"},
+		},
+		"no-signing": nil,
+	} {
+		findings, err := Simulate(config, assets, workflowID)
+		require.NoError(t, err, workflowID)
+		for i := range want {
+			want[i].WorkflowID = workflowID
+			want[i].StepID = "xcode-archive"
+		}
+		require.Equal(t, want, findings, workflowID)
+	}
+
+	// the development certificates are ambiguous, and the profiles of both match the bundle ID
+	for _, workflowID := range []string{"development", "env-method"} {
+		findings, err := Simulate(config, assets, workflowID)
+		require.NoError(t, err, workflowID)
+		require.Equal(t, 2, len(findings), workflowID)
+		require.Equal(t, SeverityAmbiguous, findings[0].Severity, workflowID)
+		require.Contains(t, findings[0].Message, "multiple certificates match the development export method", workflowID)
+		require.Equal(t, SeverityAmbiguous, findings[1].Severity, workflowID)
+		require.Equal(t, "multiple development profiles match com.company.app: Development (dev), Development 2 (dev-2)", findings[1].Message, workflowID)
+	}
+
+	findings, err := Simulate(config, assets)
+	require.NoError(t, err)
+	require.Equal(t, "ad-hoc", findings[0].WorkflowID, "every workflow is simulated, in order")
+
+	_, err = Simulate(config, assets, "missing")
+	require.Error(t, err)
+}
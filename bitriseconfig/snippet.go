@@ -0,0 +1,110 @@
+package bitriseconfig
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// The env vars of the files uploaded to the Code Signing tab of bitrise.io, the defaults of the installer step's inputs
+const (
+	CertificateURLEnvKey        = "BITRISE_CERTIFICATE_URL"
+	CertificatePassphraseEnvKey = "BITRISE_CERTIFICATE_PASSPHRASE"
+	ProvisionURLEnvKey          = "BITRISE_PROVISION_URL"
+)
+
+// SnippetFiles are the exported code signing files installed by the generated bitrise.yml snippet
+type SnippetFiles struct {
+	Certificates []certificateutil.CertificateInfoModel
+	Profiles     []profileutil.ProvisioningProfileInfoModel
+	// Uploaded is set if the files are uploaded to bitrise.io, the snippet refers to them by the env vars of the Code Signing tab
+	Uploaded bool
+	// Files are the paths of the written files, referred to as file:// URLs in the repository if the files are not uploaded
+	Files []string
+	// Dir is the directory of the written files
+	Dir string
+}
+
+// Snippet returns a bitrise.yml steps fragment installing the exported code signing files,
+// to paste before the archive step of a workflow
+func Snippet(files SnippetFiles) string {
+	certificateURL := "$" + CertificateURLEnvKey
+	profileURL := "$" + ProvisionURLEnvKey
+	var notes []string
+	if !files.Uploaded {
+		var profileURLs []string
+		for _, file := range files.Files {
+			url := "file://$BITRISE_SOURCE_DIR/" + path.Join(filepath.Base(files.Dir), filepath.ToSlash(relativePath(files.Dir, file)))
+			switch filepath.Ext(file) {
+			case ".mobileprovision", ".provisionprofile":
+				profileURLs = append(profileURLs, url)
+			case ".p12":
+				// every identity is in the merged .p12, the separately exported ones are not installed
+				if filepath.Base(file) == "Identities.p12" {
+					certificateURL = url
+				}
+			}
+		}
+		if len(profileURLs) > 0 {
+			sort.Strings(profileURLs)
+			profileURL = strings.Join(profileURLs, "|")
+		}
+		if certificateURL != "$"+CertificateURLEnvKey || profileURL != "$"+ProvisionURLEnvKey {
+			notes = append(notes,
+				"The files are referred to in the repository, commit the "+filepath.Base(files.Dir)+" directory (to a private repository only),",
+				"or upload them with `codesigndoc upload` and remove the certificate_url and provisioning_profile_url inputs.")
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("# Generated by codesigndoc, paste into the steps of your workflow, before the archive step.\n")
+	for _, note := range notes {
+		fmt.Fprintf(&b, "# %s\n", note)
+	}
+	fmt.Fprintf(&b, "# Set the .p12 password as the %s Secret.\n", CertificatePassphraseEnvKey)
+	for _, certificate := range files.Certificates {
+		fmt.Fprintf(&b, "# Identity: %s (team: %s, expires: %s)\n", certificate.CommonName, certificate.TeamID, certificate.EndDate.Format("2006-01-02"))
+	}
+	for _, profile := range files.Profiles {
+		fmt.Fprintf(&b, "# Profile: %s (%s, bundle ID: %s, export method: %s)\n", profile.Name, profile.UUID, profile.BundleID, profile.ExportType)
+	}
+	b.WriteString("- certificate-and-profile-installer@1:\n")
+	b.WriteString("    inputs:\n")
+	fmt.Fprintf(&b, "    - certificate_url: %s\n", yamlValue(certificateURL))
+	fmt.Fprintf(&b, "    - certificate_passphrase: %s\n", yamlValue("$"+CertificatePassphraseEnvKey))
+	fmt.Fprintf(&b, "    - provisioning_profile_url: %s\n", yamlValue(profileURL))
+	if method := exportMethod(files.Profiles); method != "" {
+		b.WriteString("# Set the distribution method of the archive step to the export method of the profiles:\n")
+		fmt.Fprintf(&b, "#   - distribution_method: %s\n", method)
+	}
+	return b.String()
+}
+
+// exportMethod returns the export method of the profiles, preferring a distribution method, empty if there is no profile
+func exportMethod(profiles []profileutil.ProvisioningProfileInfoModel) string {
+	method := ""
+	for _, profile := range profiles {
+		if method == "" || method == "development" {
+			method = string(profile.ExportType)
+		}
+	}
+	return method
+}
+
+func relativePath(dir, pth string) string {
+	rel, err := filepath.Rel(dir, pth)
+	if err != nil {
+		return filepath.Base(pth)
+	}
+	return rel
+}
+
+// yamlValue quotes the value, the env var references are expanded by the bitrise CLI
+func yamlValue(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
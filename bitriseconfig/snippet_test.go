@@ -0,0 +1,56 @@
+package bitriseconfig
+
+import (
+	"testing"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func snippetInputs(t *testing.T, snippet string) map[string]string {
+	var steps []map[string]struct {
+		Inputs []map[string]string `yaml:"inputs"`
+	}
+	require.NoError(t, yaml.Unmarshal([]byte(snippet), &steps))
+	require.Equal(t, 1, len(steps))
+
+	inputs := map[string]string{}
+	for _, input := range steps[0]["certificate-and-profile-installer@1"].Inputs {
+		for key, value := range input {
+			inputs[key] = value
+		}
+	}
+	return inputs
+}
+
+func TestSnippet(t *testing.T) {
+	files := SnippetFiles{
+		Certificates: []certificateutil.CertificateInfoModel{{CommonName: "Apple Distribution: Bitrise (ABCD123456)", TeamID: "ABCD123456"}},
+		Profiles: []profileutil.ProvisioningProfileInfoModel{
+			{Name: "Development", UUID: "1", ExportType: exportoptions.MethodDevelopment},
+			{Name: "App Store", UUID: "2", ExportType: exportoptions.MethodAppStore},
+		},
+		Uploaded: true,
+		Files:    []string{"/tmp/codesigndoc_exports/Identities.p12"},
+		Dir:      "/tmp/codesigndoc_exports",
+	}
+
+	snippet := Snippet(files)
+	require.Equal(t, map[string]string{
+		"certificate_url":          "$BITRISE_CERTIFICATE_URL",
+		"certificate_passphrase":   "$BITRISE_CERTIFICATE_PASSPHRASE",
+		"provisioning_profile_url": "$BITRISE_PROVISION_URL",
+	}, snippetInputs(t, snippet))
+	require.Contains(t, snippet, "distribution_method: app-store")
+
+	files.Uploaded = false
+	files.Files = append(files.Files, "/tmp/codesigndoc_exports/Apple_Distribution_1.p12", "/tmp/codesigndoc_exports/profiles/2.mobileprovision", "/tmp/codesigndoc_exports/1.mobileprovision", "/tmp/codesigndoc_exports/build.log")
+	require.Equal(t, map[string]string{
+		"certificate_url":          "file://$BITRISE_SOURCE_DIR/codesigndoc_exports/Identities.p12",
+		"certificate_passphrase":   "$BITRISE_CERTIFICATE_PASSPHRASE",
+		"provisioning_profile_url": "file://$BITRISE_SOURCE_DIR/codesigndoc_exports/1.mobileprovision|file://$BITRISE_SOURCE_DIR/codesigndoc_exports/profiles/2.mobileprovision",
+	}, snippetInputs(t, Snippet(files)))
+}
@@ -247,3 +247,21 @@ func (client *Client) ConfirmIdentityUpload(certificateSlug string, certificateU
 	}
 	return nil
 }
+
+// DeleteIdentity ...
+func (client *Client) DeleteIdentity(certificateSlug string) error {
+	log.Printf("Delete certificate (slug - %s) from Bitrise...", certificateSlug)
+
+	requestURL, err := urlutil.Join(baseURL, appsEndPoint, client.selectedAppSlug, certificatesEndPoint, certificateSlug)
+	if err != nil {
+		return err
+	}
+
+	request, err := createRequest(http.MethodDelete, requestURL, client.headers, nil)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = RunRequest(client, request, nil)
+	return err
+}
@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bitrise-io/codesigndoc/network"
 	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-io/go-utils/retry"
 	"github.com/bitrise-io/go-utils/urlutil"
@@ -23,6 +24,11 @@ const (
 	certificatesEndPoint         = "/build-certificates"
 )
 
+// APIBaseURL returns the base URL of the Bitrise API
+func APIBaseURL() string {
+	return baseURL
+}
+
 // Paging ...
 type Paging struct {
 	TotalItemCount int    `json:"total_item_count"`
@@ -68,7 +74,12 @@ type Client struct {
 
 // NewClient ...
 func NewClient(accessToken string) (*Client, error) {
-	client := &Client{accessToken, "", map[string]string{"Authorization": "token " + accessToken}, http.Client{}}
+	httpClient, err := network.NewHTTPClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client, error: %s", err)
+	}
+
+	client := &Client{accessToken, "", map[string]string{"Authorization": "token " + accessToken}, *httpClient}
 	return client, nil
 }
 
@@ -202,7 +213,7 @@ func performRequest(bitriseClient *Client, request *http.Request) (body []byte,
 	response, err := bitriseClient.client.Do(request)
 	if err != nil {
 		// On error, any Response can be ignored
-		return nil, -1, fmt.Errorf("failed to perform request, error: %s", err)
+		return nil, -1, fmt.Errorf("failed to perform request, error: %s", network.DescribeError(err))
 	}
 
 	// The client must close the response body when finished with it
@@ -240,3 +240,21 @@ func (client *Client) ConfirmProvisioningProfileUpload(profileSlug string, provU
 	requestResponse = *response.(*ConfirmProvProfileUploadResponse)
 	return nil
 }
+
+// DeleteProvisioningProfile ...
+func (client *Client) DeleteProvisioningProfile(profileSlug string) error {
+	log.Printf("Delete provisioning profile (slug - %s) from Bitrise...", profileSlug)
+
+	requestURL, err := urlutil.Join(baseURL, appsEndPoint, client.selectedAppSlug, provisioningProfilesEndPoint, profileSlug)
+	if err != nil {
+		return err
+	}
+
+	request, err := createRequest(http.MethodDelete, requestURL, client.headers, nil)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = RunRequest(client, request, nil)
+	return err
+}
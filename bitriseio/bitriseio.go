@@ -7,13 +7,15 @@ import (
 	"os"
 
 	"github.com/bitrise-io/codesigndoc/bitriseio/bitrise"
+	"github.com/bitrise-io/codesigndoc/logging"
 	"github.com/bitrise-io/codesigndoc/models"
+	"github.com/bitrise-io/codesigndoc/progress"
+	"github.com/bitrise-io/codesigndoc/prompt"
 	"github.com/bitrise-io/codesigndoc/utility"
 	"github.com/bitrise-io/go-utils/colorstring"
 	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-io/go-utils/sliceutil"
 	"github.com/bitrise-io/go-xcode/certificateutil"
-	"github.com/bitrise-io/goinp/goinp"
 )
 
 // GetInteractiveConfigClient asks for access token and app, returns a bitrise client
@@ -42,20 +44,27 @@ func GetInteractiveConfigClient() (*bitrise.Client, error) {
 	return client, nil
 }
 
-// UploadCodesigningFiles ...
-func UploadCodesigningFiles(client *bitrise.Client, certificates models.Certificates, profiles []models.ProvisioningProfile) (bool, bool, error) {
+// UploadCodesigningFiles uploads the certificates and profiles as a single set,
+// if any of the uploads fails, the already uploaded files are removed if rollbackOnFailure is set.
+func UploadCodesigningFiles(client *bitrise.Client, certificates models.Certificates, profiles []models.ProvisioningProfile, rollbackOnFailure bool) (bool, bool, error) {
+	return uploadCodesigningFiles(client, certificates, profiles, rollbackOnFailure)
+}
+
+func uploadCodesigningFiles(client uploadClient, certificates models.Certificates, profiles []models.ProvisioningProfile, rollbackOnFailure bool) (bool, bool, error) {
+	transaction := &uploadTransaction{client: client}
+
 	var provProfilesUploaded bool
 	if len(profiles) != 0 {
 		var err error
-		provProfilesUploaded, err = uploadExportedProvProfiles(client, profiles)
+		provProfilesUploaded, err = uploadExportedProvProfiles(transaction, profiles)
 		if err != nil {
-			return false, false, err
+			return false, false, transaction.fail(err, rollbackOnFailure)
 		}
 	}
 
-	certsUploaded, err := uploadExportedIdentity(client, certificates)
+	certsUploaded, err := uploadExportedIdentity(transaction, certificates)
 	if err != nil {
-		return false, false, err
+		return false, false, transaction.fail(err, rollbackOnFailure)
 	}
 	return certsUploaded, provProfilesUploaded, nil
 }
@@ -66,11 +75,12 @@ func askAccessToken() (token string, err error) {
 and select the Security tab on the left side.)`
 	fmt.Println()
 
-	accesToken, err := goinp.AskForStringFromReader(messageToAsk, os.Stdin)
+	accesToken, err := prompt.AskForStringFromReader(messageToAsk, os.Stdin)
 	if err != nil {
 		return accesToken, err
 	}
 
+	logging.AddSecret(accesToken)
 	fmt.Println()
 	log.Infof("%s %s", colorstring.Green("Given accesToken:"), accesToken)
 	fmt.Println()
@@ -84,7 +94,7 @@ func selectApp(appList []bitrise.Application) (seledtedAppSlug string, err error
 	for _, app := range appList {
 		selectionList = append(selectionList, app.Title+" ("+app.RepoURL+")")
 	}
-	userSelection, err := goinp.SelectFromStringsWithDefault("Select the app which you want to upload the provisioning profiles", 1, selectionList)
+	userSelection, err := prompt.SelectFromStringsWithDefault("Select the app which you want to upload the provisioning profiles", 1, selectionList)
 
 	if err != nil {
 		return "", fmt.Errorf("failed to read input: %s", err)
@@ -102,17 +112,17 @@ func selectApp(appList []bitrise.Application) (seledtedAppSlug string, err error
 	return "", errors.New("failed to find selected app in appList")
 }
 
-func uploadExportedProvProfiles(bitriseClient *bitrise.Client, profilesToExport []models.ProvisioningProfile) (bool, error) {
+func uploadExportedProvProfiles(transaction *uploadTransaction, profilesToExport []models.ProvisioningProfile) (bool, error) {
 	fmt.Println()
 	log.Infof("Uploading provisioning profiles...")
 
-	profilesToUpload, err := filterAlreadyUploadedProvProfiles(bitriseClient, profilesToExport)
+	profilesToUpload, err := filterAlreadyUploadedProvProfiles(transaction.client, profilesToExport)
 	if err != nil {
 		return false, err
 	}
 
 	if len(profilesToUpload) > 0 {
-		if err := uploadProvisioningProfiles(bitriseClient, profilesToUpload); err != nil {
+		if err := uploadProvisioningProfiles(transaction, profilesToUpload); err != nil {
 			return false, err
 		}
 	} else {
@@ -122,26 +132,16 @@ func uploadExportedProvProfiles(bitriseClient *bitrise.Client, profilesToExport
 	return true, nil
 }
 
-func filterAlreadyUploadedProvProfiles(client *bitrise.Client, localProfiles []models.ProvisioningProfile) ([]models.ProvisioningProfile, error) {
+func filterAlreadyUploadedProvProfiles(client uploadClient, localProfiles []models.ProvisioningProfile) ([]models.ProvisioningProfile, error) {
 	log.Printf("Looking for provisioning profile duplicates on Bitrise...")
 
-	uploadedProfileUUIDList := map[string]bool{}
 	var profilesToUpload []models.ProvisioningProfile
 
-	uploadedProfInfoList, err := client.FetchProvisioningProfiles()
+	uploadedProfileUUIDList, err := uploadedProvProfileUUIDs(client)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, uploadedProfileInfo := range uploadedProfInfoList {
-		uploadedProfileUUID, err := client.GetUploadedProvisioningProfileUUIDby(uploadedProfileInfo.Slug)
-		if err != nil {
-			return nil, err
-		}
-
-		uploadedProfileUUIDList[uploadedProfileUUID] = true
-	}
-
 	for _, localProfile := range localProfiles {
 		contains, _ := uploadedProfileUUIDList[localProfile.Info.UUID]
 		if contains {
@@ -154,7 +154,36 @@ func filterAlreadyUploadedProvProfiles(client *bitrise.Client, localProfiles []m
 	return profilesToUpload, nil
 }
 
-func uploadProvisioningProfiles(bitriseClient *bitrise.Client, profilesToUpload []models.ProvisioningProfile) error {
+// uploadedProvProfileUUIDs returns the UUIDs of the profiles already uploaded to the selected app
+func uploadedProvProfileUUIDs(client uploadClient) (map[string]bool, error) {
+	uploadedProfileUUIDList := map[string]bool{}
+
+	uploadedProfInfoList, err := client.FetchProvisioningProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	fetching := progress.Start("Fetching the uploaded provisioning profiles", len(uploadedProfInfoList))
+	defer fetching.Done()
+
+	for _, uploadedProfileInfo := range uploadedProfInfoList {
+		uploadedProfileUUID, err := client.GetUploadedProvisioningProfileUUIDby(uploadedProfileInfo.Slug)
+		if err != nil {
+			return nil, err
+		}
+
+		uploadedProfileUUIDList[uploadedProfileUUID] = true
+		fetching.Add(1)
+	}
+
+	return uploadedProfileUUIDList, nil
+}
+
+func uploadProvisioningProfiles(transaction *uploadTransaction, profilesToUpload []models.ProvisioningProfile) error {
+	bitriseClient := transaction.client
+	uploading := progress.Start("Uploading the provisioning profiles", len(profilesToUpload))
+	defer uploading.Done()
+
 	for _, profile := range profilesToUpload {
 		exportFileName := utility.ProfileExportFileNameNoPath(profile.Info)
 		exportSize := int64(len(profile.Content))
@@ -165,6 +194,7 @@ func uploadProvisioningProfiles(bitriseClient *bitrise.Client, profilesToUpload
 		if err != nil {
 			return err
 		}
+		transaction.add(uploadedProfile, provProfSlugResponseData.Slug, exportFileName)
 
 		log.Printf("Uploading %s to Bitrise...", provProfSlugResponseData.UploadFileName)
 		if err := bitriseClient.UploadArtifact(provProfSlugResponseData.UploadURL, bytes.NewReader(profile.Content)); err != nil {
@@ -174,22 +204,23 @@ func uploadProvisioningProfiles(bitriseClient *bitrise.Client, profilesToUpload
 		if err := bitriseClient.ConfirmProvisioningProfileUpload(provProfSlugResponseData.Slug, provProfSlugResponseData.UploadFileName); err != nil {
 			return err
 		}
+		uploading.Add(1)
 	}
 
 	return nil
 }
 
-func uploadExportedIdentity(bitriseClient *bitrise.Client, certificates models.Certificates) (bool, error) {
+func uploadExportedIdentity(transaction *uploadTransaction, certificates models.Certificates) (bool, error) {
 	fmt.Println()
 	log.Infof("Uploading certificate...")
 
-	shouldUploadIdentities, err := shouldUploadCertificates(bitriseClient, certificates.Info)
+	shouldUploadIdentities, err := shouldUploadCertificates(transaction.client, certificates.Info)
 	if err != nil {
 		return false, err
 	}
 
 	if shouldUploadIdentities {
-		if err := uploadIdentity(bitriseClient, certificates.Content); err != nil {
+		if err := uploadIdentity(transaction, certificates.Content); err != nil {
 			return false, err
 		}
 	} else {
@@ -199,7 +230,7 @@ func uploadExportedIdentity(bitriseClient *bitrise.Client, certificates models.C
 	return true, err
 }
 
-func shouldUploadCertificates(client *bitrise.Client, certificatesToExport []certificateutil.CertificateInfoModel) (bool, error) {
+func shouldUploadCertificates(client uploadClient, certificatesToExport []certificateutil.CertificateInfoModel) (bool, error) {
 	log.Printf("Looking for certificate duplicates on Bitrise...")
 
 	var uploadedCertificatesSerialList []string
@@ -211,11 +242,13 @@ func shouldUploadCertificates(client *bitrise.Client, certificatesToExport []cer
 	}
 
 	// Get uploaded certificates' serials
+	fetching := progress.Start("Fetching the uploaded certificates", len(uploadedItentityList))
 	for _, uploadedIdentity := range uploadedItentityList {
 		var serialListAsString []string
 
 		serialList, err := client.GetUploadedCertificatesSerialby(uploadedIdentity.Slug)
 		if err != nil {
+			fetching.Done()
 			return false, err
 		}
 
@@ -223,7 +256,9 @@ func shouldUploadCertificates(client *bitrise.Client, certificatesToExport []cer
 			serialListAsString = append(serialListAsString, serial.String())
 		}
 		uploadedCertificatesSerialList = append(uploadedCertificatesSerialList, serialListAsString...)
+		fetching.Add(1)
 	}
+	fetching.Done()
 
 	for _, certificateToExport := range certificatesToExport {
 		localCertificatesSerialList = append(localCertificatesSerialList, certificateToExport.Serial)
@@ -242,7 +277,8 @@ func shouldUploadCertificates(client *bitrise.Client, certificatesToExport []cer
 	return false, nil
 }
 
-func uploadIdentity(bitriseClient *bitrise.Client, identities []byte) error {
+func uploadIdentity(transaction *uploadTransaction, identities []byte) error {
+	bitriseClient := transaction.client
 	identitiesSize := int64(len(identities))
 	log.Debugf("\nIdentities size: %d", identitiesSize)
 
@@ -250,9 +286,13 @@ func uploadIdentity(bitriseClient *bitrise.Client, identities []byte) error {
 	if err != nil {
 		return err
 	}
+	transaction.add(uploadedIdentity, certificateResponseData.Slug, certificateResponseData.UploadFileName)
 
 	log.Printf("Uploading %s to Bitrise...", certificateResponseData.UploadFileName)
-	if err := bitriseClient.UploadArtifact(certificateResponseData.UploadURL, bytes.NewReader(identities)); err != nil {
+	progress.Run("Uploading the certificates", func() {
+		err = bitriseClient.UploadArtifact(certificateResponseData.UploadURL, bytes.NewReader(identities))
+	})
+	if err != nil {
 		return err
 	}
 
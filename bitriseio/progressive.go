@@ -0,0 +1,141 @@
+package bitriseio
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/bitrise-io/codesigndoc/bitriseio/bitrise"
+	"github.com/bitrise-io/codesigndoc/models"
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// ProgressiveUpload uploads the code signing files in the background as soon as they are ready,
+// instead of uploading the whole set at the end of the export.
+// The files are uploaded in the order they are added, the first failure stops the upload.
+type ProgressiveUpload struct {
+	transaction       *uploadTransaction
+	rollbackOnFailure bool
+
+	jobs chan func() error
+	done chan struct{}
+
+	mutex              sync.Mutex
+	err                error
+	profilesUploaded   bool
+	identitiesAdded    bool
+	identitiesUploaded bool
+
+	// uploadedProfileUUIDs caches the profiles already on Bitrise, fetched before the first profile upload
+	uploadedProfileUUIDs map[string]bool
+}
+
+// StartProgressiveUpload starts the background upload to the client's selected app
+func StartProgressiveUpload(client *bitrise.Client, rollbackOnFailure bool) *ProgressiveUpload {
+	return startProgressiveUpload(client, rollbackOnFailure)
+}
+
+func startProgressiveUpload(client uploadClient, rollbackOnFailure bool) *ProgressiveUpload {
+	upload := &ProgressiveUpload{
+		transaction:       &uploadTransaction{client: client},
+		rollbackOnFailure: rollbackOnFailure,
+		jobs:              make(chan func() error, 32),
+		done:              make(chan struct{}),
+	}
+	go upload.run()
+	return upload
+}
+
+func (upload *ProgressiveUpload) run() {
+	defer close(upload.done)
+	for job := range upload.jobs {
+		if upload.Err() != nil {
+			// drain the queue, the upload already failed
+			continue
+		}
+		if err := job(); err != nil {
+			upload.mutex.Lock()
+			upload.err = err
+			upload.mutex.Unlock()
+		}
+	}
+}
+
+// Err returns the error of the first failed upload, so the export can stop early
+func (upload *ProgressiveUpload) Err() error {
+	upload.mutex.Lock()
+	defer upload.mutex.Unlock()
+	return upload.err
+}
+
+// AddProfile queues the profile for upload, it returns the error of an already failed upload
+func (upload *ProgressiveUpload) AddProfile(profile models.ProvisioningProfile) error {
+	upload.jobs <- func() error {
+		if upload.uploadedProfileUUIDs == nil {
+			log.Printf("Looking for provisioning profile duplicates on Bitrise...")
+			uuids, err := uploadedProvProfileUUIDs(upload.transaction.client)
+			if err != nil {
+				return err
+			}
+			upload.uploadedProfileUUIDs = uuids
+		}
+
+		if upload.uploadedProfileUUIDs[profile.Info.UUID] {
+			log.Warnf("Already on Bitrise: - %s - (UUID: %s) ", profile.Info.Name, profile.Info.UUID)
+		} else {
+			log.Infof("Uploading provisioning profile: %s (UUID: %s)", profile.Info.Name, profile.Info.UUID)
+			if err := uploadProvisioningProfiles(upload.transaction, []models.ProvisioningProfile{profile}); err != nil {
+				return err
+			}
+			upload.uploadedProfileUUIDs[profile.Info.UUID] = true
+		}
+
+		upload.mutex.Lock()
+		upload.profilesUploaded = true
+		upload.mutex.Unlock()
+		return nil
+	}
+	return upload.Err()
+}
+
+// AddIdentities queues the exported .p12 for upload, it returns the error of an already failed upload
+func (upload *ProgressiveUpload) AddIdentities(certificates models.Certificates) error {
+	upload.mutex.Lock()
+	upload.identitiesAdded = true
+	upload.mutex.Unlock()
+
+	upload.jobs <- func() error {
+		uploaded, err := uploadExportedIdentity(upload.transaction, certificates)
+		if err != nil {
+			return err
+		}
+
+		upload.mutex.Lock()
+		upload.identitiesUploaded = uploaded
+		upload.mutex.Unlock()
+		return nil
+	}
+	return upload.Err()
+}
+
+// Wait waits for the queued uploads, and returns whether the certificates and the profiles were uploaded.
+// If any of the uploads failed, the already uploaded files are removed if rollbackOnFailure is set.
+func (upload *ProgressiveUpload) Wait() (bool, bool, error) {
+	close(upload.jobs)
+	<-upload.done
+
+	if err := upload.Err(); err != nil {
+		return false, false, upload.transaction.fail(err, upload.rollbackOnFailure)
+	}
+	return upload.identitiesUploaded || !upload.identitiesAdded, upload.profilesUploaded, nil
+}
+
+// Fail stops the upload because the export failed, the already uploaded files are handled as on a failed upload
+func (upload *ProgressiveUpload) Fail(err error) error {
+	close(upload.jobs)
+	<-upload.done
+
+	if uploadErr := upload.Err(); uploadErr != nil {
+		err = fmt.Errorf("%w, upload error: %s", err, uploadErr)
+	}
+	return upload.transaction.fail(err, upload.rollbackOnFailure)
+}
@@ -0,0 +1,143 @@
+package bitriseio
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func countCalls(calls []string, call string) int {
+	count := 0
+	for _, c := range calls {
+		if c == call {
+			count++
+		}
+	}
+	return count
+}
+
+func TestProgressiveUpload(t *testing.T) {
+	t.Run("profiles and identities", func(t *testing.T) {
+		client := &fakeClient{}
+		upload := startProgressiveUpload(client, true)
+
+		require.NoError(t, upload.AddProfile(testProfile("App", "uuid-1")))
+		require.NoError(t, upload.AddProfile(testProfile("Widget", "uuid-2")))
+		// added twice, uploaded once
+		require.NoError(t, upload.AddProfile(testProfile("App", "uuid-1")))
+		require.NoError(t, upload.AddIdentities(testCertificates()))
+
+		certificatesUploaded, profilesUploaded, err := upload.Wait()
+		require.NoError(t, err)
+		require.True(t, certificatesUploaded)
+		require.True(t, profilesUploaded)
+
+		calls := client.recordedCalls()
+		require.Equal(t, 1, countCalls(calls, "FetchProvisioningProfiles"))
+		require.Equal(t, 1, countCalls(calls, "RegisterProvisioningProfile uuid-1.App.mobileprovision"))
+		require.Equal(t, []string{
+			"UploadArtifact https://upload/profile-1",
+			"UploadArtifact https://upload/profile-2",
+			"UploadArtifact https://upload/identity-3",
+		}, filterCalls(calls, "UploadArtifact"))
+		require.Empty(t, deleteCalls(calls))
+	})
+
+	t.Run("no identities added", func(t *testing.T) {
+		upload := startProgressiveUpload(&fakeClient{}, true)
+		require.NoError(t, upload.AddProfile(testProfile("App", "uuid-1")))
+
+		certificatesUploaded, profilesUploaded, err := upload.Wait()
+		require.NoError(t, err)
+		require.True(t, certificatesUploaded)
+		require.True(t, profilesUploaded)
+	})
+}
+
+func TestProgressiveUploadError(t *testing.T) {
+	uploadErr := errors.New("connection reset")
+	client := &fakeClient{failures: map[string]error{"UploadArtifact https://upload/profile-2": uploadErr}}
+	upload := startProgressiveUpload(client, true)
+
+	require.NoError(t, upload.AddProfile(testProfile("App", "uuid-1")))
+	require.NoError(t, upload.AddProfile(testProfile("Widget", "uuid-2")))
+	require.Eventually(t, func() bool { return upload.Err() != nil }, 5*time.Second, time.Millisecond)
+
+	// the error of the failed upload is returned to the export, the later jobs are dropped
+	require.Equal(t, uploadErr, upload.AddIdentities(testCertificates()))
+	require.Equal(t, uploadErr, upload.AddProfile(testProfile("Extension", "uuid-3")))
+
+	certificatesUploaded, profilesUploaded, err := upload.Wait()
+	require.EqualError(t, err, "connection reset, the already uploaded files were removed from Bitrise")
+	require.True(t, errors.Is(err, uploadErr))
+	require.False(t, certificatesUploaded)
+	require.False(t, profilesUploaded)
+
+	calls := client.recordedCalls()
+	require.Equal(t, 0, countCalls(calls, "RegisterIdentity"))
+	require.Equal(t, 0, countCalls(calls, "RegisterProvisioningProfile uuid-3.Extension.mobileprovision"))
+	require.Equal(t, []string{"DeleteProvisioningProfile profile-2", "DeleteProvisioningProfile profile-1"}, deleteCalls(calls))
+}
+
+func TestProgressiveUploadFail(t *testing.T) {
+	exportErr := errors.New("export failed")
+
+	tests := []struct {
+		name        string
+		rollback    bool
+		failures    map[string]error
+		wantDeletes []string
+		wantErr     string
+	}{
+		{
+			name:        "rolled back",
+			rollback:    true,
+			wantDeletes: []string{"DeleteProvisioningProfile profile-1"},
+			wantErr:     "export failed, the already uploaded files were removed from Bitrise",
+		},
+		{
+			name:        "upload failed too",
+			rollback:    true,
+			failures:    map[string]error{"ConfirmProvisioningProfileUpload profile-1": errors.New("bad request")},
+			wantDeletes: []string{"DeleteProvisioningProfile profile-1"},
+			wantErr:     "export failed, upload error: bad request, the already uploaded files were removed from Bitrise",
+		},
+		{
+			name:        "failed rollback",
+			rollback:    true,
+			failures:    map[string]error{"DeleteProvisioningProfile profile-1": errors.New("timeout")},
+			wantDeletes: []string{"DeleteProvisioningProfile profile-1"},
+			wantErr:     "export failed, the code signing files on Bitrise are incomplete, remove or re-upload the following files: uuid-1.App.mobileprovision (provisioning profile)",
+		},
+		{
+			name:    "rollback disabled",
+			wantErr: "export failed, the code signing files on Bitrise are incomplete, remove or re-upload the following files: uuid-1.App.mobileprovision (provisioning profile)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{failures: tt.failures}
+			upload := startProgressiveUpload(client, tt.rollback)
+			require.NoError(t, upload.AddProfile(testProfile("App", "uuid-1")))
+
+			err := upload.Fail(exportErr)
+			require.EqualError(t, err, tt.wantErr)
+			require.True(t, errors.Is(err, exportErr))
+			require.Equal(t, tt.wantDeletes, deleteCalls(client.recordedCalls()))
+		})
+	}
+}
+
+// filterCalls returns the recorded calls of the method
+func filterCalls(calls []string, method string) []string {
+	var filtered []string
+	for _, call := range calls {
+		if call == method || strings.HasPrefix(call, method+" ") {
+			filtered = append(filtered, call)
+		}
+	}
+	return filtered
+}
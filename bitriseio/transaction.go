@@ -0,0 +1,106 @@
+package bitriseio
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/bitriseio/bitrise"
+	"github.com/bitrise-io/codesigndoc/confirm"
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// uploadClient is the part of the Bitrise API used by the upload, implemented by *bitrise.Client
+type uploadClient interface {
+	FetchProvisioningProfiles() ([]bitrise.ProvisioningProfileListData, error)
+	GetUploadedProvisioningProfileUUIDby(profileSlug string) (string, error)
+	RegisterProvisioningProfile(provisioningProfSize int64, exportedProfileName string) (bitrise.RegisterProvisioningProfileData, error)
+	ConfirmProvisioningProfileUpload(profileSlug string, provUploadName string) error
+	DeleteProvisioningProfile(profileSlug string) error
+
+	FetchUploadedIdentities() ([]bitrise.IdentityListData, error)
+	GetUploadedCertificatesSerialby(identitySlug string) ([]big.Int, error)
+	RegisterIdentity(certificateSize int64) (bitrise.RegisterIdentityData, error)
+	ConfirmIdentityUpload(certificateSlug string, certificateUploadName string) error
+	DeleteIdentity(certificateSlug string) error
+
+	UploadArtifact(uploadURL string, content io.Reader) error
+}
+
+var _ uploadClient = (*bitrise.Client)(nil)
+
+type uploadedFileKind string
+
+const (
+	uploadedIdentity uploadedFileKind = "certificate"
+	uploadedProfile  uploadedFileKind = "provisioning profile"
+)
+
+type uploadedFile struct {
+	kind uploadedFileKind
+	slug string
+	name string
+}
+
+// uploadTransaction tracks the files created on Bitrise during an upload,
+// so a failed upload does not leave a half-updated code signing set behind.
+type uploadTransaction struct {
+	client  uploadClient
+	created []uploadedFile
+}
+
+// add records a file registered on Bitrise, it has to be called right after the registration,
+// as an unconfirmed upload is already visible on Bitrise.
+func (t *uploadTransaction) add(kind uploadedFileKind, slug, name string) {
+	t.created = append(t.created, uploadedFile{kind: kind, slug: slug, name: name})
+}
+
+// rollback deletes the files created so far, in reverse order
+func (t *uploadTransaction) rollback() []uploadedFile {
+	var remaining []uploadedFile
+	for i := len(t.created) - 1; i >= 0; i-- {
+		file := t.created[i]
+
+		var err error
+		if file.kind == uploadedIdentity {
+			err = t.client.DeleteIdentity(file.slug)
+		} else {
+			err = t.client.DeleteProvisioningProfile(file.slug)
+		}
+		if err != nil {
+			log.Warnf("Failed to delete %s (%s) from Bitrise: %s", file.kind, file.name, err)
+			remaining = append(remaining, file)
+		}
+	}
+	return remaining
+}
+
+// fail handles a failed upload step: rolls back the already created files if requested,
+// and returns an error describing the state of the code signing files on Bitrise.
+func (t *uploadTransaction) fail(err error, rollback bool) error {
+	if len(t.created) == 0 {
+		return err
+	}
+
+	incomplete := t.created
+	if rollback {
+		log.Warnf("Upload failed: %s", err)
+		plan := confirm.NewPlan("Roll back the files already uploaded to Bitrise")
+		for _, file := range t.created {
+			plan.Add("delete", file.name, string(file.kind))
+		}
+		// the rollback is approved by the rollback flag, it only removes the files uploaded by this run
+		fmt.Println()
+		log.Warnf("%s", plan.Summary())
+		if incomplete = t.rollback(); len(incomplete) == 0 {
+			return fmt.Errorf("%w, the already uploaded files were removed from Bitrise", err)
+		}
+	}
+
+	var names []string
+	for _, file := range incomplete {
+		names = append(names, fmt.Sprintf("%s (%s)", file.name, file.kind))
+	}
+	return fmt.Errorf("%w, the code signing files on Bitrise are incomplete, remove or re-upload the following files: %s", err, strings.Join(names, ", "))
+}
@@ -0,0 +1,242 @@
+package bitriseio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bitrise-io/codesigndoc/bitriseio/bitrise"
+	"github.com/bitrise-io/codesigndoc/models"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClient records the calls of the upload, the calls listed in failures return the given error,
+// a failure is keyed by the method name, or by the method name and its slug or upload URL (e.g. "DeleteIdentity identity-1")
+type fakeClient struct {
+	mutex    sync.Mutex
+	failures map[string]error
+	calls    []string
+	slugs    int
+}
+
+func (client *fakeClient) call(method, arg string) error {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	call := strings.TrimSpace(method + " " + arg)
+	client.calls = append(client.calls, call)
+	if err, ok := client.failures[call]; ok {
+		return err
+	}
+	return client.failures[method]
+}
+
+func (client *fakeClient) newSlug(kind string) string {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	client.slugs++
+	return fmt.Sprintf("%s-%d", kind, client.slugs)
+}
+
+func (client *fakeClient) recordedCalls() []string {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	return append([]string{}, client.calls...)
+}
+
+func (client *fakeClient) FetchProvisioningProfiles() ([]bitrise.ProvisioningProfileListData, error) {
+	return nil, client.call("FetchProvisioningProfiles", "")
+}
+
+func (client *fakeClient) GetUploadedProvisioningProfileUUIDby(profileSlug string) (string, error) {
+	return "", client.call("GetUploadedProvisioningProfileUUIDby", profileSlug)
+}
+
+func (client *fakeClient) RegisterProvisioningProfile(provisioningProfSize int64, exportedProfileName string) (bitrise.RegisterProvisioningProfileData, error) {
+	slug := client.newSlug("profile")
+	if err := client.call("RegisterProvisioningProfile", exportedProfileName); err != nil {
+		return bitrise.RegisterProvisioningProfileData{}, err
+	}
+	return bitrise.RegisterProvisioningProfileData{Slug: slug, UploadFileName: exportedProfileName, UploadURL: "https://upload/" + slug}, nil
+}
+
+func (client *fakeClient) ConfirmProvisioningProfileUpload(profileSlug string, provUploadName string) error {
+	return client.call("ConfirmProvisioningProfileUpload", profileSlug)
+}
+
+func (client *fakeClient) DeleteProvisioningProfile(profileSlug string) error {
+	return client.call("DeleteProvisioningProfile", profileSlug)
+}
+
+func (client *fakeClient) FetchUploadedIdentities() ([]bitrise.IdentityListData, error) {
+	return nil, client.call("FetchUploadedIdentities", "")
+}
+
+func (client *fakeClient) GetUploadedCertificatesSerialby(identitySlug string) ([]big.Int, error) {
+	return nil, client.call("GetUploadedCertificatesSerialby", identitySlug)
+}
+
+func (client *fakeClient) RegisterIdentity(certificateSize int64) (bitrise.RegisterIdentityData, error) {
+	slug := client.newSlug("identity")
+	if err := client.call("RegisterIdentity", ""); err != nil {
+		return bitrise.RegisterIdentityData{}, err
+	}
+	return bitrise.RegisterIdentityData{Slug: slug, UploadFileName: "Identities.p12", UploadURL: "https://upload/" + slug}, nil
+}
+
+func (client *fakeClient) ConfirmIdentityUpload(certificateSlug string, certificateUploadName string) error {
+	return client.call("ConfirmIdentityUpload", certificateSlug)
+}
+
+func (client *fakeClient) DeleteIdentity(certificateSlug string) error {
+	return client.call("DeleteIdentity", certificateSlug)
+}
+
+func (client *fakeClient) UploadArtifact(uploadURL string, content io.Reader) error {
+	if _, err := io.Copy(ioutil.Discard, content); err != nil {
+		return err
+	}
+	return client.call("UploadArtifact", uploadURL)
+}
+
+// deleteCalls returns the delete calls of the recorded calls
+func deleteCalls(calls []string) []string {
+	var deletes []string
+	for _, call := range calls {
+		if strings.HasPrefix(call, "Delete") {
+			deletes = append(deletes, call)
+		}
+	}
+	return deletes
+}
+
+func testProfile(name, uuid string) models.ProvisioningProfile {
+	return models.ProvisioningProfile{Info: profileutil.ProvisioningProfileInfoModel{Name: name, UUID: uuid}, Content: []byte(name)}
+}
+
+func testCertificates() models.Certificates {
+	return models.Certificates{Info: []certificateutil.CertificateInfoModel{{CommonName: "iPhone Distribution: Test", Serial: "1234"}}, Content: []byte("p12")}
+}
+
+func TestUploadTransactionFail(t *testing.T) {
+	uploadErr := errors.New("upload failed")
+	created := []uploadedFile{
+		{kind: uploadedProfile, slug: "profile-1", name: "App.mobileprovision"},
+		{kind: uploadedIdentity, slug: "identity-2", name: "Identities.p12"},
+	}
+
+	tests := []struct {
+		name        string
+		created     []uploadedFile
+		rollback    bool
+		failures    map[string]error
+		wantDeletes []string
+		wantErr     string
+	}{
+		{
+			name:     "nothing uploaded",
+			rollback: true,
+			wantErr:  "upload failed",
+		},
+		{
+			name:        "rolled back in reverse order",
+			created:     created,
+			rollback:    true,
+			wantDeletes: []string{"DeleteIdentity identity-2", "DeleteProvisioningProfile profile-1"},
+			wantErr:     "upload failed, the already uploaded files were removed from Bitrise",
+		},
+		{
+			name:        "partially failed rollback",
+			created:     created,
+			rollback:    true,
+			failures:    map[string]error{"DeleteProvisioningProfile profile-1": errors.New("internal server error")},
+			wantDeletes: []string{"DeleteIdentity identity-2", "DeleteProvisioningProfile profile-1"},
+			wantErr:     "upload failed, the code signing files on Bitrise are incomplete, remove or re-upload the following files: App.mobileprovision (provisioning profile)",
+		},
+		{
+			name:        "failed rollback",
+			created:     created,
+			rollback:    true,
+			failures:    map[string]error{"DeleteIdentity": errors.New("timeout"), "DeleteProvisioningProfile": errors.New("timeout")},
+			wantDeletes: []string{"DeleteIdentity identity-2", "DeleteProvisioningProfile profile-1"},
+			wantErr:     "upload failed, the code signing files on Bitrise are incomplete, remove or re-upload the following files: Identities.p12 (certificate), App.mobileprovision (provisioning profile)",
+		},
+		{
+			name:    "rollback disabled",
+			created: created,
+			wantErr: "upload failed, the code signing files on Bitrise are incomplete, remove or re-upload the following files: App.mobileprovision (provisioning profile), Identities.p12 (certificate)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{failures: tt.failures}
+			transaction := &uploadTransaction{client: client}
+			for _, file := range tt.created {
+				transaction.add(file.kind, file.slug, file.name)
+			}
+
+			err := transaction.fail(uploadErr, tt.rollback)
+			require.EqualError(t, err, tt.wantErr)
+			require.True(t, errors.Is(err, uploadErr))
+			require.Equal(t, tt.wantDeletes, deleteCalls(client.recordedCalls()))
+		})
+	}
+}
+
+func TestUploadCodesigningFilesRollback(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    map[string]error
+		wantDeletes []string
+		wantErr     string
+	}{
+		{
+			name:     "profile confirmation fails",
+			failures: map[string]error{"ConfirmProvisioningProfileUpload profile-2": errors.New("bad request")},
+			// the identity is not registered yet
+			wantDeletes: []string{"DeleteProvisioningProfile profile-2", "DeleteProvisioningProfile profile-1"},
+			wantErr:     "bad request, the already uploaded files were removed from Bitrise",
+		},
+		{
+			name:        "identity upload fails",
+			failures:    map[string]error{"UploadArtifact https://upload/identity-3": errors.New("connection reset")},
+			wantDeletes: []string{"DeleteIdentity identity-3", "DeleteProvisioningProfile profile-2", "DeleteProvisioningProfile profile-1"},
+			wantErr:     "connection reset, the already uploaded files were removed from Bitrise",
+		},
+		{
+			name: "identity upload and its rollback fail",
+			failures: map[string]error{
+				"UploadArtifact https://upload/identity-3": errors.New("connection reset"),
+				"DeleteIdentity identity-3":                errors.New("connection reset"),
+			},
+			wantDeletes: []string{"DeleteIdentity identity-3", "DeleteProvisioningProfile profile-2", "DeleteProvisioningProfile profile-1"},
+			wantErr:     "connection reset, the code signing files on Bitrise are incomplete, remove or re-upload the following files: Identities.p12 (certificate)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{failures: tt.failures}
+			profiles := []models.ProvisioningProfile{testProfile("App", "uuid-1"), testProfile("Widget", "uuid-2")}
+
+			_, _, err := uploadCodesigningFiles(client, testCertificates(), profiles, true)
+			require.EqualError(t, err, tt.wantErr)
+			require.Equal(t, tt.wantDeletes, deleteCalls(client.recordedCalls()))
+		})
+	}
+
+	client := &fakeClient{}
+	certificatesUploaded, profilesUploaded, err := uploadCodesigningFiles(client, testCertificates(), []models.ProvisioningProfile{testProfile("App", "uuid-1")}, true)
+	require.NoError(t, err)
+	require.True(t, certificatesUploaded)
+	require.True(t, profilesUploaded)
+	require.Empty(t, deleteCalls(client.recordedCalls()))
+}
@@ -0,0 +1,344 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// ManifestFileName is the name of the reassembly manifest written next to the chunks
+	ManifestFileName = "manifest.json"
+
+	formatVersion = 1
+	kdfName       = "pbkdf2-sha256"
+	kdfIterations = 310000
+	keyLength     = 32
+	saltLength    = 16
+
+	// minKDFIterations and maxKDFIterations bound the iterations accepted from a manifest,
+	// a tampered manifest must not stall the key derivation
+	minKDFIterations = 1000
+	maxKDFIterations = 10000000
+)
+
+// Chunk is a part of the encrypted archive
+type Chunk struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest describes how to reassemble and decrypt the chunks
+type Manifest struct {
+	Version       int       `json:"version"`
+	CreatedAt     time.Time `json:"created_at"`
+	KDF           string    `json:"kdf"`
+	Iterations    int       `json:"iterations"`
+	Salt          string    `json:"salt"`
+	Nonce         string    `json:"nonce"`
+	ArchiveSHA256 string    `json:"archive_sha256"`
+	Chunks        []Chunk   `json:"chunks"`
+}
+
+// Split packs the files of srcDir into an encrypted archive, and writes it into outDir as base64 text chunks
+// of at most chunkSize bytes named <namePrefix>-NN.txt, plus the reassembly manifest.
+func Split(srcDir, passphrase string, chunkSize int, outDir, namePrefix string) (Manifest, error) {
+	if passphrase == "" {
+		return Manifest{}, errors.New("passphrase is required")
+	}
+	if chunkSize <= 0 {
+		return Manifest{}, fmt.Errorf("invalid chunk size: %d", chunkSize)
+	}
+
+	archive, err := createArchive(srcDir)
+	if err != nil {
+		return Manifest{}, err
+	}
+	archiveHash := sha256.Sum256(archive)
+
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return Manifest{}, err
+	}
+	aead, err := newAEAD(passphrase, salt, kdfIterations)
+	if err != nil {
+		return Manifest{}, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return Manifest{}, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(aead.Seal(nil, nonce, archive, nil))
+
+	if err := os.MkdirAll(outDir, 0700); err != nil {
+		return Manifest{}, fmt.Errorf("failed to create output directory, error: %s", err)
+	}
+
+	manifest := Manifest{
+		Version:       formatVersion,
+		CreatedAt:     time.Now(),
+		KDF:           kdfName,
+		Iterations:    kdfIterations,
+		Salt:          base64.StdEncoding.EncodeToString(salt),
+		Nonce:         base64.StdEncoding.EncodeToString(nonce),
+		ArchiveSHA256: hex.EncodeToString(archiveHash[:]),
+	}
+	for idx := 0; idx*chunkSize < len(encoded); idx++ {
+		end := (idx + 1) * chunkSize
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+		content := []byte(encoded[idx*chunkSize : end])
+		name := fmt.Sprintf("%s-%02d.txt", namePrefix, idx+1)
+		if err := ioutil.WriteFile(filepath.Join(outDir, name), content, 0600); err != nil {
+			return Manifest{}, fmt.Errorf("failed to write chunk, error: %s", err)
+		}
+
+		hash := sha256.Sum256(content)
+		manifest.Chunks = append(manifest.Chunks, Chunk{Name: name, SHA256: hex.EncodeToString(hash[:])})
+	}
+
+	manifestContent, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return Manifest{}, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(outDir, ManifestFileName), manifestContent, 0600); err != nil {
+		return Manifest{}, fmt.Errorf("failed to write manifest, error: %s", err)
+	}
+	return manifest, nil
+}
+
+// Join verifies and reassembles the chunks listed in the manifest, decrypts the archive and extracts it into outDir,
+// returns the extracted file paths relative to outDir.
+func Join(manifestPth, passphrase, outDir string) ([]string, error) {
+	content, err := ioutil.ReadFile(manifestPth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest, error: %s", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest, error: %s", err)
+	}
+	if manifest.Version != formatVersion || manifest.KDF != kdfName {
+		return nil, fmt.Errorf("unsupported bundle format (version: %d, kdf: %s)", manifest.Version, manifest.KDF)
+	}
+	if err := validateManifest(manifest); err != nil {
+		return nil, err
+	}
+
+	chunkDir := filepath.Dir(manifestPth)
+	var encoded bytes.Buffer
+	var problems []string
+	for _, chunk := range manifest.Chunks {
+		chunkContent, err := ioutil.ReadFile(filepath.Join(chunkDir, chunk.Name))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s is missing", chunk.Name))
+			continue
+		}
+		// mail clients and text tools may add line endings
+		chunkContent = bytes.TrimRight(chunkContent, "\r\n")
+		if hash := sha256.Sum256(chunkContent); hex.EncodeToString(hash[:]) != chunk.SHA256 {
+			problems = append(problems, fmt.Sprintf("%s is corrupted", chunk.Name))
+			continue
+		}
+		encoded.Write(chunkContent)
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("failed to reassemble the bundle: %s", strings.Join(problems, ", "))
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the bundle, error: %s", err)
+	}
+	salt, err := base64.StdEncoding.DecodeString(manifest.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt in manifest, error: %s", err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(manifest.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce in manifest, error: %s", err)
+	}
+
+	aead, err := newAEAD(passphrase, salt, manifest.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce in manifest")
+	}
+	archive, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt the bundle: wrong passphrase or the bundle was modified")
+	}
+	if hash := sha256.Sum256(archive); hex.EncodeToString(hash[:]) != manifest.ArchiveSHA256 {
+		return nil, errors.New("archive checksum mismatch after decryption")
+	}
+
+	return extractArchive(archive, outDir)
+}
+
+// validateManifest rejects the KDF iterations out of the accepted range and the chunk names which are not plain file names,
+// as the chunks are read from the directory of the manifest
+func validateManifest(manifest Manifest) error {
+	if manifest.Iterations < minKDFIterations || manifest.Iterations > maxKDFIterations {
+		return fmt.Errorf("invalid manifest: %d KDF iterations, expected %d - %d", manifest.Iterations, minKDFIterations, maxKDFIterations)
+	}
+	for _, chunk := range manifest.Chunks {
+		if chunk.Name == "" || chunk.Name == "." || chunk.Name == ".." || filepath.Base(chunk.Name) != chunk.Name {
+			return fmt.Errorf("invalid manifest: chunk name (%s) is not a file name", chunk.Name)
+		}
+	}
+	return nil
+}
+
+// Encrypt packs the files of srcDir into a tar.gz archive, and writes it to pth encrypted with the passphrase
+// in the OpenPGP format of gpg --symmetric, so it can be decrypted with gpg --decrypt too
+func Encrypt(srcDir, passphrase, pth string) error {
+	if passphrase == "" {
+		return errors.New("passphrase is required")
+	}
+
+	archive, err := createArchive(srcDir)
+	if err != nil {
+		return err
+	}
+	content, err := encryptOpenPGP(archive, strings.TrimSuffix(filepath.Base(pth), ".gpg"), passphrase, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to encrypt the archive, error: %s", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(pth), 0700); err != nil {
+		return fmt.Errorf("failed to create output directory, error: %s", err)
+	}
+	if err := ioutil.WriteFile(pth, content, 0600); err != nil {
+		return fmt.Errorf("failed to write encrypted file, error: %s", err)
+	}
+	return nil
+}
+
+// Decrypt decrypts the file written by Encrypt and extracts it into outDir,
+// returns the extracted file paths relative to outDir.
+func Decrypt(pth, passphrase, outDir string) ([]string, error) {
+	content, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encrypted file, error: %s", err)
+	}
+	archive, err := decryptOpenPGP(content, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return extractArchive(archive, outDir)
+}
+
+func newAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key := pbkdf2.Key([]byte(passphrase), salt, iterations, keyLength, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func createArchive(srcDir string) ([]byte, error) {
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	if err := filepath.Walk(srcDir, func(pth string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(srcDir, pth)
+		if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadFile(pth)
+		if err != nil {
+			return err
+		}
+
+		if err := tarWriter.WriteHeader(&tar.Header{Name: filepath.ToSlash(rel), Mode: 0600, Size: int64(len(content)), ModTime: info.ModTime()}); err != nil {
+			return err
+		}
+		_, err = tarWriter.Write(content)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("failed to archive %s, error: %s", srcDir, err)
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return nil, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func extractArchive(archive []byte, outDir string) ([]string, error) {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	tarReader := tar.NewReader(gzipReader)
+
+	if err := os.MkdirAll(outDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create output directory, error: %s", err)
+	}
+
+	var files []string
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if !header.FileInfo().Mode().IsRegular() {
+			// e.g. the directory entries of an archive created by tar
+			continue
+		}
+
+		rel := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(rel) || strings.HasPrefix(filepath.Clean(rel), "..") {
+			return nil, fmt.Errorf("invalid file path in the bundle: %s", header.Name)
+		}
+
+		pth := filepath.Join(outDir, rel)
+		if err := os.MkdirAll(filepath.Dir(pth), 0700); err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadAll(tarReader)
+		if err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(pth, content, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write %s, error: %s", pth, err)
+		}
+		files = append(files, rel)
+	}
+	return files, nil
+}
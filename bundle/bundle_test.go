@@ -0,0 +1,216 @@
+package bundle
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitJoin(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "bundle")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tmpDir))
+	}()
+
+	srcDir := filepath.Join(tmpDir, "src")
+	require.NoError(t, os.MkdirAll(srcDir, 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(srcDir, "Identities.p12"), []byte("identities"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(srcDir, "profile.mobileprovision"), []byte("profile"), 0600))
+
+	chunkDir := filepath.Join(tmpDir, "chunks")
+	manifest, err := Split(srcDir, "secret", 16, chunkDir, "notes")
+	require.NoError(t, err)
+	require.True(t, len(manifest.Chunks) > 1)
+
+	_, err = Join(filepath.Join(chunkDir, ManifestFileName), "wrong", filepath.Join(tmpDir, "wrong"))
+	require.Error(t, err)
+
+	outDir := filepath.Join(tmpDir, "out")
+	files, err := Join(filepath.Join(chunkDir, ManifestFileName), "secret", outDir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Identities.p12", "profile.mobileprovision"}, files)
+
+	content, err := ioutil.ReadFile(filepath.Join(outDir, "Identities.p12"))
+	require.NoError(t, err)
+	require.Equal(t, "identities", string(content))
+
+	require.NoError(t, os.Remove(filepath.Join(chunkDir, manifest.Chunks[0].Name)))
+	_, err = Join(filepath.Join(chunkDir, ManifestFileName), "secret", outDir)
+	require.EqualError(t, err, "failed to reassemble the bundle: notes-01.txt is missing")
+}
+
+func TestJoinInvalidManifest(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "bundle")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tmpDir))
+	}()
+
+	srcDir := filepath.Join(tmpDir, "src")
+	require.NoError(t, os.MkdirAll(srcDir, 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(srcDir, "Identities.p12"), []byte("identities"), 0600))
+	chunkDir := filepath.Join(tmpDir, "chunks")
+	manifest, err := Split(srcDir, "secret", 16, chunkDir, "notes")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		modify  func(manifest *Manifest)
+		wantErr string
+	}{
+		{
+			name:    "huge iteration count",
+			modify:  func(manifest *Manifest) { manifest.Iterations = 1 << 40 },
+			wantErr: "invalid manifest: 1099511627776 KDF iterations, expected 1000 - 10000000",
+		},
+		{
+			name:    "no iterations",
+			modify:  func(manifest *Manifest) { manifest.Iterations = 0 },
+			wantErr: "invalid manifest: 0 KDF iterations, expected 1000 - 10000000",
+		},
+		{
+			name:    "chunk outside of the directory",
+			modify:  func(manifest *Manifest) { manifest.Chunks[0].Name = "../src/Identities.p12" },
+			wantErr: "invalid manifest: chunk name (../src/Identities.p12) is not a file name",
+		},
+		{
+			name:    "parent directory chunk",
+			modify:  func(manifest *Manifest) { manifest.Chunks[0].Name = ".." },
+			wantErr: "invalid manifest: chunk name (..) is not a file name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified := manifest
+			modified.Chunks = append([]Chunk{}, manifest.Chunks...)
+			tt.modify(&modified)
+			content, err := json.Marshal(modified)
+			require.NoError(t, err)
+			pth := filepath.Join(chunkDir, "modified.json")
+			require.NoError(t, ioutil.WriteFile(pth, content, 0600))
+
+			_, err = Join(pth, "secret", filepath.Join(tmpDir, "out"))
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "bundle")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tmpDir))
+	}()
+
+	srcDir := filepath.Join(tmpDir, "src")
+	require.NoError(t, os.MkdirAll(filepath.Join(srcDir, "profiles"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(srcDir, "Identities.p12"), []byte("identities"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(srcDir, "profiles", "profile.mobileprovision"), []byte("profile"), 0600))
+
+	pth := filepath.Join(tmpDir, "codesigndoc_exports.tar.gz.gpg")
+	require.NoError(t, Encrypt(srcDir, "secret", pth))
+
+	content, err := ioutil.ReadFile(pth)
+	require.NoError(t, err)
+	require.NotContains(t, string(content), "identities")
+	require.Equal(t, byte(0xc3), content[0], "the file starts with the OpenPGP passphrase protected key")
+
+	_, err = Decrypt(pth, "wrong", filepath.Join(tmpDir, "wrong"))
+	require.EqualError(t, err, "failed to decrypt the file: wrong passphrase or the file was modified")
+
+	outDir := filepath.Join(tmpDir, "out")
+	files, err := Decrypt(pth, "secret", outDir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Identities.p12", filepath.Join("profiles", "profile.mobileprovision")}, files)
+}
+
+// gpgEncrypted are tar.gz archives of Identities.p12 encrypted by gpg (passphrase: secret)
+var gpgEncrypted = map[string]string{
+	"gpg --symmetric --s2k-count 1024 --compress-algo zlib": "jA0ECQMCTccQaMiUToH/0rsBJvGQB831RN2kqTbGq2Xm5mFznKAVIR2A5MiLXHwpmSIbVFoeP2ysIwuzF8h+L3D8/0NC7oxRBKo+HIvGqph2Bq8UndgICgJNfjkRTQYfXM+ryS4s4s29C0F0QoAPgwuro3bUfEkqBrpxc84twGWZEocUOVLNiCHbwLEPFv185zXCVXqr4aC56gArsnZp0azEIwNGhIFmYTemelvvchO1BMGl8zHXN0M+5zsCBpNw1Gv3nXA7O4lvXKuB",
+	"gpg --symmetric --s2k-count 1024 --cipher-algo AES128": "jA0EBwMC3tSAMoj4S3D/0roBzDOu3StMZal2wzornknHAJNc7UIK5c8l165lX+/CquQC0anG+HxkJwHO26b0bKgmix/B6KywrySoeloHeVuTiPMIPMnqIt57W5quUc441U3Izvc+usLWhyaMeNHdPYWpe6eg1sCixfc/giE5EcZQ5Yi237V77SyCGWjeCfaoocC+lyr9NnK+RL0oYzzIc6y800k7zKZtA4lvzaDaEa36aA3L1qHECY4hJMmT0FItsyyGOoaJWj8jgyA=",
+}
+
+func TestDecryptGPG(t *testing.T) {
+	for command, encrypted := range gpgEncrypted {
+		t.Run(command, func(t *testing.T) {
+			tmpDir, err := ioutil.TempDir("", "bundle")
+			require.NoError(t, err)
+			defer func() {
+				require.NoError(t, os.RemoveAll(tmpDir))
+			}()
+
+			content, err := base64.StdEncoding.DecodeString(encrypted)
+			require.NoError(t, err)
+			pth := filepath.Join(tmpDir, "codesigndoc_exports.tar.gz.gpg")
+			require.NoError(t, ioutil.WriteFile(pth, content, 0600))
+
+			_, err = Decrypt(pth, "wrong", filepath.Join(tmpDir, "wrong"))
+			require.EqualError(t, err, "failed to decrypt the file: wrong passphrase or the file was modified")
+
+			content[len(content)-1] ^= 1
+			modifiedPth := filepath.Join(tmpDir, "modified.tar.gz.gpg")
+			require.NoError(t, ioutil.WriteFile(modifiedPth, content, 0600))
+			_, err = Decrypt(modifiedPth, "secret", filepath.Join(tmpDir, "modified"))
+			require.EqualError(t, err, "failed to decrypt the file: wrong passphrase or the file was modified")
+
+			outDir := filepath.Join(tmpDir, "out")
+			files, err := Decrypt(pth, "secret", outDir)
+			require.NoError(t, err)
+			require.Equal(t, []string{"Identities.p12"}, files)
+			identities, err := ioutil.ReadFile(filepath.Join(outDir, "Identities.p12"))
+			require.NoError(t, err)
+			require.Equal(t, "identities", string(identities))
+		})
+	}
+}
+
+func TestEncryptDecryptWithGPG(t *testing.T) {
+	gpg, err := exec.LookPath("gpg")
+	if err != nil {
+		t.Skip("gpg is not installed")
+	}
+
+	tmpDir, err := ioutil.TempDir("", "bundle")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tmpDir))
+	}()
+
+	gnupgHome := filepath.Join(tmpDir, "gnupg")
+	require.NoError(t, os.MkdirAll(gnupgHome, 0700))
+	runGPG := func(args ...string) {
+		cmd := exec.Command(gpg, append([]string{"--homedir", gnupgHome, "--batch", "--quiet", "--pinentry-mode", "loopback", "--passphrase", "secret"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	srcDir := filepath.Join(tmpDir, "src")
+	require.NoError(t, os.MkdirAll(srcDir, 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(srcDir, "Identities.p12"), []byte("identities"), 0600))
+
+	// gpg decrypts the file written by Encrypt
+	pth := filepath.Join(tmpDir, "codesigndoc_exports.tar.gz.gpg")
+	require.NoError(t, Encrypt(srcDir, "secret", pth))
+	archivePth := filepath.Join(tmpDir, "codesigndoc_exports.tar.gz")
+	runGPG("--output", archivePth, "--decrypt", pth)
+	archive, err := ioutil.ReadFile(archivePth)
+	require.NoError(t, err)
+	outDir := filepath.Join(tmpDir, "out")
+	files, err := extractArchive(archive, outDir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Identities.p12"}, files)
+
+	// Decrypt reads the file written by gpg with its default settings
+	gpgPth := filepath.Join(tmpDir, "gpg.tar.gz.gpg")
+	runGPG("--output", gpgPth, "--symmetric", archivePth)
+	files, err = Decrypt(gpgPth, "secret", filepath.Join(tmpDir, "gpg"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"Identities.p12"}, files)
+}
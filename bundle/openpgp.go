@@ -0,0 +1,86 @@
+package bundle
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"golang.org/x/crypto/openpgp"
+	pgperrors "golang.org/x/crypto/openpgp/errors"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// The encrypted single file export is an OpenPGP message (RFC 4880), as written by gpg --symmetric:
+// a passphrase protected (iterated and salted S2K) AES-256 key, and the integrity protected literal data encrypted with it.
+// It can be decrypted with gpg --decrypt too.
+
+// s2kCount is the number of bytes of the salt and the passphrase hashed into the key, the maximum the format can encode
+const s2kCount = 65011712
+
+// errWrongPassphrase is returned if the decryption check fails
+var errWrongPassphrase = errors.New("failed to decrypt the file: wrong passphrase or the file was modified")
+
+var openPGPConfig = &packet.Config{
+	DefaultCipher:          packet.CipherAES256,
+	DefaultHash:            crypto.SHA256,
+	DefaultCompressionAlgo: packet.CompressionNone,
+	S2KCount:               s2kCount,
+}
+
+// encryptOpenPGP encrypts the content as the literal data named name
+func encryptOpenPGP(content []byte, name, passphrase string, modTime time.Time) ([]byte, error) {
+	var message bytes.Buffer
+	plaintext, err := openpgp.SymmetricallyEncrypt(&message, []byte(passphrase), &openpgp.FileHints{
+		IsBinary: true,
+		FileName: name,
+		ModTime:  modTime,
+	}, openPGPConfig)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := plaintext.Write(content); err != nil {
+		return nil, err
+	}
+	if err := plaintext.Close(); err != nil {
+		return nil, err
+	}
+	return message.Bytes(), nil
+}
+
+// decryptOpenPGP decrypts the passphrase encrypted message and returns its literal data
+func decryptOpenPGP(message []byte, passphrase string) ([]byte, error) {
+	prompted := false
+	prompt := func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+		if !symmetric {
+			return nil, errors.New("the encrypted file has no passphrase protected key")
+		}
+		// ReadMessage prompts again for every wrong passphrase
+		if prompted {
+			return nil, errWrongPassphrase
+		}
+		prompted = true
+		return []byte(passphrase), nil
+	}
+
+	details, err := openpgp.ReadMessage(bytes.NewReader(message), openpgp.EntityList(nil), prompt, openPGPConfig)
+	if err == errWrongPassphrase {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to parse the encrypted file, error: %s", err)
+	}
+	if !details.IsSymmetricallyEncrypted {
+		return nil, errors.New("the encrypted file has no passphrase protected key")
+	}
+
+	// the integrity of the data is checked once it is read to the end
+	content, err := ioutil.ReadAll(details.UnverifiedBody)
+	if _, ok := err.(pgperrors.SignatureError); ok {
+		return nil, errWrongPassphrase
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the file, error: %s", err)
+	}
+	return content, nil
+}
@@ -0,0 +1,122 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// azureBlobVersion is the Blob service REST API version of the requests
+const azureBlobVersion = "2021-08-06"
+
+// azureBlobUploader puts block blobs with the Blob service REST API, authorized by a SAS token or the Shared Key of the account
+type azureBlobUploader struct {
+	dest       Destination
+	endpoint   string
+	accountKey []byte
+	sasToken   string
+	client     *http.Client
+}
+
+func newAzureBlobUploader(dest Destination, config Config, client *http.Client) (*azureBlobUploader, error) {
+	uploader := &azureBlobUploader{dest: dest, sasToken: strings.TrimPrefix(config.AzureSASToken, "?"), client: client}
+	if uploader.sasToken == "" {
+		if config.AzureAccountKey == "" {
+			return nil, fmt.Errorf("the Azure Blob upload requires the %s or the %s env var", AzureSASTokenEnvKey, AzureAccountKeyEnvKey)
+		}
+		key, err := base64.StdEncoding.DecodeString(config.AzureAccountKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s, it is not base64 encoded, error: %s", AzureAccountKeyEnvKey, err)
+		}
+		uploader.accountKey = key
+	}
+
+	uploader.endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", dest.Account)
+	if config.Endpoint != "" {
+		// the emulators (e.g. Azurite) use path style URLs: <endpoint>/<account>
+		uploader.endpoint = strings.TrimSuffix(config.Endpoint, "/") + "/" + dest.Account
+	}
+	return uploader, nil
+}
+
+// URL returns the URL of the blob, without the SAS token
+func (uploader azureBlobUploader) URL(name string) string {
+	return uploader.endpoint + "/" + uploader.dest.Bucket + "/" + s3URIEncode(uploader.dest.objectName(name), false)
+}
+
+// Upload ...
+func (uploader azureBlobUploader) Upload(name string, content []byte) error {
+	requestURL := uploader.URL(name)
+	if uploader.sasToken != "" {
+		requestURL += "?" + uploader.sasToken
+	}
+	return send(uploader.client, func() (*http.Request, error) {
+		request, err := http.NewRequest(http.MethodPut, requestURL, bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Content-Type", "application/octet-stream")
+		request.Header.Set("X-Ms-Blob-Type", "BlockBlob")
+		request.Header.Set("X-Ms-Version", azureBlobVersion)
+		request.Header.Set("X-Ms-Date", time.Now().UTC().Format(http.TimeFormat))
+		if uploader.accountKey != nil {
+			request.Header.Set("Authorization", sharedKeyAuthorization(request, len(content), uploader.dest.Account, uploader.accountKey))
+		}
+		return request, nil
+	})
+}
+
+// sharedKeyAuthorization returns the Shared Key Authorization header of the request
+func sharedKeyAuthorization(request *http.Request, contentLength int, account string, key []byte) string {
+	length := ""
+	if contentLength > 0 {
+		length = strconv.Itoa(contentLength)
+	}
+
+	var msHeaders []string
+	for name, values := range request.Header {
+		if name = strings.ToLower(name); strings.HasPrefix(name, "x-ms-") {
+			msHeaders = append(msHeaders, name+":"+strings.TrimSpace(strings.Join(values, ",")))
+		}
+	}
+	sort.Strings(msHeaders)
+
+	resource := "/" + account + request.URL.EscapedPath()
+	query := request.URL.Query()
+	var params []string
+	for name := range query {
+		params = append(params, strings.ToLower(name))
+	}
+	sort.Strings(params)
+	for _, name := range params {
+		values := append([]string{}, query[name]...)
+		sort.Strings(values)
+		resource += "\n" + name + ":" + strings.Join(values, ",")
+	}
+
+	stringToSign := strings.Join([]string{
+		request.Method,
+		request.Header.Get("Content-Encoding"),
+		request.Header.Get("Content-Language"),
+		length,
+		request.Header.Get("Content-MD5"),
+		request.Header.Get("Content-Type"),
+		"", // Date, x-ms-date is set instead
+		request.Header.Get("If-Modified-Since"),
+		request.Header.Get("If-Match"),
+		request.Header.Get("If-None-Match"),
+		request.Header.Get("If-Unmodified-Since"),
+		request.Header.Get("Range"),
+	}, "\n") + "\n" + strings.Join(msHeaders, "\n") + "\n" + resource
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(stringToSign))
+	return "SharedKey " + account + ":" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
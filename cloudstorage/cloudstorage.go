@@ -0,0 +1,208 @@
+package cloudstorage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/network"
+	"github.com/bitrise-io/codesigndoc/progress"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/retry"
+)
+
+// Feature names of the cloud storage destinations
+const (
+	S3Feature        = "destination/s3"
+	GCSFeature       = "destination/gcs"
+	AzureBlobFeature = "destination/azure-blob"
+)
+
+// Env vars of the credentials, the matching command line flags take precedence
+const (
+	AWSAccessKeyIDEnvKey     = "AWS_ACCESS_KEY_ID"
+	AWSSecretAccessKeyEnvKey = "AWS_SECRET_ACCESS_KEY"
+	AWSSessionTokenEnvKey    = "AWS_SESSION_TOKEN"
+	AWSRegionEnvKey          = "AWS_REGION"
+	GCSAccessTokenEnvKey     = "CLOUDSDK_AUTH_ACCESS_TOKEN"
+	AzureAccountKeyEnvKey    = "AZURE_STORAGE_KEY"
+	AzureSASTokenEnvKey      = "AZURE_STORAGE_SAS_TOKEN"
+)
+
+// Uploader stores the exported artifacts in a bucket
+type Uploader interface {
+	// Upload stores the content as the named object, the name is relative to the prefix of the destination
+	Upload(name string, content []byte) error
+	// URL returns the location of the named object, for the logs
+	URL(name string) string
+}
+
+// Destination is a parsed storage URL: s3://<bucket>/<prefix>, gs://<bucket>/<prefix> or azblob://<account>/<container>/<prefix>
+type Destination struct {
+	Scheme string
+	// Account is the storage account of Azure Blob, empty for the other schemes
+	Account string
+	Bucket  string
+	Prefix  string
+}
+
+// ParseDestination parses the storage URL
+func ParseDestination(destination string) (Destination, error) {
+	u, err := url.Parse(destination)
+	if err != nil {
+		return Destination{}, fmt.Errorf("invalid storage URL (%s), error: %s", destination, err)
+	}
+
+	dest := Destination{Scheme: u.Scheme, Bucket: u.Host}
+	prefix := strings.Trim(u.Path, "/")
+	switch u.Scheme {
+	case "s3", "gs":
+	case "azblob":
+		split := strings.SplitN(prefix, "/", 2)
+		dest.Account, dest.Bucket, prefix = u.Host, split[0], ""
+		if len(split) == 2 {
+			prefix = split[1]
+		}
+	default:
+		return Destination{}, fmt.Errorf("invalid storage URL (%s), valid formats: s3://<bucket>/<prefix>, gs://<bucket>/<prefix>, azblob://<account>/<container>/<prefix>", destination)
+	}
+	if dest.Bucket == "" {
+		return Destination{}, fmt.Errorf("invalid storage URL (%s), the bucket is missing", destination)
+	}
+	dest.Prefix = prefix
+	return dest, nil
+}
+
+// Feature returns the feature name of the destination
+func (dest Destination) Feature() string {
+	switch dest.Scheme {
+	case "s3":
+		return S3Feature
+	case "gs":
+		return GCSFeature
+	}
+	return AzureBlobFeature
+}
+
+// objectName joins the prefix of the destination and the name
+func (dest Destination) objectName(name string) string {
+	return strings.TrimPrefix(path.Join(dest.Prefix, name), "/")
+}
+
+// Config are the credentials and the endpoint settings of the uploaders
+type Config struct {
+	AWSAccessKeyID     string
+	AWSSecretAccessKey string
+	AWSSessionToken    string
+	// AWSRegion is the region of the S3 bucket, us-east-1 if empty
+	AWSRegion string
+
+	// GCSAccessToken is an OAuth2 access token, gcloud auth print-access-token is used if empty
+	GCSAccessToken string
+
+	// AzureAccountKey signs the requests with the Shared Key of the storage account, AzureSASToken is used instead if set
+	AzureAccountKey string
+	AzureSASToken   string
+
+	// Endpoint replaces the public endpoint of the service, e.g. for MinIO, a GCS or an Azurite emulator
+	Endpoint string
+}
+
+// ConfigFromEnv returns the credentials set by the env vars
+func ConfigFromEnv() Config {
+	return Config{
+		AWSAccessKeyID:     os.Getenv(AWSAccessKeyIDEnvKey),
+		AWSSecretAccessKey: os.Getenv(AWSSecretAccessKeyEnvKey),
+		AWSSessionToken:    os.Getenv(AWSSessionTokenEnvKey),
+		AWSRegion:          os.Getenv(AWSRegionEnvKey),
+		GCSAccessToken:     os.Getenv(GCSAccessTokenEnvKey),
+		AzureAccountKey:    os.Getenv(AzureAccountKeyEnvKey),
+		AzureSASToken:      os.Getenv(AzureSASTokenEnvKey),
+	}
+}
+
+// Secrets returns the credentials of the config, to be redacted from the logs
+func (config Config) Secrets() []string {
+	return []string{config.AWSSecretAccessKey, config.AWSSessionToken, config.GCSAccessToken, config.AzureAccountKey, config.AzureSASToken}
+}
+
+// New returns the uploader of the destination
+func New(dest Destination, config Config) (Uploader, error) {
+	client, err := network.NewHTTPClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client, error: %s", err)
+	}
+
+	switch dest.Scheme {
+	case "s3":
+		return newS3Uploader(dest, config, client)
+	case "gs":
+		return newGCSUploader(dest, config, client)
+	}
+	return newAzureBlobUploader(dest, config, client)
+}
+
+// UploadFiles uploads the files, named by their path relative to the dir, and returns the URLs of the uploaded objects
+func UploadFiles(uploader Uploader, dir string, files []string) ([]string, error) {
+	uploading := progress.Start("Uploading the files", len(files))
+	defer uploading.Done()
+
+	var urls []string
+	for _, file := range files {
+		rel, err := filepath.Rel(dir, file)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			rel = filepath.Base(file)
+		}
+		name := filepath.ToSlash(rel)
+
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return urls, fmt.Errorf("failed to read %s, error: %s", file, err)
+		}
+		log.Printf("Uploading %s to %s", rel, uploader.URL(name))
+		if err := uploader.Upload(name, content); err != nil {
+			return urls, fmt.Errorf("failed to upload %s, error: %s", rel, err)
+		}
+		urls = append(urls, uploader.URL(name))
+		uploading.Add(1)
+	}
+	return urls, nil
+}
+
+// send runs the request built by newRequest, retrying the failed ones once, and returns an error unless the response status is 2xx
+func send(client *http.Client, newRequest func() (*http.Request, error)) error {
+	return retry.Times(1).Wait(5 * time.Second).Try(func(attempt uint) error {
+		if attempt > 0 {
+			log.Warnf("Retrying the upload...")
+		}
+
+		request, err := newRequest()
+		if err != nil {
+			return err
+		}
+		response, err := client.Do(request)
+		if err != nil {
+			return network.DescribeError(err)
+		}
+		defer func() {
+			if err := response.Body.Close(); err != nil {
+				log.Warnf("Failed to close response body: %s", err)
+			}
+		}()
+
+		if response.StatusCode < 200 || response.StatusCode > 299 {
+			body, err := ioutil.ReadAll(response.Body)
+			if err != nil {
+				log.Debugf("Failed to read the response body: %s", err)
+			}
+			return fmt.Errorf("%s %s failed, status: %d, response: %s", request.Method, request.URL.Host, response.StatusCode, strings.TrimSpace(string(body)))
+		}
+		return nil
+	})
+}
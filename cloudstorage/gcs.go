@@ -0,0 +1,65 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/logging"
+	"github.com/bitrise-io/go-utils/command"
+)
+
+// gcsUploader uploads the objects with the media upload of the Cloud Storage JSON API
+type gcsUploader struct {
+	dest        Destination
+	endpoint    string
+	accessToken string
+	client      *http.Client
+}
+
+func newGCSUploader(dest Destination, config Config, client *http.Client) (*gcsUploader, error) {
+	accessToken, err := GoogleAccessToken(config)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := "https://storage.googleapis.com"
+	if config.Endpoint != "" {
+		endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	}
+	return &gcsUploader{dest: dest, endpoint: endpoint, accessToken: accessToken, client: client}, nil
+}
+
+// URL returns the gs:// URL of the object
+func (uploader gcsUploader) URL(name string) string {
+	return "gs://" + uploader.dest.Bucket + "/" + uploader.dest.objectName(name)
+}
+
+// Upload ...
+func (uploader gcsUploader) Upload(name string, content []byte) error {
+	requestURL := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=media&name=%s", uploader.endpoint, url.PathEscape(uploader.dest.Bucket), url.QueryEscape(uploader.dest.objectName(name)))
+	return send(uploader.client, func() (*http.Request, error) {
+		request, err := http.NewRequest(http.MethodPost, requestURL, bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Content-Type", "application/octet-stream")
+		request.Header.Set("Authorization", "Bearer "+uploader.accessToken)
+		return request, nil
+	})
+}
+
+// GoogleAccessToken returns the OAuth2 access token of the config, or the token of the gcloud login if it is not set
+func GoogleAccessToken(config Config) (string, error) {
+	if config.GCSAccessToken != "" {
+		return config.GCSAccessToken, nil
+	}
+	out, err := command.New("gcloud", "auth", "print-access-token").RunAndReturnTrimmedOutput()
+	if err != nil {
+		return "", fmt.Errorf("an access token is required: set the %s env var, or log in with gcloud auth login, error: %s", GCSAccessTokenEnvKey, err)
+	}
+	logging.AddSecret(out)
+	return out, nil
+}
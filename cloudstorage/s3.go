@@ -0,0 +1,147 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3Uploader puts the objects with the AWS Signature Version 4 signed requests of the S3 REST API
+type s3Uploader struct {
+	dest   Destination
+	config Config
+	client *http.Client
+}
+
+func newS3Uploader(dest Destination, config Config, client *http.Client) (*s3Uploader, error) {
+	config, err := config.WithAWSDefaults("the S3 upload")
+	if err != nil {
+		return nil, err
+	}
+	return &s3Uploader{dest: dest, config: config, client: client}, nil
+}
+
+// WithAWSDefaults returns an error if the AWS credentials are missing, and sets the default region
+func (config Config) WithAWSDefaults(usage string) (Config, error) {
+	if config.AWSAccessKeyID == "" || config.AWSSecretAccessKey == "" {
+		return Config{}, fmt.Errorf("%s requires the %s and %s env vars", usage, AWSAccessKeyIDEnvKey, AWSSecretAccessKeyEnvKey)
+	}
+	if config.AWSRegion == "" {
+		config.AWSRegion = "us-east-1"
+	}
+	return config, nil
+}
+
+// URL returns the virtual-hosted style URL of the object, or the path style URL of the custom endpoint
+func (uploader s3Uploader) URL(name string) string {
+	key := s3URIEncode(uploader.dest.objectName(name), false)
+	if uploader.config.Endpoint != "" {
+		return strings.TrimSuffix(uploader.config.Endpoint, "/") + "/" + uploader.dest.Bucket + "/" + key
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", uploader.dest.Bucket, uploader.config.AWSRegion, key)
+}
+
+// Upload ...
+func (uploader s3Uploader) Upload(name string, content []byte) error {
+	return send(uploader.client, func() (*http.Request, error) {
+		request, err := http.NewRequest(http.MethodPut, uploader.URL(name), bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Content-Type", "application/octet-stream")
+		if uploader.config.AWSSessionToken != "" {
+			request.Header.Set("X-Amz-Security-Token", uploader.config.AWSSessionToken)
+		}
+		SignAWSV4(request, content, uploader.config, "s3", time.Now())
+		return request, nil
+	})
+}
+
+// SignAWSV4 sets the Authorization header of the request to the service (e.g. s3) with the AWS Signature Version 4 of its headers and the payload
+func SignAWSV4(request *http.Request, payload []byte, config Config, service string, now time.Time) {
+	now = now.UTC()
+	payloadHash := sha256.Sum256(payload)
+	request.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	request.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	headers := map[string]string{"host": request.URL.Host}
+	for name, values := range request.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	var names []string
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalPath := s3URIEncode(request.URL.Path, false)
+	if canonicalPath == "" {
+		canonicalPath = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		canonicalPath,
+		canonicalQuery(request),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	date := now.Format("20060102")
+	scope := date + "/" + config.AWSRegion + "/" + service + "/aws4_request"
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + scope + "\n" + hex.EncodeToString(canonicalRequestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+config.AWSSecretAccessKey), date)
+	for _, part := range []string{config.AWSRegion, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", config.AWSAccessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery returns the sorted, encoded query parameters of the request
+func canonicalQuery(request *http.Request) string {
+	var params []string
+	for name, values := range request.URL.Query() {
+		for _, value := range values {
+			params = append(params, s3URIEncode(name, true)+"="+s3URIEncode(value, true))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// s3URIEncode encodes everything but the unreserved characters (and the slashes of a path, unless encodeSlash is set)
+func s3URIEncode(s string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			encoded.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			encoded.WriteByte(b)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return encoded.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
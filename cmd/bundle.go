@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bitrise-io/codesigndoc/bundle"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/logging"
+	"github.com/bitrise-io/codesigndoc/prompt"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// bundlePassphraseEnvKey can be used to provide the bundle passphrase non-interactively
+const bundlePassphraseEnvKey = "CODESIGNDOC_BUNDLE_PASSPHRASE"
+
+// bundleCmd represents the bundle command
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Package the exported files into encrypted chunks for restricted channels",
+	Long: `Package the exported files into encrypted chunks for restricted channels
+
+For channels with size and file type restrictions (e.g. email, DLP filters) the export directory
+can be split into passphrase encrypted, plain text chunks plus a reassembly manifest.
+On the other side the chunks are verified and reconstructed with the join command.`,
+}
+
+var bundleSplitCmd = &cobra.Command{
+	Use:   "split",
+	Short: "Split the export directory into encrypted chunks",
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          bundleSplit,
+}
+
+var bundleJoinCmd = &cobra.Command{
+	Use:   "join <manifest.json>",
+	Short: "Verify, reassemble and decrypt the chunks of a bundle",
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          bundleJoin,
+}
+
+var bundleDecryptCmd = &cobra.Command{
+	Use:   "decrypt <file>",
+	Short: "Decrypt and extract the file written by scan --encrypt-output",
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          bundleDecrypt,
+}
+
+var (
+	paramBundleDir         string
+	paramBundleOutputDir   string
+	paramBundleChunkSizeKB int
+	paramBundleNamePrefix  string
+)
+
+func init() {
+	RootCmd.AddCommand(bundleCmd)
+	bundleCmd.AddCommand(bundleSplitCmd, bundleJoinCmd, bundleDecryptCmd)
+	features.Register(features.Feature{Name: "tool/bundle", Kind: features.Tool, Description: "Split the exported files into encrypted chunks and join them"})
+
+	bundleSplitCmd.Flags().StringVar(&paramBundleDir, "dir", "./codesigndoc_exports", "Directory to split")
+	bundleSplitCmd.Flags().StringVar(&paramBundleOutputDir, "output", "./codesigndoc_bundle", "Directory of the chunks and the manifest")
+	bundleSplitCmd.Flags().IntVar(&paramBundleChunkSizeKB, "chunk-size", 1024, "Maximum chunk size in KB")
+	bundleSplitCmd.Flags().StringVar(&paramBundleNamePrefix, "name-prefix", "notes", "File name prefix of the chunks")
+
+	bundleJoinCmd.Flags().StringVar(&paramBundleOutputDir, "output", "./codesigndoc_exports", "Directory to extract the files into")
+	bundleDecryptCmd.Flags().StringVar(&paramBundleOutputDir, "output", "./codesigndoc_exports", "Directory to extract the files into")
+}
+
+// askBundlePassphrase reads the passphrase from the env or asks for it, confirm asks for it twice
+func askBundlePassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv(bundlePassphraseEnvKey); passphrase != "" {
+		logging.AddSecret(passphrase)
+		return passphrase, nil
+	}
+
+	read := func(message string) (string, error) {
+		if err := prompt.Check(message); err != nil {
+			return "", fmt.Errorf("%s, set it with the %s env var", err, bundlePassphraseEnvKey)
+		}
+		fmt.Print(message)
+		passphrase, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase, error: %s", err)
+		}
+		return string(passphrase), nil
+	}
+
+	passphrase, err := read("Bundle passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("empty passphrase")
+	}
+	if confirm {
+		again, err := read("Bundle passphrase again: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", errors.New("the passphrases do not match")
+		}
+	}
+	logging.AddSecret(passphrase)
+	return passphrase, nil
+}
+
+func splitDir(dir, outDir string, chunkSizeKB int, namePrefix string) error {
+	passphrase, err := askBundlePassphrase(true)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := bundle.Split(dir, passphrase, chunkSizeKB*1024, outDir, namePrefix)
+	if err != nil {
+		return err
+	}
+
+	log.Successf("Bundle created: %d chunk(s) and the %s in %s", len(manifest.Chunks), bundle.ManifestFileName, outDir)
+	log.Printf("Send the chunks and the manifest, and share the passphrase on a different channel.")
+	log.Printf("Reconstruct the files with: codesigndoc bundle join %s", filepath.Join(filepath.Base(outDir), bundle.ManifestFileName))
+	return nil
+}
+
+func bundleSplit(_ *cobra.Command, _ []string) error {
+	if err := features.CheckEnabled("tool/bundle"); err != nil {
+		return err
+	}
+	return splitDir(paramBundleDir, paramBundleOutputDir, paramBundleChunkSizeKB, paramBundleNamePrefix)
+}
+
+func bundleJoin(_ *cobra.Command, args []string) error {
+	if err := features.CheckEnabled("tool/bundle"); err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errors.New("the manifest path is required")
+	}
+
+	passphrase, err := askBundlePassphrase(false)
+	if err != nil {
+		return err
+	}
+
+	files, err := bundle.Join(args[0], passphrase, paramBundleOutputDir)
+	if err != nil {
+		return err
+	}
+
+	log.Successf("Bundle verified and extracted to %s:", paramBundleOutputDir)
+	for _, file := range files {
+		log.Printf("- %s", file)
+	}
+	return nil
+}
+
+func bundleDecrypt(_ *cobra.Command, args []string) error {
+	if err := features.CheckEnabled("tool/bundle"); err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errors.New("the encrypted file path is required")
+	}
+
+	passphrase, err := askBundlePassphrase(false)
+	if err != nil {
+		return err
+	}
+
+	files, err := bundle.Decrypt(args[0], passphrase, paramBundleOutputDir)
+	if err != nil {
+		return err
+	}
+
+	log.Successf("Decrypted and extracted to %s:", paramBundleOutputDir)
+	for _, file := range files {
+		log.Printf("- %s", file)
+	}
+	return nil
+}
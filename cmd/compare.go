@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/report"
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/spf13/cobra"
+)
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+	Use:   "compare <baseline.json> [<current.json>]",
+	Short: "Compare two scan results",
+	Long: `Compare two scan results
+
+Lists the identities and profiles added, removed or renewed between two results of
+"codesigndoc scan --format json", e.g. to audit what changed after a certificate rotation.
+Without the second file the baseline is compared against the identities and profiles
+currently installed for the teams of the baseline.`,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          compareScanResults,
+}
+
+var paramCompareFormat string
+
+func init() {
+	RootCmd.AddCommand(compareCmd)
+	features.Register(features.Feature{Name: "tool/compare", Kind: features.Tool, Description: "Compare a scan result against a previous one"})
+
+	compareCmd.Flags().StringVar(&paramCompareFormat, "format", "text", `Format of the differences. Valid values: "text", "json"`)
+}
+
+func compareScanResults(_ *cobra.Command, args []string) error {
+	if err := features.CheckEnabled("tool/compare"); err != nil {
+		return err
+	}
+	if len(args) != 1 && len(args) != 2 {
+		return fmt.Errorf("a baseline scan result, and optionally a current one is required")
+	}
+	if paramCompareFormat != "text" && paramCompareFormat != "json" {
+		return fmt.Errorf("invalid value for --format flag (%s). Valid values: 'text', 'json'", paramCompareFormat)
+	}
+
+	baseline, err := report.ReadScanResult(args[0])
+	if err != nil {
+		return err
+	}
+
+	currentName := "the installed files"
+	var current report.ScanResult
+	if len(args) == 2 {
+		currentName = args[1]
+		if current, err = report.ReadScanResult(args[1]); err != nil {
+			return err
+		}
+	} else {
+		if paramCompareFormat == "text" {
+			log.Infof("Collecting the installed identities and profiles of the teams: %v", baseline.TeamIDs())
+		}
+		if current, err = report.CollectScanResult(baseline.TeamIDs()); err != nil {
+			return fmt.Errorf("failed to collect the installed identities and profiles, error: %s", err)
+		}
+	}
+
+	diff := report.DiffScanResults(baseline, current)
+	if paramCompareFormat == "json" {
+		return report.WriteScanDiff(diff, "")
+	}
+
+	if diff.Empty() {
+		log.Successf("No identity or profile changed between %s and %s.", args[0], currentName)
+		return nil
+	}
+
+	if len(diff.Identities) > 0 {
+		fmt.Println()
+		log.Infof("Identities")
+		for _, change := range diff.Identities {
+			fmt.Printf("%s %s\n", changeMark(change.Kind), change.Message())
+		}
+	}
+	if len(diff.Profiles) > 0 {
+		fmt.Println()
+		log.Infof("Profiles")
+		for _, change := range diff.Profiles {
+			fmt.Printf("%s %s\n", changeMark(change.Kind), change.Message())
+		}
+	}
+	fmt.Println()
+	return nil
+}
+
+func changeMark(kind report.ChangeKind) string {
+	switch kind {
+	case report.ChangeAdded:
+		return colorstring.Green("+")
+	case report.ChangeRemoved:
+		return colorstring.Red("-")
+	}
+	return colorstring.Yellow("~")
+}
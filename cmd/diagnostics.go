@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/diagnostics"
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/bitrise-io/codesigndoc/utility"
+	"github.com/bitrise-io/codesigndoc/version"
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	xcodeutility "github.com/bitrise-io/go-xcode/utility"
+)
+
+// defaultDiagnosticsPath is the archive written by --collect-diagnostics without a value
+const defaultDiagnosticsPath = "codesigndoc_diagnostics.tar.gz"
+
+var paramDiagnosticsPath string
+
+// diagnosticsLog captures the redacted log of the run, nil if no diagnostics are collected
+var diagnosticsLog *bytes.Buffer
+
+// startDiagnostics starts capturing the log for --collect-diagnostics, the capture is set up by configureLogging
+func startDiagnostics() {
+	if paramDiagnosticsPath == "" {
+		return
+	}
+	diagnosticsLog = &bytes.Buffer{}
+}
+
+// writeDiagnostics writes the diagnostics archive of the run, runErr is the error the command failed with
+func writeDiagnostics(runErr error) {
+	if diagnosticsLog == nil {
+		return
+	}
+
+	info := diagnostics.Info{
+		GeneratedAt: time.Now(),
+		ToolVersion: version.VERSION,
+		GoVersion:   runtime.Version(),
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		Command:     diagnostics.CommandFlags(os.Args[1:]),
+		Keychains:   keychainStats(),
+	}
+	if runErr != nil {
+		info.Error = runErr.Error()
+	}
+	if out, err := command.New("sw_vers", "-productVersion").RunAndReturnTrimmedCombinedOutput(); err == nil {
+		info.MacOSVersion = out
+	}
+	if xcodeVersion, err := xcodeutility.GetXcodeVersion(); err == nil {
+		info.XcodeVersion = xcodeVersion.Version + " (" + xcodeVersion.BuildVersion + ")"
+	}
+
+	logText := diagnosticsLog.String()
+	info.OSStatusCodes = diagnostics.OSStatusCodes(info.Error, logText)
+	if err := diagnostics.Write(paramDiagnosticsPath, info, logText, pathutil.UserHomeDir()); err != nil {
+		log.Errorf("Failed to write the diagnostics archive: %s", err)
+		return
+	}
+	fmt.Println()
+	log.Infof("Diagnostics archive written to %s, attach it to the bug report.", paramDiagnosticsPath)
+	log.Printf("It contains no certificate, key, password or fingerprint, the home directory is replaced with ~. Check the content with: tar -xzOf %s", paramDiagnosticsPath)
+}
+
+// keychainStats counts the keychains, identities and profiles of the machine, a count is 0 if it can not be read
+func keychainStats() diagnostics.KeychainStats {
+	var stats diagnostics.KeychainStats
+	if searchList, err := osxkeychain.SearchList(); err != nil {
+		log.Debugf("Failed to read the keychain search list: %s", err)
+	} else {
+		stats.SearchList = len(searchList)
+	}
+	if legacy, err := osxkeychain.FindLegacyKeychains(); err != nil {
+		log.Debugf("Failed to look for legacy keychains: %s", err)
+	} else {
+		stats.Legacy = len(legacy)
+	}
+	if names, err := certificateutil.InstalledCodesigningCertificateNames(); err != nil {
+		log.Debugf("Failed to list the installed identities: %s", err)
+	} else {
+		stats.Identities = len(names)
+	}
+	for _, profileType := range []profileutil.ProfileType{profileutil.ProfileTypeIos, profileutil.ProfileTypeMacOs} {
+		if profiles, err := utility.InstalledProfileInfos(profileType); err != nil {
+			log.Debugf("Failed to list the installed %s profiles: %s", profileType, err)
+		} else {
+			stats.Profiles += len(profiles)
+		}
+	}
+	return stats
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/spf13/cobra"
+)
+
+// elevatedExportCmd exports the identities as root, it is run by the scan with sudo
+// if the system keychain denies the export, see codesign.ElevatedExportRequest
+var elevatedExportCmd = &cobra.Command{
+	Use:    codesign.ElevatedExportCommand,
+	Short:  "Export identities as root, used internally by the scan",
+	Hidden: true,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		// the standard output is the exported files, everything else goes to the standard error
+		out := os.Stdout
+		os.Stdout = os.Stderr
+		log.SetOutWriter(os.Stderr)
+		return codesign.RunElevatedExport(os.Stdin, out)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(elevatedExportCmd)
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/exitcode"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/utility"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/plistutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/spf13/cobra"
+)
+
+// checkEntitlementsCmd represents the check-entitlements command
+var checkEntitlementsCmd = &cobra.Command{
+	Use:   "check-entitlements <.entitlements file or .app>",
+	Short: "Compare the entitlements of a target against its provisioning profiles",
+	Long: `Compare the entitlements of a target against its provisioning profiles
+
+The entitlements are read from the target's .entitlements file, or from the signature of a built .app or .appex bundle.
+They are compared against the given profiles, or against the installed profiles of the target's bundle ID,
+and the capabilities not granted by the profiles (e.g. a missing aps-environment, an associated domain or an app group)
+are reported, as these fail the signing or the install of the app later.`,
+
+	Args:          cobra.ExactArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          checkEntitlements,
+}
+
+var (
+	paramEntitlementsBundleID string
+	paramEntitlementsProfiles []string
+)
+
+func init() {
+	RootCmd.AddCommand(checkEntitlementsCmd)
+	features.Register(features.Feature{Name: "tool/check-entitlements", Kind: features.Tool, Description: "Compare the entitlements of a target against its provisioning profiles"})
+
+	checkEntitlementsCmd.Flags().StringVar(&paramEntitlementsBundleID, "bundle-id", "", "Bundle ID of the target, read from the Info.plist of an .app or .appex if empty")
+	checkEntitlementsCmd.Flags().StringSliceVar(&paramEntitlementsProfiles, "profile", nil, "Provisioning profile file to compare against, can be specified multiple times. The installed profiles of the bundle ID are used if empty")
+}
+
+func checkEntitlements(_ *cobra.Command, args []string) error {
+	if err := features.CheckEnabled("tool/check-entitlements"); err != nil {
+		return err
+	}
+
+	target := args[0]
+	entitlements, err := codesign.ReadTargetEntitlements(target)
+	if err != nil {
+		return err
+	}
+
+	profiles, err := entitlementsCandidateProfiles(target)
+	if err != nil {
+		return err
+	}
+	if len(profiles) == 0 {
+		return exitcode.Wrap(exitcode.ProfileMissing, fmt.Errorf("no provisioning profile installed for bundle ID: %s", paramEntitlementsBundleID))
+	}
+
+	fmt.Println()
+	log.Infof("Entitlements of %s (%d):", target, len(entitlements))
+	for key, value := range entitlements {
+		log.Printf("- %s: %v", key, value)
+	}
+
+	granting := 0
+	for _, profile := range profiles {
+		fmt.Println()
+		mismatches := codesign.CompareEntitlements(entitlements, profile.Entitlements, profile.Type)
+		if len(mismatches) == 0 {
+			granting++
+			log.Donef("%s (%s): grants every entitlement", profile.Name, profile.UUID)
+			continue
+		}
+		log.Warnf("%s (%s):", profile.Name, profile.UUID)
+		for _, mismatch := range mismatches {
+			log.Warnf("- %s", mismatch)
+		}
+	}
+
+	fmt.Println()
+	if granting == 0 {
+		return errors.New("none of the profiles grant the entitlements of the target, enable the missing capabilities on the App ID and regenerate the profile")
+	}
+	log.Donef("%d of %d profile(s) grant the entitlements of the target", granting, len(profiles))
+	return nil
+}
+
+// entitlementsCandidateProfiles returns the --profile files, or the installed profiles of the target's bundle ID
+func entitlementsCandidateProfiles(target string) ([]profileutil.ProvisioningProfileInfoModel, error) {
+	var profiles []profileutil.ProvisioningProfileInfoModel
+	if len(paramEntitlementsProfiles) > 0 {
+		for _, pth := range paramEntitlementsProfiles {
+			profile, err := profileutil.NewProvisioningProfileInfoFromFile(pth)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read provisioning profile (%s), error: %s", pth, err)
+			}
+			profiles = append(profiles, profile)
+		}
+		return profiles, nil
+	}
+
+	if paramEntitlementsBundleID == "" {
+		bundleID, err := bundleIDOfBundle(target)
+		if err != nil {
+			return nil, err
+		}
+		paramEntitlementsBundleID = bundleID
+	}
+
+	for _, profileType := range []profileutil.ProfileType{profileutil.ProfileTypeIos, profileutil.ProfileTypeMacOs} {
+		installed, err := utility.InstalledProfileInfos(profileType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list installed provisioning profiles, error: %s", err)
+		}
+		for _, match := range codesign.RankProfiles(paramEntitlementsBundleID, installed) {
+			profiles = append(profiles, match.Profile)
+		}
+	}
+	return profiles, nil
+}
+
+// bundleIDOfBundle reads the bundle ID of an iOS or a macOS .app or .appex bundle
+func bundleIDOfBundle(pth string) (string, error) {
+	if ext := filepath.Ext(pth); ext != ".app" && ext != ".appex" {
+		return "", errors.New("the bundle ID of an .entitlements file is unknown, set it with --bundle-id or give the profiles with --profile")
+	}
+	for _, infoPlistPth := range []string{filepath.Join(pth, "Info.plist"), filepath.Join(pth, "Contents", "Info.plist")} {
+		infoPlist, err := plistutil.NewPlistDataFromFile(infoPlistPth)
+		if err != nil {
+			continue
+		}
+		if bundleID, ok := infoPlist.GetString("CFBundleIdentifier"); ok {
+			return bundleID, nil
+		}
+	}
+	return "", fmt.Errorf("failed to read the bundle ID of %s, set it with --bundle-id", pth)
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/external"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/state"
+	"github.com/bitrise-io/codesigndoc/utility"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/spf13/cobra"
+)
+
+// externalCmd represents the external command
+var externalCmd = &cobra.Command{
+	Use:   "external",
+	Short: "Run a custom project scanner executable",
+	Long: `Run a custom project scanner executable
+
+The scanners are registered in the config.yml of the codesigndoc state directory (~/.codesigndoc by default):
+
+scanners:
+- name: bazel
+  path: /usr/local/bin/bazel-codesign-scanner
+  args: ["--config", "release"]
+
+The scanner receives a JSON request on its standard input:
+{"version": 1, "working_dir": "...", "args": [...], "certificates_only": false}
+
+and has to write the required identities and profiles to its standard output:
+{
+  "version": 1,
+  "identities": [{"common_name": "...", "sha1_fingerprint": "...", "serial": "..."}],
+  "profiles": [{"uuid": "...", "name": "...", "bundle_id": "...", "export_method": "app-store"}],
+  "warnings": ["..."]
+}
+
+Every field of an identity or profile is optional, but at least one is required. The arguments after -- are passed to the scanner.`,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          scanExternal,
+}
+
+var (
+	paramExternalScannerName string
+	paramExternalPlatform    string
+)
+
+func init() {
+	scanCmd.AddCommand(externalCmd)
+	features.Register(features.Feature{Name: scannerFeaturePrefix + "external", Kind: features.Scanner, Description: "Run a custom project scanner executable registered in the config"})
+
+	externalCmd.Flags().StringVar(&paramExternalScannerName, "name", "", "Name of the registered scanner to run")
+	externalCmd.Flags().StringVar(&paramExternalPlatform, "platform", "ios", `Platform of the project. Valid values: "ios", "macos".`)
+}
+
+func scanExternal(_ *cobra.Command, args []string) error {
+	absExportOutputDirPath, err := absOutputDir()
+	if err != nil {
+		return err
+	}
+
+	config, err := state.ReadConfig()
+	if err != nil {
+		return err
+	}
+
+	scannerConfig, ok := config.Scanner(paramExternalScannerName)
+	if !ok {
+		var names []string
+		for _, scanner := range config.Scanners {
+			names = append(names, scanner.Name)
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("no external scanner registered, see 'codesigndoc scan external --help'")
+		}
+		return fmt.Errorf("external scanner (%s) not found, registered scanners: %s", paramExternalScannerName, strings.Join(names, ", "))
+	}
+
+	certType, profileType := codesign.IOSCertificate, profileutil.ProfileTypeIos
+	switch paramExternalPlatform {
+	case "ios":
+	case "macos":
+		certType, profileType = codesign.MacOSCertificate, profileutil.ProfileTypeMacOs
+	default:
+		return fmt.Errorf("invalid platform (%s), valid values: 'ios', 'macos'", paramExternalPlatform)
+	}
+
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	log.Infof("Running scanner: %s", scannerConfig.Name)
+	scanner := external.Scanner{Name: scannerConfig.Name, Path: scannerConfig.Path, Args: scannerConfig.Args}
+	result, err := scanner.Run(external.Request{
+		WorkingDir:       workingDir,
+		Args:             args,
+		CertificatesOnly: certificatesOnly,
+	})
+	if err != nil {
+		return err
+	}
+	for _, warning := range result.Warnings {
+		log.Warnf("%s", warning)
+	}
+
+	installedCertificates, err := codesign.InstalledCertificates(certType)
+	if err != nil {
+		return fmt.Errorf("failed to list installed code signing identities, error: %s", err)
+	}
+	if certType == codesign.MacOSCertificate {
+		installerCertificates, err := certificateutil.InstalledInstallerCertificateInfos()
+		if err != nil {
+			return fmt.Errorf("failed to list installed code signing identities, error: %s", err)
+		}
+		if installerCertificates, err = codesign.FilterNeverExportCertificates(installerCertificates); err != nil {
+			return err
+		}
+		installedCertificates = append(installedCertificates, installerCertificates...)
+	}
+	installedProfiles, err := utility.InstalledProfileInfos(profileType)
+	if err != nil {
+		return fmt.Errorf("failed to list installed provisioning profiles, error: %s", err)
+	}
+
+	certificatesToExport, profilesToExport, err := external.Resolve(result, installedCertificates, installedProfiles)
+	if err != nil {
+		return err
+	}
+	if certificatesOnly {
+		profilesToExport = nil
+	}
+
+	if dryRun {
+		return printDryRun(certificatesToExport, profilesToExport, absExportOutputDirPath)
+	}
+
+	exportResult, err := codesign.ExportUploadAndWriteCodesignFiles(scanCtx, certificatesToExport,
+		profilesToExport,
+		identityExportConfig,
+		codesign.WriteFilesConfig{
+			WriteFiles:       writeFiles,
+			AbsOutputDirPath: absExportOutputDirPath,
+			Match:            matchRepoConfig,
+			Naming:           namingConfig,
+			CertificateFiles: exportCerts,
+			PerTeam:          perTeam,
+		},
+		codesign.UploadConfig{
+			PersonalAccessToken: personalAccessToken,
+			AppSlug:             appSlug,
+			RollbackOnFailure:   uploadRollback,
+			SecretStore:         secretStore,
+		})
+	if err != nil {
+		return err
+	}
+
+	return printFinished(exportResult, absExportOutputDirPath)
+}
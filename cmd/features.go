@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/version"
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/spf13/cobra"
+)
+
+// featuresCmd represents the features command
+var featuresCmd = &cobra.Command{
+	Use:   "features",
+	Short: "Lists the capabilities compiled into this binary",
+	Long: `Lists the capabilities compiled into this binary
+
+Every scanner, destination and tool is compiled into the single codesigndoc binary,
+features can be disabled at runtime with the ` + features.DisabledFeaturesEnvKey + ` env var
+(comma separated list of feature names).`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Printf("codesigndoc %s (%s/%s, %s)\n", version.VERSION, runtime.GOOS, runtime.GOARCH, runtime.Version())
+
+		var lastKind features.Kind
+		for _, feature := range features.List() {
+			if feature.Kind != lastKind {
+				fmt.Println()
+				fmt.Printf("%ss:\n", feature.Kind)
+				lastKind = feature.Kind
+			}
+
+			state := colorstring.Green("enabled")
+			if !features.IsEnabled(feature.Name) {
+				state = colorstring.Yellow("disabled")
+			}
+			fmt.Printf("- %s [%s]: %s\n", feature.Name, state, feature.Description)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(featuresCmd)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/spf13/cobra"
+)
+
+// identitiesCmd represents the identities command
+var identitiesCmd = &cobra.Command{
+	Use:   "identities",
+	Short: "List the code signing identities by the keychain they are stored in",
+	Long: `List the code signing identities by the keychain they are stored in
+
+Every keychain of the search list is searched separately, so an identity installed into multiple keychains
+is listed under each of them. The expired identities are listed too, with a warning.`,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          identities,
+}
+
+func init() {
+	RootCmd.AddCommand(identitiesCmd)
+	features.Register(features.Feature{Name: "tool/identities", Kind: features.Tool, Description: "List the identities by keychain"})
+}
+
+func identities(_ *cobra.Command, _ []string) error {
+	if err := features.CheckEnabled("tool/identities"); err != nil {
+		return err
+	}
+	if err := checkKeychainPlatform(); err != nil {
+		return err
+	}
+
+	searchList, err := osxkeychain.CopySearchList()
+	if err != nil {
+		return fmt.Errorf("failed to read the keychain search list, error: %s", err)
+	}
+	byKeychain, err := osxkeychain.FindIdentitiesByKeychain(osxkeychain.IdentityQuery{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, found := range byKeychain {
+			osxkeychain.ReleaseIdentityWithRefList(found)
+		}
+	}()
+
+	total := 0
+	for _, pth := range searchList {
+		found := byKeychain[pth]
+		fmt.Println()
+		fmt.Printf("%s %s\n", colorstring.Blue("keychain:"), pth)
+		if len(found) == 0 {
+			log.Printf("no identity")
+			continue
+		}
+		for _, identity := range found {
+			line := fmt.Sprintf("%s [%s]", identity.Label, identity.SHA1Fingerprint)
+			if identity.NotAfter.IsZero() {
+				log.Printf("%s", line)
+			} else if identity.NotAfter.Before(time.Now()) {
+				log.Warnf("%s, expired: %s", line, identity.NotAfter.Format("2006-01-02"))
+			} else {
+				log.Donef("%s, expires: %s", line, identity.NotAfter.Format("2006-01-02"))
+			}
+			total++
+		}
+	}
+
+	fmt.Println()
+	log.Infof("%d identities in %d keychains", total, len(searchList))
+	return nil
+}
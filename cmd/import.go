@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/logging"
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/spf13/cobra"
+)
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import <.p12 file>",
+	Short: "Import an exported .p12 into a keychain, e.g. on a CI machine",
+	Long: `Import an exported .p12 into a keychain, e.g. on a CI machine
+
+The identities are imported into the --keychain-path keychain (the login keychain by default), codesign is allowed to use their private keys.
+The .p12 password is read from the ` + passphraseEnvKey + ` env var or the standard input (--pass-stdin).
+With the keychain password (--keychain-password or the ` + keychainPasswordEnvKey + ` env var) the keychain is unlocked
+and the key partition list is set (as security set-key-partition-list does), so codesign can sign without a Keychain prompt.`,
+
+	Args:          cobra.ExactArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          importP12,
+}
+
+func init() {
+	RootCmd.AddCommand(importCmd)
+	features.Register(features.Feature{Name: "tool/import", Kind: features.Tool, Description: "Import an exported .p12 into a keychain"})
+
+	importCmd.Flags().BoolVar(&passphraseStdin, "pass-stdin", false, "Read the .p12 password from the first line of the standard input")
+	importCmd.Flags().StringVar(&keychainPath, "keychain-path", "", "Keychain file to import into, the login keychain if empty")
+	importCmd.Flags().StringVar(&keychainPassword, "keychain-password", "", "Password of the keychain, to unlock it and to set the key partition list")
+}
+
+func importP12(_ *cobra.Command, args []string) error {
+	if err := features.CheckEnabled("tool/import"); err != nil {
+		return err
+	}
+	if err := checkKeychainPlatform(); err != nil {
+		return err
+	}
+
+	exportConfig, err := readIdentityExportConfig()
+	if err != nil {
+		return err
+	}
+	passphrase := exportConfig.Passphrase
+	logging.AddSecret(passphrase)
+
+	pth := args[0]
+	content, certificates, err := readP12(pth, passphrase)
+	if err != nil {
+		return err
+	}
+
+	if err := unlockKeychain(); err != nil {
+		return err
+	}
+
+	name := keychainPath
+	if name == "" {
+		name = "login keychain"
+	}
+	fmt.Println()
+	log.Infof("Importing %s into the %s", pth, name)
+	for _, certificate := range certificates {
+		log.Printf("- %s", certificate)
+	}
+
+	count, err := osxkeychain.ImportPKCS12(content, passphrase, keychainPath, []string{osxkeychain.CodesignPath})
+	if errors.Is(err, osxkeychain.ErrDuplicateItem) {
+		log.Warnf("The identities are already in the %s", name)
+	} else if err != nil {
+		return fmt.Errorf("failed to import the .p12 (%s), error: %s", pth, err)
+	} else {
+		log.Donef("%d item(s) imported", count)
+	}
+
+	if password := readKeychainPassword(); password != "" {
+		if err := osxkeychain.SetKeyPartitionList(keychainPath, password); err != nil {
+			return err
+		}
+		log.Donef("Key partition list set: %s", osxkeychain.KeyPartitions)
+	} else {
+		log.Warnf("The key partition list is not set, codesign can show a Keychain prompt for the imported keys.")
+		log.Warnf("Set the keychain password with --keychain-password or the %s env var to avoid it.", keychainPasswordEnvKey)
+	}
+
+	fmt.Println()
+	log.Successf("Imported %s", pth)
+	return nil
+}
+
+// readP12 reads the .p12 file and its certificates, so a wrong password is reported before touching the keychain
+func readP12(pth, passphrase string) ([]byte, []certificateutil.CertificateInfoModel, error) {
+	content, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the .p12 (%s), error: %s", pth, err)
+	}
+	certificates, err := certificateutil.CertificatesFromPKCS12Content(content, passphrase)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the certificates of the .p12 (%s), check the password, error: %s", pth, err)
+	}
+	return content, certificates, nil
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/logging"
+	"github.com/bitrise-io/codesigndoc/report"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/spf13/cobra"
+)
+
+// inspectCmd represents the inspect command
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <.p12 file>",
+	Short: "Print and validate the content of an exported .p12 without importing it",
+	Long: `Print and validate the content of an exported .p12 without importing it
+
+The certificates of the .p12 are paired with their private keys, and for every identity
+the certificate chain, the team, the validity and the fingerprint are printed.
+The .p12 password is read from the ` + passphraseEnvKey + ` env var or the standard input (--pass-stdin).
+It fails if the .p12 has no identity, a private key without its certificate, or an expired identity.`,
+
+	Args:          cobra.ExactArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          inspectP12,
+}
+
+var paramInspectExpiryWarningDays int
+
+func init() {
+	RootCmd.AddCommand(inspectCmd)
+	features.Register(features.Feature{Name: "tool/inspect", Kind: features.Tool, Description: "Print and validate the content of an exported .p12"})
+
+	inspectCmd.Flags().BoolVar(&passphraseStdin, "pass-stdin", false, "Read the .p12 password from the first line of the standard input")
+	inspectCmd.Flags().IntVar(&paramInspectExpiryWarningDays, "expiry-warning-days", report.DefaultExpiryWarningDays, "Warn about the identities expiring within this many days")
+}
+
+func inspectP12(_ *cobra.Command, args []string) error {
+	if err := features.CheckEnabled("tool/inspect"); err != nil {
+		return err
+	}
+
+	exportConfig, err := readIdentityExportConfig()
+	if err != nil {
+		return err
+	}
+	logging.AddSecret(exportConfig.Passphrase)
+
+	pth := args[0]
+	content, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return fmt.Errorf("failed to read the .p12 (%s), error: %s", pth, err)
+	}
+	inspection, err := codesign.InspectP12(content, exportConfig.Passphrase, time.Now(), paramInspectExpiryWarningDays)
+	if err != nil {
+		return err
+	}
+
+	for _, identity := range inspection.Identities {
+		fmt.Println()
+		log.Infof("Identity: %s", identity.CommonName)
+		log.Printf("team: %s (%s)", identity.TeamName, identity.TeamID)
+		log.Printf("valid: %s - %s", identity.StartDate.Format("2006-01-02"), identity.EndDate.Format("2006-01-02"))
+		log.Printf("serial: %s", identity.Serial)
+		log.Printf("SHA-1 fingerprint: %s", identity.SHA1Fingerprint)
+		log.Printf("private key: matches the certificate")
+		if len(identity.Chain) > 0 {
+			log.Printf("chain: %s", strings.Join(identity.Chain, " -> "))
+		}
+		for _, warning := range identity.Warnings {
+			log.Warnf("- %s", warning)
+		}
+	}
+	if len(inspection.Certificates) > 0 {
+		fmt.Println()
+		log.Infof("Certificates without a private key:")
+		for _, certificate := range inspection.Certificates {
+			log.Printf("- %s [%s], expires: %s", certificate.CommonName, certificate.SHA1Fingerprint, certificate.EndDate.Format("2006-01-02"))
+		}
+	}
+
+	fmt.Println()
+	if len(inspection.Problems) > 0 {
+		for _, problem := range inspection.Problems {
+			log.Warnf("- %s", problem)
+		}
+		return fmt.Errorf("%d problem(s) found in %s", len(inspection.Problems), pth)
+	}
+	log.Successf("%s contains %d valid identities.", pth, len(inspection.Identities))
+	return nil
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/spf13/cobra"
+)
+
+// installProfilesCmd represents the install-profiles command
+var installProfilesCmd = &cobra.Command{
+	Use:   "install-profiles <.mobileprovision files or directories>",
+	Short: "Install provisioning profiles, e.g. the exported ones on a CI machine",
+	Long: `Install provisioning profiles, e.g. the exported ones on a CI machine
+
+The profiles are copied into ~/Library/MobileDevice/Provisioning Profiles named after their UUID, as Xcode does.
+The .mobileprovision and .provisionprofile files of the given directories (e.g. ./codesigndoc_exports) are installed too.
+The profiles already installed (also in ~/Library/Developer/Xcode/UserData/Provisioning Profiles) are skipped.`,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          installProfiles,
+}
+
+func init() {
+	RootCmd.AddCommand(installProfilesCmd)
+	features.Register(features.Feature{Name: "tool/install-profiles", Kind: features.Tool, Description: "Install provisioning profiles"})
+}
+
+func installProfiles(_ *cobra.Command, args []string) error {
+	if err := features.CheckEnabled("tool/install-profiles"); err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errors.New("the provisioning profile files or directories are required")
+	}
+
+	profiles, err := codesign.ReadProfileFiles(args)
+	if err != nil {
+		return err
+	}
+	if len(profiles) == 0 {
+		return fmt.Errorf("no provisioning profile found in: %v", args)
+	}
+
+	installed, skipped := 0, 0
+	for _, profile := range profiles {
+		fmt.Println()
+		log.Infof("%s (%s, UUID: %s)", profile.Info.Name, profile.Info.ExportType, profile.Info.UUID)
+		if err := profile.Info.CheckValidity(); err != nil {
+			log.Warnf("The profile is not valid: %s", err)
+		}
+
+		pth, err := codesign.InstalledProfilePath(profile)
+		if err != nil {
+			return fmt.Errorf("failed to check whether the profile (%s) is installed, error: %s", profile.Path, err)
+		}
+		if pth != "" {
+			log.Printf("already installed: %s", pth)
+			skipped++
+			continue
+		}
+
+		if pth, err = codesign.InstallProfile(profile); err != nil {
+			return err
+		}
+		log.Donef("installed: %s", pth)
+		installed++
+	}
+
+	fmt.Println()
+	log.Successf("%d profile(s) installed, %d already installed", installed, skipped)
+	return nil
+}
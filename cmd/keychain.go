@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/bitrise-io/codesigndoc/confirm"
+	"github.com/bitrise-io/codesigndoc/exitcode"
+	"github.com/bitrise-io/codesigndoc/logging"
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// checkKeychainPlatform fails the commands using the keychain on the platforms without it, with the Unsupported exit code
+func checkKeychainPlatform() error {
+	if err := osxkeychain.CheckPlatform(); err != nil {
+		return exitcode.Wrap(exitcode.Unsupported, err)
+	}
+	return nil
+}
+
+// checkLegacyKeychains looks for keychains which were not converted to the .keychain-db format after a macOS upgrade,
+// these cause hard to explain Security framework failures during the export, so the user is offered to repair them.
+func checkLegacyKeychains() error {
+	legacyKeychains, err := osxkeychain.FindLegacyKeychains()
+	if err != nil {
+		log.Warnf("Failed to check for legacy keychains: %s", err)
+		return nil
+	}
+	if len(legacyKeychains) == 0 {
+		return nil
+	}
+
+	fmt.Println()
+	log.Warnf("🚨  Legacy keychain file(s) found, which were not converted to the .keychain-db format after a macOS upgrade.")
+	log.Printf("These keychains can make the identity search or the export fail with unexpected errors.")
+
+	plan := confirm.NewPlan("Repair the legacy keychains (the legacy files will be kept with " + colorstring.Green(".codesigndoc-backup") + " extension)")
+	for _, keychain := range legacyKeychains {
+		plan.Add("convert", keychain.Path, keychain.Problem())
+	}
+	repair, err := plan.Confirm()
+	if err != nil {
+		// the legacy keychains do not block the non-interactive scans
+		log.Warnf("Continuing without repairing the legacy keychains: %s", err)
+		return nil
+	}
+	if !repair {
+		log.Warnf("Continuing without repairing the legacy keychains.")
+		return nil
+	}
+
+	for _, keychain := range legacyKeychains {
+		if err := osxkeychain.RepairLegacyKeychain(keychain); err != nil {
+			return fmt.Errorf("failed to repair keychain (%s), error: %s", keychain.Path, err)
+		}
+		log.Donef("Repaired: %s -> %s", keychain.Path, keychain.ConvertedPath)
+	}
+	return nil
+}
+
+// unlockKeychain unlocks the keychain with the --keychain-password flag or the env var, if it is locked,
+// so the Security framework does not need to show an unlock prompt.
+func unlockKeychain() error {
+	password := readKeychainPassword()
+	if password == "" {
+		if keychainPath != "" {
+			return fmt.Errorf("--keychain-path requires the keychain password, set --keychain-password or the %s env var", keychainPasswordEnvKey)
+		}
+		return nil
+	}
+
+	logging.AddSecret(password)
+
+	name := keychainPath
+	if name == "" {
+		name = "login keychain"
+	}
+	locked, err := osxkeychain.IsKeychainLocked(keychainPath)
+	if err != nil {
+		return fmt.Errorf("failed to check if the %s is locked, error: %s", name, err)
+	}
+	if !locked {
+		log.Debugf("The %s is already unlocked", name)
+		return nil
+	}
+
+	if err := osxkeychain.UnlockKeychain(keychainPath, password); errors.Is(err, osxkeychain.ErrAuthFailed) {
+		return fmt.Errorf("failed to unlock the %s: wrong password", name)
+	} else if err != nil {
+		return fmt.Errorf("failed to unlock the %s, error: %s", name, err)
+	}
+	log.Donef("Unlocked the %s", name)
+	return nil
+}
+
+// readKeychainPassword returns the keychain password of the --keychain-password flag or the env var
+func readKeychainPassword() string {
+	if keychainPassword != "" {
+		return keychainPassword
+	}
+	return os.Getenv(keychainPasswordEnvKey)
+}
+
+// includeSystemKeychain adds the system keychain to the search list for the time of the scan,
+// so its identities are listed and exported too
+func includeSystemKeychain() error {
+	restore, err := osxkeychain.AddToSearchList(osxkeychain.SystemKeychainPath)
+	if err != nil {
+		return fmt.Errorf("failed to add the system keychain to the search list, error: %s", err)
+	}
+	restoreSearchList = func() {
+		if err := restore(); err != nil {
+			log.Errorf("Failed to restore the keychain search list: %s", err)
+		}
+	}
+	log.Debugf("Searching the system keychain (%s) too", osxkeychain.SystemKeychainPath)
+	return nil
+}
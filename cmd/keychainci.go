@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/confirm"
+	"github.com/bitrise-io/codesigndoc/exitcode"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/logging"
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/bitrise-io/codesigndoc/state"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/spf13/cobra"
+)
+
+// keychainCmd represents the keychain command
+var keychainCmd = &cobra.Command{
+	Use:   "keychain",
+	Short: "Manage the keychains of CI machines",
+}
+
+var keychainCreateCICmd = &cobra.Command{
+	Use:   "create-ci [.p12 and provisioning profile files or directories]",
+	Short: "Create a keychain for the CI build and import the exported files into it",
+	Long: `Create a keychain for the CI build and import the exported files into it
+
+The keychain is created in ~/Library/Keychains named after --name, a keychain left over by an earlier run is replaced.
+An existing keychain which was not created by create-ci is never replaced, and the names of the system keychains (e.g. login) are refused.
+It is unlocked, never locks by default (see --lock-timeout) and is added to the keychain search list.
+The .p12 files are imported into it (the password is read from the ` + passphraseEnvKey + ` env var or with --pass-stdin)
+with the key partition list set for codesign, the provisioning profiles are installed.
+Remove everything with: codesigndoc keychain delete-ci`,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          createCIKeychain,
+}
+
+var keychainDeleteCICmd = &cobra.Command{
+	Use:   "delete-ci",
+	Short: "Delete the keychain created by create-ci and the profiles it installed",
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          deleteCIKeychain,
+}
+
+var (
+	paramCIKeychainName string
+	paramLockTimeout    time.Duration
+	paramLockOnSleep    bool
+)
+
+func init() {
+	RootCmd.AddCommand(keychainCmd)
+	keychainCmd.AddCommand(keychainCreateCICmd, keychainDeleteCICmd)
+	features.Register(features.Feature{Name: "tool/ci-keychain", Kind: features.Tool, Description: "Create and delete a keychain for the CI build"})
+
+	for _, cmd := range []*cobra.Command{keychainCreateCICmd, keychainDeleteCICmd} {
+		cmd.Flags().StringVar(&paramCIKeychainName, "name", "codesigndoc-ci", "Name of the keychain")
+	}
+	keychainCreateCICmd.Flags().StringVar(&keychainPassword, "keychain-password", "", "Password of the new keychain, a random one if empty. The password can also be set with the "+keychainPasswordEnvKey+" env var")
+	keychainCreateCICmd.Flags().DurationVar(&paramLockTimeout, "lock-timeout", 0, "Lock the keychain after the inactivity time (e.g. 2h), 0 never locks it")
+	keychainCreateCICmd.Flags().BoolVar(&paramLockOnSleep, "lock-on-sleep", false, "Lock the keychain when the machine sleeps")
+	keychainCreateCICmd.Flags().BoolVar(&passphraseStdin, "pass-stdin", false, "Read the .p12 password from the first line of the standard input")
+}
+
+func createCIKeychain(_ *cobra.Command, args []string) error {
+	if err := features.CheckEnabled("tool/ci-keychain"); err != nil {
+		return err
+	}
+	if err := checkKeychainPlatform(); err != nil {
+		return err
+	}
+	if err := validateCIKeychainName(paramCIKeychainName); err != nil {
+		return err
+	}
+
+	p12Paths, profilePaths, err := codesign.SplitArtifactPaths(args)
+	if err != nil {
+		return err
+	}
+	var profiles []codesign.BundleProfile
+	if len(profilePaths) > 0 {
+		if profiles, err = codesign.ReadProfileFiles(profilePaths); err != nil {
+			return err
+		}
+	}
+	exportConfig, err := readIdentityExportConfig()
+	if err != nil {
+		return err
+	}
+	logging.AddSecret(exportConfig.Passphrase)
+	p12Contents := map[string][]byte{}
+	for _, pth := range p12Paths {
+		if p12Contents[pth], _, err = readP12(pth, exportConfig.Passphrase); err != nil {
+			return err
+		}
+	}
+
+	password := readKeychainPassword()
+	if password == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		password = fmt.Sprintf("%x", b)
+		log.Printf("Using a random keychain password, the keychain can not be unlocked once it is locked")
+	}
+	logging.AddSecret(password)
+
+	list, err := state.ReadCIKeychainList()
+	if err != nil {
+		return err
+	}
+	keychain := state.CIKeychain{Name: paramCIKeychainName, Path: osxkeychain.UserKeychainPath(paramCIKeychainName), CreatedAt: time.Now()}
+	if recorded, ok := list.Find(keychain.Name); ok {
+		if err := removeCIKeychain(&list, recorded.Name, recorded.Path, "left over by an earlier run"); err != nil {
+			return err
+		}
+	}
+	if exists, err := pathutil.IsPathExists(keychain.Path); err != nil {
+		return err
+	} else if exists {
+		return fmt.Errorf("the keychain (%s) already exists and was not created by keychain create-ci, choose another --name", keychain.Path)
+	}
+
+	fmt.Println()
+	log.Infof("Creating the keychain: %s", keychain.Path)
+	if err := osxkeychain.CreateKeychain(keychain.Path, password, osxkeychain.KeychainSettings{LockTimeout: paramLockTimeout, LockOnSleep: paramLockOnSleep}); err != nil {
+		return fmt.Errorf("failed to create the keychain, error: %s", err)
+	}
+	// the keychain is recorded right away, so a failed run can be torn down too
+	list.Add(keychain)
+	if err := state.WriteCIKeychainList(list); err != nil {
+		return err
+	}
+	if _, err := osxkeychain.AddToSearchList(keychain.Path); err != nil {
+		return fmt.Errorf("failed to add the keychain to the search list, error: %s", err)
+	}
+	log.Donef("created, unlocked and added to the search list")
+
+	for _, pth := range p12Paths {
+		count, err := osxkeychain.ImportPKCS12(p12Contents[pth], exportConfig.Passphrase, keychain.Path, []string{osxkeychain.CodesignPath})
+		if err != nil {
+			return fmt.Errorf("failed to import the .p12 (%s), error: %s", pth, err)
+		}
+		log.Donef("%s: %d item(s) imported", pth, count)
+	}
+	if len(p12Paths) > 0 {
+		if err := osxkeychain.SetKeyPartitionList(keychain.Path, password); err != nil {
+			return err
+		}
+		log.Donef("Key partition list set: %s", osxkeychain.KeyPartitions)
+	}
+
+	for _, profile := range profiles {
+		if pth, err := codesign.InstalledProfilePath(profile); err != nil {
+			return fmt.Errorf("failed to check whether the profile (%s) is installed, error: %s", profile.Path, err)
+		} else if pth != "" {
+			log.Printf("%s (%s): already installed", profile.Info.Name, profile.Info.UUID)
+			continue
+		}
+		pth, err := codesign.InstallProfile(profile)
+		if err != nil {
+			return err
+		}
+		keychain.Profiles = append(keychain.Profiles, pth)
+		list.Add(keychain)
+		if err := state.WriteCIKeychainList(list); err != nil {
+			return err
+		}
+		log.Donef("%s (%s): installed", profile.Info.Name, profile.Info.UUID)
+	}
+
+	fmt.Println()
+	log.Successf("The keychain is ready: %s", keychain.Path)
+	log.Printf("Delete it after the build with: codesigndoc keychain delete-ci --name %s", keychain.Name)
+	return nil
+}
+
+func deleteCIKeychain(_ *cobra.Command, _ []string) error {
+	if err := features.CheckEnabled("tool/ci-keychain"); err != nil {
+		return err
+	}
+	if err := checkKeychainPlatform(); err != nil {
+		return err
+	}
+	if err := validateCIKeychainName(paramCIKeychainName); err != nil {
+		return err
+	}
+
+	list, err := state.ReadCIKeychainList()
+	if err != nil {
+		return err
+	}
+	// only the keychains recorded by create-ci are deleted, never a keychain of the user which happens to have the name
+	keychain, ok := list.Find(paramCIKeychainName)
+	if !ok {
+		return fmt.Errorf("no keychain was created with the name %s by keychain create-ci", paramCIKeychainName)
+	}
+	pth := keychain.Path
+
+	if err := removeCIKeychain(&list, paramCIKeychainName, pth, "created by keychain create-ci"); err != nil {
+		return err
+	}
+	fmt.Println()
+	log.Successf("Deleted the keychain: %s", pth)
+	return nil
+}
+
+// reservedKeychainNames are the keychains of the system, create-ci and delete-ci never touch them
+var reservedKeychainNames = []string{"login", "System", "System Roots", "metadata", "iCloud"}
+
+// validateCIKeychainName returns an error if the name is not a single file name or it is the name of a keychain of the system
+func validateCIKeychainName(name string) error {
+	if name == "" || strings.ContainsAny(name, "/:") || name == "." || name == ".." {
+		return fmt.Errorf("invalid keychain name: %q", name)
+	}
+	base := strings.TrimSuffix(strings.TrimSuffix(name, "-db"), ".keychain")
+	for _, reserved := range reservedKeychainNames {
+		if strings.EqualFold(base, reserved) {
+			return fmt.Errorf("the keychain name %q is reserved by the system, choose another --name", name)
+		}
+	}
+	return nil
+}
+
+// removeCIKeychain deletes the keychain and the profiles installed for it after a confirmation, and removes it from the list
+func removeCIKeychain(list *state.CIKeychainList, name, pth, reason string) error {
+	keychain, _ := list.Find(name)
+
+	plan := confirm.NewPlan("Delete the CI keychain " + name)
+	plan.Add("delete", pth, reason)
+	for _, profilePth := range keychain.Profiles {
+		plan.Add("delete", profilePth, "provisioning profile installed for the keychain")
+	}
+	if ok, err := plan.Confirm(); err != nil {
+		return err
+	} else if !ok {
+		return exitcode.Wrap(exitcode.Cancelled, errors.New("the keychain deletion was not approved"))
+	}
+
+	if exists, err := pathutil.IsPathExists(pth); err != nil {
+		return err
+	} else if exists {
+		if err := osxkeychain.DeleteKeychain(pth); err != nil {
+			return fmt.Errorf("failed to delete the keychain, error: %s", err)
+		}
+	}
+	for _, profilePth := range keychain.Profiles {
+		if err := os.Remove(profilePth); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete the provisioning profile, error: %s", err)
+		}
+	}
+
+	if list.Remove(name) {
+		return state.WriteCIKeychainList(*list)
+	}
+	return nil
+}
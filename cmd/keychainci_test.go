@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCIKeychainName(t *testing.T) {
+	for _, name := range []string{"codesigndoc-ci", "ci.build-42", "login-ci"} {
+		require.NoError(t, validateCIKeychainName(name), name)
+	}
+	for _, name := range []string{"", ".", "..", "a/b", "a:b", "login", "Login", "login.keychain", "login.keychain-db", "System", "system.keychain"} {
+		require.Error(t, validateCIKeychainName(name), name)
+	}
+}
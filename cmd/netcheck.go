@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/bitriseio/bitrise"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/network"
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/spf13/cobra"
+)
+
+// netCheckCmd represents the net-check command
+var netCheckCmd = &cobra.Command{
+	Use:   "net-check",
+	Short: "Check the connectivity to the services codesigndoc uses",
+	Long: `Check the connectivity to the services codesigndoc uses
+
+Every endpoint is checked hop by hop (proxy, DNS, TCP, proxy CONNECT, TLS handshake, HTTP request)
+with the configured CA bundle, proxy and certificate pins, and the first failing hop is reported.`,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          netCheck,
+}
+
+var paramNetCheckEndpoints []string
+
+func init() {
+	RootCmd.AddCommand(netCheckCmd)
+	features.Register(features.Feature{Name: "tool/net-check", Kind: features.Tool, Description: "Check the connectivity to the used services"})
+
+	netCheckCmd.Flags().StringSliceVar(&paramNetCheckEndpoints, "endpoint", nil, "Additional endpoint to check, format: name=https://host/path. Can be specified multiple times")
+}
+
+func netCheck(_ *cobra.Command, _ []string) error {
+	if err := features.CheckEnabled("tool/net-check"); err != nil {
+		return err
+	}
+
+	endpoints := []network.Endpoint{
+		{Name: "Bitrise API", URL: bitrise.APIBaseURL()},
+		{Name: "App Store Connect API", URL: "https://api.appstoreconnect.apple.com/v1"},
+	}
+	for _, endpoint := range paramNetCheckEndpoints {
+		split := strings.SplitN(endpoint, "=", 2)
+		if len(split) != 2 {
+			return fmt.Errorf("invalid endpoint (%s), expected format: name=https://host/path", endpoint)
+		}
+		endpoints = append(endpoints, network.Endpoint{Name: split[0], URL: split[1]})
+	}
+
+	config := network.CurrentConfig()
+	if config.CABundlePath != "" {
+		log.Printf("CA bundle: %s", config.CABundlePath)
+	}
+	if config.ProxyURL != "" {
+		log.Printf("Proxy: %s", config.RedactedProxyURL())
+	}
+	if len(config.Pins) > 0 {
+		log.Printf("Certificate pins: %s", strings.Join(config.Pins, ", "))
+	}
+
+	var failed []string
+	for _, endpoint := range endpoints {
+		fmt.Println()
+		log.Infof("%s (%s)", endpoint.Name, endpoint.URL)
+
+		for _, result := range network.Check(endpoint, config) {
+			if result.Err != nil {
+				fmt.Printf("%s %s: %s\n", colorstring.Red("✗"), result.Hop, result.Err)
+				failed = append(failed, fmt.Sprintf("%s (%s)", endpoint.Name, result.Hop))
+				continue
+			}
+
+			duration := ""
+			if result.Duration > 0 {
+				duration = fmt.Sprintf(" (%s)", result.Duration.Round(time.Millisecond))
+			}
+			fmt.Printf("%s %s: %s%s\n", colorstring.Green("✓"), result.Hop, result.Detail, duration)
+		}
+	}
+
+	fmt.Println()
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to reach: %s", strings.Join(failed, ", "))
+	}
+	log.Successf("Every endpoint is reachable.")
+	return nil
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/state"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/spf13/cobra"
+)
+
+// neverExportCmd represents the never-export command
+var neverExportCmd = &cobra.Command{
+	Use:   "never-export",
+	Short: "Manage the identities which are never offered or exported on this machine",
+	Long: `Manage the identities which are never offered or exported on this machine
+
+The listed identities (e.g. personal development certificates, other clients' keys) are removed
+from every selection list and export, regardless of the project settings.
+The list is stored in the never-export.yml of the codesigndoc state directory.`,
+}
+
+var neverExportAddCmd = &cobra.Command{
+	Use:   "add <sha1 fingerprint>",
+	Short: "Add an identity to the never export list",
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          neverExportAdd,
+}
+
+var neverExportRemoveCmd = &cobra.Command{
+	Use:   "remove <sha1 fingerprint>",
+	Short: "Remove an identity from the never export list",
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          neverExportRemove,
+}
+
+var neverExportListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the identities of the never export list",
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          neverExportList,
+}
+
+var paramNeverExportReason string
+
+func init() {
+	RootCmd.AddCommand(neverExportCmd)
+	neverExportCmd.AddCommand(neverExportAddCmd, neverExportRemoveCmd, neverExportListCmd)
+
+	neverExportAddCmd.Flags().StringVar(&paramNeverExportReason, "reason", "", "Why the identity must not be exported, shown in the list")
+}
+
+func fingerprintArg(args []string) (string, error) {
+	if len(args) != 1 || strings.TrimSpace(args[0]) == "" {
+		return "", errors.New("the SHA1 fingerprint of the identity is required")
+	}
+	return args[0], nil
+}
+
+func neverExportAdd(_ *cobra.Command, args []string) error {
+	fingerprint, err := fingerprintArg(args)
+	if err != nil {
+		return err
+	}
+
+	list, err := state.ReadNeverExportList()
+	if err != nil {
+		return err
+	}
+
+	entry := state.NeverExportEntry{SHA1Fingerprint: fingerprint, Reason: paramNeverExportReason, AddedAt: time.Now()}
+	if installed, err := certificateutil.InstalledCodesigningCertificateInfos(); err != nil {
+		log.Warnf("Failed to list installed certificates: %s", err)
+	} else {
+		for _, certificate := range installed {
+			if state.NormalizeFingerprint(fingerprint) == state.NormalizeFingerprint(certificate.SHA1Fingerprint) {
+				entry.CommonName = certificate.CommonName
+				break
+			}
+		}
+	}
+	if entry.CommonName == "" {
+		log.Warnf("No installed identity found with the given fingerprint, adding it anyway.")
+	}
+
+	list.Add(entry)
+	if err := state.WriteNeverExportList(list); err != nil {
+		return err
+	}
+	log.Successf("Added to the never export list: %s %s", fingerprint, entry.CommonName)
+	return nil
+}
+
+func neverExportRemove(_ *cobra.Command, args []string) error {
+	fingerprint, err := fingerprintArg(args)
+	if err != nil {
+		return err
+	}
+
+	list, err := state.ReadNeverExportList()
+	if err != nil {
+		return err
+	}
+	if !list.Remove(fingerprint) {
+		return fmt.Errorf("identity (%s) is not in the never export list", fingerprint)
+	}
+	if err := state.WriteNeverExportList(list); err != nil {
+		return err
+	}
+	log.Successf("Removed from the never export list: %s", fingerprint)
+	return nil
+}
+
+func neverExportList(_ *cobra.Command, _ []string) error {
+	list, err := state.ReadNeverExportList()
+	if err != nil {
+		return err
+	}
+	if len(list.Identities) == 0 {
+		log.Printf("The never export list is empty.")
+		return nil
+	}
+
+	for _, entry := range list.Identities {
+		line := entry.SHA1Fingerprint
+		if entry.CommonName != "" {
+			line += " " + entry.CommonName
+		}
+		if entry.Reason != "" {
+			line += " (" + entry.Reason + ")"
+		}
+		log.Printf("- %s", line)
+	}
+	return nil
+}
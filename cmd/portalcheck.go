@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bitrise-io/codesigndoc/appstoreconnect"
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/utility"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/spf13/cobra"
+)
+
+// portalCheckCmd represents the portal-check command
+var portalCheckCmd = &cobra.Command{
+	Use:   "portal-check",
+	Short: "Compare the local code signing files against the developer portal",
+	Long: `Compare the local code signing files against the developer portal
+
+The certificates and provisioning profiles registered on the developer portal are listed with the App Store Connect API,
+and compared against the installed code signing identities and provisioning profiles of the API key's team.
+The identities revoked or deleted on the portal, and the profiles invalidated, regenerated or deleted on the portal are reported.
+
+The API key can be set with the ` + ascKeyIDEnvKey + `, ` + ascIssuerIDEnvKey + ` and ` + ascPrivateKeyPathEnvKey + ` env vars too.`,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          portalCheck,
+}
+
+var (
+	paramPortalCheckKeyID          string
+	paramPortalCheckIssuerID       string
+	paramPortalCheckPrivateKeyPath string
+)
+
+func init() {
+	RootCmd.AddCommand(portalCheckCmd)
+	features.Register(features.Feature{Name: "tool/portal-check", Kind: features.Tool, Description: "Compare the local code signing files against the developer portal"})
+
+	portalCheckCmd.Flags().StringVar(&paramPortalCheckKeyID, "key-id", os.Getenv(ascKeyIDEnvKey), "App Store Connect API key ID")
+	portalCheckCmd.Flags().StringVar(&paramPortalCheckIssuerID, "issuer-id", os.Getenv(ascIssuerIDEnvKey), "App Store Connect API issuer ID")
+	portalCheckCmd.Flags().StringVar(&paramPortalCheckPrivateKeyPath, "private-key", os.Getenv(ascPrivateKeyPathEnvKey), "App Store Connect API key (.p8) file path")
+}
+
+func portalCheck(_ *cobra.Command, _ []string) error {
+	if err := features.CheckEnabled("tool/portal-check"); err != nil {
+		return err
+	}
+
+	client, err := newAppStoreConnectClient(paramPortalCheckKeyID, paramPortalCheckIssuerID, paramPortalCheckPrivateKeyPath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	log.Infof("Fetching the certificates and profiles of the developer portal...")
+	certificates, err := client.Certificates()
+	if err != nil {
+		return err
+	}
+	profiles, err := client.Profiles()
+	if err != nil {
+		return err
+	}
+	log.Printf("%d certificate(s), %d profile(s)", len(certificates), len(profiles))
+
+	var installedCertificates []certificateutil.CertificateInfoModel
+	addInstalled := func(certs []certificateutil.CertificateInfoModel, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to list installed code signing identities, error: %s", err)
+		}
+		installedCertificates = append(installedCertificates, certs...)
+		return nil
+	}
+	if err := addInstalled(codesign.InstalledCertificates(codesign.IOSCertificate)); err != nil {
+		return err
+	}
+	if err := addInstalled(codesign.InstalledCertificates(codesign.MacOSCertificate)); err != nil {
+		return err
+	}
+	if err := addInstalled(codesign.InstalledCertificates(codesign.MacOSInstallerCertificate)); err != nil {
+		return err
+	}
+	unique, _ := codesign.DeduplicateCertificates(installedCertificates)
+
+	var installedProfiles []profileutil.ProvisioningProfileInfoModel
+	for _, profileType := range []profileutil.ProfileType{profileutil.ProfileTypeIos, profileutil.ProfileTypeMacOs} {
+		installed, err := utility.InstalledProfileInfos(profileType)
+		if err != nil {
+			return fmt.Errorf("failed to list installed provisioning profiles, error: %s", err)
+		}
+		installedProfiles = append(installedProfiles, installed...)
+	}
+
+	certificateMismatches, profileMismatches := appstoreconnect.CrossCheck(certificates, profiles, unique, installedProfiles)
+
+	fmt.Println()
+	if len(certificateMismatches) == 0 && len(profileMismatches) == 0 {
+		log.Donef("The local code signing files are in sync with the developer portal")
+		return nil
+	}
+	if len(certificateMismatches) > 0 {
+		log.Warnf("Identities revoked on the developer portal (they fail at submission time):")
+		for _, mismatch := range certificateMismatches {
+			log.Printf("- %s: %s", mismatch.Name, mismatch.Description)
+		}
+	}
+	if len(profileMismatches) > 0 {
+		log.Warnf("Provisioning profiles out of date:")
+		for _, mismatch := range profileMismatches {
+			log.Printf("- %s: %s", mismatch.Name, mismatch.Description)
+		}
+	}
+	return fmt.Errorf("%d identity and %d provisioning profile mismatch(es) found", len(certificateMismatches), len(profileMismatches))
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/bitrise-io/codesigndoc/report"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/spf13/cobra"
+)
+
+// profileInfoCmd represents the profile-info command
+var profileInfoCmd = &cobra.Command{
+	Use:   "profile-info <.mobileprovision or .provisionprofile files>",
+	Short: "Print the content of provisioning profile files",
+	Long: `Print the content of provisioning profile files
+
+The name, UUID, app ID, team, entitlements, developer certificates, provisioned devices and expiry of the profiles are printed,
+with whether the identity of each certificate is in the local keychains (on macOS).
+The profiles do not have to be installed, e.g. the ones downloaded from the developer portal or exported by a scan.`,
+
+	Args:          cobra.MinimumNArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          profileInfo,
+}
+
+var paramProfileInfoFormat string
+
+func init() {
+	RootCmd.AddCommand(profileInfoCmd)
+	features.Register(features.Feature{Name: "tool/profile-info", Kind: features.Tool, Description: "Print the content of provisioning profile files"})
+
+	profileInfoCmd.Flags().StringVar(&paramProfileInfoFormat, "format", "text", `Format of the profile content. Valid values: "text", "json"`)
+}
+
+func profileInfo(_ *cobra.Command, args []string) error {
+	if err := features.CheckEnabled("tool/profile-info"); err != nil {
+		return err
+	}
+	if paramProfileInfoFormat != "text" && paramProfileInfoFormat != "json" {
+		return fmt.Errorf("invalid value for --format flag (%s). Valid values: 'text', 'json'", paramProfileInfoFormat)
+	}
+
+	installed, err := keychainFingerprints()
+	if errors.Is(err, osxkeychain.ErrUnsupportedPlatform) {
+		installed = nil
+	} else if err != nil {
+		return err
+	}
+
+	var infos []report.ProfileInfo
+	for _, pth := range args {
+		profile, err := profileutil.NewProvisioningProfileInfoFromFile(pth)
+		if err != nil {
+			return fmt.Errorf("failed to read provisioning profile %s, error: %s", pth, err)
+		}
+		plistData, err := profileutil.NewPlistDataFromFile(pth)
+		if err != nil {
+			return fmt.Errorf("failed to read provisioning profile %s, error: %s", pth, err)
+		}
+		infos = append(infos, report.NewProfileInfo(pth, profile, plistData.GetApplicationIdentifier(), installed, time.Now()))
+	}
+
+	if paramProfileInfoFormat == "json" {
+		return report.WriteProfileInfos(infos, "")
+	}
+	for _, info := range infos {
+		printProfileInfo(info)
+	}
+	return nil
+}
+
+// keychainFingerprints returns the lowercase SHA-1 fingerprints of the identities in the keychains
+func keychainFingerprints() (map[string]bool, error) {
+	identities, err := osxkeychain.FindIdentities(osxkeychain.IdentityQuery{IncludeExpired: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the identities of the keychains, error: %w", err)
+	}
+	defer osxkeychain.ReleaseIdentityWithRefList(identities)
+
+	fingerprints := map[string]bool{}
+	for _, identity := range identities {
+		fingerprints[strings.ToLower(identity.SHA1Fingerprint)] = true
+	}
+	return fingerprints, nil
+}
+
+func printProfileInfo(info report.ProfileInfo) {
+	fmt.Println()
+	log.Infof("%s (%s)", info.Name, info.Path)
+	log.Printf("UUID: %s", info.UUID)
+	log.Printf("app ID: %s", info.AppID)
+	log.Printf("team: %s (%s)", info.TeamName, info.TeamID)
+	log.Printf("platform: %s, export type: %s, Xcode managed: %t", info.Platform, info.ExportType, info.XcodeManaged)
+	log.Printf("created: %s", info.CreatedAt.Format("2006-01-02"))
+	if info.Expired {
+		log.Warnf("expired: %s", info.ExpiresAt.Format("2006-01-02"))
+	} else {
+		log.Printf("expires: %s (in %d days)", info.ExpiresAt.Format("2006-01-02"), info.DaysLeft)
+	}
+
+	log.Printf("entitlements (%d):", len(info.Entitlements))
+	var keys []string
+	for key := range info.Entitlements {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		log.Printf("- %s: %v", key, info.Entitlements[key])
+	}
+
+	log.Printf("certificates (%d):", len(info.Certificates))
+	for _, certificate := range info.Certificates {
+		line := fmt.Sprintf("- %s [%s], expires: %s", certificate.CommonName, certificate.SHA1Fingerprint, certificate.ExpiresAt.Format("2006-01-02"))
+		switch {
+		case certificate.Expired:
+			log.Warnf("%s, expired", line)
+		case certificate.InKeychain == nil:
+			log.Printf("%s", line)
+		case *certificate.InKeychain:
+			log.Donef("%s, in the keychain", line)
+		default:
+			log.Warnf("%s, not in the keychain", line)
+		}
+	}
+
+	switch {
+	case info.ProvisionsAllDevices:
+		log.Printf("devices: all (enterprise)")
+	case len(info.Devices) > 0:
+		log.Printf("devices (%d):", len(info.Devices))
+		for _, device := range info.Devices {
+			log.Printf("- %s", device)
+		}
+	}
+}
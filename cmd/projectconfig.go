@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/confirm"
+	"github.com/bitrise-io/codesigndoc/projectconfig"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/spf13/cobra"
+)
+
+// paramConfigFile is the --config-file of the scans, projectconfig.FileName of the working directory if empty
+var paramConfigFile string
+
+// applyProjectConfig sets the flags of the scan subcommand not given on the command line to the values of the config file.
+// The upload and secret store settings of a config file found in the working directory are applied only if approved,
+// as anyone committing the file to the project could redirect the exported files.
+func applyProjectConfig(cmd *cobra.Command) error {
+	pth := paramConfigFile
+	discovered := false
+	if pth == "" {
+		if _, err := os.Stat(projectconfig.FileName); err != nil {
+			return nil
+		}
+		pth = projectconfig.FileName
+		discovered = true
+	}
+
+	config, err := projectconfig.NewFromFile(pth)
+	if err != nil {
+		return fmt.Errorf("invalid config file (%s): %s", pth, err)
+	}
+	flags, err := config.Flags(cmd.Name())
+	if err != nil {
+		return fmt.Errorf("invalid config file (%s): %s", pth, err)
+	}
+
+	log.Debugf("Using the config file: %s", pth)
+	var pending, destinations []projectconfig.Flag
+	for _, flag := range flags {
+		f := cmd.Flags().Lookup(flag.Name)
+		if f == nil {
+			log.Debugf("The %s command has no --%s flag, the config value is ignored", cmd.Name(), flag.Name)
+			continue
+		}
+		if f.Changed {
+			// the command line takes precedence
+			continue
+		}
+		if discovered && flag.Destination {
+			destinations = append(destinations, flag)
+			continue
+		}
+		pending = append(pending, flag)
+	}
+
+	if len(destinations) > 0 {
+		plan := confirm.NewPlan("Send the exported files to the destinations of the config file found in the working directory (" + pth + ")")
+		for _, flag := range destinations {
+			plan.Add("apply", "--"+flag.Name+" "+strings.Join(flag.Values, " "), "")
+		}
+		approved, err := plan.Confirm()
+		if err != nil {
+			log.Debugf("The destinations of the config file are not approved: %s", err)
+		}
+		if approved {
+			pending = append(pending, destinations...)
+		} else {
+			log.Warnf("The upload and secret store settings of %s are ignored, pass it with --config-file %s (or approve them with --yes) to apply them", pth, pth)
+		}
+	}
+
+	for _, flag := range pending {
+		for _, value := range flag.Values {
+			if err := cmd.Flags().Set(flag.Name, value); err != nil {
+				return fmt.Errorf("invalid value of --%s in the config file (%s): %s", flag.Name, pth, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/exitcode"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/report"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/spf13/cobra"
+)
+
+// pushCmd represents the push command
+var pushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Export the push notification (APNs) certificates",
+	Long: `Export the push notification (APNs) certificates
+
+The "Apple Push Services" and "Apple Development IOS Push Services" certificates with their private keys are exported from the Keychain,
+as a .p12 and a .pem file each, protected by the .p12 password (see --ask-pass and --pass-stdin).
+These are not code signing identities, so they are never exported by the project scanners.`,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          scanPush,
+}
+
+var paramPushBundleIDs []string
+
+func init() {
+	scanCmd.AddCommand(pushCmd)
+	features.Register(features.Feature{Name: scannerFeaturePrefix + "push", Kind: features.Scanner, Description: "Export the push notification (APNs) certificates"})
+
+	pushCmd.Flags().StringSliceVar(&paramPushBundleIDs, "bundle-id", nil, "Export only the push certificates of this bundle ID, can be specified multiple times. Exports every push certificate if empty")
+}
+
+func scanPush(_ *cobra.Command, _ []string) error {
+	if appSlug != "" {
+		return errors.New("the push certificates can not be uploaded to bitrise.io, remove the --auth-token and --app-slug flags")
+	}
+	if writeFiles == codesign.WriteFilesDisabled {
+		return fmt.Errorf("the push certificates are only written to files, remove --%s=disable", writeFilesFlag)
+	}
+
+	absExportOutputDirPath, err := absOutputDir()
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		certificates, err := codesign.FindPushCertificates(scanCtx, paramPushBundleIDs, identityExportConfig)
+		if err != nil {
+			return err
+		}
+		if len(certificates) == 0 {
+			return exitcode.Wrap(exitcode.IdentityNotFound, errors.New("no push certificate found in the keychain"))
+		}
+		files := codesign.PushCertificateFilePaths(certificates, absExportOutputDirPath)
+		if paramFormat == "json" {
+			var planned []report.PlannedFile
+			for _, file := range files {
+				planned = append(planned, report.PlannedFile{Path: file})
+			}
+			return report.WriteDryRunResult(report.NewDryRunResult(certificates, nil, planned, false), paramFormatOutput)
+		}
+		fmt.Println()
+		log.Infof("Push certificates to export (%d):", len(certificates))
+		for _, certificate := range certificates {
+			log.Printf("- %s (expires: %s)", certificate.CommonName, certificate.EndDate.Format("2006-01-02"))
+		}
+		fmt.Println()
+		log.Infof("Files to write:")
+		for _, file := range files {
+			log.Printf("- %s", file)
+		}
+		return nil
+	}
+
+	certificates, err := codesign.ExportPushCertificates(scanCtx, paramPushBundleIDs, identityExportConfig)
+	if err != nil {
+		return err
+	}
+	if len(certificates) == 0 {
+		return exitcode.Wrap(exitcode.IdentityNotFound, errors.New("no push certificate found in the keychain"))
+	}
+
+	files, err := codesign.WritePushCertificates(certificates, absExportOutputDirPath)
+	if err != nil {
+		return err
+	}
+
+	exportResult := codesign.ExportReport{CodesignFilesWritten: true, Files: files}
+	for _, certificate := range certificates {
+		exportResult.Certificates = append(exportResult.Certificates, certificate.Info)
+	}
+	if paramFormat == "json" {
+		if err := report.WriteScanResult(report.NewScanResult(exportResult.Certificates, nil, files), paramFormatOutput); err != nil {
+			log.Errorf("Failed to write the scan result: %s", err)
+		}
+	}
+	if encryptOutput {
+		_, err := encryptStagedOutput(exportResult)
+		return err
+	}
+
+	fmt.Println()
+	log.Successf("Exports finished you can find the exported push certificates at: %s", absExportOutputDirPath)
+	for _, file := range files {
+		log.Printf("- %s", file)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/report"
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/spf13/cobra"
+)
+
+// reportCmd represents the report command
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Create a snapshot report of the machine's code signing setup",
+	Long: `Create a snapshot report of the machine's code signing setup
+
+The report contains the installed identities (with their validity and trust),
+the installed provisioning profiles and the keychain layout.
+The reports of two machines can be compared with the compare-machines command.`,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          createReport,
+}
+
+// compareMachinesCmd represents the compare-machines command
+var compareMachinesCmd = &cobra.Command{
+	Use:   "compare-machines <reportA.json> <reportB.json>",
+	Short: "Compare the code signing setup of two machines",
+	Long: `Compare the code signing setup of two machines
+
+Lists the identities, profiles, trust settings and keychain layout differences of two reports
+created by the report command, to find out why a project builds on one machine but not on the other.`,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          compareMachines,
+}
+
+var paramReportOutputPath string
+
+func init() {
+	RootCmd.AddCommand(reportCmd)
+	RootCmd.AddCommand(compareMachinesCmd)
+	features.Register(features.Feature{Name: "tool/compare-machines", Kind: features.Tool, Description: "Create and compare machine code signing reports"})
+
+	reportCmd.Flags().StringVar(&paramReportOutputPath, "output", "codesigndoc-report.json", "Report file path")
+}
+
+func createReport(_ *cobra.Command, _ []string) error {
+	if err := features.CheckEnabled("tool/compare-machines"); err != nil {
+		return err
+	}
+
+	log.Infof("Collecting the code signing setup of this machine...")
+	machineReport, err := report.Collect()
+	if err != nil {
+		return fmt.Errorf("failed to collect report, error: %s", err)
+	}
+	if err := report.WriteFile(machineReport, paramReportOutputPath); err != nil {
+		return err
+	}
+
+	log.Printf("%d identities, %d provisioning profiles, %d keychains in the search list", len(machineReport.Identities), len(machineReport.Profiles), len(machineReport.Keychains.SearchList))
+	log.Successf("Report written to: %s", paramReportOutputPath)
+	return nil
+}
+
+func compareMachines(_ *cobra.Command, args []string) error {
+	if err := features.CheckEnabled("tool/compare-machines"); err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("two report files are required")
+	}
+
+	reportA, err := report.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	reportB, err := report.ReadFile(args[1])
+	if err != nil {
+		return err
+	}
+
+	nameA, nameB := machineName(reportA, args[0]), machineName(reportB, args[1])
+	if nameA == nameB {
+		nameA, nameB = args[0], args[1]
+	}
+
+	differences := report.Compare(reportA, reportB, nameA, nameB)
+	if len(differences) == 0 {
+		log.Successf("No code signing relevant difference found between %s and %s.", nameA, nameB)
+		return nil
+	}
+
+	lastSection := report.Section("")
+	for _, difference := range differences {
+		if difference.Section != lastSection {
+			fmt.Println()
+			log.Infof("%s", strings.Title(string(difference.Section)))
+			lastSection = difference.Section
+		}
+		fmt.Printf("%s %s\n", colorstring.Yellow("-"), difference.Message)
+	}
+	fmt.Println()
+	return nil
+}
+
+func machineName(machineReport report.Report, pth string) string {
+	if machineReport.Hostname != "" {
+		return machineReport.Hostname
+	}
+	return filepath.Base(pth)
+}
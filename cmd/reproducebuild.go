@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/bitrise-io/codesigndoc/appstoreconnect"
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/utility"
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/spf13/cobra"
+)
+
+const (
+	ascKeyIDEnvKey          = "CODESIGNDOC_ASC_KEY_ID"
+	ascIssuerIDEnvKey       = "CODESIGNDOC_ASC_ISSUER_ID"
+	ascPrivateKeyPathEnvKey = "CODESIGNDOC_ASC_PRIVATE_KEY_PATH"
+)
+
+// reproduceBuildCmd represents the reproduce-build command
+var reproduceBuildCmd = &cobra.Command{
+	Use:   "reproduce-build",
+	Short: "Check whether the signing of the latest App Store Connect build can be reproduced on this machine",
+	Long: `Check whether the signing of the latest App Store Connect build can be reproduced on this machine
+
+The latest uploaded (TestFlight / App Store) build of the app is fetched with the App Store Connect API,
+and the App Store provisioning profiles of the bundle ID, which were valid at the upload, are compared
+against the installed provisioning profiles and code signing identities.
+
+App Store Connect does not expose the exact certificate and profile a build was signed with,
+so every profile the build could have been signed with is checked. Profiles deleted from the developer portal are not listed.
+
+The API key can be set with the ` + ascKeyIDEnvKey + `, ` + ascIssuerIDEnvKey + ` and ` + ascPrivateKeyPathEnvKey + ` env vars too.`,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          reproduceBuild,
+}
+
+var (
+	paramReproduceBundleID       string
+	paramReproducePlatform       string
+	paramReproduceKeyID          string
+	paramReproduceIssuerID       string
+	paramReproducePrivateKeyPath string
+)
+
+func init() {
+	RootCmd.AddCommand(reproduceBuildCmd)
+	features.Register(features.Feature{Name: "tool/reproduce-build", Kind: features.Tool, Description: "Compare the signing of the latest App Store Connect build against the local code signing files"})
+
+	reproduceBuildCmd.Flags().StringVar(&paramReproduceBundleID, "bundle-id", "", "Bundle ID of the app")
+	reproduceBuildCmd.Flags().StringVar(&paramReproducePlatform, "platform", "ios", `Platform of the app. Valid values: "ios", "macos".`)
+	reproduceBuildCmd.Flags().StringVar(&paramReproduceKeyID, "key-id", os.Getenv(ascKeyIDEnvKey), "App Store Connect API key ID")
+	reproduceBuildCmd.Flags().StringVar(&paramReproduceIssuerID, "issuer-id", os.Getenv(ascIssuerIDEnvKey), "App Store Connect API issuer ID")
+	reproduceBuildCmd.Flags().StringVar(&paramReproducePrivateKeyPath, "private-key", os.Getenv(ascPrivateKeyPathEnvKey), "App Store Connect API key (.p8) file path")
+}
+
+func reproduceBuild(_ *cobra.Command, _ []string) error {
+	if err := features.CheckEnabled("tool/reproduce-build"); err != nil {
+		return err
+	}
+	if paramReproduceBundleID == "" {
+		return errors.New("bundle ID is required, use --bundle-id")
+	}
+
+	certType, profileType := codesign.IOSCertificate, profileutil.ProfileTypeIos
+	switch paramReproducePlatform {
+	case "ios":
+	case "macos":
+		certType, profileType = codesign.MacOSCertificate, profileutil.ProfileTypeMacOs
+	default:
+		return fmt.Errorf("invalid platform (%s), valid values: 'ios', 'macos'", paramReproducePlatform)
+	}
+
+	client, err := newAppStoreConnectClient(paramReproduceKeyID, paramReproduceIssuerID, paramReproducePrivateKeyPath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	log.Infof("Fetching the latest build of %s from App Store Connect...", paramReproduceBundleID)
+	app, err := client.FindApp(paramReproduceBundleID)
+	if err != nil {
+		return err
+	}
+	build, err := client.LatestBuild(app.ID)
+	if err != nil {
+		return err
+	}
+	log.Printf("%s build %s, uploaded at %s (%s)", app.Name, build.Version, build.UploadedDate, build.ProcessingState)
+
+	profiles, err := client.BundleIDProfiles(paramReproduceBundleID)
+	if err != nil {
+		return err
+	}
+	candidateProfiles := appstoreconnect.SigningCandidates(build, profiles)
+	if len(candidateProfiles) == 0 {
+		return fmt.Errorf("no App Store provisioning profile of %s was valid at the upload of the build, the profile was deleted from the developer portal or regenerated since then", paramReproduceBundleID)
+	}
+
+	certificates := map[string][]appstoreconnect.Certificate{}
+	for _, profile := range candidateProfiles {
+		if certificates[profile.ID], err = client.ProfileCertificates(profile.ID); err != nil {
+			return err
+		}
+	}
+
+	installedCertificates, err := codesign.InstalledCertificates(certType)
+	if err != nil {
+		return fmt.Errorf("failed to list installed code signing identities, error: %s", err)
+	}
+	installedProfiles, err := utility.InstalledProfileInfos(profileType)
+	if err != nil {
+		return fmt.Errorf("failed to list installed provisioning profiles, error: %s", err)
+	}
+
+	candidates := appstoreconnect.CompareLocal(candidateProfiles, certificates, installedCertificates, installedProfiles)
+
+	var reproducible []appstoreconnect.Candidate
+	for _, candidate := range candidates {
+		fmt.Println()
+		log.Infof("%s (UUID: %s, %s)", candidate.Profile.Name, candidate.Profile.UUID, candidate.Profile.ProfileState)
+		fmt.Printf("%s provisioning profile\n", installedMark(candidate.ProfileInstalled))
+		for _, certificate := range candidate.Certificates {
+			installed := false
+			for _, installedCertificate := range candidate.InstalledCertificates {
+				if installedCertificate.ID == certificate.ID {
+					installed = true
+				}
+			}
+			fmt.Printf("%s %s (serial: %s, expires: %s)\n", installedMark(installed), certificate.Name, certificate.SerialNumber, certificate.ExpirationDate)
+		}
+		if candidate.Reproducible() {
+			reproducible = append(reproducible, candidate)
+		}
+	}
+
+	fmt.Println()
+	if len(reproducible) == 0 {
+		return fmt.Errorf("the signing of build %s can not be reproduced on this machine, install one of the listed profiles and one of its certificates with the private key", build.Version)
+	}
+	if len(candidates) > 1 {
+		log.Warnf("The build could have been signed with any of the %d listed profiles, App Store Connect does not tell which one was used.", len(candidates))
+	}
+	log.Successf("The signing of build %s can be reproduced with: %s (UUID: %s)", build.Version, reproducible[0].Profile.Name, reproducible[0].Profile.UUID)
+	return nil
+}
+
+// newAppStoreConnectClient creates an App Store Connect API client with the key of the flags
+func newAppStoreConnectClient(keyID, issuerID, privateKeyPath string) (*appstoreconnect.Client, error) {
+	if keyID == "" || issuerID == "" || privateKeyPath == "" {
+		return nil, errors.New("App Store Connect API key ID, issuer ID and private key are required")
+	}
+	privateKey, err := ioutil.ReadFile(privateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API key, error: %s", err)
+	}
+	return appstoreconnect.NewClient(keyID, issuerID, privateKey)
+}
+
+func installedMark(installed bool) string {
+	if installed {
+		return colorstring.Green("installed:")
+	}
+	return colorstring.Red("missing:  ")
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/spf13/cobra"
+)
+
+// resignCmd represents the resign command
+var resignCmd = &cobra.Command{
+	Use:   "resign <.ipa>",
+	Short: "Re-sign an .ipa with the exported code signing files",
+	Long: `Re-sign an .ipa with the exported code signing files
+
+The embedded provisioning profiles of the app and its app extensions are replaced with the best matching given profile,
+the bundles are signed with the entitlements of their profile and the identity of the .p12, the nested bundles first.
+The identity is imported into a temporary keychain, the keychains of the machine are not changed.
+Use it to validate the exported files end-to-end, e.g. install the re-signed .ipa on a device.`,
+
+	Args:          cobra.ExactArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          resign,
+}
+
+var (
+	paramResignP12      string
+	paramResignP12Pass  string
+	paramResignProfiles []string
+	paramResignOutput   string
+)
+
+func init() {
+	RootCmd.AddCommand(resignCmd)
+	features.Register(features.Feature{Name: "tool/resign", Kind: features.Tool, Description: "Re-sign an .ipa with the exported code signing files"})
+
+	resignCmd.Flags().StringVar(&paramResignP12, "p12", "", "Path of the .p12 containing the signing identity")
+	resignCmd.Flags().StringVar(&paramResignP12Pass, "p12-pass", "", "Passphrase of the .p12")
+	resignCmd.Flags().StringSliceVar(&paramResignProfiles, "profile", nil, "Path of a provisioning profile of the app or an app extension, can be specified multiple times")
+	resignCmd.Flags().StringVar(&paramResignOutput, "output", "./resigned.ipa", "Path of the re-signed .ipa")
+}
+
+func resign(_ *cobra.Command, args []string) error {
+	if err := features.CheckEnabled("tool/resign"); err != nil {
+		return err
+	}
+	if err := checkKeychainPlatform(); err != nil {
+		return err
+	}
+	if paramResignP12 == "" {
+		return errors.New("--p12 is required")
+	}
+	if len(paramResignProfiles) == 0 {
+		return errors.New("at least one --profile is required")
+	}
+
+	fmt.Println()
+	log.Infof("Re-signing %s", args[0])
+	bundles, err := codesign.Resign(codesign.ResignConfig{
+		IPAPath:       args[0],
+		OutputPath:    paramResignOutput,
+		P12Path:       paramResignP12,
+		P12Passphrase: paramResignP12Pass,
+		ProfilePaths:  paramResignProfiles,
+	})
+	if err != nil {
+		return err
+	}
+	for _, bundle := range bundles {
+		if bundle.Profile == nil {
+			log.Printf("%s: %s", bundle.Path, bundle.Identity.CommonName)
+			continue
+		}
+		log.Printf("%s (%s): %s, %s (%s)", bundle.Path, bundle.BundleID, bundle.Identity.CommonName, bundle.Profile.Name, bundle.Profile.UUID)
+	}
+
+	fmt.Println()
+	log.Successf("Re-signed .ipa: %s", paramResignOutput)
+	return nil
+}
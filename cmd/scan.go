@@ -1,12 +1,37 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/bitrise-io/codesigndoc/appstoreconnect"
+	"github.com/bitrise-io/codesigndoc/bitriseconfig"
+	"github.com/bitrise-io/codesigndoc/bundle"
+	"github.com/bitrise-io/codesigndoc/cloudstorage"
 	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/exitcode"
+	"github.com/bitrise-io/codesigndoc/fastlane"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/keychain"
+	"github.com/bitrise-io/codesigndoc/logging"
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/bitrise-io/codesigndoc/projectconfig"
+	"github.com/bitrise-io/codesigndoc/prompt"
+	"github.com/bitrise-io/codesigndoc/report"
+	"github.com/bitrise-io/codesigndoc/secretstore"
 	"github.com/bitrise-io/go-utils/colorstring"
 	"github.com/bitrise-io/go-utils/command"
 	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
 	"github.com/spf13/cobra"
 )
 
@@ -24,6 +49,31 @@ var scanCmd = &cobra.Command{
 and export the require code signing files.`,
 	TraverseChildren: true,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkLogFlags(); err != nil {
+			return err
+		}
+		if cmd.HasParent() && cmd.Parent().Name() == "scan" {
+			if err := features.CheckEnabled(scannerFeaturePrefix + cmd.Name()); err != nil {
+				return err
+			}
+		}
+		if err := checkKeychainPlatform(); err != nil {
+			return err
+		}
+		if err := applyProjectConfig(cmd); err != nil {
+			return err
+		}
+		if expiryWarningDays < 1 {
+			return fmt.Errorf("invalid value for --expiry-warning-days flag (%d), it has to be at least 1", expiryWarningDays)
+		}
+		if paramTestDevices != "" {
+			content, err := ioutil.ReadFile(paramTestDevices)
+			if err != nil {
+				return fmt.Errorf("failed to read the test devices (%s), error: %s", paramTestDevices, err)
+			}
+			testDevices = report.ParseTestDevices(string(content))
+		}
+
 		switch cmd.Flag(writeFilesFlag).Value.String() {
 		case "always":
 			{
@@ -48,23 +98,298 @@ and export the require code signing files.`,
 			appSlug == "" && authToken != "" {
 			return fmt.Errorf("both or none flags %s and %s are required to be set", appSlugFlag, authTokenFlag)
 		}
+		if appSlug != "" {
+			if err := features.CheckEnabled(codesign.BitriseDestinationFeature); err != nil {
+				return err
+			}
+		}
+		if writeFiles != codesign.WriteFilesDisabled && !features.IsEnabled(codesign.FilesDestinationFeature) {
+			return fmt.Errorf("%s, use --%s=disable", features.CheckEnabled(codesign.FilesDestinationFeature), writeFilesFlag)
+		}
+
+		if uploadTo != "" {
+			if writeFiles == codesign.WriteFilesDisabled || dryRun {
+				return fmt.Errorf("--upload-to uploads the written files, it can not be used together with --dry-run or --%s=disable", writeFilesFlag)
+			}
+			uploader, err := newCloudUploader(uploadTo, uploadRegion, uploadEndpoint)
+			if err != nil {
+				return err
+			}
+			cloudUploader = uploader
+		}
+
+		if paramSaveSelection != "" && !dryRun {
+			return fmt.Errorf("--save-selection saves the identities found by the dry run, it requires --dry-run")
+		}
+		if dryRun && (encryptOutput || splitExport) {
+			return fmt.Errorf("--dry-run can not be used together with --encrypt-output or --split-export")
+		}
+		if encryptOutput && splitExport {
+			return fmt.Errorf("--encrypt-output can not be used together with --split-export")
+		}
+		if perTeam && personalAccessToken != "" {
+			return fmt.Errorf("--per-team can not be used together with --%s and --%s, the files of every team would be uploaded to the same app", authTokenFlag, appSlugFlag)
+		}
+		if perTeam && paramSecretStore != "" {
+			return fmt.Errorf("--per-team can not be used together with --secret-store, the secret holds the .p12 of a single team")
+		}
+		if requirePairing && certificatesOnly {
+			return fmt.Errorf("--require-pairing can not be used together with --certs-only, no profile is exported")
+		}
+
+		if paramTimeout < 0 {
+			return fmt.Errorf("invalid value for --timeout flag (%s), it must not be negative", paramTimeout)
+		}
+		if paramTimeout > 0 {
+			scanCtx, cancelScan = context.WithTimeout(context.Background(), paramTimeout)
+		}
+
+		if paramFormat != "text" && paramFormat != "json" {
+			return fmt.Errorf("invalid value for --format flag (%s). Valid values: 'text', 'json'", paramFormat)
+		}
+		if err := namingConfig.Validate(); err != nil {
+			return err
+		}
+
+		config, err := readIdentityExportConfig()
+		if err != nil {
+			return err
+		}
+		identityExportConfig = config
+		identityExportConfig.Keychain = identityKeychain
+		logging.AddSecret(identityExportConfig.Passphrase)
+		logging.AddSecret(personalAccessToken)
+		identityExportConfig.PEM = exportPEM
+		identityExportConfig.Each = exportEach
+		identityExportConfig.SeparateKeys = separateKeys
+		if separateKeys && !identityExportConfig.AskForPassword && identityExportConfig.Passphrase == "" {
+			return fmt.Errorf("--separate-keys requires a .p12 password to encrypt the private keys, set it with --ask-pass, --pass-stdin or the %s env var", passphraseEnvKey)
+		}
+		identityExportConfig.Deduplicate = dedupeIdentities
+		identityExportConfig.Chain = exportChain
+		identityExportConfig.IncludeExpired = includeExpired
+		kc, keychains, err := identityKeychainFlag(identityKeychains)
+		if err != nil {
+			return err
+		}
+		if kc != nil {
+			identityExportConfig.Keychain = kc
+		}
+		identityExportConfig.Keychains = keychains
+		for _, teamID := range teamIDs {
+			if !teamIDPattern.MatchString(teamID) {
+				return fmt.Errorf("invalid value for --team-id flag (%s), a Team ID consists of 10 uppercase letters and digits", teamID)
+			}
+		}
+		identityExportConfig.TeamIDs = teamIDs
+		for _, fingerprint := range identityFingerprints {
+			if !fingerprintPattern.MatchString(keychain.NormalizeFingerprint(fingerprint)) {
+				return fmt.Errorf("invalid value for --identity-fingerprint flag (%s), it has to be a hex SHA-1 (40 characters) or SHA-256 (64 characters) fingerprint", fingerprint)
+			}
+		}
+		identityExportConfig.Fingerprints = identityFingerprints
+		codesign.CertificateFingerprints = identityFingerprints
+		identityExportConfig.InspectKeyAccess = inspectKeyAccess
+		identityExportConfig.TokenCertificatesOnly = tokenCertificatesOnly
+		identityExportConfig.AllowEnterprise = allowEnterprise
+		identityExportConfig.SkipRevocationCheck = skipRevocationCheck
+		if paramSecretStore != "" && !dryRun {
+			if identityExportConfig.AskForPassword {
+				return fmt.Errorf("--secret-store stores the .p12 password, it can not be used together with --ask-pass, set the password with --pass-stdin or the %s env var", passphraseEnvKey)
+			}
+			if secretStore, err = newSecretStore(paramSecretStore); err != nil {
+				return err
+			}
+		}
+		if authorizeKeyAccess {
+			if identityExportConfig.AuthorizeApplications, err = keyAccessApplications(); err != nil {
+				return err
+			}
+		}
+		codesign.IncludeExpiredCertificates = includeExpired
+		if identityExportConfig.Types, err = identityTypes(paramIdentityType); err != nil {
+			return err
+		}
+		if paramMatchRepo != "" {
+			if matchRepoConfig, err = readMatchRepoConfig(paramMatchRepo, identityExportConfig); err != nil {
+				return err
+			}
+			identityExportConfig.Each = true
+		}
+
+		if encryptOutput && !dryRun {
+			// asked before the export, so a wrong or missing passphrase does not fail the run with the files already exported
+			if encryptPassphrase, err = askBundlePassphrase(true); err != nil {
+				return fmt.Errorf("--encrypt-output requires the passphrase of the encrypted file, error: %s", err)
+			}
+		}
+
+		if !skipKeychainCheck {
+			if err := checkLegacyKeychains(); err != nil {
+				return err
+			}
+		}
+		if err := unlockKeychain(); err != nil {
+			return err
+		}
+		if systemKeychain {
+			return includeSystemKeychain()
+		}
 		return nil
 	},
 }
 
 var (
-	isAskForPassword bool
-	certificatesOnly bool
-	writeFiles       codesign.WriteFilesLevel
+	isAskForPassword  bool
+	passphraseStdin   bool
+	exportPEM         bool
+	exportEach        bool
+	separateKeys      bool
+	exportCerts       bool
+	dedupeIdentities  bool
+	exportChain       bool
+	includeExpired    bool
+	paramIdentityType string
+	// teamIDs restricts the export to the identities and profiles of the teams, set by --team-id
+	teamIDs []string
+	// perTeam exports the files of every team into a separate directory, set by --per-team
+	perTeam bool
+	// identityFingerprints restricts the export to the identities of the certificate fingerprints, set by --identity-fingerprint
+	identityFingerprints []string
+	// identityKeychains restricts the export to the identities of the keychains, set by --identity-keychain
+	identityKeychains []string
+	certificatesOnly  bool
+	skipKeychainCheck bool
+	keychainPath      string
+	keychainPassword  string
+	systemKeychain    bool
+	writeFiles        codesign.WriteFilesLevel
+
+	// inspectKeyAccess and authorizeKeyAccess are set by --inspect-key-access and --authorize-key-access
+	inspectKeyAccess   bool
+	authorizeKeyAccess bool
+	// tokenCertificatesOnly is set by --token-certificates-only
+	tokenCertificatesOnly bool
+	// allowEnterprise is set by --allow-enterprise
+	allowEnterprise bool
+	// skipRevocationCheck is set by --skip-revocation-check
+	skipRevocationCheck bool
+	// requirePairing is set by --require-pairing
+	requirePairing bool
+
+	identityExportConfig codesign.IdentityExportConfig
+
+	paramMatchRepo  string
+	matchRepoConfig codesign.MatchRepoConfig
 
 	personalAccessToken string
 	appSlug             string
+	uploadRollback      bool
+	// cloudUploader uploads the written files to the --upload-to bucket, nil if it is not set
+	cloudUploader  cloudstorage.Uploader
+	uploadTo       string
+	uploadRegion   string
+	uploadEndpoint string
+	// secretStore receives the exported .p12 and its password, nil if --secret-store is not set
+	secretStore      secretstore.Store
+	paramSecretStore string
+
+	splitExport   bool
+	encryptOutput bool
+	dryRun        bool
+	// stagingOutputDir is the temporary export directory of --encrypt-output, removed once encrypted
+	stagingOutputDir string
+	// encryptPassphrase encrypts the files of --encrypt-output
+	encryptPassphrase string
+	// paramSaveSelection is the file the dry run writes the found identities into, see the selection scanner
+	paramSaveSelection string
+
+	paramOutputDir string
+	// namingConfig names the written .p12 and profile files, set by the --*-name-template flags
+	namingConfig codesign.NamingConfig
+
+	paramFormat       string
+	paramFormatOutput string
+	paramHTMLReport   string
+	// expiryWarningDays is the period of --expiry-warning-days, in which the expiring identities and profiles are reported
+	expiryWarningDays int
+	// testDevices are the UDIDs of the --test-devices file, checked against the devices of the ad-hoc profiles
+	testDevices      []string
+	paramTestDevices string
+	redactDevices    bool
+
+	paramTimeout time.Duration
+	// scanCtx is done when the --timeout passes, the keychain operations return with an error instead of hanging
+	scanCtx    = context.Background()
+	cancelScan = func() {}
+	// identityKeychain searches and exports the identities of the scans
+	identityKeychain keychain.Keychain = osxkeychain.NewKeychain("")
+	// restoreSearchList removes the system keychain from the search list, if --system-keychain added it
+	restoreSearchList = func() {}
 )
 
+// teamIDPattern matches an Apple Developer Team ID, e.g. ABCD123456
+var teamIDPattern = regexp.MustCompile(`^[A-Z0-9]{10}$`)
+
+// fingerprintPattern matches a normalized SHA-1 or SHA-256 certificate fingerprint
+var fingerprintPattern = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+
+// passphraseEnvKey is the env var of the .p12 password for non-interactive runs
+const passphraseEnvKey = "CODESIGNDOC_P12_PASSPHRASE"
+
+// keychainPasswordEnvKey is the env var of the password unlocking the keychain for non-interactive runs
+const keychainPasswordEnvKey = "CODESIGNDOC_KEYCHAIN_PASSWORD"
+
+// scannerFeaturePrefix prefixes the feature name of the scan subcommands
+const scannerFeaturePrefix = "scan/"
+
 func init() {
 	RootCmd.AddCommand(scanCmd)
+	features.Register(features.Feature{Name: codesign.FilesDestinationFeature, Kind: features.Destination, Description: "Write the code signing files to the export directory"})
+	features.Register(features.Feature{Name: codesign.BitriseDestinationFeature, Kind: features.Destination, Description: "Upload the code signing files to bitrise.io"})
+	features.Register(features.Feature{Name: cloudstorage.S3Feature, Kind: features.Destination, Description: "Upload the exported files to an S3 bucket"})
+	features.Register(features.Feature{Name: cloudstorage.GCSFeature, Kind: features.Destination, Description: "Upload the exported files to a Google Cloud Storage bucket"})
+	features.Register(features.Feature{Name: cloudstorage.AzureBlobFeature, Kind: features.Destination, Description: "Upload the exported files to an Azure Blob Storage container"})
+	features.Register(features.Feature{Name: secretstore.VaultFeature, Kind: features.Destination, Description: "Store the exported .p12 and its password in HashiCorp Vault"})
+	features.Register(features.Feature{Name: secretstore.AWSSecretsManagerFeature, Kind: features.Destination, Description: "Store the exported .p12 and its password in AWS Secrets Manager"})
+	features.Register(features.Feature{Name: secretstore.GoogleSecretManagerFeature, Kind: features.Destination, Description: "Store the exported .p12 and its password in GCP Secret Manager"})
 	scanCmd.PersistentFlags().BoolVar(&isAskForPassword, "ask-pass", false, "Ask for .p12 password, instead of using an empty password")
+	scanCmd.PersistentFlags().BoolVar(&passphraseStdin, "pass-stdin", false, "Read the .p12 password from the first line of the standard input, instead of using an empty password. The password can also be set with the "+passphraseEnvKey+" env var")
+	scanCmd.PersistentFlags().BoolVar(&exportPEM, "pem", false, "Also export the Identities as a PEM sequence (Identities.pem) for openssl based tools, the private keys are protected by the .p12 password")
+	scanCmd.PersistentFlags().BoolVar(&exportEach, "p12-per-identity", false, "Also export every Identity into a separate .p12 file, named after the certificate's common name and serial")
+	scanCmd.PersistentFlags().BoolVar(&separateKeys, "separate-keys", false, "Also export the private key of every Identity as an encrypted PKCS#8 PEM (.key.pem) and its certificate as DER (.cer) and PEM (.crt.pem), for HSM backed or cloud signing systems which can not import a .p12. The private keys are encrypted with the .p12 password")
+	scanCmd.PersistentFlags().BoolVar(&exportCerts, "export-certificates", false, "Also write the certificate of every Identity without the private key as DER (.cer) and PEM (.crt.pem), next to the .p12, for the services which need the certificate only (e.g. push providers, MDM)")
+	scanCmd.PersistentFlags().BoolVar(&includeExpired, "include-expired", false, "Export the expired and not yet valid Identities too, e.g. for archival purposes")
+	scanCmd.PersistentFlags().BoolVar(&exportChain, "include-chain", false, "Also export the intermediate certificates (e.g. Apple WWDR G3) of the Identities as .cer files, for CI machines without them")
+	scanCmd.PersistentFlags().BoolVar(&dedupeIdentities, "dedupe-identities", false, "Export only one copy of the Identities found multiple times, e.g. installed in multiple keychains")
+	scanCmd.PersistentFlags().StringVar(&paramIdentityType, "identity-type", "", `Export only the Identities of the given certificate type. Valid values: "development", "distribution", "developer-id" (macOS apps outside of the Mac App Store). Exports every type if empty`)
+	scanCmd.PersistentFlags().StringSliceVar(&teamIDs, "team-id", nil, "Export only the Identities and Profiles of this Apple Developer Team ID (the OU of the certificate subject), can be specified multiple times. For machines with the Identities of multiple clients. Exports every team if empty")
+	scanCmd.PersistentFlags().BoolVar(&perTeam, "per-team", false, "Export the Identities and Profiles of every Apple Developer team separately, into a directory named after the Team ID in the output directory, each with its own Identities.p12. For agencies exporting the files of multiple clients on one machine, the scan result and the HTML report list the files per team too")
+	scanCmd.PersistentFlags().StringSliceVar(&identityFingerprints, "identity-fingerprint", nil, "Export only the Identity with this SHA-1 or SHA-256 certificate fingerprint (as printed by security find-identity -v -p codesigning), can be specified multiple times. For selecting one of multiple certificates with the same name. Exports every Identity if empty")
+	scanCmd.PersistentFlags().StringSliceVar(&identityKeychains, "identity-keychain", nil, "Export only the Identities stored in this keychain, a path or a name (e.g. login), can be specified multiple times. For the Identities installed both in the login and in a CI keychain. A keychain file outside the search list (security list-keychains) is searched alone, it can not be combined with other keychains. Searches every keychain of the search list if empty")
+	scanCmd.PersistentFlags().BoolVar(&inspectKeyAccess, "inspect-key-access", false, "Log the applications which can sign with the private key of every exported Identity without a Keychain prompt (the access control list of the key)")
+	scanCmd.PersistentFlags().BoolVar(&authorizeKeyAccess, "authorize-key-access", false, "Add codesigndoc and "+osxkeychain.CodesignPath+" to the access control list of the private keys not allowing them yet, after a confirmation, so later exports and the signing on this machine do not show Keychain prompts")
+	scanCmd.PersistentFlags().BoolVar(&tokenCertificatesOnly, "token-certificates-only", false, "Export only the certificates (.cer and .crt.pem) of the Identities with the private key on a hardware token (e.g. a smart card or a YubiKey), without asking. The private key of a token can not be exported")
+	scanCmd.PersistentFlags().BoolVar(&allowEnterprise, "allow-enterprise", false, "Allow the export of enterprise (In-House) distribution Identities, which sign the profiles provisioning all devices. The export fails if it contains such an Identity otherwise, a leaked enterprise Identity can sign apps for any device")
+	scanCmd.PersistentFlags().BoolVar(&skipRevocationCheck, "skip-revocation-check", false, "Do not check online (OCSP) whether the exported Identities are revoked, e.g. on offline machines. The check is skipped with a warning if --proxy or --ca-bundle is set, it uses the system network settings")
+	scanCmd.PersistentFlags().BoolVar(&requirePairing, "require-pairing", false, "Fail the scan if the exported files are incomplete: an exported Identity is in none of the exported Profiles, or none of the certificates of an exported Profile is exported. The problems are reported as warnings otherwise")
+	scanCmd.PersistentFlags().StringVar(&paramMatchRepo, "match-repo", "", "Also write the Identities and Profiles into the given directory in the fastlane match repo layout, encrypted with the "+fastlane.MatchPasswordEnvKey+" env var")
 	scanCmd.PersistentFlags().BoolVar(&certificatesOnly, "certs-only", false, "Collect Certificates (Identities) only")
+	scanCmd.PersistentFlags().BoolVar(&skipKeychainCheck, "skip-keychain-check", false, "Skip checking for legacy (not converted to .keychain-db) keychains before scanning")
+	scanCmd.PersistentFlags().StringVar(&keychainPath, "keychain-path", "", "Keychain file to unlock with the keychain password, the login keychain if empty")
+	scanCmd.PersistentFlags().StringVar(&keychainPassword, "keychain-password", "", "Unlock the keychain with this password before scanning, for headless machines where the keychain is locked. The password can also be set with the "+keychainPasswordEnvKey+" env var")
+	scanCmd.PersistentFlags().BoolVar(&systemKeychain, "system-keychain", false, "Also search the system keychain ("+osxkeychain.SystemKeychainPath+"), e.g. for Developer ID and enterprise identities. The export is re-run with sudo if the system keychain requires admin rights")
+	scanCmd.PersistentFlags().DurationVar(&paramTimeout, "timeout", 0, "Cancel the scan if it takes longer than this (e.g. 30m), instead of waiting forever for a hung keychain search or an unanswered Keychain prompt. No timeout if 0")
+	scanCmd.PersistentFlags().StringVar(&paramConfigFile, "config-file", "", "Project config file declaring the defaults of the flags (project, schemes, configurations, team IDs, output and upload options), "+projectconfig.FileName+" of the working directory if empty. The flags given on the command line take precedence, the relative paths of the file are resolved against its directory. The upload and secret store settings of a file found in the working directory are applied only if approved (or with --yes)")
+	scanCmd.PersistentFlags().StringVar(&paramOutputDir, "output-dir", "./codesigndoc_exports", "Directory of the exported codesigning files and build logs")
+	scanCmd.PersistentFlags().StringVar(&namingConfig.IdentityTemplate, "identity-name-template", "", "File name of the separately exported .p12 files (see --p12-per-identity) in the output directory, e.g. {team_id}/{cert_type}-{common_name}.p12. Placeholders: {"+strings.Join(codesign.IdentityPlaceholders, "}, {")+"}. Named after the common name and serial if empty")
+	scanCmd.PersistentFlags().StringVar(&namingConfig.ProfileTemplate, "profile-name-template", "", "File name of the provisioning profiles in the output directory, e.g. {profile_name}-{uuid}{ext}. Placeholders: {"+strings.Join(codesign.ProfilePlaceholders, "}, {")+"}, {ext} is .mobileprovision or .provisionprofile. Named <uuid>.<profile name>{ext} if empty")
+	scanCmd.PersistentFlags().StringVar(&paramFormat, "format", "text", `Format of the scan result. Valid values: "text", "json". The json result lists the exported identities, profiles and files`)
+	scanCmd.PersistentFlags().StringVar(&paramFormatOutput, "format-output", "", "File path of the json scan result, printed to the standard output if empty")
+	scanCmd.PersistentFlags().IntVar(&expiryWarningDays, "expiry-warning-days", report.DefaultExpiryWarningDays, "Report the Identities and Profiles expiring within this many days, with their expiry date and how to replace them, in the console and the json result")
+	scanCmd.PersistentFlags().StringVar(&paramTestDevices, "test-devices", "", "File of the project's test device UDIDs (a UDID per line, or the device list file of the Apple Developer Portal), the ad-hoc profiles not provisioning any of them are reported")
+	scanCmd.PersistentFlags().BoolVar(&redactDevices, "redact-devices", false, "Shorten the device UDIDs of the ad-hoc profiles in the json result and the console to their first 5 and last 4 characters")
+	scanCmd.PersistentFlags().StringVar(&paramHTMLReport, "html-report", "", "Also write a shareable HTML summary of the exported files to the given path: certificate details, expiry timeline, profile to target mapping and warnings. It contains no private key or password")
 	scanCmd.PersistentFlags().String(writeFilesFlag, "always", `Set wether to export build logs and codesigning files to the ./codesigndoc_exports directory. Defaults to "always". Valid values: "always", "fallback", "disable".
 - always: Writes artifacts in every case.
 - fallback: Does not write artifacts if the automatic upload option is chosen interactively or by providing the auth-token and app-slug flag. Writes build log only on failure.
@@ -74,6 +399,15 @@ func init() {
 Will upload codesigning files automatically if provided. Requires the app-slug paramater to be also set.`)
 	scanCmd.PersistentFlags().StringVar(&appSlug, appSlugFlag, "", `Bitrise app slug. By default codesigndoc will ask for it interactively.
 Will upload codesigning files automatically if provided. Requires the auth-token parameter to be also set.`)
+	scanCmd.PersistentFlags().BoolVar(&splitExport, "split-export", false, "Also package the written codesigning files into encrypted chunks (see the bundle command) in the ./codesigndoc_bundle directory")
+	scanCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Find and validate the required Identities and Profiles, and print what would be exported and written (with the estimated file sizes), without exporting from the Keychain, writing or uploading any file")
+	scanCmd.PersistentFlags().StringVar(&paramSaveSelection, "save-selection", "", "Write the identities found by the --dry-run into this file with their keychain references, to export exactly these identities later (e.g. after an approval) with the selection scanner")
+	scanCmd.PersistentFlags().BoolVar(&encryptOutput, "encrypt-output", false, "Write the codesigning files, the build logs and the json scan result only as a single passphrase encrypted tar.gz archive (<output-dir>.tar.gz.gpg, in the OpenPGP format of gpg --symmetric), decrypt it with gpg --decrypt or the bundle decrypt command. The passphrase is asked before the export, it can be set with the "+bundlePassphraseEnvKey+" env var")
+	scanCmd.PersistentFlags().StringVar(&uploadTo, "upload-to", "", "Also upload the written codesigning files (or the encrypted file of --encrypt-output) to this bucket: s3://<bucket>/<prefix>, gs://<bucket>/<prefix> or azblob://<account>/<container>/<prefix>. The credentials are read from the env vars, see the upload command")
+	scanCmd.PersistentFlags().StringVar(&uploadRegion, "upload-region", "", "Region of the --upload-to S3 bucket, the "+cloudstorage.AWSRegionEnvKey+" env var or us-east-1 if empty")
+	scanCmd.PersistentFlags().StringVar(&uploadEndpoint, "upload-endpoint", "", "Endpoint of the --upload-to S3, GCS or Azure Blob compatible service (e.g. MinIO or an emulator), instead of the public one")
+	scanCmd.PersistentFlags().StringVar(&paramSecretStore, "secret-store", "", "Also store the exported .p12 (base64 encoded, as "+secretstore.P12Field+") and its password (as "+secretstore.P12PasswordField+") in this secret: vault://<mount>/<path> (KV version 2, with the "+secretstore.VaultAddrEnvKey+" and "+secretstore.VaultTokenEnvKey+" env vars), aws-sm://<secret name> or gcp-sm://<project>/<secret ID>. Pass --write-files=disable to keep the identities off the disk")
+	scanCmd.PersistentFlags().BoolVar(&uploadRollback, "upload-rollback", true, "Remove the already uploaded codesigning files from Bitrise if any of the uploads fails. If disabled, the incomplete files are listed instead.")
 }
 
 // Tool ...
@@ -128,8 +462,367 @@ with as many details & logs as you can share!
 `
 }
 
-func printFinished(exportResult codesign.ExportReport, absOutputDir string) {
-	if exportResult.CodesignFilesWritten {
+// withFlagHint extends the error of a question asked in non-interactive mode with the flag answering it
+func withFlagHint(err error, flag string) error {
+	if _, ok := err.(prompt.NonInteractiveError); ok {
+		return fmt.Errorf("%s, set it with the --%s flag", err, flag)
+	}
+	return err
+}
+
+// readMatchRepoConfig reads the match repo password, and the developer portal IDs of the certificates if an App Store Connect API key is set
+func readMatchRepoConfig(dir string, exportConfig codesign.IdentityExportConfig) (codesign.MatchRepoConfig, error) {
+	absDir, err := pathutil.AbsPath(dir)
+	if err != nil {
+		return codesign.MatchRepoConfig{}, fmt.Errorf("failed to expand match repo path (%s), error: %s", dir, err)
+	}
+	password := os.Getenv(fastlane.MatchPasswordEnvKey)
+	if password == "" {
+		return codesign.MatchRepoConfig{}, fmt.Errorf("the %s env var is required to encrypt the match repo", fastlane.MatchPasswordEnvKey)
+	}
+	logging.AddSecret(password)
+	if exportConfig.AskForPassword || exportConfig.Passphrase != "" {
+		log.Warnf("match imports the .p12 files with an empty password, the password set for the export is not accepted by match")
+	}
+
+	config := codesign.MatchRepoConfig{DirPath: absDir, Password: password, CertificateIDs: map[string]string{}}
+	if os.Getenv(ascKeyIDEnvKey) == "" {
+		log.Warnf("The certificate files of match are named after their developer portal ID, set the %s, %s and %s env vars to look them up", ascKeyIDEnvKey, ascIssuerIDEnvKey, ascPrivateKeyPathEnvKey)
+		return config, nil
+	}
+
+	client, err := newAppStoreConnectClient(os.Getenv(ascKeyIDEnvKey), os.Getenv(ascIssuerIDEnvKey), os.Getenv(ascPrivateKeyPathEnvKey))
+	if err != nil {
+		return codesign.MatchRepoConfig{}, err
+	}
+	certificates, err := client.Certificates()
+	if err != nil {
+		return codesign.MatchRepoConfig{}, fmt.Errorf("failed to list the certificates of the developer portal, error: %s", err)
+	}
+	for _, certificate := range certificates {
+		config.CertificateIDs[appstoreconnect.DecimalSerial(certificate.SerialNumber)] = certificate.ID
+	}
+	return config, nil
+}
+
+// readIdentityExportConfig returns the .p12 password settings of the --ask-pass and --pass-stdin flags and the env var
+func readIdentityExportConfig() (codesign.IdentityExportConfig, error) {
+	passphrase, fromEnv := os.LookupEnv(passphraseEnvKey)
+	if isAskForPassword && (passphraseStdin || fromEnv) {
+		return codesign.IdentityExportConfig{}, fmt.Errorf("--ask-pass can not be used together with --pass-stdin or the %s env var", passphraseEnvKey)
+	}
+	if isAskForPassword {
+		return codesign.IdentityExportConfig{AskForPassword: true}, nil
+	}
+
+	if passphraseStdin {
+		// read byte by byte, the rest of the input is left for the interactive questions
+		var line []byte
+		b := make([]byte, 1)
+		for {
+			n, err := os.Stdin.Read(b)
+			if n == 1 && b[0] != '\n' {
+				line = append(line, b[0])
+				continue
+			}
+			if n == 1 || err == io.EOF {
+				break
+			}
+			if err != nil {
+				return codesign.IdentityExportConfig{}, fmt.Errorf("failed to read the .p12 password from the standard input, error: %s", err)
+			}
+		}
+		passphrase = strings.TrimSuffix(string(line), "\r")
+	}
+	return codesign.IdentityExportConfig{Passphrase: passphrase}, nil
+}
+
+// identityKeychainFlag returns the Keychain and the keychain filter of the --identity-keychain flag:
+// a keychain file outside the user's search list is opened and searched alone,
+// the keychains of the search list are selected by the filter (the Keychain is nil)
+func identityKeychainFlag(values []string) (keychain.Keychain, []string, error) {
+	var outside []string
+	for _, value := range values {
+		if !strings.Contains(value, "/") && !strings.HasSuffix(value, ".keychain") && !strings.HasSuffix(value, ".keychain-db") {
+			// a keychain name, e.g. login
+			continue
+		}
+		pth, err := filepath.Abs(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid value for --identity-keychain flag (%s): %s", value, err)
+		}
+		if _, err := os.Stat(pth); err != nil {
+			return nil, nil, fmt.Errorf("invalid value for --identity-keychain flag (%s), the keychain file does not exist", value)
+		}
+		searchList, err := osxkeychain.SearchList()
+		if err != nil {
+			return nil, nil, err
+		}
+		inSearchList := false
+		for _, searched := range searchList {
+			if filepath.Clean(searched) == pth {
+				inSearchList = true
+				break
+			}
+		}
+		if !inSearchList {
+			outside = append(outside, pth)
+		}
+	}
+
+	if len(outside) == 0 {
+		return nil, values, nil
+	}
+	if len(values) > 1 {
+		return nil, nil, fmt.Errorf("the keychain (%s) is not in the search list, it can be given only alone with --identity-keychain, or add it to the search list (security list-keychains -d user -s)", outside[0])
+	}
+	log.Printf("Searching the identities of the keychain outside the search list: %s", outside[0])
+	// the search is restricted to the keychain, it is not filtered by path: the reported path may differ (e.g. a symlink)
+	return osxkeychain.NewKeychain(outside[0]), nil, nil
+}
+
+// identityTypes returns the certificate types of the --identity-type flag
+func identityTypes(identityType string) ([]osxkeychain.CertificateType, error) {
+	switch identityType {
+	case "":
+		return nil, nil
+	case "development":
+		return osxkeychain.DevelopmentCertificateTypes, nil
+	case "distribution":
+		return osxkeychain.DistributionCertificateTypes, nil
+	case "developer-id":
+		return osxkeychain.DeveloperIDCertificateTypes, nil
+	}
+	return nil, fmt.Errorf("invalid value for --identity-type flag (%s). Valid values: 'development', 'distribution', 'developer-id'", identityType)
+}
+
+// keyAccessApplications returns the applications --authorize-key-access adds to the access control list of the private keys:
+// the running codesigndoc binary and the codesign tool
+func keyAccessApplications() ([]string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the path of codesigndoc, error: %s", err)
+	}
+	if executable, err = filepath.EvalSymlinks(executable); err != nil {
+		return nil, fmt.Errorf("failed to get the path of codesigndoc, error: %s", err)
+	}
+	return []string{executable, osxkeychain.CodesignPath}, nil
+}
+
+// printDryRun prints the plan of the export instead of exporting the code signing files
+func printDryRun(certificates []certificateutil.CertificateInfoModel, profiles []profileutil.ProvisioningProfileInfoModel, absOutputDir string) error {
+	plan, err := codesign.PlanCodesignFiles(scanCtx, certificates, profiles, identityExportConfig,
+		codesign.WriteFilesConfig{
+			WriteFiles:       writeFiles,
+			AbsOutputDirPath: absOutputDir,
+			Match:            matchRepoConfig,
+			Naming:           namingConfig,
+			CertificateFiles: exportCerts,
+			PerTeam:          perTeam,
+		},
+		codesign.UploadConfig{
+			PersonalAccessToken: personalAccessToken,
+			AppSlug:             appSlug,
+		})
+	if err != nil {
+		return err
+	}
+	if paramSaveSelection != "" {
+		if err := saveIdentitySelection(plan.Selection); err != nil {
+			return err
+		}
+	}
+
+	if paramFormat == "json" {
+		var files []report.PlannedFile
+		for _, file := range plan.Files {
+			files = append(files, report.PlannedFile{Path: file.Path, Size: file.Size, Estimated: file.Estimated})
+		}
+		result := report.NewDryRunResult(plan.Certificates, plan.Profiles, files, plan.Upload)
+		report.AddProfileMatches(result.Profiles, profileMatches())
+		report.AddProfileDevices(result.Profiles, testDevices, redactDevices)
+		result.AddExpiryWarnings(expiryWarningDays, time.Now())
+		if !certificatesOnly {
+			result.AddPairingProblems()
+		}
+		result.Schemes = scannedSchemes
+		result.AddTeams(nil)
+		if err := report.WriteDryRunResult(result, paramFormatOutput); err != nil {
+			return err
+		}
+		return pairingError(result.PairingProblems)
+	}
+	codesign.PrintExportPlan(plan)
+
+	result := report.NewDryRunResult(plan.Certificates, plan.Profiles, nil, plan.Upload)
+	result.AddExpiryWarnings(expiryWarningDays, time.Now())
+	printExpiryWarnings(result.Expiring)
+	report.AddProfileDevices(result.Profiles, testDevices, redactDevices)
+	printProfileDevices(result.Profiles)
+	if !certificatesOnly {
+		result.AddPairingProblems()
+		printPairingProblems(result.PairingProblems)
+	}
+	return pairingError(result.PairingProblems)
+}
+
+// printProfileDevices lists the devices of the ad-hoc profiles, and the test devices they do not provision
+func printProfileDevices(profiles []report.ScanProfile) {
+	for _, profile := range profiles {
+		if profile.Devices == nil {
+			continue
+		}
+		fmt.Println()
+		log.Infof("Ad-hoc profile %s (%s) provisions %d device(s):", profile.Name, profile.UUID, profile.Devices.Count)
+		for _, udid := range profile.Devices.UDIDs {
+			log.Printf("- %s", udid)
+		}
+	}
+	for _, warning := range report.DeviceWarnings(profiles) {
+		log.Warnf("%s", warning)
+	}
+}
+
+// printExpiryWarnings highlights the expired and soon expiring identities and profiles, with the remediation
+func printExpiryWarnings(warnings []report.ExpiryWarning) {
+	if len(warnings) == 0 {
+		return
+	}
+	fmt.Println()
+	log.Warnf("%d code signing file(s) expired or expire within %d days:", len(warnings), expiryWarningDays)
+	for _, warning := range warnings {
+		log.Warnf("- %s", warning)
+		log.Printf("  %s", warning.Remediation)
+	}
+}
+
+// printPairingProblems lists the exported identities and profiles without their pair
+func printPairingProblems(problems []report.PairingProblem) {
+	if len(problems) == 0 {
+		return
+	}
+	fmt.Println()
+	log.Warnf("%d exported code signing file(s) do not pair up:", len(problems))
+	for _, problem := range problems {
+		log.Warnf("- %s", problem)
+	}
+}
+
+// pairingError fails the scan with --require-pairing if the exported files are incomplete
+func pairingError(problems []report.PairingProblem) error {
+	if !requirePairing {
+		return nil
+	}
+	incomplete := 0
+	for _, problem := range problems {
+		if problem.Incomplete() {
+			incomplete++
+		}
+	}
+	if incomplete > 0 {
+		return fmt.Errorf("%d exported identity or profile file(s) have no pair in the export, the exported files are incomplete", incomplete)
+	}
+	return nil
+}
+
+// profileMatches returns the ranking of the profiles chosen during the scan
+func profileMatches() []report.ProfileMatch {
+	var matches []report.ProfileMatch
+	for _, match := range codesign.ProfileSelections() {
+		matches = append(matches, report.ProfileMatch{
+			ProfileUUID: match.Profile.UUID,
+			BundleID:    match.BundleID,
+			Kind:        match.Kind,
+			Rank:        match.Rank,
+			Candidates:  match.Candidates,
+			Reason:      match.Reason,
+		})
+	}
+	return matches
+}
+
+// newScanResult describes the export with the choices made during the scan
+func newScanResult(exportResult codesign.ExportReport) report.ScanResult {
+	result := report.NewScanResult(exportResult.Certificates, exportResult.Profiles, exportResult.Files)
+	report.AddProfileMatches(result.Profiles, profileMatches())
+	report.AddProfileDevices(result.Profiles, testDevices, redactDevices)
+	report.AddIdentityKeychains(result.Identities, codesign.IdentityKeychains())
+	result.AddExpiryWarnings(expiryWarningDays, time.Now())
+	if !certificatesOnly {
+		result.AddPairingProblems()
+	}
+	result.Schemes = scannedSchemes
+	result.AddTeams(teamOutputDirs(exportResult.Teams))
+	result.CertificatesUploaded = exportResult.CertificatesUploaded
+	result.ProfilesUploaded = exportResult.ProvisioningProfilesUploaded
+	return result
+}
+
+// teamOutputDirs returns the output directories of the teams exported separately with --per-team, by team ID
+func teamOutputDirs(teams []codesign.TeamExportReport) map[string]string {
+	if len(teams) == 0 {
+		return nil
+	}
+	dirs := map[string]string{}
+	for _, team := range teams {
+		if team.CodesignFilesWritten {
+			dirs[team.TeamID] = team.AbsOutputDirPath
+		}
+	}
+	return dirs
+}
+
+// printTeams lists the written files of the teams exported separately with --per-team
+func printTeams(teams []codesign.TeamExportReport) {
+	if len(teams) == 0 {
+		return
+	}
+	fmt.Println()
+	log.Infof("Files per team (%d):", len(teams))
+	for _, team := range teams {
+		name := codesign.TeamDirName(team.TeamID)
+		if team.TeamName != "" {
+			name = fmt.Sprintf("%s (%s)", team.TeamName, name)
+		}
+		log.Printf("- %s: %d identities, %d profiles, %d files in %s", name, len(team.Certificates), len(team.Profiles), len(team.Files), team.AbsOutputDirPath)
+	}
+}
+
+func printFinished(exportResult codesign.ExportReport, absOutputDir string) error {
+	snippetFiles := bitriseconfig.SnippetFiles{
+		Certificates: exportResult.Certificates,
+		Profiles:     exportResult.Profiles,
+		Uploaded:     exportResult.CertificatesUploaded && (exportResult.ProvisioningProfilesUploaded || len(exportResult.Profiles) == 0),
+		Dir:          absOutputDir,
+	}
+	if !encryptOutput {
+		// the encrypted files can not be referred to in the repository
+		snippetFiles.Files = exportResult.Files
+	}
+	snippet := bitriseconfig.Snippet(snippetFiles)
+	if paramFormat == "json" {
+		result := newScanResult(exportResult)
+		if len(exportResult.Certificates) > 0 || len(exportResult.Profiles) > 0 {
+			result.BitriseYMLSnippet = snippet
+		}
+		if err := report.WriteScanResult(result, paramFormatOutput); err != nil {
+			log.Errorf("Failed to write the scan result: %s", err)
+		}
+	}
+	if paramHTMLReport != "" {
+		writeHTMLReport(exportResult)
+	}
+
+	var uploadErr error
+	if encryptOutput {
+		pth, err := encryptStagedOutput(exportResult)
+		if err != nil {
+			return err
+		}
+		uploadErr = uploadToCloud(filepath.Dir(pth), []string{pth})
+	} else if exportResult.CodesignFilesWritten {
+		uploadErr = uploadToCloud(absOutputDir, exportResult.Files)
+
 		fmt.Println()
 		log.Successf("Exports finished you can find the exported files at: %s", absOutputDir)
 
@@ -138,6 +831,31 @@ func printFinished(exportResult codesign.ExportReport, absOutputDir string) {
 		} else {
 			fmt.Println("Opened the directory in Finder.")
 		}
+
+		printTeams(exportResult.Teams)
+
+		if splitExport {
+			fmt.Println()
+			if err := splitDir(absOutputDir, filepath.Join(filepath.Dir(absOutputDir), "codesigndoc_bundle"), 1024, "notes"); err != nil {
+				log.Errorf("Failed to package the exported files: %s", err)
+			}
+		}
+	}
+
+	if len(exportResult.Certificates) > 0 || len(exportResult.Profiles) > 0 {
+		fmt.Println()
+		log.Infof("bitrise.yml steps installing the exported files:")
+		fmt.Println(snippet)
+	}
+
+	result := report.NewScanResult(exportResult.Certificates, exportResult.Profiles, nil)
+	result.AddExpiryWarnings(expiryWarningDays, time.Now())
+	printExpiryWarnings(result.Expiring)
+	report.AddProfileDevices(result.Profiles, testDevices, redactDevices)
+	printProfileDevices(result.Profiles)
+	if !certificatesOnly {
+		result.AddPairingProblems()
+		printPairingProblems(result.PairingProblems)
 	}
 
 	fmt.Println()
@@ -145,9 +863,129 @@ func printFinished(exportResult codesign.ExportReport, absOutputDir string) {
 
 	if !exportResult.ProvisioningProfilesUploaded && !exportResult.CertificatesUploaded {
 		log.Warnf("You just have to upload the found certificates (.p12) and provisioning profiles (.mobileprovision) and you'll be good to go!")
+		log.Printf("The exported files can be uploaded to Bitrise with: codesigndoc upload")
 		fmt.Println()
 	} else if !exportResult.CertificatesUploaded {
 		log.Warnf("You just have to upload the found certificates (.p12) and you'll be good to go!")
+		log.Printf("The exported files can be uploaded to Bitrise with: codesigndoc upload")
 		fmt.Println()
 	}
+	if uploadErr != nil {
+		return uploadErr
+	}
+	return pairingError(result.PairingProblems)
+}
+
+// writeHTMLReport writes the --html-report summary of the export
+func writeHTMLReport(exportResult codesign.ExportReport) {
+	result := newScanResult(exportResult)
+	if encryptOutput {
+		// the listed paths are in the removed staging directory
+		result.Files = nil
+	}
+
+	var warnings []string
+	if !exportResult.CertificatesUploaded {
+		warnings = append(warnings, "The certificates are not uploaded to bitrise.io")
+	}
+	if len(exportResult.Profiles) > 0 && !exportResult.ProvisioningProfilesUploaded {
+		warnings = append(warnings, "The provisioning profiles are not uploaded to bitrise.io")
+	}
+
+	if err := report.WriteHTMLReport(result, warnings, paramHTMLReport); err != nil {
+		log.Errorf("Failed to write the HTML report: %s", err)
+		return
+	}
+	fmt.Println()
+	log.Donef("HTML report written to: %s", paramHTMLReport)
+}
+
+// stageEncryptedOutput creates the temporary export directory of --encrypt-output
+func stageEncryptedOutput() (string, error) {
+	if stagingOutputDir != "" {
+		return stagingOutputDir, nil
+	}
+	dir, err := ioutil.TempDir("", "codesigndoc_exports")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary export directory, error: %s", err)
+	}
+	stagingOutputDir = dir
+	return dir, nil
+}
+
+// removeStagedOutput removes the unencrypted files of --encrypt-output
+func removeStagedOutput() {
+	if stagingOutputDir == "" {
+		return
+	}
+	if err := os.RemoveAll(stagingOutputDir); err != nil {
+		log.Errorf("Failed to remove the temporary export directory (%s): %s", stagingOutputDir, err)
+	}
+	stagingOutputDir = ""
+}
+
+// encryptStagedOutput encrypts the temporary export directory with the json scan result into <output-dir>.tar.gz.gpg, and returns its path
+func encryptStagedOutput(exportResult codesign.ExportReport) (string, error) {
+	result := newScanResult(exportResult)
+	if err := report.WriteScanResult(result, filepath.Join(stagingOutputDir, "scan_result.json")); err != nil {
+		log.Errorf("Failed to write the scan result: %s", err)
+	}
+	pth, err := encryptStagingDir()
+	if err != nil {
+		return "", err
+	}
+	fmt.Println()
+	log.Successf("Exports finished, the exported files are encrypted into: %s", pth)
+	log.Printf("Decrypt them with: codesigndoc bundle decrypt %s, or: gpg --decrypt %s | tar xz", pth, pth)
+	return pth, nil
+}
+
+// encryptStagingDir encrypts and removes the temporary export directory, it is kept if the encryption fails
+func encryptStagingDir() (string, error) {
+	absOutputDir, err := pathutil.AbsPath(paramOutputDir)
+	if err == nil {
+		pth := strings.TrimSuffix(absOutputDir, string(filepath.Separator)) + ".tar.gz.gpg"
+		if err = bundle.Encrypt(stagingOutputDir, encryptPassphrase, pth); err == nil {
+			removeStagedOutput()
+			return pth, nil
+		}
+	}
+
+	log.Warnf("The unencrypted exported files are kept in: %s, remove them once they are moved to a safe place", stagingOutputDir)
+	stagingOutputDir = ""
+	return "", fmt.Errorf("failed to encrypt the exported files, error: %s", err)
+}
+
+// encryptFailedOutput encrypts the files exported before the command failed, e.g. the files written before a failed upload,
+// so they are not lost with the temporary export directory
+func encryptFailedOutput() {
+	if stagingOutputDir == "" {
+		return
+	}
+	infos, err := ioutil.ReadDir(stagingOutputDir)
+	if err != nil || len(infos) == 0 || encryptPassphrase == "" {
+		removeStagedOutput()
+		return
+	}
+	pth, err := encryptStagingDir()
+	if err != nil {
+		log.Errorf("%s", err)
+		return
+	}
+	fmt.Println()
+	log.Warnf("The files exported before the failure are encrypted into: %s", pth)
+}
+
+// uploadToCloud uploads the files to the --upload-to bucket, if it is set
+func uploadToCloud(dir string, files []string) error {
+	if cloudUploader == nil {
+		return nil
+	}
+	fmt.Println()
+	log.Infof("Uploading %d file(s) to %s", len(files), uploadTo)
+	if _, err := cloudstorage.UploadFiles(cloudUploader, dir, files); err != nil {
+		return exitcode.Wrap(exitcode.UploadFailed, fmt.Errorf("failed to upload the exported files to %s, error: %s", uploadTo, err))
+	}
+	log.Donef("The exported files are uploaded to %s", uploadTo)
+	return nil
 }
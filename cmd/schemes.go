@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/report"
+	"github.com/bitrise-io/codesigndoc/xcode"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/sliceutil"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// scannedSchemes is the per scheme breakdown of a multi-scheme scan, for the scan result
+var scannedSchemes []report.SchemeFiles
+
+// scanXcodeSchemes archives the schemes of the --scheme flags (or every shared scheme with --all-schemes) in every configuration
+// of the --configuration flags, and returns the union of the identities and profiles they need.
+// A scheme which fails to archive is skipped with a warning.
+func scanXcodeSchemes(absExportOutputDirPath string, managedExportMethod exportoptions.Method) ([]certificateutil.CertificateInfoModel, []profileutil.ProvisioningProfileInfoModel, error) {
+	xcodeCmd, fastlaneConfig, err := openXcodeProject()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var schemes []string
+	if paramAllSchemes {
+		if schemes, err = sharedXcodeSchemes(xcodeCmd); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		requestedSchemes := paramXcodeSchemes
+		if len(requestedSchemes) == 0 {
+			requestedSchemes = []string{""}
+		}
+		for _, requested := range requestedSchemes {
+			scheme, err := selectXcodeScheme(xcodeCmd, fastlaneConfig, requested)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !sliceutil.IsStringInSlice(scheme, schemes) {
+				schemes = append(schemes, scheme)
+			}
+		}
+	}
+
+	var certificates []certificateutil.CertificateInfoModel
+	var profiles []profileutil.ProvisioningProfileInfoModel
+	scannedSchemes = nil
+	configurations := paramXcodeConfigurations
+	if len(configurations) == 0 {
+		configurations = []string{""}
+	}
+
+	scanned := 0
+	total := len(schemes) * len(configurations)
+	for _, scheme := range schemes {
+		for _, configuration := range configurations {
+			files := report.SchemeFiles{Scheme: scheme, Configuration: configuration, Identities: []string{}, Profiles: []string{}}
+			fmt.Println()
+			log.Infof("Scanning %d/%d: %s", len(scannedSchemes)+1, total, files.Label())
+
+			schemeCertificates, schemeProfiles, err := scanXcodeScheme(xcodeCmd, scheme, configuration, managedExportMethod, absExportOutputDirPath)
+			if err != nil {
+				log.Warnf("Skipping %s: %s", files.Label(), err)
+				files.Error = err.Error()
+				scannedSchemes = append(scannedSchemes, files)
+				continue
+			}
+			printFastlaneConflicts(fastlaneConfig, scheme, schemeCertificates, schemeProfiles)
+			scanned++
+
+			certificates = appendMissingCertificates(certificates, schemeCertificates)
+			profiles = appendMissingProfiles(profiles, schemeProfiles)
+			files = report.NewSchemeFiles(scheme, schemeCertificates, schemeProfiles)
+			files.Configuration = configuration
+			scannedSchemes = append(scannedSchemes, files)
+		}
+	}
+
+	printSchemeBreakdown(scannedSchemes, certificates, profiles)
+
+	if scanned == 0 {
+		return nil, nil, errors.New("none of the Schemes could be scanned")
+	}
+	return certificates, profiles, nil
+}
+
+// scanXcodeScheme archives the scheme in the configuration and collects the code signing files of the archive
+func scanXcodeScheme(xcodeCmd xcode.CommandModel, scheme, configuration string, managedExportMethod exportoptions.Method, absExportOutputDirPath string) ([]certificateutil.CertificateInfoModel, []profileutil.ProvisioningProfileInfoModel, error) {
+	archivePath, err := archiveXcodeScheme(xcodeCmd, scheme, configuration, absExportOutputDirPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return collectArchiveFiles(archivePath, managedExportMethod, absExportOutputDirPath)
+}
+
+// sharedXcodeSchemes returns the shared schemes of the project, the user schemes are not available on CI
+func sharedXcodeSchemes(xcodeCmd xcode.CommandModel) ([]string, error) {
+	fmt.Println()
+	log.Printf("🔦  Scanning Schemes ...")
+	schemes, err := xcodeCmd.ListSchemes()
+	if err != nil {
+		return nil, ArchiveError{toolXcode, "failed to scan Schemes: " + err.Error()}
+	}
+
+	var shared, user []string
+	for _, scheme := range schemes {
+		if scheme.Shared {
+			shared = append(shared, scheme.Name)
+		} else {
+			user = append(user, scheme.Name)
+		}
+	}
+	if len(user) > 0 {
+		log.Warnf("Skipping the user Schemes, they are not available on CI: %s", strings.Join(user, ", "))
+	}
+	if len(shared) == 0 {
+		return nil, ArchiveError{toolXcode, "no shared schemes found"}
+	}
+	log.Printf("Shared Schemes: %s", strings.Join(shared, ", "))
+	return shared, nil
+}
+
+// printSchemeBreakdown lists the identities and profiles needed by each scheme
+func printSchemeBreakdown(schemes []report.SchemeFiles, certificates []certificateutil.CertificateInfoModel, profiles []profileutil.ProvisioningProfileInfoModel) {
+	identityNames := map[string]string{}
+	for _, certificate := range certificates {
+		identityNames[certificate.SHA1Fingerprint] = certificate.CommonName
+	}
+	profileNames := map[string]string{}
+	for _, profile := range profiles {
+		profileNames[profile.UUID] = profile.Name
+	}
+
+	fmt.Println()
+	log.Infof("Code signing files per Scheme and configuration, %d identities and %d profiles in total:", len(certificates), len(profiles))
+	for _, scheme := range schemes {
+		if scheme.Error != "" {
+			log.Warnf("%s: not scanned", scheme.Label())
+			continue
+		}
+		log.Printf("%s:", scheme.Label())
+		for _, fingerprint := range scheme.Identities {
+			log.Printf("- identity: %s [%s]", identityNames[fingerprint], fingerprint)
+		}
+		for _, uuid := range scheme.Profiles {
+			log.Printf("- profile: %s (%s)", profileNames[uuid], uuid)
+		}
+	}
+}
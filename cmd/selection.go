@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/spf13/cobra"
+)
+
+// selectionCmd represents the selection command
+var selectionCmd = &cobra.Command{
+	Use:   "selection <selection.json>",
+	Short: "Export the identities saved by a dry run with --save-selection",
+	Long: `Export the identities saved by a dry run with --save-selection
+
+The identities are resolved by their keychain persistent references instead of searching them by label again,
+so exactly the identities listed by the dry run (e.g. approved by a reviewer) are exported into the Identities.p12.
+An identity which was deleted or replaced since the selection fails the export, create a new selection then.
+The selection is only valid on the machine it was created on.`,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          scanSelection,
+}
+
+func init() {
+	scanCmd.AddCommand(selectionCmd)
+	features.Register(features.Feature{Name: scannerFeaturePrefix + "selection", Kind: features.Scanner, Description: "Export the identities saved by a dry run with --save-selection"})
+}
+
+// saveIdentitySelection writes the identities found by the dry run into the --save-selection file
+func saveIdentitySelection(selection codesign.IdentitySelection) error {
+	if len(selection.Identities) == 0 {
+		return errors.New("no identity is required by the scan, nothing to save with --save-selection")
+	}
+	if err := codesign.WriteIdentitySelection(selection, paramSaveSelection); err != nil {
+		return err
+	}
+	log.Donef("The %d identities found are saved to: %s, export them with: codesigndoc scan selection %s", len(selection.Identities), paramSaveSelection, paramSaveSelection)
+	return nil
+}
+
+func scanSelection(_ *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("the selection file is required")
+	}
+	if dryRun {
+		return fmt.Errorf("the selection is created by a dry run, it can not be exported with --dry-run")
+	}
+	if perTeam {
+		return fmt.Errorf("the selection is exported into a single .p12, it can not be exported with --per-team")
+	}
+
+	selection, err := codesign.ReadIdentitySelection(args[0])
+	if err != nil {
+		return err
+	}
+	absExportOutputDirPath, err := absOutputDir()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	log.Infof("Exporting the selected Identities (%d), selected at: %s", len(selection.Identities), selection.CreatedAt.Format("2006-01-02 15:04:05"))
+	exportResult, err := codesign.ExportUploadAndWriteIdentitySelection(scanCtx, selection,
+		identityExportConfig,
+		codesign.WriteFilesConfig{
+			WriteFiles:       writeFiles,
+			AbsOutputDirPath: absExportOutputDirPath,
+			Match:            matchRepoConfig,
+			Naming:           namingConfig,
+			CertificateFiles: exportCerts,
+		},
+		codesign.UploadConfig{
+			PersonalAccessToken: personalAccessToken,
+			AppSlug:             appSlug,
+			RollbackOnFailure:   uploadRollback,
+			SecretStore:         secretStore,
+		})
+	if err != nil {
+		return err
+	}
+	return printFinished(exportResult, absExportOutputDirPath)
+}
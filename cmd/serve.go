@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/service"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/spf13/cobra"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the scans over a localhost HTTP API",
+	Long: `Serve the scans over a localhost HTTP API
+
+GUI wrappers and onboarding flows can start the scans and read their json results and logs
+instead of parsing the console output. Every request needs the token in an "Authorization: Bearer <token>" header,
+the token is read from the ` + service.TokenEnvKey + ` env var or generated and printed at start.
+
+  GET    /v1/scanners    lists the available scanners
+  POST   /v1/scans       starts a scan: {"scanner": "xcode", "args": ["--file", "App.xcworkspace"], "passphrase": "..."}
+  GET    /v1/scans       lists the scans
+  GET    /v1/scans/<id>  returns the state, the exit code, the log and the json result of a scan
+  DELETE /v1/scans/<id>  cancels a running scan
+
+One scan runs at a time, non-interactively: the answers have to be given as flags in the args.
+The Keychain prompts of the export are still shown on the machine.`,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          serve,
+}
+
+var paramServeAddress string
+
+func init() {
+	RootCmd.AddCommand(serveCmd)
+	features.Register(features.Feature{Name: "tool/serve", Kind: features.Tool, Description: "Serve the scans over a localhost HTTP API"})
+
+	serveCmd.Flags().StringVar(&paramServeAddress, "address", service.DefaultAddress, "Listen address of the API, it has to be a loopback address")
+}
+
+func serve(_ *cobra.Command, _ []string) error {
+	if err := features.CheckEnabled("tool/serve"); err != nil {
+		return err
+	}
+	if err := service.CheckLocalAddress(paramServeAddress); err != nil {
+		return err
+	}
+
+	token := os.Getenv(service.TokenEnvKey)
+	generated := token == ""
+	if generated {
+		var err error
+		if token, err = service.NewToken(); err != nil {
+			return err
+		}
+	}
+
+	scanners := servedScanners()
+	server := service.New(token, scanners, passphraseEnvKey, runCodesigndoc)
+	httpServer := &http.Server{Addr: paramServeAddress, Handler: server.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		server.Cancel()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(ctx); err != nil {
+			log.Errorf("Failed to stop the API: %s", err)
+		}
+	}()
+
+	log.Infof("Serving the codesigndoc API on http://%s", paramServeAddress)
+	log.Printf("Scanners: %s", strings.Join(scanners, ", "))
+	if generated {
+		log.Printf("Token: %s (set the %s env var to use a fixed token)", token, service.TokenEnvKey)
+	}
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve the API, error: %s", err)
+	}
+	log.Donef("The API is stopped")
+	return nil
+}
+
+// servedScanners returns the enabled scan subcommands
+func servedScanners() []string {
+	var scanners []string
+	for _, feature := range features.List() {
+		if feature.Kind == features.Scanner && strings.HasPrefix(feature.Name, scannerFeaturePrefix) && features.IsEnabled(feature.Name) {
+			scanners = append(scanners, strings.TrimPrefix(feature.Name, scannerFeaturePrefix))
+		}
+	}
+	return scanners
+}
+
+// runCodesigndoc runs the scan of the API in a separate codesigndoc process, so the flags and state of the scans are not shared
+func runCodesigndoc(ctx context.Context, run service.Run) (int, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return -1, fmt.Errorf("failed to find the codesigndoc executable, error: %s", err)
+	}
+
+	cmd := exec.CommandContext(ctx, executable, run.Args...)
+	cmd.Env = append(os.Environ(), run.Env...)
+	cmd.Stdout = run.Log
+	cmd.Stderr = run.Log
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return -1, fmt.Errorf("failed to run the scan, error: %s", err)
+	}
+	return 0, nil
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/bitrise-io/codesigndoc/bitriseconfig"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/spf13/cobra"
+)
+
+// simulateCmd represents the simulate command
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Simulate which signing files the steps of a bitrise.yml would select",
+	Long: `Simulate which signing files the steps of a bitrise.yml would select
+
+The exported code signing files (.p12 and provisioning profiles) are matched against the
+signing steps of the given workflows the same way the steps select them on CI,
+and every gap (nothing matches) or ambiguity (more than one file matches) is reported.`,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          simulate,
+}
+
+var (
+	paramSimulateConfigPath string
+	paramSimulateAssetsDir  string
+	paramSimulateP12Pass    string
+	paramSimulateWorkflows  []string
+)
+
+func init() {
+	RootCmd.AddCommand(simulateCmd)
+	features.Register(features.Feature{Name: "tool/simulate", Kind: features.Tool, Description: "Simulate the signing file selection of a bitrise.yml"})
+
+	simulateCmd.Flags().StringVar(&paramSimulateConfigPath, "config", "bitrise.yml", "bitrise.yml file path")
+	simulateCmd.Flags().StringVar(&paramSimulateAssetsDir, "dir", "./codesigndoc_exports", "Directory containing the exported code signing files")
+	simulateCmd.Flags().StringVar(&paramSimulateP12Pass, "p12-pass", "", "Passphrase of the exported .p12 files")
+	simulateCmd.Flags().StringSliceVar(&paramSimulateWorkflows, "workflow", nil, "Workflow to simulate, can be specified multiple times. Defaults to every workflow")
+}
+
+func simulate(_ *cobra.Command, _ []string) error {
+	if err := features.CheckEnabled("tool/simulate"); err != nil {
+		return err
+	}
+
+	config, err := bitriseconfig.NewConfigFromFile(paramSimulateConfigPath)
+	if err != nil {
+		return err
+	}
+
+	assets, err := bitriseconfig.LoadAssetsFromDir(paramSimulateAssetsDir, paramSimulateP12Pass)
+	if err != nil {
+		return err
+	}
+	log.Infof("Signing files: %d certificate(s), %d provisioning profile(s)", len(assets.Certificates), len(assets.Profiles))
+
+	findings, err := bitriseconfig.Simulate(config, assets, paramSimulateWorkflows...)
+	if err != nil {
+		return err
+	}
+
+	if len(findings) == 0 {
+		fmt.Println()
+		log.Warnf("No code signing step found in the simulated workflows.")
+		return nil
+	}
+
+	gaps := 0
+	lastHeader := ""
+	for _, finding := range findings {
+		if header := finding.WorkflowID + " > " + finding.StepID; header != lastHeader {
+			fmt.Println()
+			log.Infof(header)
+			lastHeader = header
+		}
+
+		switch finding.Severity {
+		case bitriseconfig.SeverityGap:
+			gaps++
+			fmt.Printf("%s %s\n", colorstring.Red("gap:"), finding.Message)
+		case bitriseconfig.SeverityAmbiguous:
+			fmt.Printf("%s %s\n", colorstring.Yellow("ambiguous:"), finding.Message)
+		default:
+			fmt.Printf("%s %s\n", colorstring.Green("ok:"), finding.Message)
+		}
+	}
+
+	fmt.Println()
+	if gaps > 0 {
+		return fmt.Errorf("%d signing gap(s) found, the affected steps will fail on CI", gaps)
+	}
+	log.Successf("Every signing step will find its signing files.")
+	return nil
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/bitrise-io/codesigndoc/confirm"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/state"
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/spf13/cobra"
+)
+
+// stateCmd represents the state command
+var stateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "Manage the machine local codesigndoc state directory",
+	Long: `Manage the machine local codesigndoc state directory
+
+The state directory (~/.codesigndoc by default, can be changed with the ` + state.DirEnvKey + ` env var)
+stores the configuration and the registries of codesigndoc. Its schema is versioned and migrated automatically
+when a newer codesigndoc runs.`,
+}
+
+var stateDoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Detect and repair corrupt state files and pending migrations",
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          stateDoctor,
+}
+
+var paramStateDoctorFix bool
+
+func init() {
+	RootCmd.AddCommand(stateCmd)
+	stateCmd.AddCommand(stateDoctorCmd)
+	features.Register(features.Feature{Name: "tool/state-doctor", Kind: features.Tool, Description: "Detect and repair corrupt state files"})
+
+	stateDoctorCmd.Flags().BoolVar(&paramStateDoctorFix, "fix", false, "Repair the problems which can be fixed automatically, corrupt files are moved aside with a .corrupt-<timestamp> suffix")
+}
+
+// migrateState applies the pending state migrations before every command,
+// a failed migration is reported but does not stop the command, the doctor can repair the state later.
+func migrateState() {
+	applied, err := state.Migrate()
+	for _, migration := range applied {
+		log.Debugf("State migrated from version %d: %s", migration.From, migration.Description)
+	}
+	if err != nil {
+		log.Warnf("%s", err)
+		log.Warnf("Run 'codesigndoc state doctor' to check the state directory.")
+	}
+}
+
+func stateDoctor(_ *cobra.Command, _ []string) error {
+	if err := features.CheckEnabled("tool/state-doctor"); err != nil {
+		return err
+	}
+
+	log.Infof("Checking the state directory: %s", state.Dir())
+	problems, err := state.Diagnose()
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		log.Successf("No problem found.")
+		return nil
+	}
+
+	manual := 0
+	plan := confirm.NewPlan("Repair the state directory")
+	var repairable []state.Problem
+	for _, problem := range problems {
+		fmt.Println()
+		fmt.Printf("%s %s\n", colorstring.Yellow("problem:"), problem.File)
+		log.Printf("%s", problem.Description)
+		if !problem.Repairable() {
+			manual++
+			log.Printf("%s", colorstring.Red("has to be fixed manually"))
+			continue
+		}
+		log.Printf("fix: %s", problem.Repair)
+		plan.Add(problem.Repair, problem.File, problem.Description)
+		repairable = append(repairable, problem)
+	}
+
+	fmt.Println()
+	if !paramStateDoctorFix {
+		return fmt.Errorf("%d problem(s) found, run 'codesigndoc state doctor --fix' to repair them", len(problems))
+	}
+
+	approved, err := plan.Confirm()
+	if err != nil {
+		return err
+	}
+	if !approved {
+		return fmt.Errorf("repair cancelled, %d problem(s) left", len(problems))
+	}
+
+	fmt.Println()
+	for _, problem := range repairable {
+		if err := problem.Fix(); err != nil {
+			manual++
+			log.Errorf("Failed to %s (%s): %s", problem.Repair, problem.File, err)
+			continue
+		}
+		log.Donef("fixed: %s (%s)", problem.Repair, problem.File)
+	}
+
+	fmt.Println()
+	if manual > 0 {
+		return fmt.Errorf("%d problem(s) have to be fixed manually", manual)
+	}
+	log.Successf("The state directory is repaired.")
+	return nil
+}
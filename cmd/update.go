@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/bitrise-io/codesigndoc/confirm"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/network"
+	"github.com/bitrise-io/codesigndoc/selfupdate"
+	"github.com/bitrise-io/codesigndoc/version"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/spf13/cobra"
+)
+
+// updateCmd represents the update command
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update codesigndoc to the latest release",
+	Long: `Update codesigndoc to the latest release
+
+The latest GitHub release is downloaded, its SHA-256 checksum is verified against the checksum published with the release,
+then the running binary is replaced. A binary installed by Homebrew is not replaced, run brew upgrade codesigndoc instead.`,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          update,
+}
+
+var paramUpdateCheckOnly bool
+
+func init() {
+	RootCmd.AddCommand(updateCmd)
+	features.Register(features.Feature{Name: "tool/update", Kind: features.Tool, Description: "Update codesigndoc to the latest release"})
+
+	updateCmd.Flags().BoolVar(&paramUpdateCheckOnly, "check", false, "Only check whether a newer release is available")
+}
+
+func update(_ *cobra.Command, _ []string) error {
+	if err := features.CheckEnabled("tool/update"); err != nil {
+		return err
+	}
+
+	client, err := network.NewHTTPClient()
+	if err != nil {
+		return err
+	}
+	release, err := selfupdate.LatestRelease(client, selfupdate.LatestReleaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to check the latest release, error: %s", network.DescribeError(err))
+	}
+
+	fmt.Println()
+	log.Printf("current version: %s", version.VERSION)
+	log.Printf("latest version: %s %s", release.Version(), release.HTMLURL)
+	newer, err := selfupdate.IsNewer(version.VERSION, release.Version())
+	if err != nil {
+		return err
+	}
+	if !newer {
+		log.Donef("codesigndoc is up to date.")
+		return nil
+	}
+	if paramUpdateCheckOnly {
+		log.Warnf("A newer release is available, update with: codesigndoc update")
+		return nil
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the running binary, error: %s", err)
+	}
+	if exePath, err = filepath.EvalSymlinks(exePath); err != nil {
+		return fmt.Errorf("failed to resolve the running binary (%s), error: %s", exePath, err)
+	}
+	if selfupdate.IsHomebrewInstall(exePath) {
+		return fmt.Errorf("codesigndoc is installed by Homebrew (%s), update it with: brew upgrade codesigndoc", exePath)
+	}
+	if runtime.GOOS != "darwin" {
+		return fmt.Errorf("the released binary (%s) runs on macOS only", selfupdate.AssetName)
+	}
+
+	asset, ok := release.Asset(selfupdate.AssetName)
+	if !ok {
+		return fmt.Errorf("the release %s has no %s asset", release.TagName, selfupdate.AssetName)
+	}
+	checksumAsset, ok := release.Asset(selfupdate.ChecksumAssetName)
+	if !ok {
+		return fmt.Errorf("the release %s has no %s asset, the download can not be verified", release.TagName, selfupdate.ChecksumAssetName)
+	}
+
+	plan := confirm.NewPlan("Update codesigndoc")
+	plan.Add("replace", exePath, fmt.Sprintf("%s -> %s", version.VERSION, release.Version()))
+	if ok, err := plan.Confirm(); err != nil {
+		return err
+	} else if !ok {
+		log.Warnf("Update cancelled.")
+		return nil
+	}
+
+	checksumContent, err := selfupdate.DownloadString(client, checksumAsset.DownloadURL)
+	if err != nil {
+		return fmt.Errorf("failed to download the checksum, error: %s", network.DescribeError(err))
+	}
+	expected, err := selfupdate.ParseChecksum(checksumContent, selfupdate.AssetName)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Downloading %s ...", asset.DownloadURL)
+	// the binary is downloaded next to the running one, so it can be renamed over it
+	downloaded, checksum, err := selfupdate.Download(client, asset.DownloadURL, filepath.Dir(exePath))
+	if err != nil {
+		return fmt.Errorf("failed to download the release, error: %s", network.DescribeError(err))
+	}
+	if checksum != expected {
+		if err := os.Remove(downloaded); err != nil {
+			log.Warnf("Failed to remove the download (%s): %s", downloaded, err)
+		}
+		return fmt.Errorf("the checksum of the download (%s) does not match the published one (%s)", checksum, expected)
+	}
+	log.Donef("checksum verified: %s", checksum)
+
+	if err := selfupdate.Replace(exePath, downloaded); err != nil {
+		if rerr := os.Remove(downloaded); rerr != nil {
+			log.Warnf("Failed to remove the download (%s): %s", downloaded, rerr)
+		}
+		return err
+	}
+
+	fmt.Println()
+	log.Successf("Updated codesigndoc to %s: %s", release.Version(), exePath)
+	return nil
+}
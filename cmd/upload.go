@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bitrise-io/codesigndoc/cloudstorage"
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/exitcode"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/logging"
+	"github.com/bitrise-io/codesigndoc/secretstore"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/spf13/cobra"
+)
+
+// uploadCmd represents the upload command
+var uploadCmd = &cobra.Command{
+	Use:   "upload",
+	Short: "Upload previously exported code signing files to Bitrise",
+	Long: `Upload previously exported code signing files to Bitrise
+
+The .p12 and provisioning profile files of the export directory are uploaded to the code signing file storage of a Bitrise app,
+the same way as at the end of a scan. The app and the token are asked interactively, if the --auth-token and --app-slug flags are not set.
+
+With --to, every file of the export directory is uploaded to the given bucket instead: s3://<bucket>/<prefix>, gs://<bucket>/<prefix>
+or azblob://<account>/<container>/<prefix>. The credentials are read from the env vars:
+- S3: ` + cloudstorage.AWSAccessKeyIDEnvKey + `, ` + cloudstorage.AWSSecretAccessKeyEnvKey + `, ` + cloudstorage.AWSSessionTokenEnvKey + ` (optional), ` + cloudstorage.AWSRegionEnvKey + ` (or --region)
+- GCS: ` + cloudstorage.GCSAccessTokenEnvKey + `, the token of gcloud auth print-access-token if not set
+- Azure Blob: ` + cloudstorage.AzureSASTokenEnvKey + ` or ` + cloudstorage.AzureAccountKeyEnvKey,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          upload,
+}
+
+var (
+	paramUploadDir       string
+	paramUploadP12Name   string
+	paramUploadP12Pass   string
+	paramUploadAuthToken string
+	paramUploadAppSlug   string
+	paramUploadRollback  bool
+	paramUploadTo        string
+	paramUploadRegion    string
+	paramUploadEndpoint  string
+)
+
+func init() {
+	RootCmd.AddCommand(uploadCmd)
+	features.Register(features.Feature{Name: "tool/upload", Kind: features.Tool, Description: "Upload previously exported code signing files to Bitrise"})
+
+	uploadCmd.Flags().StringVar(&paramUploadDir, "dir", "./codesigndoc_exports", "Directory containing the exported code signing files")
+	uploadCmd.Flags().StringVar(&paramUploadP12Name, "p12", "Identities.p12", "Name of the identities file in the directory")
+	uploadCmd.Flags().StringVar(&paramUploadP12Pass, "p12-pass", "", "Passphrase of the .p12 file")
+	uploadCmd.Flags().StringVar(&paramUploadAuthToken, authTokenFlag, "", "Bitrise personal access token. Requires the app-slug paramater to be also set.")
+	uploadCmd.Flags().StringVar(&paramUploadAppSlug, appSlugFlag, "", "Bitrise app slug. Requires the auth-token parameter to be also set.")
+	uploadCmd.Flags().BoolVar(&paramUploadRollback, "upload-rollback", true, "Remove the already uploaded codesigning files from Bitrise if any of the uploads fails")
+	uploadCmd.Flags().StringVar(&paramUploadTo, "to", "", "Upload the files to this bucket instead of Bitrise: s3://<bucket>/<prefix>, gs://<bucket>/<prefix> or azblob://<account>/<container>/<prefix>")
+	uploadCmd.Flags().StringVar(&paramUploadRegion, "region", "", "Region of the S3 bucket, the "+cloudstorage.AWSRegionEnvKey+" env var or us-east-1 if empty")
+	uploadCmd.Flags().StringVar(&paramUploadEndpoint, "endpoint", "", "Endpoint of an S3, GCS or Azure Blob compatible service (e.g. MinIO or an emulator), instead of the public one")
+}
+
+func upload(_ *cobra.Command, _ []string) error {
+	if err := features.CheckEnabled("tool/upload"); err != nil {
+		return err
+	}
+	if paramUploadTo != "" {
+		return uploadDirToCloud()
+	}
+	if err := features.CheckEnabled(codesign.BitriseDestinationFeature); err != nil {
+		return err
+	}
+	if (paramUploadAuthToken == "") != (paramUploadAppSlug == "") {
+		return fmt.Errorf("both or none flags %s and %s are required to be set", appSlugFlag, authTokenFlag)
+	}
+
+	dir, err := pathutil.AbsPath(paramUploadDir)
+	if err != nil {
+		return fmt.Errorf("failed to expand path (%s), error: %s", paramUploadDir, err)
+	}
+	certificates, profiles, err := codesign.ReadExportedFiles(dir, paramUploadP12Name, paramUploadP12Pass)
+	if err != nil {
+		return err
+	}
+	if len(certificates.Info) == 0 && len(profiles) == 0 {
+		return fmt.Errorf("no code signing files found in %s", dir)
+	}
+	log.Infof("Code signing files: %d certificate(s), %d provisioning profile(s)", len(certificates.Info), len(profiles))
+
+	result, err := codesign.UploadAndWriteCodesignFiles(certificates, profiles,
+		codesign.WriteFilesConfig{WriteFiles: codesign.WriteFilesDisabled},
+		codesign.UploadConfig{
+			PersonalAccessToken: paramUploadAuthToken,
+			AppSlug:             paramUploadAppSlug,
+			RollbackOnFailure:   paramUploadRollback,
+		})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	if !result.CertificatesUploaded || !result.ProvisioningProfilesUploaded {
+		return exitcode.Wrap(exitcode.UploadFailed, fmt.Errorf("the code signing files were not uploaded"))
+	}
+	log.Successf("The code signing files are uploaded.")
+	return nil
+}
+
+// uploadDirToCloud uploads every file of the export directory to the --to bucket
+func uploadDirToCloud() error {
+	uploader, err := newCloudUploader(paramUploadTo, paramUploadRegion, paramUploadEndpoint)
+	if err != nil {
+		return err
+	}
+
+	dir, err := pathutil.AbsPath(paramUploadDir)
+	if err != nil {
+		return fmt.Errorf("failed to expand path (%s), error: %s", paramUploadDir, err)
+	}
+	var files []string
+	if err := filepath.Walk(dir, func(pth string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			files = append(files, pth)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to list the files of %s, error: %s", dir, err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no files found in %s", dir)
+	}
+
+	fmt.Println()
+	log.Infof("Uploading %d file(s) to %s", len(files), paramUploadTo)
+	if _, err := cloudstorage.UploadFiles(uploader, dir, files); err != nil {
+		return exitcode.Wrap(exitcode.UploadFailed, err)
+	}
+	log.Successf("The files are uploaded to %s", paramUploadTo)
+	return nil
+}
+
+// newCloudUploader returns the uploader of the storage URL, with the credentials of the env vars
+func newCloudUploader(destination, region, endpoint string) (cloudstorage.Uploader, error) {
+	dest, err := cloudstorage.ParseDestination(destination)
+	if err != nil {
+		return nil, err
+	}
+	if err := features.CheckEnabled(dest.Feature()); err != nil {
+		return nil, err
+	}
+
+	config := cloudstorage.ConfigFromEnv()
+	if region != "" {
+		config.AWSRegion = region
+	}
+	config.Endpoint = endpoint
+	for _, secret := range config.Secrets() {
+		logging.AddSecret(secret)
+	}
+	return cloudstorage.New(dest, config)
+}
+
+// newSecretStore returns the store of the secret URL, with the credentials of the env vars
+func newSecretStore(location string) (secretstore.Store, error) {
+	loc, err := secretstore.ParseLocation(location)
+	if err != nil {
+		return nil, err
+	}
+	if err := features.CheckEnabled(loc.Feature()); err != nil {
+		return nil, err
+	}
+
+	config := cloudstorage.ConfigFromEnv()
+	for _, secret := range append(config.Secrets(), os.Getenv(secretstore.VaultTokenEnvKey)) {
+		logging.AddSecret(secret)
+	}
+	return secretstore.New(loc, config)
+}
@@ -8,9 +8,10 @@ import (
 	"github.com/bitrise-io/bitrise-init/scanners/ios"
 	"github.com/bitrise-io/bitrise-init/scanners/xamarin"
 	"github.com/bitrise-io/bitrise-init/utility"
+	"github.com/bitrise-io/codesigndoc/prompt"
+	"github.com/bitrise-io/codesigndoc/xcode"
 	"github.com/bitrise-io/go-utils/colorstring"
 	"github.com/bitrise-io/go-utils/log"
-	"github.com/bitrise-io/goinp/goinp"
 )
 
 // projectType enum.
@@ -70,6 +71,19 @@ func scanForProjectFiles(projType projectType) ([]string, error) {
 func findXcodeProject() (string, error) {
 	var projpth string
 
+	// the example apps of the Flutter plugins and the React Native libraries contain projects too, the app's own is in ios/
+	if dir, err := os.Getwd(); err != nil {
+		return "", err
+	} else if project, found, err := xcode.DetectCrossPlatformProject(dir); err != nil {
+		log.Warnf("Failed to detect a Flutter or React Native project: %s", err)
+	} else if found {
+		log.Printf("Found a %s project, scanning its iOS project: %s", project.Framework, project.ProjectPath)
+		if project.Hint != "" {
+			log.Warnf("The iOS project is not ready to build: %s", project.Hint)
+		}
+		return project.ProjectPath, nil
+	}
+
 	projPaths, err := scanForProjectFiles(iOSProjectType)
 	if err != nil {
 		log.Printf("Failed: %s", err)
@@ -79,7 +93,7 @@ func findXcodeProject() (string, error) {
 		askText := `Please drag-and-drop your Xcode Project (` + colorstring.Green(".xcodeproj") + `) or Workspace (` + colorstring.Green(".xcworkspace") + `) file, 
 the one you usually open in Xcode, then hit Enter.
 (Note: if you have a Workspace file you should most likely use that)`
-		projpth, err = goinp.AskForPath(askText)
+		projpth, err = prompt.AskForPath(askText)
 		if err != nil {
 			return "", fmt.Errorf("failed to read input: %s", err)
 		}
@@ -93,7 +107,7 @@ the one you usually open in Xcode, then hit Enter.
 	}
 
 	log.Printf("Found multiple project file: %s.", path.Base(projpth))
-	projpth, err = goinp.SelectFromStringsWithDefault("Select the project file you want to scan", 1, projPaths)
+	projpth, err = prompt.SelectFromStringsWithDefault("Select the project file you want to scan", 1, projPaths)
 	if err != nil {
 		return "", fmt.Errorf("failed to select project file: %s", err)
 	}
@@ -113,7 +127,7 @@ func findXamarinSolution() (string, error) {
 		log.Infof("Provide the solution file manually")
 		askText := `Please drag-and-drop your Xamarin Solution (` + colorstring.Green(".sln") + `) file,
 and then hit Enter`
-		solutionPth, err = goinp.AskForPath(askText)
+		solutionPth, err = prompt.AskForPath(askText)
 		if err != nil {
 			return "", fmt.Errorf("failed to read input: %s", err)
 		}
@@ -127,7 +141,7 @@ and then hit Enter`
 	}
 
 	log.Printf("Found multiple solution file: %s.", path.Base(solutionPth))
-	solutionPth, err = goinp.SelectFromStringsWithDefault("Select the solution file you want to scan", 1, solPaths)
+	solutionPth, err = prompt.SelectFromStringsWithDefault("Select the solution file you want to scan", 1, solPaths)
 	if err != nil {
 		return "", fmt.Errorf("failed to select solution file: %s", err)
 	}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/bitriseconfig"
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/spf13/cobra"
+)
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify <.ipa or .app>",
+	Short: "Check the keychain and the exported files against the signature of a built app",
+	Long: `Check the keychain and the exported files against the signature of a built app
+
+The signing certificate and the embedded provisioning profile are read from the signature of the .ipa or .app (codesign -dvvv),
+then it is checked whether the keychain contains the exact identity the app was signed with (not only one with the same name),
+and whether the exported code signing files (.p12 and provisioning profiles) can re-sign the app with it.`,
+
+	Args:          cobra.ExactArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          verify,
+}
+
+var (
+	paramVerifyDir     string
+	paramVerifyP12Pass string
+)
+
+func init() {
+	RootCmd.AddCommand(verifyCmd)
+	features.Register(features.Feature{Name: "tool/verify", Kind: features.Tool, Description: "Check the keychain and the exported files against the signature of a built app"})
+
+	verifyCmd.Flags().StringVar(&paramVerifyDir, "dir", "./codesigndoc_exports", "Directory containing the exported code signing files")
+	verifyCmd.Flags().StringVar(&paramVerifyP12Pass, "p12-pass", "", "Passphrase of the exported .p12 files")
+}
+
+func verify(_ *cobra.Command, args []string) error {
+	if err := features.CheckEnabled("tool/verify"); err != nil {
+		return err
+	}
+	if err := checkKeychainPlatform(); err != nil {
+		return err
+	}
+
+	signature, err := codesign.ReadSignature(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	log.Infof("Signature of %s:", args[0])
+	log.Printf("identifier: %s", signature.Identifier)
+	log.Printf("team: %s", signature.TeamIdentifier)
+	for _, authority := range signature.Authorities {
+		log.Printf("authority: %s", authority)
+	}
+	if signature.Certificate == nil {
+		return errors.New("the app is ad-hoc signed, it has no signing identity to verify")
+	}
+	log.Printf("signing certificate: %s [%s], expires: %s", signature.Certificate.CommonName, signature.Certificate.SHA1Fingerprint, signature.Certificate.EndDate.Format("2006-01-02"))
+	if signature.Profile != nil {
+		log.Printf("embedded profile: %s (%s), expires: %s", signature.Profile.Name, signature.Profile.UUID, signature.Profile.ExpirationDate.Format("2006-01-02"))
+	}
+
+	problems := 0
+	fmt.Println()
+	log.Infof("Keychain:")
+	if installed, err := verifyKeychainIdentity(*signature.Certificate); err != nil {
+		return err
+	} else if !installed {
+		problems++
+	}
+
+	fmt.Println()
+	log.Infof("Exported files (%s):", paramVerifyDir)
+	assets, err := bitriseconfig.LoadAssetsFromDir(paramVerifyDir, paramVerifyP12Pass)
+	if err != nil {
+		return err
+	}
+	check := codesign.CheckResign(signature, assets.Certificates, assets.Profiles, time.Now())
+	if check.Identity != nil {
+		log.Donef("identity: %s [%s]", check.Identity.CommonName, check.Identity.SHA1Fingerprint)
+	}
+	if check.Profile != nil {
+		log.Donef("profile: %s (%s)", check.Profile.Name, check.Profile.UUID)
+	}
+	for _, problem := range check.Problems {
+		log.Warnf("- %s", problem)
+	}
+	problems += len(check.Problems)
+
+	fmt.Println()
+	if problems > 0 {
+		return fmt.Errorf("%d problem(s) found, the app can not be re-signed with the same identity", problems)
+	}
+	log.Successf("The keychain contains the identity of the app, and the exported files can re-sign it.")
+	return nil
+}
+
+// verifyKeychainIdentity logs whether the keychain contains the exact identity of the signing certificate,
+// or only identities with the same name but another certificate
+func verifyKeychainIdentity(certificate certificateutil.CertificateInfoModel) (bool, error) {
+	identities, err := osxkeychain.FindIdentity(certificate.CommonName)
+	if err != nil {
+		return false, fmt.Errorf("failed to search the keychain for %s, error: %s", certificate.CommonName, err)
+	}
+	defer osxkeychain.ReleaseIdentityWithRefList(identities)
+
+	for _, identity := range identities {
+		if identity.SHA1Fingerprint == certificate.SHA1Fingerprint {
+			log.Donef("identity: %s [%s] in %s", identity.Label, identity.SHA1Fingerprint, identity.KeychainPath)
+			return true, nil
+		}
+	}
+	if len(identities) == 0 {
+		log.Warnf("- the identity of the signing certificate (%s) is not installed", certificate.CommonName)
+		return false, nil
+	}
+	for _, identity := range identities {
+		log.Warnf("- %s [%s] has the same name but another certificate (e.g. a renewed one), expires: %s", identity.Label, identity.SHA1Fingerprint, identity.NotAfter.Format("2006-01-02"))
+	}
+	return false, nil
+}
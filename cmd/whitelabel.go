@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/models"
+	"github.com/bitrise-io/codesigndoc/utility"
+	"github.com/bitrise-io/codesigndoc/whitelabel"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/spf13/cobra"
+)
+
+// whitelabelCmd represents the whitelabel command
+var whitelabelCmd = &cobra.Command{
+	Use:   "whitelabel",
+	Short: "White-label project scanner, driven by a brand mapping file",
+	Long: `Export the code signing files of every brand of a white-label project
+
+The brands and their bundle IDs are read from the mapping file:
+
+brands:
+- name: brand-a
+  bundle_ids:
+  - com.company.brand-a
+  - com.company.brand-a.widget
+
+The installed provisioning profiles of every bundle ID and the certificates included in them
+are exported into a separate directory per brand.`,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          scanWhitelabel,
+}
+
+var (
+	paramWhitelabelMappingPath   string
+	paramWhitelabelPlatform      string
+	paramWhitelabelExportMethods []string
+)
+
+func init() {
+	scanCmd.AddCommand(whitelabelCmd)
+	features.Register(features.Feature{Name: scannerFeaturePrefix + "whitelabel", Kind: features.Scanner, Description: "Export the code signing files of every brand of a white-label project"})
+
+	whitelabelCmd.Flags().StringVar(&paramWhitelabelMappingPath, "mapping", "", "Brand mapping file path")
+	whitelabelCmd.Flags().StringVar(&paramWhitelabelPlatform, "platform", "ios", `Platform of the brands. Valid values: "ios", "macos".`)
+	whitelabelCmd.Flags().StringSliceVar(&paramWhitelabelExportMethods, "export-method", nil, "Export method of the profiles to export (app-store, ad-hoc, enterprise, development), can be specified multiple times. Defaults to every method")
+}
+
+func scanWhitelabel(_ *cobra.Command, _ []string) error {
+	if paramWhitelabelMappingPath == "" {
+		return fmt.Errorf("no mapping file specified, use the --mapping flag")
+	}
+	if writeFiles == codesign.WriteFilesDisabled {
+		return fmt.Errorf("the white-label export writes the code signing files per brand, it can not be used with --%s=disable", writeFilesFlag)
+	}
+	if dryRun && (paramFormat == "json" || paramSaveSelection != "") {
+		return fmt.Errorf("the white-label dry run prints the plan of every brand, it can not be used with --format json or --save-selection")
+	}
+
+	certType, profileType := codesign.IOSCertificate, profileutil.ProfileTypeIos
+	switch paramWhitelabelPlatform {
+	case "ios":
+	case "macos":
+		certType, profileType = codesign.MacOSCertificate, profileutil.ProfileTypeMacOs
+	default:
+		return fmt.Errorf("invalid platform (%s), valid values: 'ios', 'macos'", paramWhitelabelPlatform)
+	}
+
+	var exportMethods []exportoptions.Method
+	for _, method := range paramWhitelabelExportMethods {
+		exportMethod, err := exportoptions.ParseMethod(method)
+		if err != nil {
+			return err
+		}
+		exportMethods = append(exportMethods, exportMethod)
+	}
+
+	absExportOutputDirPath, err := absOutputDir()
+	if err != nil {
+		return err
+	}
+
+	mapping, err := whitelabel.NewMappingFromFile(paramWhitelabelMappingPath)
+	if err != nil {
+		return err
+	}
+
+	certificates, err := codesign.InstalledCertificates(certType)
+	if err != nil {
+		return fmt.Errorf("failed to list installed certificates, error: %s", err)
+	}
+	profiles, err := utility.InstalledProfileInfos(profileType)
+	if err != nil {
+		return fmt.Errorf("failed to list installed provisioning profiles, error: %s", err)
+	}
+	log.Infof("Installed: %d certificate(s), %d provisioning profile(s), brands: %d", len(certificates), len(profiles), len(mapping.Brands))
+
+	// brands usually share the same certificates, export them only once
+	exportedIdentities := map[string]models.Certificates{}
+	var incompleteBrands []string
+	exportResult := codesign.ExportReport{CodesignFilesWritten: true}
+	reportedCertificates := map[string]bool{}
+
+	for _, brand := range mapping.Brands {
+		assets := whitelabel.MatchBrand(brand, profiles, certificates, exportMethods...)
+
+		fmt.Println()
+		log.Infof("Brand: %s", brand.Name)
+		for _, profile := range assets.Profiles {
+			log.Printf("- %s (%s, %s)", profile.Name, profile.BundleID, profile.ExportType)
+		}
+		if len(assets.MissingBundleIDs) > 0 {
+			log.Warnf("No matching provisioning profile installed for: %s", strings.Join(assets.MissingBundleIDs, ", "))
+			incompleteBrands = append(incompleteBrands, brand.Name)
+		}
+		if len(assets.Profiles) == 0 {
+			log.Warnf("Skipping brand, nothing to export.")
+			continue
+		}
+		absBrandOutputDirPath := filepath.Join(absExportOutputDirPath, brand.Name)
+
+		if dryRun {
+			var profilesToExport []profileutil.ProvisioningProfileInfoModel
+			if !certificatesOnly {
+				profilesToExport = assets.Profiles
+			}
+			if err := printDryRun(assets.Certificates, profilesToExport, absBrandOutputDirPath); err != nil {
+				return fmt.Errorf("failed to plan the export of brand (%s), error: %s", brand.Name, err)
+			}
+			continue
+		}
+
+		identities, ok := exportedIdentities[certificatesKey(assets.Certificates)]
+		if !ok {
+			// the certificates are exported without the profiles, which tell the enterprise identities
+			if err := codesign.CheckEnterpriseIdentities(assets.Certificates, assets.Profiles, identityExportConfig); err != nil {
+				return err
+			}
+			if identities, _, err = codesign.ExportCodesigningFiles(scanCtx, assets.Certificates, nil, identityExportConfig); err != nil {
+				return fmt.Errorf("failed to export the certificates of brand (%s), error: %s", brand.Name, err)
+			}
+			exportedIdentities[certificatesKey(assets.Certificates)] = identities
+		}
+
+		var exportedProfiles []models.ProvisioningProfile
+		if !certificatesOnly {
+			if _, exportedProfiles, err = codesign.ExportCodesigningFiles(scanCtx, nil, assets.Profiles, identityExportConfig); err != nil {
+				return fmt.Errorf("failed to export the provisioning profiles of brand (%s), error: %s", brand.Name, err)
+			}
+		}
+
+		writeFilesConfig := codesign.WriteFilesConfig{
+			WriteFiles:       writeFiles,
+			AbsOutputDirPath: absBrandOutputDirPath,
+			Naming:           namingConfig,
+		}
+		if err := codesign.WriteFiles(identities, exportedProfiles, writeFilesConfig); err != nil {
+			return err
+		}
+
+		// the shared certificates are reported once, their files are written for every brand
+		brandResult := codesign.WriteFilesReport(identities, exportedProfiles, writeFilesConfig)
+		for _, certificate := range brandResult.Certificates {
+			if !reportedCertificates[certificate.Serial] {
+				reportedCertificates[certificate.Serial] = true
+				exportResult.Certificates = append(exportResult.Certificates, certificate)
+			}
+		}
+		exportResult.Profiles = append(exportResult.Profiles, brandResult.Profiles...)
+		exportResult.Files = append(exportResult.Files, brandResult.Files...)
+	}
+
+	fmt.Println()
+	if len(incompleteBrands) > 0 {
+		log.Warnf("Code signing files are missing for brand(s): %s", strings.Join(incompleteBrands, ", "))
+	}
+	if dryRun {
+		return nil
+	}
+	return printFinished(exportResult, absExportOutputDirPath)
+}
+
+func certificatesKey(certificates []certificateutil.CertificateInfoModel) string {
+	var serials []string
+	for _, certificate := range certificates {
+		serials = append(serials, certificate.Serial)
+	}
+	sort.Strings(serials)
+	return strings.Join(serials, ",")
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/bitrise-io/codesigndoc/codesign"
 	"github.com/bitrise-io/codesigndoc/codesigndoc"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/prompt"
 	"github.com/bitrise-io/codesigndoc/xamarin"
 	"github.com/bitrise-io/go-utils/colorstring"
 	"github.com/bitrise-io/go-utils/fileutil"
@@ -18,7 +20,6 @@ import (
 	"github.com/bitrise-io/go-xamarin/analyzers/solution"
 	"github.com/bitrise-io/go-xamarin/builder"
 	"github.com/bitrise-io/go-xamarin/constants"
-	"github.com/bitrise-io/goinp/goinp"
 	"github.com/spf13/cobra"
 )
 
@@ -40,6 +41,7 @@ var (
 
 func init() {
 	scanCmd.AddCommand(xamarinCmd)
+	features.Register(features.Feature{Name: scannerFeaturePrefix + "xamarin", Kind: features.Scanner, Description: "Scan a Xamarin solution by running an Archive for Publishing"})
 
 	xamarinCmd.Flags().StringVar(&paramXamarinSolutionFilePath, "file", "", `Xamarin Solution file path`)
 	xamarinCmd.Flags().StringVar(&paramXamarinConfigurationName, "config", "", `Xamarin Configuration Name (e.g.: "Release|iPhone")`)
@@ -99,7 +101,7 @@ func scanXamarinProject(cmd *cobra.Command, args []string) error {
 		// If can't find any, ask the user to drag-and-drop the file
 		var err error
 		if xamarinCmd.SolutionFilePath, err = findXamarinSolution(); err != nil {
-			return err
+			return withFlagHint(err, "file")
 		}
 	}
 	log.Debugf("xamSolutionPth: %s", xamarinCmd.SolutionFilePath)
@@ -142,9 +144,9 @@ func scanXamarinProject(cmd *cobra.Command, args []string) error {
 				selectedXamarinConfigurationName = archivableSolutionConfigNames[0]
 			} else {
 				fmt.Println()
-				answerValue, err := goinp.SelectFromStringsWithDefault(`Select the Configuration Name you use for "Archive for Publishing" (usually Release|iPhone)`, 1, archivableSolutionConfigNames)
+				answerValue, err := prompt.SelectFromStringsWithDefault(`Select the Configuration Name you use for "Archive for Publishing" (usually Release|iPhone)`, 1, archivableSolutionConfigNames)
 				if err != nil {
-					return fmt.Errorf("failed to select Configuration: %s", err)
+					return fmt.Errorf("failed to select Configuration: %s", withFlagHint(err, "config"))
 				}
 				log.Debugf("selected configuration: %v", answerValue)
 				selectedXamarinConfigurationName = answerValue
@@ -163,8 +165,8 @@ func scanXamarinProject(cmd *cobra.Command, args []string) error {
 	log.Printf(`🔦  Running a Build, to get all the required code signing settings...`)
 	logOutputFilePath := filepath.Join(absExportOutputDirPath, "xamarin-build-output.log")
 
-	archivePath, logOutput, err := xamarinCmd.GenerateArchive()
-	if writeFiles == codesign.WriteFilesAlways || writeFiles == codesign.WriteFilesFallback && err != nil { // save the xamarin output into a debug log file
+	archivePath, logOutput, err := xamarinCmd.GenerateArchive(scanCtx)
+	if !dryRun && (writeFiles == codesign.WriteFilesAlways || writeFiles == codesign.WriteFilesFallback && err != nil) { // save the xamarin output into a debug log file
 		if err := os.MkdirAll(absExportOutputDirPath, 0700); err != nil {
 			return fmt.Errorf("failed to create output directory, error: %s", err)
 		}
@@ -189,31 +191,42 @@ func scanXamarinProject(cmd *cobra.Command, args []string) error {
 		return ArchiveError{toolXamarin, "failed to run xamarin build command: " + err.Error()}
 	}
 
+	if !certificatesOnly && !dryRun {
+		if err := codesigndoc.InstallBundleProfiles(archivePath, absExportOutputDirPath); err != nil {
+			log.Warnf("Failed to install the provisioning profiles of the export bundle: %s", err)
+		}
+	}
+
 	// If certificatesOnly is set, CollectCodesignFiles returns an empty slice for profiles
 	certificatesToExport, profilesToExport, err := codesigndoc.CollectCodesignFiles(archivePath, certificatesOnly)
 	if err != nil {
 		return err
 	}
 
-	certificates, profiles, err := codesign.ExportCodesigningFiles(certificatesToExport, profilesToExport, isAskForPassword)
-	if err != nil {
-		return err
+	if dryRun {
+		return printDryRun(certificatesToExport, profilesToExport, absExportOutputDirPath)
 	}
 
-	exportResult, err := codesign.UploadAndWriteCodesignFiles(certificates,
-		profiles,
+	exportResult, err := codesign.ExportUploadAndWriteCodesignFiles(scanCtx, certificatesToExport,
+		profilesToExport,
+		identityExportConfig,
 		codesign.WriteFilesConfig{
 			WriteFiles:       writeFiles,
 			AbsOutputDirPath: absExportOutputDirPath,
+			Match:            matchRepoConfig,
+			Naming:           namingConfig,
+			CertificateFiles: exportCerts,
+			PerTeam:          perTeam,
 		},
 		codesign.UploadConfig{
 			PersonalAccessToken: personalAccessToken,
 			AppSlug:             appSlug,
+			RollbackOnFailure:   uploadRollback,
+			SecretStore:         secretStore,
 		})
 	if err != nil {
 		return err
 	}
 
-	printFinished(exportResult, absExportOutputDirPath)
-	return nil
+	return printFinished(exportResult, absExportOutputDirPath)
 }
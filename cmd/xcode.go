@@ -8,12 +8,18 @@ import (
 
 	"github.com/bitrise-io/codesigndoc/codesign"
 	"github.com/bitrise-io/codesigndoc/codesigndoc"
+	"github.com/bitrise-io/codesigndoc/fastlane"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/prompt"
 	"github.com/bitrise-io/codesigndoc/xcode"
 	"github.com/bitrise-io/go-utils/colorstring"
 	"github.com/bitrise-io/go-utils/fileutil"
 	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-io/go-utils/pathutil"
-	"github.com/bitrise-io/goinp/goinp"
+	"github.com/bitrise-io/go-utils/stringutil"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
 	"github.com/spf13/cobra"
 )
 
@@ -32,18 +38,37 @@ var (
 	paramXcodeProjectFilePath string
 	paramXcodeScheme          string
 	paramXcodebuildSDK        string
+	paramXcodeArchivePath     string
+	paramManagedExportMethod  string
+	paramListSchemes          bool
+)
+
+var (
+	paramXcodeSchemes        []string
+	paramAllSchemes          bool
+	paramXcodeConfigurations []string
 )
 
 func init() {
 	scanCmd.AddCommand(xcodeCmd)
+	features.Register(features.Feature{Name: scannerFeaturePrefix + "xcode", Kind: features.Scanner, Description: "Scan an Xcode project or workspace by running an Xcode Archive"})
 
 	xcodeCmd.Flags().StringVar(&paramXcodeProjectFilePath, "file", "", "Xcode Project/Workspace file path")
-	xcodeCmd.Flags().StringVar(&paramXcodeScheme, "scheme", "", "Xcode Scheme")
+	xcodeCmd.Flags().StringSliceVar(&paramXcodeSchemes, "scheme", nil, "Xcode Scheme, the shared and user Schemes are offered if empty. Can be specified multiple times to collect the code signing files of every Scheme in one run")
+	xcodeCmd.Flags().BoolVar(&paramAllSchemes, "all-schemes", false, "Archive every shared Scheme of the project and collect the code signing files needed by any of them")
+	xcodeCmd.Flags().StringSliceVar(&paramXcodeConfigurations, "configuration", nil, "Build configuration to archive the Scheme with (e.g. Debug, Release, AdHoc), the archive configuration of the Scheme if empty. Can be specified multiple times to collect the code signing files of every configuration, e.g. the development identity of Debug and the distribution identity of Release")
+	xcodeCmd.Flags().BoolVar(&paramListSchemes, "list-schemes", false, "List the shared and user Schemes of the project and exit, the default Scheme is the one named after the project")
+	xcodeCmd.Flags().StringVar(&paramXcodeArchivePath, "archive", "", "Path of an existing .xcarchive to scan, instead of archiving the project")
+	xcodeCmd.Flags().StringVar(&paramManagedExportMethod, "managed-export", "", `Also export the archive with Xcode's automatic signing for this export method, and collect the (Xcode managed) profiles and identities the export is signed with. Valid values: "app-store", "ad-hoc", "enterprise", "development". Requires an Apple ID signed in to Xcode`)
 	xcodeCmd.Flags().StringVar(&paramXcodebuildSDK, "xcodebuild-sdk", "", "xcodebuild -sdk param. If a value is specified for this flag it'll be passed to xcodebuild as the value of the -sdk flag. For more info about the values please see xcodebuild's -sdk flag docs. Example value: iphoneos")
 }
 
 func absOutputDir() (string, error) {
-	confExportOutputDirPath := "./codesigndoc_exports"
+	if encryptOutput {
+		return stageEncryptedOutput()
+	}
+
+	confExportOutputDirPath := paramOutputDir
 	absExportOutputDirPath, err := pathutil.AbsPath(confExportOutputDirPath)
 	log.Debugf("absExportOutputDirPath: %s", absExportOutputDirPath)
 	if err != nil {
@@ -53,63 +78,202 @@ func absOutputDir() (string, error) {
 }
 
 func scanXcodeProject(_ *cobra.Command, _ []string) error {
+	if paramListSchemes {
+		return listXcodeSchemes()
+	}
+
 	absExportOutputDirPath, err := absOutputDir()
 	if err != nil {
 		return err
 	}
 
-	xcodeCmd := xcode.CommandModel{}
-
-	projectPath := paramXcodeProjectFilePath
-	if projectPath == "" {
-		log.Infof("Scan the directory for project files")
-		log.Warnf("You can specify the Xcode project/workscape file to scan with the --file flag.")
+	var managedExportMethod exportoptions.Method
+	if paramManagedExportMethod != "" {
+		if dryRun {
+			return fmt.Errorf("--managed-export can not be used together with --dry-run, Xcode may create profiles in the developer portal during the export")
+		}
+		if managedExportMethod, err = exportoptions.ParseMethod(paramManagedExportMethod); err != nil {
+			return fmt.Errorf("invalid value for --managed-export flag (%s), error: %s", paramManagedExportMethod, err)
+		}
+	}
 
-		// Scan the directory for Xcode Project (.xcworkspace / .xcodeproject) file first
-		// If can't find any, ask the user to drag-and-drop the file
-		projpth, err := findXcodeProject()
+	if paramAllSchemes || len(paramXcodeSchemes) > 1 || len(paramXcodeConfigurations) > 1 {
+		if paramXcodeArchivePath != "" {
+			return fmt.Errorf("--archive can not be used together with multiple Schemes or configurations")
+		}
+		certificatesToExport, profilesToExport, err := scanXcodeSchemes(absExportOutputDirPath, managedExportMethod)
 		if err != nil {
 			return err
 		}
+		return exportXcodeFiles(certificatesToExport, profilesToExport, absExportOutputDirPath)
+	}
 
-		projectPath = strings.Trim(strings.TrimSpace(projpth), "'\"")
+	var archivePath, schemeToUse string
+	var fastlaneConfig *fastlane.Config
+	if paramXcodeArchivePath != "" {
+		if archivePath, err = existingArchive(paramXcodeArchivePath); err != nil {
+			return err
+		}
+	} else if archivePath, fastlaneConfig, schemeToUse, err = archiveXcodeProject(absExportOutputDirPath); err != nil {
+		return err
 	}
-	log.Debugf("projectPath: %s", projectPath)
-	xcodeCmd.ProjectFilePath = projectPath
 
-	schemeToUse := paramXcodeScheme
-	if schemeToUse == "" {
-		fmt.Println()
-		log.Printf("🔦  Scanning Schemes ...")
-		schemes, err := xcodeCmd.ScanSchemes()
+	certificatesToExport, profilesToExport, err := collectArchiveFiles(archivePath, managedExportMethod, absExportOutputDirPath)
+	if err != nil {
+		return err
+	}
+	printFastlaneConflicts(fastlaneConfig, schemeToUse, certificatesToExport, profilesToExport)
+
+	return exportXcodeFiles(certificatesToExport, profilesToExport, absExportOutputDirPath)
+}
+
+// collectArchiveFiles returns the identities and profiles the archive is signed with,
+// and the ones of the managed export if managedExportMethod is set
+func collectArchiveFiles(archivePath string, managedExportMethod exportoptions.Method, absExportOutputDirPath string) ([]certificateutil.CertificateInfoModel, []profileutil.ProvisioningProfileInfoModel, error) {
+	if !certificatesOnly && !dryRun {
+		if err := codesigndoc.InstallBundleProfiles(archivePath, absExportOutputDirPath); err != nil {
+			log.Warnf("Failed to install the provisioning profiles of the export bundle: %s", err)
+		}
+	}
+
+	// If certificatesOnly is set, CollectCodesignFiles returns an empty slice for profiles
+	certificatesToExport, profilesToExport, err := codesigndoc.CollectCodesignFiles(archivePath, certificatesOnly)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if managedExportMethod != "" {
+		certificates, profiles, err := codesigndoc.ManagedExportFiles(scanCtx, archivePath, managedExportMethod)
 		if err != nil {
-			return ArchiveError{toolXcode, "failed to scan Schemes: " + err.Error()}
+			return nil, nil, err
+		}
+		certificatesToExport = appendMissingCertificates(certificatesToExport, certificates)
+		if !certificatesOnly {
+			profilesToExport = appendMissingProfiles(profilesToExport, profiles)
 		}
-		log.Debugf("schemes: %v", schemes)
+	}
+	return certificatesToExport, profilesToExport, nil
+}
 
-		if len(schemes) == 0 {
-			return ArchiveError{toolXcode, "no schemes found"}
-		} else if len(schemes) == 1 {
-			schemeToUse = schemes[0]
-		} else {
-			fmt.Println()
-			selectedScheme, err := goinp.SelectFromStringsWithDefault("Select the Scheme you usually use in Xcode", 1, schemes)
-			if err != nil {
-				return fmt.Errorf("failed to select Scheme: %s", err)
-			}
-			schemeToUse = selectedScheme
+// printFastlaneConflicts warns about the fastlane lanes of the scheme which would sign with other settings than the collected ones
+func printFastlaneConflicts(fastlaneConfig *fastlane.Config, scheme string, certificates []certificateutil.CertificateInfoModel, profiles []profileutil.ProvisioningProfileInfoModel) {
+	if fastlaneConfig == nil || certificatesOnly {
+		return
+	}
+	if conflicts := fastlane.Conflicts(*fastlaneConfig, scheme, certificates, profiles); len(conflicts) > 0 {
+		fmt.Println()
+		log.Warnf("🚨  The fastlane configuration conflicts with the collected code signing settings:")
+		for _, conflict := range conflicts {
+			log.Warnf("- %s", conflict)
 		}
+		log.Printf("fastlane will use its own settings on CI, make sure the required code signing files are collected for them.")
+	}
+}
+
+// exportXcodeFiles prints the dry run plan, or exports, uploads and writes the collected code signing files
+func exportXcodeFiles(certificatesToExport []certificateutil.CertificateInfoModel, profilesToExport []profileutil.ProvisioningProfileInfoModel, absExportOutputDirPath string) error {
+	if dryRun {
+		return printDryRun(certificatesToExport, profilesToExport, absExportOutputDirPath)
+	}
+
+	exportResult, err := codesign.ExportUploadAndWriteCodesignFiles(scanCtx, certificatesToExport,
+		profilesToExport,
+		identityExportConfig,
+		codesign.WriteFilesConfig{
+			WriteFiles:       writeFiles,
+			AbsOutputDirPath: absExportOutputDirPath,
+			Match:            matchRepoConfig,
+			Naming:           namingConfig,
+			CertificateFiles: exportCerts,
+			PerTeam:          perTeam,
+		},
+		codesign.UploadConfig{
+			PersonalAccessToken: personalAccessToken,
+			AppSlug:             appSlug,
+			RollbackOnFailure:   uploadRollback,
+			SecretStore:         secretStore,
+		})
+	if err != nil {
+		return err
+	}
+
+	return printFinished(exportResult, absExportOutputDirPath)
+}
+
+// archiveXcodeProject selects the project and the scheme, and builds an archive of it
+func archiveXcodeProject(absExportOutputDirPath string) (string, *fastlane.Config, string, error) {
+	xcodeCmd, fastlaneConfig, err := openXcodeProject()
+	if err != nil {
+		return "", nil, "", err
+	}
 
-		log.Debugf("selected scheme: %v", schemeToUse)
+	var requested string
+	if len(paramXcodeSchemes) > 0 {
+		requested = paramXcodeSchemes[0]
 	}
-	xcodeCmd.Scheme = schemeToUse
+	schemeToUse, err := selectXcodeScheme(xcodeCmd, fastlaneConfig, requested)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	var configuration string
+	if len(paramXcodeConfigurations) > 0 {
+		configuration = paramXcodeConfigurations[0]
+	}
+	archivePath, err := archiveXcodeScheme(xcodeCmd, schemeToUse, configuration, absExportOutputDirPath)
+	if err != nil {
+		return "", nil, "", err
+	}
+	return archivePath, fastlaneConfig, schemeToUse, nil
+}
+
+// openXcodeProject returns the command of the project or workspace to scan, and its fastlane configuration if it has one
+func openXcodeProject() (xcode.CommandModel, *fastlane.Config, error) {
+	projectPath, err := xcodeProjectPath()
+	if err != nil {
+		return xcode.CommandModel{}, nil, err
+	}
+	xcodeCmd := xcode.CommandModel{ProjectFilePath: projectPath}
+
+	fastlaneConfig, err := fastlane.NewConfigFromDir(filepath.Dir(projectPath))
+	if err != nil {
+		log.Warnf("Failed to read the fastlane configuration: %s", err)
+	} else if fastlaneConfig != nil {
+		fmt.Println()
+		log.Infof("fastlane configuration found, the code signing settings will be compared with it")
+		for _, lane := range fastlaneConfig.Lanes {
+			if lane.Scheme != "" || lane.ExportMethod != "" {
+				log.Printf("- lane %s: scheme: %s, export method: %s", lane.Name, lane.Scheme, lane.ExportMethod)
+			}
+		}
+	}
+	return xcodeCmd, fastlaneConfig, nil
+}
 
+// archiveXcodeScheme prints the code signing settings of the scheme in the configuration, and builds an archive of it,
+// the archive configuration of the scheme is used if configuration is empty
+func archiveXcodeScheme(xcodeCmd xcode.CommandModel, scheme, configuration, absExportOutputDirPath string) (string, error) {
+	var err error
+	xcodeCmd.Scheme = scheme
+	xcodeCmd.Configuration = configuration
 	if paramXcodebuildSDK != "" {
 		xcodeCmd.SDK = paramXcodebuildSDK
 	}
+	resolveSwiftPackages(xcodeCmd)
+
+	fmt.Println()
+	log.Printf("🔦  Reading the code signing settings of the Scheme ...")
+	if settings, err := xcodeCmd.ShowCodeSignSettings(""); err != nil {
+		log.Warnf("Failed to read the build settings: %s", err)
+	} else {
+		printCodeSignSettings(settings)
+		if paramManagedExportMethod == "" {
+			codesigndoc.PrintAutomaticSigning(xcode.AutomaticSigningTargets(settings))
+		}
+	}
 
 	writeBuildLogs := func(xcodebuildOutput string) error {
-		if writeFiles == codesign.WriteFilesAlways || writeFiles == codesign.WriteFilesFallback && err != nil { // save the xcodebuild output into a debug log file
+		if !dryRun && (writeFiles == codesign.WriteFilesAlways || writeFiles == codesign.WriteFilesFallback && err != nil) { // save the xcodebuild output into a debug log file
 			xcodebuildOutputFilePath := filepath.Join(absExportOutputDirPath, "xcodebuild-output.log")
 			if err := os.MkdirAll(absExportOutputDirPath, 0700); err != nil {
 				return fmt.Errorf("failed to create output directory, error: %s", err)
@@ -123,30 +287,241 @@ func scanXcodeProject(_ *cobra.Command, _ []string) error {
 		return nil
 	}
 
-	archivePath, err := codesigndoc.BuildXcodeArchive(xcodeCmd, writeBuildLogs)
+	archivePath, err := codesigndoc.BuildXcodeArchive(scanCtx, xcodeCmd, writeBuildLogs)
 	if err != nil {
-		return ArchiveError{toolXcode, err.Error()}
+		return "", ArchiveError{toolXcode, err.Error()}
+	}
+	return archivePath, nil
+}
+
+// xcodeProjectPath returns the project or workspace of the --file flag, or the one found in the current directory
+func xcodeProjectPath() (string, error) {
+	projectPath := paramXcodeProjectFilePath
+	if projectPath == "" {
+		log.Infof("Scan the directory for project files")
+		log.Warnf("You can specify the Xcode project/workscape file to scan with the --file flag.")
+
+		// Scan the directory for Xcode Project (.xcworkspace / .xcodeproject) file first
+		// If can't find any, ask the user to drag-and-drop the file
+		projpth, err := findXcodeProject()
+		if err != nil {
+			return "", withFlagHint(err, "file")
+		}
+
+		projectPath = strings.Trim(strings.TrimSpace(projpth), "'\"")
+	}
+	log.Debugf("projectPath: %s", projectPath)
+
+	if workspace, usesPods, err := xcode.CocoaPodsWorkspace(projectPath); err != nil {
+		log.Warnf("Failed to look for the CocoaPods workspace of the project: %s", err)
+	} else if workspace != "" {
+		log.Warnf("The project uses CocoaPods, scanning the workspace generated by pod install instead: %s", workspace)
+		projectPath = workspace
+	} else if usesPods {
+		log.Warnf("The project has a Podfile, but no workspace generated for it, run pod install if the build fails on missing Pods")
 	}
+	return projectPath, nil
+}
 
-	certificates, profiles, err := codesigndoc.CodesigningFilesForXCodeProject(archivePath, certificatesOnly, isAskForPassword)
+// resolveSwiftPackages resolves the Swift packages of the project before its build settings are read
+func resolveSwiftPackages(xcodeCmd xcode.CommandModel) {
+	usesPackages, err := xcode.UsesSwiftPackages(xcodeCmd.ProjectFilePath)
 	if err != nil {
-		return err
+		log.Warnf("Failed to check the Swift package dependencies of the project: %s", err)
+		return
+	} else if !usesPackages {
+		return
 	}
 
-	exportResult, err := codesign.UploadAndWriteCodesignFiles(certificates,
-		profiles,
-		codesign.WriteFilesConfig{
-			WriteFiles:       writeFiles,
-			AbsOutputDirPath: absExportOutputDirPath,
-		},
-		codesign.UploadConfig{
-			PersonalAccessToken: personalAccessToken,
-			AppSlug:             appSlug,
-		})
+	fmt.Println()
+	log.Printf("🔦  Resolving the Swift package dependencies ...")
+	if xcodebuildOutput, err := xcodeCmd.ResolvePackageDependencies(); err != nil {
+		log.Warnf("Failed to resolve the Swift packages, the scan may fail: %s", err)
+		log.Warnf("Last lines of the xcodebuild output:")
+		fmt.Println(stringutil.LastNLines(xcodebuildOutput, 15))
+	}
+}
+
+// listXcodeSchemes prints the shared and user schemes of the project, for --list-schemes
+func listXcodeSchemes() error {
+	projectPath, err := xcodeProjectPath()
 	if err != nil {
 		return err
 	}
+	schemes, err := xcode.CommandModel{ProjectFilePath: projectPath}.ListSchemes()
+	if err != nil {
+		return ArchiveError{toolXcode, "failed to scan Schemes: " + err.Error()}
+	}
 
-	printFinished(exportResult, absExportOutputDirPath)
+	fmt.Println()
+	log.Infof("Schemes of %s:", projectPath)
+	defaultIndex := xcode.DefaultSchemeIndex(projectPath, schemes)
+	for i, scheme := range schemes {
+		if i == defaultIndex {
+			log.Printf("- %s [default]", scheme)
+		} else {
+			log.Printf("- %s", scheme)
+		}
+	}
 	return nil
 }
+
+// selectXcodeScheme returns the requested scheme (of the --scheme flag), checked against the schemes of the project,
+// or asks for one of the schemes, offering the scheme of the fastlane lanes or the one named after the project by default
+func selectXcodeScheme(xcodeCmd xcode.CommandModel, fastlaneConfig *fastlane.Config, requested string) (string, error) {
+	fmt.Println()
+	log.Printf("🔦  Scanning Schemes ...")
+	schemes, err := xcodeCmd.ListSchemes()
+	if err != nil && requested != "" {
+		log.Warnf("Failed to scan Schemes, using the Scheme (%s) without checking: %s", requested, err)
+		return requested, nil
+	} else if err != nil {
+		return "", ArchiveError{toolXcode, "failed to scan Schemes: " + err.Error()}
+	}
+	log.Debugf("schemes: %v", schemes)
+
+	var names []string
+	for _, scheme := range schemes {
+		names = append(names, scheme.Name)
+	}
+
+	var selected *xcode.Scheme
+	switch {
+	case requested != "":
+		for i, scheme := range schemes {
+			if scheme.Name == requested {
+				selected = &schemes[i]
+				break
+			}
+			if selected == nil && strings.EqualFold(scheme.Name, requested) {
+				selected = &schemes[i]
+			}
+		}
+		if selected == nil {
+			return "", fmt.Errorf("scheme (%s) not found in the project, available schemes: %s", requested, strings.Join(names, ", "))
+		}
+		if selected.Name != requested {
+			log.Warnf("Scheme (%s) not found, using the Scheme: %s", requested, selected.Name)
+		}
+	case len(schemes) == 0:
+		return "", ArchiveError{toolXcode, "no schemes found"}
+	case len(schemes) == 1:
+		selected = &schemes[0]
+	default:
+		defaultIndex, ok := fastlaneSchemeIndex(fastlaneConfig, names)
+		if !ok {
+			defaultIndex = xcode.DefaultSchemeIndex(xcodeCmd.ProjectFilePath, schemes)
+		}
+
+		if prompt.NonInteractive && schemes[defaultIndex].Name == xcode.ProjectName(xcodeCmd.ProjectFilePath) {
+			// the scheme named after the project is the usual one, it is used instead of failing the non-interactive scan
+			log.Warnf("Using the Scheme named after the project (%s), select another one with the --scheme flag", schemes[defaultIndex].Name)
+			selected = &schemes[defaultIndex]
+			break
+		}
+
+		var labels []string
+		for _, scheme := range schemes {
+			labels = append(labels, scheme.String())
+		}
+		fmt.Println()
+		label, err := prompt.SelectFromStringsWithDefault("Select the Scheme you usually use in Xcode", defaultIndex+1, labels)
+		if err != nil {
+			return "", fmt.Errorf("failed to select Scheme: %s", withFlagHint(err, "scheme"))
+		}
+		for i := range schemes {
+			if labels[i] == label {
+				selected = &schemes[i]
+			}
+		}
+	}
+
+	if !selected.Shared {
+		log.Warnf("The Scheme %s is not shared, so it is not available on CI: tick Shared in Xcode's Product > Scheme > Manage Schemes and commit the xcshareddata directory", selected.Name)
+	}
+	log.Debugf("selected scheme: %v", selected.Name)
+	return selected.Name, nil
+}
+
+// existingArchive validates the archive given by the --archive flag
+func existingArchive(pth string) (string, error) {
+	absPath, err := pathutil.AbsPath(pth)
+	if err != nil {
+		return "", fmt.Errorf("failed to expand archive path (%s), error: %s", pth, err)
+	}
+	if filepath.Ext(absPath) != ".xcarchive" {
+		return "", fmt.Errorf("invalid archive (%s), the extension should be .xcarchive", pth)
+	}
+	if exist, err := pathutil.IsDirExists(absPath); err != nil {
+		return "", err
+	} else if !exist {
+		return "", fmt.Errorf("archive does not exist: %s", absPath)
+	}
+
+	fmt.Println()
+	log.Infof("Scanning the existing archive: %s", absPath)
+	return absPath, nil
+}
+
+// printCodeSignSettings lists the signing settings of the targets, which are verified against the archive later
+func printCodeSignSettings(settings []xcode.TargetCodeSignSettings) {
+	for _, target := range settings {
+		style := target.CodeSignStyle
+		if style == "" {
+			style = "Manual"
+		}
+		log.Printf("- %s (%s): %s signing, identity: %s, profile: %s, team: %s", target.Target, target.BundleID, style, target.CodeSignIdentity, target.ProvisioningProfileSpecifier, target.DevelopmentTeam)
+		if !target.IsAutomatic() && target.CodeSignIdentity != "" && target.DevelopmentTeam == "" {
+			log.Warnf("  no development team is set for the manually signed target")
+		}
+	}
+}
+
+// appendMissingCertificates appends the certificates not collected yet
+func appendMissingCertificates(certificates, additional []certificateutil.CertificateInfoModel) []certificateutil.CertificateInfoModel {
+	for _, certificate := range additional {
+		found := false
+		for _, collected := range certificates {
+			if collected.SHA1Fingerprint == certificate.SHA1Fingerprint {
+				found = true
+				break
+			}
+		}
+		if !found {
+			certificates = append(certificates, certificate)
+		}
+	}
+	return certificates
+}
+
+// appendMissingProfiles appends the profiles not collected yet
+func appendMissingProfiles(profiles, additional []profileutil.ProvisioningProfileInfoModel) []profileutil.ProvisioningProfileInfoModel {
+	for _, profile := range additional {
+		found := false
+		for _, collected := range profiles {
+			if collected.UUID == profile.UUID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles
+}
+
+// fastlaneSchemeIndex returns the index of the first scheme built by a fastlane lane, to offer it as default
+func fastlaneSchemeIndex(config *fastlane.Config, schemes []string) (int, bool) {
+	if config == nil {
+		return 0, false
+	}
+	for _, lane := range config.Lanes {
+		for idx, scheme := range schemes {
+			if lane.Scheme == scheme {
+				return idx, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -4,17 +4,18 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/bitrise-io/codesigndoc/codesign"
 	"github.com/bitrise-io/codesigndoc/codesigndocuitests"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/prompt"
+	"github.com/bitrise-io/codesigndoc/xcode"
 	"github.com/bitrise-io/codesigndoc/xcodeuitest"
 	"github.com/bitrise-io/go-utils/colorstring"
 	"github.com/bitrise-io/go-utils/fileutil"
 	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-io/go-utils/stringutil"
 	"github.com/bitrise-io/go-xcode/utility"
-	"github.com/bitrise-io/goinp/goinp"
 	"github.com/spf13/cobra"
 )
 
@@ -30,6 +31,7 @@ var xcodeUITestsCmd = &cobra.Command{
 
 func init() {
 	scanCmd.AddCommand(xcodeUITestsCmd)
+	features.Register(features.Feature{Name: scannerFeaturePrefix + "xcodeuitests", Kind: features.Scanner, Description: "Scan the UI test targets of an Xcode project or workspace by running build-for-testing"})
 
 	xcodeUITestsCmd.Flags().StringVar(&paramXcodeProjectFilePath, "file", "", "Xcode Project/Workspace file path")
 	xcodeUITestsCmd.Flags().StringVar(&paramXcodeScheme, "scheme", "", "Xcode Scheme")
@@ -51,22 +53,10 @@ func scanXcodeUITestsProject(cmd *cobra.Command, args []string) error {
 	log.Infof("%s: %s (%s)", colorstring.Green("Xcode (xcodebuild) version"), xcodebuildVersion.Version, xcodebuildVersion.BuildVersion)
 	fmt.Println()
 
-	projectPath := paramXcodeProjectFilePath
-	if projectPath == "" {
-		log.Infof("Scan the directory for project files")
-		log.Warnf("You can specify the Xcode project/workscape file to scan with the --file flag.")
-
-		//
-		// Scan the directory for Xcode Project (.xcworkspace / .xcodeproject) file first
-		// If can't find any, ask the user to drag-and-drop the file
-		projpth, err := findXcodeProject()
-		if err != nil {
-			return err
-		}
-
-		projectPath = strings.Trim(strings.TrimSpace(projpth), "'\"")
+	projectPath, err := xcodeProjectPath()
+	if err != nil {
+		return err
 	}
-	log.Debugf("projectPath: %s", projectPath)
 	xcodeUITestsCmd := xcodeuitest.CommandModel{ProjectFilePath: projectPath}
 
 	schemeToUse := paramXcodeScheme
@@ -99,9 +89,9 @@ func scanXcodeUITestsProject(cmd *cobra.Command, args []string) error {
 				}
 			}
 
-			selectedScheme, err := goinp.SelectFromStringsWithDefault("Select the Scheme you usually use in Xcode", 1, schemesWitUITestNames)
+			selectedScheme, err := prompt.SelectFromStringsWithDefault("Select the Scheme you usually use in Xcode", 1, schemesWitUITestNames)
 			if err != nil {
-				return fmt.Errorf("failed to select Scheme: %s", err)
+				return fmt.Errorf("failed to select Scheme: %s", withFlagHint(err, "scheme"))
 			}
 			schemeToUse = selectedScheme
 		}
@@ -113,14 +103,15 @@ func scanXcodeUITestsProject(cmd *cobra.Command, args []string) error {
 	if paramXcodebuildSDK != "" {
 		xcodeUITestsCmd.SDK = paramXcodebuildSDK
 	}
+	resolveSwiftPackages(xcode.CommandModel{ProjectFilePath: xcodeUITestsCmd.ProjectFilePath, Scheme: xcodeUITestsCmd.Scheme, SDK: xcodeUITestsCmd.SDK})
 
 	fmt.Println()
 	fmt.Println()
 	log.Printf("🔦  Running an Xcode build-for-testing, to get all the required code signing settings...")
 	xcodebuildOutputFilePath := filepath.Join(absExportOutputDirPath, "xcodebuild-output.log")
 
-	buildForTestingPath, xcodebuildOutput, err := xcodeUITestsCmd.RunBuildForTesting()
-	if writeFiles == codesign.WriteFilesAlways || writeFiles == codesign.WriteFilesFallback && err != nil { // save the xcodebuild output into a debug log file
+	buildForTestingPath, xcodebuildOutput, err := xcodeUITestsCmd.RunBuildForTesting(scanCtx)
+	if !dryRun && (writeFiles == codesign.WriteFilesAlways || writeFiles == codesign.WriteFilesFallback && err != nil) { // save the xcodebuild output into a debug log file
 		if err := os.MkdirAll(absExportOutputDirPath, 0700); err != nil {
 			return fmt.Errorf("failed to create output directory, error: %s", err)
 		}
@@ -151,25 +142,30 @@ func scanXcodeUITestsProject(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	certificates, profiles, err := codesign.ExportCodesigningFiles(certificatesToExport, profilesToExport, isAskForPassword)
-	if err != nil {
-		return err
+	if dryRun {
+		return printDryRun(certificatesToExport, profilesToExport, absExportOutputDirPath)
 	}
 
-	exportResult, err := codesign.UploadAndWriteCodesignFiles(certificates,
-		profiles,
+	exportResult, err := codesign.ExportUploadAndWriteCodesignFiles(scanCtx, certificatesToExport,
+		profilesToExport,
+		identityExportConfig,
 		codesign.WriteFilesConfig{
 			WriteFiles:       writeFiles,
 			AbsOutputDirPath: absExportOutputDirPath,
+			Match:            matchRepoConfig,
+			Naming:           namingConfig,
+			CertificateFiles: exportCerts,
+			PerTeam:          perTeam,
 		},
 		codesign.UploadConfig{
 			PersonalAccessToken: personalAccessToken,
 			AppSlug:             appSlug,
+			RollbackOnFailure:   uploadRollback,
+			SecretStore:         secretStore,
 		})
 	if err != nil {
 		return err
 	}
 
-	printFinished(exportResult, absExportOutputDirPath)
-	return nil
+	return printFinished(exportResult, absExportOutputDirPath)
 }
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/bitrise-io/codesigndoc/keychain"
+	"github.com/bitrise-io/codesigndoc/state"
+	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-io/go-xcode/certificateutil"
 	"github.com/pkg/errors"
 )
@@ -39,8 +42,15 @@ var (
 	}
 )
 
+// IncludeExpiredCertificates keeps the expired and not yet valid certificates in the InstalledCertificates list
+var IncludeExpiredCertificates bool
+
+// CertificateFingerprints restricts the InstalledCertificates list to the certificates with the SHA-1 or SHA-256 fingerprints,
+// to select one of the certificates with the same common name
+var CertificateFingerprints []string
+
 // InstalledCertificates returns the certificate installed in the keychain,
-// the expired certificates are removed from the list
+// the expired certificates are removed from the list unless IncludeExpiredCertificates is set
 func InstalledCertificates(certType certificateType) ([]certificateutil.CertificateInfoModel, error) {
 	var certs []certificateutil.CertificateInfoModel
 	var err error
@@ -67,8 +77,70 @@ func InstalledCertificates(certType certificateType) ([]certificateutil.Certific
 			})
 		}
 	}
+	if err != nil {
+		return nil, err
+	}
+	// filter before the validation, which keeps only one of the certificates with the same name
+	certs = FilterCertificatesByFingerprint(certs, CertificateFingerprints)
+
+	filtered := certificateutil.FilterValidCertificateInfos(certs)
+	for _, certificate := range filtered.InvalidCertificates {
+		if IncludeExpiredCertificates {
+			log.Warnf("Including invalid certificate %s [%s]: %s", certificate.CommonName, certificate.SHA1Fingerprint, certificate.CheckValidity())
+			filtered.ValidCertificates = append(filtered.ValidCertificates, certificate)
+		} else {
+			log.Debugf("Skipping invalid certificate %s [%s]: %s", certificate.CommonName, certificate.SHA1Fingerprint, certificate.CheckValidity())
+		}
+	}
+	for _, certificate := range filtered.DuplicatedCertificates {
+		log.Debugf("Skipping certificate %s [%s]: an other valid certificate has the same name", certificate.CommonName, certificate.SHA1Fingerprint)
+	}
+	return FilterNeverExportCertificates(filtered.ValidCertificates)
+}
+
+// FilterCertificatesByFingerprint keeps the certificates with any of the SHA-1 or SHA-256 fingerprints, every certificate if none is given
+func FilterCertificatesByFingerprint(certificates []certificateutil.CertificateInfoModel, fingerprints []string) []certificateutil.CertificateInfoModel {
+	if len(fingerprints) == 0 {
+		return certificates
+	}
+	query := keychain.Query{Fingerprints: fingerprints}
+	return certificateutil.FilterCertificateInfoModelsByFilterFunc(certificates, func(certificate certificateutil.CertificateInfoModel) bool {
+		return query.MatchesCertificate(&certificate.Certificate)
+	})
+}
+
+// FilterNeverExportCertificates removes the certificates listed in the local never export list
+func FilterNeverExportCertificates(certificates []certificateutil.CertificateInfoModel) ([]certificateutil.CertificateInfoModel, error) {
+	neverExport, err := state.ReadNeverExportList()
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []certificateutil.CertificateInfoModel
+	for _, certificate := range certificates {
+		if neverExport.Contains(certificate.SHA1Fingerprint) {
+			log.Debugf("Skipping certificate listed in the never export list: %s [%s]", certificate.CommonName, certificate.SHA1Fingerprint)
+			continue
+		}
+		filtered = append(filtered, certificate)
+	}
+	return filtered, nil
+}
 
-	return certificateutil.FilterValidCertificateInfos(certs).ValidCertificates, nil
+// DeduplicateCertificates keeps the first certificate of each SHA-1 fingerprint,
+// the same certificate is listed once for every keychain it is installed in.
+func DeduplicateCertificates(certificates []certificateutil.CertificateInfoModel) (unique []certificateutil.CertificateInfoModel, duplicates []certificateutil.CertificateInfoModel) {
+	seen := map[string]bool{}
+	for _, certificate := range certificates {
+		fingerprint := strings.ToLower(certificate.SHA1Fingerprint)
+		if seen[fingerprint] {
+			duplicates = append(duplicates, certificate)
+			continue
+		}
+		seen[fingerprint] = true
+		unique = append(unique, certificate)
+	}
+	return unique, duplicates
 }
 
 // IsDistributionCertificate returns true if the given certificate
@@ -0,0 +1,36 @@
+package codesign
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"testing"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeduplicateCertificates(t *testing.T) {
+	development := certificateutil.CertificateInfoModel{CommonName: "iPhone Developer: Bitrise Bot (ABCD1234)", SHA1Fingerprint: "aa11"}
+	distribution := certificateutil.CertificateInfoModel{CommonName: "iPhone Distribution: Bitrise Inc. (ABCD1234)", SHA1Fingerprint: "bb22"}
+	distributionCopy := certificateutil.CertificateInfoModel{CommonName: "iPhone Distribution: Bitrise Inc. (ABCD1234)", SHA1Fingerprint: "BB22"}
+
+	unique, duplicates := DeduplicateCertificates([]certificateutil.CertificateInfoModel{development, distribution, distributionCopy, development})
+	require.Equal(t, []certificateutil.CertificateInfoModel{development, distribution}, unique)
+	require.Equal(t, []certificateutil.CertificateInfoModel{distributionCopy, development}, duplicates)
+
+	unique, duplicates = DeduplicateCertificates([]certificateutil.CertificateInfoModel{development})
+	require.Equal(t, []certificateutil.CertificateInfoModel{development}, unique)
+	require.Nil(t, duplicates)
+}
+
+func TestFilterCertificatesByFingerprint(t *testing.T) {
+	renewed := certificateutil.CertificateInfoModel{CommonName: "Apple Development: John Doe (ABCD1234)", Certificate: x509.Certificate{Raw: []byte("renewed")}}
+	old := certificateutil.CertificateInfoModel{CommonName: "Apple Development: John Doe (ABCD1234)", Certificate: x509.Certificate{Raw: []byte("old")}}
+	certificates := []certificateutil.CertificateInfoModel{renewed, old}
+
+	require.Equal(t, certificates, FilterCertificatesByFingerprint(certificates, nil))
+	require.Equal(t, []certificateutil.CertificateInfoModel{old}, FilterCertificatesByFingerprint(certificates, []string{fmt.Sprintf("%X", sha1.Sum(old.Certificate.Raw))}))
+	require.Equal(t, []certificateutil.CertificateInfoModel{renewed}, FilterCertificatesByFingerprint(certificates, []string{fmt.Sprintf("%x", sha256.Sum256(renewed.Certificate.Raw))}))
+}
@@ -0,0 +1,204 @@
+package codesign
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bitrise-io/codesigndoc/exitcode"
+	"github.com/bitrise-io/codesigndoc/fastlane"
+	"github.com/bitrise-io/codesigndoc/models"
+	"github.com/bitrise-io/codesigndoc/utility"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// estimatedPrivateKeySize is the approximate size of an encrypted RSA 2048 private key with its PKCS#12 bag
+const estimatedPrivateKeySize = 1800
+
+// PlannedFile is a file which would be written by the export
+type PlannedFile struct {
+	Path string
+	// Size is in bytes, it is an estimate for the files exported from the Keychain
+	Size      int64
+	Estimated bool
+}
+
+// ExportPlan describes the files an export would produce, without exporting from the Keychain or writing any file
+type ExportPlan struct {
+	Certificates []certificateutil.CertificateInfoModel
+	Profiles     []profileutil.ProvisioningProfileInfoModel
+	// ProfilePaths are the installed files of the profiles
+	ProfilePaths []string
+	Files        []PlannedFile
+	// Upload is true if the files would be uploaded to bitrise.io
+	Upload bool
+	// Selection is the found identities with their persistent references, see WriteIdentitySelection
+	Selection IdentitySelection
+}
+
+// PlanCodesignFiles finds and validates the required identities and profiles like the export does,
+// but it never exports from the Keychain, writes or uploads any file.
+func PlanCodesignFiles(ctx context.Context, certificatesRequired []certificateutil.CertificateInfoModel, profilesRequired []profileutil.ProvisioningProfileInfoModel, identityConfig IdentityExportConfig, writeFilesConfig WriteFilesConfig, uploadConfig UploadConfig) (ExportPlan, error) {
+	if writeFilesConfig.PerTeam {
+		return planPerTeam(ctx, certificatesRequired, profilesRequired, identityConfig, writeFilesConfig, uploadConfig)
+	}
+
+	plan := ExportPlan{Upload: uploadConfig.PersonalAccessToken != "" && uploadConfig.AppSlug != ""}
+
+	certificatesRequired, profilesRequired, err := filterTeams(certificatesRequired, profilesRequired, identityConfig.TeamIDs)
+	if err != nil {
+		return ExportPlan{}, err
+	}
+	if err := CheckEnterpriseIdentities(certificatesRequired, profilesRequired, identityConfig); err != nil {
+		return ExportPlan{}, err
+	}
+
+	var profileSizes []int64
+	var certificateOnlyPlan []certificateutil.CertificateInfoModel
+	for _, profile := range profilesRequired {
+		_, pth, err := utility.FindProfileInfo(profile.UUID)
+		if err != nil {
+			return ExportPlan{}, exitcode.Wrap(exitcode.ProfileMissing, fmt.Errorf("failed to find Provisioning Profile: %s", err))
+		}
+		info, err := os.Stat(pth)
+		if err != nil {
+			return ExportPlan{}, fmt.Errorf("failed to check Provisioning Profile, error: %s", err)
+		}
+		plan.Profiles = append(plan.Profiles, profile)
+		plan.ProfilePaths = append(plan.ProfilePaths, pth)
+		profileSizes = append(profileSizes, info.Size())
+	}
+
+	if len(certificatesRequired) > 0 {
+		// the access of the private keys is inspected, but not changed
+		if len(identityConfig.AuthorizeApplications) > 0 {
+			identityConfig.InspectKeyAccess = true
+			identityConfig.AuthorizeApplications = nil
+		}
+		certificates, identities, certificateOnly, release, err := findIdentitiesToExport(ctx, identityConfig.identityKeychain(), certificatesRequired, identityConfig, false)
+		if err != nil {
+			return ExportPlan{}, err
+		}
+		// the persistent references of the selection are only available in the macOS keychain
+		if securityIdentities, ok := securityFrameworkIdentities(identities); ok {
+			plan.Selection, err = newIdentitySelection(securityIdentities)
+		}
+		release()
+		if err != nil {
+			return ExportPlan{}, err
+		}
+		plan.Certificates = certificates
+		certificateOnlyPlan = certificateOnly
+	}
+
+	writes := writeFilesConfig.WriteFiles == WriteFilesAlways || writeFilesConfig.WriteFiles == WriteFilesFallback && !plan.Upload
+	if !writes {
+		return plan, nil
+	}
+
+	dir := writeFilesConfig.AbsOutputDirPath
+	if len(plan.Certificates) > 0 {
+		size := estimatedIdentitiesSize(plan.Certificates)
+		plan.Files = append(plan.Files, PlannedFile{Path: filepath.Join(dir, "Identities.p12"), Size: size, Estimated: true})
+		if identityConfig.PEM {
+			plan.Files = append(plan.Files, PlannedFile{Path: filepath.Join(dir, "Identities.pem"), Size: size * 4 / 3, Estimated: true})
+		}
+		if identityConfig.Each {
+			for _, certificate := range plan.Certificates {
+				plan.Files = append(plan.Files, PlannedFile{Path: filepath.Join(dir, writeFilesConfig.Naming.IdentityFileName(certificate)), Size: estimatedIdentitiesSize([]certificateutil.CertificateInfoModel{certificate}), Estimated: true})
+			}
+		}
+		if identityConfig.SeparateKeys {
+			for _, certificate := range plan.Certificates {
+				keyName, derName, pemName := separateKeyFileNames(certificate, writeFilesConfig.Naming)
+				plan.Files = append(plan.Files,
+					PlannedFile{Path: filepath.Join(dir, keyName), Size: estimatedPrivateKeySize * 4 / 3, Estimated: true},
+					PlannedFile{Path: filepath.Join(dir, derName), Size: int64(len(certificate.Certificate.Raw))},
+					PlannedFile{Path: filepath.Join(dir, pemName), Size: int64(len(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Certificate.Raw})))},
+				)
+			}
+		} else if writeFilesConfig.CertificateFiles {
+			for _, file := range certificateFilesOf(plan.Certificates, writeFilesConfig.Naming) {
+				plan.Files = append(plan.Files, PlannedFile{Path: filepath.Join(dir, file.Name), Size: int64(len(file.Content))})
+			}
+		}
+		if identityConfig.Chain {
+			for _, intermediate := range intermediateCertificates(plan.Certificates) {
+				plan.Files = append(plan.Files, PlannedFile{Path: filepath.Join(dir, intermediateFileName(intermediate)), Size: int64(len(intermediate.Raw))})
+			}
+		}
+	}
+	for _, file := range certificateOnlyFiles(models.Certificates{CertificateOnly: certificateOnlyPlan}, writeFilesConfig.Naming) {
+		plan.Files = append(plan.Files, PlannedFile{Path: filepath.Join(dir, file.Name), Size: int64(len(file.Content))})
+	}
+	for i, profile := range plan.Profiles {
+		plan.Files = append(plan.Files, PlannedFile{Path: filepath.Join(dir, writeFilesConfig.Naming.ProfileFileName(profile)), Size: profileSizes[i]})
+	}
+
+	if match := writeFilesConfig.Match; match.DirPath != "" {
+		for _, certificate := range plan.Certificates {
+			id, ok := match.CertificateIDs[certificate.Serial]
+			if !ok {
+				id = certificate.Serial
+			}
+			for _, file := range fastlane.MatchCertificateFiles(certificate, id, nil) {
+				plan.Files = append(plan.Files, PlannedFile{Path: filepath.Join(match.DirPath, file.Path), Estimated: true})
+			}
+		}
+		for _, profile := range plan.Profiles {
+			plan.Files = append(plan.Files, PlannedFile{Path: filepath.Join(match.DirPath, fastlane.MatchProfilePath(profile)), Estimated: true})
+		}
+	}
+	return plan, nil
+}
+
+// estimatedIdentitiesSize approximates the size of the .p12 file of the identities
+func estimatedIdentitiesSize(certificates []certificateutil.CertificateInfoModel) int64 {
+	var size int64
+	for _, certificate := range certificates {
+		size += int64(len(certificate.Certificate.Raw)) + estimatedPrivateKeySize
+	}
+	return size
+}
+
+// PrintExportPlan lists the identities, profiles and files of the plan
+func PrintExportPlan(plan ExportPlan) {
+	fmt.Println()
+	log.Infof("Dry run, nothing is exported from the Keychain, written or uploaded")
+
+	fmt.Println()
+	log.Infof("Identities to export (%d):", len(plan.Certificates))
+	for _, certificate := range plan.Certificates {
+		log.Printf("- %s [%s] team: %s, expires: %s", certificate.CommonName, certificate.SHA1Fingerprint, certificate.TeamID, certificate.EndDate.Format("2006-01-02"))
+	}
+
+	fmt.Println()
+	log.Infof("Provisioning Profiles to export (%d):", len(plan.Profiles))
+	for i, profile := range plan.Profiles {
+		log.Printf("- %s (UUID: %s) from %s", profile.Name, profile.UUID, plan.ProfilePaths[i])
+	}
+
+	fmt.Println()
+	if len(plan.Files) == 0 {
+		log.Infof("No files would be written")
+	} else {
+		log.Infof("Files to write (%d):", len(plan.Files))
+		for _, file := range plan.Files {
+			switch {
+			case file.Size == 0:
+				log.Printf("- %s", file.Path)
+			case file.Estimated:
+				log.Printf("- %s (~%d bytes)", file.Path, file.Size)
+			default:
+				log.Printf("- %s (%d bytes)", file.Path, file.Size)
+			}
+		}
+	}
+	if plan.Upload {
+		log.Infof("The identities and profiles would be uploaded to bitrise.io")
+	}
+}
@@ -0,0 +1,189 @@
+package codesign
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/bitrise-io/codesigndoc/keychain"
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/bitrise-io/codesigndoc/prompt"
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// ElevatedExportCommand is the hidden command which exports the identities as root,
+// it is run with sudo if the system keychain denies the export of a private key.
+const ElevatedExportCommand = "elevated-export"
+
+// ElevatedExportRequest is read by the elevated export command from its standard input,
+// so the passphrase does not show up in the process list.
+type ElevatedExportRequest struct {
+	Identities []ElevatedExportIdentity `json:"identities"`
+	Format     keychain.ExportFormat    `json:"format"`
+	// Each exports every identity into a separate file, instead of a single merged one
+	Each       bool   `json:"each"`
+	Passphrase string `json:"passphrase"`
+}
+
+// ElevatedExportIdentity is an identity to export, searched in its keychain only
+type ElevatedExportIdentity struct {
+	KeychainPath    string `json:"keychain_path"`
+	Label           string `json:"label"`
+	SHA1Fingerprint string `json:"sha1_fingerprint"`
+}
+
+// elevatedExportResponse is written by the elevated export command to its standard output
+type elevatedExportResponse struct {
+	Contents [][]byte `json:"contents"`
+}
+
+// needsElevation returns true if the export failed because of an identity of the system keychain, and the process is not root
+func needsElevation(err error, identities []*keychain.Identity) bool {
+	if os.Geteuid() == 0 {
+		return false
+	}
+	if !errors.Is(err, osxkeychain.ErrAccessDenied) && !errors.Is(err, osxkeychain.ErrAuthFailed) && !errors.Is(err, osxkeychain.ErrInteractionNotAllowed) {
+		return false
+	}
+	for _, identity := range identities {
+		if identity.KeychainPath == osxkeychain.SystemKeychainPath {
+			return true
+		}
+	}
+	return false
+}
+
+// exportFromKeychain exports the identities of the Keychain merged into a single file, or one by one if each is set,
+// and re-executes the export with sudo if the system keychain denies it.
+func exportFromKeychain(ctx context.Context, kc keychain.Keychain, identities []*keychain.Identity, format keychain.ExportFormat, each bool, config IdentityExportConfig) ([][]byte, error) {
+	options := keychain.ExportOptions{Format: format, AskForPassword: config.AskForPassword, Passphrase: config.Passphrase}
+
+	var contents [][]byte
+	err := withPromptRetry(ctx, func() error {
+		var err error
+		if each || format == keychain.ExportFormatPKCS8 {
+			contents, err = kc.ExportEach(ctx, identities, options)
+		} else {
+			var content []byte
+			content, err = kc.Export(ctx, identities, options)
+			contents = [][]byte{content}
+		}
+		return err
+	})
+	if err == nil || !needsElevation(err, identities) {
+		return contents, err
+	}
+	return elevatedExport(ctx, err, identities, format, each, config)
+}
+
+// exportFromKeychainTo exports the identities of the Keychain merged into a single file and writes it to w,
+// the export is re-executed with sudo if the system keychain denies it
+func exportFromKeychainTo(ctx context.Context, kc keychain.Keychain, w io.Writer, identities []*keychain.Identity, format keychain.ExportFormat, config IdentityExportConfig) (int64, error) {
+	options := keychain.ExportOptions{Format: format, AskForPassword: config.AskForPassword, Passphrase: config.Passphrase}
+
+	var written int64
+	err := withPromptRetry(ctx, func() error {
+		var err error
+		// the prompt fails before anything is written, so the retried export does not duplicate the content
+		written, err = kc.ExportTo(ctx, w, identities, options)
+		return err
+	})
+	if err == nil || written > 0 || !needsElevation(err, identities) {
+		return written, err
+	}
+	contents, err := elevatedExport(ctx, err, identities, format, false, config)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(contents[0])
+	return int64(n), err
+}
+
+// elevatedExport re-runs the export denied by the system keychain with sudo
+func elevatedExport(ctx context.Context, err error, identities []*keychain.Identity, format keychain.ExportFormat, each bool, config IdentityExportConfig) ([][]byte, error) {
+	fmt.Println()
+	log.Warnf("Exporting the private keys of the system keychain (%s) requires admin rights: %s", osxkeychain.SystemKeychainPath, err)
+	if config.AskForPassword {
+		return nil, fmt.Errorf("the Keychain password prompt is not available for the admin export, set the .p12 password with --pass-stdin or the env var instead of --ask-pass: %w", err)
+	}
+	log.Printf("Re-running the export with sudo, enter the password of an admin user if asked")
+	request := ElevatedExportRequest{Format: format, Each: each, Passphrase: config.Passphrase}
+	for _, identity := range identities {
+		request.Identities = append(request.Identities, ElevatedExportIdentity{KeychainPath: identity.KeychainPath, Label: identity.Label, SHA1Fingerprint: identity.SHA1Fingerprint()})
+	}
+	return runElevatedExport(ctx, request)
+}
+
+// runElevatedExport runs the elevated export command of this executable with sudo
+func runElevatedExport(ctx context.Context, request ElevatedExportRequest) ([][]byte, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the codesigndoc executable, error: %s", err)
+	}
+	in, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{executable, ElevatedExportCommand}
+	if prompt.NonInteractive {
+		// fail instead of asking for the admin password
+		args = append([]string{"-n"}, args...)
+	}
+	var out bytes.Buffer
+	cmd := command.NewWithCmd(exec.CommandContext(ctx, "sudo", args...)).SetStdin(bytes.NewReader(in)).SetStdout(&out).SetStderr(os.Stderr)
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to export as admin with sudo, error: %s", err)
+	}
+
+	var response elevatedExportResponse
+	if err := json.Unmarshal(out.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("failed to read the result of the admin export, error: %s", err)
+	}
+	if len(response.Contents) == 0 || request.Each && len(response.Contents) != len(request.Identities) {
+		return nil, fmt.Errorf("the admin export returned %d file(s) for %d identities", len(response.Contents), len(request.Identities))
+	}
+	return response.Contents, nil
+}
+
+// RunElevatedExport reads an ElevatedExportRequest from in, exports the identities from their keychains
+// and writes the exported files to out. It is the implementation of the ElevatedExportCommand.
+func RunElevatedExport(in io.Reader, out io.Writer) error {
+	var request ElevatedExportRequest
+	if err := json.NewDecoder(in).Decode(&request); err != nil {
+		return fmt.Errorf("failed to read the export request, error: %s", err)
+	}
+
+	ctx := context.Background()
+	// the identities are searched in their keychain files, the export only uses their references
+	kc := osxkeychain.NewKeychain("")
+	var identities []*keychain.Identity
+	defer func() { _ = keychain.CloseAll(identities) }()
+	for _, item := range request.Identities {
+		query := keychain.Query{Labels: []string{item.Label}}
+		if item.SHA1Fingerprint != "" {
+			query.Fingerprints = []string{item.SHA1Fingerprint}
+		}
+		found, err := osxkeychain.NewKeychain(item.KeychainPath).FindIdentities(ctx, query)
+		if err != nil {
+			return fmt.Errorf("failed to find identity (%s) in %s, error: %s", item.Label, item.KeychainPath, err)
+		}
+		if len(found) == 0 {
+			return fmt.Errorf("identity (%s) [%s] not found in %s", item.Label, item.SHA1Fingerprint, item.KeychainPath)
+		}
+		identities = append(identities, found[0])
+		_ = keychain.CloseAll(found[1:])
+	}
+
+	contents, err := exportFromKeychain(ctx, kc, identities, request.Format, request.Each, IdentityExportConfig{Passphrase: request.Passphrase})
+	if err != nil {
+		return keychainExportError(err)
+	}
+	return json.NewEncoder(out).Encode(elevatedExportResponse{Contents: contents})
+}
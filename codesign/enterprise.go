@@ -0,0 +1,55 @@
+package codesign
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// enterpriseCertificates returns the certificates of the In-House (enterprise) profiles, which provision all devices:
+// anyone with such an identity can sign apps installable on any device, in the name of the company.
+func enterpriseCertificates(certificates []certificateutil.CertificateInfoModel, profiles []profileutil.ProvisioningProfileInfoModel) []certificateutil.CertificateInfoModel {
+	enterprise := map[string]bool{}
+	for _, profile := range profiles {
+		if !profile.ProvisionsAllDevices {
+			continue
+		}
+		for _, certificate := range profile.DeveloperCertificates {
+			enterprise[certificate.SHA1Fingerprint] = true
+		}
+	}
+
+	var enterpriseCertificates []certificateutil.CertificateInfoModel
+	for _, certificate := range certificates {
+		if enterprise[certificate.SHA1Fingerprint] {
+			enterpriseCertificates = append(enterpriseCertificates, certificate)
+		}
+	}
+	return enterpriseCertificates
+}
+
+// CheckEnterpriseIdentities labels the enterprise distribution identities among the certificates (see enterpriseCertificates),
+// their export fails unless it is allowed by IdentityExportConfig.AllowEnterprise.
+func CheckEnterpriseIdentities(certificates []certificateutil.CertificateInfoModel, profiles []profileutil.ProvisioningProfileInfoModel, config IdentityExportConfig) error {
+	enterprise := enterpriseCertificates(certificates, profiles)
+	if len(enterprise) == 0 {
+		return nil
+	}
+
+	fmt.Println()
+	log.Warnf("🚨  Enterprise (In-House) distribution identities:")
+	var names []string
+	for _, certificate := range enterprise {
+		log.Warnf("- [ENTERPRISE] %s", certificate)
+		names = append(names, certificate.CommonName)
+	}
+	log.Warnf("Apps signed with these identities install on any device without App Store review.")
+	log.Warnf("A leaked enterprise identity can get the Apple Developer Enterprise account of the company revoked, share the export with care.")
+	if !config.AllowEnterprise {
+		return fmt.Errorf("the export contains enterprise distribution identities (%s), pass --allow-enterprise to export them", strings.Join(names, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package codesign
+
+import (
+	"testing"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnterpriseCertificates(t *testing.T) {
+	enterprise := certificateutil.CertificateInfoModel{CommonName: "iPhone Distribution: Company", SHA1Fingerprint: "aaaa"}
+	adHoc := certificateutil.CertificateInfoModel{CommonName: "iPhone Distribution: Client", SHA1Fingerprint: "bbbb"}
+	profiles := []profileutil.ProvisioningProfileInfoModel{
+		{UUID: "in-house", ProvisionsAllDevices: true, DeveloperCertificates: []certificateutil.CertificateInfoModel{enterprise}},
+		{UUID: "ad-hoc", DeveloperCertificates: []certificateutil.CertificateInfoModel{adHoc}},
+	}
+
+	require.Equal(t, []certificateutil.CertificateInfoModel{enterprise}, enterpriseCertificates([]certificateutil.CertificateInfoModel{enterprise, adHoc}, profiles))
+	require.Nil(t, enterpriseCertificates([]certificateutil.CertificateInfoModel{adHoc}, profiles))
+	require.NoError(t, CheckEnterpriseIdentities([]certificateutil.CertificateInfoModel{adHoc}, profiles, IdentityExportConfig{}))
+	require.Error(t, CheckEnterpriseIdentities([]certificateutil.CertificateInfoModel{enterprise}, profiles, IdentityExportConfig{}))
+	require.NoError(t, CheckEnterpriseIdentities([]certificateutil.CertificateInfoModel{enterprise}, profiles, IdentityExportConfig{AllowEnterprise: true}))
+}
@@ -0,0 +1,138 @@
+package codesign
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-xcode/plistutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// EntitlementMismatch is an entitlement of the target which the profile does not grant
+type EntitlementMismatch struct {
+	Key string
+	// Missing is true if the profile does not have the entitlement at all
+	Missing bool
+	// Values are the values of the target not granted by the profile, e.g. an app group or a domain
+	Values []string
+}
+
+func (mismatch EntitlementMismatch) String() string {
+	if mismatch.Missing {
+		return fmt.Sprintf("%s is missing from the profile", mismatch.Key)
+	}
+	return fmt.Sprintf("%s does not grant: %s", mismatch.Key, strings.Join(mismatch.Values, ", "))
+}
+
+// presenceOnlyEntitlements are set to the profile's value during the signing, e.g. a development aps-environment
+// of the .entitlements file is signed as production with a distribution profile.
+var presenceOnlyEntitlements = map[string]bool{
+	"aps-environment":                     true,
+	"com.apple.developer.aps-environment": true,
+}
+
+// CompareEntitlements returns the capabilities of the target entitlements not granted by the profile entitlements,
+// the keys not provisioned by profiles (see profileutil.KnownProfileCapabilitiesMap) are ignored.
+func CompareEntitlements(target, profile plistutil.PlistData, profileType profileutil.ProfileType) []EntitlementMismatch {
+	var keys []string
+	for key := range target {
+		if profileutil.KnownProfileCapabilitiesMap[profileType][key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var mismatches []EntitlementMismatch
+	for _, key := range keys {
+		profileValue, ok := profile[key]
+		if !ok {
+			mismatches = append(mismatches, EntitlementMismatch{Key: key, Missing: true})
+			continue
+		}
+		if presenceOnlyEntitlements[key] {
+			continue
+		}
+
+		granted := entitlementValues(profileValue)
+		var notGranted []string
+		for _, value := range entitlementValues(target[key]) {
+			if !isEntitlementValueGranted(value, granted) {
+				notGranted = append(notGranted, value)
+			}
+		}
+		if len(notGranted) > 0 {
+			mismatches = append(mismatches, EntitlementMismatch{Key: key, Values: notGranted})
+		}
+	}
+	return mismatches
+}
+
+// entitlementValues returns the string values of an entitlement, a boolean has none
+func entitlementValues(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	case []string:
+		return v
+	}
+	return nil
+}
+
+// isEntitlementValueGranted returns true if any of the profile's values covers the value,
+// e.g. the profile's * covers any associated domain and TEAM.* covers any keychain group of the team
+func isEntitlementValueGranted(value string, granted []string) bool {
+	// the build setting variables of the .entitlements files are expanded only during the build
+	if strings.Contains(value, "$(") {
+		return true
+	}
+	for _, g := range granted {
+		if g == value || strings.HasSuffix(g, "*") && strings.HasPrefix(value, strings.TrimSuffix(g, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadTargetEntitlements reads the entitlements of an .entitlements (or .xcent, .plist) file,
+// or the entitlements the .app or .appex bundle was signed with.
+func ReadTargetEntitlements(pth string) (plistutil.PlistData, error) {
+	info, err := os.Stat(pth)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		entitlements, err := plistutil.NewPlistDataFromFile(pth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read entitlements (%s), error: %s", pth, err)
+		}
+		return entitlements, nil
+	}
+
+	if ext := filepath.Ext(pth); ext != ".app" && ext != ".appex" {
+		return nil, fmt.Errorf("%s is neither an entitlements file nor an .app or .appex bundle", pth)
+	}
+	out, err := command.New("codesign", "-d", "--entitlements", ":-", pth).RunAndReturnTrimmedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the entitlements of %s, output: %s, error: %s", pth, out, err)
+	}
+	if out == "" {
+		return plistutil.PlistData{}, nil
+	}
+	entitlements, err := plistutil.NewPlistDataFromContent(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the entitlements of %s, error: %s", pth, err)
+	}
+	return entitlements, nil
+}
@@ -0,0 +1,36 @@
+package codesign
+
+import (
+	"testing"
+
+	"github.com/bitrise-io/go-xcode/plistutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareEntitlements(t *testing.T) {
+	target := plistutil.PlistData{
+		"aps-environment":                        "development",
+		"com.apple.developer.associated-domains": []interface{}{"applinks:example.com", "webcredentials:example.com"},
+		"com.apple.security.application-groups":  []interface{}{"group.io.bitrise.app", "group.io.bitrise.$(TARGET_NAME)"},
+		"keychain-access-groups":                 []interface{}{"TEAM.io.bitrise.app"},
+		"com.apple.developer.healthkit":          true,
+		"get-task-allow":                         true,
+	}
+	profile := plistutil.PlistData{
+		"aps-environment":                        "production",
+		"com.apple.developer.associated-domains": "*",
+		"com.apple.security.application-groups":  []interface{}{"group.io.bitrise.other"},
+		"keychain-access-groups":                 []interface{}{"TEAM.*"},
+	}
+
+	mismatches := CompareEntitlements(target, profile, profileutil.ProfileTypeIos)
+	require.Equal(t, []EntitlementMismatch{
+		{Key: "com.apple.developer.healthkit", Missing: true},
+		{Key: "com.apple.security.application-groups", Values: []string{"group.io.bitrise.app"}},
+	}, mismatches)
+	require.Equal(t, "com.apple.developer.healthkit is missing from the profile", mismatches[0].String())
+	require.Equal(t, "com.apple.security.application-groups does not grant: group.io.bitrise.app", mismatches[1].String())
+
+	require.Empty(t, CompareEntitlements(plistutil.PlistData{"get-task-allow": true}, plistutil.PlistData{}, profileutil.ProfileTypeIos))
+}
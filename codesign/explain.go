@@ -0,0 +1,227 @@
+package codesign
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/keychain"
+	"github.com/bitrise-io/codesigndoc/utility"
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// installedIdentity is an identity of the keychains, as listed by the no match diagnostics
+type installedIdentity struct {
+	Label           string
+	SHA1Fingerprint string
+	NotAfter        time.Time
+	KeychainPath    string
+}
+
+// keychainCertificate is a certificate of the keychains, with or without a private key
+type keychainCertificate struct {
+	Label           string
+	SHA1Fingerprint string
+}
+
+// noMatchFindings are the likely causes of a required identity not found in the keychains
+type noMatchFindings struct {
+	Label string
+	// NearMisses are the labels of the installed identities similar to the required one
+	NearMisses []string
+	// Expired are the installed identities of the label which are expired
+	Expired []installedIdentity
+	// WithoutPrivateKey are the fingerprints of the certificates of the label installed without their private key
+	WithoutPrivateKey []string
+	// MissingProfileCertificates are the fingerprints of the certificates of the label by profile name,
+	// which are in an installed profile but not installed as an identity
+	MissingProfileCertificates map[string][]string
+}
+
+// explainNoMatch looks for the causes of the required identity not found in the keychains, and prints the remediation steps
+func explainNoMatch(ctx context.Context, kc keychain.Keychain, label string) {
+	fmt.Println()
+	log.Infof("Looking for the reason why the identity (%s) was not found...", label)
+
+	identities, err := kc.FindIdentities(ctx, keychain.Query{IncludeExpired: true})
+	if err != nil {
+		log.Warnf("Failed to list the installed identities: %s", err)
+		return
+	}
+	var installed []installedIdentity
+	for _, identity := range identities {
+		installed = append(installed, installedIdentity{Label: identity.Label, SHA1Fingerprint: identity.SHA1Fingerprint(), NotAfter: identity.NotAfter(), KeychainPath: identity.KeychainPath})
+	}
+	_ = keychain.CloseAll(identities)
+
+	var certificates []keychainCertificate
+	if out, err := command.New("security", "find-certificate", "-a", "-Z", "-c", label).RunAndReturnTrimmedCombinedOutput(); err != nil {
+		log.Debugf("Failed to list the certificates of %s: %s %s", label, out, err)
+	} else {
+		certificates = parseFindCertificateOutput(out)
+	}
+
+	var profiles []profileutil.ProvisioningProfileInfoModel
+	for _, profileType := range []profileutil.ProfileType{profileutil.ProfileTypeIos, profileutil.ProfileTypeMacOs} {
+		typeProfiles, err := utility.InstalledProfileInfos(profileType)
+		if err != nil {
+			log.Debugf("Failed to list the installed %s profiles: %s", profileType, err)
+			continue
+		}
+		profiles = append(profiles, typeProfiles...)
+	}
+
+	printNoMatchGuide(findNoMatchCauses(label, installed, certificates, profiles, time.Now()))
+}
+
+// findNoMatchCauses collects the findings of the no match diagnostics
+func findNoMatchCauses(label string, identities []installedIdentity, certificates []keychainCertificate, profiles []profileutil.ProvisioningProfileInfoModel, now time.Time) noMatchFindings {
+	findings := noMatchFindings{Label: label, MissingProfileCertificates: map[string][]string{}}
+
+	identityFingerprints := map[string]bool{}
+	var labels []string
+	for _, identity := range identities {
+		identityFingerprints[strings.ToLower(identity.SHA1Fingerprint)] = true
+		labels = append(labels, identity.Label)
+		if identity.Label == label && identity.NotAfter.Before(now) {
+			findings.Expired = append(findings.Expired, identity)
+		}
+	}
+	findings.NearMisses = nearMissLabels(label, labels)
+
+	for _, certificate := range certificates {
+		if certificate.Label == label && !identityFingerprints[strings.ToLower(certificate.SHA1Fingerprint)] {
+			findings.WithoutPrivateKey = append(findings.WithoutPrivateKey, certificate.SHA1Fingerprint)
+		}
+	}
+
+	for _, profile := range profiles {
+		for _, certificate := range profile.DeveloperCertificates {
+			if certificate.CommonName == label && !identityFingerprints[strings.ToLower(certificate.SHA1Fingerprint)] {
+				findings.MissingProfileCertificates[profile.Name] = append(findings.MissingProfileCertificates[profile.Name], certificate.SHA1Fingerprint)
+			}
+		}
+	}
+	return findings
+}
+
+// printNoMatchGuide prints the findings with a step by step remediation guide
+func printNoMatchGuide(findings noMatchFindings) {
+	var steps []string
+	if len(findings.Expired) > 0 {
+		for _, identity := range findings.Expired {
+			log.Warnf("- expired on %s: %s [%s] in %s", identity.NotAfter.Format("2006-01-02"), identity.Label, identity.SHA1Fingerprint, keychainOf(identity.KeychainPath))
+		}
+		steps = append(steps, "Renew the expired certificate (Xcode > Settings > Accounts > Manage Certificates, or the Apple Developer Portal), regenerate the profiles with the new certificate, then scan again. Use --include-expired to export the expired identity anyway.")
+	}
+	if len(findings.WithoutPrivateKey) > 0 {
+		for _, fingerprint := range findings.WithoutPrivateKey {
+			log.Warnf("- installed without its private key: %s [%s]", findings.Label, fingerprint)
+		}
+		steps = append(steps, "Import the identity (with the private key) from the machine which created the certificate signing request: export it there as a .p12, and install it here with: codesigndoc import <.p12>. If the private key is lost, revoke the certificate and create a new one.")
+	}
+	if len(findings.NearMisses) > 0 {
+		for _, nearMiss := range findings.NearMisses {
+			log.Warnf("- similar installed identity: %s", nearMiss)
+		}
+		steps = append(steps, fmt.Sprintf("Check the code signing identity of the project (CODE_SIGN_IDENTITY): it requires %s, but only similar identities are installed. Select the installed identity in the project, or install the required one.", findings.Label))
+	}
+	if len(findings.MissingProfileCertificates) > 0 {
+		for name, fingerprints := range findings.MissingProfileCertificates {
+			log.Warnf("- the profile %s contains the certificate(s) %s of %s, which is not installed as an identity", name, strings.Join(fingerprints, ", "), findings.Label)
+		}
+		steps = append(steps, "Install the identity of the certificate contained by the profiles, or regenerate the profiles with an installed certificate and download them (Xcode > Settings > Accounts > Download Manual Profiles).")
+	}
+	if len(steps) == 0 {
+		log.Warnf("- no installed identity, certificate or profile is related to %s", findings.Label)
+		steps = append(steps, "Install the identity: import its .p12 with codesigndoc import <.p12>, or create it in Xcode > Settings > Accounts > Manage Certificates.")
+	}
+	steps = append(steps, "Make sure the keychain of the identity is in the search list (security list-keychains) or given by its path with --identity-keychain, and unlocked, and that the --team-id, --identity-type, --identity-fingerprint and --identity-keychain flags, if set, select it.")
+
+	fmt.Println()
+	log.Infof("How to fix it:")
+	for i, step := range steps {
+		log.Printf("%d. %s", i+1, step)
+	}
+}
+
+// parseFindCertificateOutput returns the certificates of the security find-certificate -Z output
+func parseFindCertificateOutput(out string) []keychainCertificate {
+	var certificates []keychainCertificate
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if fingerprint := strings.TrimPrefix(line, "SHA-1 hash: "); fingerprint != line {
+			certificates = append(certificates, keychainCertificate{SHA1Fingerprint: fingerprint})
+			continue
+		}
+		if label := strings.TrimPrefix(line, `"labl"<blob>="`); label != line && len(certificates) > 0 {
+			certificates[len(certificates)-1].Label = strings.TrimSuffix(label, `"`)
+		}
+	}
+	return certificates
+}
+
+// nearMissLabels returns the labels similar to the label: the same name with a different case or certificate type
+// (e.g. iPhone Distribution and Apple Distribution of the same team), or with a few typos
+func nearMissLabels(label string, labels []string) []string {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.Join(strings.Fields(s), " "))
+	}
+	owner := func(s string) string {
+		if i := strings.Index(s, ":"); i >= 0 {
+			return strings.TrimSpace(s[i+1:])
+		}
+		return ""
+	}
+
+	seen := map[string]bool{label: true}
+	var nearMisses []string
+	for _, candidate := range labels {
+		if seen[candidate] {
+			continue
+		}
+		near := normalize(candidate) == normalize(label) ||
+			(owner(label) != "" && normalize(owner(candidate)) == normalize(owner(label))) ||
+			editDistance(normalize(candidate), normalize(label)) <= 3
+		if near {
+			seen[candidate] = true
+			nearMisses = append(nearMisses, candidate)
+		}
+	}
+	return nearMisses
+}
+
+// editDistance returns the Levenshtein distance of the strings
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current := make([]int, len(rb)+1)
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(rb)]
+}
+
+func minInt(values ...int) int {
+	min := values[0]
+	for _, value := range values[1:] {
+		if value < min {
+			min = value
+		}
+	}
+	return min
+}
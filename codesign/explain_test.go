@@ -0,0 +1,75 @@
+package codesign
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNearMissLabels(t *testing.T) {
+	labels := []string{
+		"iPhone Distribution: Bitrise Inc (ABCD123456)",
+		"Apple Distribution: Bitrise Inc (ABCD123456)",
+		"apple distribution: bitrise inc (ABCD123456)",
+		"Apple Development: John Doe (EFGH123456)",
+		"Apple Distribution: Bitrise Inc (ABCD123456)",
+	}
+	require.Equal(t, []string{"iPhone Distribution: Bitrise Inc (ABCD123456)", "apple distribution: bitrise inc (ABCD123456)"}, nearMissLabels("Apple Distribution: Bitrise Inc (ABCD123456)", labels))
+	require.Equal(t, []string{"Apple Development: John Doe (EFGH123456)"}, nearMissLabels("Apple Development: Jon Doe (EFGH123456)", labels))
+}
+
+func TestEditDistance(t *testing.T) {
+	require.Equal(t, 0, editDistance("", ""))
+	require.Equal(t, 3, editDistance("abc", ""))
+	require.Equal(t, 3, editDistance("kitten", "sitting"))
+	require.Equal(t, 0, editDistance("same", "same"))
+}
+
+func TestParseFindCertificateOutput(t *testing.T) {
+	out := `SHA-1 hash: 0123456789ABCDEF0123456789ABCDEF01234567
+keychain: "/Users/vagrant/Library/Keychains/login.keychain-db"
+version: 512
+class: 0x80001000
+attributes:
+    "alis"<blob>="Apple Distribution: Bitrise Inc (ABCD123456)"
+    "labl"<blob>="Apple Distribution: Bitrise Inc (ABCD123456)"
+SHA-1 hash: 89ABCDEF0123456789ABCDEF0123456789ABCDEF
+keychain: "/Library/Keychains/System.keychain"
+attributes:
+    "labl"<blob>="Apple Distribution: Bitrise Inc (ABCD123456)"`
+
+	require.Equal(t, []keychainCertificate{
+		{Label: "Apple Distribution: Bitrise Inc (ABCD123456)", SHA1Fingerprint: "0123456789ABCDEF0123456789ABCDEF01234567"},
+		{Label: "Apple Distribution: Bitrise Inc (ABCD123456)", SHA1Fingerprint: "89ABCDEF0123456789ABCDEF0123456789ABCDEF"},
+	}, parseFindCertificateOutput(out))
+}
+
+func TestFindNoMatchCauses(t *testing.T) {
+	label := "Apple Distribution: Bitrise Inc (ABCD123456)"
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	identities := []installedIdentity{
+		{Label: label, SHA1Fingerprint: "expired", NotAfter: now.AddDate(0, -1, 0)},
+		{Label: "iPhone Distribution: Bitrise Inc (ABCD123456)", SHA1Fingerprint: "other", NotAfter: now.AddDate(1, 0, 0)},
+	}
+	certificates := []keychainCertificate{
+		{Label: label, SHA1Fingerprint: "EXPIRED"},
+		{Label: label, SHA1Fingerprint: "nokey"},
+	}
+	profiles := []profileutil.ProvisioningProfileInfoModel{
+		{Name: "App Store", DeveloperCertificates: []certificateutil.CertificateInfoModel{
+			{CommonName: label, SHA1Fingerprint: "expired"},
+			{CommonName: label, SHA1Fingerprint: "missing"},
+			{CommonName: "iPhone Distribution: Bitrise Inc (ABCD123456)", SHA1Fingerprint: "unrelated"},
+		}},
+	}
+
+	findings := findNoMatchCauses(label, identities, certificates, profiles, now)
+	require.Equal(t, 1, len(findings.Expired))
+	require.Equal(t, "expired", findings.Expired[0].SHA1Fingerprint)
+	require.Equal(t, []string{"nokey"}, findings.WithoutPrivateKey)
+	require.Equal(t, []string{"iPhone Distribution: Bitrise Inc (ABCD123456)"}, findings.NearMisses)
+	require.Equal(t, map[string][]string{"App Store": {"missing"}}, findings.MissingProfileCertificates)
+}
@@ -1,34 +1,66 @@
 package codesign
 
 import (
+	"context"
+	"crypto/sha1"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/bitrise-io/codesigndoc/bitriseio"
 	"github.com/bitrise-io/codesigndoc/bitriseio/bitrise"
+	"github.com/bitrise-io/codesigndoc/exitcode"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/keychain"
 	"github.com/bitrise-io/codesigndoc/models"
+	"github.com/bitrise-io/codesigndoc/network"
 	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/bitrise-io/codesigndoc/prompt"
+	"github.com/bitrise-io/codesigndoc/secretstore"
+	"github.com/bitrise-io/codesigndoc/state"
 	"github.com/bitrise-io/codesigndoc/utility"
 	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-io/go-xcode/certificateutil"
 	"github.com/bitrise-io/go-xcode/profileutil"
-	"github.com/bitrise-io/goinp/goinp"
+)
+
+const (
+	// FilesDestinationFeature is the feature name of writing the code signing files to the export directory
+	FilesDestinationFeature = "destination/files"
+	// BitriseDestinationFeature is the feature name of uploading the code signing files to bitrise.io
+	BitriseDestinationFeature = "destination/bitrise"
 )
 
 // UploadConfig contains configuration to automatically upload artifacts to bitrise.io
 type UploadConfig struct {
 	PersonalAccessToken string
 	AppSlug             string
+	// RollbackOnFailure removes the already uploaded files if any of the uploads fails
+	RollbackOnFailure bool
+	// SecretStore receives the exported .p12 (base64) and its passphrase as a secret, nil if it is not set
+	SecretStore secretstore.Store
 }
 
 // WriteFilesConfig controls writing artifacts as files
 type WriteFilesConfig struct {
 	WriteFiles       WriteFilesLevel
 	AbsOutputDirPath string
+	Match            MatchRepoConfig
+	Naming           NamingConfig
+	// CertificateFiles writes the certificate of every exported identity as DER (.cer) and PEM (.crt.pem) too,
+	// for the services which need the certificate without the private key, e.g. push providers and MDM
+	CertificateFiles bool
+	// PerTeam exports the files of every Apple Developer team separately, into a directory named after the team ID
+	// in the output directory, see TeamDirName
+	PerTeam bool
 }
 
 // WriteFilesLevel describes if codesigning files should be written to the output directory
@@ -50,84 +82,265 @@ type ExportReport struct {
 	CertificatesUploaded         bool
 	ProvisioningProfilesUploaded bool
 	CodesignFilesWritten         bool
+
+	Certificates []certificateutil.CertificateInfoModel
+	Profiles     []profileutil.ProvisioningProfileInfoModel
+	// Files are the paths of the written codesigning files
+	Files []string
+	// Teams are the exports of the teams with WriteFilesConfig.PerTeam
+	Teams []TeamExportReport
+}
+
+// TeamExportReport describes the export of a team's files with WriteFilesConfig.PerTeam
+type TeamExportReport struct {
+	TeamID   string
+	TeamName string
+	// AbsOutputDirPath is the output directory of the team's files
+	AbsOutputDirPath string
+	ExportReport
+}
+
+// newExportReport describes the exported files, the upload state has to be set by the caller
+func newExportReport(certificates models.Certificates, profiles []models.ProvisioningProfile, writeFilesConfig WriteFilesConfig, filesWritten bool) ExportReport {
+	report := ExportReport{
+		CodesignFilesWritten: filesWritten,
+		Certificates:         certificates.Info,
+	}
+	for _, profile := range profiles {
+		report.Profiles = append(report.Profiles, profile.Info)
+	}
+	if filesWritten {
+		report.Files = writtenFilePaths(certificates, profiles, writeFilesConfig)
+		if writeFilesConfig.Match.DirPath != "" {
+			if files, err := matchFiles(certificates, profiles, writeFilesConfig.Match); err == nil {
+				for _, file := range files {
+					report.Files = append(report.Files, filepath.Join(writeFilesConfig.Match.DirPath, file.Path))
+				}
+			}
+		}
+	}
+	return report
+}
+
+// IdentityExportConfig controls how the identities are exported from the Keychain
+type IdentityExportConfig struct {
+	// AskForPassword shows the Keychain prompt for the passphrase of the .p12 file
+	AskForPassword bool
+	// Passphrase protects the .p12 file if AskForPassword is not set, an empty passphrase is used by default
+	Passphrase string
+	// PEM exports the identities as a PEM sequence too, for openssl based tools; the private keys are protected by the same passphrase
+	PEM bool
+	// Each exports every identity into a separate .p12 file too, named after the certificate's common name and serial
+	Each bool
+	// SeparateKeys exports the private key of every identity as an encrypted PKCS#8 PEM and its certificate as DER and PEM too,
+	// for the signing systems which can not import a .p12; the private keys are protected by the passphrase
+	SeparateKeys bool
+	// Types restricts the exported identities to the given certificate types, e.g. to not export development certificates for App Store builds
+	Types []osxkeychain.CertificateType
+	// Deduplicate exports only one copy of the identities required multiple times
+	Deduplicate bool
+	// Chain exports the intermediate certificates (e.g. Apple WWDR G3) of the identities as separate .cer files too
+	Chain bool
+	// IncludeExpired exports the expired and not yet valid identities too, e.g. for archival
+	IncludeExpired bool
+	// TeamIDs restricts the exported identities and profiles to the given Apple Developer Team IDs,
+	// the team ID of a certificate is the organizational unit of its subject
+	TeamIDs []string
+	// Fingerprints restricts the exported identities to the given SHA-1 or SHA-256 certificate fingerprints,
+	// for the identities with the same common name
+	Fingerprints []string
+	// Keychains restricts the exported identities to the given keychain files or names (e.g. login),
+	// for the identities installed both in the login and in a CI keychain
+	Keychains []string
+	// InspectKeyAccess logs the applications which can sign with the private keys without a Keychain prompt
+	InspectKeyAccess bool
+	// AuthorizeApplications are added to the access control list of the private keys after a confirmation,
+	// so the export and the signing with the tools do not show Keychain prompts
+	AuthorizeApplications []string
+	// TokenCertificatesOnly exports only the certificates of the identities with the private key on a hardware token
+	// (e.g. a smart card or a YubiKey) without asking, the export fails because of these identities otherwise
+	TokenCertificatesOnly bool
+	// AllowEnterprise allows the export of the enterprise (In-House) distribution identities, see CheckEnterpriseIdentities
+	AllowEnterprise bool
+	// SkipRevocationCheck skips the online OCSP revocation check of the exported identities, e.g. on offline machines
+	SkipRevocationCheck bool
+	// Keychain searches and exports the identities, the user's keychain search list (osxkeychain.Keychain) if nil
+	Keychain keychain.Keychain
+}
+
+// ExportHooks are called as the code signing files become ready during the export,
+// an error returned by a hook stops the export.
+type ExportHooks struct {
+	ProfileExported    func(profile models.ProvisioningProfile) error
+	IdentitiesExported func(certificates models.Certificates) error
 }
 
 // ExportCodesigningFiles exports certificates from the Keychain and provisoining profiles from their directory
-func ExportCodesigningFiles(certificatesRequired []certificateutil.CertificateInfoModel, profilesRequired []profileutil.ProvisioningProfileInfoModel, askForPassword bool) (models.Certificates, []models.ProvisioningProfile, error) {
-	certificates, err := exportIdentities(certificatesRequired, askForPassword)
+func ExportCodesigningFiles(ctx context.Context, certificatesRequired []certificateutil.CertificateInfoModel, profilesRequired []profileutil.ProvisioningProfileInfoModel, identityConfig IdentityExportConfig) (models.Certificates, []models.ProvisioningProfile, error) {
+	return ExportCodesigningFilesWithHooks(ctx, certificatesRequired, profilesRequired, identityConfig, ExportHooks{})
+}
+
+// ExportCodesigningFilesWithHooks exports the provisioning profiles first, as they are ready right after matching,
+// then the certificates from the Keychain, and calls the hooks with each exported file.
+func ExportCodesigningFilesWithHooks(ctx context.Context, certificatesRequired []certificateutil.CertificateInfoModel, profilesRequired []profileutil.ProvisioningProfileInfoModel, identityConfig IdentityExportConfig, hooks ExportHooks) (models.Certificates, []models.ProvisioningProfile, error) {
+	certificatesRequired, profilesRequired, err := filterTeams(certificatesRequired, profilesRequired, identityConfig.TeamIDs)
 	if err != nil {
 		return models.Certificates{}, nil, err
 	}
+	if err := CheckEnterpriseIdentities(certificatesRequired, profilesRequired, identityConfig); err != nil {
+		return models.Certificates{}, nil, err
+	}
 
 	profiles, err := exportProvisioningProfiles(profilesRequired)
 	if err != nil {
 		return models.Certificates{}, nil, err
 	}
+	if hooks.ProfileExported != nil {
+		for _, profile := range profiles {
+			if err := hooks.ProfileExported(profile); err != nil {
+				return models.Certificates{}, nil, err
+			}
+		}
+	}
+
+	certificates, err := exportIdentities(ctx, certificatesRequired, identityConfig)
+	if err != nil {
+		return models.Certificates{}, nil, err
+	}
+	if hooks.IdentitiesExported != nil && len(certificates.Info) > 0 {
+		if err := hooks.IdentitiesExported(certificates); err != nil {
+			return models.Certificates{}, nil, err
+		}
+	}
 
 	return certificates, profiles, nil
 }
 
-// UploadAndWriteCodesignFiles exports then uploads codesign files to bitrise.io and saves them to output folder
-func UploadAndWriteCodesignFiles(certificates models.Certificates, provisioningProfiles []models.ProvisioningProfile, writeFilesConfig WriteFilesConfig, uploadConfig UploadConfig) (ExportReport, error) {
-	var client *bitrise.Client
+// uploadClient returns the client of the upload destination, either configured by the CLI flags or asked interactively,
+// nil is returned if the files are not uploaded.
+func uploadClient(uploadConfig UploadConfig, hasProfiles bool) (*bitrise.Client, error) {
 	// both or none CLI flags are required
 	if uploadConfig.PersonalAccessToken != "" && uploadConfig.AppSlug != "" {
 		// Upload automatically if token is provided as CLI paramter, do not export to filesystem
 		// Used to upload artifacts as part of an other CLI tool
-		var err error
-		client, err = bitrise.NewClient(uploadConfig.PersonalAccessToken)
+		client, err := bitrise.NewClient(uploadConfig.PersonalAccessToken)
 		if err != nil {
-			return ExportReport{}, err
+			return nil, err
 		}
 
 		client.SetSelectedAppSlug(uploadConfig.AppSlug)
+		return client, nil
 	}
 
-	if client == nil {
-		uploadConfirmMsg := "Do you want to upload the provisioning profiles and certificates to Bitrise?"
-		if len(provisioningProfiles) == 0 {
-			uploadConfirmMsg = "Do you want to upload the certificates to Bitrise?"
-		}
-		fmt.Println()
+	if !features.IsEnabled(BitriseDestinationFeature) || prompt.NonInteractive {
+		return nil, nil
+	}
 
-		shouldUpload, err := goinp.AskForBoolFromReader(uploadConfirmMsg, os.Stdin)
-		if err != nil {
-			return ExportReport{}, err
+	uploadConfirmMsg := "Do you want to upload the provisioning profiles and certificates to Bitrise?"
+	if !hasProfiles {
+		uploadConfirmMsg = "Do you want to upload the certificates to Bitrise?"
+	}
+	fmt.Println()
+
+	shouldUpload, err := prompt.AskForBoolFromReader(uploadConfirmMsg, os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	if !shouldUpload {
+		return nil, nil
+	}
+	return bitriseio.GetInteractiveConfigClient()
+}
+
+// ExportUploadAndWriteCodesignFiles exports the required codesign files, uploads each file to bitrise.io as soon as it is exported,
+// and saves them to the output folder.
+func ExportUploadAndWriteCodesignFiles(ctx context.Context, certificatesRequired []certificateutil.CertificateInfoModel, profilesRequired []profileutil.ProvisioningProfileInfoModel, identityConfig IdentityExportConfig, writeFilesConfig WriteFilesConfig, uploadConfig UploadConfig) (ExportReport, error) {
+	if writeFilesConfig.PerTeam {
+		return exportUploadAndWritePerTeam(ctx, certificatesRequired, profilesRequired, identityConfig, writeFilesConfig, uploadConfig)
+	}
+
+	client, err := uploadClient(uploadConfig, len(profilesRequired) > 0)
+	if err != nil {
+		return ExportReport{}, err
+	}
+
+	var hooks ExportHooks
+	var upload *bitriseio.ProgressiveUpload
+	if client != nil {
+		upload = bitriseio.StartProgressiveUpload(client, uploadConfig.RollbackOnFailure)
+		hooks = ExportHooks{
+			ProfileExported:    upload.AddProfile,
+			IdentitiesExported: upload.AddIdentities,
 		}
+	}
+	if uploadConfig.SecretStore != nil {
+		hooks.IdentitiesExported = storeIdentitiesSecret(uploadConfig.SecretStore, identityConfig.Passphrase, hooks.IdentitiesExported)
+	}
 
-		if shouldUpload {
-			if client, err = bitriseio.GetInteractiveConfigClient(); err != nil {
-				return ExportReport{}, err
-			}
+	certificates, profiles, err := ExportCodesigningFilesWithHooks(ctx, certificatesRequired, profilesRequired, identityConfig, hooks)
+	if err != nil {
+		if upload != nil {
+			return ExportReport{}, upload.Fail(err)
 		}
+		return ExportReport{}, err
 	}
 
-	var filesWritten bool
-	if writeFilesConfig.WriteFiles == WriteFilesAlways ||
-		writeFilesConfig.WriteFiles == WriteFilesFallback && client == nil {
-		if err := writeFiles(certificates, provisioningProfiles, writeFilesConfig); err != nil {
-			return ExportReport{}, err
+	filesWritten, err := writeCodesignFiles(certificates, profiles, writeFilesConfig, client != nil)
+	if err != nil {
+		if upload != nil {
+			return ExportReport{}, upload.Fail(err)
 		}
-		filesWritten = true
+		return ExportReport{}, err
+	}
+
+	report := newExportReport(certificates, profiles, writeFilesConfig, filesWritten)
+	if upload == nil {
+		report.CertificatesUploaded = len(certificates.Info) == 0
+		report.ProvisioningProfilesUploaded = len(profiles) == 0
+		return report, nil
+	}
+
+	report.CertificatesUploaded, report.ProvisioningProfilesUploaded, err = upload.Wait()
+	return report, exitcode.Wrap(exitcode.UploadFailed, err)
+}
+
+// UploadAndWriteCodesignFiles exports then uploads codesign files to bitrise.io and saves them to output folder
+func UploadAndWriteCodesignFiles(certificates models.Certificates, provisioningProfiles []models.ProvisioningProfile, writeFilesConfig WriteFilesConfig, uploadConfig UploadConfig) (ExportReport, error) {
+	client, err := uploadClient(uploadConfig, len(provisioningProfiles) > 0)
+	if err != nil {
+		return ExportReport{}, err
 	}
 
+	filesWritten, err := writeCodesignFiles(certificates, provisioningProfiles, writeFilesConfig, client != nil)
+	if err != nil {
+		return ExportReport{}, err
+	}
+
+	report := newExportReport(certificates, provisioningProfiles, writeFilesConfig, filesWritten)
 	if client == nil {
-		return ExportReport{
-			CertificatesUploaded:         len(certificates.Info) == 0,
-			ProvisioningProfilesUploaded: len(provisioningProfiles) == 0,
-			CodesignFilesWritten:         filesWritten,
-		}, nil
-	}
-
-	certificatesUploaded, profilesUploaded, err := bitriseio.UploadCodesigningFiles(client, certificates, provisioningProfiles)
-	return ExportReport{
-		CertificatesUploaded:         certificatesUploaded,
-		ProvisioningProfilesUploaded: profilesUploaded,
-		CodesignFilesWritten:         filesWritten,
-	}, err
+		report.CertificatesUploaded = len(certificates.Info) == 0
+		report.ProvisioningProfilesUploaded = len(provisioningProfiles) == 0
+		return report, nil
+	}
+
+	report.CertificatesUploaded, report.ProvisioningProfilesUploaded, err = bitriseio.UploadCodesigningFiles(client, certificates, provisioningProfiles, uploadConfig.RollbackOnFailure)
+	return report, exitcode.Wrap(exitcode.UploadFailed, err)
+}
+
+// writeCodesignFiles writes the files to the output folder according to the write files level
+func writeCodesignFiles(certificates models.Certificates, provisioningProfiles []models.ProvisioningProfile, writeFilesConfig WriteFilesConfig, uploading bool) (bool, error) {
+	if writeFilesConfig.WriteFiles == WriteFilesAlways ||
+		writeFilesConfig.WriteFiles == WriteFilesFallback && !uploading {
+		if err := WriteFiles(certificates, provisioningProfiles, writeFilesConfig); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
 }
 
-func writeFiles(identities models.Certificates, provisioningProfiles []models.ProvisioningProfile, writeFilesConfig WriteFilesConfig) error {
+// WriteFiles writes the exported identities and provisioning profiles to the output directory
+func WriteFiles(identities models.Certificates, provisioningProfiles []models.ProvisioningProfile, writeFilesConfig WriteFilesConfig) error {
 	if err := os.MkdirAll(writeFilesConfig.AbsOutputDirPath, 0700); err != nil {
 		return fmt.Errorf("failed to create output directory for codesigning files, error: %s", err)
 	}
@@ -148,17 +361,94 @@ func writeFiles(identities models.Certificates, provisioningProfiles []models.Pr
 		log.Warnf("Export output directory exists and is not empty.")
 	}
 
-	if err := writeIdentities(identities.Content, writeFilesConfig.AbsOutputDirPath); err != nil {
-		return err
+	naming := writeFilesConfig.Naming
+	// the default names are unique, except for the duplicate identities which are overwritten
+	if naming != (NamingConfig{}) {
+		if err := checkUniqueNames(writtenFilePaths(identities, provisioningProfiles, writeFilesConfig)); err != nil {
+			return err
+		}
+	}
+
+	if len(identities.Content) > 0 || len(identities.CertificateOnly) == 0 {
+		if err := writeIdentities(identities.Content, writeFilesConfig.AbsOutputDirPath); err != nil {
+			return err
+		}
+	}
+	for i, content := range identities.Each {
+		if err := writeNamedFile(writeFilesConfig.AbsOutputDirPath, naming.IdentityFileName(identities.Info[i]), content); err != nil {
+			return err
+		}
+	}
+	if len(identities.PEM) > 0 {
+		if err := ioutil.WriteFile(filepath.Join(writeFilesConfig.AbsOutputDirPath, "Identities.pem"), identities.PEM, 0600); err != nil {
+			return err
+		}
+	}
+	for _, file := range identityFiles(identities, writeFilesConfig) {
+		if err := writeNamedFile(writeFilesConfig.AbsOutputDirPath, file.Name, file.Content); err != nil {
+			return err
+		}
+	}
+	for _, intermediate := range identities.Intermediates {
+		if err := ioutil.WriteFile(filepath.Join(writeFilesConfig.AbsOutputDirPath, intermediateFileName(intermediate)), intermediate.Raw, 0600); err != nil {
+			return err
+		}
 	}
-	if err := writeProvisioningProfiles(provisioningProfiles, writeFilesConfig.AbsOutputDirPath); err != nil {
+	if err := writeProvisioningProfiles(provisioningProfiles, writeFilesConfig.AbsOutputDirPath, naming); err != nil {
 		return err
 	}
+	if writeFilesConfig.Match.DirPath != "" {
+		if _, err := writeMatchRepo(identities, provisioningProfiles, writeFilesConfig.Match); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// warnRevokedCertificates checks the revocation of the date-valid certificates online,
+// a revoked certificate can still sign locally, but it fails at submission time
+func warnRevokedCertificates(certificates []certificateutil.CertificateInfoModel, config IdentityExportConfig) {
+	if config.SkipRevocationCheck {
+		return
+	}
+	if networkConfig := network.CurrentConfig(); networkConfig.ProxyURL != "" || networkConfig.CABundlePath != "" {
+		// security verify-cert reaches the OCSP responder with the system network settings
+		log.Warnf("The revocation of the identities is not checked, the check can not use the --proxy and --ca-bundle settings, pass --skip-revocation-check to hide this warning")
+		return
+	}
+
+	for _, certificate := range certificates {
+		if certificate.CheckValidity() != nil {
+			continue
+		}
+		revoked, reason, err := osxkeychain.CheckRevocation(certificate.Certificate)
+		if err != nil {
+			log.Warnf("Failed to check the revocation of %s: %s, pass --skip-revocation-check on the machines without access to the OCSP responder", certificate.CommonName, err)
+		} else if revoked {
+			log.Warnf("Identity (%s) is revoked (%s), it will fail at submission time, create a new one in the developer portal", certificate.CommonName, reason)
+		}
+	}
+}
+
+// keychainExportError adds an actionable hint to the common Keychain export failures
+func keychainExportError(err error) error {
+	switch {
+	case errors.Is(err, osxkeychain.ErrUserCanceled):
+		return exitcode.Wrap(exitcode.Cancelled, fmt.Errorf("failed to export from Keychain, the Keychain prompt was canceled, rerun the scan and click Allow: %w", err))
+	case errors.Is(err, osxkeychain.ErrAuthFailed):
+		return exitcode.Wrap(exitcode.ExportDenied, fmt.Errorf("failed to export from Keychain, the Keychain password was wrong: %w", err))
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("failed to export from Keychain, timed out, check for an unanswered Keychain prompt or raise --timeout: %w", err)
+	case errors.Is(err, osxkeychain.ErrInteractionNotAllowed):
+		return exitcode.Wrap(exitcode.ExportDenied, fmt.Errorf("failed to export from Keychain, unlock the keychain with 'security unlock-keychain' first: %w", err))
+	case errors.Is(err, osxkeychain.ErrAccessDenied):
+		return exitcode.Wrap(exitcode.ExportDenied, fmt.Errorf("failed to export from Keychain, the access was denied: %w", err))
+	}
+	return fmt.Errorf("failed to export from Keychain: %w", err)
+}
+
 // exportIdentities exports the given certificates merged in a single .p12 file
-func exportIdentities(certificates []certificateutil.CertificateInfoModel, isAskForPassword bool) (models.Certificates, error) {
+func exportIdentities(ctx context.Context, certificates []certificateutil.CertificateInfoModel, config IdentityExportConfig) (models.Certificates, error) {
 	if len(certificates) == 0 {
 		return models.Certificates{}, nil
 	}
@@ -173,33 +463,34 @@ func exportIdentities(certificates []certificateutil.CertificateInfoModel, isAsk
 	fmt.Println()
 	log.Infof("Exporting the Identities (Certificates):")
 
-	identitiesWithKeychainRefs := []osxkeychain.IdentityWithRefModel{}
-	defer osxkeychain.ReleaseIdentityWithRefList(identitiesWithKeychainRefs)
-
-	for _, certificate := range certificates {
-		log.Printf("searching for Identity: %s", certificate.CommonName)
-		identityRef, err := osxkeychain.FindAndValidateIdentity(certificate.CommonName)
-		if err != nil {
-			return models.Certificates{}, fmt.Errorf("failed to export, error: %s", err)
-		}
-
-		if identityRef == nil {
-			return models.Certificates{}, errors.New("identity not found in the keychain, or it was invalid (expired)")
-		}
+	allCertificates := certificates
+	kc := config.identityKeychain()
+	certificates, identitiesToExport, certificateOnly, release, err := findIdentitiesToExport(ctx, kc, certificates, config, true)
+	if err != nil {
+		return models.Certificates{}, err
+	}
+	defer release()
 
-		identitiesWithKeychainRefs = append(identitiesWithKeychainRefs, *identityRef)
+	// the missing intermediates are reported even if the chain is not exported
+	intermediates := intermediateCertificates(allCertificates)
+	if !config.Chain {
+		intermediates = nil
+	}
+	if len(identitiesToExport) == 0 {
+		log.Warnf("The private key of every identity is stored on a hardware token, exporting the certificates only")
+		return models.Certificates{CertificateOnly: certificateOnly, Intermediates: intermediates}, nil
 	}
 
-	identityKechainRefs := osxkeychain.CreateEmptyCFTypeRefSlice()
-	for _, aIdentityWithRefItm := range identitiesWithKeychainRefs {
-		fmt.Println("exporting Identity:", aIdentityWithRefItm.Label)
-		identityKechainRefs = append(identityKechainRefs, aIdentityWithRefItm.KeychainRef)
+	for _, aIdentityWithRefItm := range identitiesToExport {
+		log.Printf("exporting Identity: %s", aIdentityWithRefItm)
 	}
 
 	fmt.Println()
-	if isAskForPassword {
+	if config.AskForPassword {
 		log.Infof("Exporting from Keychain")
 		log.Warnf(" You'll be asked to provide a Passphrase for the .p12 file!")
+	} else if config.Passphrase != "" {
+		log.Infof("Exporting from Keychain using the provided Passphrase...")
 	} else {
 		log.Warnf("Exporting from Keychain using empty Passphrase...")
 		log.Printf("This means that if you want to import the file the passphrase at import should be left empty,")
@@ -210,14 +501,198 @@ func exportIdentities(certificates []certificateutil.CertificateInfoModel, isAsk
 	log.Warnf("you will have to accept (Allow) those to be able to export the Identities!")
 	fmt.Println()
 
-	identities, err := osxkeychain.ExportFromKeychain(identityKechainRefs, isAskForPassword)
+	identities, err := exportFromKeychain(ctx, kc, identitiesToExport, keychain.ExportFormatPKCS12, false, config)
 	if err != nil {
-		return models.Certificates{}, fmt.Errorf("failed to export from Keychain: %s", err)
+		return models.Certificates{}, keychainExportError(err)
+	}
+
+	var pem [][]byte
+	if config.PEM {
+		fmt.Println()
+		log.Infof("Exporting the Identities as PEM, the Keychain popups are shown again")
+		if pem, err = exportFromKeychain(ctx, kc, identitiesToExport, keychain.ExportFormatPEM, false, config); err != nil {
+			return models.Certificates{}, fmt.Errorf("failed to export PEM, error: %w", keychainExportError(err))
+		}
+	}
+
+	var each [][]byte
+	if config.Each {
+		fmt.Println()
+		log.Infof("Exporting the Identities one by one, the Keychain popups are shown again")
+		if each, err = exportFromKeychain(ctx, kc, identitiesToExport, keychain.ExportFormatPKCS12, true, config); err != nil {
+			return models.Certificates{}, keychainExportError(err)
+		}
+	}
+
+	var keys [][]byte
+	if config.SeparateKeys {
+		fmt.Println()
+		log.Infof("Exporting the private keys as PKCS#8 one by one, the Keychain popups are shown again")
+		if keys, err = exportFromKeychain(ctx, kc, identitiesToExport, keychain.ExportFormatPKCS8, true, config); err != nil {
+			return models.Certificates{}, fmt.Errorf("failed to export the private keys, error: %w", keychainExportError(err))
+		}
+	}
+
+	exported := models.Certificates{
+		Info:            certificates,
+		Content:         identities[0],
+		Each:            each,
+		Keys:            keys,
+		Intermediates:   intermediates,
+		CertificateOnly: certificateOnly,
+	}
+	if len(pem) > 0 {
+		exported.PEM = pem[0]
 	}
-	return models.Certificates{
-		Info:    certificates,
-		Content: identities,
-	}, nil
+	return exported, nil
+}
+
+// ExportIdentitiesTo exports the identities of the certificates merged into a single .p12 and streams it to w,
+// e.g. into an uploader or an encryptor, instead of keeping the export of dozens of identities in memory.
+// Returns the number of bytes written.
+func ExportIdentitiesTo(ctx context.Context, w io.Writer, certificates []certificateutil.CertificateInfoModel, config IdentityExportConfig) (int64, error) {
+	if len(certificates) == 0 {
+		return 0, errors.New("no identity to export")
+	}
+	kc := config.identityKeychain()
+	_, identitiesToExport, certificateOnly, release, err := findIdentitiesToExport(ctx, kc, certificates, config, false)
+	if err == nil && len(certificateOnly) > 0 {
+		release()
+		err = fmt.Errorf("%d identities are stored on a hardware token, their certificates can not be streamed in a .p12", len(certificateOnly))
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	written, err := exportFromKeychainTo(ctx, kc, w, identitiesToExport, keychain.ExportFormatPKCS12, config)
+	if err != nil {
+		return written, keychainExportError(err)
+	}
+	return written, nil
+}
+
+// findIdentitiesToExport selects the identities of the certificates in the Keychain and checks that they can be exported,
+// the returned release function closes the identities.
+// The certificates of the identities with the private key on a hardware token are returned separately, if their certificate-only export
+// is approved by config.TokenCertificatesOnly, or interactively if ask is set.
+func findIdentitiesToExport(ctx context.Context, kc keychain.Keychain, certificates []certificateutil.CertificateInfoModel, config IdentityExportConfig, ask bool) ([]certificateutil.CertificateInfoModel, []*keychain.Identity, []certificateutil.CertificateInfoModel, func(), error) {
+	if unique, duplicates := DeduplicateCertificates(certificates); len(duplicates) > 0 {
+		for _, certificate := range duplicates {
+			log.Warnf("Identity (%s) [%s] is required more than once, e.g. it is installed in multiple keychains", certificate.CommonName, certificate.SHA1Fingerprint)
+		}
+		if config.Deduplicate {
+			log.Printf("Exporting only one copy of the duplicated identities")
+			certificates = unique
+		} else {
+			log.Warnf("The export will contain duplicated identities, which might fail to import, use --dedupe-identities to export only one copy")
+		}
+	}
+
+	neverExport, err := state.ReadNeverExportList()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	for _, certificate := range certificates {
+		if neverExport.Contains(certificate.SHA1Fingerprint) {
+			return nil, nil, nil, nil, fmt.Errorf("identity (%s) is listed in the never export list of this machine", certificate.CommonName)
+		}
+	}
+
+	var labels []string
+	for _, certificate := range certificates {
+		log.Printf("searching for Identity: %s", certificate.CommonName)
+		labels = append(labels, certificate.CommonName)
+	}
+	selected, skipped, err := kc.SelectIdentities(ctx, config.identityQuery(labels))
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("failed to export, error: %w", err)
+	}
+	release := func() {
+		_ = keychain.CloseAll(selected)
+	}
+	selectedByLabel := map[string]*keychain.Identity{}
+	for _, identity := range selected {
+		selectedByLabel[identity.Label] = identity
+		if config.IncludeExpired && identity.NotAfter().Before(time.Now()) {
+			log.Warnf("Exporting expired identity: %s", identity)
+		}
+	}
+	skipReasons := map[string][]string{}
+	for _, skippedIdentity := range skipped {
+		log.Warnf("Skipping identity %s [%s] of %s: %s", skippedIdentity.Label, skippedIdentity.SHA1Fingerprint, keychainOf(skippedIdentity.KeychainPath), skippedIdentity.Reason)
+		skipReasons[skippedIdentity.Label] = append(skipReasons[skippedIdentity.Label], skippedIdentity.Reason)
+	}
+	for _, identity := range selected {
+		log.Printf("Identity %s [%s] found in %s", identity.Label, identity.SHA1Fingerprint(), keychainOf(identity.KeychainPath))
+		RecordIdentityKeychain(identity.SHA1Fingerprint(), identity.KeychainPath)
+	}
+
+	identitiesToExport := []*keychain.Identity{}
+	for _, certificate := range certificates {
+		identity, ok := selectedByLabel[certificate.CommonName]
+		if !ok && len(skipReasons[certificate.CommonName]) == 0 {
+			explainNoMatch(ctx, kc, certificate.CommonName)
+		}
+		if !ok && len(skipReasons[certificate.CommonName]) > 0 {
+			release()
+			return nil, nil, nil, nil, exitcode.Wrap(exitcode.IdentityNotFound, fmt.Errorf("identity (%s) was skipped: %s, use --include-expired to export expired identities", certificate.CommonName, strings.Join(skipReasons[certificate.CommonName], ", ")))
+		} else if !ok && len(config.TeamIDs) > 0 {
+			release()
+			return nil, nil, nil, nil, exitcode.Wrap(exitcode.IdentityNotFound, fmt.Errorf("identity (%s) of the teams (%s) not found in the keychain", certificate.CommonName, strings.Join(config.TeamIDs, ", ")))
+		} else if !ok && len(config.Fingerprints) > 0 {
+			release()
+			return nil, nil, nil, nil, exitcode.Wrap(exitcode.IdentityNotFound, fmt.Errorf("identity (%s) with the fingerprints (%s) not found in the keychain", certificate.CommonName, strings.Join(config.Fingerprints, ", ")))
+		} else if !ok && len(config.Keychains) > 0 {
+			release()
+			return nil, nil, nil, nil, exitcode.Wrap(exitcode.IdentityNotFound, fmt.Errorf("identity (%s) not found in the keychains: %s", certificate.CommonName, strings.Join(config.Keychains, ", ")))
+		} else if !ok && len(config.Types) > 0 {
+			release()
+			return nil, nil, nil, nil, exitcode.Wrap(exitcode.IdentityNotFound, fmt.Errorf("identity (%s) not found in the keychain, or its type is not one of: %v", certificate.CommonName, config.Types))
+		} else if !ok {
+			release()
+			return nil, nil, nil, nil, exitcode.Wrap(exitcode.IdentityNotFound, fmt.Errorf("identity (%s) not found in the keychain", certificate.CommonName))
+		}
+		identitiesToExport = append(identitiesToExport, identity)
+	}
+
+	warnRevokedCertificates(certificates, config)
+
+	// the private key export and access checks are implemented with the Security framework only
+	securityIdentities, ok := securityFrameworkIdentities(identitiesToExport)
+	if !ok {
+		return certificates, identitiesToExport, nil, release, nil
+	}
+
+	var certificateOnly []certificateutil.CertificateInfoModel
+	if err := osxkeychain.CheckExportable(securityIdentities); err != nil {
+		var notExportable osxkeychain.NotExportableError
+		if !errors.As(err, &notExportable) || len(notExportable.HardwareBound) == 0 {
+			release()
+			return nil, nil, nil, nil, fmt.Errorf("failed to export, error: %s", err)
+		}
+		printHardwareBoundGuidance(notExportable)
+		approved := false
+		if notExportable.HardwareBoundOnly() {
+			if approved, err = approveCertificateOnlyExport(config, ask); err != nil {
+				release()
+				return nil, nil, nil, nil, err
+			}
+		}
+		if !approved {
+			release()
+			return nil, nil, nil, nil, fmt.Errorf("failed to export, error: %s", notExportable)
+		}
+		certificates, identitiesToExport, certificateOnly = splitHardwareBound(certificates, identitiesToExport, notExportable)
+		securityIdentities, _ = securityFrameworkIdentities(identitiesToExport)
+	}
+	if config.InspectKeyAccess || len(config.AuthorizeApplications) > 0 {
+		if err := checkKeyAccess(securityIdentities, config.AuthorizeApplications); err != nil {
+			release()
+			return nil, nil, nil, nil, err
+		}
+	}
+	return certificates, identitiesToExport, certificateOnly, release, nil
 }
 
 // writeIdentities writes identities to a file path
@@ -225,6 +700,75 @@ func writeIdentities(identites []byte, absExportOutputDirPath string) error {
 	return ioutil.WriteFile(filepath.Join(absExportOutputDirPath, "Identities.p12"), identites, 0600)
 }
 
+// WriteFilesReport describes the files written by WriteFiles, the upload state has to be set by the caller
+func WriteFilesReport(identities models.Certificates, provisioningProfiles []models.ProvisioningProfile, writeFilesConfig WriteFilesConfig) ExportReport {
+	return newExportReport(identities, provisioningProfiles, writeFilesConfig, true)
+}
+
+// writtenFilePaths returns the paths of the files written by WriteFiles
+func writtenFilePaths(identities models.Certificates, provisioningProfiles []models.ProvisioningProfile, writeFilesConfig WriteFilesConfig) []string {
+	absExportOutputDirPath, naming := writeFilesConfig.AbsOutputDirPath, writeFilesConfig.Naming
+	var paths []string
+	if len(identities.Content) > 0 {
+		paths = append(paths, filepath.Join(absExportOutputDirPath, "Identities.p12"))
+	}
+	if len(identities.PEM) > 0 {
+		paths = append(paths, filepath.Join(absExportOutputDirPath, "Identities.pem"))
+	}
+	for i := range identities.Each {
+		paths = append(paths, filepath.Join(absExportOutputDirPath, naming.IdentityFileName(identities.Info[i])))
+	}
+	for _, file := range identityFiles(identities, writeFilesConfig) {
+		paths = append(paths, filepath.Join(absExportOutputDirPath, file.Name))
+	}
+	for _, intermediate := range identities.Intermediates {
+		paths = append(paths, filepath.Join(absExportOutputDirPath, intermediateFileName(intermediate)))
+	}
+	for _, profile := range provisioningProfiles {
+		paths = append(paths, filepath.Join(absExportOutputDirPath, naming.ProfileFileName(profile.Info)))
+	}
+	return paths
+}
+
+// unsafeFileNameChars matches the characters replaced in the generated file names
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9.()-]+`)
+
+// identityFileName returns the name of the separately exported .p12 of the certificate
+func identityFileName(certificate certificateutil.CertificateInfoModel) string {
+	return unsafeFileNameChars.ReplaceAllString(certificate.CommonName+" "+certificate.Serial, "_") + ".p12"
+}
+
+// intermediateFileName returns the name of the exported .cer of the intermediate certificate,
+// the generation (e.g. G3) is stored in the organizational unit of the Apple WWDR certificates
+func intermediateFileName(certificate x509.Certificate) string {
+	name := strings.Join(append([]string{certificate.Subject.CommonName}, certificate.Subject.OrganizationalUnit...), " ")
+	return unsafeFileNameChars.ReplaceAllString(name, "_") + ".cer"
+}
+
+// intermediateCertificates finds the issuers of the certificates in the keychains and warns about the missing ones,
+// a missing Apple WWDR intermediate is a common cause of "no identity found" on fresh CI machines
+func intermediateCertificates(certificates []certificateutil.CertificateInfoModel) []x509.Certificate {
+	var intermediates []x509.Certificate
+	found := map[string]bool{}
+	for _, certificate := range certificates {
+		issuer, err := osxkeychain.FindIssuer(certificate.Certificate)
+		if err != nil {
+			log.Warnf("Failed to find the intermediate certificate of %s: %s", certificate.CommonName, err)
+			continue
+		}
+		if issuer == nil {
+			log.Warnf("The intermediate certificate (%s) of %s is not installed in the keychain,", certificate.Certificate.Issuer.CommonName, certificate.CommonName)
+			log.Warnf("download and install it from https://www.apple.com/certificateauthority/, on the CI machines too")
+			continue
+		}
+		if fingerprint := fmt.Sprintf("%x", sha1.Sum(issuer.Raw)); !found[fingerprint] {
+			found[fingerprint] = true
+			intermediates = append(intermediates, *issuer)
+		}
+	}
+	return intermediates
+}
+
 // exportProvisioningProfiles returns provisioning profies
 func exportProvisioningProfiles(profiles []profileutil.ProvisioningProfileInfoModel) ([]models.ProvisioningProfile, error) {
 	if len(profiles) == 0 {
@@ -242,17 +786,12 @@ func exportProvisioningProfiles(profiles []profileutil.ProvisioningProfileInfoMo
 	var exportedProfiles []models.ProvisioningProfile
 	for _, profile := range profiles {
 		log.Printf("searching for required Provisioning Profile: %s (UUID: %s)", profile.Name, profile.UUID)
-		provisioningProfile, pth, err := profileutil.FindProvisioningProfile(profile.UUID)
+		exportedProfile, pth, err := utility.FindProfileInfo(profile.UUID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to find Provisioning Profile: %s", err)
+			return nil, exitcode.Wrap(exitcode.ProfileMissing, fmt.Errorf("failed to find Provisioning Profile: %s", err))
 		}
 		log.Printf("file found at: %s", pth)
 
-		exportedProfile, err := profileutil.NewProvisioningProfileInfo(*provisioningProfile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse exported profile, error: %s", err)
-		}
-
 		contents, err := ioutil.ReadFile(pth)
 		if err != nil {
 			return nil, fmt.Errorf("could not read provisioning profile file, error: %s", err)
@@ -267,13 +806,20 @@ func exportProvisioningProfiles(profiles []profileutil.ProvisioningProfileInfoMo
 }
 
 // writeProvisioningProfiles writes provisioning profiles to the filesystem
-func writeProvisioningProfiles(profiles []models.ProvisioningProfile, absExportOutputDirPath string) error {
+func writeProvisioningProfiles(profiles []models.ProvisioningProfile, absExportOutputDirPath string, naming NamingConfig) error {
 	for _, profile := range profiles {
-		exportFileName := utility.ProfileExportFileNameNoPath(profile.Info)
-		exportPth := filepath.Join(absExportOutputDirPath, exportFileName)
-		if err := ioutil.WriteFile(exportPth, profile.Content, 0600); err != nil {
+		if err := writeNamedFile(absExportOutputDirPath, naming.ProfileFileName(profile.Info), profile.Content); err != nil {
 			return fmt.Errorf("failed to write file, error: %s", err)
 		}
 	}
 	return nil
 }
+
+// writeNamedFile writes the file into the output directory, creating the subdirectories of the name
+func writeNamedFile(absExportOutputDirPath, name string, content []byte) error {
+	pth := filepath.Join(absExportOutputDirPath, name)
+	if err := os.MkdirAll(filepath.Dir(pth), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(pth, content, 0600)
+}
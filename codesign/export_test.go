@@ -0,0 +1,118 @@
+package codesign
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/exitcode"
+	"github.com/bitrise-io/codesigndoc/keychain/keychaintest"
+	"github.com/bitrise-io/go-utils/pkcs12"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityFileName(t *testing.T) {
+	certificate := certificateutil.CertificateInfoModel{CommonName: "iPhone Distribution: Bitrise Inc. (ABCD1234)", Serial: "1A2B3C"}
+	require.Equal(t, "iPhone_Distribution_Bitrise_Inc._(ABCD1234)_1A2B3C.p12", identityFileName(certificate))
+
+	certificate = certificateutil.CertificateInfoModel{CommonName: "Apple Development: a/b", Serial: "01"}
+	require.Equal(t, "Apple_Development_a_b_01.p12", identityFileName(certificate))
+}
+
+func TestIntermediateFileName(t *testing.T) {
+	certificate := x509.Certificate{Subject: pkix.Name{CommonName: "Apple Worldwide Developer Relations Certification Authority", OrganizationalUnit: []string{"G3"}}}
+	require.Equal(t, "Apple_Worldwide_Developer_Relations_Certification_Authority_G3.cer", intermediateFileName(certificate))
+
+	certificate = x509.Certificate{Subject: pkix.Name{CommonName: "Developer ID Certification Authority"}}
+	require.Equal(t, "Developer_ID_Certification_Authority.cer", intermediateFileName(certificate))
+}
+
+// addFakeIdentity adds the fixture to the fake keychain and returns its certificate, as required by a scan
+func addFakeIdentity(t *testing.T, fake *keychaintest.Fake, fixture keychaintest.Fixture) certificateutil.CertificateInfoModel {
+	content, err := fixture.PEM()
+	require.NoError(t, err)
+	require.NoError(t, fake.AddPEM(content))
+
+	block, _ := pem.Decode(content)
+	require.NotNil(t, block)
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	return certificateutil.NewCertificateInfo(*certificate, nil)
+}
+
+func TestExportIdentities(t *testing.T) {
+	now := time.Now()
+	fake, err := keychaintest.New()
+	require.NoError(t, err)
+	distribution := addFakeIdentity(t, fake, keychaintest.Fixture{CommonName: "Apple Distribution: Bitrise (ABCD123456)", TeamID: "ABCD123456", NotBefore: now.AddDate(-1, 0, 0), NotAfter: now.AddDate(1, 0, 0)})
+	addFakeIdentity(t, fake, keychaintest.Fixture{CommonName: "Apple Distribution: Bitrise (ABCD123456)", TeamID: "ABCD123456", NotBefore: now.AddDate(-2, 0, 0), NotAfter: now.AddDate(0, 0, -1)})
+
+	config := IdentityExportConfig{Keychain: fake, Passphrase: "secret", PEM: true, Each: true, SeparateKeys: true}
+	exported, err := exportIdentities(context.Background(), []certificateutil.CertificateInfoModel{distribution}, config)
+	require.NoError(t, err)
+	require.Equal(t, 0, fake.Open())
+
+	require.Equal(t, []certificateutil.CertificateInfoModel{distribution}, exported.Info)
+	_, certificate, err := pkcs12.Decode(exported.Content, "secret")
+	require.NoError(t, err)
+	require.Equal(t, distribution.SHA1Fingerprint, certificateutil.NewCertificateInfo(*certificate, nil).SHA1Fingerprint)
+	require.Len(t, exported.Each, 1)
+	require.Len(t, exported.Keys, 1)
+	require.True(t, bytes.Contains(exported.PEM, []byte("CERTIFICATE")))
+	require.Empty(t, exported.CertificateOnly)
+}
+
+func TestExportIdentitiesNotFound(t *testing.T) {
+	now := time.Now()
+	fake, err := keychaintest.New()
+	require.NoError(t, err)
+	valid := addFakeIdentity(t, fake, keychaintest.Fixture{CommonName: "Apple Distribution: Bitrise (ABCD123456)", TeamID: "ABCD123456", NotBefore: now.AddDate(-1, 0, 0), NotAfter: now.AddDate(1, 0, 0)})
+	expired := addFakeIdentity(t, fake, keychaintest.Fixture{CommonName: "Apple Development: John Doe (ABCD123456)", TeamID: "ABCD123456", NotBefore: now.AddDate(-2, 0, 0), NotAfter: now.AddDate(0, 0, -1)})
+	withoutKey := addFakeIdentity(t, fake, keychaintest.Fixture{CommonName: "iPhone Distribution: Bitrise (ABCD123456)", TeamID: "ABCD123456", NotBefore: now.AddDate(-1, 0, 0), NotAfter: now.AddDate(1, 0, 0), WithoutPrivateKey: true})
+
+	for _, tt := range []struct {
+		name        string
+		certificate certificateutil.CertificateInfoModel
+		config      IdentityExportConfig
+		wantErr     string
+	}{
+		{name: "expired", certificate: expired, wantErr: "was skipped: expired on"},
+		{name: "without private key", certificate: withoutKey, wantErr: "was skipped: missing private key"},
+		{name: "other team", certificate: valid, config: IdentityExportConfig{TeamIDs: []string{"XYZ9876543"}}, wantErr: "of the teams (XYZ9876543) not found"},
+		{name: "other keychain", certificate: valid, config: IdentityExportConfig{Keychains: []string{"ci"}}, wantErr: "not found in the keychains: ci"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Keychain = fake
+			_, err := exportIdentities(context.Background(), []certificateutil.CertificateInfoModel{tt.certificate}, tt.config)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.wantErr)
+			require.Equal(t, exitcode.IdentityNotFound, exitcode.Of(err))
+			require.Equal(t, 0, fake.Open())
+		})
+	}
+
+	exported, err := exportIdentities(context.Background(), []certificateutil.CertificateInfoModel{expired}, IdentityExportConfig{Keychain: fake, IncludeExpired: true})
+	require.NoError(t, err)
+	require.NotEmpty(t, exported.Content)
+	require.Equal(t, 0, fake.Open())
+}
+
+func TestExportIdentitiesTo(t *testing.T) {
+	now := time.Now()
+	fake, err := keychaintest.New()
+	require.NoError(t, err)
+	distribution := addFakeIdentity(t, fake, keychaintest.Fixture{CommonName: "Apple Distribution: Bitrise (ABCD123456)", TeamID: "ABCD123456", NotBefore: now.AddDate(-1, 0, 0), NotAfter: now.AddDate(1, 0, 0)})
+
+	var out bytes.Buffer
+	written, err := ExportIdentitiesTo(context.Background(), &out, []certificateutil.CertificateInfoModel{distribution}, IdentityExportConfig{Keychain: fake, Passphrase: "secret"})
+	require.NoError(t, err)
+	require.Equal(t, int64(out.Len()), written)
+	_, _, err = pkcs12.Decode(out.Bytes(), "secret")
+	require.NoError(t, err)
+	require.Equal(t, 0, fake.Open())
+}
@@ -0,0 +1,39 @@
+package codesign
+
+import (
+	"sync"
+)
+
+var (
+	identityKeychainsMux sync.Mutex
+	identityKeychains    = map[string]string{}
+)
+
+// RecordIdentityKeychain remembers the keychain file the exported identity of the SHA-1 fingerprint is read from, for the scan result
+func RecordIdentityKeychain(sha1Fingerprint, keychainPath string) {
+	if sha1Fingerprint == "" || keychainPath == "" {
+		return
+	}
+	identityKeychainsMux.Lock()
+	defer identityKeychainsMux.Unlock()
+	identityKeychains[sha1Fingerprint] = keychainPath
+}
+
+// IdentityKeychains returns the recorded keychain files of the identities, by SHA-1 fingerprint
+func IdentityKeychains() map[string]string {
+	identityKeychainsMux.Lock()
+	defer identityKeychainsMux.Unlock()
+	keychains := map[string]string{}
+	for fingerprint, keychainPath := range identityKeychains {
+		keychains[fingerprint] = keychainPath
+	}
+	return keychains
+}
+
+// keychainOf describes the source keychain of an identity for the logs
+func keychainOf(keychainPath string) string {
+	if keychainPath == "" {
+		return "an unknown keychain"
+	}
+	return keychainPath
+}
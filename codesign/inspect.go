@@ -0,0 +1,158 @@
+package codesign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/bitrise-io/go-utils/pkcs12"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+)
+
+// P12Inspection is the content of a .p12 file, read without importing it into a keychain
+type P12Inspection struct {
+	// Identities are the certificates with their private keys
+	Identities []P12Identity
+	// Certificates are the certificates without a private key, e.g. the intermediate certificates of the chains
+	Certificates []certificateutil.CertificateInfoModel
+	// UnmatchedKeys is the number of private keys without a certificate
+	UnmatchedKeys int
+	// Problems explain why the .p12 can not be used for code signing
+	Problems []string
+}
+
+// P12Identity is a certificate of the .p12 with its private key
+type P12Identity struct {
+	certificateutil.CertificateInfoModel
+	// Chain are the common names of the issuer certificates, the ones not in the .p12 are marked
+	Chain []string
+	// Problems of the identity, e.g. expired
+	Problems []string
+	// Warnings of the identity, e.g. expires soon
+	Warnings []string
+}
+
+// InspectP12 decodes the .p12 content, pairs the certificates with their private keys by the public key,
+// builds the chains from the certificates of the .p12 and validates the identities: an identity expiring within warnDays gets a warning.
+func InspectP12(content []byte, passphrase string, now time.Time, warnDays int) (P12Inspection, error) {
+	certificates, privateKeys, err := pkcs12.DecodeAll(content, passphrase)
+	if err != nil {
+		return P12Inspection{}, fmt.Errorf("failed to decode the .p12, check the password, error: %s", err)
+	}
+
+	var inspection P12Inspection
+	matched := make([]bool, len(privateKeys))
+	for _, certificate := range certificates {
+		if certificate == nil {
+			continue
+		}
+		info := certificateutil.NewCertificateInfo(*certificate, nil)
+		found := false
+		for i, privateKey := range privateKeys {
+			if !matched[i] && keyMatches(privateKey, certificate) {
+				matched[i] = true
+				info.PrivateKey = privateKey
+				found = true
+				break
+			}
+		}
+		if found {
+			inspection.Identities = append(inspection.Identities, P12Identity{CertificateInfoModel: info})
+		} else {
+			inspection.Certificates = append(inspection.Certificates, info)
+		}
+	}
+	for _, ok := range matched {
+		if !ok {
+			inspection.UnmatchedKeys++
+		}
+	}
+
+	if len(inspection.Identities) == 0 {
+		inspection.Problems = append(inspection.Problems, "no certificate with its private key found, the .p12 can not sign")
+	}
+	if inspection.UnmatchedKeys > 0 {
+		inspection.Problems = append(inspection.Problems, fmt.Sprintf("%d private key(s) do not match any certificate of the .p12", inspection.UnmatchedKeys))
+	}
+	for i := range inspection.Identities {
+		identity := &inspection.Identities[i]
+		identity.Chain = p12Chain(identity.Certificate, certificates)
+		identity.Problems, identity.Warnings = validateP12Identity(identity.CertificateInfoModel, now, warnDays)
+		for _, problem := range identity.Problems {
+			inspection.Problems = append(inspection.Problems, fmt.Sprintf("%s: %s", identity.CommonName, problem))
+		}
+	}
+	return inspection, nil
+}
+
+// keyMatches tells whether the private key belongs to the public key of the certificate
+func keyMatches(privateKey interface{}, certificate *x509.Certificate) bool {
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return false
+	}
+	public, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	return ok && public.Equal(certificate.PublicKey)
+}
+
+// p12Chain follows the issuers of the certificate through the certificates of the .p12,
+// the issuer which is not in the .p12 closes the chain, marked as such
+func p12Chain(certificate x509.Certificate, certificates []*x509.Certificate) []string {
+	var chain []string
+	current := &certificate
+	for len(chain) < len(certificates) {
+		if bytes.Equal(current.RawIssuer, current.RawSubject) {
+			// self-signed, the root of the chain
+			return chain
+		}
+		var issuer *x509.Certificate
+		for _, candidate := range certificates {
+			if candidate != nil && candidate != current && !candidate.Equal(current) && current.CheckSignatureFrom(candidate) == nil {
+				issuer = candidate
+				break
+			}
+		}
+		if issuer == nil {
+			return append(chain, fmt.Sprintf("%s (not in the .p12)", current.Issuer.CommonName))
+		}
+		chain = append(chain, issuer.Subject.CommonName)
+		current = issuer
+	}
+	return chain
+}
+
+// validateP12Identity returns the problems which prevent the code signing with the identity, and the warnings
+func validateP12Identity(identity certificateutil.CertificateInfoModel, now time.Time, warnDays int) ([]string, []string) {
+	var problems, warnings []string
+	switch {
+	case now.After(identity.EndDate):
+		problems = append(problems, fmt.Sprintf("expired at %s", identity.EndDate.Format("2006-01-02")))
+	case now.Before(identity.StartDate):
+		problems = append(problems, fmt.Sprintf("not valid before %s", identity.StartDate.Format("2006-01-02")))
+	case identity.EndDate.Before(now.AddDate(0, 0, warnDays)):
+		warnings = append(warnings, fmt.Sprintf("expires in %d day(s), at %s", int(identity.EndDate.Sub(now).Hours()/24), identity.EndDate.Format("2006-01-02")))
+	}
+	if identity.TeamID == "" {
+		warnings = append(warnings, "the certificate has no team ID (organizational unit), it is not an Apple issued code signing certificate")
+	}
+	if !codeSigningUsage(identity.Certificate) {
+		warnings = append(warnings, "the extended key usage of the certificate does not allow the code signing, e.g. an installer or a push certificate")
+	}
+	return problems, warnings
+}
+
+// codeSigningUsage tells whether the extended key usage of the certificate allows the code signing,
+// a certificate without extended key usage is not restricted
+func codeSigningUsage(certificate x509.Certificate) bool {
+	if len(certificate.ExtKeyUsage) == 0 && len(certificate.UnknownExtKeyUsage) == 0 {
+		return true
+	}
+	for _, usage := range certificate.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageCodeSigning || usage == x509.ExtKeyUsageAny {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package codesign
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/pkcs12"
+	"github.com/stretchr/testify/require"
+)
+
+func testCertificate(t *testing.T, commonName, teamID string, notAfter time.Time, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName, OrganizationalUnit: []string{teamID}},
+		NotBefore:             notAfter.AddDate(-1, 0, 0),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	content, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(content)
+	require.NoError(t, err)
+	return certificate, key
+}
+
+func TestInspectP12(t *testing.T) {
+	now := time.Now()
+	ca, caKey := testCertificate(t, "Test WWDR CA", "", now.AddDate(5, 0, 0), nil, nil)
+	certificate, key := testCertificate(t, "Apple Distribution: Test", "ABCDE12345", now.AddDate(0, 0, 10), ca, caKey)
+	content, err := pkcs12.Encode(rand.Reader, key, certificate, []*x509.Certificate{ca}, "secret")
+	require.NoError(t, err)
+
+	_, err = InspectP12(content, "wrong", now, 30)
+	require.Error(t, err)
+
+	inspection, err := InspectP12(content, "secret", now, 30)
+	require.NoError(t, err)
+	require.Empty(t, inspection.Problems)
+	require.Len(t, inspection.Identities, 1)
+	require.Equal(t, "ABCDE12345", inspection.Identities[0].TeamID)
+	require.Equal(t, []string{"Test WWDR CA"}, inspection.Identities[0].Chain)
+	require.Len(t, inspection.Identities[0].Warnings, 1)
+	require.Len(t, inspection.Certificates, 1)
+	require.Equal(t, "Test WWDR CA", inspection.Certificates[0].CommonName)
+
+	inspection, err = InspectP12(content, "secret", now.AddDate(0, 0, 11), 30)
+	require.NoError(t, err)
+	require.Len(t, inspection.Problems, 1)
+
+	_, otherKey := testCertificate(t, "Other", "ABCDE12345", now.AddDate(1, 0, 0), nil, nil)
+	content, err = pkcs12.Encode(rand.Reader, otherKey, certificate, nil, "secret")
+	require.NoError(t, err)
+	inspection, err = InspectP12(content, "secret", now, 30)
+	require.NoError(t, err)
+	require.Empty(t, inspection.Identities)
+	require.Equal(t, 1, inspection.UnmatchedKeys)
+	require.Len(t, inspection.Problems, 2)
+	require.Len(t, inspection.Certificates, 1)
+}
+
+func TestP12Chain(t *testing.T) {
+	now := time.Now()
+	ca, caKey := testCertificate(t, "Test WWDR CA", "", now.AddDate(5, 0, 0), nil, nil)
+	certificate, _ := testCertificate(t, "Apple Development: Test", "ABCDE12345", now.AddDate(1, 0, 0), ca, caKey)
+
+	require.Equal(t, []string{"Test WWDR CA (not in the .p12)"}, p12Chain(*certificate, []*x509.Certificate{certificate}))
+	require.Equal(t, []string{"Test WWDR CA"}, p12Chain(*certificate, []*x509.Certificate{certificate, ca}))
+	require.Empty(t, p12Chain(*ca, []*x509.Certificate{ca}))
+}
@@ -0,0 +1,183 @@
+package codesign
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/utility"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// BundleProfile is a provisioning profile file of a previously collected export bundle (e.g. ./codesigndoc_exports)
+type BundleProfile struct {
+	Path string
+	Info profileutil.ProvisioningProfileInfoModel
+}
+
+func profileExt(profileType profileutil.ProfileType) string {
+	if profileType == profileutil.ProfileTypeMacOs {
+		return ".provisionprofile"
+	}
+	return ".mobileprovision"
+}
+
+// ReadBundleProfiles reads the provisioning profiles of the given type from the export directory, a missing directory has no profiles
+func ReadBundleProfiles(dir string, profileType profileutil.ProfileType) ([]BundleProfile, error) {
+	if exists, err := pathutil.IsDirExists(dir); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, nil
+	}
+
+	pths, err := filepath.Glob(filepath.Join(dir, "*"+profileExt(profileType)))
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []BundleProfile
+	for _, pth := range pths {
+		info, err := profileutil.NewProvisioningProfileInfoFromFile(pth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse provisioning profile (%s), error: %s", pth, err)
+		}
+		profiles = append(profiles, BundleProfile{Path: pth, Info: info})
+	}
+	return profiles, nil
+}
+
+// IsWildcardBundleIDMatch returns true if the profile's wildcard bundle ID (e.g. com.company.*) covers the given bundle ID
+func IsWildcardBundleIDMatch(profileBundleID, bundleID string) bool {
+	if !strings.HasSuffix(profileBundleID, "*") {
+		return false
+	}
+	return strings.HasPrefix(bundleID, strings.TrimSuffix(profileBundleID, "*"))
+}
+
+// NotInstalledBundleProfiles returns the valid bundle profiles of the team provisioning any of the bundle IDs,
+// which are not installed locally.
+func NotInstalledBundleProfiles(bundleProfiles []BundleProfile, installedProfiles []profileutil.ProvisioningProfileInfoModel, bundleIDs []string, teamID string) []BundleProfile {
+	installed := map[string]bool{}
+	for _, profile := range installedProfiles {
+		installed[profile.UUID] = true
+	}
+
+	var missing []BundleProfile
+	for _, profile := range bundleProfiles {
+		if installed[profile.Info.UUID] || profile.Info.CheckValidity() != nil {
+			continue
+		}
+		if teamID != "" && profile.Info.TeamID != teamID {
+			continue
+		}
+
+		for _, bundleID := range bundleIDs {
+			if profile.Info.BundleID == bundleID || IsWildcardBundleIDMatch(profile.Info.BundleID, bundleID) {
+				missing = append(missing, profile)
+				installed[profile.Info.UUID] = true
+				break
+			}
+		}
+	}
+	return missing
+}
+
+// InstallProfile copies the bundle profile to the provisioning profiles directory, returns the installed file path
+func InstallProfile(profile BundleProfile) (string, error) {
+	profilesDir, err := pathutil.AbsPath(profileutil.ProvProfileSystemDirPath)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(profilesDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create provisioning profiles directory, error: %s", err)
+	}
+
+	content, err := ioutil.ReadFile(profile.Path)
+	if err != nil {
+		return "", err
+	}
+	pth := filepath.Join(profilesDir, profile.Info.UUID+profileExt(profile.Info.Type))
+	if err := ioutil.WriteFile(pth, content, 0600); err != nil {
+		return "", fmt.Errorf("failed to install provisioning profile, error: %s", err)
+	}
+	return pth, nil
+}
+
+// ReadProfileFiles reads the given provisioning profile files, the .mobileprovision and .provisionprofile files of the directories are read too
+func ReadProfileFiles(pths []string) ([]BundleProfile, error) {
+	var profiles []BundleProfile
+	for _, pth := range pths {
+		if isDir, err := pathutil.IsDirExists(pth); err != nil {
+			return nil, err
+		} else if isDir {
+			for _, profileType := range []profileutil.ProfileType{profileutil.ProfileTypeIos, profileutil.ProfileTypeMacOs} {
+				dirProfiles, err := ReadBundleProfiles(pth, profileType)
+				if err != nil {
+					return nil, err
+				}
+				profiles = append(profiles, dirProfiles...)
+			}
+			continue
+		}
+
+		info, err := profileutil.NewProvisioningProfileInfoFromFile(pth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse provisioning profile (%s), error: %s", pth, err)
+		}
+		profiles = append(profiles, BundleProfile{Path: pth, Info: info})
+	}
+	return profiles, nil
+}
+
+// InstalledProfilePath returns the installed file of the profile in any of the provisioning profile directories
+// (utility.ProfileDirPaths), empty if it is not installed
+func InstalledProfilePath(profile BundleProfile) (string, error) {
+	var dirs []string
+	for _, dir := range utility.ProfileDirPaths {
+		absDir, err := pathutil.AbsPath(dir)
+		if err != nil {
+			return "", err
+		}
+		dirs = append(dirs, absDir)
+	}
+	return installedProfilePath(dirs, profile)
+}
+
+// installedProfilePath returns the file of the profile named after its UUID in any of the dirs, empty if there is none
+func installedProfilePath(dirs []string, profile BundleProfile) (string, error) {
+	for _, dir := range dirs {
+		pth := filepath.Join(dir, profile.Info.UUID+profileExt(profile.Info.Type))
+		if _, err := os.Stat(pth); err == nil {
+			return pth, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// SplitArtifactPaths sorts the exported files into the .p12 files and the provisioning profile files,
+// the .p12 files of a directory are listed, the directory itself is returned as a profile path (see ReadProfileFiles)
+func SplitArtifactPaths(pths []string) ([]string, []string, error) {
+	var p12Paths, profilePaths []string
+	for _, pth := range pths {
+		if isDir, err := pathutil.IsDirExists(pth); err != nil {
+			return nil, nil, err
+		} else if isDir {
+			dirP12Paths, err := filepath.Glob(filepath.Join(pth, "*.p12"))
+			if err != nil {
+				return nil, nil, err
+			}
+			p12Paths = append(p12Paths, dirP12Paths...)
+			profilePaths = append(profilePaths, pth)
+		} else if strings.EqualFold(filepath.Ext(pth), ".p12") {
+			p12Paths = append(p12Paths, pth)
+		} else {
+			profilePaths = append(profilePaths, pth)
+		}
+	}
+	return p12Paths, profilePaths, nil
+}
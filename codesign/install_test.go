@@ -0,0 +1,68 @@
+package codesign
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotInstalledBundleProfiles(t *testing.T) {
+	valid := time.Now().Add(24 * time.Hour)
+	bundleProfiles := []BundleProfile{
+		{Path: "installed", Info: profileutil.ProvisioningProfileInfoModel{UUID: "1", BundleID: "io.bitrise.app", TeamID: "TEAM", ExpirationDate: valid}},
+		{Path: "missing", Info: profileutil.ProvisioningProfileInfoModel{UUID: "2", BundleID: "io.bitrise.app", TeamID: "TEAM", ExpirationDate: valid}},
+		{Path: "wildcard", Info: profileutil.ProvisioningProfileInfoModel{UUID: "3", BundleID: "io.bitrise.*", TeamID: "TEAM", ExpirationDate: valid}},
+		{Path: "expired", Info: profileutil.ProvisioningProfileInfoModel{UUID: "4", BundleID: "io.bitrise.app", TeamID: "TEAM", ExpirationDate: time.Now().Add(-time.Hour)}},
+		{Path: "other team", Info: profileutil.ProvisioningProfileInfoModel{UUID: "5", BundleID: "io.bitrise.app", TeamID: "OTHER", ExpirationDate: valid}},
+		{Path: "other app", Info: profileutil.ProvisioningProfileInfoModel{UUID: "6", BundleID: "io.other.app", TeamID: "TEAM", ExpirationDate: valid}},
+	}
+	installed := []profileutil.ProvisioningProfileInfoModel{{UUID: "1"}}
+
+	missing := NotInstalledBundleProfiles(bundleProfiles, installed, []string{"io.bitrise.app", "io.bitrise.app.widget"}, "TEAM")
+	require.Equal(t, 2, len(missing))
+	require.Equal(t, "missing", missing[0].Path)
+	require.Equal(t, "wildcard", missing[1].Path)
+
+	require.True(t, IsWildcardBundleIDMatch("*", "io.bitrise.app"))
+	require.False(t, IsWildcardBundleIDMatch("io.bitrise.app", "io.bitrise.app"))
+}
+
+func TestInstalledProfilePath(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "profiles")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tmpDir))
+	}()
+	mobileDeviceDir, xcodeDir := filepath.Join(tmpDir, "MobileDevice"), filepath.Join(tmpDir, "Xcode")
+	require.NoError(t, os.MkdirAll(xcodeDir, 0700))
+	installed := filepath.Join(xcodeDir, "1.mobileprovision")
+	require.NoError(t, ioutil.WriteFile(installed, []byte("profile"), 0600))
+
+	pth, err := installedProfilePath([]string{mobileDeviceDir, xcodeDir}, BundleProfile{Info: profileutil.ProvisioningProfileInfoModel{UUID: "1", Type: profileutil.ProfileTypeIos}})
+	require.NoError(t, err)
+	require.Equal(t, installed, pth)
+
+	pth, err = installedProfilePath([]string{mobileDeviceDir, xcodeDir}, BundleProfile{Info: profileutil.ProvisioningProfileInfoModel{UUID: "1", Type: profileutil.ProfileTypeMacOs}})
+	require.NoError(t, err)
+	require.Empty(t, pth)
+}
+
+func TestSplitArtifactPaths(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "artifacts")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tmpDir))
+	}()
+	dirP12 := filepath.Join(tmpDir, "Identities.p12")
+	require.NoError(t, ioutil.WriteFile(dirP12, []byte("p12"), 0600))
+
+	p12Paths, profilePaths, err := SplitArtifactPaths([]string{tmpDir, "Distribution.P12", "App.mobileprovision"})
+	require.NoError(t, err)
+	require.Equal(t, []string{dirP12, "Distribution.P12"}, p12Paths)
+	require.Equal(t, []string{tmpDir, "App.mobileprovision"}, profilePaths)
+}
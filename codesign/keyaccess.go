@@ -0,0 +1,52 @@
+package codesign
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/confirm"
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// checkKeyAccess logs which applications can use the private keys of the identities without a Keychain prompt,
+// and offers to add the given applications to the access control list of the keys which do not allow them yet.
+func checkKeyAccess(identities []osxkeychain.IdentityWithRefModel, applications []string) error {
+	fmt.Println()
+	log.Infof("Applications allowed to sign with the private keys without a Keychain prompt:")
+
+	plan := confirm.NewPlan("Allow the applications to use the private keys without a Keychain prompt (macOS asks for the keychain password to change the access)")
+	var toAuthorize []osxkeychain.IdentityWithRefModel
+	for _, identity := range identities {
+		access, err := osxkeychain.InspectKeyAccess(identity)
+		if err != nil {
+			log.Warnf("Failed to read the access control list of the private key of %s: %s", identity.Label, err)
+			continue
+		}
+		log.Printf("%s: %s", identity.Label, access)
+
+		if missing := access.Missing(applications); len(missing) > 0 {
+			plan.Add("authorize", identity.Label, strings.Join(missing, ", "))
+			toAuthorize = append(toAuthorize, identity)
+		}
+	}
+	if len(toAuthorize) == 0 {
+		return nil
+	}
+
+	authorize, err := plan.Confirm()
+	if err != nil {
+		return err
+	}
+	if !authorize {
+		log.Warnf("Continuing without changing the access of the private keys, the export and the signing might show Keychain prompts.")
+		return nil
+	}
+	for _, identity := range toAuthorize {
+		if err := osxkeychain.AllowApplications(identity, applications); err != nil {
+			return fmt.Errorf("failed to change the access of the private key of %s, error: %s", identity.Label, err)
+		}
+		log.Donef("Authorized: %s", identity.Label)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package codesign
+
+import (
+	"github.com/bitrise-io/codesigndoc/keychain"
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+)
+
+// identityKeychain returns the Keychain of the config, the user's keychain search list if it is not set
+func (config IdentityExportConfig) identityKeychain() keychain.Keychain {
+	if config.Keychain != nil {
+		return config.Keychain
+	}
+	return osxkeychain.NewKeychain("")
+}
+
+// identityQuery returns the query of the identities of the labels, restricted by the config
+func (config IdentityExportConfig) identityQuery(labels []string) keychain.Query {
+	query := keychain.Query{Labels: labels, TeamIDs: config.TeamIDs, Fingerprints: config.Fingerprints, Keychains: config.Keychains, IncludeExpired: config.IncludeExpired}
+	for _, t := range config.Types {
+		query.Types = append(query.Types, string(t))
+	}
+	return query
+}
+
+// securityFrameworkIdentities returns the osxkeychain models of the identities, for the checks implemented with the Security framework only
+// (e.g. the private key export and access checks), false if an identity is not of an osxkeychain.Keychain (e.g. of keychaintest.Fake).
+// The references are owned by the identities.
+func securityFrameworkIdentities(identities []*keychain.Identity) ([]osxkeychain.IdentityWithRefModel, bool) {
+	var models []osxkeychain.IdentityWithRefModel
+	for _, identity := range identities {
+		model, err := osxkeychain.UnwrapIdentity(identity)
+		if err != nil {
+			return nil, false
+		}
+		models = append(models, model)
+	}
+	return models, true
+}
@@ -0,0 +1,78 @@
+package codesign
+
+import (
+	"encoding/pem"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/models"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+)
+
+// namedFile is a file of the export directory
+type namedFile struct {
+	Name    string
+	Content []byte
+}
+
+// separateKeyFileNames returns the names of the private key (.key.pem), the DER certificate (.cer)
+// and the PEM certificate (.crt.pem) of the identity, named after its separately exported .p12 without the extension
+func separateKeyFileNames(certificate certificateutil.CertificateInfoModel, naming NamingConfig) (string, string, string) {
+	name := naming.IdentityFileName(certificate)
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	return base + ".key.pem", base + ".cer", base + ".crt.pem"
+}
+
+// separateKeyFiles returns the private key and certificate files of the identities exported with SeparateKeys
+func separateKeyFiles(identities models.Certificates, naming NamingConfig) []namedFile {
+	var files []namedFile
+	for i, key := range identities.Keys {
+		if i >= len(identities.Info) {
+			break
+		}
+		certificate := identities.Info[i]
+		keyName, derName, pemName := separateKeyFileNames(certificate, naming)
+		files = append(files,
+			namedFile{Name: keyName, Content: key},
+			namedFile{Name: derName, Content: certificate.Certificate.Raw},
+			namedFile{Name: pemName, Content: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Certificate.Raw})},
+		)
+	}
+	return files
+}
+
+// certificateOnlyFiles returns the DER and PEM certificate files of the identities exported without the private key
+func certificateOnlyFiles(identities models.Certificates, naming NamingConfig) []namedFile {
+	return certificateFilesOf(identities.CertificateOnly, naming)
+}
+
+// certificateFiles returns the DER and PEM certificate files of the exported identities, for WriteFilesConfig.CertificateFiles,
+// the identities exported with SeparateKeys have these files already
+func certificateFiles(identities models.Certificates, naming NamingConfig) []namedFile {
+	if len(identities.Keys) > 0 {
+		return nil
+	}
+	return certificateFilesOf(identities.Info, naming)
+}
+
+// identityFiles returns the separate key and certificate files of the identities written by WriteFiles
+func identityFiles(identities models.Certificates, writeFilesConfig WriteFilesConfig) []namedFile {
+	files := append(separateKeyFiles(identities, writeFilesConfig.Naming), certificateOnlyFiles(identities, writeFilesConfig.Naming)...)
+	if writeFilesConfig.CertificateFiles {
+		files = append(files, certificateFiles(identities, writeFilesConfig.Naming)...)
+	}
+	return files
+}
+
+// certificateFilesOf returns the DER (.cer) and PEM (.crt.pem) files of the certificates
+func certificateFilesOf(certificates []certificateutil.CertificateInfoModel, naming NamingConfig) []namedFile {
+	var files []namedFile
+	for _, certificate := range certificates {
+		_, derName, pemName := separateKeyFileNames(certificate, naming)
+		files = append(files,
+			namedFile{Name: derName, Content: certificate.Certificate.Raw},
+			namedFile{Name: pemName, Content: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Certificate.Raw})},
+		)
+	}
+	return files
+}
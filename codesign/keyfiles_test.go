@@ -0,0 +1,57 @@
+package codesign
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/bitrise-io/codesigndoc/models"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeparateKeyFiles(t *testing.T) {
+	certificate := certificateutil.CertificateInfoModel{CommonName: "Apple Distribution: Bitrise Ltd. (ABCD123456)", TeamID: "ABCD123456", Serial: "1A2B", Certificate: x509.Certificate{Raw: []byte("der")}}
+	identities := models.Certificates{Info: []certificateutil.CertificateInfoModel{certificate}, Keys: [][]byte{[]byte("key")}}
+
+	files := separateKeyFiles(identities, NamingConfig{})
+	require.Len(t, files, 3)
+	require.Equal(t, "Apple_Distribution_Bitrise_Ltd._(ABCD123456)_1A2B.key.pem", files[0].Name)
+	require.Equal(t, []byte("key"), files[0].Content)
+	require.Equal(t, "Apple_Distribution_Bitrise_Ltd._(ABCD123456)_1A2B.cer", files[1].Name)
+	require.Equal(t, []byte("der"), files[1].Content)
+	require.Equal(t, "Apple_Distribution_Bitrise_Ltd._(ABCD123456)_1A2B.crt.pem", files[2].Name)
+	block, _ := pem.Decode(files[2].Content)
+	require.Equal(t, "CERTIFICATE", block.Type)
+	require.Equal(t, []byte("der"), block.Bytes)
+
+	files = separateKeyFiles(identities, NamingConfig{IdentityTemplate: "{team_id}/{serial}.p12"})
+	require.Equal(t, "ABCD123456/1A2B.key.pem", files[0].Name)
+	require.Empty(t, separateKeyFiles(models.Certificates{Info: identities.Info}, NamingConfig{}))
+}
+
+func TestCertificateOnlyFiles(t *testing.T) {
+	certificate := certificateutil.CertificateInfoModel{CommonName: "Apple Development: John Doe (ABCD123456)", Serial: "3C4D", Certificate: x509.Certificate{Raw: []byte("der")}}
+
+	files := certificateOnlyFiles(models.Certificates{CertificateOnly: []certificateutil.CertificateInfoModel{certificate}}, NamingConfig{})
+	require.Len(t, files, 2)
+	require.Equal(t, "Apple_Development_John_Doe_(ABCD123456)_3C4D.cer", files[0].Name)
+	require.Equal(t, []byte("der"), files[0].Content)
+	require.Equal(t, "Apple_Development_John_Doe_(ABCD123456)_3C4D.crt.pem", files[1].Name)
+	require.Empty(t, certificateOnlyFiles(models.Certificates{}, NamingConfig{}))
+}
+
+func TestIdentityFiles(t *testing.T) {
+	certificate := certificateutil.CertificateInfoModel{CommonName: "Apple Push Services: com.bitrise.app", Serial: "5E6F", Certificate: x509.Certificate{Raw: []byte("der")}}
+	identities := models.Certificates{Info: []certificateutil.CertificateInfoModel{certificate}}
+
+	require.Empty(t, identityFiles(identities, WriteFilesConfig{}))
+	files := identityFiles(identities, WriteFilesConfig{CertificateFiles: true})
+	require.Len(t, files, 2)
+	require.Equal(t, "Apple_Push_Services_com.bitrise.app_5E6F.cer", files[0].Name)
+	require.Equal(t, "Apple_Push_Services_com.bitrise.app_5E6F.crt.pem", files[1].Name)
+
+	// the separate keys come with the certificate files
+	identities.Keys = [][]byte{[]byte("key")}
+	require.Len(t, identityFiles(identities, WriteFilesConfig{CertificateFiles: true}), 3)
+}
@@ -0,0 +1,58 @@
+package codesign
+
+import (
+	"fmt"
+
+	"github.com/bitrise-io/codesigndoc/fastlane"
+	"github.com/bitrise-io/codesigndoc/models"
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// MatchRepoConfig writes the exported files in the directory layout and encryption of a fastlane match repo too
+type MatchRepoConfig struct {
+	// DirPath is the root of the match repo, the match layout is not written if empty
+	DirPath string
+	// Password encrypts the files, the MATCH_PASSWORD of the repo
+	Password string
+	// CertificateIDs maps the certificate serials to their developer portal IDs, which name the certificate files,
+	// the serial is used if the ID is unknown
+	CertificateIDs map[string]string
+}
+
+// matchFiles returns the files of the match repo, the identities have to be exported one by one with an empty passphrase
+func matchFiles(identities models.Certificates, provisioningProfiles []models.ProvisioningProfile, config MatchRepoConfig) ([]fastlane.MatchFile, error) {
+	if len(identities.Info) > 0 && len(identities.Each) != len(identities.Info) {
+		return nil, fmt.Errorf("the identities have to be exported one by one for the match repo")
+	}
+
+	var files []fastlane.MatchFile
+	for i, certificate := range identities.Info {
+		id, ok := config.CertificateIDs[certificate.Serial]
+		if !ok {
+			log.Warnf("The developer portal ID of %s is unknown, the certificate is named after its serial in the match repo, rename it to the ID", certificate.CommonName)
+			id = certificate.Serial
+		}
+		files = append(files, fastlane.MatchCertificateFiles(certificate, id, identities.Each[i])...)
+	}
+	for _, profile := range provisioningProfiles {
+		files = append(files, fastlane.MatchFile{Path: fastlane.MatchProfilePath(profile.Info), Content: profile.Content})
+	}
+	return files, nil
+}
+
+// writeMatchRepo writes the encrypted files into the match repo, returns the written paths
+func writeMatchRepo(identities models.Certificates, provisioningProfiles []models.ProvisioningProfile, config MatchRepoConfig) ([]string, error) {
+	files, err := matchFiles(identities, provisioningProfiles, config)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Println()
+	log.Infof("Writing the fastlane match repo: %s", config.DirPath)
+	paths, err := fastlane.WriteMatchRepo(config.DirPath, config.Password, files)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Commit and push the directory as the match repo, then run match in readonly mode")
+	return paths, nil
+}
@@ -0,0 +1,129 @@
+package codesign
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/keychain"
+	"github.com/bitrise-io/codesigndoc/utility"
+	"github.com/bitrise-io/go-utils/sliceutil"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// NamingConfig sets the names of the written files, relative to the output directory,
+// the default names are used for the empty templates.
+type NamingConfig struct {
+	// IdentityTemplate names the separately exported .p12 files, e.g. {team_id}-{cert_type}-{common_name}.p12
+	IdentityTemplate string
+	// ProfileTemplate names the provisioning profiles, e.g. {profile_name}-{uuid}{ext}
+	ProfileTemplate string
+}
+
+// IdentityPlaceholders are the placeholders of the NamingConfig's IdentityTemplate
+var IdentityPlaceholders = []string{"common_name", "cert_type", "team_id", "team_name", "serial", "sha1"}
+
+// ProfilePlaceholders are the placeholders of the NamingConfig's ProfileTemplate,
+// ext is .mobileprovision, or .provisionprofile for the macOS profiles
+var ProfilePlaceholders = []string{"profile_name", "uuid", "team_id", "team_name", "bundle_id", "export_type", "ext"}
+
+// placeholderPattern matches a placeholder of a naming template
+var placeholderPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// Validate checks the placeholders of the templates, and that the names stay in the output directory
+func (config NamingConfig) Validate() error {
+	if err := validateTemplate(config.IdentityTemplate, IdentityPlaceholders); err != nil {
+		return fmt.Errorf("invalid identity naming template (%s): %s", config.IdentityTemplate, err)
+	}
+	if err := validateTemplate(config.ProfileTemplate, ProfilePlaceholders); err != nil {
+		return fmt.Errorf("invalid profile naming template (%s): %s", config.ProfileTemplate, err)
+	}
+	return nil
+}
+
+func validateTemplate(template string, placeholders []string) error {
+	if template == "" {
+		return nil
+	}
+	for _, match := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		if !sliceutil.IsStringInSlice(match[1], placeholders) {
+			return fmt.Errorf("unknown placeholder {%s}, valid placeholders: {%s}", match[1], strings.Join(placeholders, "}, {"))
+		}
+	}
+	if strings.ContainsAny(placeholderPattern.ReplaceAllString(template, ""), "{}") {
+		return errors.New("unbalanced braces")
+	}
+	if path.IsAbs(template) || filepath.IsAbs(template) {
+		return errors.New("the name has to be relative to the output directory")
+	}
+	for _, segment := range strings.Split(template, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return errors.New("empty, . or .. path segment")
+		}
+	}
+	return nil
+}
+
+// expandTemplate replaces the placeholders with the values, made safe for file names;
+// the slashes of the template separate subdirectories, the slashes of the values are replaced.
+func expandTemplate(template string, values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value := values[strings.Trim(placeholder, "{}")]
+		if placeholder == "{ext}" {
+			return value
+		}
+		return strings.Trim(unsafeFileNameChars.ReplaceAllString(value, "_"), "_")
+	})
+}
+
+// IdentityFileName returns the name of the separately exported .p12 of the certificate
+func (config NamingConfig) IdentityFileName(certificate certificateutil.CertificateInfoModel) string {
+	if config.IdentityTemplate == "" {
+		return identityFileName(certificate)
+	}
+	return filepath.FromSlash(expandTemplate(config.IdentityTemplate, map[string]string{
+		"common_name": certificate.CommonName,
+		"cert_type":   keychain.TypeOf(certificate.CommonName),
+		"team_id":     certificate.TeamID,
+		"team_name":   certificate.TeamName,
+		"serial":      certificate.Serial,
+		"sha1":        certificate.SHA1Fingerprint,
+	}))
+}
+
+// ProfileFileName returns the name of the written provisioning profile
+func (config NamingConfig) ProfileFileName(profile profileutil.ProvisioningProfileInfoModel) string {
+	if config.ProfileTemplate == "" {
+		return utility.ProfileExportFileNameNoPath(profile)
+	}
+	ext := ".mobileprovision"
+	if profile.Type == profileutil.ProfileTypeMacOs {
+		ext = ".provisionprofile"
+	}
+	return filepath.FromSlash(expandTemplate(config.ProfileTemplate, map[string]string{
+		"profile_name": profile.Name,
+		"uuid":         profile.UUID,
+		"team_id":      profile.TeamID,
+		"team_name":    profile.TeamName,
+		"bundle_id":    profile.BundleID,
+		"export_type":  string(profile.ExportType),
+		"ext":          ext,
+	}))
+}
+
+// checkUniqueNames returns an error if a template names multiple files the same, e.g. it lacks the {uuid}
+func checkUniqueNames(names []string) error {
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i] == sorted[i-1] {
+			return fmt.Errorf("multiple files are named %s, add a distinguishing placeholder (e.g. {serial} or {uuid}) to the naming template", sorted[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package codesign
+
+import (
+	"testing"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamingConfig(t *testing.T) {
+	certificate := certificateutil.CertificateInfoModel{CommonName: "Apple Distribution: Bitrise Ltd. (ABCD123456)", TeamID: "ABCD123456", Serial: "1A2B"}
+	profile := profileutil.ProvisioningProfileInfoModel{Name: "iOS Team Provisioning Profile: *", UUID: "8f2e-11aa", TeamID: "ABCD123456"}
+
+	require.Equal(t, identityFileName(certificate), NamingConfig{}.IdentityFileName(certificate))
+	require.Equal(t, "8f2e-11aa.iOSTeamProvisioningProfile.mobileprovision", NamingConfig{}.ProfileFileName(profile))
+
+	naming := NamingConfig{IdentityTemplate: "{team_id}/{cert_type}-{common_name}.p12", ProfileTemplate: "{profile_name}-{uuid}{ext}"}
+	require.NoError(t, naming.Validate())
+	require.Equal(t, "ABCD123456/Apple_Distribution-Apple_Distribution_Bitrise_Ltd._(ABCD123456).p12", naming.IdentityFileName(certificate))
+	require.Equal(t, "iOS_Team_Provisioning_Profile-8f2e-11aa.mobileprovision", naming.ProfileFileName(profile))
+
+	profile.Type = profileutil.ProfileTypeMacOs
+	require.Equal(t, "iOS_Team_Provisioning_Profile-8f2e-11aa.provisionprofile", naming.ProfileFileName(profile))
+}
+
+func TestNamingConfigValidate(t *testing.T) {
+	for _, template := range []string{"{common_name}.p12", "certs/{team_id}/{serial}.p12"} {
+		require.NoError(t, NamingConfig{IdentityTemplate: template}.Validate(), template)
+	}
+	for _, template := range []string{"{uuid}.p12", "{common_name.p12", "/tmp/{serial}.p12", "../{serial}.p12", "certs//{serial}.p12"} {
+		require.Error(t, NamingConfig{IdentityTemplate: template}.Validate(), template)
+	}
+	require.Error(t, NamingConfig{ProfileTemplate: "{serial}{ext}"}.Validate())
+
+	require.NoError(t, checkUniqueNames([]string{"a.p12", "b.p12"}))
+	require.Error(t, checkUniqueNames([]string{"a.p12", "b.p12", "a.p12"}))
+}
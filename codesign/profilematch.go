@@ -0,0 +1,122 @@
+package codesign
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// The kinds of the profile matches, an exact match is ranked above a wildcard one
+const (
+	ProfileMatchExact    = "exact"
+	ProfileMatchWildcard = "wildcard"
+)
+
+// ProfileMatch is a profile which can sign the bundle ID, with its rank among the candidates
+type ProfileMatch struct {
+	BundleID string
+	Profile  profileutil.ProvisioningProfileInfoModel
+	Kind     string
+	// Rank is 1 based, 1 is the best
+	Rank       int
+	Candidates int
+	Reason     string
+}
+
+// MatchBundleID returns the kind of the match if the profile's bundle ID covers the bundle ID, the team prefix
+// (TEAM.* or TEAM.com.company.*) is already removed from the profile's bundle ID.
+func MatchBundleID(profileBundleID, bundleID string) (string, bool) {
+	if profileBundleID == bundleID {
+		return ProfileMatchExact, true
+	}
+	if IsWildcardBundleIDMatch(profileBundleID, bundleID) {
+		return ProfileMatchWildcard, true
+	}
+	return "", false
+}
+
+// RankProfiles returns the profiles covering the bundle ID from the best to the worst:
+// the exact matches, then the wildcard matches with the longer (more specific) prefix,
+// then the later expiration date, then the name.
+func RankProfiles(bundleID string, profiles []profileutil.ProvisioningProfileInfoModel) []ProfileMatch {
+	var matches []ProfileMatch
+	for _, profile := range profiles {
+		if kind, ok := MatchBundleID(profile.BundleID, bundleID); ok {
+			matches = append(matches, ProfileMatch{BundleID: bundleID, Profile: profile, Kind: kind})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Kind != b.Kind {
+			return a.Kind == ProfileMatchExact
+		}
+		if len(a.Profile.BundleID) != len(b.Profile.BundleID) {
+			return len(a.Profile.BundleID) > len(b.Profile.BundleID)
+		}
+		if !a.Profile.ExpirationDate.Equal(b.Profile.ExpirationDate) {
+			return a.Profile.ExpirationDate.After(b.Profile.ExpirationDate)
+		}
+		return a.Profile.Name < b.Profile.Name
+	})
+
+	for i := range matches {
+		matches[i].Rank = i + 1
+		matches[i].Candidates = len(matches)
+		matches[i].Reason = matchReason(matches, i)
+	}
+	return matches
+}
+
+// matchReason describes the match and why it is ranked below the previous one
+func matchReason(matches []ProfileMatch, i int) string {
+	match := matches[i]
+	var reasons []string
+	if match.Kind == ProfileMatchExact {
+		reasons = append(reasons, "the profile's bundle ID is exactly "+match.BundleID)
+	} else {
+		reasons = append(reasons, fmt.Sprintf("the wildcard App ID %s covers %s", match.Profile.BundleID, match.BundleID))
+	}
+
+	if i == 0 {
+		if len(matches) > 1 {
+			reasons = append(reasons, fmt.Sprintf("ranked first of %d candidates", len(matches)))
+		}
+		return strings.Join(reasons, ", ")
+	}
+
+	previous := matches[i-1]
+	switch {
+	case previous.Kind != match.Kind:
+		reasons = append(reasons, "ranked below the exact matches")
+	case len(previous.Profile.BundleID) != len(match.Profile.BundleID):
+		reasons = append(reasons, fmt.Sprintf("ranked below the more specific %s", previous.Profile.BundleID))
+	case !previous.Profile.ExpirationDate.Equal(match.Profile.ExpirationDate):
+		reasons = append(reasons, "ranked below the profiles expiring later")
+	default:
+		reasons = append(reasons, "ranked by name")
+	}
+	return strings.Join(reasons, ", ")
+}
+
+var (
+	profileSelectionsMux sync.Mutex
+	profileSelections    []ProfileMatch
+)
+
+// RecordProfileSelection remembers the chosen profile of a bundle ID, for the scan result
+func RecordProfileSelection(match ProfileMatch) {
+	profileSelectionsMux.Lock()
+	defer profileSelectionsMux.Unlock()
+	profileSelections = append(profileSelections, match)
+}
+
+// ProfileSelections returns the recorded profile choices, in the order of the recording
+func ProfileSelections() []ProfileMatch {
+	profileSelectionsMux.Lock()
+	defer profileSelectionsMux.Unlock()
+	return append([]ProfileMatch{}, profileSelections...)
+}
@@ -0,0 +1,39 @@
+package codesign
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRankProfiles(t *testing.T) {
+	later := time.Now().Add(48 * time.Hour)
+	sooner := time.Now().Add(24 * time.Hour)
+	profiles := []profileutil.ProvisioningProfileInfoModel{
+		{UUID: "team wildcard", BundleID: "*", ExpirationDate: later},
+		{UUID: "prefix wildcard", BundleID: "io.bitrise.*", ExpirationDate: later},
+		{UUID: "exact expiring sooner", BundleID: "io.bitrise.app", ExpirationDate: sooner},
+		{UUID: "exact", BundleID: "io.bitrise.app", ExpirationDate: later},
+		{UUID: "other app", BundleID: "io.other.app", ExpirationDate: later},
+	}
+
+	matches := RankProfiles("io.bitrise.app", profiles)
+	var uuids, kinds []string
+	for i, match := range matches {
+		require.Equal(t, i+1, match.Rank)
+		require.Equal(t, 4, match.Candidates)
+		uuids = append(uuids, match.Profile.UUID)
+		kinds = append(kinds, match.Kind)
+	}
+	require.Equal(t, []string{"exact", "exact expiring sooner", "prefix wildcard", "team wildcard"}, uuids)
+	require.Equal(t, []string{ProfileMatchExact, ProfileMatchExact, ProfileMatchWildcard, ProfileMatchWildcard}, kinds)
+
+	require.Equal(t, "the profile's bundle ID is exactly io.bitrise.app, ranked first of 4 candidates", matches[0].Reason)
+	require.Equal(t, "the profile's bundle ID is exactly io.bitrise.app, ranked below the profiles expiring later", matches[1].Reason)
+	require.Equal(t, "the wildcard App ID io.bitrise.* covers io.bitrise.app, ranked below the exact matches", matches[2].Reason)
+	require.Equal(t, "the wildcard App ID * covers io.bitrise.app, ranked below the more specific io.bitrise.*", matches[3].Reason)
+
+	require.Equal(t, 0, len(RankProfiles("io.unknown", profiles[2:])))
+}
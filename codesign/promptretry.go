@@ -0,0 +1,64 @@
+package codesign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/bitrise-io/codesigndoc/prompt"
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// MaxPromptAttempts is the number of times a Keychain export is tried if its prompt is canceled or the password is wrong
+const MaxPromptAttempts = 3
+
+// promptRetryBackoff is the wait before the Keychain prompt is shown again, it is doubled after every attempt,
+// so the closed prompt of the previous attempt does not swallow the click
+var promptRetryBackoff = time.Second
+
+// askPromptRetry asks whether the Keychain prompt should be shown again
+var askPromptRetry = func() (bool, error) {
+	return prompt.AskForBoolFromReader("Do you want to show the Keychain prompt again?", os.Stdin)
+}
+
+// isPromptFailure reports whether the export failed at the Keychain prompt, which can be answered again
+func isPromptFailure(err error) bool {
+	return errors.Is(err, osxkeychain.ErrUserCanceled) || errors.Is(err, osxkeychain.ErrAuthFailed)
+}
+
+// withPromptRetry runs the Keychain export, and offers to run it again, up to MaxPromptAttempts times,
+// if its prompt was canceled or the password was wrong, instead of failing the scan with the already collected results.
+// The non interactive runs fail at the first attempt.
+func withPromptRetry(ctx context.Context, export func() error) error {
+	backoff := promptRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := export()
+		if err == nil || !isPromptFailure(err) || attempt >= MaxPromptAttempts || prompt.NonInteractive {
+			return err
+		}
+
+		fmt.Println()
+		if errors.Is(err, osxkeychain.ErrUserCanceled) {
+			log.Warnf("The Keychain prompt was canceled (attempt %d of %d), click Allow to export the identities.", attempt, MaxPromptAttempts)
+		} else {
+			log.Warnf("The Keychain password was wrong (attempt %d of %d).", attempt, MaxPromptAttempts)
+		}
+		retry, askErr := askPromptRetry()
+		if askErr != nil {
+			return askErr
+		}
+		if !retry {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
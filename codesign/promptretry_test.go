@@ -0,0 +1,60 @@
+package codesign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPromptRetry(t *testing.T) {
+	originalAsk, originalBackoff := askPromptRetry, promptRetryBackoff
+	defer func() {
+		askPromptRetry, promptRetryBackoff = originalAsk, originalBackoff
+	}()
+	promptRetryBackoff = 0
+
+	asked := 0
+	askPromptRetry = func() (bool, error) {
+		asked++
+		return true, nil
+	}
+	attempts := 0
+	err := withPromptRetry(context.Background(), func() error {
+		attempts++
+		if attempts < 2 {
+			return fmt.Errorf("failed to export: %w", osxkeychain.ErrUserCanceled)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, attempts)
+	require.Equal(t, 1, asked)
+
+	attempts, asked = 0, 0
+	err = withPromptRetry(context.Background(), func() error {
+		attempts++
+		return osxkeychain.ErrAuthFailed
+	})
+	require.True(t, errors.Is(err, osxkeychain.ErrAuthFailed))
+	require.Equal(t, MaxPromptAttempts, attempts)
+	require.Equal(t, MaxPromptAttempts-1, asked)
+
+	attempts = 0
+	askPromptRetry = func() (bool, error) { return false, nil }
+	require.True(t, errors.Is(withPromptRetry(context.Background(), func() error {
+		attempts++
+		return osxkeychain.ErrUserCanceled
+	}), osxkeychain.ErrUserCanceled))
+	require.Equal(t, 1, attempts)
+
+	attempts = 0
+	require.Error(t, withPromptRetry(context.Background(), func() error {
+		attempts++
+		return errors.New("other failure")
+	}))
+	require.Equal(t, 1, attempts)
+}
@@ -0,0 +1,219 @@
+package codesign
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/keychain"
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/bitrise-io/codesigndoc/state"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+)
+
+// PushCertificate is an exported APNs certificate with its private key
+type PushCertificate struct {
+	Info certificateutil.CertificateInfoModel
+	// BundleID is the app the certificate sends the push notifications of, the topic of the APNs requests
+	BundleID string
+	P12      []byte
+	PEM      []byte
+}
+
+// PushBundleID returns the bundle ID of an APNs certificate's common name,
+// e.g. com.company.app of "Apple Push Services: com.company.app"
+func PushBundleID(commonName string) string {
+	split := strings.SplitN(commonName, ":", 2)
+	if len(split) < 2 {
+		return ""
+	}
+	return strings.TrimSpace(split[1])
+}
+
+// isPushCertificateOf returns true if the certificate sends the push notifications of any of the bundle IDs,
+// an empty list matches every certificate
+func isPushCertificateOf(commonName string, bundleIDs []string) bool {
+	if len(bundleIDs) == 0 {
+		return true
+	}
+	bundleID := PushBundleID(commonName)
+	for _, b := range bundleIDs {
+		if b == bundleID {
+			return true
+		}
+	}
+	return false
+}
+
+// pushFileName returns the name of the exported APNs certificate without extension, named after its common name and serial
+func pushFileName(certificate certificateutil.CertificateInfoModel) string {
+	return unsafeFileNameChars.ReplaceAllString(certificate.CommonName+" "+certificate.Serial, "_")
+}
+
+// ExportPushCertificates exports the APNs certificates of the bundle IDs (every APNs certificate if empty) from the Keychain,
+// as a separate .p12 and .pem file each, protected by the passphrase of the config.
+func ExportPushCertificates(ctx context.Context, bundleIDs []string, config IdentityExportConfig) ([]PushCertificate, error) {
+	kc := config.identityKeychain()
+	identities, release, err := findPushIdentities(ctx, kc, bundleIDs, config)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if len(identities) == 0 {
+		return nil, nil
+	}
+
+	for _, identity := range identities {
+		log.Printf("exporting push certificate: %s", identity)
+	}
+
+	fmt.Println()
+	log.Warnf("You'll most likely see two popups for each certificate from Keychain (one for the .p12 and one for the .pem),")
+	log.Warnf("you will have to accept (Allow) those to be able to export the certificates!")
+	fmt.Println()
+
+	p12s, err := exportFromKeychain(ctx, kc, identities, keychain.ExportFormatPKCS12, true, config)
+	if err != nil {
+		return nil, keychainExportError(err)
+	}
+	pems, err := exportFromKeychain(ctx, kc, identities, keychain.ExportFormatPEM, true, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export PEM, error: %w", keychainExportError(err))
+	}
+
+	var certificates []PushCertificate
+	for i, identity := range identities {
+		cert, err := kc.CopyCertificate(identity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the certificate of %s, error: %s", identity.Label, err)
+		}
+		info := certificateutil.NewCertificateInfo(*cert, nil)
+		certificates = append(certificates, PushCertificate{
+			Info:     info,
+			BundleID: PushBundleID(info.CommonName),
+			P12:      p12s[i],
+			PEM:      pems[i],
+		})
+	}
+	return certificates, nil
+}
+
+// FindPushCertificates lists the APNs certificates of the bundle IDs (every APNs certificate if empty) which would be exported
+func FindPushCertificates(ctx context.Context, bundleIDs []string, config IdentityExportConfig) ([]certificateutil.CertificateInfoModel, error) {
+	kc := config.identityKeychain()
+	identities, release, err := findPushIdentities(ctx, kc, bundleIDs, config)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var certificates []certificateutil.CertificateInfoModel
+	for _, identity := range identities {
+		cert, err := kc.CopyCertificate(identity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the certificate of %s, error: %s", identity.Label, err)
+		}
+		certificates = append(certificates, certificateutil.NewCertificateInfo(*cert, nil))
+	}
+	return certificates, nil
+}
+
+// findPushIdentities selects the latest valid APNs identity of each bundle ID in the Keychain, the returned release function closes the identities
+func findPushIdentities(ctx context.Context, kc keychain.Keychain, bundleIDs []string, config IdentityExportConfig) ([]*keychain.Identity, func(), error) {
+	fmt.Println()
+	log.Infof("Searching for the push notification (APNs) certificates:")
+	query := keychain.Query{TeamIDs: config.TeamIDs, Keychains: config.Keychains, IncludeExpired: config.IncludeExpired}
+	for _, t := range osxkeychain.PushCertificateTypes {
+		query.Types = append(query.Types, string(t))
+	}
+	selected, skippedIdentities, err := kc.SelectIdentities(ctx, query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find the push certificates, error: %w", err)
+	}
+
+	var identities []*keychain.Identity
+	var others []*keychain.Identity
+	for _, identity := range selected {
+		if isPushCertificateOf(identity.Label, bundleIDs) {
+			identities = append(identities, identity)
+		} else {
+			others = append(others, identity)
+		}
+	}
+	_ = keychain.CloseAll(others)
+	release := func() {
+		_ = keychain.CloseAll(identities)
+	}
+	sort.Slice(identities, func(i, j int) bool { return identities[i].Label < identities[j].Label })
+
+	for _, skipped := range skippedIdentities {
+		if isPushCertificateOf(skipped.Label, bundleIDs) {
+			log.Warnf("Skipping push certificate %s [%s]: %s", skipped.Label, skipped.SHA1Fingerprint, skipped.Reason)
+		}
+	}
+	for _, bundleID := range bundleIDs {
+		found := false
+		for _, identity := range identities {
+			found = found || PushBundleID(identity.Label) == bundleID
+		}
+		if !found {
+			log.Warnf("No push certificate with a private key found for bundle ID: %s", bundleID)
+		}
+	}
+
+	neverExport, err := state.ReadNeverExportList()
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	for _, identity := range identities {
+		if neverExport.Contains(identity.SHA1Fingerprint()) {
+			release()
+			return nil, nil, fmt.Errorf("push certificate (%s) is listed in the never export list of this machine", identity.Label)
+		}
+	}
+	// the private key export check is implemented with the Security framework only
+	if securityIdentities, ok := securityFrameworkIdentities(identities); ok {
+		if err := osxkeychain.CheckExportable(securityIdentities); err != nil {
+			release()
+			return nil, nil, fmt.Errorf("failed to export, error: %s", err)
+		}
+	}
+	return identities, release, nil
+}
+
+// PushCertificateFilePaths returns the paths of the files written by WritePushCertificates
+func PushCertificateFilePaths(certificates []certificateutil.CertificateInfoModel, absOutputDirPath string) []string {
+	var paths []string
+	for _, certificate := range certificates {
+		name := pushFileName(certificate)
+		paths = append(paths, filepath.Join(absOutputDirPath, name+".p12"), filepath.Join(absOutputDirPath, name+".pem"))
+	}
+	return paths
+}
+
+// WritePushCertificates writes the .p12 and .pem file of every APNs certificate to the output directory
+func WritePushCertificates(certificates []PushCertificate, absOutputDirPath string) ([]string, error) {
+	if err := os.MkdirAll(absOutputDirPath, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create output directory for push certificates, error: %s", err)
+	}
+
+	var paths []string
+	for _, certificate := range certificates {
+		name := pushFileName(certificate.Info)
+		p12Pth, pemPth := filepath.Join(absOutputDirPath, name+".p12"), filepath.Join(absOutputDirPath, name+".pem")
+		if err := ioutil.WriteFile(p12Pth, certificate.P12, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write file, error: %s", err)
+		}
+		if err := ioutil.WriteFile(pemPth, certificate.PEM, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write file, error: %s", err)
+		}
+		paths = append(paths, p12Pth, pemPth)
+	}
+	return paths, nil
+}
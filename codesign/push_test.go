@@ -0,0 +1,44 @@
+package codesign
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/keychain/keychaintest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushBundleID(t *testing.T) {
+	require.Equal(t, "com.company.app", PushBundleID("Apple Push Services: com.company.app"))
+	require.Equal(t, "com.company.app", PushBundleID("Apple Development IOS Push Services: com.company.app"))
+	require.Equal(t, "", PushBundleID("Apple Push Services"))
+
+	require.True(t, isPushCertificateOf("Apple Push Services: com.company.app", nil))
+	require.True(t, isPushCertificateOf("Apple Push Services: com.company.app", []string{"com.company.other", "com.company.app"}))
+	require.False(t, isPushCertificateOf("Apple Push Services: com.company.app.beta", []string{"com.company.app"}))
+}
+
+func TestExportPushCertificates(t *testing.T) {
+	now := time.Now()
+	fake, err := keychaintest.New()
+	require.NoError(t, err)
+	push := addFakeIdentity(t, fake, keychaintest.Fixture{CommonName: "Apple Push Services: com.company.app", TeamID: "ABCD123456", NotBefore: now.AddDate(-1, 0, 0), NotAfter: now.AddDate(1, 0, 0)})
+	addFakeIdentity(t, fake, keychaintest.Fixture{CommonName: "Apple Push Services: com.company.other", TeamID: "ABCD123456", NotBefore: now.AddDate(-1, 0, 0), NotAfter: now.AddDate(1, 0, 0)})
+	addFakeIdentity(t, fake, keychaintest.Fixture{CommonName: "Apple Distribution: Bitrise (ABCD123456)", TeamID: "ABCD123456", NotBefore: now.AddDate(-1, 0, 0), NotAfter: now.AddDate(1, 0, 0)})
+	config := IdentityExportConfig{Keychain: fake, Passphrase: "secret"}
+
+	found, err := FindPushCertificates(context.Background(), nil, config)
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	require.Equal(t, 0, fake.Open())
+
+	certificates, err := ExportPushCertificates(context.Background(), []string{"com.company.app"}, config)
+	require.NoError(t, err)
+	require.Len(t, certificates, 1)
+	require.Equal(t, push.SHA1Fingerprint, certificates[0].Info.SHA1Fingerprint)
+	require.Equal(t, "com.company.app", certificates[0].BundleID)
+	require.NotEmpty(t, certificates[0].P12)
+	require.NotEmpty(t, certificates[0].PEM)
+	require.Equal(t, 0, fake.Open())
+}
@@ -0,0 +1,52 @@
+package codesign
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/bitrise-io/codesigndoc/models"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// ReadExportedFiles reads the codesigning files written by a previous export from the given directory,
+// p12Name is the name of the identities file in the directory.
+func ReadExportedFiles(dir, p12Name, p12Passphrase string) (models.Certificates, []models.ProvisioningProfile, error) {
+	var certificates models.Certificates
+	p12Pth := filepath.Join(dir, p12Name)
+	if exist, err := pathutil.IsPathExists(p12Pth); err != nil {
+		return models.Certificates{}, nil, err
+	} else if exist {
+		content, err := ioutil.ReadFile(p12Pth)
+		if err != nil {
+			return models.Certificates{}, nil, fmt.Errorf("failed to read %s, error: %s", p12Pth, err)
+		}
+		infos, err := certificateutil.CertificatesFromPKCS12Content(content, p12Passphrase)
+		if err != nil {
+			return models.Certificates{}, nil, fmt.Errorf("failed to read certificates from %s, error: %s", p12Pth, err)
+		}
+		certificates = models.Certificates{Info: infos, Content: content}
+	}
+
+	var profiles []models.ProvisioningProfile
+	for _, ext := range []string{"*.mobileprovision", "*.provisionprofile"} {
+		pths, err := filepath.Glob(filepath.Join(dir, ext))
+		if err != nil {
+			return models.Certificates{}, nil, err
+		}
+		for _, pth := range pths {
+			content, err := ioutil.ReadFile(pth)
+			if err != nil {
+				return models.Certificates{}, nil, fmt.Errorf("failed to read %s, error: %s", pth, err)
+			}
+			info, err := profileutil.NewProvisioningProfileInfoFromFile(pth)
+			if err != nil {
+				return models.Certificates{}, nil, fmt.Errorf("failed to read provisioning profile %s, error: %s", pth, err)
+			}
+			profiles = append(profiles, models.ProvisioningProfile{Info: info, Content: content})
+		}
+	}
+	return certificates, profiles, nil
+}
@@ -0,0 +1,331 @@
+package codesign
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/plistutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"howett.net/plist"
+)
+
+// ResignConfig configures the re-signing of an .ipa with exported code signing files
+type ResignConfig struct {
+	IPAPath       string
+	OutputPath    string
+	P12Path       string
+	P12Passphrase string
+	ProfilePaths  []string
+}
+
+// ResignedBundle is a bundle of the re-signed .ipa with the identity and the profile it is signed with
+type ResignedBundle struct {
+	Path     string
+	BundleID string
+	Identity certificateutil.CertificateInfoModel
+	// Profile is nil for frameworks and dylibs, they are signed without a profile and entitlements
+	Profile *profileutil.ProvisioningProfileInfoModel
+}
+
+// Resign re-signs the app and the nested bundles of the .ipa with the identity of the .p12 and the profiles:
+// the embedded profiles are replaced, the entitlements are taken from the profiles and the bundles are signed with codesign,
+// the nested ones first. The identity is imported into a temporary keychain, which is deleted afterwards.
+func Resign(config ResignConfig) ([]ResignedBundle, error) {
+	certificates, err := certificateutil.CertificatesFromPKCS12File(config.P12Path, config.P12Passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s, error: %s", config.P12Path, err)
+	}
+	var profiles []profileutil.ProvisioningProfileInfoModel
+	profilePaths := map[string]string{}
+	for _, pth := range config.ProfilePaths {
+		profile, err := profileutil.NewProvisioningProfileInfoFromFile(pth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s, error: %s", pth, err)
+		}
+		profiles = append(profiles, profile)
+		profilePaths[profile.UUID] = pth
+	}
+
+	tmpDir, err := ioutil.TempDir("", "codesigndoc-resign")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			log.Warnf("Failed to remove temporary directory (%s), error: %s", tmpDir, err)
+		}
+	}()
+
+	extractDir := filepath.Join(tmpDir, "ipa")
+	appPth, err := extractIPAApp(config.IPAPath, extractDir)
+	if err != nil {
+		return nil, err
+	}
+
+	keychainPth := filepath.Join(tmpDir, "resign.keychain-db")
+	cleanup, err := createSigningKeychain(keychainPth, config.P12Path, config.P12Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	bundles, err := findSignableBundles(appPth)
+	if err != nil {
+		return nil, err
+	}
+
+	// frameworks and dylibs are signed with the identity of the app, without a profile and entitlements
+	appBundleID, err := readBundleID(appPth)
+	if err != nil {
+		return nil, err
+	}
+	appIdentity, _, err := selectResignProfile(appBundleID, certificates, profiles, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var signed []ResignedBundle
+	for _, pth := range bundles {
+		if !isProvisionedBundle(pth) {
+			if err := runCodesign(pth, appIdentity.SHA1Fingerprint, keychainPth, ""); err != nil {
+				return nil, err
+			}
+			signed = append(signed, ResignedBundle{Path: pth, Identity: appIdentity})
+			continue
+		}
+		bundle, err := resignBundle(pth, certificates, profiles, profilePaths, keychainPth, tmpDir)
+		if err != nil {
+			return nil, err
+		}
+		signed = append(signed, bundle)
+	}
+
+	absOutput, err := filepath.Abs(config.OutputPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Remove(absOutput); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	cmd := command.New("zip", "-qry", absOutput, ".").SetDir(extractDir)
+	if out, err := cmd.RunAndReturnTrimmedCombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to zip the re-signed app, output: %s, error: %s", out, err)
+	}
+
+	for i := range signed {
+		if rel, err := filepath.Rel(extractDir, signed[i].Path); err == nil {
+			signed[i].Path = rel
+		}
+	}
+	return signed, nil
+}
+
+// resignBundle replaces the embedded profile of the app or app extension and signs it with the entitlements of the profile
+func resignBundle(pth string, certificates []certificateutil.CertificateInfoModel, profiles []profileutil.ProvisioningProfileInfoModel, profilePaths map[string]string, keychainPth, tmpDir string) (ResignedBundle, error) {
+	bundleID, err := readBundleID(pth)
+	if err != nil {
+		return ResignedBundle{}, err
+	}
+
+	identity, profile, err := selectResignProfile(bundleID, certificates, profiles, time.Now())
+	if err != nil {
+		return ResignedBundle{}, err
+	}
+
+	content, err := ioutil.ReadFile(profilePaths[profile.UUID])
+	if err != nil {
+		return ResignedBundle{}, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(pth, "embedded.mobileprovision"), content, 0644); err != nil {
+		return ResignedBundle{}, fmt.Errorf("failed to replace the embedded profile of %s, error: %s", pth, err)
+	}
+
+	entitlements, err := plist.Marshal(map[string]interface{}(resignEntitlements(profile.Entitlements, profile.TeamID, bundleID)), plist.XMLFormat)
+	if err != nil {
+		return ResignedBundle{}, fmt.Errorf("failed to marshal the entitlements of %s, error: %s", profile.Name, err)
+	}
+	entitlementsPth := filepath.Join(tmpDir, bundleID+".entitlements")
+	if err := ioutil.WriteFile(entitlementsPth, entitlements, 0600); err != nil {
+		return ResignedBundle{}, err
+	}
+
+	if err := runCodesign(pth, identity.SHA1Fingerprint, keychainPth, entitlementsPth); err != nil {
+		return ResignedBundle{}, err
+	}
+	return ResignedBundle{Path: pth, BundleID: bundleID, Identity: identity, Profile: &profile}, nil
+}
+
+// readBundleID reads the bundle ID from the Info.plist of the bundle
+func readBundleID(pth string) (string, error) {
+	info, err := plistutil.NewPlistDataFromFile(filepath.Join(pth, "Info.plist"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the Info.plist of %s, error: %s", pth, err)
+	}
+	bundleID, ok := info.GetString("CFBundleIdentifier")
+	if !ok {
+		return "", fmt.Errorf("the Info.plist of %s has no CFBundleIdentifier", pth)
+	}
+	return bundleID, nil
+}
+
+// resignEntitlements returns the entitlements of the profile to sign the bundle with,
+// a wildcard application identifier (TEAM.* or TEAM.com.company.*) is replaced with the bundle ID.
+// The other wildcard values (e.g. TEAM.* keychain access group, * associated domains) only list what the profile allows,
+// they are dropped instead of being signed into the bundle.
+func resignEntitlements(profileEntitlements plistutil.PlistData, teamID, bundleID string) plistutil.PlistData {
+	entitlements := plistutil.PlistData{}
+	for key, value := range profileEntitlements {
+		if value, ok := withoutWildcards(value); ok {
+			entitlements[key] = value
+		}
+	}
+	if appID, ok := profileEntitlements.GetString("application-identifier"); ok && strings.HasSuffix(appID, "*") {
+		entitlements["application-identifier"] = teamID + "." + bundleID
+	}
+	return entitlements
+}
+
+// withoutWildcards returns the entitlement value without the wildcard strings, false if only wildcards were in it
+func withoutWildcards(value interface{}) (interface{}, bool) {
+	switch value := value.(type) {
+	case string:
+		return value, !strings.Contains(value, "*")
+	case []interface{}:
+		values := []interface{}{}
+		for _, item := range value {
+			if s, ok := item.(string); ok && strings.Contains(s, "*") {
+				continue
+			}
+			values = append(values, item)
+		}
+		return values, len(values) > 0 || len(value) == 0
+	}
+	return value, true
+}
+
+// selectResignProfile returns the best ranked valid profile of the bundle ID which contains one of the certificates with a private key,
+// with that certificate
+func selectResignProfile(bundleID string, certificates []certificateutil.CertificateInfoModel, profiles []profileutil.ProvisioningProfileInfoModel, now time.Time) (certificateutil.CertificateInfoModel, profileutil.ProvisioningProfileInfoModel, error) {
+	matches := RankProfiles(bundleID, profiles)
+	for _, match := range matches {
+		if !match.Profile.ExpirationDate.After(now) {
+			continue
+		}
+		for _, profileCertificate := range match.Profile.DeveloperCertificates {
+			for _, certificate := range certificates {
+				if certificate.PrivateKey != nil && certificate.SHA1Fingerprint == profileCertificate.SHA1Fingerprint {
+					return certificate, match.Profile, nil
+				}
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return certificateutil.CertificateInfoModel{}, profileutil.ProvisioningProfileInfoModel{}, fmt.Errorf("no provisioning profile of %s is given", bundleID)
+	}
+	return certificateutil.CertificateInfoModel{}, profileutil.ProvisioningProfileInfoModel{}, fmt.Errorf("no valid provisioning profile of %s contains the certificate of the .p12", bundleID)
+}
+
+// findSignableBundles returns the app, its app extensions, watch apps, frameworks and dylibs, the nested ones first,
+// as a bundle has to be signed after the bundles it contains
+func findSignableBundles(appPth string) ([]string, error) {
+	var pths []string
+	if err := filepath.Walk(appPth, func(pth string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		switch ext := filepath.Ext(pth); {
+		case info.IsDir() && (ext == ".app" || ext == ".appex" || ext == ".framework"):
+			pths = append(pths, pth)
+		case !info.IsDir() && ext == ".dylib":
+			pths = append(pths, pth)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to find the bundles of %s, error: %s", appPth, err)
+	}
+	return signingOrder(pths), nil
+}
+
+// signingOrder sorts the paths deeper first, so every bundle comes after the bundles it contains
+func signingOrder(pths []string) []string {
+	sorted := append([]string{}, pths...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return strings.Count(sorted[i], string(filepath.Separator)) > strings.Count(sorted[j], string(filepath.Separator))
+	})
+	return sorted
+}
+
+// isProvisionedBundle returns true for the bundles which embed a provisioning profile (apps and app extensions)
+func isProvisionedBundle(pth string) bool {
+	ext := filepath.Ext(pth)
+	return ext == ".app" || ext == ".appex"
+}
+
+func runCodesign(pth, identity, keychainPth, entitlementsPth string) error {
+	args := []string{"--force", "--sign", identity, "--keychain", keychainPth}
+	if entitlementsPth != "" {
+		args = append(args, "--entitlements", entitlementsPth)
+	}
+	cmd := command.New("codesign", append(args, pth)...)
+	if out, err := cmd.RunAndReturnTrimmedCombinedOutput(); err != nil {
+		return fmt.Errorf("failed to sign %s, output: %s, error: %s", pth, out, err)
+	}
+	log.Debugf("Signed: %s", pth)
+	return nil
+}
+
+// createSigningKeychain imports the .p12 into a new keychain, allowing codesign to use its private key without a Keychain prompt,
+// and adds the keychain to the search list. The returned function deletes the keychain and restores the search list.
+// The passwords are not passed on the command line of security, the .p12 is imported in process.
+func createSigningKeychain(keychainPth, p12Pth, p12Passphrase string) (func(), error) {
+	content, err := ioutil.ReadFile(p12Pth)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	password := fmt.Sprintf("%x", b)
+
+	if err := osxkeychain.CreateKeychain(keychainPth, password, osxkeychain.KeychainSettings{}); err != nil {
+		return nil, fmt.Errorf("failed to create the temporary keychain, error: %s", err)
+	}
+	deleteKeychain := func() {
+		if err := osxkeychain.DeleteKeychain(keychainPth); err != nil {
+			log.Warnf("Failed to delete the temporary keychain (%s), error: %s", keychainPth, err)
+		}
+	}
+
+	if _, err := osxkeychain.ImportPKCS12(content, p12Passphrase, keychainPth, []string{osxkeychain.CodesignPath}); err != nil {
+		deleteKeychain()
+		return nil, fmt.Errorf("failed to import %s, error: %s", p12Pth, err)
+	}
+	if err := osxkeychain.SetKeyPartitionList(keychainPth, password); err != nil {
+		deleteKeychain()
+		return nil, err
+	}
+
+	restoreSearchList, err := osxkeychain.AddToSearchList(keychainPth)
+	if err != nil {
+		deleteKeychain()
+		return nil, fmt.Errorf("failed to add the temporary keychain to the search list, error: %s", err)
+	}
+	return func() {
+		if err := restoreSearchList(); err != nil {
+			log.Warnf("Failed to restore the keychain search list, error: %s", err)
+		}
+		deleteKeychain()
+	}, nil
+}
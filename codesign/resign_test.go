@@ -0,0 +1,71 @@
+package codesign
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/plistutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigningOrder(t *testing.T) {
+	pths := []string{
+		"Payload/App.app",
+		"Payload/App.app/Frameworks/Lib.framework",
+		"Payload/App.app/PlugIns/Widget.appex",
+		"Payload/App.app/PlugIns/Widget.appex/Frameworks/libswift.dylib",
+	}
+	require.Equal(t, []string{
+		"Payload/App.app/PlugIns/Widget.appex/Frameworks/libswift.dylib",
+		"Payload/App.app/Frameworks/Lib.framework",
+		"Payload/App.app/PlugIns/Widget.appex",
+		"Payload/App.app",
+	}, signingOrder(pths))
+}
+
+func TestResignEntitlements(t *testing.T) {
+	profile := plistutil.PlistData{"application-identifier": "ABCD123456.*", "get-task-allow": false}
+	entitlements := resignEntitlements(profile, "ABCD123456", "io.bitrise.app")
+	require.Equal(t, "ABCD123456.io.bitrise.app", entitlements["application-identifier"])
+	require.Equal(t, false, entitlements["get-task-allow"])
+	require.Equal(t, "ABCD123456.*", profile["application-identifier"])
+
+	wildcards := plistutil.PlistData{
+		"application-identifier":                           "ABCD123456.*",
+		"keychain-access-groups":                           []interface{}{"ABCD123456.*", "ABCD123456.io.bitrise.shared"},
+		"com.apple.developer.associated-domains":           "*",
+		"com.apple.developer.icloud-container-identifiers": []interface{}{},
+		"com.apple.developer.ubiquity-kvstore-identifier":  "ABCD123456.*",
+		"com.apple.developer.team-identifier":              "ABCD123456",
+	}
+	require.Equal(t, plistutil.PlistData{
+		"application-identifier":                           "ABCD123456.io.bitrise.app",
+		"keychain-access-groups":                           []interface{}{"ABCD123456.io.bitrise.shared"},
+		"com.apple.developer.icloud-container-identifiers": []interface{}{},
+		"com.apple.developer.team-identifier":              "ABCD123456",
+	}, resignEntitlements(wildcards, "ABCD123456", "io.bitrise.app"))
+
+	explicit := plistutil.PlistData{"application-identifier": "ABCD123456.io.bitrise.app"}
+	require.Equal(t, explicit, resignEntitlements(explicit, "ABCD123456", "io.bitrise.app"))
+}
+
+func TestSelectResignProfile(t *testing.T) {
+	now := time.Now()
+	identity := certificateutil.CertificateInfoModel{SHA1Fingerprint: "aa", PrivateKey: "key"}
+	other := certificateutil.CertificateInfoModel{SHA1Fingerprint: "bb"}
+	exact := profileutil.ProvisioningProfileInfoModel{UUID: "exact", BundleID: "io.bitrise.app", ExpirationDate: now.AddDate(0, 1, 0), DeveloperCertificates: []certificateutil.CertificateInfoModel{other}}
+	wildcard := profileutil.ProvisioningProfileInfoModel{UUID: "wildcard", BundleID: "io.bitrise.*", ExpirationDate: now.AddDate(0, 1, 0), DeveloperCertificates: []certificateutil.CertificateInfoModel{identity}}
+
+	certificate, profile, err := selectResignProfile("io.bitrise.app", []certificateutil.CertificateInfoModel{identity}, []profileutil.ProvisioningProfileInfoModel{exact, wildcard}, now)
+	require.NoError(t, err)
+	require.Equal(t, "aa", certificate.SHA1Fingerprint)
+	require.Equal(t, "wildcard", profile.UUID)
+
+	_, _, err = selectResignProfile("io.bitrise.app", []certificateutil.CertificateInfoModel{identity}, []profileutil.ProvisioningProfileInfoModel{exact}, now)
+	require.Error(t, err)
+
+	_, _, err = selectResignProfile("com.other.app", []certificateutil.CertificateInfoModel{identity}, []profileutil.ProvisioningProfileInfoModel{wildcard}, now)
+	require.Error(t, err)
+}
@@ -0,0 +1,27 @@
+package codesign
+
+import (
+	"fmt"
+
+	"github.com/bitrise-io/codesigndoc/exitcode"
+	"github.com/bitrise-io/codesigndoc/models"
+	"github.com/bitrise-io/codesigndoc/secretstore"
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// storeIdentitiesSecret returns an IdentitiesExported hook writing the .p12 and its passphrase into the secret store, then calling next
+func storeIdentitiesSecret(store secretstore.Store, passphrase string, next func(models.Certificates) error) func(models.Certificates) error {
+	return func(certificates models.Certificates) error {
+		fmt.Println()
+		log.Infof("Writing the identities into the secret: %s", store.Location())
+		if err := store.Write(secretstore.P12Fields(certificates.Content, passphrase)); err != nil {
+			return exitcode.Wrap(exitcode.UploadFailed, fmt.Errorf("failed to write the identities into the secret store, error: %s", err))
+		}
+		log.Donef("The .p12 (%s) and its password (%s) are stored", secretstore.P12Field, secretstore.P12PasswordField)
+
+		if next != nil {
+			return next(certificates)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,180 @@
+package codesign
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/exitcode"
+	"github.com/bitrise-io/codesigndoc/keychain"
+	"github.com/bitrise-io/codesigndoc/models"
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/bitrise-io/codesigndoc/state"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+)
+
+// IdentitySelectionVersion is the version of the identity selection file format
+const IdentitySelectionVersion = 1
+
+// IdentitySelection lists the identities found by a dry run with their keychain persistent references,
+// so they can be exported in a later run (e.g. after an approval) without searching them again
+type IdentitySelection struct {
+	Version    int                `json:"version"`
+	CreatedAt  time.Time          `json:"created_at"`
+	Identities []SelectedIdentity `json:"identities"`
+}
+
+// SelectedIdentity is an identity of the selection
+type SelectedIdentity struct {
+	Label           string    `json:"label"`
+	SHA1Fingerprint string    `json:"sha1_fingerprint"`
+	KeychainPath    string    `json:"keychain_path,omitempty"`
+	NotAfter        time.Time `json:"not_after"`
+	// PersistentRef is the opaque keychain handle of the identity, it is only valid on the machine of the selection
+	PersistentRef []byte `json:"persistent_ref"`
+}
+
+// newIdentitySelection creates the selection of the found identities
+func newIdentitySelection(identities []osxkeychain.IdentityWithRefModel) (IdentitySelection, error) {
+	selection := IdentitySelection{Version: IdentitySelectionVersion, CreatedAt: time.Now(), Identities: []SelectedIdentity{}}
+	for _, identity := range identities {
+		persistentRef, err := identity.PersistentRef()
+		if err != nil {
+			return IdentitySelection{}, fmt.Errorf("failed to get the persistent reference of %s, error: %s", identity.Label, err)
+		}
+		selection.Identities = append(selection.Identities, SelectedIdentity{
+			Label:           identity.Label,
+			SHA1Fingerprint: identity.SHA1Fingerprint,
+			KeychainPath:    identity.KeychainPath,
+			NotAfter:        identity.NotAfter,
+			PersistentRef:   persistentRef,
+		})
+	}
+	return selection, nil
+}
+
+// WriteIdentitySelection writes the selection as indented JSON
+func WriteIdentitySelection(selection IdentitySelection, pth string) error {
+	content, err := json.MarshalIndent(selection, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(pth, content, 0600); err != nil {
+		return fmt.Errorf("failed to write identity selection, error: %s", err)
+	}
+	return nil
+}
+
+// ReadIdentitySelection reads a selection written by WriteIdentitySelection
+func ReadIdentitySelection(pth string) (IdentitySelection, error) {
+	content, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return IdentitySelection{}, fmt.Errorf("failed to read identity selection, error: %s", err)
+	}
+
+	var selection IdentitySelection
+	if err := json.Unmarshal(content, &selection); err != nil {
+		return IdentitySelection{}, fmt.Errorf("failed to parse identity selection (%s), error: %s", pth, err)
+	}
+	if selection.Version != IdentitySelectionVersion {
+		return IdentitySelection{}, fmt.Errorf("unsupported identity selection version (%d) in %s, supported: %d", selection.Version, pth, IdentitySelectionVersion)
+	}
+	if len(selection.Identities) == 0 {
+		return IdentitySelection{}, fmt.Errorf("the identity selection (%s) is empty", pth)
+	}
+	return selection, nil
+}
+
+// resolveIdentitySelection finds the identities of the persistent references. An identity replaced since the selection
+// (e.g. deleted and imported again with a different certificate) fails the resolution, as it was not the approved one.
+func resolveIdentitySelection(selection IdentitySelection) ([]osxkeychain.IdentityWithRefModel, error) {
+	var identities []osxkeychain.IdentityWithRefModel
+	for _, selected := range selection.Identities {
+		identity, err := osxkeychain.FindIdentityByPersistentRef(selected.PersistentRef)
+		if errors.Is(err, osxkeychain.ErrPersistentRefNotFound) {
+			osxkeychain.ReleaseIdentityWithRefList(identities)
+			return nil, exitcode.Wrap(exitcode.IdentityNotFound, fmt.Errorf("identity (%s) [%s] of the selection is no longer in %s, create a new selection", selected.Label, selected.SHA1Fingerprint, keychainOf(selected.KeychainPath)))
+		} else if err != nil {
+			osxkeychain.ReleaseIdentityWithRefList(identities)
+			return nil, fmt.Errorf("failed to find identity (%s) of the selection, error: %s", selected.Label, err)
+		}
+		identities = append(identities, identity)
+
+		if !strings.EqualFold(identity.SHA1Fingerprint, selected.SHA1Fingerprint) {
+			osxkeychain.ReleaseIdentityWithRefList(identities)
+			return nil, exitcode.Wrap(exitcode.IdentityNotFound, fmt.Errorf("identity (%s) of the selection changed: its SHA-1 fingerprint is %s instead of %s, create a new selection", selected.Label, identity.SHA1Fingerprint, selected.SHA1Fingerprint))
+		}
+	}
+	return identities, nil
+}
+
+// ExportIdentitySelection exports the identities of the selection merged into a single .p12, and into a separate one each if config.Each is set
+func ExportIdentitySelection(ctx context.Context, selection IdentitySelection, config IdentityExportConfig) (models.Certificates, error) {
+	neverExport, err := state.ReadNeverExportList()
+	if err != nil {
+		return models.Certificates{}, err
+	}
+	for _, selected := range selection.Identities {
+		if neverExport.Contains(selected.SHA1Fingerprint) {
+			return models.Certificates{}, fmt.Errorf("identity (%s) is listed in the never export list of this machine", selected.Label)
+		}
+	}
+
+	resolved, err := resolveIdentitySelection(selection)
+	if err != nil {
+		return models.Certificates{}, err
+	}
+	defer osxkeychain.ReleaseIdentityWithRefList(resolved)
+
+	// the persistent references are resolved in the macOS keychain, so the selection is exported from there too
+	kc := osxkeychain.NewKeychain("")
+	var identities []*keychain.Identity
+	defer func() { _ = keychain.CloseAll(identities) }()
+	var certificates []certificateutil.CertificateInfoModel
+	for _, identity := range resolved {
+		log.Printf("exporting Identity: %s", identity)
+		certificate, err := osxkeychain.GetCertificateDataFromIdentityRef(identity.KeychainRef)
+		if err != nil {
+			return models.Certificates{}, fmt.Errorf("failed to read the certificate of %s, error: %s", identity.Label, err)
+		}
+		certificates = append(certificates, certificateutil.NewCertificateInfo(*certificate, nil))
+		identities = append(identities, osxkeychain.WrapIdentity(identity))
+	}
+
+	fmt.Println()
+	log.Warnf("You'll most likely see popups one for each Identity from Keychain,")
+	log.Warnf("you will have to accept (Allow) those to be able to export the Identities!")
+	fmt.Println()
+
+	merged, err := exportFromKeychain(ctx, kc, identities, keychain.ExportFormatPKCS12, false, config)
+	if err != nil {
+		return models.Certificates{}, keychainExportError(err)
+	}
+	exported := models.Certificates{Info: certificates, Content: merged[0]}
+	if config.Each {
+		if exported.Each, err = exportFromKeychain(ctx, kc, identities, keychain.ExportFormatPKCS12, true, config); err != nil {
+			return models.Certificates{}, keychainExportError(err)
+		}
+	}
+	return exported, nil
+}
+
+// ExportUploadAndWriteIdentitySelection exports the identities of the selection, stores them in the secret store,
+// uploads them to bitrise.io and writes them to the output directory, like ExportUploadAndWriteCodesignFiles
+func ExportUploadAndWriteIdentitySelection(ctx context.Context, selection IdentitySelection, identityConfig IdentityExportConfig, writeFilesConfig WriteFilesConfig, uploadConfig UploadConfig) (ExportReport, error) {
+	certificates, err := ExportIdentitySelection(ctx, selection, identityConfig)
+	if err != nil {
+		return ExportReport{}, err
+	}
+	if uploadConfig.SecretStore != nil {
+		if err := storeIdentitiesSecret(uploadConfig.SecretStore, identityConfig.Passphrase, nil)(certificates); err != nil {
+			return ExportReport{}, err
+		}
+	}
+	return UploadAndWriteCodesignFiles(certificates, nil, writeFilesConfig, uploadConfig)
+}
@@ -0,0 +1,168 @@
+package codesign
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/sliceutil"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// filterTeams drops the certificates and profiles which belong to none of the team IDs (e.g. the identities of another client
+// installed on the same machine), every file is kept if teamIDs is empty. An error is returned if nothing is left to export.
+func filterTeams(certificates []certificateutil.CertificateInfoModel, profiles []profileutil.ProvisioningProfileInfoModel, teamIDs []string) ([]certificateutil.CertificateInfoModel, []profileutil.ProvisioningProfileInfoModel, error) {
+	if len(teamIDs) == 0 {
+		return certificates, profiles, nil
+	}
+
+	var teamCertificates []certificateutil.CertificateInfoModel
+	for _, certificate := range certificates {
+		if !sliceutil.IsStringInSlice(certificate.TeamID, teamIDs) {
+			log.Warnf("Skipping identity %s of team %s, the export is restricted to the team(s): %s", certificate.CommonName, certificate.TeamID, strings.Join(teamIDs, ", "))
+			continue
+		}
+		teamCertificates = append(teamCertificates, certificate)
+	}
+
+	var teamProfiles []profileutil.ProvisioningProfileInfoModel
+	for _, profile := range profiles {
+		if !sliceutil.IsStringInSlice(profile.TeamID, teamIDs) {
+			log.Warnf("Skipping profile %s (%s) of team %s, the export is restricted to the team(s): %s", profile.Name, profile.UUID, profile.TeamID, strings.Join(teamIDs, ", "))
+			continue
+		}
+		teamProfiles = append(teamProfiles, profile)
+	}
+
+	if len(certificates) > 0 && len(teamCertificates) == 0 && len(teamProfiles) == 0 {
+		return nil, nil, fmt.Errorf("none of the required identities and profiles belong to the team(s): %s", strings.Join(teamIDs, ", "))
+	}
+	return teamCertificates, teamProfiles, nil
+}
+
+// noTeamDirName is the output directory of the files without a team ID with WriteFilesConfig.PerTeam
+const noTeamDirName = "no-team"
+
+// TeamDirName returns the output directory of the team's files with WriteFilesConfig.PerTeam, relative to the output directory
+func TeamDirName(teamID string) string {
+	if teamID == "" {
+		return noTeamDirName
+	}
+	return teamID
+}
+
+// teamFiles are the required certificates and profiles of a team
+type teamFiles struct {
+	TeamID       string
+	TeamName     string
+	Certificates []certificateutil.CertificateInfoModel
+	Profiles     []profileutil.ProvisioningProfileInfoModel
+}
+
+func (team teamFiles) label() string {
+	if team.TeamName == "" {
+		return TeamDirName(team.TeamID)
+	}
+	return fmt.Sprintf("%s (%s)", team.TeamName, TeamDirName(team.TeamID))
+}
+
+// groupByTeam groups the certificates and profiles by their team ID, ordered by the team ID
+func groupByTeam(certificates []certificateutil.CertificateInfoModel, profiles []profileutil.ProvisioningProfileInfoModel) []teamFiles {
+	byTeam := map[string]*teamFiles{}
+	team := func(teamID, teamName string) *teamFiles {
+		files, ok := byTeam[teamID]
+		if !ok {
+			files = &teamFiles{TeamID: teamID}
+			byTeam[teamID] = files
+		}
+		if files.TeamName == "" {
+			files.TeamName = teamName
+		}
+		return files
+	}
+	for _, certificate := range certificates {
+		files := team(certificate.TeamID, certificate.TeamName)
+		files.Certificates = append(files.Certificates, certificate)
+	}
+	for _, profile := range profiles {
+		files := team(profile.TeamID, profile.TeamName)
+		files.Profiles = append(files.Profiles, profile)
+	}
+
+	var teams []teamFiles
+	for _, files := range byTeam {
+		teams = append(teams, *files)
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].TeamID < teams[j].TeamID })
+	return teams
+}
+
+// exportUploadAndWritePerTeam exports, uploads and writes the files of every team separately, into a directory of the team
+// in the output directory, so the files of the clients sharing a machine are never mixed in one .p12 or upload
+func exportUploadAndWritePerTeam(ctx context.Context, certificatesRequired []certificateutil.CertificateInfoModel, profilesRequired []profileutil.ProvisioningProfileInfoModel, identityConfig IdentityExportConfig, writeFilesConfig WriteFilesConfig, uploadConfig UploadConfig) (ExportReport, error) {
+	certificatesRequired, profilesRequired, err := filterTeams(certificatesRequired, profilesRequired, identityConfig.TeamIDs)
+	if err != nil {
+		return ExportReport{}, err
+	}
+
+	teams := groupByTeam(certificatesRequired, profilesRequired)
+	merged := ExportReport{CertificatesUploaded: true, ProvisioningProfilesUploaded: true}
+	for i, team := range teams {
+		fmt.Println()
+		log.Infof("Exporting the files of team %s (%d/%d)", team.label(), i+1, len(teams))
+
+		teamWriteFilesConfig := writeFilesConfig
+		teamWriteFilesConfig.PerTeam = false
+		teamWriteFilesConfig.AbsOutputDirPath = filepath.Join(writeFilesConfig.AbsOutputDirPath, TeamDirName(team.TeamID))
+		teamReport, err := ExportUploadAndWriteCodesignFiles(ctx, team.Certificates, team.Profiles, identityConfig, teamWriteFilesConfig, uploadConfig)
+		if err != nil {
+			return ExportReport{}, fmt.Errorf("failed to export the files of team %s: %w", team.label(), err)
+		}
+
+		merged.CertificatesUploaded = merged.CertificatesUploaded && teamReport.CertificatesUploaded
+		merged.ProvisioningProfilesUploaded = merged.ProvisioningProfilesUploaded && teamReport.ProvisioningProfilesUploaded
+		merged.CodesignFilesWritten = merged.CodesignFilesWritten || teamReport.CodesignFilesWritten
+		merged.Certificates = append(merged.Certificates, teamReport.Certificates...)
+		merged.Profiles = append(merged.Profiles, teamReport.Profiles...)
+		merged.Files = append(merged.Files, teamReport.Files...)
+		merged.Teams = append(merged.Teams, TeamExportReport{
+			TeamID:           team.TeamID,
+			TeamName:         team.TeamName,
+			AbsOutputDirPath: teamWriteFilesConfig.AbsOutputDirPath,
+			ExportReport:     teamReport,
+		})
+	}
+	return merged, nil
+}
+
+// planPerTeam plans the export of every team separately, like exportUploadAndWritePerTeam
+func planPerTeam(ctx context.Context, certificatesRequired []certificateutil.CertificateInfoModel, profilesRequired []profileutil.ProvisioningProfileInfoModel, identityConfig IdentityExportConfig, writeFilesConfig WriteFilesConfig, uploadConfig UploadConfig) (ExportPlan, error) {
+	certificatesRequired, profilesRequired, err := filterTeams(certificatesRequired, profilesRequired, identityConfig.TeamIDs)
+	if err != nil {
+		return ExportPlan{}, err
+	}
+
+	merged := ExportPlan{Selection: IdentitySelection{Version: IdentitySelectionVersion, Identities: []SelectedIdentity{}}}
+	for _, team := range groupByTeam(certificatesRequired, profilesRequired) {
+		teamWriteFilesConfig := writeFilesConfig
+		teamWriteFilesConfig.PerTeam = false
+		teamWriteFilesConfig.AbsOutputDirPath = filepath.Join(writeFilesConfig.AbsOutputDirPath, TeamDirName(team.TeamID))
+		plan, err := PlanCodesignFiles(ctx, team.Certificates, team.Profiles, identityConfig, teamWriteFilesConfig, uploadConfig)
+		if err != nil {
+			return ExportPlan{}, fmt.Errorf("failed to plan the export of team %s: %w", team.label(), err)
+		}
+
+		merged.Upload = plan.Upload
+		merged.Certificates = append(merged.Certificates, plan.Certificates...)
+		merged.Profiles = append(merged.Profiles, plan.Profiles...)
+		merged.ProfilePaths = append(merged.ProfilePaths, plan.ProfilePaths...)
+		merged.Files = append(merged.Files, plan.Files...)
+		merged.Selection.CreatedAt = plan.Selection.CreatedAt
+		merged.Selection.Identities = append(merged.Selection.Identities, plan.Selection.Identities...)
+	}
+	return merged, nil
+}
@@ -0,0 +1,44 @@
+package codesign
+
+import (
+	"testing"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterTeams(t *testing.T) {
+	certificates := []certificateutil.CertificateInfoModel{{CommonName: "Apple Distribution: Client A", TeamID: "AAAAAAAAAA"}, {CommonName: "Apple Distribution: Client B", TeamID: "BBBBBBBBBB"}}
+	profiles := []profileutil.ProvisioningProfileInfoModel{{UUID: "a", TeamID: "AAAAAAAAAA"}, {UUID: "b", TeamID: "BBBBBBBBBB"}}
+
+	teamCertificates, teamProfiles, err := filterTeams(certificates, profiles, nil)
+	require.NoError(t, err)
+	require.Equal(t, certificates, teamCertificates)
+	require.Equal(t, profiles, teamProfiles)
+
+	teamCertificates, teamProfiles, err = filterTeams(certificates, profiles, []string{"BBBBBBBBBB"})
+	require.NoError(t, err)
+	require.Equal(t, certificates[1:], teamCertificates)
+	require.Equal(t, profiles[1:], teamProfiles)
+
+	_, _, err = filterTeams(certificates, profiles, []string{"CCCCCCCCCC"})
+	require.Error(t, err)
+}
+
+func TestGroupByTeam(t *testing.T) {
+	certificates := []certificateutil.CertificateInfoModel{{CommonName: "Apple Distribution: Client B", TeamID: "BBBBBBBBBB", TeamName: "Client B"}, {CommonName: "Apple Distribution: Client A", TeamID: "AAAAAAAAAA"}}
+	profiles := []profileutil.ProvisioningProfileInfoModel{{UUID: "a", TeamID: "AAAAAAAAAA", TeamName: "Client A"}, {UUID: "b", TeamID: "BBBBBBBBBB"}, {UUID: "c", TeamID: "AAAAAAAAAA"}}
+
+	teams := groupByTeam(certificates, profiles)
+	require.Len(t, teams, 2)
+	require.Equal(t, "AAAAAAAAAA", teams[0].TeamID)
+	require.Equal(t, "Client A", teams[0].TeamName)
+	require.Equal(t, certificates[1:], teams[0].Certificates)
+	require.Equal(t, []profileutil.ProvisioningProfileInfoModel{profiles[0], profiles[2]}, teams[0].Profiles)
+	require.Equal(t, "BBBBBBBBBB", teams[1].TeamID)
+	require.Equal(t, "Client B", teams[1].TeamName)
+
+	require.Equal(t, "no-team", TeamDirName(""))
+	require.Equal(t, "AAAAAAAAAA", TeamDirName("AAAAAAAAAA"))
+}
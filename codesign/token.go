@@ -0,0 +1,60 @@
+package codesign
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/bitrise-io/codesigndoc/keychain"
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/bitrise-io/codesigndoc/prompt"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+)
+
+// printHardwareBoundGuidance explains why the identities of a hardware token can not be exported and how to use them on CI
+func printHardwareBoundGuidance(notExportable osxkeychain.NotExportableError) {
+	var labels []string
+	for label := range notExportable.HardwareBound {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	fmt.Println()
+	for _, label := range labels {
+		log.Warnf("The private key of %s is stored on the hardware token %s, it can not be exported.", label, notExportable.HardwareBound[label])
+	}
+	log.Printf("The private keys of the smart cards and PIV tokens (e.g. YubiKey) never leave the token, only their certificates can be exported.")
+	log.Printf("To sign on another machine, plug the token in there (with its CryptoTokenKit driver installed),")
+	log.Printf("or create a new, software based identity for the CI machines in the Apple Developer Portal.")
+}
+
+// approveCertificateOnlyExport asks whether the certificates of the hardware-bound identities should be exported without their private keys
+// if ask is set, it is approved without asking by IdentityExportConfig.TokenCertificatesOnly
+func approveCertificateOnlyExport(config IdentityExportConfig, ask bool) (bool, error) {
+	if config.TokenCertificatesOnly {
+		return true, nil
+	}
+	if !ask || prompt.NonInteractive {
+		log.Printf("Export the certificates only with --token-certificates-only.")
+		return false, nil
+	}
+	fmt.Println()
+	return prompt.AskForBoolFromReader("Do you want to export the certificates of these identities only, without the private keys?", os.Stdin)
+}
+
+// splitHardwareBound removes the hardware-bound identities of the error from the identities to export,
+// and returns their certificates separately, for the certificate-only export
+func splitHardwareBound(certificates []certificateutil.CertificateInfoModel, identities []*keychain.Identity, notExportable osxkeychain.NotExportableError) ([]certificateutil.CertificateInfoModel, []*keychain.Identity, []certificateutil.CertificateInfoModel) {
+	var exportableCertificates, certificateOnly []certificateutil.CertificateInfoModel
+	var exportableIdentities []*keychain.Identity
+	for i, certificate := range certificates {
+		if _, ok := notExportable.HardwareBound[certificate.CommonName]; ok {
+			certificateOnly = append(certificateOnly, certificate)
+			continue
+		}
+		exportableCertificates = append(exportableCertificates, certificate)
+		exportableIdentities = append(exportableIdentities, identities[i])
+	}
+	return exportableCertificates, exportableIdentities, certificateOnly
+}
@@ -0,0 +1,188 @@
+package codesign
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// Signature is the code signature of a built .app bundle
+type Signature struct {
+	// Identifier is the signing identifier, the bundle ID of the app
+	Identifier     string
+	TeamIdentifier string
+	// Authorities are the common names of the signing certificate chain, the signing certificate first
+	Authorities []string
+	// Certificate is the signing certificate, nil if the bundle is ad-hoc signed
+	Certificate *certificateutil.CertificateInfoModel
+	// Profile is the embedded provisioning profile, nil if the bundle has none (e.g. a Developer ID signed macOS app)
+	Profile *profileutil.ProvisioningProfileInfoModel
+}
+
+// ReadSignature reads the code signature and the embedded provisioning profile of an .app bundle,
+// or of the app of an .ipa, with codesign -dvvv
+func ReadSignature(pth string) (Signature, error) {
+	tmpDir, err := ioutil.TempDir("", "codesigndoc-verify")
+	if err != nil {
+		return Signature{}, err
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			log.Warnf("Failed to remove temporary directory (%s), error: %s", tmpDir, err)
+		}
+	}()
+
+	if filepath.Ext(pth) == ".ipa" {
+		if pth, err = extractIPAApp(pth, filepath.Join(tmpDir, "ipa")); err != nil {
+			return Signature{}, err
+		}
+	}
+	if ext := filepath.Ext(pth); ext != ".app" && ext != ".appex" {
+		return Signature{}, fmt.Errorf("%s is neither an .ipa nor an .app or .appex bundle", pth)
+	}
+
+	out, err := command.New("codesign", "-dvvv", pth).RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		return Signature{}, fmt.Errorf("failed to read the signature of %s, output: %s, error: %s", pth, out, err)
+	}
+	signature := parseCodesignDisplay(out)
+
+	// codesign writes the certificates of the signature as codesign0 (the signing certificate), codesign1, ...
+	prefix := filepath.Join(tmpDir, "codesign")
+	if out, err := command.New("codesign", "-d", "--extract-certificates="+prefix, pth).RunAndReturnTrimmedCombinedOutput(); err != nil {
+		return Signature{}, fmt.Errorf("failed to extract the signing certificate of %s, output: %s, error: %s", pth, out, err)
+	}
+	if content, err := ioutil.ReadFile(prefix + "0"); err == nil {
+		certificate, err := x509.ParseCertificate(content)
+		if err != nil {
+			return Signature{}, fmt.Errorf("failed to parse the signing certificate of %s, error: %s", pth, err)
+		}
+		info := certificateutil.NewCertificateInfo(*certificate, nil)
+		signature.Certificate = &info
+	} else if !os.IsNotExist(err) {
+		return Signature{}, err
+	}
+
+	for _, profilePth := range []string{filepath.Join(pth, "embedded.mobileprovision"), filepath.Join(pth, "Contents", "embedded.provisionprofile")} {
+		if exist, err := pathutil.IsPathExists(profilePth); err != nil {
+			return Signature{}, err
+		} else if !exist {
+			continue
+		}
+		profile, err := profileutil.NewProvisioningProfileInfoFromFile(profilePth)
+		if err != nil {
+			return Signature{}, fmt.Errorf("failed to read the embedded provisioning profile of %s, error: %s", pth, err)
+		}
+		signature.Profile = &profile
+		break
+	}
+	return signature, nil
+}
+
+// extractIPAApp unzips the .ipa into the directory and returns the path of its app
+func extractIPAApp(ipaPth, dir string) (string, error) {
+	if out, err := command.New("unzip", "-q", "-o", ipaPth, "-d", dir).RunAndReturnTrimmedCombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to unzip %s, output: %s, error: %s", ipaPth, out, err)
+	}
+	apps, err := filepath.Glob(filepath.Join(dir, "Payload", "*.app"))
+	if err != nil {
+		return "", err
+	}
+	if len(apps) != 1 {
+		return "", fmt.Errorf("%s contains %d apps in its Payload directory instead of 1", ipaPth, len(apps))
+	}
+	return apps[0], nil
+}
+
+// parseCodesignDisplay reads the signature details from the output of codesign -dvvv
+func parseCodesignDisplay(out string) Signature {
+	var signature Signature
+	for _, line := range strings.Split(out, "\n") {
+		i := strings.Index(line, "=")
+		if i < 0 {
+			continue
+		}
+		key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		switch key {
+		case "Identifier":
+			signature.Identifier = value
+		case "TeamIdentifier":
+			if value != "not set" {
+				signature.TeamIdentifier = value
+			}
+		case "Authority":
+			signature.Authorities = append(signature.Authorities, value)
+		}
+	}
+	return signature
+}
+
+// ResignCheck tells whether a set of code signing files can re-sign a bundle with its original identity
+type ResignCheck struct {
+	// Identity is the identity of the signing certificate, nil if it is missing
+	Identity *certificateutil.CertificateInfoModel
+	// Profile is the profile containing the signing certificate and provisioning the bundle ID, nil if it is missing
+	Profile *profileutil.ProvisioningProfileInfoModel
+	// Problems explain why the bundle can not be re-signed
+	Problems []string
+}
+
+// CheckResign checks if the certificates (with their private keys) and profiles contain the exact identity the bundle is signed with
+// and a valid profile of the bundle ID with that certificate, the embedded profile is preferred.
+func CheckResign(signature Signature, certificates []certificateutil.CertificateInfoModel, profiles []profileutil.ProvisioningProfileInfoModel, now time.Time) ResignCheck {
+	var check ResignCheck
+	if signature.Certificate == nil {
+		check.Problems = append(check.Problems, "the bundle is ad-hoc signed, it has no signing identity")
+		return check
+	}
+
+	var others []string
+	for i, certificate := range certificates {
+		if certificate.SHA1Fingerprint == signature.Certificate.SHA1Fingerprint && certificate.PrivateKey != nil {
+			check.Identity = &certificates[i]
+			break
+		}
+		if certificate.CommonName == signature.Certificate.CommonName {
+			others = append(others, certificate.SHA1Fingerprint)
+		}
+	}
+	if check.Identity == nil {
+		problem := fmt.Sprintf("the identity of the signing certificate (%s) [%s] is missing", signature.Certificate.CommonName, signature.Certificate.SHA1Fingerprint)
+		if len(others) > 0 {
+			problem += fmt.Sprintf(", found the same name with other certificates (e.g. renewed ones): %s", strings.Join(others, ", "))
+		}
+		check.Problems = append(check.Problems, problem)
+	}
+
+	var candidates []profileutil.ProvisioningProfileInfoModel
+	for _, profile := range profiles {
+		if _, ok := MatchBundleID(profile.BundleID, signature.Identifier); !ok || !profile.ExpirationDate.After(now) {
+			continue
+		}
+		for _, certificate := range profile.DeveloperCertificates {
+			if certificate.SHA1Fingerprint == signature.Certificate.SHA1Fingerprint {
+				candidates = append(candidates, profile)
+				break
+			}
+		}
+	}
+	for i, candidate := range candidates {
+		if check.Profile == nil || signature.Profile != nil && candidate.UUID == signature.Profile.UUID {
+			check.Profile = &candidates[i]
+		}
+	}
+	if check.Profile == nil && signature.Profile != nil {
+		check.Problems = append(check.Problems, fmt.Sprintf("no valid provisioning profile of %s contains the signing certificate, e.g. the embedded %s (%s)", signature.Identifier, signature.Profile.Name, signature.Profile.UUID))
+	}
+	return check
+}
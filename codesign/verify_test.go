@@ -0,0 +1,57 @@
+package codesign
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCodesignDisplay(t *testing.T) {
+	out := `Executable=/tmp/Payload/App.app/App
+Identifier=io.bitrise.app
+Format=app bundle with Mach-O thin (arm64)
+Authority=Apple Distribution: Bitrise Ltd. (ABCD123456)
+Authority=Apple Worldwide Developer Relations Certification Authority
+Authority=Apple Root CA
+TeamIdentifier=ABCD123456`
+
+	signature := parseCodesignDisplay(out)
+	require.Equal(t, "io.bitrise.app", signature.Identifier)
+	require.Equal(t, "ABCD123456", signature.TeamIdentifier)
+	require.Equal(t, []string{"Apple Distribution: Bitrise Ltd. (ABCD123456)", "Apple Worldwide Developer Relations Certification Authority", "Apple Root CA"}, signature.Authorities)
+
+	require.Equal(t, "", parseCodesignDisplay("Identifier=App\nSignature=adhoc\nTeamIdentifier=not set").TeamIdentifier)
+}
+
+func TestCheckResign(t *testing.T) {
+	now := time.Now()
+	signing := certificateutil.CertificateInfoModel{CommonName: "Apple Distribution: Bitrise Ltd. (ABCD123456)", SHA1Fingerprint: "aa"}
+	renewed := certificateutil.CertificateInfoModel{CommonName: signing.CommonName, SHA1Fingerprint: "bb", PrivateKey: "key"}
+	embedded := profileutil.ProvisioningProfileInfoModel{Name: "App Store", UUID: "uuid-1", BundleID: "io.bitrise.app", ExpirationDate: now.AddDate(0, 1, 0), DeveloperCertificates: []certificateutil.CertificateInfoModel{signing}}
+	wildcard := profileutil.ProvisioningProfileInfoModel{Name: "Wildcard", UUID: "uuid-2", BundleID: "io.bitrise.*", ExpirationDate: now.AddDate(0, 1, 0), DeveloperCertificates: []certificateutil.CertificateInfoModel{signing}}
+	signature := Signature{Identifier: "io.bitrise.app", Certificate: &signing, Profile: &embedded}
+
+	exported := signing
+	exported.PrivateKey = "key"
+	check := CheckResign(signature, []certificateutil.CertificateInfoModel{renewed, exported}, []profileutil.ProvisioningProfileInfoModel{wildcard, embedded}, now)
+	require.Empty(t, check.Problems)
+	require.Equal(t, "aa", check.Identity.SHA1Fingerprint)
+	require.Equal(t, "uuid-1", check.Profile.UUID)
+
+	check = CheckResign(signature, []certificateutil.CertificateInfoModel{renewed}, []profileutil.ProvisioningProfileInfoModel{wildcard}, now)
+	require.Nil(t, check.Identity)
+	require.Equal(t, "uuid-2", check.Profile.UUID)
+	require.Len(t, check.Problems, 1)
+	require.Contains(t, check.Problems[0], "bb")
+
+	expired := embedded
+	expired.ExpirationDate = now.AddDate(0, -1, 0)
+	check = CheckResign(signature, []certificateutil.CertificateInfoModel{exported}, []profileutil.ProvisioningProfileInfoModel{expired}, now)
+	require.Nil(t, check.Profile)
+	require.Len(t, check.Problems, 1)
+
+	require.Len(t, CheckResign(Signature{Identifier: "io.bitrise.app"}, nil, nil, now).Problems, 1)
+}
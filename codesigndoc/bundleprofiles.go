@@ -0,0 +1,88 @@
+package codesigndoc
+
+import (
+	"fmt"
+
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/prompt"
+	"github.com/bitrise-io/codesigndoc/utility"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/bitrise-io/go-xcode/xcarchive"
+)
+
+// InstallBundleProfiles offers to install the provisioning profiles of a previously collected export bundle,
+// which provision the archive's bundle IDs but are not installed locally, so the scan can use them.
+func InstallBundleProfiles(archivePath, bundleDir string) error {
+	isMacOs, err := xcarchive.IsMacOS(archivePath)
+	if err != nil {
+		return err
+	}
+
+	var archive Archive
+	profileType := profileutil.ProfileTypeIos
+	if isMacOs {
+		profileType = profileutil.ProfileTypeMacOs
+		if archive, err = xcarchive.NewMacosArchive(archivePath); err != nil {
+			return err
+		}
+	} else {
+		if archive, err = newIOSArchive(archivePath); err != nil {
+			return err
+		}
+	}
+
+	bundleProfiles, err := codesign.ReadBundleProfiles(bundleDir, profileType)
+	if err != nil {
+		return err
+	}
+	if len(bundleProfiles) == 0 {
+		return nil
+	}
+
+	installedProfiles, err := utility.InstalledProfileInfos(profileType)
+	if err != nil {
+		return fmt.Errorf("failed to list installed provisioning profiles, error: %s", err)
+	}
+
+	var bundleIDs []string
+	for bundleID := range archive.BundleIDEntitlementsMap() {
+		bundleIDs = append(bundleIDs, bundleID)
+	}
+	teamID := ""
+	for _, profile := range archive.BundleIDProfileInfoMap() {
+		teamID = profile.TeamID
+		break
+	}
+
+	missing := codesign.NotInstalledBundleProfiles(bundleProfiles, installedProfiles, bundleIDs, teamID)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	fmt.Println()
+	log.Warnf("The export bundle (%s) contains provisioning profiles of the archive, which are not installed:", bundleDir)
+	for _, profile := range missing {
+		log.Printf("- %s (%s, UUID: %s)", profile.Info.Name, profile.Info.ExportType, profile.Info.UUID)
+	}
+	if prompt.NonInteractive {
+		log.Printf("Install them manually, or rerun the scan interactively to install them.")
+		return nil
+	}
+	install, err := prompt.AskForBoolWithDefault("Do you want to install them, so the scan can use them?", true)
+	if err != nil {
+		return err
+	}
+	if !install {
+		return nil
+	}
+
+	for _, profile := range missing {
+		pth, err := codesign.InstallProfile(profile)
+		if err != nil {
+			return err
+		}
+		log.Donef("Installed: %s", pth)
+	}
+	return nil
+}
@@ -3,8 +3,11 @@ package codesigndoc
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/utility"
 	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-io/go-xcode/certificateutil"
 	"github.com/bitrise-io/go-xcode/export"
@@ -46,6 +49,9 @@ func CollectCodesignFiles(archivePath string, certificatesOnly bool) ([]certific
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to list installed code signing identities, error: %s", err)
 	}
+	if installerCertificates, err = codesign.FilterNeverExportCertificates(installerCertificates); err != nil {
+		return nil, nil, err
+	}
 
 	log.Debugf("Installed certificates:")
 	for _, installedCertificate := range certificates {
@@ -53,7 +59,7 @@ func CollectCodesignFiles(archivePath string, certificatesOnly bool) ([]certific
 	}
 
 	// Profiles
-	profiles, err := profileutil.InstalledProvisioningProfileInfos(profileType)
+	profiles, err := utility.InstalledProfileInfos(profileType)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to list installed provisioning profiles, error: %s", err)
 	}
@@ -94,6 +100,8 @@ func getFilesToExport(archivePath string, installedCertificates []certificateuti
 		certificate = achiveCodeSignGroup.Certificate()
 	}
 
+	printArchiveCodeSigning(archive)
+
 	certificatesToExport := []certificateutil.CertificateInfoModel{}
 	profilesToExport := []profileutil.ProvisioningProfileInfoModel{}
 
@@ -110,11 +118,46 @@ func getFilesToExport(archivePath string, installedCertificates []certificateuti
 		if err != nil {
 			return nil, nil, err
 		}
+
+		for bundleID, profile := range achiveCodeSignGroup.BundleIDProfileMap() {
+			kind, _ := codesign.MatchBundleID(profile.BundleID, bundleID)
+			codesign.RecordProfileSelection(codesign.ProfileMatch{BundleID: bundleID, Profile: profile, Kind: kind, Rank: 1, Candidates: 1, Reason: "the archive was signed with this profile"})
+		}
+
+		if ios, ok := archive.(iosArchive); ok {
+			if missing := unprovisionedBundleIDs(ios.targets, profilesToExport); len(missing) > 0 {
+				return nil, nil, fmt.Errorf("no provisioning profile to export for the embedded targets: %s", strings.Join(missing, ", "))
+			}
+		}
 	}
 
 	return certificatesToExport, profilesToExport, nil
 }
 
+// printArchiveCodeSigning lists the signing identity and the embedded profiles of the archive,
+// these are the exact code signing files used by the build, regardless of the project's build settings.
+func printArchiveCodeSigning(archive Archive) {
+	fmt.Println()
+	log.Infof("Archive code signing (Xcode managed: %v)", archive.IsXcodeManaged())
+	log.Printf("signing identity: %s", archive.SigningIdentity())
+
+	profiles := archive.BundleIDProfileInfoMap()
+	var bundleIDs []string
+	for bundleID := range profiles {
+		bundleIDs = append(bundleIDs, bundleID)
+	}
+	sort.Strings(bundleIDs)
+	for _, bundleID := range bundleIDs {
+		profile := profiles[bundleID]
+		log.Printf("- %s: %s (%s), team: %s", bundleID, profile.Name, profile.UUID, profile.TeamID)
+	}
+	if archive.IsXcodeManaged() {
+		fmt.Println()
+		log.Warnf("The archive is signed with Xcode managed profiles")
+		log.Printf(xcodeManagedSigningInfo)
+	}
+}
+
 func collectCertificatesAndProfiles(archive Archive, certificate certificateutil.CertificateInfoModel,
 	installedCertificates []certificateutil.CertificateInfoModel, installedProfiles []profileutil.ProvisioningProfileInfoModel,
 	certificatesToExport []certificateutil.CertificateInfoModel, profilesToExport []profileutil.ProvisioningProfileInfoModel,
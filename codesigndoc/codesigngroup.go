@@ -7,14 +7,16 @@ import (
 	"strings"
 
 	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/prompt"
 	"github.com/bitrise-io/go-utils/colorstring"
 	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/sliceutil"
 	"github.com/bitrise-io/go-xcode/certificateutil"
 	"github.com/bitrise-io/go-xcode/export"
 	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/plistutil"
 	"github.com/bitrise-io/go-xcode/profileutil"
 	"github.com/bitrise-io/go-xcode/xcarchive"
-	"github.com/bitrise-io/goinp/goinp"
 )
 
 // extractCertificatesAndProfiles returns the certificates and provisioning profiles of the given codesign group
@@ -90,7 +92,7 @@ func collectExportCertificate(isMacArchive bool, archiveCertificate certificateu
 	// Asking the user over and over until we find a valid certificate for the selected export method.
 	for searchingValidCertificate := true; searchingValidCertificate; {
 		fmt.Println()
-		selectedExportMethod, err := goinp.SelectFromStringsWithDefault("Select the ipa export method", 1, exportMethods)
+		selectedExportMethod, err := prompt.SelectFromStringsWithDefault("Select the ipa export method", 1, exportMethods)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read input: %s", err)
 		}
@@ -104,7 +106,7 @@ func collectExportCertificate(isMacArchive bool, archiveCertificate certificateu
 
 		fmt.Println()
 		question := `Do you want to collect another certificate?`
-		searchingValidCertificate, err = goinp.AskForBoolWithDefault(question, true)
+		searchingValidCertificate, err = prompt.AskForBoolWithDefault(question, true)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read input: %s", err)
 		}
@@ -163,7 +165,7 @@ func filterCertificates(isMacArchive bool, selectedExportMethod, selectedTeam st
 
 			question := fmt.Sprintf(`The archive used codesigning files of team: %s - %s
 Would you like to use this team to export an ipa file?`, archiveCertificate.TeamID, archiveCertificate.TeamName)
-			useArchiveTeam, err = goinp.AskForBoolWithDefault(question, true)
+			useArchiveTeam, err = prompt.AskForBoolWithDefault(question, true)
 			if err != nil {
 				return selectedCertificates, fmt.Errorf("failed to read input: %s", err)
 			}
@@ -191,7 +193,7 @@ Would you like to use this team to export an ipa file?`, archiveCertificate.Team
 			}
 
 			fmt.Println()
-			selectedTeam, err = goinp.SelectFromStringsWithDefault("Select the Development team to export your app", 1, teams)
+			selectedTeam, err = prompt.SelectFromStringsWithDefault("Select the Development team to export your app", 1, teams)
 			if err != nil {
 				return selectedCertificates, fmt.Errorf("failed to read input: %s", err)
 			}
@@ -222,15 +224,15 @@ Would you like to use this team to export an ipa file?`, archiveCertificate.Team
 	}
 
 	fmt.Println()
-	question := fmt.Sprintf("Please select a %s certificate:", certType)
-	selectedCertificateOption, err := goinp.SelectFromStringsWithDefault(question, 1, certificateOptions)
+	question := fmt.Sprintf("Please select the %s certificates to export:", certType)
+	selectedCertificateOptions, err := prompt.MultiSelect(question, certificateOptions)
 	if err != nil {
 		return selectedCertificates, fmt.Errorf("failed to read input: %s", err)
 	}
 
 	for _, certInfo := range filteredTeamCertificates {
 		certificateOption := fmt.Sprintf("%s [%s]", certInfo.CommonName, certInfo.Serial)
-		if certificateOption == selectedCertificateOption {
+		if sliceutil.IsStringInSlice(certificateOption, selectedCertificateOptions) {
 			selectedCertificates = append(selectedCertificates, certInfo)
 		}
 	}
@@ -239,7 +241,7 @@ Would you like to use this team to export an ipa file?`, archiveCertificate.Team
 	if selectedExportMethod == "app-store" && isMacArchive {
 		fmt.Println()
 		question := `Do you want to collect installer certificate for the app-store export? [yes,no]`
-		collectInstallerCert, err := goinp.AskForBoolWithDefault(question, true)
+		collectInstallerCert, err := prompt.AskForBoolWithDefault(question, true)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read input: %s", err)
 		}
@@ -280,7 +282,7 @@ func collectExportCodeSignGroups(archive Archive, installedCertificates []certif
 	}
 
 	for true {
-		selectedExportMethod, err := goinp.SelectFromStringsWithDefault("Select the ipa export method", 1, exportMethods)
+		selectedExportMethod, err := prompt.SelectFromStringsWithDefault("Select the ipa export method", 1, exportMethods)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read input: %s", err)
 		}
@@ -303,7 +305,7 @@ func collectExportCodeSignGroups(archive Archive, installedCertificates []certif
 			fmt.Println()
 			question := "Do you want to collect another ipa export code sign files"
 			question += "\n(select NO to finish collecting codesign files and continue)"
-			anotherExport, err := goinp.AskForBoolWithDefault(question, false)
+			anotherExport, err := prompt.AskForBoolWithDefault(question, false)
 			if err != nil {
 				return nil, fmt.Errorf("failed to read input: %s", err)
 			}
@@ -332,7 +334,7 @@ func collectExportCodeSignGroups(archive Archive, installedCertificates []certif
 			sort.Strings(certificateOptions)
 
 			question := fmt.Sprintf("Select the Codesign Indentity for %s ipa export", selectedExportMethod)
-			selectedCertificateOption, err = goinp.SelectFromStringsWithDefault(question, 1, certificateOptions)
+			selectedCertificateOption, err = prompt.SelectFromStringsWithDefault(question, 1, certificateOptions)
 			if err != nil {
 				return nil, fmt.Errorf("failed to read input: %s", err)
 			}
@@ -365,10 +367,11 @@ func collectExportCodeSignGroups(archive Archive, installedCertificates []certif
 
 		selectedBundleIDProfileMap := map[string]profileutil.ProvisioningProfileInfoModel{}
 		for bundleID, profiles := range bundleIDProfilesMap {
-			profiles = codesign.FilterLatestProfiles(profiles)
+			// the best ranked profile is the default, exact bundle ID matches first
+			matches := codesign.RankProfiles(bundleID, codesign.FilterLatestProfiles(profiles))
 			profileOptions := []string{}
-			for _, profile := range profiles {
-				profileOption := fmt.Sprintf("%s (%s)", profile.Name, profile.UUID)
+			for _, match := range matches {
+				profileOption := fmt.Sprintf("%s (%s) - %s match", match.Profile.Name, match.Profile.UUID, match.Kind)
 				profileOptions = append(profileOptions, profileOption)
 			}
 
@@ -377,21 +380,20 @@ func collectExportCodeSignGroups(archive Archive, installedCertificates []certif
 				selectedProfileOption = profileOptions[0]
 
 				fmt.Printf("Provisioning Profile to sign target (%s): %s\n", bundleID, selectedProfileOption)
-			} else {
-				sort.Strings(profileOptions)
-
+			} else if len(profileOptions) > 1 {
 				fmt.Println()
 				question := fmt.Sprintf("Select the Provisioning Profile to sign target with bundle ID: %s", bundleID)
-				selectedProfileOption, err = goinp.SelectFromStringsWithDefault(question, 1, profileOptions)
+				selectedProfileOption, err = prompt.SelectFromStringsWithDefault(question, 1, profileOptions)
 				if err != nil {
 					return nil, fmt.Errorf("failed to read input: %s", err)
 				}
 			}
 
-			for _, profile := range profiles {
-				option := fmt.Sprintf("%s (%s)", profile.Name, profile.UUID)
-				if option == selectedProfileOption {
-					selectedBundleIDProfileMap[bundleID] = profile
+			for i, match := range matches {
+				if profileOptions[i] == selectedProfileOption {
+					log.Debugf("%s: %s", match.Profile.Name, match.Reason)
+					selectedBundleIDProfileMap[bundleID] = match.Profile
+					codesign.RecordProfileSelection(match)
 				}
 			}
 		}
@@ -411,6 +413,9 @@ func collectExportCodeSignGroups(archive Archive, installedCertificates []certif
 				}
 
 				installedInstallerCertificates = certificateutil.FilterValidCertificateInfos(installedInstallerCertificates).ValidCertificates
+				if installedInstallerCertificates, err = codesign.FilterNeverExportCertificates(installedInstallerCertificates); err != nil {
+					return nil, err
+				}
 
 				log.Debugf("\n")
 				log.Debugf("Installed installer certificates:")
@@ -440,7 +445,7 @@ func collectExportCodeSignGroups(archive Archive, installedCertificates []certif
 		fmt.Println()
 		question := "Do you want to collect another ipa export code sign files"
 		question += "\n(select NO to finish collecting codesign files and continue)"
-		anotherExport, err := goinp.AskForBoolWithDefault(question, false)
+		anotherExport, err := prompt.AskForBoolWithDefault(question, false)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read input: %s", err)
 		}
@@ -478,6 +483,7 @@ func collectExportSelectableCodeSignGroups(archive Archive, installedCertificate
 		return []export.SelectableCodeSignGroup{}
 	}
 
+	printEntitlementMismatches(codeSignGroups, bundleIDEntitlemenstMap)
 	codeSignGroups = export.FilterSelectableCodeSignGroups(codeSignGroups,
 		export.CreateEntitlementsSelectableCodeSignGroupFilter(bundleIDEntitlemenstMap),
 	)
@@ -502,6 +508,38 @@ func collectExportSelectableCodeSignGroups(archive Archive, installedCertificate
 	return codeSignGroups
 }
 
+// printEntitlementMismatches warns about the profiles which do not grant the entitlements of their target,
+// these are not offered for the export, as the signing or the install would fail with them.
+func printEntitlementMismatches(codeSignGroups []export.SelectableCodeSignGroup, bundleIDEntitlementsMap map[string]plistutil.PlistData) {
+	var warnings []string
+	seen := map[string]bool{}
+	for _, group := range codeSignGroups {
+		for bundleID, profiles := range group.BundleIDProfilesMap {
+			for _, profile := range profiles {
+				if seen[bundleID+profile.UUID] {
+					continue
+				}
+				seen[bundleID+profile.UUID] = true
+
+				mismatches := codesign.CompareEntitlements(bundleIDEntitlementsMap[bundleID], profile.Entitlements, profile.Type)
+				for _, mismatch := range mismatches {
+					warnings = append(warnings, fmt.Sprintf("%s - %s (%s): %s", bundleID, profile.Name, profile.UUID, mismatch))
+				}
+			}
+		}
+	}
+	if len(warnings) == 0 {
+		return
+	}
+
+	sort.Strings(warnings)
+	log.Warnf("Profiles not granting the entitlements of their target, these are not offered for the export:")
+	for _, warning := range warnings {
+		log.Warnf("- %s", warning)
+	}
+	fmt.Println()
+}
+
 // hasCertificateForDistType returns true if the provided certificate list has certificate for the selected cert type.
 // If isDistCert == true it will search for Distribution Certificates. If it's == false it will search for Developmenttion Certificates.
 // If the team doesn't have any certificate for the selected cert type, it will return false.
@@ -525,20 +563,20 @@ func hasCertificateForDistType(exportMethod string, certificates []certificateut
 	}
 }
 
-func getIOSCodeSignGroup(archivePath string, installedCertificates []certificateutil.CertificateInfoModel) (xcarchive.IosArchive, *export.IosCodeSignGroup, error) {
-	archive, err := xcarchive.NewIosArchive(archivePath)
+func getIOSCodeSignGroup(archivePath string, installedCertificates []certificateutil.CertificateInfoModel) (iosArchive, *export.IosCodeSignGroup, error) {
+	archive, err := newIOSArchive(archivePath)
 	if err != nil {
-		return xcarchive.IosArchive{}, &export.IosCodeSignGroup{}, fmt.Errorf("failed to analyze archive, error: %s", err)
+		return iosArchive{}, &export.IosCodeSignGroup{}, fmt.Errorf("failed to analyze archive, error: %s", err)
 	}
 
 	codeSignGroup, err := getCodeSignGroup(archive, installedCertificates, false)
 	if err != nil {
-		return xcarchive.IosArchive{}, &export.IosCodeSignGroup{}, fmt.Errorf("failed to analyze archive, error: %s", err)
+		return iosArchive{}, &export.IosCodeSignGroup{}, fmt.Errorf("failed to analyze archive, error: %s", err)
 	}
 
 	archiveCodeSignGroup, ok := codeSignGroup.(*export.IosCodeSignGroup)
 	if !ok {
-		return xcarchive.IosArchive{}, &export.IosCodeSignGroup{}, fmt.Errorf("failed to analyze archive, error: %s", err)
+		return iosArchive{}, &export.IosCodeSignGroup{}, fmt.Errorf("failed to analyze archive, error: %s", err)
 	}
 
 	return archive, archiveCodeSignGroup, nil
@@ -0,0 +1,177 @@
+package codesigndoc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/plistutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/bitrise-io/go-xcode/xcarchive"
+)
+
+// EmbeddedTarget is a signed bundle of the archived app: the app itself, an app extension, a watch app or an App Clip
+type EmbeddedTarget struct {
+	// Path is relative to the archived app
+	Path         string
+	BundleID     string
+	Entitlements plistutil.PlistData
+	// Profile is the embedded profile, its UUID is empty if the bundle has no embedded profile
+	Profile profileutil.ProvisioningProfileInfoModel
+}
+
+// embeddedTargets walks the app for every .app and .appex bundle at any depth,
+// e.g. PlugIns/*.appex, Extensions/*.appex, AppClips/*.app and Watch/*.app with its own extensions.
+func embeddedTargets(appPath string) ([]EmbeddedTarget, error) {
+	var targets []EmbeddedTarget
+	err := filepath.Walk(appPath, func(pth string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if ext := filepath.Ext(pth); ext == ".framework" || ext == ".dSYM" {
+			return filepath.SkipDir
+		} else if ext != ".app" && ext != ".appex" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(appPath, pth)
+		if err != nil {
+			return err
+		}
+		target, err := newEmbeddedTarget(pth, rel)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, target)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk the embedded targets of %s, error: %s", appPath, err)
+	}
+	return targets, nil
+}
+
+func newEmbeddedTarget(pth, rel string) (EmbeddedTarget, error) {
+	infoPlist, err := plistutil.NewPlistDataFromFile(filepath.Join(pth, "Info.plist"))
+	if err != nil {
+		return EmbeddedTarget{}, fmt.Errorf("failed to read the Info.plist of %s, error: %s", rel, err)
+	}
+	bundleID, _ := infoPlist.GetString("CFBundleIdentifier")
+	target := EmbeddedTarget{Path: rel, BundleID: bundleID, Entitlements: plistutil.PlistData{}}
+
+	if entitlementsPth := filepath.Join(pth, "archived-expanded-entitlements.xcent"); fileExists(entitlementsPth) {
+		if target.Entitlements, err = plistutil.NewPlistDataFromFile(entitlementsPth); err != nil {
+			return EmbeddedTarget{}, fmt.Errorf("failed to read the entitlements of %s, error: %s", rel, err)
+		}
+	}
+	if profilePth := filepath.Join(pth, "embedded.mobileprovision"); fileExists(profilePth) {
+		if target.Profile, err = profileutil.NewProvisioningProfileInfoFromFile(profilePth); err != nil {
+			return EmbeddedTarget{}, fmt.Errorf("failed to read the embedded profile of %s, error: %s", rel, err)
+		}
+	}
+	return target, nil
+}
+
+func fileExists(pth string) bool {
+	_, err := os.Stat(pth)
+	return err == nil
+}
+
+// missingEmbeddedProfiles returns the targets without an embedded profile
+func missingEmbeddedProfiles(targets []EmbeddedTarget) []EmbeddedTarget {
+	var missing []EmbeddedTarget
+	for _, target := range targets {
+		if target.Profile.UUID == "" {
+			missing = append(missing, target)
+		}
+	}
+	return missing
+}
+
+// unprovisionedBundleIDs returns the bundle IDs of the targets not covered by any of the profiles
+func unprovisionedBundleIDs(targets []EmbeddedTarget, profiles []profileutil.ProvisioningProfileInfoModel) []string {
+	var bundleIDs []string
+	for _, target := range targets {
+		provisioned := false
+		for _, profile := range profiles {
+			if profile.BundleID == target.BundleID || codesign.IsWildcardBundleIDMatch(profile.BundleID, target.BundleID) {
+				provisioned = true
+				break
+			}
+		}
+		if !provisioned {
+			bundleIDs = append(bundleIDs, target.BundleID)
+		}
+	}
+	sort.Strings(bundleIDs)
+	return bundleIDs
+}
+
+// iosArchive extends the archive with the embedded targets the archive parser does not know about, e.g. the App Clips
+type iosArchive struct {
+	xcarchive.IosArchive
+	targets []EmbeddedTarget
+}
+
+// newIOSArchive parses the archive, the targets without an embedded profile fail with a descriptive error
+func newIOSArchive(archivePath string) (iosArchive, error) {
+	apps, err := filepath.Glob(filepath.Join(archivePath, "Products", "Applications", "*.app"))
+	if err != nil {
+		return iosArchive{}, err
+	}
+	if len(apps) == 0 {
+		return iosArchive{}, fmt.Errorf("no application found in the archive: %s", archivePath)
+	}
+
+	targets, err := embeddedTargets(apps[0])
+	if err != nil {
+		return iosArchive{}, err
+	}
+	if missing := missingEmbeddedProfiles(targets); len(missing) > 0 {
+		var descriptions []string
+		for _, target := range missing {
+			descriptions = append(descriptions, fmt.Sprintf("%s (%s)", target.BundleID, target.Path))
+		}
+		return iosArchive{}, fmt.Errorf("the embedded targets are not signed with a provisioning profile: %s, set up their signing in the project", strings.Join(descriptions, ", "))
+	}
+
+	archive, err := xcarchive.NewIosArchive(archivePath)
+	if err != nil {
+		return iosArchive{}, err
+	}
+
+	log.Debugf("Embedded targets:")
+	for _, target := range targets {
+		log.Debugf("- %s: %s (%s)", target.Path, target.BundleID, target.Profile.Name)
+	}
+	return iosArchive{IosArchive: archive, targets: targets}, nil
+}
+
+// BundleIDEntitlementsMap ...
+func (archive iosArchive) BundleIDEntitlementsMap() map[string]plistutil.PlistData {
+	entitlements := archive.IosArchive.BundleIDEntitlementsMap()
+	for _, target := range archive.targets {
+		if _, ok := entitlements[target.BundleID]; !ok {
+			entitlements[target.BundleID] = target.Entitlements
+		}
+	}
+	return entitlements
+}
+
+// BundleIDProfileInfoMap ...
+func (archive iosArchive) BundleIDProfileInfoMap() map[string]profileutil.ProvisioningProfileInfoModel {
+	profiles := archive.IosArchive.BundleIDProfileInfoMap()
+	for _, target := range archive.targets {
+		if _, ok := profiles[target.BundleID]; !ok {
+			profiles[target.BundleID] = target.Profile
+		}
+	}
+	return profiles
+}
@@ -0,0 +1,189 @@
+package codesigndoc
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/utility"
+	"github.com/bitrise-io/codesigndoc/xcode"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-utils/stringutil"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/bitrise-io/go-xcode/xcarchive"
+)
+
+const xcodeManagedSigningInfo = `Xcode managed signing ("Automatically manage signing"):
+- the profiles (named "XC ..." or "iOS Team Provisioning Profile: ...") are created and renewed by Xcode, they can not be edited in the developer portal
+- Xcode regenerates them when a device, a capability or the certificate changes, so an exported copy gets outdated and the CI build fails with it
+- the archive is signed with a development profile, the distribution profile is only created when exporting the archive
+- on CI either let the build manage signing too (with an App Store Connect API key, e.g. the automatic code signing of the xcode-archive step), or switch the targets to manual signing with profiles created in the developer portal`
+
+// PrintAutomaticSigning explains the implications of the targets using "Automatically manage signing"
+func PrintAutomaticSigning(targets []xcode.TargetCodeSignSettings) {
+	if len(targets) == 0 {
+		return
+	}
+	var names []string
+	for _, target := range targets {
+		names = append(names, target.Target)
+	}
+
+	fmt.Println()
+	log.Warnf("Automatic signing is used by the target(s): %s", strings.Join(names, ", "))
+	log.Printf(xcodeManagedSigningInfo)
+	log.Printf("To collect the managed distribution profile Xcode uses, run the scan with --managed-export <method> (e.g. app-store)")
+}
+
+// ManagedExportFiles exports the archive with Xcode's automatic signing for the export method,
+// and returns the profiles Xcode signed the export with (created by Xcode if needed), with their installed identities.
+func ManagedExportFiles(ctx context.Context, archivePath string, method exportoptions.Method) ([]certificateutil.CertificateInfoModel, []profileutil.ProvisioningProfileInfoModel, error) {
+	if isMacOs, err := xcarchive.IsMacOS(archivePath); err != nil {
+		return nil, nil, err
+	} else if isMacOs {
+		return nil, nil, fmt.Errorf("the managed export is only supported for iOS archives")
+	}
+	archive, err := newIOSArchive(archivePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	options := map[string]interface{}{
+		exportoptions.MethodKey:       string(method),
+		exportoptions.SigningStyleKey: "automatic",
+	}
+	for _, profile := range archive.BundleIDProfileInfoMap() {
+		options[exportoptions.TeamIDKey] = profile.TeamID
+		break
+	}
+	optionsPath, err := exportoptions.WritePlistToTmpFile(options)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to write the export options, error: %s", err)
+	}
+
+	fmt.Println()
+	log.Printf("🔦  Exporting the archive with automatic signing (%s), Xcode may create the managed profiles...", method)
+	exportPath, xcodebuildOutput, err := xcode.ExportArchive(ctx, archivePath, optionsPath)
+	if err != nil {
+		log.Warnf("Last lines of the export log:")
+		fmt.Println(stringutil.LastNLines(xcodebuildOutput, 15))
+		return nil, nil, fmt.Errorf("the automatically signed export failed, sign in to your Apple ID in Xcode (Settings > Accounts), error: %s", err)
+	}
+
+	profiles, err := exportedProfiles(exportPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(profiles) == 0 {
+		return nil, nil, fmt.Errorf("no provisioning profile found in the export: %s", exportPath)
+	}
+
+	installedCertificates, err := codesign.InstalledCertificates(codesign.IOSCertificate)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list installed code signing identities, error: %s", err)
+	}
+
+	var certificates []certificateutil.CertificateInfoModel
+	var installedProfiles []profileutil.ProvisioningProfileInfoModel
+	seen := map[string]bool{}
+	for _, profile := range profiles {
+		// the export reads the profiles from the installed ones, Xcode installs the profiles it creates
+		if _, _, err := utility.FindProfileInfo(profile.UUID); err != nil {
+			log.Warnf("The profile of the export (%s, %s) is not installed, it can not be collected: %s", profile.Name, profile.UUID, err)
+			continue
+		}
+		installedProfiles = append(installedProfiles, profile)
+
+		for _, certificate := range installedCertificates {
+			if seen[certificate.SHA1Fingerprint] {
+				continue
+			}
+			for _, developerCertificate := range profile.DeveloperCertificates {
+				if developerCertificate.SHA1Fingerprint == certificate.SHA1Fingerprint {
+					certificates = append(certificates, certificate)
+					seen[certificate.SHA1Fingerprint] = true
+					break
+				}
+			}
+		}
+	}
+
+	fmt.Println()
+	log.Infof("Profiles of the automatically signed export:")
+	for _, profile := range installedProfiles {
+		log.Printf("- %s: %s (%s), Xcode managed: %v", profile.BundleID, profile.Name, profile.UUID, profile.IsXcodeManaged())
+	}
+	return certificates, installedProfiles, nil
+}
+
+// exportedProfiles reads the embedded profiles of the .ipa files of the export dir, including the ones of the app extensions
+func exportedProfiles(exportPath string) ([]profileutil.ProvisioningProfileInfoModel, error) {
+	ipas, err := filepath.Glob(filepath.Join(exportPath, "*.ipa"))
+	if err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := pathutil.NormalizedOSTempDirPath("__codesigndoc_profiles__")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			log.Warnf("Failed to remove temp dir (%s): %s", tmpDir, err)
+		}
+	}()
+
+	var profiles []profileutil.ProvisioningProfileInfoModel
+	for _, ipa := range ipas {
+		reader, err := zip.OpenReader(ipa)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the exported ipa (%s), error: %s", ipa, err)
+		}
+
+		for i, file := range reader.File {
+			if filepath.Base(file.Name) != "embedded.mobileprovision" {
+				continue
+			}
+			pth := filepath.Join(tmpDir, fmt.Sprintf("%d.mobileprovision", i))
+			if err := extractZipFile(file, pth); err != nil {
+				reader.Close()
+				return nil, fmt.Errorf("failed to extract %s from the ipa, error: %s", file.Name, err)
+			}
+			profile, err := profileutil.NewProvisioningProfileInfoFromFile(pth)
+			if err != nil {
+				reader.Close()
+				return nil, fmt.Errorf("failed to parse %s of the ipa, error: %s", file.Name, err)
+			}
+			profiles = append(profiles, profile)
+		}
+		if err := reader.Close(); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].BundleID < profiles[j].BundleID })
+	return profiles, nil
+}
+
+func extractZipFile(file *zip.File, pth string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	content, err := ioutil.ReadAll(io.LimitReader(src, 10*1024*1024))
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(pth, content, 0600)
+}
@@ -1,10 +1,9 @@
 package codesigndoc
 
 import (
+	"context"
 	"fmt"
 
-	"github.com/bitrise-io/codesigndoc/codesign"
-	"github.com/bitrise-io/codesigndoc/models"
 	"github.com/bitrise-io/codesigndoc/xcode"
 	"github.com/bitrise-io/go-utils/colorstring"
 	"github.com/bitrise-io/go-utils/log"
@@ -13,7 +12,7 @@ import (
 )
 
 // BuildXcodeArchive builds an Xcode archive
-func BuildXcodeArchive(xcodeCmd xcode.CommandModel, handleBuildLog func(string) error) (archivePath string, err error) {
+func BuildXcodeArchive(ctx context.Context, xcodeCmd xcode.CommandModel, handleBuildLog func(string) error) (archivePath string, err error) {
 	// Output tools versions
 	xcodebuildVersion, err := utility.GetXcodeVersion()
 	if err != nil {
@@ -26,7 +25,7 @@ func BuildXcodeArchive(xcodeCmd xcode.CommandModel, handleBuildLog func(string)
 	fmt.Println()
 	log.Printf("🔦  Running an Xcode Archive, to get all the required code signing settings...")
 
-	archivePath, xcodebuildOutput, err := xcodeCmd.GenerateArchive()
+	archivePath, xcodebuildOutput, err := xcodeCmd.GenerateArchive(ctx)
 
 	defer func() {
 		if handleBuildLog != nil {
@@ -55,14 +54,3 @@ func BuildXcodeArchive(xcodeCmd xcode.CommandModel, handleBuildLog func(string)
 
 	return archivePath, nil
 }
-
-// CodesigningFilesForXCodeProject ...
-func CodesigningFilesForXCodeProject(archivePath string, certificatesOnly bool, isAskForPassword bool) (models.Certificates, []models.ProvisioningProfile, error) {
-	// If certificatesOnly is set, CollectCodesignFiles returns an empty slice for profiles
-	certificatesToExport, profilesToExport, err := CollectCodesignFiles(archivePath, certificatesOnly)
-	if err != nil {
-		return models.Certificates{}, nil, err
-	}
-
-	return codesign.ExportCodesigningFiles(certificatesToExport, profilesToExport, isAskForPassword)
-}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/utility"
 	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-io/go-xcode/certificateutil"
 	"github.com/bitrise-io/go-xcode/export"
@@ -36,7 +37,7 @@ func CollectCodesignFiles(buildPath string, certificatesOnly bool) ([]certificat
 	}
 
 	// Profiles
-	profiles, err := profileutil.InstalledProvisioningProfileInfos(profileType)
+	profiles, err := utility.InstalledProfileInfos(profileType)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to list installed provisioning profiles, error: %s", err)
 	}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/prompt"
 	"github.com/bitrise-io/go-utils/colorstring"
 	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-io/go-xcode/certificateutil"
@@ -15,7 +16,6 @@ import (
 	"github.com/bitrise-io/go-xcode/exportoptions"
 	"github.com/bitrise-io/go-xcode/plistutil"
 	"github.com/bitrise-io/go-xcode/profileutil"
-	"github.com/bitrise-io/goinp/goinp"
 )
 
 // extractCertificatesAndProfiles returns the certificates and provisioning profiles of the given codesign group
@@ -76,7 +76,7 @@ func collectExportCertificate(installedCertificates []certificateutil.Certificat
 	// Asking the user over and over until we find a valid certificate for the selected export method.
 	for searchingValidCertificate := true; searchingValidCertificate; {
 		fmt.Println()
-		selectedCodeSignMethod, err := goinp.SelectFromStringsWithDefault("Select the code signing method", 1, codesignMethods)
+		selectedCodeSignMethod, err := prompt.SelectFromStringsWithDefault("Select the code signing method", 1, codesignMethods)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read input: %s", err)
 		}
@@ -92,7 +92,7 @@ func collectExportCertificate(installedCertificates []certificateutil.Certificat
 
 		fmt.Println()
 		question := `Do you want to collect another certificate?`
-		searchingValidCertificate, err = goinp.AskForBoolWithDefault(question, true)
+		searchingValidCertificate, err = prompt.AskForBoolWithDefault(question, true)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read input: %s", err)
 		}
@@ -143,7 +143,7 @@ func selectFilteredCertificates(selectedCodeSignMethod, selectedTeam string, ins
 		}
 
 		fmt.Println()
-		selectedTeam, err = goinp.SelectFromStringsWithDefault("Select the Development team to export your app", 1, teams)
+		selectedTeam, err = prompt.SelectFromStringsWithDefault("Select the Development team to export your app", 1, teams)
 		if err != nil {
 			return selectedCertificates, fmt.Errorf("failed to read input: %s", err)
 		}
@@ -165,7 +165,7 @@ func selectFilteredCertificates(selectedCodeSignMethod, selectedTeam string, ins
 
 	fmt.Println()
 	question := fmt.Sprintf("Please select a %s certificate:", certType)
-	selectedCertificateOption, err := goinp.SelectFromStringsWithDefault(question, 1, certificateOptions)
+	selectedCertificateOption, err := prompt.SelectFromStringsWithDefault(question, 1, certificateOptions)
 	if err != nil {
 		return selectedCertificates, fmt.Errorf("failed to read input: %s", err)
 	}
@@ -195,7 +195,7 @@ func collectExportCodeSignGroups(testRunner IOSTestRunner, installedCertificates
 
 	codeSignMethods := []string{"development", "app-store", "ad-hoc", "enterprise"}
 	for true {
-		selectedCodeSignMethod, err := goinp.SelectFromStringsWithDefault("Select the code signing method", 1, codeSignMethods)
+		selectedCodeSignMethod, err := prompt.SelectFromStringsWithDefault("Select the code signing method", 1, codeSignMethods)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read input: %s", err)
 		}
@@ -218,7 +218,7 @@ func collectExportCodeSignGroups(testRunner IOSTestRunner, installedCertificates
 			fmt.Println()
 			question := fmt.Sprintf("Do you want to collect other  code sign files for (%s)", testRunnerID)
 			question += "\n(select NO to finish collecting codesign files and continue)"
-			anotherExport, err := goinp.AskForBoolWithDefault(question, false)
+			anotherExport, err := prompt.AskForBoolWithDefault(question, false)
 			if err != nil {
 				return nil, fmt.Errorf("failed to read input: %s", err)
 			}
@@ -247,7 +247,7 @@ func collectExportCodeSignGroups(testRunner IOSTestRunner, installedCertificates
 			sort.Strings(certificateOptions)
 
 			question := fmt.Sprintf("Select the Codesign Indentity for %s method", selectedCodeSignMethod)
-			selectedCertificateOption, err = goinp.SelectFromStringsWithDefault(question, 1, certificateOptions)
+			selectedCertificateOption, err = prompt.SelectFromStringsWithDefault(question, 1, certificateOptions)
 			if err != nil {
 				return nil, fmt.Errorf("failed to read input: %s", err)
 			}
@@ -297,7 +297,7 @@ func collectExportCodeSignGroups(testRunner IOSTestRunner, installedCertificates
 
 				fmt.Println()
 				question := fmt.Sprintf("Select the Provisioning Profile to sign target with bundle ID: %s", bundleID)
-				selectedProfileOption, err = goinp.SelectFromStringsWithDefault(question, 1, profileOptions)
+				selectedProfileOption, err = prompt.SelectFromStringsWithDefault(question, 1, profileOptions)
 				if err != nil {
 					return nil, fmt.Errorf("failed to read input: %s", err)
 				}
@@ -327,7 +327,7 @@ func collectExportCodeSignGroups(testRunner IOSTestRunner, installedCertificates
 		fmt.Println()
 		question := fmt.Sprintf("Do you want to collect other code sign files for (%s)", testRunnerID)
 		question += "\n(select NO to finish collecting codesign files and continue)"
-		anotherExport, err := goinp.AskForBoolWithDefault(question, false)
+		anotherExport, err := prompt.AskForBoolWithDefault(question, false)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read input: %s", err)
 		}
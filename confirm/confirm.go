@@ -0,0 +1,116 @@
+package confirm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/prompt"
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/bitrise-io/go-utils/log"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Answer has to be typed to approve a plan, a single "y" is not accepted
+const Answer = "yes"
+
+// MinReviewTime is the time the summary has to be on the screen before an answer is accepted,
+// faster answers were typed ahead (or mashed) without reading the summary.
+const MinReviewTime = time.Second
+
+// AssumeYes approves every plan without asking, set by the --yes flag
+var AssumeYes bool
+
+// Action is a destructive operation of a plan
+type Action struct {
+	// Verb is the operation, e.g.: delete, overwrite, move aside
+	Verb   string
+	Target string
+	Detail string
+}
+
+// Plan collects the destructive actions of a command, which are approved with a single confirmation
+type Plan struct {
+	Title   string
+	Actions []Action
+}
+
+// NewPlan ...
+func NewPlan(title string) *Plan {
+	return &Plan{Title: title}
+}
+
+// Add appends an action to the plan
+func (plan *Plan) Add(verb, target, detail string) {
+	plan.Actions = append(plan.Actions, Action{Verb: verb, Target: target, Detail: detail})
+}
+
+// Summary lists the actions grouped by their verb, in the order of their first occurrence
+func (plan Plan) Summary() string {
+	var verbs []string
+	byVerb := map[string][]Action{}
+	for _, action := range plan.Actions {
+		if _, ok := byVerb[action.Verb]; !ok {
+			verbs = append(verbs, action.Verb)
+		}
+		byVerb[action.Verb] = append(byVerb[action.Verb], action)
+	}
+
+	lines := []string{fmt.Sprintf("%s (%d action(s)):", plan.Title, len(plan.Actions))}
+	for _, verb := range verbs {
+		lines = append(lines, fmt.Sprintf("%s %d item(s):", strings.ToUpper(verb[:1])+verb[1:], len(byVerb[verb])))
+		for _, action := range byVerb[verb] {
+			line := "- " + action.Target
+			if action.Detail != "" {
+				line += " (" + action.Detail + ")"
+			}
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Confirm prints the summary of the plan and asks for a single approval on the standard input.
+// An empty plan is approved without asking, a non interactive session requires AssumeYes.
+func (plan Plan) Confirm() (bool, error) {
+	if len(plan.Actions) == 0 {
+		return true, nil
+	}
+
+	fmt.Println()
+	log.Warnf("%s", plan.Summary())
+	if AssumeYes {
+		log.Printf("Approved by the --yes flag.")
+		return true, nil
+	}
+	if prompt.NonInteractive || !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return false, fmt.Errorf("the destructive actions have to be approved interactively, or with the --yes flag")
+	}
+	return ask(os.Stdin, os.Stdout, time.Now)
+}
+
+// ask reads the answer, answers typed before MinReviewTime passed are discarded and asked again
+func ask(input io.Reader, output io.Writer, now func() time.Time) (bool, error) {
+	reader := bufio.NewReader(input)
+	for {
+		asked := now()
+		if _, err := fmt.Fprintf(output, "Type %s to apply all the above actions, anything else cancels: ", colorstring.Yellow(Answer)); err != nil {
+			return false, err
+		}
+
+		answer, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || answer == "") {
+			return false, fmt.Errorf("failed to read input: %s", err)
+		}
+		if now().Sub(asked) < MinReviewTime {
+			if _, err := fmt.Fprintln(output, "Answered too fast, please review the summary above."); err != nil {
+				return false, err
+			}
+			continue
+		}
+		return strings.TrimSpace(answer) == Answer, nil
+	}
+}
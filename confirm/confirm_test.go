@@ -0,0 +1,54 @@
+package confirm
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummary(t *testing.T) {
+	plan := NewPlan("Repair the state directory")
+	plan.Add("move aside", "config.yml", "corrupt")
+	plan.Add("delete", ".config.yml.tmp1", "")
+	plan.Add("move aside", "never-export.yml", "corrupt")
+
+	require.Equal(t, `Repair the state directory (3 action(s)):
+Move aside 2 item(s):
+- config.yml (corrupt)
+- never-export.yml (corrupt)
+Delete 1 item(s):
+- .config.yml.tmp1`, plan.Summary())
+}
+
+func TestAsk(t *testing.T) {
+	// every call of the clock advances it by the given steps
+	clock := func(steps ...time.Duration) func() time.Time {
+		current := time.Now()
+		return func() time.Time {
+			if len(steps) > 0 {
+				current = current.Add(steps[0])
+				steps = steps[1:]
+			}
+			return current
+		}
+	}
+
+	approved, err := ask(strings.NewReader("yes\n"), ioutil.Discard, clock(0, 2*time.Second))
+	require.NoError(t, err)
+	require.True(t, approved)
+
+	approved, err = ask(strings.NewReader("y\n"), ioutil.Discard, clock(0, 2*time.Second))
+	require.NoError(t, err)
+	require.False(t, approved)
+
+	// the typed ahead answer is discarded
+	approved, err = ask(strings.NewReader("yes\nno\n"), ioutil.Discard, clock(0, 0, 0, 2*time.Second))
+	require.NoError(t, err)
+	require.False(t, approved)
+
+	_, err = ask(strings.NewReader(""), ioutil.Discard, clock())
+	require.Error(t, err)
+}
@@ -0,0 +1,202 @@
+package diagnostics
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/logging"
+)
+
+// File names in the diagnostics archive
+const (
+	InfoFileName = "diagnostics.json"
+	LogFileName  = "codesigndoc.log"
+)
+
+// KeychainStats are the anonymized counts of the keychain setup, no name, path or fingerprint is included
+type KeychainStats struct {
+	SearchList int `json:"search_list"`
+	Legacy     int `json:"legacy"`
+	Identities int `json:"identities"`
+	Profiles   int `json:"profiles"`
+}
+
+// Info describes the environment and the failure of a run
+type Info struct {
+	GeneratedAt  time.Time `json:"generated_at"`
+	ToolVersion  string    `json:"tool_version"`
+	GoVersion    string    `json:"go_version"`
+	OS           string    `json:"os"`
+	Arch         string    `json:"arch"`
+	MacOSVersion string    `json:"macos_version,omitempty"`
+	XcodeVersion string    `json:"xcode_version,omitempty"`
+	// Command is the command with the flag names only, the flag values and the arguments are dropped
+	Command       []string      `json:"command"`
+	Error         string        `json:"error,omitempty"`
+	OSStatusCodes []int         `json:"osstatus_codes,omitempty"`
+	Keychains     KeychainStats `json:"keychains"`
+}
+
+var (
+	osStatusPattern = regexp.MustCompile(`OSStatus:? (-?\d+)`)
+	// fingerprintPattern matches the hex SHA-1 and SHA-256 fingerprints of the certificates
+	fingerprintPattern = regexp.MustCompile(`\b([0-9A-Fa-f]{64}|[0-9A-Fa-f]{40})\b`)
+	// uuidPattern matches the provisioning profile UUIDs
+	uuidPattern = regexp.MustCompile(`\b[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}\b`)
+	// commonNamePattern matches the common names of the code signing certificates, e.g. Apple Development: Jane Doe (ABCD123456),
+	// the certificate type is kept
+	commonNamePattern = regexp.MustCompile(`\b((?:iPhone|Apple|Mac|Developer ID|3rd Party Mac Developer)(?: [A-Za-z]+)*): [^\n()\[\]"]+? \([A-Z0-9]{10}\)`)
+	// teamIDPattern matches the 10 character Apple Developer Team IDs, see isTeamID
+	teamIDPattern = regexp.MustCompile(`\b[A-Z0-9]{10}\b`)
+	// bundleIDPattern matches the reverse DNS bundle IDs (with an optional wildcard), see isBundleID
+	bundleIDPattern = regexp.MustCompile(`\b[a-z][a-z0-9-]*(?:\.[A-Za-z][A-Za-z0-9-]*)+\.(?:[A-Za-z][A-Za-z0-9-]*|\*)`)
+	// projectPathPattern matches the absolute and home relative paths of the Xcode and Xamarin projects
+	projectPathPattern = regexp.MustCompile(`(^|[\s'"(=\[])((?:~|/)[^\s'"()\[\],;]*\.(?:xcodeproj|xcworkspace|xcarchive|sln|csproj))\b`)
+	// absolutePathPattern matches the absolute paths, the URLs are not matched
+	absolutePathPattern = regexp.MustCompile(`(^|[\s'"(=\[])(/[^\s'"()\[\],;]+)`)
+)
+
+// systemPathPrefixes are the directories of the system and the tools, their paths do not identify the user or the project
+var systemPathPrefixes = []string{"/Applications/", "/Library/", "/System/", "/usr/", "/bin/", "/sbin/", "/etc/", "/dev/", "/opt/", "/private/", "/var/", "/tmp/"}
+
+// notBundleIDSuffixes are the last labels of the host names and the file names matching bundleIDPattern
+var notBundleIDSuffixes = map[string]bool{
+	"com": true, "net": true, "org": true, "io": true, "dev": true,
+	"gz": true, "gpg": true, "zip": true, "json": true, "plist": true, "p12": true, "cer": true, "pem": true,
+	"log": true, "txt": true, "html": true, "yml": true, "yaml": true, "mobileprovision": true, "keychain-db": true,
+}
+
+// OSStatusCodes returns the distinct OSStatus codes of the Security framework errors in the texts
+func OSStatusCodes(texts ...string) []int {
+	seen := map[int]bool{}
+	var codes []int
+	for _, text := range texts {
+		for _, match := range osStatusPattern.FindAllStringSubmatch(text, -1) {
+			code, err := strconv.Atoi(match[1])
+			if err != nil || seen[code] {
+				continue
+			}
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	sort.Ints(codes)
+	return codes
+}
+
+// CommandFlags returns the subcommands and the flag names of the arguments, e.g. [scan xcode --file --yes]:
+// the values of the flags and the positional arguments can contain paths, names and secrets
+func CommandFlags(args []string) []string {
+	command := []string{}
+	flags := false
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			flags = true
+			if i := strings.Index(arg, "="); i > 0 {
+				arg = arg[:i]
+			}
+			command = append(command, arg)
+		} else if !flags {
+			command = append(command, arg)
+		}
+	}
+	return command
+}
+
+// Sanitize removes the certificate and key material and the secrets from the text (see logging.Redact),
+// the certificate fingerprints and common names, the team IDs, bundle IDs and profile UUIDs, the home directory of the user,
+// and the project paths and the other absolute paths outside the system directories
+func Sanitize(text, home string) string {
+	text = logging.Redact(text)
+	text = fingerprintPattern.ReplaceAllString(text, logging.Redacted)
+	text = uuidPattern.ReplaceAllString(text, logging.Redacted)
+	text = commonNamePattern.ReplaceAllString(text, "$1: "+logging.Redacted)
+	if home != "" && home != "/" {
+		text = strings.Replace(text, home, "~", -1)
+	}
+	text = projectPathPattern.ReplaceAllString(text, "${1}"+logging.Redacted)
+	text = absolutePathPattern.ReplaceAllStringFunc(text, func(match string) string {
+		submatches := absolutePathPattern.FindStringSubmatch(match)
+		for _, prefix := range systemPathPrefixes {
+			if strings.HasPrefix(submatches[2], prefix) {
+				return match
+			}
+		}
+		return submatches[1] + logging.Redacted
+	})
+	text = bundleIDPattern.ReplaceAllStringFunc(text, func(match string) string {
+		if !isBundleID(match) {
+			return match
+		}
+		return logging.Redacted
+	})
+	text = teamIDPattern.ReplaceAllStringFunc(text, func(match string) string {
+		if !isTeamID(match) {
+			return match
+		}
+		return logging.Redacted
+	})
+	return text
+}
+
+// isBundleID tells whether the match of bundleIDPattern is a bundle ID, not a host or a file name,
+// the bundle IDs of Apple (e.g. the entitlement keys) are kept
+func isBundleID(match string) bool {
+	if strings.HasPrefix(match, "com.apple.") {
+		return false
+	}
+	return !notBundleIDSuffixes[match[strings.LastIndex(match, ".")+1:]]
+}
+
+// isTeamID tells whether the match of teamIDPattern is a team ID, which mixes letters and digits
+func isTeamID(match string) bool {
+	return strings.IndexAny(match, "0123456789") >= 0 && strings.IndexAny(match, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") >= 0
+}
+
+// Write creates the gzipped tar archive of the info and the sanitized log at pth
+func Write(pth string, info Info, logText, home string) error {
+	infoContent, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(pth, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create %s, error: %s", pth, err)
+	}
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+
+	for _, entry := range []struct {
+		name    string
+		content []byte
+	}{
+		{InfoFileName, []byte(Sanitize(string(infoContent), home))},
+		{LogFileName, []byte(Sanitize(logText, home))},
+	} {
+		if err = tw.WriteHeader(&tar.Header{Name: entry.name, Mode: 0600, Size: int64(len(entry.content)), ModTime: info.GeneratedAt}); err != nil {
+			break
+		}
+		if _, err = tw.Write(entry.content); err != nil {
+			break
+		}
+	}
+
+	for _, closer := range []interface{ Close() error }{tw, gz, file} {
+		if cerr := closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s, error: %s", pth, err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package diagnostics
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/logging"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOSStatusCodes(t *testing.T) {
+	codes := OSStatusCodes("SecItemExport failed: User interaction is not allowed. (OSStatus: -25308)", "SecKeychainSearch failed - OSStatus: -25300\nretry (OSStatus: -25308)")
+	require.Equal(t, []int{-25308, -25300}, codes)
+	require.Nil(t, OSStatusCodes("no keychain error"))
+}
+
+func TestCommandFlags(t *testing.T) {
+	args := []string{"scan", "xcode", "--file", "/Users/me/App.xcworkspace", "--scheme=App", "-y", "extra"}
+	require.Equal(t, []string{"scan", "xcode", "--file", "--scheme", "-y"}, CommandFlags(args))
+	require.Equal(t, []string{}, CommandFlags(nil))
+}
+
+func TestSanitize(t *testing.T) {
+	text := "Exporting iPhone Distribution [0123456789abcdef0123456789abcdef01234567] from /Users/me/Library/Keychains/login.keychain-db\n" +
+		"-----BEGIN CERTIFICATE-----\nMIIFazCCBFOgAwIBAgIIT\n-----END CERTIFICATE-----"
+	sanitized := Sanitize(text, "/Users/me")
+	require.Equal(t, "Exporting iPhone Distribution ["+logging.Redacted+"] from ~/Library/Keychains/login.keychain-db\n"+
+		"-----BEGIN CERTIFICATE-----"+logging.Redacted+"-----END CERTIFICATE-----", sanitized)
+}
+
+func TestSanitizeIdentifiers(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "certificate common name",
+			text: "Exporting Apple Development: Jane Doe (ABCD123456) and iPhone Distribution: Acme Inc. (XYZ9876543)",
+			want: "Exporting Apple Development: [REDACTED] and iPhone Distribution: [REDACTED]",
+		},
+		{
+			name: "team ID",
+			text: "Skipping profile of team ABCD123456, the export is restricted to the team(s): XYZ9876543 (OSStatus: -25300, 1234567890, DEVELOPMENT)",
+			want: "Skipping profile of team [REDACTED], the export is restricted to the team(s): [REDACTED] (OSStatus: -25300, 1234567890, DEVELOPMENT)",
+		},
+		{
+			name: "bundle ID",
+			text: "No profile for com.acme.app, io.acme.app.widget or the wildcard com.acme.*; com.apple.developer.team-identifier from api.bitrise.io in go1.16.3 (scan.json.gpg)",
+			want: "No profile for [REDACTED], [REDACTED] or the wildcard [REDACTED]; com.apple.developer.team-identifier from api.bitrise.io in go1.16.3 (scan.json.gpg)",
+		},
+		{
+			name: "profile UUID",
+			text: "Installed App Store (UUID: 6b2a3c1e-0f4d-4e8a-9b7c-5d1e2f3a4b5c)",
+			want: "Installed App Store (UUID: [REDACTED])",
+		},
+		{
+			name: "absolute project path",
+			text: "Opening /Volumes/Work/Acme/App.xcworkspace and /Users/me/acme/App.xcodeproj, writing to /Volumes/Work/out with /Applications/Xcode.app/Contents/Developer/usr/bin/xcodebuild from https://app.bitrise.io/apps",
+			want: "Opening [REDACTED] and [REDACTED], writing to [REDACTED] with /Applications/Xcode.app/Contents/Developer/usr/bin/xcodebuild from https://app.bitrise.io/apps",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, Sanitize(tt.text, "/Users/me"))
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "diagnostics")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tmpDir))
+	}()
+
+	pth := filepath.Join(tmpDir, "diagnostics.tar.gz")
+	info := Info{GeneratedAt: time.Now(), ToolVersion: "2.4.1", Error: "failed in /Users/me/project"}
+	require.NoError(t, Write(pth, info, "scanning /Users/me/project", "/Users/me"))
+
+	file, err := os.Open(pth)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, file.Close())
+	}()
+	gz, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	contents := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		content, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		contents[header.Name] = string(content)
+	}
+	require.Contains(t, contents[InfoFileName], `"error": "failed in ~/project"`)
+	require.Equal(t, "scanning ~/project", contents[LogFileName])
+}
@@ -0,0 +1,58 @@
+package exitcode
+
+import "errors"
+
+// The exit codes of the failure categories, every other failure exits with Failure
+const (
+	Success = 0
+	Failure = 1
+	// IdentityNotFound: a required code signing identity is not in the keychains (or it was filtered out)
+	IdentityNotFound = 10
+	// ExportDenied: the keychain denied the export, e.g. a wrong password or a locked keychain
+	ExportDenied = 11
+	// ProfileMissing: a required provisioning profile is not installed
+	ProfileMissing = 12
+	// UploadFailed: the upload to Bitrise, a bucket or a secret store failed
+	UploadFailed = 13
+	// Cancelled: the user canceled a Keychain prompt or did not approve the actions
+	Cancelled = 14
+	// Unsupported: the keychain features are not available on the platform (Linux, Windows)
+	Unsupported = 15
+)
+
+// Error is a failure of a category, the exit code of the CLI is its code
+type Error struct {
+	Code int
+	Err  error
+}
+
+// Error ...
+func (err Error) Error() string {
+	return err.Err.Error()
+}
+
+// Unwrap ...
+func (err Error) Unwrap() error {
+	return err.Err
+}
+
+// Wrap returns the error with the exit code, nil if the error is nil
+func Wrap(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return Error{Code: code, Err: err}
+}
+
+// Of returns the exit code of the outermost Error in the chain of the error,
+// Success if the error is nil, and Failure if the chain has no Error
+func Of(err error) int {
+	if err == nil {
+		return Success
+	}
+	var exitErr Error
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return Failure
+}
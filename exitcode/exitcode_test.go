@@ -0,0 +1,21 @@
+package exitcode
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOf(t *testing.T) {
+	sentinel := errors.New("canceled")
+	require.Equal(t, Success, Of(nil))
+	require.Equal(t, Failure, Of(errors.New("failed")))
+	require.Equal(t, ProfileMissing, Of(Wrap(ProfileMissing, errors.New("profile not found"))))
+	require.Equal(t, ExportDenied, Of(fmt.Errorf("export failed: %w", Wrap(ExportDenied, sentinel))))
+	require.Equal(t, UploadFailed, Of(Wrap(UploadFailed, Wrap(IdentityNotFound, sentinel))), "the outermost code wins")
+
+	require.True(t, errors.Is(Wrap(Cancelled, sentinel), sentinel))
+	require.NoError(t, Wrap(Cancelled, nil))
+}
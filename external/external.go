@@ -0,0 +1,205 @@
+package external
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// ProtocolVersion is the version of the scanner contract
+const ProtocolVersion = 1
+
+// Request is written to the standard input of the scanner executable as JSON
+type Request struct {
+	Version    int      `json:"version"`
+	WorkingDir string   `json:"working_dir"`
+	Args       []string `json:"args,omitempty"`
+	// CertificatesOnly is set if the provisioning profiles are not collected
+	CertificatesOnly bool `json:"certificates_only"`
+}
+
+// IdentityRequirement describes a required code signing identity, at least one of the fields is required
+type IdentityRequirement struct {
+	CommonName      string `json:"common_name,omitempty"`
+	SHA1Fingerprint string `json:"sha1_fingerprint,omitempty"`
+	Serial          string `json:"serial,omitempty"`
+}
+
+// ProfileRequirement describes a required provisioning profile, either by UUID, or by bundle ID and export method
+type ProfileRequirement struct {
+	UUID         string               `json:"uuid,omitempty"`
+	Name         string               `json:"name,omitempty"`
+	BundleID     string               `json:"bundle_id,omitempty"`
+	ExportMethod exportoptions.Method `json:"export_method,omitempty"`
+}
+
+// Result is the JSON the scanner executable writes to its standard output,
+// its logs are expected on the standard error.
+type Result struct {
+	Version    int                   `json:"version"`
+	Identities []IdentityRequirement `json:"identities"`
+	Profiles   []ProfileRequirement  `json:"profiles"`
+	Warnings   []string              `json:"warnings,omitempty"`
+}
+
+// Scanner is a project scanner implemented by an external executable
+type Scanner struct {
+	Name string
+	Path string
+	Args []string
+}
+
+// Run executes the scanner and parses its result
+func (scanner Scanner) Run(request Request) (Result, error) {
+	request.Version = ProtocolVersion
+	input, err := json.Marshal(request)
+	if err != nil {
+		return Result{}, err
+	}
+
+	args := append(append([]string{}, scanner.Args...), request.Args...)
+	var stdout bytes.Buffer
+	cmd := command.New(scanner.Path, args...).SetStdin(bytes.NewReader(input)).SetStdout(&stdout).SetStderr(os.Stderr)
+	if request.WorkingDir != "" {
+		cmd.SetDir(request.WorkingDir)
+	}
+	if err := cmd.Run(); err != nil {
+		return Result{}, fmt.Errorf("scanner (%s) failed, error: %s", scanner.Name, err)
+	}
+
+	return ParseResult(stdout.Bytes())
+}
+
+// ParseResult parses and validates the output of a scanner
+func ParseResult(output []byte) (Result, error) {
+	var result Result
+	if err := json.Unmarshal(output, &result); err != nil {
+		return Result{}, fmt.Errorf("failed to parse scanner output (%s), error: %s", strings.TrimSpace(string(output)), err)
+	}
+	if result.Version != ProtocolVersion {
+		return Result{}, fmt.Errorf("unsupported scanner protocol version: %d, supported: %d", result.Version, ProtocolVersion)
+	}
+
+	for i, identity := range result.Identities {
+		if identity.CommonName == "" && identity.SHA1Fingerprint == "" && identity.Serial == "" {
+			return Result{}, fmt.Errorf("identity #%d has none of common_name, sha1_fingerprint and serial set", i+1)
+		}
+	}
+	for i, profile := range result.Profiles {
+		if profile.UUID == "" && profile.Name == "" && profile.BundleID == "" {
+			return Result{}, fmt.Errorf("profile #%d has none of uuid, name and bundle_id set", i+1)
+		}
+	}
+	return result, nil
+}
+
+// Resolve selects the installed certificates and profiles required by the scanner result
+func Resolve(result Result, certificates []certificateutil.CertificateInfoModel, profiles []profileutil.ProvisioningProfileInfoModel) ([]certificateutil.CertificateInfoModel, []profileutil.ProvisioningProfileInfoModel, error) {
+	var missing []string
+
+	var selectedCertificates []certificateutil.CertificateInfoModel
+	for _, identity := range result.Identities {
+		found := false
+		for _, certificate := range certificates {
+			if identity.matches(certificate) {
+				selectedCertificates = appendCertificate(selectedCertificates, certificate)
+				found = true
+			}
+		}
+		if !found {
+			missing = append(missing, fmt.Sprintf("identity %s", identity))
+		}
+	}
+
+	var selectedProfiles []profileutil.ProvisioningProfileInfoModel
+	for _, requirement := range result.Profiles {
+		var latest *profileutil.ProvisioningProfileInfoModel
+		for i, profile := range profiles {
+			if !requirement.matches(profile) {
+				continue
+			}
+			if latest == nil || profile.ExpirationDate.After(latest.ExpirationDate) {
+				latest = &profiles[i]
+			}
+		}
+		if latest == nil {
+			missing = append(missing, fmt.Sprintf("profile %s", requirement))
+			continue
+		}
+		selectedProfiles = append(selectedProfiles, *latest)
+	}
+
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("required code signing files are not installed: %s", strings.Join(missing, ", "))
+	}
+	return selectedCertificates, selectedProfiles, nil
+}
+
+func appendCertificate(certificates []certificateutil.CertificateInfoModel, certificate certificateutil.CertificateInfoModel) []certificateutil.CertificateInfoModel {
+	for _, c := range certificates {
+		if c.Serial == certificate.Serial {
+			return certificates
+		}
+	}
+	return append(certificates, certificate)
+}
+
+func (identity IdentityRequirement) matches(certificate certificateutil.CertificateInfoModel) bool {
+	if identity.SHA1Fingerprint != "" && !strings.EqualFold(identity.SHA1Fingerprint, certificate.SHA1Fingerprint) {
+		return false
+	}
+	if identity.Serial != "" && identity.Serial != certificate.Serial {
+		return false
+	}
+	if identity.CommonName != "" && identity.CommonName != certificate.CommonName {
+		return false
+	}
+	return true
+}
+
+// String ...
+func (identity IdentityRequirement) String() string {
+	return describe("common name", identity.CommonName, "sha1", identity.SHA1Fingerprint, "serial", identity.Serial)
+}
+
+func (requirement ProfileRequirement) matches(profile profileutil.ProvisioningProfileInfoModel) bool {
+	if profile.CheckValidity() != nil {
+		return false
+	}
+	if requirement.UUID != "" && requirement.UUID != profile.UUID {
+		return false
+	}
+	if requirement.Name != "" && requirement.Name != profile.Name {
+		return false
+	}
+	if requirement.BundleID != "" && requirement.BundleID != profile.BundleID {
+		return false
+	}
+	if requirement.ExportMethod != "" && requirement.ExportMethod != profile.ExportType {
+		return false
+	}
+	return true
+}
+
+// String ...
+func (requirement ProfileRequirement) String() string {
+	return describe("uuid", requirement.UUID, "name", requirement.Name, "bundle id", requirement.BundleID, "export method", string(requirement.ExportMethod))
+}
+
+// describe formats the non empty key-value pairs
+func describe(keyValues ...string) string {
+	var fields []string
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		if keyValues[i+1] != "" {
+			fields = append(fields, keyValues[i]+": "+keyValues[i+1])
+		}
+	}
+	return "(" + strings.Join(fields, ", ") + ")"
+}
@@ -0,0 +1,53 @@
+package external
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResult(t *testing.T) {
+	result, err := ParseResult([]byte(`{
+  "version": 1,
+  "identities": [{"common_name": "iPhone Distribution: Company (ABCD1234)"}],
+  "profiles": [{"bundle_id": "com.company.app", "export_method": "app-store"}]
+}`))
+	require.NoError(t, err)
+	require.Equal(t, "iPhone Distribution: Company (ABCD1234)", result.Identities[0].CommonName)
+	require.Equal(t, exportoptions.MethodAppStore, result.Profiles[0].ExportMethod)
+
+	_, err = ParseResult([]byte(`{"version": 2}`))
+	require.Error(t, err)
+
+	_, err = ParseResult([]byte(`{"version": 1, "identities": [{}]}`))
+	require.Error(t, err)
+}
+
+func TestResolve(t *testing.T) {
+	certificate := certificateutil.CertificateInfoModel{CommonName: "iPhone Distribution: Company (ABCD1234)", Serial: "1", SHA1Fingerprint: "ab12"}
+	expire := time.Now().Add(24 * time.Hour)
+	profiles := []profileutil.ProvisioningProfileInfoModel{
+		{UUID: "1", BundleID: "com.company.app", ExportType: exportoptions.MethodAppStore, ExpirationDate: expire},
+		{UUID: "2", BundleID: "com.company.app", ExportType: exportoptions.MethodAppStore, ExpirationDate: expire.Add(time.Hour)},
+		{UUID: "3", BundleID: "com.company.app", ExportType: exportoptions.MethodAdHoc, ExpirationDate: expire},
+	}
+
+	result := Result{
+		Version:    ProtocolVersion,
+		Identities: []IdentityRequirement{{SHA1Fingerprint: "AB12"}},
+		Profiles:   []ProfileRequirement{{BundleID: "com.company.app", ExportMethod: exportoptions.MethodAppStore}},
+	}
+	certificates, selectedProfiles, err := Resolve(result, []certificateutil.CertificateInfoModel{certificate}, profiles)
+	require.NoError(t, err)
+	require.Equal(t, []certificateutil.CertificateInfoModel{certificate}, certificates)
+	require.Equal(t, 1, len(selectedProfiles))
+	require.Equal(t, "2", selectedProfiles[0].UUID)
+
+	result.Profiles = []ProfileRequirement{{UUID: "missing"}}
+	_, _, err = Resolve(result, []certificateutil.CertificateInfoModel{certificate}, profiles)
+	require.EqualError(t, err, "required code signing files are not installed: profile (uuid: missing)")
+}
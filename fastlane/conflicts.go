@@ -0,0 +1,122 @@
+package fastlane
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// Conflicts compares the fastlane configuration with the code signing files collected from the Xcode project,
+// and returns the settings fastlane would use differently. Only the lanes building the given scheme
+// (or not specifying any scheme) are checked.
+func Conflicts(config Config, scheme string, certificates []certificateutil.CertificateInfoModel, profiles []profileutil.ProvisioningProfileInfoModel) []string {
+	var conflicts []string
+
+	teamIDs := map[string]bool{}
+	for _, certificate := range certificates {
+		teamIDs[certificate.TeamID] = true
+	}
+	for _, profile := range profiles {
+		teamIDs[profile.TeamID] = true
+	}
+	checkTeam := func(source, teamID string) {
+		if teamID != "" && len(teamIDs) > 0 && !teamIDs[teamID] {
+			conflicts = append(conflicts, fmt.Sprintf("%s uses team %s, the project is signed with team(s): %s", source, teamID, joinKeys(teamIDs)))
+		}
+	}
+	checkAppIdentifiers := func(source string, appIdentifiers []string) {
+		for _, appIdentifier := range appIdentifiers {
+			if len(profilesForBundleID(profiles, appIdentifier)) == 0 {
+				conflicts = append(conflicts, fmt.Sprintf("%s uses app identifier %s, no collected provisioning profile belongs to it", source, appIdentifier))
+			}
+		}
+	}
+	checkExportMethod := func(source string, method exportoptions.Method) {
+		if method == "" {
+			return
+		}
+		for _, profile := range profiles {
+			if profile.ExportType == method {
+				return
+			}
+		}
+		conflicts = append(conflicts, fmt.Sprintf("%s exports with the %s method, no %s provisioning profile was collected", source, method, method))
+	}
+	checkMatch := func(source string, match Match) {
+		checkTeam(source, match.TeamID)
+		checkAppIdentifiers(source, match.AppIdentifiers)
+		checkExportMethod(source, match.ExportMethod())
+	}
+
+	checkTeam("Appfile", config.TeamID)
+	checkAppIdentifiers("Appfile", config.AppIdentifiers)
+	if config.Match != nil {
+		checkMatch("Matchfile", *config.Match)
+	}
+
+	for _, lane := range config.Lanes {
+		if lane.Scheme != "" && scheme != "" && lane.Scheme != scheme {
+			continue
+		}
+		source := fmt.Sprintf("lane %s", lane.Name)
+
+		checkTeam(source, lane.TeamID)
+		checkExportMethod(source, lane.ExportMethod)
+		for _, match := range lane.Matches {
+			checkMatch(source+" (match)", match)
+		}
+
+		var bundleIDs []string
+		for bundleID := range lane.ProvisioningProfiles {
+			bundleIDs = append(bundleIDs, bundleID)
+		}
+		sort.Strings(bundleIDs)
+		for _, bundleID := range bundleIDs {
+			pinned := lane.ProvisioningProfiles[bundleID]
+			candidates := profilesForBundleID(profiles, bundleID)
+			if len(candidates) == 0 {
+				conflicts = append(conflicts, fmt.Sprintf("%s pins profile '%s' for %s, no collected provisioning profile belongs to it", source, pinned, bundleID))
+				continue
+			}
+
+			found := false
+			var names []string
+			for _, profile := range candidates {
+				if profile.Name == pinned || profile.UUID == pinned {
+					found = true
+					break
+				}
+				names = append(names, fmt.Sprintf("'%s'", profile.Name))
+			}
+			if !found {
+				conflicts = append(conflicts, fmt.Sprintf("%s pins profile '%s' for %s, the project uses %s", source, pinned, bundleID, strings.Join(names, ", ")))
+			}
+		}
+	}
+
+	return conflicts
+}
+
+func profilesForBundleID(profiles []profileutil.ProvisioningProfileInfoModel, bundleID string) []profileutil.ProvisioningProfileInfoModel {
+	var matching []profileutil.ProvisioningProfileInfoModel
+	for _, profile := range profiles {
+		if profile.BundleID == bundleID ||
+			strings.HasSuffix(profile.BundleID, "*") && strings.HasPrefix(bundleID, strings.TrimSuffix(profile.BundleID, "*")) {
+			matching = append(matching, profile)
+		}
+	}
+	return matching
+}
+
+func joinKeys(m map[string]bool) string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
@@ -0,0 +1,300 @@
+package fastlane
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/go-xcode/exportoptions"
+)
+
+// Match is the code signing configuration of fastlane match (Matchfile or a match action of a lane)
+type Match struct {
+	Type           string
+	AppIdentifiers []string
+	TeamID         string
+}
+
+// ExportMethod returns the export method belonging to the match type
+func (match Match) ExportMethod() exportoptions.Method {
+	switch match.Type {
+	case "appstore":
+		return exportoptions.MethodAppStore
+	case "adhoc":
+		return exportoptions.MethodAdHoc
+	case "enterprise":
+		return exportoptions.MethodEnterprise
+	case "development":
+		return exportoptions.MethodDevelopment
+	case "developer_id":
+		return exportoptions.MethodDeveloperID
+	}
+	return ""
+}
+
+// Lane is the code signing configuration of a Fastfile lane
+type Lane struct {
+	Name         string
+	Scheme       string
+	ExportMethod exportoptions.Method
+	TeamID       string
+	// ProvisioningProfiles maps the bundle IDs to the profile names or UUIDs of the export_options
+	ProvisioningProfiles map[string]string
+	Matches              []Match
+}
+
+// Config is the code signing configuration read from the fastlane files
+type Config struct {
+	// AppIdentifiers and TeamID are read from the Appfile
+	AppIdentifiers []string
+	TeamID         string
+	// Match is read from the Matchfile
+	Match *Match
+	Lanes []Lane
+}
+
+// NewConfigFromDir reads the Appfile, Matchfile and Fastfile from the given directory or its fastlane subdirectory,
+// returns nil if none of them exists.
+func NewConfigFromDir(dir string) (*Config, error) {
+	fastlaneDir := ""
+	for _, candidate := range []string{filepath.Join(dir, "fastlane"), dir} {
+		if _, err := os.Stat(filepath.Join(candidate, "Fastfile")); err == nil {
+			fastlaneDir = candidate
+			break
+		}
+		if _, err := os.Stat(filepath.Join(candidate, "Appfile")); err == nil {
+			fastlaneDir = candidate
+			break
+		}
+	}
+	if fastlaneDir == "" {
+		return nil, nil
+	}
+
+	read := func(name string) (string, error) {
+		content, err := ioutil.ReadFile(filepath.Join(fastlaneDir, name))
+		if os.IsNotExist(err) {
+			return "", nil
+		} else if err != nil {
+			return "", fmt.Errorf("failed to read %s, error: %s", name, err)
+		}
+		return string(content), nil
+	}
+
+	config := Config{}
+
+	appfile, err := read("Appfile")
+	if err != nil {
+		return nil, err
+	}
+	config.AppIdentifiers, config.TeamID = ParseAppfile(appfile)
+
+	matchfile, err := read("Matchfile")
+	if err != nil {
+		return nil, err
+	}
+	if matchfile != "" {
+		match := ParseMatchfile(matchfile)
+		config.Match = &match
+	}
+
+	fastfile, err := read("Fastfile")
+	if err != nil {
+		return nil, err
+	}
+	config.Lanes = ParseFastfile(fastfile)
+
+	return &config, nil
+}
+
+// ParseAppfile returns the app identifiers and the team ID of an Appfile
+func ParseAppfile(content string) ([]string, string) {
+	content = stripComments(content)
+	return listValue(content, "app_identifier"), stringValue(content, "team_id")
+}
+
+// ParseMatchfile returns the configuration of a Matchfile
+func ParseMatchfile(content string) Match {
+	content = stripComments(content)
+	return Match{
+		Type:           stringValue(content, "type"),
+		AppIdentifiers: listValue(content, "app_identifier"),
+		TeamID:         stringValue(content, "team_id"),
+	}
+}
+
+var (
+	laneStartPattern   = regexp.MustCompile(`(?m)^\s*(?:private_)?lane\s+:(\w+)`)
+	buildActionPattern = regexp.MustCompile(`(?m)^\s*(?:build_app|build_ios_app|build_mac_app|gym)\b`)
+	matchActionPattern = regexp.MustCompile(`(?m)^\s*(?:match|sync_code_signing)\b`)
+	profilePairPattern = regexp.MustCompile(`["']?([\w.\-*]+)["']?\s*(?:=>|:)\s*["']([^"']+)["']`)
+)
+
+// ParseFastfile returns the code signing configuration of the lanes of a Fastfile
+func ParseFastfile(content string) []Lane {
+	content = stripComments(content)
+
+	var lanes []Lane
+	starts := laneStartPattern.FindAllStringSubmatchIndex(content, -1)
+	for i, start := range starts {
+		end := len(content)
+		if i+1 < len(starts) {
+			end = starts[i+1][0]
+		}
+		body := content[start[1]:end]
+		lane := Lane{Name: content[start[2]:start[3]]}
+
+		for _, args := range actionArgs(body, buildActionPattern) {
+			if scheme := stringValue(args, "scheme"); scheme != "" {
+				lane.Scheme = scheme
+			}
+			if teamID := stringValue(args, "export_team_id"); teamID != "" {
+				lane.TeamID = teamID
+			}
+
+			method := stringValue(args, "export_method")
+			if exportOptions := hashValue(args, "export_options"); exportOptions != "" {
+				if method == "" {
+					method = stringValue(exportOptions, "method")
+				}
+				if teamID := stringValue(exportOptions, "teamID"); teamID != "" && lane.TeamID == "" {
+					lane.TeamID = teamID
+				}
+				if profiles := hashValue(exportOptions, "provisioningProfiles"); profiles != "" {
+					lane.ProvisioningProfiles = map[string]string{}
+					for _, pair := range profilePairPattern.FindAllStringSubmatch(profiles, -1) {
+						lane.ProvisioningProfiles[pair[1]] = pair[2]
+					}
+				}
+			}
+			if method != "" {
+				lane.ExportMethod = exportoptions.Method(method)
+			}
+		}
+
+		for _, args := range actionArgs(body, matchActionPattern) {
+			lane.Matches = append(lane.Matches, Match{
+				Type:           stringValue(args, "type"),
+				AppIdentifiers: listValue(args, "app_identifier"),
+				TeamID:         stringValue(args, "team_id"),
+			})
+		}
+
+		lanes = append(lanes, lane)
+	}
+	return lanes
+}
+
+// stripComments removes the ruby line comments, keeping the # characters inside string literals
+func stripComments(content string) string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		var quote rune
+		for i, r := range line {
+			if quote != 0 {
+				if r == quote {
+					quote = 0
+				}
+				continue
+			}
+			if r == '"' || r == '\'' {
+				quote = r
+			} else if r == '#' {
+				line = line[:i]
+				break
+			}
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// actionArgs returns the argument lists of the action calls matching the given pattern,
+// both the parenthesized and the multiline (trailing comma) call forms are supported
+func actionArgs(body string, action *regexp.Regexp) []string {
+	var argsList []string
+	for _, loc := range action.FindAllStringIndex(body, -1) {
+		rest := body[loc[1]:]
+		trimmed := strings.TrimLeft(rest, " \t")
+		if strings.HasPrefix(trimmed, "(") {
+			if args, ok := balanced(trimmed, '(', ')'); ok {
+				argsList = append(argsList, args)
+			}
+			continue
+		}
+
+		var lines []string
+		for _, line := range strings.Split(rest, "\n") {
+			lines = append(lines, line)
+			if !strings.HasSuffix(strings.TrimSpace(line), ",") {
+				break
+			}
+		}
+		if args := strings.Join(lines, "\n"); strings.TrimSpace(args) != "" {
+			argsList = append(argsList, args)
+		}
+	}
+	return argsList
+}
+
+// balanced returns the content between the opening character at the start of s and its closing pair
+func balanced(s string, open, close byte) (string, bool) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return s[1:i], true
+			}
+		}
+	}
+	return "", false
+}
+
+// keyPattern matches the ruby hash key or method call forms: key: , :key => , "key" => , key(, key
+func keyPattern(key string) string {
+	return `(?:^|[\s,({])["':]?` + regexp.QuoteMeta(key) + `["']?\s*(?::|=>|\()?\s*`
+}
+
+func stringValue(content, key string) string {
+	pattern := regexp.MustCompile(keyPattern(key) + `["']([^"']*)["']`)
+	if match := pattern.FindStringSubmatch(content); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+func listValue(content, key string) []string {
+	pattern := regexp.MustCompile(keyPattern(key) + `\[([^\]]*)\]`)
+	if match := pattern.FindStringSubmatch(content); match != nil {
+		var values []string
+		for _, item := range regexp.MustCompile(`["']([^"']+)["']`).FindAllStringSubmatch(match[1], -1) {
+			values = append(values, item[1])
+		}
+		return values
+	}
+	if value := stringValue(content, key); value != "" {
+		return []string{value}
+	}
+	return nil
+}
+
+func hashValue(content, key string) string {
+	pattern := regexp.MustCompile(keyPattern(key))
+	loc := pattern.FindStringIndex(content)
+	if loc == nil {
+		return ""
+	}
+	rest := strings.TrimLeft(content[loc[1]:], " \t\n")
+	if !strings.HasPrefix(rest, "{") {
+		return ""
+	}
+	hash, _ := balanced(rest, '{', '}')
+	return hash
+}
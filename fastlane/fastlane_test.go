@@ -0,0 +1,91 @@
+package fastlane
+
+import (
+	"testing"
+
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAppfile(t *testing.T) {
+	appIdentifiers, teamID := ParseAppfile(`# app_identifier("com.commented.out")
+app_identifier("com.company.app")
+apple_id "dev@company.com"
+team_id "ABCD1234" # the team
+`)
+	require.Equal(t, []string{"com.company.app"}, appIdentifiers)
+	require.Equal(t, "ABCD1234", teamID)
+}
+
+func TestParseMatchfile(t *testing.T) {
+	match := ParseMatchfile(`git_url("https://github.com/company/certificates")
+type("appstore")
+app_identifier(["com.company.app", "com.company.app.widget"])
+`)
+	require.Equal(t, "appstore", match.Type)
+	require.Equal(t, exportoptions.MethodAppStore, match.ExportMethod())
+	require.Equal(t, []string{"com.company.app", "com.company.app.widget"}, match.AppIdentifiers)
+	require.Equal(t, "", match.TeamID)
+}
+
+func TestParseFastfile(t *testing.T) {
+	lanes := ParseFastfile(`default_platform(:ios)
+
+platform :ios do
+  lane :beta do
+    match(type: "adhoc", team_id: "ABCD1234")
+    build_app(
+      scheme: "App",
+      export_method: "ad-hoc",
+      export_options: {
+        provisioningProfiles: {
+          "com.company.app" => "match AdHoc com.company.app",
+          "com.company.app.widget": "match AdHoc com.company.app.widget"
+        }
+      }
+    )
+  end
+
+  private_lane :release do
+    gym scheme: "App Release",
+      export_options: { method: "app-store", teamID: "EFGH5678" }
+  end
+end
+`)
+	require.Equal(t, 2, len(lanes))
+
+	require.Equal(t, "beta", lanes[0].Name)
+	require.Equal(t, "App", lanes[0].Scheme)
+	require.Equal(t, exportoptions.MethodAdHoc, lanes[0].ExportMethod)
+	require.Equal(t, map[string]string{
+		"com.company.app":        "match AdHoc com.company.app",
+		"com.company.app.widget": "match AdHoc com.company.app.widget",
+	}, lanes[0].ProvisioningProfiles)
+	require.Equal(t, []Match{{Type: "adhoc", TeamID: "ABCD1234"}}, lanes[0].Matches)
+
+	require.Equal(t, "release", lanes[1].Name)
+	require.Equal(t, "App Release", lanes[1].Scheme)
+	require.Equal(t, exportoptions.MethodAppStore, lanes[1].ExportMethod)
+	require.Equal(t, "EFGH5678", lanes[1].TeamID)
+}
+
+func TestConflicts(t *testing.T) {
+	config := Config{
+		TeamID: "ABCD1234",
+		Lanes: []Lane{
+			{Name: "beta", Scheme: "App", ExportMethod: exportoptions.MethodAdHoc, ProvisioningProfiles: map[string]string{"com.company.app": "AdHoc App"}},
+			{Name: "other", Scheme: "Other", ExportMethod: exportoptions.MethodEnterprise},
+		},
+	}
+	profiles := []profileutil.ProvisioningProfileInfoModel{
+		{Name: "Dev App", BundleID: "com.company.app", TeamID: "EFGH5678", ExportType: exportoptions.MethodDevelopment},
+	}
+
+	conflicts := Conflicts(config, "App", nil, profiles)
+	require.Equal(t, []string{
+		"Appfile uses team ABCD1234, the project is signed with team(s): EFGH5678",
+		"lane beta exports with the ad-hoc method, no ad-hoc provisioning profile was collected",
+		"lane beta pins profile 'AdHoc App' for com.company.app, the project uses 'Dev App'",
+	}, conflicts)
+}
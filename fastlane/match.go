@@ -0,0 +1,166 @@
+package fastlane
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// MatchPasswordEnvKey is the env var of the match repo encryption password, as used by fastlane match
+const MatchPasswordEnvKey = "MATCH_PASSWORD"
+
+// matchSaltHeader prefixes the OpenSSL compatible encrypted files of match
+const matchSaltHeader = "Salted__"
+
+// MatchCertificateType returns the certs/<type> directory name of the certificate in a match repo
+func MatchCertificateType(certificate certificateutil.CertificateInfoModel) string {
+	prefix := strings.TrimSpace(strings.SplitN(certificate.CommonName, ":", 2)[0])
+	switch prefix {
+	case "iPhone Developer", "Apple Development", "Mac Developer":
+		return "development"
+	case "Developer ID Application":
+		return "developer_id_application"
+	case "3rd Party Mac Developer Installer":
+		return "mac_installer_distribution"
+	case "Developer ID Installer":
+		return "developer_id_installer"
+	}
+	return "distribution"
+}
+
+// MatchProfilePath returns the path of the profile in a match repo, e.g. profiles/appstore/AppStore_io.bitrise.app.mobileprovision
+func MatchProfilePath(profile profileutil.ProvisioningProfileInfoModel) string {
+	dir, prefix := "development", "Development"
+	switch profile.ExportType {
+	case exportoptions.MethodAppStore:
+		dir, prefix = "appstore", "AppStore"
+	case exportoptions.MethodAdHoc:
+		dir, prefix = "adhoc", "AdHoc"
+	case exportoptions.MethodEnterprise:
+		dir, prefix = "enterprise", "InHouse"
+	case exportoptions.MethodDeveloperID:
+		dir, prefix = "developer_id", "Direct"
+	}
+
+	ext := ".mobileprovision"
+	if profile.Type == profileutil.ProfileTypeMacOs {
+		ext = ".provisionprofile"
+		prefix += "_macos"
+	}
+	return filepath.Join("profiles", dir, prefix+"_"+profile.BundleID+ext)
+}
+
+// MatchFile is a file of a match repo
+type MatchFile struct {
+	// Path is relative to the repo root
+	Path    string
+	Content []byte
+}
+
+// MatchCertificateFiles returns the .cer and the .p12 file of the identity, named after its developer portal ID
+func MatchCertificateFiles(certificate certificateutil.CertificateInfoModel, certificateID string, p12 []byte) []MatchFile {
+	dir := filepath.Join("certs", MatchCertificateType(certificate))
+	return []MatchFile{
+		{Path: filepath.Join(dir, certificateID+".cer"), Content: certificate.Certificate.Raw},
+		{Path: filepath.Join(dir, certificateID+".p12"), Content: p12},
+	}
+}
+
+// WriteMatchRepo writes the files encrypted with the password into the repo directory, returns the written paths
+func WriteMatchRepo(dir, password string, files []MatchFile) ([]string, error) {
+	if password == "" {
+		return nil, fmt.Errorf("the match repo password is required, set the %s env var", MatchPasswordEnvKey)
+	}
+
+	var paths []string
+	for _, file := range files {
+		encrypted, err := EncryptMatchFile(file.Content, password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt %s, error: %s", file.Path, err)
+		}
+
+		pth := filepath.Join(dir, file.Path)
+		if err := os.MkdirAll(filepath.Dir(pth), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create match repo directory, error: %s", err)
+		}
+		if err := ioutil.WriteFile(pth, encrypted, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write %s, error: %s", pth, err)
+		}
+		paths = append(paths, pth)
+	}
+	return paths, nil
+}
+
+// EncryptMatchFile encrypts the content the way match does (openssl aes-256-cbc -md md5 -a),
+// the result can be decrypted by match with the same password.
+func EncryptMatchFile(content []byte, password string) ([]byte, error) {
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, iv := matchKeyIV(password, salt)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	padding := aes.BlockSize - len(content)%aes.BlockSize
+	plaintext := append(append([]byte{}, content...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+
+	data := append(append([]byte(matchSaltHeader), salt...), ciphertext...)
+	return []byte(base64.StdEncoding.EncodeToString(data) + "\n"), nil
+}
+
+// DecryptMatchFile decrypts a file encrypted by EncryptMatchFile or by match
+func DecryptMatchFile(encrypted []byte, password string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.Replace(strings.TrimSpace(string(encrypted)), "\n", "", -1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 16+aes.BlockSize || string(data[:8]) != matchSaltHeader || (len(data)-16)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid encrypted file")
+	}
+	key, iv := matchKeyIV(password, data[8:16])
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(data)-16)
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, data[16:])
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize || !bytes.Equal(plaintext[len(plaintext)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, errors.New("invalid password")
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+// matchKeyIV derives the AES-256 key and IV like OpenSSL's EVP_BytesToKey with MD5 and a single iteration
+func matchKeyIV(password string, salt []byte) ([]byte, []byte) {
+	var derived, block []byte
+	for len(derived) < 32+aes.BlockSize {
+		hash := md5.New()
+		hash.Write(block)
+		hash.Write([]byte(password))
+		hash.Write(salt)
+		block = hash.Sum(nil)
+		derived = append(derived, block...)
+	}
+	return derived[:32], derived[32 : 32+aes.BlockSize]
+}
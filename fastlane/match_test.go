@@ -0,0 +1,42 @@
+package fastlane
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchCertificateType(t *testing.T) {
+	require.Equal(t, "development", MatchCertificateType(certificateutil.CertificateInfoModel{CommonName: "Apple Development: Bitrise Bot (ABCD1234)"}))
+	require.Equal(t, "distribution", MatchCertificateType(certificateutil.CertificateInfoModel{CommonName: "iPhone Distribution: Bitrise Inc. (ABCD1234)"}))
+	require.Equal(t, "developer_id_application", MatchCertificateType(certificateutil.CertificateInfoModel{CommonName: "Developer ID Application: Bitrise Inc. (ABCD1234)"}))
+	require.Equal(t, "mac_installer_distribution", MatchCertificateType(certificateutil.CertificateInfoModel{CommonName: "3rd Party Mac Developer Installer: Bitrise Inc. (ABCD1234)"}))
+}
+
+func TestMatchProfilePath(t *testing.T) {
+	profile := profileutil.ProvisioningProfileInfoModel{BundleID: "io.bitrise.app", ExportType: exportoptions.MethodAppStore, Type: profileutil.ProfileTypeIos}
+	require.Equal(t, filepath.Join("profiles", "appstore", "AppStore_io.bitrise.app.mobileprovision"), MatchProfilePath(profile))
+
+	profile = profileutil.ProvisioningProfileInfoModel{BundleID: "io.bitrise.app", ExportType: exportoptions.MethodDevelopment, Type: profileutil.ProfileTypeMacOs}
+	require.Equal(t, filepath.Join("profiles", "development", "Development_macos_io.bitrise.app.provisionprofile"), MatchProfilePath(profile))
+}
+
+func TestEncryptMatchFile(t *testing.T) {
+	for _, content := range [][]byte{[]byte("profile content"), make([]byte, 32), {}} {
+		encrypted, err := EncryptMatchFile(content, "secret")
+		require.NoError(t, err)
+
+		decrypted, err := DecryptMatchFile(encrypted, "secret")
+		require.NoError(t, err)
+		require.Equal(t, content, decrypted)
+	}
+
+	encrypted, err := EncryptMatchFile([]byte("profile content"), "secret")
+	require.NoError(t, err)
+	_, err = DecryptMatchFile(encrypted, "wrong")
+	require.Error(t, err)
+}
@@ -0,0 +1,89 @@
+package features
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Kind is the category of a compiled-in capability
+type Kind string
+
+const (
+	// Scanner collects the code signing settings of a project type
+	Scanner Kind = "scanner"
+	// Destination receives the exported code signing files
+	Destination Kind = "destination"
+	// Tool is a standalone command working on code signing files
+	Tool Kind = "tool"
+)
+
+// DisabledFeaturesEnvKey can be used to disable compiled-in features at runtime, as a comma separated list of feature names
+const DisabledFeaturesEnvKey = "CODESIGNDOC_DISABLED_FEATURES"
+
+// Feature is a capability compiled into the codesigndoc binary
+type Feature struct {
+	Name        string
+	Kind        Kind
+	Description string
+}
+
+var (
+	registryMutex sync.Mutex
+	registry      = map[string]Feature{}
+)
+
+// Register adds a feature to the list of compiled-in capabilities,
+// the commands register the features they expose from their init.
+func Register(feature Feature) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	registry[feature.Name] = feature
+}
+
+// List returns the registered features ordered by kind and name
+func List() []Feature {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	var list []Feature
+	for _, feature := range registry {
+		list = append(list, feature)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Kind != list[j].Kind {
+			return list[i].Kind < list[j].Kind
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// IsEnabled returns true if the feature is compiled in and not disabled by the DisabledFeaturesEnvKey env var
+func IsEnabled(name string) bool {
+	registryMutex.Lock()
+	_, registered := registry[name]
+	registryMutex.Unlock()
+
+	if !registered {
+		return false
+	}
+
+	for _, disabled := range strings.Split(os.Getenv(DisabledFeaturesEnvKey), ",") {
+		if strings.TrimSpace(disabled) == name {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckEnabled returns an error if the given feature can not be used
+func CheckEnabled(name string) error {
+	if !IsEnabled(name) {
+		return fmt.Errorf("feature (%s) is not available in this build or disabled by the %s env var, run 'codesigndoc features' to list the available features", name, DisabledFeaturesEnvKey)
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+// Package keychain is the CGo free API of the code signing identity export, so other Go programs
+// can search and export identities without the Security framework types, and test their code with a fake Keychain.
+// The macOS implementation is osxkeychain.Keychain.
+package keychain
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/go-utils/sliceutil"
+)
+
+// ExportFormat is the file format of the exported identities
+type ExportFormat int
+
+const (
+	// ExportFormatPKCS12 is a .p12 bundle, as exported by Keychain Access.app
+	ExportFormatPKCS12 ExportFormat = iota
+	// ExportFormatPEM is a PEM armoured sequence of the certificates and the passphrase protected private keys
+	ExportFormatPEM
+	// ExportFormatPKCS8 is a passphrase encrypted PKCS#8 PEM of the private key, exported one by one by ExportEach
+	ExportFormatPKCS8
+)
+
+// ErrClosed is returned for an identity used after its Close
+var ErrClosed = errors.New("the identity is already closed")
+
+// Query selects the identities matching any of the Labels, any of the TeamIDs, any of the Types
+// (the prefix of the common name, e.g. "Apple Distribution") and any of the Fingerprints, an empty list matches every identity.
+type Query struct {
+	Labels  []string
+	TeamIDs []string
+	Types   []string
+	// Fingerprints are the SHA-1 or SHA-256 fingerprints of the certificates (hex, as printed by security find-identity),
+	// unlike the labels these are unique if multiple certificates have the same common name
+	Fingerprints []string
+	// Keychains selects the identities stored in any of the keychain files, e.g. a CI keychain,
+	// a file name without extension (e.g. login) matches the keychain in any directory
+	Keychains []string
+	// IncludeExpired selects the expired and not yet valid identities too, if there is no valid one
+	IncludeExpired bool
+}
+
+// TypeOf returns the certificate type of the common name, e.g. "iPhone Developer" of "iPhone Developer: John Doe (ABCD123456)"
+func TypeOf(commonName string) string {
+	return strings.TrimSpace(strings.SplitN(commonName, ":", 2)[0])
+}
+
+// MatchesLabel returns true if the label is one of the query's Labels
+func (query Query) MatchesLabel(label string) bool {
+	return len(query.Labels) == 0 || sliceutil.IsStringInSlice(label, query.Labels)
+}
+
+// MatchesCertificate returns true if the certificate belongs to one of the query's TeamIDs, is one of its Types
+// and has one of its Fingerprints
+func (query Query) MatchesCertificate(certificate *x509.Certificate) bool {
+	return query.matchesTeam(certificate) && query.matchesType(certificate) && query.matchesFingerprint(certificate)
+}
+
+// MatchesKeychain returns true if the keychain file is one of the query's Keychains
+func (query Query) MatchesKeychain(keychainPath string) bool {
+	if len(query.Keychains) == 0 {
+		return true
+	}
+	for _, keychain := range query.Keychains {
+		if filepath.Clean(keychain) == filepath.Clean(keychainPath) || keychain == KeychainName(keychainPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// KeychainName returns the name of the keychain file without the directory and extension, e.g. login of login.keychain-db
+func KeychainName(keychainPath string) string {
+	name := filepath.Base(keychainPath)
+	for _, ext := range []string{".keychain-db", ".keychain"} {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+	return name
+}
+
+func (query Query) matchesTeam(certificate *x509.Certificate) bool {
+	if len(query.TeamIDs) == 0 {
+		return true
+	}
+	for _, teamID := range certificate.Subject.OrganizationalUnit {
+		if sliceutil.IsStringInSlice(teamID, query.TeamIDs) {
+			return true
+		}
+	}
+	return false
+}
+
+func (query Query) matchesType(certificate *x509.Certificate) bool {
+	return len(query.Types) == 0 || sliceutil.IsStringInSlice(TypeOf(certificate.Subject.CommonName), query.Types)
+}
+
+func (query Query) matchesFingerprint(certificate *x509.Certificate) bool {
+	if len(query.Fingerprints) == 0 {
+		return true
+	}
+	for _, fingerprint := range query.Fingerprints {
+		if HasFingerprint(certificate, fingerprint) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeFingerprint returns the lowercase hex fingerprint without the space and colon separators
+func NormalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", ":", "").Replace(fingerprint))
+}
+
+// HasFingerprint returns true if the SHA-1 or the SHA-256 fingerprint of the certificate is the given one
+func HasFingerprint(certificate *x509.Certificate, fingerprint string) bool {
+	switch fingerprint = NormalizeFingerprint(fingerprint); len(fingerprint) {
+	case sha1.Size * 2:
+		return fingerprint == fmt.Sprintf("%x", sha1.Sum(certificate.Raw))
+	case sha256.Size * 2:
+		return fingerprint == fmt.Sprintf("%x", sha256.Sum256(certificate.Raw))
+	}
+	return false
+}
+
+// Skipped is an identity left out by the selection
+type Skipped struct {
+	Label           string
+	SHA1Fingerprint string
+	// KeychainPath is the keychain file the skipped identity is stored in
+	KeychainPath string
+	// Reason is why the identity was skipped, e.g. expired on 2019-01-02
+	Reason string
+}
+
+// ExportOptions controls the export of the identities
+type ExportOptions struct {
+	Format ExportFormat
+	// AskForPassword shows the Keychain prompt for the passphrase of the exported file
+	AskForPassword bool
+	// Passphrase protects the exported private keys if AskForPassword is not set
+	Passphrase string
+}
+
+// Keychain searches and exports the code signing identities, osxkeychain.Keychain implements it with the Security framework
+// and keychaintest.Fake in memory, for tests
+type Keychain interface {
+	// FindIdentities returns every identity matching the query, including the expired ones and the ones without private key.
+	// The returned identities have to be closed.
+	FindIdentities(ctx context.Context, query Query) ([]*Identity, error)
+	// CopyCertificate reads the certificate of the identity from the keychain
+	CopyCertificate(identity *Identity) (*x509.Certificate, error)
+	// SelectIdentities returns the latest valid identity of each label matching the query, and the skipped identities.
+	// The returned identities have to be closed.
+	SelectIdentities(ctx context.Context, query Query) ([]*Identity, []Skipped, error)
+	// Export exports the identities merged into a single file
+	Export(ctx context.Context, identities []*Identity, options ExportOptions) ([]byte, error)
+	// ExportTo exports the identities merged into a single file like Export, and writes it to w, returns the number of bytes written
+	ExportTo(ctx context.Context, w io.Writer, identities []*Identity, options ExportOptions) (int64, error)
+	// ExportEach exports every identity into a separate file, in the order of the identities
+	ExportEach(ctx context.Context, identities []*Identity, options ExportOptions) ([][]byte, error)
+}
+
+// KeychainClient is the keychain backend of the search and export, the Security framework or the in-memory fake of the tests
+type KeychainClient = Keychain
+
+// Identity is an opaque handle of a keychain identity (a certificate and its private key),
+// Close releases the keychain's reference of the identity.
+type Identity struct {
+	Label        string
+	Certificate  *x509.Certificate
+	KeychainPath string
+
+	handle   io.Closer
+	closed   bool
+	closeErr error
+	mux      sync.Mutex
+}
+
+// NewIdentity returns the handle of an identity, for the Keychain implementations;
+// the handle is the implementation's reference of the identity, closed by the identity's Close.
+func NewIdentity(label string, certificate *x509.Certificate, keychainPath string, handle io.Closer) *Identity {
+	return &Identity{Label: label, Certificate: certificate, KeychainPath: keychainPath, handle: handle}
+}
+
+// Handle returns the implementation's reference of the identity, for the Keychain implementations
+func (identity *Identity) Handle() (io.Closer, error) {
+	identity.mux.Lock()
+	defer identity.mux.Unlock()
+	if identity.closed {
+		return nil, ErrClosed
+	}
+	return identity.handle, nil
+}
+
+// SHA1Fingerprint returns the lowercase hex SHA-1 fingerprint of the identity's certificate, empty if the certificate is unknown
+func (identity *Identity) SHA1Fingerprint() string {
+	if identity.Certificate == nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha1.Sum(identity.Certificate.Raw))
+}
+
+// String describes the identity for the logs
+func (identity *Identity) String() string {
+	if identity.Certificate == nil {
+		return identity.Label
+	}
+	description := fmt.Sprintf("%s (team: %s, serial: %s, SHA-1: %s, expires: %s", identity.Label, strings.Join(identity.Certificate.Subject.OrganizationalUnit, " "),
+		identity.Certificate.SerialNumber, identity.SHA1Fingerprint(), identity.Certificate.NotAfter.Format(time.RFC3339))
+	if identity.KeychainPath != "" {
+		description += ", keychain: " + identity.KeychainPath
+	}
+	return description + ")"
+}
+
+// NotAfter returns the expiry date of the identity's certificate, zero if the certificate is unknown
+func (identity *Identity) NotAfter() time.Time {
+	if identity.Certificate == nil {
+		return time.Time{}
+	}
+	return identity.Certificate.NotAfter
+}
+
+// Close releases the identity, it is safe to call it multiple times
+func (identity *Identity) Close() error {
+	identity.mux.Lock()
+	defer identity.mux.Unlock()
+	if identity.closed {
+		return identity.closeErr
+	}
+	identity.closed = true
+	if identity.handle != nil {
+		identity.closeErr = identity.handle.Close()
+	}
+	return identity.closeErr
+}
+
+// CloseAll closes the identities, and returns the first error
+func CloseAll(identities []*Identity) error {
+	var firstErr error
+	for _, identity := range identities {
+		if err := identity.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,73 @@
+package keychain
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type countingCloser struct {
+	closed int
+}
+
+func (closer *countingCloser) Close() error {
+	closer.closed++
+	return nil
+}
+
+func TestIdentityClose(t *testing.T) {
+	closer := &countingCloser{}
+	identity := NewIdentity("Apple Distribution: Bitrise (ABCD123456)", nil, "/Users/vagrant/Library/Keychains/login.keychain-db", closer)
+
+	handle, err := identity.Handle()
+	require.NoError(t, err)
+	require.Equal(t, closer, handle)
+	require.True(t, identity.NotAfter().IsZero())
+
+	require.NoError(t, CloseAll([]*Identity{identity, identity}))
+	require.Equal(t, 1, closer.closed)
+
+	_, err = identity.Handle()
+	require.Equal(t, ErrClosed, err)
+}
+
+func TestQueryMatches(t *testing.T) {
+	certificate := &x509.Certificate{Subject: pkix.Name{CommonName: "Apple Distribution: Bitrise (ABCD123456)", OrganizationalUnit: []string{"ABCD123456"}}}
+
+	require.Equal(t, "Apple Distribution", TypeOf(certificate.Subject.CommonName))
+	require.True(t, Query{}.MatchesLabel("any"))
+	require.True(t, Query{}.MatchesCertificate(certificate))
+	require.True(t, Query{Labels: []string{"other", "Apple Distribution: Bitrise (ABCD123456)"}}.MatchesLabel(certificate.Subject.CommonName))
+	require.False(t, Query{Labels: []string{"other"}}.MatchesLabel(certificate.Subject.CommonName))
+	require.True(t, Query{TeamIDs: []string{"ABCD123456"}, Types: []string{"Apple Distribution"}}.MatchesCertificate(certificate))
+	require.False(t, Query{TeamIDs: []string{"XYZ"}}.MatchesCertificate(certificate))
+	require.False(t, Query{Types: []string{"Apple Development"}}.MatchesCertificate(certificate))
+
+	login := "/Users/vagrant/Library/Keychains/login.keychain-db"
+	require.True(t, Query{}.MatchesKeychain(login))
+	require.True(t, Query{Keychains: []string{"login"}}.MatchesKeychain(login))
+	require.True(t, Query{Keychains: []string{"/Users/vagrant/Library/Keychains/../Keychains/login.keychain-db"}}.MatchesKeychain(login))
+	require.False(t, Query{Keychains: []string{"ci", "/tmp/login.keychain-db"}}.MatchesKeychain(login))
+	require.False(t, Query{Keychains: []string{"login"}}.MatchesKeychain(""))
+	require.Equal(t, "ci", KeychainName("/tmp/ci.keychain"))
+}
+
+func TestQueryMatchesFingerprint(t *testing.T) {
+	certificate := &x509.Certificate{Raw: []byte("certificate"), Subject: pkix.Name{CommonName: "Apple Development: John Doe (ABCD123456)"}}
+	sha1Fingerprint := fmt.Sprintf("%X", sha1.Sum(certificate.Raw))
+	sha256Fingerprint := fmt.Sprintf("%x", sha256.Sum256(certificate.Raw))
+
+	require.True(t, HasFingerprint(certificate, sha1Fingerprint))
+	require.True(t, HasFingerprint(certificate, sha256Fingerprint))
+	require.True(t, HasFingerprint(certificate, strings.ToLower(sha1Fingerprint[:2])+":"+sha1Fingerprint[2:]))
+	require.False(t, HasFingerprint(certificate, sha1Fingerprint[:20]))
+
+	require.True(t, Query{Fingerprints: []string{"other", sha256Fingerprint}}.MatchesCertificate(certificate))
+	require.False(t, Query{Fingerprints: []string{strings.Repeat("0", 40)}}.MatchesCertificate(certificate))
+}
@@ -0,0 +1,322 @@
+// Package keychaintest is an in-memory keychain.Keychain, seeded from PEM fixtures,
+// so the code using the keychain package can be unit-tested without the Security framework, e.g. on Linux CI.
+package keychaintest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/keychain"
+	"github.com/bitrise-io/go-utils/pkcs12"
+)
+
+// Fake is an in-memory keychain.Keychain, its identities are added with AddPEM
+type Fake struct {
+	// Path is reported as the KeychainPath of the identities
+	Path string
+
+	items []item
+	open  int
+	mux   sync.Mutex
+}
+
+var _ keychain.Keychain = (*Fake)(nil)
+
+// item is a certificate of the fake keychain, with its private key if it is an identity
+type item struct {
+	certificate *x509.Certificate
+	privateKey  interface{}
+}
+
+// handle is the Fake's reference of an identity
+type handle struct {
+	fake *Fake
+	item item
+}
+
+func (h *handle) Close() error {
+	h.fake.mux.Lock()
+	defer h.fake.mux.Unlock()
+	h.fake.open--
+	return nil
+}
+
+// New returns a Fake seeded with the PEM fixtures, see AddPEM
+func New(fixtures ...[]byte) (*Fake, error) {
+	fake := &Fake{Path: "/Users/fake/Library/Keychains/login.keychain-db"}
+	for _, fixture := range fixtures {
+		if err := fake.AddPEM(fixture); err != nil {
+			return nil, err
+		}
+	}
+	return fake, nil
+}
+
+// AddPEM adds a CERTIFICATE block, and its PRIVATE KEY (PKCS#8), RSA PRIVATE KEY or EC PRIVATE KEY block if it is an identity.
+// A certificate without private key is found, but never selected, as in a keychain missing the private key.
+func (fake *Fake) AddPEM(fixture []byte) error {
+	var added item
+	for block, rest := pem.Decode(fixture); block != nil; block, rest = pem.Decode(rest) {
+		var err error
+		switch block.Type {
+		case "CERTIFICATE":
+			added.certificate, err = x509.ParseCertificate(block.Bytes)
+		case "PRIVATE KEY":
+			added.privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			added.privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			added.privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+		default:
+			err = fmt.Errorf("unsupported PEM block: %s", block.Type)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse the PEM fixture, error: %s", err)
+		}
+	}
+	if added.certificate == nil {
+		return errors.New("the PEM fixture has no CERTIFICATE block")
+	}
+
+	fake.mux.Lock()
+	defer fake.mux.Unlock()
+	fake.items = append(fake.items, added)
+	return nil
+}
+
+// Open returns the number of identities returned by the Fake and not closed yet, to check for leaked handles
+func (fake *Fake) Open() int {
+	fake.mux.Lock()
+	defer fake.mux.Unlock()
+	return fake.open
+}
+
+func (fake *Fake) newIdentity(found item) *keychain.Identity {
+	fake.open++
+	return keychain.NewIdentity(found.certificate.Subject.CommonName, found.certificate, fake.Path, &handle{fake: fake, item: found})
+}
+
+func (fake *Fake) find(query keychain.Query) []item {
+	var found []item
+	for _, candidate := range fake.items {
+		if query.MatchesLabel(candidate.certificate.Subject.CommonName) && query.MatchesCertificate(candidate.certificate) && query.MatchesKeychain(fake.Path) {
+			found = append(found, candidate)
+		}
+	}
+	return found
+}
+
+// FindIdentities returns every certificate matching the query, including the expired ones and the ones without private key
+func (fake *Fake) FindIdentities(ctx context.Context, query keychain.Query) ([]*keychain.Identity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("keychain operation canceled: %w", err)
+	}
+
+	fake.mux.Lock()
+	defer fake.mux.Unlock()
+	var identities []*keychain.Identity
+	for _, found := range fake.find(query) {
+		identities = append(identities, fake.newIdentity(found))
+	}
+	return identities, nil
+}
+
+// CopyCertificate returns the certificate of the identity
+func (fake *Fake) CopyCertificate(identity *keychain.Identity) (*x509.Certificate, error) {
+	found, err := fake.itemOf(identity)
+	if err != nil {
+		return nil, err
+	}
+	return found.certificate, nil
+}
+
+// SelectIdentities returns the latest valid identity of each label matching the query, and the skipped identities,
+// the same way as osxkeychain.Keychain
+func (fake *Fake) SelectIdentities(ctx context.Context, query keychain.Query) ([]*keychain.Identity, []keychain.Skipped, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, fmt.Errorf("keychain operation canceled: %w", err)
+	}
+
+	fake.mux.Lock()
+	defer fake.mux.Unlock()
+
+	byLabel := map[string][]item{}
+	var labels []string
+	for _, found := range fake.find(query) {
+		label := found.certificate.Subject.CommonName
+		if _, ok := byLabel[label]; !ok {
+			labels = append(labels, label)
+		}
+		byLabel[label] = append(byLabel[label], found)
+	}
+	sort.Strings(labels)
+
+	var identities []*keychain.Identity
+	var skipped []keychain.Skipped
+	for _, label := range labels {
+		latest, skippedOfLabel := selectItem(byLabel[label], query.IncludeExpired, time.Now())
+		if latest != nil {
+			identities = append(identities, fake.newIdentity(*latest))
+		}
+		for i := range skippedOfLabel {
+			skippedOfLabel[i].KeychainPath = fake.Path
+		}
+		skipped = append(skipped, skippedOfLabel...)
+	}
+	return identities, skipped, nil
+}
+
+// selectItem selects the identity with the latest expiring certificate, a valid one is preferred if includeExpired is set
+func selectItem(items []item, includeExpired bool, now time.Time) (*item, []keychain.Skipped) {
+	var latest *item
+	var latestValid bool
+	var skipped []keychain.Skipped
+
+	fingerprints := map[string]bool{}
+	for i := range items {
+		candidate := &items[i]
+		skip := func(skippedItem *item, reason string) {
+			skipped = append(skipped, keychain.Skipped{Label: skippedItem.certificate.Subject.CommonName, SHA1Fingerprint: fingerprint(skippedItem.certificate), Reason: reason})
+		}
+
+		if fingerprints[fingerprint(candidate.certificate)] {
+			skip(candidate, "duplicate, the identity is installed in multiple keychains")
+			continue
+		}
+		fingerprints[fingerprint(candidate.certificate)] = true
+
+		if candidate.privateKey == nil {
+			skip(candidate, "missing private key")
+			continue
+		}
+
+		valid := true
+		if now.After(candidate.certificate.NotAfter) {
+			valid = false
+			if !includeExpired {
+				skip(candidate, fmt.Sprintf("expired on %s", candidate.certificate.NotAfter.Format("2006-01-02")))
+				continue
+			}
+		} else if now.Before(candidate.certificate.NotBefore) {
+			valid = false
+			if !includeExpired {
+				skip(candidate, fmt.Sprintf("not valid until %s", candidate.certificate.NotBefore.Format("2006-01-02")))
+				continue
+			}
+		}
+
+		reason := "a later expiring identity with the same name is selected"
+		if valid != latestValid {
+			reason = "a valid identity with the same name is selected"
+		}
+		if latest != nil && !(valid && !latestValid || valid == latestValid && candidate.certificate.NotAfter.After(latest.certificate.NotAfter)) {
+			skip(candidate, reason)
+			continue
+		}
+		if latest != nil {
+			skip(latest, reason)
+		}
+		latest, latestValid = candidate, valid
+	}
+	return latest, skipped
+}
+
+func fingerprint(certificate *x509.Certificate) string {
+	return fmt.Sprintf("%x", sha1.Sum(certificate.Raw))
+}
+
+// Export exports the identities merged into a single file, a .p12 can hold a single identity only
+func (fake *Fake) Export(ctx context.Context, identities []*keychain.Identity, options keychain.ExportOptions) ([]byte, error) {
+	if options.Format == keychain.ExportFormatPKCS8 {
+		return nil, errors.New("the private keys are exported one by one, use ExportEach")
+	}
+	if options.Format == keychain.ExportFormatPKCS12 && len(identities) != 1 {
+		return nil, fmt.Errorf("the fake keychain exports a single identity into a .p12, %d given", len(identities))
+	}
+	contents, err := fake.ExportEach(ctx, identities, options)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Join(contents, nil), nil
+}
+
+// ExportTo exports the identities like Export and writes the content to w
+func (fake *Fake) ExportTo(ctx context.Context, w io.Writer, identities []*keychain.Identity, options keychain.ExportOptions) (int64, error) {
+	content, err := fake.Export(ctx, identities, options)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(content)
+	return int64(n), err
+}
+
+// ExportEach exports every identity into a separate file, in the order of the identities
+func (fake *Fake) ExportEach(ctx context.Context, identities []*keychain.Identity, options keychain.ExportOptions) ([][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("keychain operation canceled: %w", err)
+	}
+	if options.AskForPassword {
+		return nil, errors.New("the fake keychain can not ask for the password, set the passphrase")
+	}
+
+	var contents [][]byte
+	for _, identity := range identities {
+		found, err := fake.itemOf(identity)
+		if err != nil {
+			return nil, err
+		}
+		if found.privateKey == nil {
+			return nil, fmt.Errorf("identity (%s) has no private key", identity.Label)
+		}
+		content, err := export(found, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export identity (%s), error: %s", identity.Label, err)
+		}
+		contents = append(contents, content)
+	}
+	return contents, nil
+}
+
+func export(found item, options keychain.ExportOptions) ([]byte, error) {
+	if options.Format == keychain.ExportFormatPKCS12 {
+		return pkcs12.Encode(rand.Reader, found.privateKey, found.certificate, nil, options.Passphrase)
+	}
+
+	key, err := x509.MarshalPKCS8PrivateKey(found.privateKey)
+	if err != nil {
+		return nil, err
+	}
+	keyBlock := &pem.Block{Type: "PRIVATE KEY", Bytes: key}
+	if options.Passphrase != "" {
+		if keyBlock, err = x509.EncryptPEMBlock(rand.Reader, "PRIVATE KEY", key, []byte(options.Passphrase), x509.PEMCipherAES256); err != nil {
+			return nil, err
+		}
+	}
+	if options.Format == keychain.ExportFormatPKCS8 {
+		return pem.EncodeToMemory(keyBlock), nil
+	}
+	return append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: found.certificate.Raw}), pem.EncodeToMemory(keyBlock)...), nil
+}
+
+// itemOf returns the item of an identity returned by the Fake
+func (fake *Fake) itemOf(identity *keychain.Identity) (item, error) {
+	h, err := identity.Handle()
+	if err != nil {
+		return item{}, err
+	}
+	found, ok := h.(*handle)
+	if !ok || found.fake != fake {
+		return item{}, errors.New("the identity was not returned by this fake keychain")
+	}
+	return found.item, nil
+}
@@ -0,0 +1,108 @@
+package keychaintest
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/keychain"
+	"github.com/bitrise-io/go-utils/pkcs12"
+	"github.com/stretchr/testify/require"
+)
+
+func fixturePEM(t *testing.T, fixture Fixture) []byte {
+	content, err := fixture.PEM()
+	require.NoError(t, err)
+	return content
+}
+
+func TestFakeSelectIdentities(t *testing.T) {
+	now := time.Now()
+	distribution := "Apple Distribution: Bitrise (ABCD123456)"
+	fake, err := New(
+		fixturePEM(t, Fixture{CommonName: distribution, TeamID: "ABCD123456", NotBefore: now.AddDate(-1, 0, 0), NotAfter: now.AddDate(0, 1, 0)}),
+		fixturePEM(t, Fixture{CommonName: distribution, TeamID: "ABCD123456", NotBefore: now.AddDate(-1, 0, 0), NotAfter: now.AddDate(1, 0, 0)}),
+		fixturePEM(t, Fixture{CommonName: distribution, TeamID: "ABCD123456", NotBefore: now.AddDate(-2, 0, 0), NotAfter: now.AddDate(0, 0, -1)}),
+		fixturePEM(t, Fixture{CommonName: "Apple Development: John Doe (ABCD123456)", TeamID: "ABCD123456", NotBefore: now.AddDate(-1, 0, 0), NotAfter: now.AddDate(1, 0, 0), WithoutPrivateKey: true}),
+		fixturePEM(t, Fixture{CommonName: "Apple Distribution: Other (XYZ9876543)", TeamID: "XYZ9876543", NotBefore: now.AddDate(-1, 0, 0), NotAfter: now.AddDate(1, 0, 0)}),
+	)
+	require.NoError(t, err)
+
+	identities, skipped, err := fake.SelectIdentities(context.Background(), keychain.Query{TeamIDs: []string{"ABCD123456"}})
+	require.NoError(t, err)
+	require.Len(t, identities, 1)
+	require.Equal(t, distribution, identities[0].Label)
+	require.Equal(t, now.AddDate(1, 0, 0).Year(), identities[0].NotAfter().Year())
+
+	var reasons []string
+	for _, s := range skipped {
+		reasons = append(reasons, s.Reason)
+	}
+	require.ElementsMatch(t, []string{
+		"missing private key",
+		"a later expiring identity with the same name is selected",
+		"expired on " + now.AddDate(0, 0, -1).Format("2006-01-02"),
+	}, reasons)
+
+	require.Equal(t, 1, fake.Open())
+	require.NoError(t, keychain.CloseAll(identities))
+	require.Equal(t, 0, fake.Open())
+
+	found, err := fake.FindIdentities(context.Background(), keychain.Query{Types: []string{"Apple Distribution"}})
+	require.NoError(t, err)
+	require.Len(t, found, 4)
+	require.NoError(t, keychain.CloseAll(found))
+	require.Equal(t, 0, fake.Open())
+}
+
+func TestFakeExport(t *testing.T) {
+	now := time.Now()
+	fake, err := New(fixturePEM(t, Fixture{CommonName: "Apple Distribution: Bitrise (ABCD123456)", TeamID: "ABCD123456", NotBefore: now.AddDate(-1, 0, 0), NotAfter: now.AddDate(1, 0, 0)}))
+	require.NoError(t, err)
+
+	identities, _, err := fake.SelectIdentities(context.Background(), keychain.Query{})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, keychain.CloseAll(identities)) }()
+
+	content, err := fake.Export(context.Background(), identities, keychain.ExportOptions{Passphrase: "secret"})
+	require.NoError(t, err)
+	_, certificate, err := pkcs12.Decode(content, "secret")
+	require.NoError(t, err)
+	require.Equal(t, identities[0].Certificate.Raw, certificate.Raw)
+
+	copied, err := fake.CopyCertificate(identities[0])
+	require.NoError(t, err)
+	require.Equal(t, identities[0].Certificate.Raw, copied.Raw)
+
+	contents, err := fake.ExportEach(context.Background(), identities, keychain.ExportOptions{Format: keychain.ExportFormatPEM})
+	require.NoError(t, err)
+	other, err := New(contents[0])
+	require.NoError(t, err)
+	reimported, _, err := other.SelectIdentities(context.Background(), keychain.Query{})
+	require.NoError(t, err)
+	require.Len(t, reimported, 1)
+	require.NoError(t, keychain.CloseAll(reimported))
+
+	require.NoError(t, identities[0].Close())
+	_, err = fake.CopyCertificate(identities[0])
+	require.Equal(t, keychain.ErrClosed, err)
+}
+
+func TestFakeExportTo(t *testing.T) {
+	now := time.Now()
+	fake, err := New(fixturePEM(t, Fixture{CommonName: "Apple Distribution: Bitrise (ABCD123456)", TeamID: "ABCD123456", NotBefore: now.AddDate(-1, 0, 0), NotAfter: now.AddDate(1, 0, 0)}))
+	require.NoError(t, err)
+
+	identities, _, err := fake.SelectIdentities(context.Background(), keychain.Query{})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, keychain.CloseAll(identities)) }()
+
+	var b bytes.Buffer
+	written, err := fake.ExportTo(context.Background(), &b, identities, keychain.ExportOptions{Passphrase: "secret"})
+	require.NoError(t, err)
+	require.Equal(t, int64(b.Len()), written)
+	_, certificate, err := pkcs12.Decode(b.Bytes(), "secret")
+	require.NoError(t, err)
+	require.Equal(t, identities[0].Certificate.Raw, certificate.Raw)
+}
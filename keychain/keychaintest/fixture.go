@@ -0,0 +1,57 @@
+package keychaintest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+)
+
+// Fixture is a self-signed identity generated for tests
+type Fixture struct {
+	CommonName string
+	TeamID     string
+	NotBefore  time.Time
+	NotAfter   time.Time
+	// WithoutPrivateKey leaves out the private key, as a certificate imported without it
+	WithoutPrivateKey bool
+}
+
+// PEM generates the certificate and the private key of the fixture, in the format read by AddPEM
+func (fixture Fixture) PEM() ([]byte, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: fixture.CommonName, OrganizationalUnit: []string{fixture.TeamID}},
+		NotBefore:    fixture.NotBefore,
+		NotAfter:     fixture.NotAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	content := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate})
+	if fixture.WithoutPrivateKey {
+		return content, nil
+	}
+
+	key, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return append(content, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key})...), nil
+}
@@ -0,0 +1,138 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/progress"
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// Level selects the messages printed
+type Level int
+
+const (
+	// LevelNormal prints every message but the debug ones
+	LevelNormal Level = iota
+	// LevelQuiet prints only the warnings and the errors
+	LevelQuiet
+	// LevelVerbose prints the debug messages too
+	LevelVerbose
+)
+
+// Format is the format of the printed messages
+type Format string
+
+const (
+	// FormatText prints the messages as they are, colored by their severity
+	FormatText Format = "text"
+	// FormatJSON prints every message as a single line JSON object with its time, severity and text
+	FormatJSON Format = "json"
+)
+
+// Config configures the log subsystem
+type Config struct {
+	Level  Level
+	Format Format
+	// Capture receives every message redacted and uncolored, the debug ones too, independently of the level
+	Capture io.Writer
+}
+
+// Entry is a message printed in the json format
+type Entry struct {
+	Time     string `json:"time"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// the colors of the go-utils log severities, see colorstring
+var severityColors = []struct {
+	color    string
+	severity string
+}{
+	{"\x1b[31;1m", "error"},
+	{"\x1b[33;1m", "warn"},
+	{"\x1b[34;1m", "info"},
+	{"\x1b[32;1m", "success"},
+	{"\x1b[35;1m", "debug"},
+}
+
+var colorPattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// Writer is the output of the go-utils log functions: it redacts, filters and formats every message.
+// The log package writes a message with a single Write call, so every Write is handled as one message.
+type Writer struct {
+	out    io.Writer
+	config Config
+	now    func() time.Time
+	mux    sync.Mutex
+}
+
+// NewWriter ...
+func NewWriter(out io.Writer, config Config) *Writer {
+	return &Writer{out: out, config: config, now: time.Now}
+}
+
+// Write prints the redacted message if its severity is enabled by the level
+func (w *Writer) Write(p []byte) (int, error) {
+	message := strings.TrimSuffix(string(p), "\n")
+	severity := severityOf(message)
+	quiet := w.config.Level == LevelQuiet && severity != "error" && severity != "warn"
+	debug := w.config.Level != LevelVerbose && severity == "debug"
+	if w.config.Capture == nil && (quiet || debug) {
+		return len(p), nil
+	}
+	message = Redact(message)
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if w.config.Capture != nil {
+		if _, err := fmt.Fprintln(w.config.Capture, colorPattern.ReplaceAllString(message, "")); err != nil {
+			return 0, err
+		}
+	}
+	if quiet || debug {
+		return len(p), nil
+	}
+
+	if w.config.Format != FormatJSON {
+		_, err := fmt.Fprintln(w.out, message)
+		return len(p), err
+	}
+
+	entry := Entry{
+		Time:     w.now().UTC().Format(time.RFC3339),
+		Severity: severity,
+		Message:  colorPattern.ReplaceAllString(message, ""),
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return 0, err
+	}
+	_, err = fmt.Fprintln(w.out, string(line))
+	return len(p), err
+}
+
+// severityOf returns the severity of a go-utils log message by its color, "normal" if it is not colored
+func severityOf(message string) string {
+	for _, sc := range severityColors {
+		if strings.HasPrefix(message, sc.color) {
+			return sc.severity
+		}
+	}
+	return "normal"
+}
+
+// Configure routes the go-utils log messages of the whole tool through a Writer to the standard output,
+// the messages are printed above the progress spinner
+func Configure(config Config) {
+	// the debug messages are captured even if they are not printed
+	log.SetEnableDebugLog(config.Level == LevelVerbose || config.Capture != nil)
+	log.SetOutWriter(NewWriter(progress.Stdout, config))
+}
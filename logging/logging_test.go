@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact(t *testing.T) {
+	AddSecret("s3cr3t-passphrase")
+	AddSecret("abc")
+
+	require.Equal(t, "using passphrase: [REDACTED]", Redact("using passphrase: s3cr3t-passphrase"))
+	require.Equal(t, "too short to redact: abc", Redact("too short to redact: abc"))
+
+	pem := "-----BEGIN CERTIFICATE-----\nMIIB\nAAAA\n-----END CERTIFICATE-----"
+	require.Equal(t, "cert: -----BEGIN CERTIFICATE-----[REDACTED]-----END CERTIFICATE-----", Redact("cert: "+pem))
+
+	body := strings.Repeat("MIIFa2A", 12) + "=="
+	require.Equal(t, "content: [REDACTED]", Redact("content: "+body))
+
+	fingerprint := strings.Repeat("9b1d5a2b", 8)
+	require.Equal(t, "fingerprint: "+fingerprint, Redact("fingerprint: "+fingerprint))
+	pth := "/Users/runner/" + strings.Repeat("Library/", 12)
+	require.Equal(t, pth, Redact(pth))
+}
+
+func TestWriter(t *testing.T) {
+	messages := []string{
+		colorstring.Yellowf("careful"),
+		colorstring.Bluef("exporting"),
+		"plain",
+		colorstring.Redf("failed"),
+	}
+	write := func(config Config) string {
+		var out bytes.Buffer
+		w := NewWriter(&out, config)
+		w.now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+		for _, message := range messages {
+			_, err := w.Write([]byte(message + "\n"))
+			require.NoError(t, err)
+		}
+		return out.String()
+	}
+
+	require.Equal(t, strings.Join(messages, "\n")+"\n", write(Config{}))
+	require.Equal(t, messages[0]+"\n"+messages[3]+"\n", write(Config{Level: LevelQuiet}))
+	require.Equal(t, `{"time":"2020-01-02T03:04:05Z","severity":"warn","message":"careful"}
+{"time":"2020-01-02T03:04:05Z","severity":"info","message":"exporting"}
+{"time":"2020-01-02T03:04:05Z","severity":"normal","message":"plain"}
+{"time":"2020-01-02T03:04:05Z","severity":"error","message":"failed"}
+`, write(Config{Format: FormatJSON}))
+}
+
+func TestWriterCapture(t *testing.T) {
+	var out, capture bytes.Buffer
+	w := NewWriter(&out, Config{Level: LevelQuiet, Capture: &capture})
+	for _, message := range []string{colorstring.Magentaf("debug details"), colorstring.Bluef("exporting"), colorstring.Redf("failed")} {
+		_, err := w.Write([]byte(message + "\n"))
+		require.NoError(t, err)
+	}
+	require.Equal(t, colorstring.Redf("failed")+"\n", out.String())
+	require.Equal(t, "debug details\nexporting\nfailed\n", capture.String())
+}
@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces the sensitive values in the log messages
+const Redacted = "[REDACTED]"
+
+// minSecretLength avoids redacting every occurrence of a too short value, e.g. a single character passphrase
+const minSecretLength = 4
+
+var (
+	secretsMux sync.Mutex
+	secrets    []string
+)
+
+var (
+	// pemPattern matches the PEM blocks, e.g. certificates and private keys, the block type is kept
+	pemPattern = regexp.MustCompile(`(?s)(-----BEGIN ([A-Z0-9 ]+)-----).*?(-----END ([A-Z0-9 ]+)-----)`)
+	// base64Pattern matches the long base64 runs, e.g. certificate bodies, .p12 and profile contents,
+	// it is longer than the hex SHA-256 fingerprints
+	base64Pattern = regexp.MustCompile(`[A-Za-z0-9+/]{80,}={0,2}`)
+)
+
+// AddSecret registers a value to be redacted from every log message, e.g. a passphrase or an API token
+func AddSecret(secret string) {
+	if len(secret) < minSecretLength {
+		return
+	}
+
+	secretsMux.Lock()
+	defer secretsMux.Unlock()
+	for _, s := range secrets {
+		if s == secret {
+			return
+		}
+	}
+	secrets = append(secrets, secret)
+	// the longer secrets first, so a secret containing another one is replaced as a whole
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+}
+
+// Redact replaces the registered secrets, the PEM blocks and the long base64 runs of the message
+func Redact(message string) string {
+	secretsMux.Lock()
+	for _, secret := range secrets {
+		message = strings.Replace(message, secret, Redacted, -1)
+	}
+	secretsMux.Unlock()
+
+	message = pemPattern.ReplaceAllString(message, "$1"+Redacted+"$3")
+	return base64Pattern.ReplaceAllStringFunc(message, func(run string) string {
+		if isBase64Data(run) {
+			return Redacted
+		}
+		return run
+	})
+}
+
+// isBase64Data tells the encoded data from the long paths and identifiers, the data has digits and mixed case letters
+func isBase64Data(run string) bool {
+	return strings.ContainsAny(run, "0123456789") &&
+		strings.ContainsAny(run, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") &&
+		strings.ContainsAny(run, "abcdefghijklmnopqrstuvwxyz")
+}
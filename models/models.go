@@ -1,6 +1,8 @@
 package models
 
 import (
+	"crypto/x509"
+
 	"github.com/bitrise-io/go-xcode/certificateutil"
 	"github.com/bitrise-io/go-xcode/profileutil"
 )
@@ -9,6 +11,17 @@ import (
 type Certificates struct {
 	Info    []certificateutil.CertificateInfoModel
 	Content []byte
+	// PEM is the PEM armoured export of the same identities, if it was requested
+	PEM []byte
+	// Each contains a separate .p12 export of every identity in the order of Info, if it was requested
+	Each [][]byte
+	// Keys contains the passphrase encrypted PKCS#8 PEM private key of every identity in the order of Info, if the separate keys were requested
+	Keys [][]byte
+	// Intermediates are the issuer certificates (e.g. Apple WWDR G3) of the identities, if the chain was requested
+	Intermediates []x509.Certificate
+	// CertificateOnly are the certificates of the identities with the private key on a hardware token (e.g. a smart card),
+	// exported without the private key
+	CertificateOnly []certificateutil.CertificateInfoModel
 }
 
 // ProvisioningProfile contains parsed data in the provisioning profile and the original profile file contents
@@ -0,0 +1,169 @@
+package network
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const checkTimeout = 15 * time.Second
+
+// Endpoint is a service codesigndoc connects to
+type Endpoint struct {
+	Name string
+	URL  string
+}
+
+// HopResult is the result of a single step of connecting to an endpoint
+type HopResult struct {
+	Hop      string
+	Detail   string
+	Duration time.Duration
+	Err      error
+}
+
+// Check connects to the endpoint hop by hop (DNS, TCP, proxy CONNECT, TLS handshake, HTTP request)
+// using the given configuration, and stops at the first failing hop.
+func Check(endpoint Endpoint, cfg Config) []HopResult {
+	var results []HopResult
+	fail := func(hop string, err error) []HopResult {
+		return append(results, HopResult{Hop: hop, Err: DescribeError(err)})
+	}
+
+	target, err := url.Parse(endpoint.URL)
+	if err != nil || target.Host == "" {
+		return fail("url", fmt.Errorf("invalid endpoint URL (%s)", endpoint.URL))
+	}
+	targetAddr := hostPort(target)
+
+	transport, err := NewTransport(cfg)
+	if err != nil {
+		return fail("configuration", err)
+	}
+
+	request, err := http.NewRequest(http.MethodGet, target.String(), nil)
+	if err != nil {
+		return fail("url", err)
+	}
+	proxyURL, err := transport.Proxy(request)
+	if err != nil {
+		return fail("proxy", err)
+	}
+
+	dialAddr := targetAddr
+	if proxyURL != nil {
+		dialAddr = hostPort(proxyURL)
+		results = append(results, HopResult{Hop: "proxy", Detail: fmt.Sprintf("%s://%s", proxyURL.Scheme, proxyURL.Host)})
+	} else {
+		results = append(results, HopResult{Hop: "proxy", Detail: "none, connecting directly"})
+	}
+
+	// DNS
+	host, _, _ := net.SplitHostPort(dialAddr)
+	start := time.Now()
+	addrs, err := net.LookupHost(host)
+	if err != nil {
+		return fail("dns", fmt.Errorf("failed to resolve %s, error: %s", host, err))
+	}
+	results = append(results, HopResult{Hop: "dns", Detail: fmt.Sprintf("%s -> %v", host, addrs), Duration: time.Since(start)})
+
+	// TCP
+	start = time.Now()
+	conn, err := net.DialTimeout("tcp", dialAddr, checkTimeout)
+	if err != nil {
+		return fail("tcp", fmt.Errorf("failed to connect to %s, error: %s", dialAddr, err))
+	}
+	defer conn.Close()
+	results = append(results, HopResult{Hop: "tcp", Detail: dialAddr, Duration: time.Since(start)})
+
+	if target.Scheme == "https" {
+		if proxyURL != nil && proxyURL.Scheme == "http" {
+			// proxy CONNECT
+			start = time.Now()
+			if err := connectThroughProxy(conn, proxyURL, targetAddr); err != nil {
+				return fail("proxy connect", err)
+			}
+			results = append(results, HopResult{Hop: "proxy connect", Detail: targetAddr, Duration: time.Since(start)})
+		}
+
+		// TLS, checked separately only on direct and plain HTTP proxy connections
+		if proxyURL == nil || proxyURL.Scheme == "http" {
+			tlsConfig := transport.TLSClientConfig.Clone()
+			tlsConfig.ServerName = target.Hostname()
+
+			start = time.Now()
+			tlsConn := tls.Client(conn, tlsConfig)
+			if err := tlsConn.SetDeadline(time.Now().Add(checkTimeout)); err != nil {
+				return fail("tls", err)
+			}
+			if err := tlsConn.Handshake(); err != nil {
+				return fail("tls", fmt.Errorf("TLS handshake with %s failed, error: %w", target.Hostname(), err))
+			}
+			state := tlsConn.ConnectionState()
+			detail := ""
+			if len(state.PeerCertificates) > 0 {
+				certificate := state.PeerCertificates[0]
+				detail = fmt.Sprintf("server: %s, issuer: %s, pin: %s", certificate.Subject.CommonName, certificate.Issuer.CommonName, PublicKeyPin(certificate))
+			}
+			results = append(results, HopResult{Hop: "tls", Detail: detail, Duration: time.Since(start)})
+		}
+	}
+
+	// HTTP
+	client := http.Client{Transport: transport, Timeout: checkTimeout}
+	start = time.Now()
+	response, err := client.Do(request)
+	if err != nil {
+		return fail("http", err)
+	}
+	if err := response.Body.Close(); err != nil {
+		return fail("http", err)
+	}
+	// any response means the endpoint is reachable, the requests are not authenticated
+	return append(results, HopResult{Hop: "http", Detail: response.Status, Duration: time.Since(start)})
+}
+
+func connectThroughProxy(conn net.Conn, proxyURL *url.URL, targetAddr string) error {
+	if err := conn.SetDeadline(time.Now().Add(checkTimeout)); err != nil {
+		return err
+	}
+
+	connectRequest := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", targetAddr, targetAddr)
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		connectRequest += "Proxy-Authorization: Basic " + credentials + "\r\n"
+	}
+	if _, err := conn.Write([]byte(connectRequest + "\r\n")); err != nil {
+		return fmt.Errorf("failed to send CONNECT to the proxy, error: %s", err)
+	}
+
+	response, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return fmt.Errorf("failed to read the proxy CONNECT response, error: %s", err)
+	}
+	switch response.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusProxyAuthRequired:
+		return fmt.Errorf("the proxy requires authentication (%s), provide the credentials in the proxy URL", response.Status)
+	default:
+		return fmt.Errorf("the proxy refused to connect to %s: %s", targetAddr, response.Status)
+	}
+}
+
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	port := "80"
+	if u.Scheme == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
@@ -0,0 +1,204 @@
+//go:build darwin
+// +build darwin
+
+package osxkeychain
+
+import (
+	"bytes"
+	"unsafe"
+
+	"github.com/bitrise-io/go-utils/sliceutil"
+)
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <stdlib.h>
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+*/
+import "C"
+
+// InspectKeyAccess reads which applications are allowed to sign with the private key of the identity
+// by the access control list of the key, other applications trigger a Keychain prompt.
+func InspectKeyAccess(identity IdentityWithRefModel) (KeyAccess, error) {
+	var access KeyAccess
+	err := withSigningACLs(identity.KeychainRef, func(_ C.SecKeyRef, _ C.SecAccessRef, acls []C.SecACLRef) error {
+		for _, acl := range acls {
+			contents, err := copyACLContents(acl)
+			if err != nil {
+				return err
+			}
+			if contents.applications == 0 {
+				access.AnyApplication = true
+			}
+			pths, err := contents.applicationPaths()
+			contents.release()
+			if err != nil {
+				return err
+			}
+			for _, pth := range pths {
+				if !sliceutil.IsStringInSlice(pth, access.Applications) {
+					access.Applications = append(access.Applications, pth)
+				}
+			}
+		}
+		return nil
+	})
+	return access, err
+}
+
+// AllowApplications adds the applications to the access control list of the identity's private key,
+// so they can sign with (and export) the key without a Keychain prompt.
+// Changing the access of the key shows a Keychain prompt for the keychain password itself.
+func AllowApplications(identity IdentityWithRefModel, pths []string) error {
+	return withSigningACLs(identity.KeychainRef, func(key C.SecKeyRef, access C.SecAccessRef, acls []C.SecACLRef) error {
+		changed := false
+		for _, acl := range acls {
+			added, err := addApplications(acl, pths)
+			if err != nil {
+				return err
+			}
+			changed = changed || added
+		}
+		if !changed {
+			return nil
+		}
+		if status := C.SecKeychainItemSetAccess(C.SecKeychainItemRef(key), access); status != C.errSecSuccess {
+			return newStatusError("SecKeychainItemSetAccess", status)
+		}
+		return nil
+	})
+}
+
+// addApplications appends the missing applications to the entry, returns false if the entry allows every application or all of them already
+func addApplications(acl C.SecACLRef, pths []string) (bool, error) {
+	contents, err := copyACLContents(acl)
+	if err != nil {
+		return false, err
+	}
+	defer contents.release()
+	if contents.applications == 0 {
+		return false, nil
+	}
+	allowed, err := contents.applicationPaths()
+	if err != nil {
+		return false, err
+	}
+
+	updated := C.CFArrayCreateMutableCopy(C.kCFAllocatorDefault, 0, contents.applications)
+	defer C.CFRelease(C.CFTypeRef(updated))
+	added := false
+	for _, pth := range pths {
+		if sliceutil.IsStringInSlice(pth, allowed) {
+			continue
+		}
+		pathCString := C.CString(pth)
+		var application C.SecTrustedApplicationRef
+		status := C.SecTrustedApplicationCreateFromPath(pathCString, &application)
+		C.free(unsafe.Pointer(pathCString))
+		if status != C.errSecSuccess {
+			return false, newStatusError("SecTrustedApplicationCreateFromPath", status)
+		}
+		C.CFArrayAppendValue(updated, unsafe.Pointer(application))
+		C.CFRelease(C.CFTypeRef(application))
+		added = true
+	}
+	if !added {
+		return false, nil
+	}
+	if status := C.SecACLSetContents(acl, C.CFArrayRef(updated), contents.description, contents.selector); status != C.errSecSuccess {
+		return false, newStatusError("SecACLSetContents", status)
+	}
+	return true, nil
+}
+
+// withSigningACLs calls fn with the private key of the identity, its access and the entries of the access authorizing signing,
+// the references are released when fn returns
+func withSigningACLs(identityRef C.CFTypeRef, fn func(key C.SecKeyRef, access C.SecAccessRef, acls []C.SecACLRef) error) error {
+	key, err := copyPrivateKey(identityRef)
+	if err != nil {
+		return err
+	}
+	defer C.CFRelease(C.CFTypeRef(key))
+
+	var access C.SecAccessRef
+	if status := C.SecKeychainItemCopyAccess(C.SecKeychainItemRef(key), &access); status != C.errSecSuccess {
+		return newStatusError("SecKeychainItemCopyAccess", status)
+	}
+	defer C.CFRelease(C.CFTypeRef(access))
+
+	var aclList C.CFArrayRef
+	if status := C.SecAccessCopyACLList(access, &aclList); status != C.errSecSuccess {
+		return newStatusError("SecAccessCopyACLList", status)
+	}
+	defer C.CFRelease(C.CFTypeRef(aclList))
+
+	var acls []C.SecACLRef
+	for i := C.CFIndex(0); i < C.CFArrayGetCount(aclList); i++ {
+		acl := C.SecACLRef(C.CFArrayGetValueAtIndex(aclList, i))
+		if authorizesSigning(acl) {
+			acls = append(acls, acl)
+		}
+	}
+	return fn(key, access, acls)
+}
+
+// authorizesSigning returns true if the access control entry covers signing with the key
+func authorizesSigning(acl C.SecACLRef) bool {
+	authorizations := C.SecACLCopyAuthorizations(acl)
+	if authorizations == 0 {
+		return false
+	}
+	defer C.CFRelease(C.CFTypeRef(authorizations))
+
+	for i := C.CFIndex(0); i < C.CFArrayGetCount(authorizations); i++ {
+		if C.CFEqual(C.CFTypeRef(C.CFArrayGetValueAtIndex(authorizations, i)), C.CFTypeRef(C.kSecACLAuthorizationSign)) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// aclContents is the content of an access control entry, it has to be released
+type aclContents struct {
+	// applications is 0 if every application is allowed
+	applications C.CFArrayRef
+	description  C.CFStringRef
+	selector     C.SecKeychainPromptSelector
+}
+
+func copyACLContents(acl C.SecACLRef) (aclContents, error) {
+	var contents aclContents
+	if status := C.SecACLCopyContents(acl, &contents.applications, &contents.description, &contents.selector); status != C.errSecSuccess {
+		return aclContents{}, newStatusError("SecACLCopyContents", status)
+	}
+	return contents, nil
+}
+
+func (contents aclContents) release() {
+	if contents.applications != 0 {
+		C.CFRelease(C.CFTypeRef(contents.applications))
+	}
+	if contents.description != 0 {
+		C.CFRelease(C.CFTypeRef(contents.description))
+	}
+}
+
+// applicationPaths returns the paths of the trusted applications of the entry
+func (contents aclContents) applicationPaths() ([]string, error) {
+	if contents.applications == 0 {
+		return nil, nil
+	}
+	var pths []string
+	for i := C.CFIndex(0); i < C.CFArrayGetCount(contents.applications); i++ {
+		application := C.SecTrustedApplicationRef(C.CFArrayGetValueAtIndex(contents.applications, i))
+		var data C.CFDataRef
+		if status := C.SecTrustedApplicationCopyData(application, &data); status != C.errSecSuccess {
+			return nil, newStatusError("SecTrustedApplicationCopyData", status)
+		}
+		pth := string(bytes.TrimRight(convertCFDataRefToGoBytes(data), "\x00"))
+		C.CFRelease(C.CFTypeRef(data))
+		pths = append(pths, pth)
+	}
+	return pths, nil
+}
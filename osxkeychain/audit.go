@@ -0,0 +1,137 @@
+//go:build darwin
+// +build darwin
+
+package osxkeychain
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+*/
+import "C"
+
+// auditKindIdentity and auditKindCertificate are the kinds of the audited references
+const (
+	auditKindIdentity    = "identity"
+	auditKindCertificate = "certificate"
+)
+
+type auditSite struct {
+	kind string
+	site string
+}
+
+type auditTracker struct {
+	retained  int
+	released  int
+	finalized int
+	sites     map[C.CFTypeRef][]auditSite
+	mux       sync.Mutex
+}
+
+var tracker = auditTracker{sites: map[C.CFTypeRef][]auditSite{}}
+
+// auditRetain records a retain of the reference of the kind, and the first caller outside of this package
+func auditRetain(ref C.CFTypeRef, kind string) {
+	if !Audit {
+		return
+	}
+	site := callSite()
+
+	tracker.mux.Lock()
+	defer tracker.mux.Unlock()
+	tracker.retained++
+	tracker.sites[ref] = append(tracker.sites[ref], auditSite{kind: kind, site: site})
+}
+
+// callSite returns the file and line of the first caller outside of this package
+func callSite() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.Function, "/osxkeychain.") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// auditRelease records a release of the reference, the references not retained by auditRetain are ignored
+func auditRelease(ref C.CFTypeRef, finalized bool) {
+	if !Audit {
+		return
+	}
+
+	tracker.mux.Lock()
+	defer tracker.mux.Unlock()
+	sites := tracker.sites[ref]
+	if len(sites) == 0 {
+		return
+	}
+	tracker.released++
+	if finalized {
+		tracker.finalized++
+	}
+	if len(sites) == 1 {
+		delete(tracker.sites, ref)
+	} else {
+		tracker.sites[ref] = sites[:len(sites)-1]
+	}
+}
+
+// Report returns the current state of the CF object audit
+func Report() AuditReport {
+	tracker.mux.Lock()
+	defer tracker.mux.Unlock()
+
+	report := AuditReport{Retained: tracker.retained, Released: tracker.released, Finalized: tracker.finalized}
+	counts := map[auditSite]int{}
+	for _, sites := range tracker.sites {
+		for _, site := range sites {
+			counts[site]++
+		}
+	}
+	for site, count := range counts {
+		report.Leaks = append(report.Leaks, AuditLeak{Kind: site.kind, Site: site.site, Count: count})
+	}
+	sort.Slice(report.Leaks, func(i, j int) bool {
+		if report.Leaks[i].Site != report.Leaks[j].Site {
+			return report.Leaks[i].Site < report.Leaks[j].Site
+		}
+		return report.Leaks[i].Kind < report.Leaks[j].Kind
+	})
+	return report
+}
+
+// ReportLeaks logs the summary of the CF object audit and the leaked references, if the audit is enabled
+func ReportLeaks() {
+	if !Audit {
+		return
+	}
+	report := Report()
+
+	fmt.Println()
+	log.Infof("CF object audit: %d retained, %d released (%d by finalizer)", report.Retained, report.Released, report.Finalized)
+	if report.Finalized > 0 {
+		log.Warnf("%d identity reference(s) were not released by their owner, only by the garbage collector", report.Finalized)
+	}
+	for _, leak := range report.Leaks {
+		log.Warnf("Leaked %d %s reference(s) retained at: %s", leak.Count, leak.Kind, leak.Site)
+	}
+	if len(report.Leaks) == 0 {
+		log.Donef("No leaked identity or certificate reference")
+	}
+}
@@ -0,0 +1,127 @@
+//go:build darwin
+// +build darwin
+
+package osxkeychain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+*/
+import "C"
+
+// identityCache keeps the found identities of the queries for the run, so the repeated scans (e.g. the Xcode and the Xamarin flow)
+// do not query the keychains again. The cache holds its own reference of every identity, a hit returns newly retained ones.
+type identityCache struct {
+	entries map[string][]IdentityWithRefModel
+	// generation is increased by every invalidation, a query started before it is not stored
+	generation int
+	mux        sync.Mutex
+}
+
+var cache = identityCache{entries: map[string][]IdentityWithRefModel{}}
+
+// InvalidateIdentityCache drops the cached identities, the next queries read the keychains again.
+// The functions of this package changing the keychains or the search list invalidate the cache.
+func InvalidateIdentityCache() {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+	for _, identities := range cache.entries {
+		for _, identity := range identities {
+			C.CFRelease(identity.KeychainRef)
+		}
+	}
+	cache.entries = map[string][]IdentityWithRefModel{}
+	cache.generation++
+}
+
+// cachedFindIdentities returns the cached identities of the query, or finds and caches them,
+// the returned identities have to be released by the caller as the ones of findIdentities
+func cachedFindIdentities(query IdentityQuery, searchList C.CFArrayRef, find func() ([]IdentityWithRefModel, error)) ([]IdentityWithRefModel, error) {
+	key, err := identityCacheKey(query, searchList)
+	if err != nil {
+		log.Debugf("Identity cache skipped: %s", err)
+		return find()
+	}
+
+	cache.mux.Lock()
+	cached, ok := cache.entries[key]
+	generation := cache.generation
+	if ok {
+		cached = retainIdentities(cached, true)
+	}
+	cache.mux.Unlock()
+	if ok {
+		log.Debugf("Identity cache hit: %d identities", len(cached))
+		return cached, nil
+	}
+
+	identities, err := find()
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+	if _, ok := cache.entries[key]; !ok && generation == cache.generation {
+		cache.entries[key] = retainIdentities(identities, false)
+	}
+	return identities, nil
+}
+
+// retainIdentities returns a copy of the identities with retained references, audited if they are handed to the caller
+func retainIdentities(identities []IdentityWithRefModel, audit bool) []IdentityWithRefModel {
+	retained := make([]IdentityWithRefModel, len(identities))
+	for i, identity := range identities {
+		retained[i] = identity
+		C.CFRetain(identity.KeychainRef)
+		if audit {
+			auditRetain(identity.KeychainRef, auditKindIdentity)
+		}
+	}
+	return retained
+}
+
+// identityCacheKey returns the key of the query in the keychains of the search list, or in the default search list if it is 0
+func identityCacheKey(query IdentityQuery, searchList C.CFArrayRef) (string, error) {
+	var keychains []string
+	if searchList != 0 {
+		for i := C.CFIndex(0); i < C.CFArrayGetCount(searchList); i++ {
+			pth, err := keychainRefPath(C.SecKeychainRef(C.CFArrayGetValueAtIndex(searchList, i)))
+			if err != nil {
+				return "", err
+			}
+			keychains = append(keychains, pth)
+		}
+	}
+
+	var types []string
+	for _, t := range query.Types {
+		types = append(types, string(t))
+	}
+	parts := []string{
+		strings.Join(keychains, ","),
+		sortedJoin(query.Labels),
+		sortedJoin(query.TeamIDs),
+		sortedJoin(types),
+		sortedJoin(query.Fingerprints),
+		sortedJoin(query.Keychains),
+		fmt.Sprintf("%t", query.IncludeExpired),
+	}
+	return strings.Join(parts, "|"), nil
+}
+
+func sortedJoin(values []string) string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return fmt.Sprintf("%q", sorted)
+}
@@ -0,0 +1,124 @@
+//go:build darwin
+// +build darwin
+
+package osxkeychain
+
+import (
+	"context"
+	"fmt"
+)
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+*/
+import "C"
+
+// withContext runs the Security framework call in the background until it returns or the context is done.
+// The calls can not be interrupted, e.g. while a Keychain prompt is shown, so if the context is done first
+// the call is abandoned and cleanup frees its results once it returns.
+func withContext(ctx context.Context, call func(), cleanup func()) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("keychain operation canceled: %w", err)
+	}
+	return inBackground(ctx, call, cleanup)
+}
+
+// withRetainedRefs is withContext for the calls using the caller's references: they are retained before the call starts
+// and released once it returns, so the caller releasing them after an abandoned call does not free them while they are still used.
+func withRetainedRefs(ctx context.Context, refs []C.CFTypeRef, call func(), cleanup func()) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("keychain operation canceled: %w", err)
+	}
+
+	refs = append([]C.CFTypeRef{}, refs...)
+	for _, ref := range refs {
+		C.CFRetain(ref)
+	}
+	return inBackground(ctx, func() {
+		defer func() {
+			for _, ref := range refs {
+				C.CFRelease(ref)
+			}
+		}()
+		call()
+	}, cleanup)
+}
+
+func inBackground(ctx context.Context, call func(), cleanup func()) error {
+	done := make(chan struct{})
+	go func() {
+		call()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		go func() {
+			<-done
+			cleanup()
+		}()
+		return fmt.Errorf("keychain operation canceled: %w", ctx.Err())
+	}
+}
+
+// FindIdentityContext is FindIdentity, returning with the context's error if it is done before the search finishes
+//  IMPORTANT: you have to C.CFRelease the returned items (one-by-one)!!
+func FindIdentityContext(ctx context.Context, identityLabel string) ([]IdentityWithRefModel, error) {
+	var identities []IdentityWithRefModel
+	var err error
+	if ctxErr := withContext(ctx, func() {
+		identities, err = FindIdentity(identityLabel)
+	}, func() {
+		ReleaseIdentityWithRefList(identities)
+	}); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return identities, err
+}
+
+// SelectIdentitiesContext is SelectIdentities, returning with the context's error if it is done before the search finishes
+//  IMPORTANT: you have to C.CFRelease the selected items (one-by-one)!!
+func SelectIdentitiesContext(ctx context.Context, query IdentityQuery) (IdentitySelection, error) {
+	var selection IdentitySelection
+	var err error
+	if ctxErr := withContext(ctx, func() {
+		selection, err = SelectIdentities(query)
+	}, func() {
+		for _, identity := range selection.Selected {
+			ReleaseRef(identity.KeychainRef)
+		}
+	}); ctxErr != nil {
+		return IdentitySelection{}, ctxErr
+	}
+	return selection, err
+}
+
+// ExportFromKeychainInFormatContext is ExportFromKeychainInFormat, returning with the context's error
+// if it is done before the export finishes, e.g. while a Keychain prompt is waiting for an answer.
+func ExportFromKeychainInFormatContext(ctx context.Context, itemRefsToExport []C.CFTypeRef, format ExportFormat, isAskForPassword bool, passphrase string) ([]byte, error) {
+	var exported []byte
+	var err error
+	if ctxErr := withRetainedRefs(ctx, itemRefsToExport, func() {
+		exported, err = ExportFromKeychainInFormat(itemRefsToExport, format, isAskForPassword, passphrase)
+	}, func() {}); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return exported, err
+}
+
+// ExportEachFromKeychainContext is ExportEachFromKeychain, the context is checked before every item
+func ExportEachFromKeychainContext(ctx context.Context, itemRefsToExport []C.CFTypeRef, format ExportFormat, isAskForPassword bool, passphrase string) ([][]byte, error) {
+	var exported [][]byte
+	for i, itemRef := range itemRefsToExport {
+		content, err := ExportFromKeychainInFormatContext(ctx, []C.CFTypeRef{itemRef}, format, isAskForPassword, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export item #%d, error: %w", i+1, err)
+		}
+		exported = append(exported, content)
+	}
+	return exported, nil
+}
@@ -0,0 +1,108 @@
+package osxkeychain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/pathutil"
+)
+
+// KeychainSettings are the lock settings of a keychain
+type KeychainSettings struct {
+	// LockTimeout locks the keychain after the inactivity time, 0 never locks it
+	LockTimeout time.Duration
+	LockOnSleep bool
+}
+
+// args returns the security set-keychain-settings options of the settings
+func (settings KeychainSettings) args() []string {
+	var args []string
+	if settings.LockTimeout > 0 {
+		args = append(args, "-u", "-t", strconv.Itoa(int(settings.LockTimeout/time.Second)))
+	}
+	if settings.LockOnSleep {
+		args = append(args, "-l")
+	}
+	return args
+}
+
+// UserKeychainPath returns the path of the user's keychain with the name, as created by security create-keychain <name>
+func UserKeychainPath(name string) string {
+	return filepath.Join(pathutil.UserHomeDir(), "Library", "Keychains", name+".keychain-db")
+}
+
+// CreateKeychain creates the keychain file with the password and the lock settings, the new keychain is unlocked.
+// It is not added to the search list.
+func CreateKeychain(keychainPath, password string, settings KeychainSettings) error {
+	if err := securityWithStdin("create-keychain", "-p", password, keychainPath); err != nil {
+		return err
+	}
+	if err := security(append(append([]string{"set-keychain-settings"}, settings.args()...), keychainPath)...); err != nil {
+		return err
+	}
+	return UnlockKeychain(keychainPath, password)
+}
+
+// DeleteKeychain deletes the keychain file, and removes it from the search list
+func DeleteKeychain(keychainPath string) error {
+	defer InvalidateIdentityCache()
+	return security("delete-keychain", keychainPath)
+}
+
+func security(args ...string) error {
+	if err := CheckPlatform(); err != nil {
+		return err
+	}
+	if out, err := command.New("security", args...).RunAndReturnTrimmedCombinedOutput(); err != nil {
+		// the arguments are not printed, they contain the passwords
+		return fmt.Errorf("security %s failed, output: %s, error: %s", args[0], out, err)
+	}
+	return nil
+}
+
+// securityWithStdin runs the security command in interactive mode (security -i) with the command written to its stdin,
+// so the passwords of the arguments are not visible in the process list
+func securityWithStdin(args ...string) error {
+	if err := CheckPlatform(); err != nil {
+		return err
+	}
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteSecurityArg(arg)
+	}
+
+	var out, errOut bytes.Buffer
+	cmd := command.New("security", "-i").SetStdin(strings.NewReader(strings.Join(quoted, " ") + "\n")).SetStdout(&out).SetStderr(&errOut)
+	err := cmd.Run()
+	if err == nil {
+		// security -i does not fail on the failed commands, only prints their error
+		err = interactiveSecurityError(errOut.String())
+	}
+	if err != nil {
+		// the arguments are not printed, they contain the passwords
+		return fmt.Errorf("security %s failed, output: %s, error: %s", args[0], strings.TrimSpace(errOut.String()), err)
+	}
+	return nil
+}
+
+// interactiveSecurityError returns the error printed by security -i to its stderr (security: <function>: <message>)
+func interactiveSecurityError(stderr string) error {
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "security: ") {
+			return errors.New(strings.TrimPrefix(line, "security: "))
+		}
+	}
+	return nil
+}
+
+// quoteSecurityArg quotes the argument for the command line of security -i, which splits the line at the unquoted whitespaces
+func quoteSecurityArg(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
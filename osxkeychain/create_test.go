@@ -0,0 +1,64 @@
+package osxkeychain
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInteractiveSecurityError(t *testing.T) {
+	tests := []struct {
+		name    string
+		stderr  string
+		wantErr string
+	}{
+		{name: "no output"},
+		{name: "warning only", stderr: "Warning: the keychain is not in the search list\n"},
+		{
+			name:    "failed command",
+			stderr:  "security: SecKeychainUnlock /tmp/test.keychain-db: The user name or passphrase you entered is not correct.\n",
+			wantErr: "SecKeychainUnlock /tmp/test.keychain-db: The user name or passphrase you entered is not correct.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := interactiveSecurityError(tt.stderr)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestQuoteSecurityArg(t *testing.T) {
+	require.Equal(t, `"pass word"`, quoteSecurityArg("pass word"))
+	require.Equal(t, `"a\"b\\c"`, quoteSecurityArg(`a"b\c`))
+}
+
+func TestSecurityWithStdin(t *testing.T) {
+	if runtime.GOOS != "darwin" {
+		t.Skip("security is only available on macOS")
+	}
+
+	tmpDir, err := ioutil.TempDir("", "osxkeychain")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tmpDir))
+	}()
+
+	keychainPath := filepath.Join(tmpDir, "test.keychain-db")
+	require.NoError(t, CreateKeychain(keychainPath, `pass "word`, KeychainSettings{}))
+	defer func() {
+		require.NoError(t, DeleteKeychain(keychainPath))
+	}()
+
+	require.NoError(t, securityWithStdin("lock-keychain", keychainPath))
+	require.Error(t, securityWithStdin("unlock-keychain", "-p", "wrong", keychainPath))
+	require.NoError(t, securityWithStdin("unlock-keychain", "-p", `pass "word`, keychainPath))
+}
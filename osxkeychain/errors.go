@@ -0,0 +1,88 @@
+package osxkeychain
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+)
+
+// ErrUnsupportedPlatform is returned by the keychain operations on the platforms without the macOS Security framework,
+// the profile, report and upload features work on every platform
+var ErrUnsupportedPlatform = errors.New("the keychain is only available on macOS")
+
+// CheckPlatform returns ErrUnsupportedPlatform if the keychain is not available on the platform
+func CheckPlatform() error {
+	if runtime.GOOS != "darwin" {
+		return ErrUnsupportedPlatform
+	}
+	return nil
+}
+
+// NotExportableError lists the identities with a private key which can not be exported from the Keychain
+type NotExportableError struct {
+	// Reasons maps the identity labels to the reason
+	Reasons map[string]string
+	// HardwareBound maps the labels of the identities with the private key on a hardware token
+	// (e.g. a smart card or a YubiKey, through CryptoTokenKit) to the token ID
+	HardwareBound map[string]string
+}
+
+// HardwareBoundOnly reports whether every identity of the error is bound to a hardware token,
+// so the certificates of the identities can still be exported without their private keys
+func (err NotExportableError) HardwareBoundOnly() bool {
+	return len(err.Reasons) > 0 && len(err.HardwareBound) == len(err.Reasons)
+}
+
+func (err NotExportableError) Error() string {
+	var labels []string
+	for label := range err.Reasons {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	msg := "the private key of the following identities can not be exported:"
+	for _, label := range labels {
+		msg += fmt.Sprintf("\n- %s: %s", label, err.Reasons[label])
+	}
+	if len(err.HardwareBound) > 0 {
+		msg += "\nthe private key of a hardware token never leaves the token, sign with the token plugged in, or export the certificates only"
+	}
+	return msg + "\nexport the identities on the Mac where they were created (without the Secure Enclave), or create new ones"
+}
+
+// The common failure causes of the Security framework calls, a StatusError matches them with errors.Is
+var (
+	ErrUserCanceled          = errors.New("the operation was canceled by the user")
+	ErrAuthFailed            = errors.New("the authorization failed, e.g. a wrong password was given")
+	ErrItemNotFound          = errors.New("the item could not be found in the keychain")
+	ErrDuplicateItem         = errors.New("the item already exists in the keychain")
+	ErrNoSuchKeychain        = errors.New("the keychain does not exist")
+	ErrInteractionNotAllowed = errors.New("user interaction is not allowed, e.g. the keychain is locked in a non-interactive session")
+	ErrPassphraseRequired    = errors.New("a passphrase is required")
+	ErrAccessDenied          = errors.New("the access to the item is denied, e.g. the private key of the system keychain requires an admin")
+)
+
+// StatusError is a failed Security framework call, translated by SecCopyErrorMessageString
+type StatusError struct {
+	Function string
+	Status   int
+	Message  string
+}
+
+func (err StatusError) Error() string {
+	if err.Message == "" {
+		return fmt.Sprintf("%s failed - OSStatus: %d", err.Function, err.Status)
+	}
+	return fmt.Sprintf("%s failed: %s (OSStatus: %d)", err.Function, err.Message, err.Status)
+}
+
+// Is reports whether the status code belongs to the target failure cause, e.g. ErrUserCanceled
+func (err StatusError) Is(target error) bool {
+	cause, ok := statusErrors[err.Status]
+	return ok && cause == target
+}
+
+// ErrPersistentRefNotFound is returned if the identity of a persistent reference is no longer in the keychains,
+// e.g. it was deleted or its keychain was removed from the search list
+var ErrPersistentRefNotFound = errors.New("the identity of the persistent reference is not found")
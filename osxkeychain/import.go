@@ -0,0 +1,108 @@
+//go:build darwin
+// +build darwin
+
+package osxkeychain
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <stdlib.h>
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+*/
+import "C"
+
+// ImportPKCS12 imports the identities of the .p12 content into the keychain, or the default keychain if the path is empty,
+// the applications (e.g. CodesignPath) are allowed to use the private keys. Returns the number of the imported items.
+// A wrong passphrase fails with ErrAuthFailed, an identity which is already in the keychain with ErrDuplicateItem.
+func ImportPKCS12(content []byte, passphrase, keychainPath string, applications []string) (int, error) {
+	if len(content) == 0 {
+		return 0, errors.New("empty .p12 content")
+	}
+	defer InvalidateIdentityCache()
+	keychainRef, err := openKeychain(keychainPath)
+	if err != nil {
+		return 0, err
+	}
+	defer C.CFRelease(C.CFTypeRef(keychainRef))
+
+	access, err := newAccess("Imported Private Key", applications)
+	if err != nil {
+		return 0, err
+	}
+	defer C.CFRelease(C.CFTypeRef(access))
+
+	passphraseCString := C.CString(passphrase)
+	defer C.free(unsafe.Pointer(passphraseCString))
+	passphraseRef := convertCStringToCFString(passphraseCString)
+	defer C.CFRelease(C.CFTypeRef(passphraseRef))
+
+	var importParams C.SecItemImportExportKeyParameters
+	importParams.version = C.SEC_KEY_IMPORT_EXPORT_PARAMS_VERSION
+	importParams.passphrase = C.CFTypeRef(passphraseRef)
+	importParams.accessRef = access
+
+	data := C.CFDataCreate(C.kCFAllocatorDefault, (*C.UInt8)(unsafe.Pointer(&content[0])), C.CFIndex(len(content)))
+	defer C.CFRelease(C.CFTypeRef(data))
+
+	format, itemType := C.SecExternalFormat(C.kSecFormatPKCS12), C.SecExternalItemType(C.kSecItemTypeAggregate)
+	var items C.CFArrayRef
+	if status := C.SecItemImport(data, 0, &format, &itemType, 0, &importParams, keychainRef, &items); status != C.errSecSuccess {
+		return 0, newStatusError("SecItemImport", status)
+	}
+	defer C.CFRelease(C.CFTypeRef(items))
+	return int(C.CFArrayGetCount(items)), nil
+}
+
+// newAccess creates an access allowing the applications, the returned access has to be released with CFRelease
+func newAccess(description string, applications []string) (C.SecAccessRef, error) {
+	trusted := C.CFArrayCreateMutable(C.kCFAllocatorDefault, 0, &C.kCFTypeArrayCallBacks)
+	defer C.CFRelease(C.CFTypeRef(trusted))
+	// the nil path is the running codesigndoc binary
+	pths := []*C.char{nil}
+	for _, application := range applications {
+		pathCString := C.CString(application)
+		defer C.free(unsafe.Pointer(pathCString))
+		pths = append(pths, pathCString)
+	}
+	for _, pathCString := range pths {
+		var application C.SecTrustedApplicationRef
+		if status := C.SecTrustedApplicationCreateFromPath(pathCString, &application); status != C.errSecSuccess {
+			return 0, newStatusError("SecTrustedApplicationCreateFromPath", status)
+		}
+		C.CFArrayAppendValue(trusted, unsafe.Pointer(application))
+		C.CFRelease(C.CFTypeRef(application))
+	}
+
+	descriptionCString := C.CString(description)
+	defer C.free(unsafe.Pointer(descriptionCString))
+	descriptionRef := convertCStringToCFString(descriptionCString)
+	defer C.CFRelease(C.CFTypeRef(descriptionRef))
+
+	var access C.SecAccessRef
+	if status := C.SecAccessCreate(descriptionRef, C.CFArrayRef(trusted), &access); status != C.errSecSuccess {
+		return 0, newStatusError("SecAccessCreate", status)
+	}
+	return access, nil
+}
+
+// SetKeyPartitionList allows the KeyPartitions to use the private keys of the keychain, or the default keychain if the path is empty,
+// without a Keychain prompt (security set-key-partition-list). It requires the password of the keychain, which is passed on the stdin.
+func SetKeyPartitionList(keychainPath, password string) error {
+	if keychainPath == "" {
+		pth, err := DefaultKeychain()
+		if err != nil {
+			return err
+		}
+		keychainPath = pth
+	}
+	if err := securityWithStdin("set-key-partition-list", "-S", KeyPartitions, "-s", "-k", password, keychainPath); err != nil {
+		return fmt.Errorf("failed to set the key partition list of the keychain (%s), error: %s", keychainPath, err)
+	}
+	return nil
+}
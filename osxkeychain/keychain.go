@@ -0,0 +1,229 @@
+//go:build darwin
+// +build darwin
+
+package osxkeychain
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"runtime"
+	"sort"
+
+	"github.com/bitrise-io/codesigndoc/keychain"
+)
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+*/
+import "C"
+
+// Keychain implements keychain.Keychain with the Security framework,
+// it searches the user's keychain search list, or the single keychain file of Path.
+type Keychain struct {
+	// Path is the .keychain or .keychain-db file to search, the user's search list is searched if empty
+	Path string
+}
+
+// NewKeychain returns the Keychain of the keychain file, or of the user's search list if the path is empty
+func NewKeychain(keychainPath string) *Keychain {
+	return &Keychain{Path: keychainPath}
+}
+
+var _ keychain.Keychain = (*Keychain)(nil)
+
+// identityHandle is the keychain reference of an Identity, released by its Close,
+// or by the garbage collector if the Identity is never closed.
+type identityHandle struct {
+	ref C.CFTypeRef
+}
+
+func newIdentityHandle(ref C.CFTypeRef) *identityHandle {
+	handle := &identityHandle{ref: ref}
+	runtime.SetFinalizer(handle, func(handle *identityHandle) { releaseFinalized(handle.ref) })
+	return handle
+}
+
+func (handle *identityHandle) Close() error {
+	runtime.SetFinalizer(handle, nil)
+	ReleaseRef(handle.ref)
+	return nil
+}
+
+// identityQueryOf converts the query of the keychain package
+func identityQueryOf(query keychain.Query) IdentityQuery {
+	converted := IdentityQuery{Labels: query.Labels, TeamIDs: query.TeamIDs, Fingerprints: query.Fingerprints, Keychains: query.Keychains, IncludeExpired: query.IncludeExpired}
+	for _, t := range query.Types {
+		converted.Types = append(converted.Types, CertificateType(t))
+	}
+	return converted
+}
+
+// FindIdentities returns every identity matching the query, without validating them
+func (kc *Keychain) FindIdentities(ctx context.Context, query keychain.Query) ([]*keychain.Identity, error) {
+	var found []IdentityWithRefModel
+	var err error
+	if ctxErr := withContext(ctx, func() {
+		found, err = kc.findIdentities(identityQueryOf(query))
+	}, func() {
+		ReleaseIdentityWithRefList(found)
+	}); ctxErr != nil {
+		return nil, ctxErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var identities []*keychain.Identity
+	for _, identity := range found {
+		identities = append(identities, keychain.NewIdentity(identity.Label, identity.certificate, identity.KeychainPath, newIdentityHandle(identity.KeychainRef)))
+	}
+	return identities, nil
+}
+
+func (kc *Keychain) findIdentities(query IdentityQuery) ([]IdentityWithRefModel, error) {
+	if kc.Path == "" {
+		return FindIdentities(query)
+	}
+	searchList, err := keychainSearchList(kc.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer C.CFRelease(C.CFTypeRef(searchList))
+	return findIdentities(query, searchList)
+}
+
+// CopyCertificate reads the certificate of the identity from the keychain
+func (kc *Keychain) CopyCertificate(identity *keychain.Identity) (*x509.Certificate, error) {
+	refs, err := identityRefs([]*keychain.Identity{identity})
+	if err != nil {
+		return nil, err
+	}
+	certificate, err := GetCertificateDataFromIdentityRef(refs[0])
+	runtime.KeepAlive(identity)
+	return certificate, err
+}
+
+// SelectIdentities returns the latest valid identity of each label matching the query, and the skipped identities
+func (kc *Keychain) SelectIdentities(ctx context.Context, query keychain.Query) ([]*keychain.Identity, []keychain.Skipped, error) {
+	var selection IdentitySelection
+	var err error
+	if ctxErr := withContext(ctx, func() {
+		selection, err = kc.selectIdentities(identityQueryOf(query))
+	}, func() {
+		for _, identity := range selection.Selected {
+			ReleaseRef(identity.KeychainRef)
+		}
+	}); ctxErr != nil {
+		return nil, nil, ctxErr
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var identities []*keychain.Identity
+	for _, identity := range selection.Selected {
+		identities = append(identities, keychain.NewIdentity(identity.Label, identity.certificate, identity.KeychainPath, newIdentityHandle(identity.KeychainRef)))
+	}
+	sort.Slice(identities, func(i, j int) bool { return identities[i].Label < identities[j].Label })
+
+	var skipped []keychain.Skipped
+	for _, identity := range selection.Skipped {
+		skipped = append(skipped, keychain.Skipped{Label: identity.Label, SHA1Fingerprint: identity.SHA1Fingerprint, KeychainPath: identity.KeychainPath, Reason: identity.Reason})
+	}
+	return identities, skipped, nil
+}
+
+func (kc *Keychain) selectIdentities(query IdentityQuery) (IdentitySelection, error) {
+	if kc.Path == "" {
+		return SelectIdentities(query)
+	}
+	searchList, err := keychainSearchList(kc.Path)
+	if err != nil {
+		return IdentitySelection{}, err
+	}
+	defer C.CFRelease(C.CFTypeRef(searchList))
+	return selectIdentities(query, searchList)
+}
+
+// Export exports the identities merged into a single file
+func (kc *Keychain) Export(ctx context.Context, identities []*keychain.Identity, options keychain.ExportOptions) ([]byte, error) {
+	refs, err := identityRefs(identities)
+	if err != nil {
+		return nil, err
+	}
+	exported, err := ExportFromKeychainInFormatContext(ctx, refs, ExportFormat(options.Format), options.AskForPassword, options.Passphrase)
+	runtime.KeepAlive(identities)
+	return exported, err
+}
+
+// ExportTo exports the identities merged into a single file and writes it to w,
+// without copying the whole export into memory first
+func (kc *Keychain) ExportTo(ctx context.Context, w io.Writer, identities []*keychain.Identity, options keychain.ExportOptions) (int64, error) {
+	refs, err := identityRefs(identities)
+	if err != nil {
+		return 0, err
+	}
+	written, err := ExportFromKeychainToContext(ctx, w, refs, ExportFormat(options.Format), options.AskForPassword, options.Passphrase)
+	runtime.KeepAlive(identities)
+	return written, err
+}
+
+// ExportEach exports every identity into a separate file, in the order of the identities
+func (kc *Keychain) ExportEach(ctx context.Context, identities []*keychain.Identity, options keychain.ExportOptions) ([][]byte, error) {
+	refs, err := identityRefs(identities)
+	if err != nil {
+		return nil, err
+	}
+	var exported [][]byte
+	if options.Format == keychain.ExportFormatPKCS8 {
+		exported, err = ExportPrivateKeysContext(ctx, refs, options.AskForPassword, options.Passphrase)
+	} else {
+		exported, err = ExportEachFromKeychainContext(ctx, refs, ExportFormat(options.Format), options.AskForPassword, options.Passphrase)
+	}
+	runtime.KeepAlive(identities)
+	return exported, err
+}
+
+// WrapIdentity returns the identity as a keychain.Identity of the Keychain, for the code using the keychain package.
+// The returned identity retains the reference, close it after use; the identity's KeychainRef is still released by the caller.
+func WrapIdentity(identity IdentityWithRefModel) *keychain.Identity {
+	C.CFRetain(identity.KeychainRef)
+	return keychain.NewIdentity(identity.Label, identity.certificate, identity.KeychainPath, newIdentityHandle(identity.KeychainRef))
+}
+
+// UnwrapIdentity returns the IdentityWithRefModel of an identity selected by a Keychain, for the Security framework
+// specific operations (e.g. CheckExportable). The KeychainRef is owned by the identity, it is valid until the identity is closed.
+func UnwrapIdentity(identity *keychain.Identity) (IdentityWithRefModel, error) {
+	refs, err := identityRefs([]*keychain.Identity{identity})
+	if err != nil {
+		return IdentityWithRefModel{}, err
+	}
+	unwrapped := IdentityWithRefModel{KeychainRef: refs[0], Label: identity.Label, KeychainPath: identity.KeychainPath}
+	if identity.Certificate != nil {
+		unwrapped.setCertificate(identity.Certificate)
+	}
+	return unwrapped, nil
+}
+
+// identityRefs returns the keychain references of the identities selected by a Keychain.
+// The references are released by the finalizers of the identities' handles, keep the identities alive
+// with runtime.KeepAlive until the Security calls using the references return.
+func identityRefs(identities []*keychain.Identity) ([]C.CFTypeRef, error) {
+	var refs []C.CFTypeRef
+	for _, identity := range identities {
+		handle, err := identity.Handle()
+		if err != nil {
+			return nil, err
+		}
+		ref, ok := handle.(*identityHandle)
+		if !ok {
+			return nil, errors.New("the identity was not selected by an osxkeychain.Keychain")
+		}
+		refs = append(refs, ref.ref)
+	}
+	return refs, nil
+}
@@ -0,0 +1,171 @@
+package osxkeychain
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-utils/sliceutil"
+)
+
+const (
+	legacyKeychainExt    = ".keychain"
+	convertedKeychainExt = ".keychain-db"
+	legacyBackupExt      = ".codesigndoc-backup"
+)
+
+// LegacyKeychain is a keychain file stored in the pre-Sierra .keychain format
+// which was not converted to the .keychain-db format after an OS upgrade
+type LegacyKeychain struct {
+	Path string
+	// ConvertedPath is the .keychain-db path macOS expects the keychain at
+	ConvertedPath string
+	// ConvertedExists is true if both the legacy and the converted keychain exist, the legacy file is stale in this case
+	ConvertedExists bool
+	// InSearchList is true if the legacy path is referenced by the user's keychain search list
+	InSearchList bool
+}
+
+// Problem describes why the legacy keychain is a problem
+func (keychain LegacyKeychain) Problem() string {
+	var problems []string
+	if keychain.ConvertedExists {
+		problems = append(problems, fmt.Sprintf("a stale copy of %s", filepath.Base(keychain.ConvertedPath)))
+	} else {
+		problems = append(problems, "not converted to the .keychain-db format")
+	}
+	if keychain.InSearchList {
+		problems = append(problems, "referenced by the keychain search list with the legacy path")
+	}
+	return strings.Join(problems, ", ")
+}
+
+// UserKeychainsDir returns the directory of the user's keychain files
+func UserKeychainsDir() string {
+	return filepath.Join(pathutil.UserHomeDir(), "Library", "Keychains")
+}
+
+// SearchList returns the paths of the user's keychain search list
+func SearchList() ([]string, error) {
+	if err := CheckPlatform(); err != nil {
+		return nil, err
+	}
+	out, err := command.New("security", "list-keychains", "-d", "user").RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keychains, output: %s, error: %s", out, err)
+	}
+	return parseKeychainList(out), nil
+}
+
+// SetSearchList replaces the user's keychain search list
+func SetSearchList(pths []string) error {
+	if err := CheckPlatform(); err != nil {
+		return err
+	}
+	defer InvalidateIdentityCache()
+	args := append([]string{"list-keychains", "-d", "user", "-s"}, pths...)
+	if out, err := command.New("security", args...).RunAndReturnTrimmedCombinedOutput(); err != nil {
+		return fmt.Errorf("failed to set keychain search list, output: %s, error: %s", out, err)
+	}
+	return nil
+}
+
+func parseKeychainList(out string) []string {
+	var pths []string
+	for _, line := range strings.Split(out, "\n") {
+		if pth := strings.Trim(strings.TrimSpace(line), `"`); pth != "" {
+			pths = append(pths, pth)
+		}
+	}
+	return pths
+}
+
+// FindLegacyKeychains returns the user's keychains which are still in the legacy .keychain format
+func FindLegacyKeychains() ([]LegacyKeychain, error) {
+	searchList, err := SearchList()
+	if err != nil {
+		return nil, err
+	}
+
+	pths, err := filepath.Glob(filepath.Join(UserKeychainsDir(), "*"+legacyKeychainExt))
+	if err != nil {
+		return nil, err
+	}
+	for _, pth := range searchList {
+		if filepath.Ext(pth) == legacyKeychainExt && !sliceutil.IsStringInSlice(pth, pths) {
+			pths = append(pths, pth)
+		}
+	}
+
+	var legacyKeychains []LegacyKeychain
+	for _, pth := range pths {
+		if info, err := os.Stat(pth); err != nil || info.IsDir() {
+			// a search list entry with a missing legacy file is resolved to the .keychain-db file by macOS
+			continue
+		}
+
+		convertedPth := strings.TrimSuffix(pth, legacyKeychainExt) + convertedKeychainExt
+		convertedExists, err := pathutil.IsPathExists(convertedPth)
+		if err != nil {
+			return nil, err
+		}
+
+		legacyKeychains = append(legacyKeychains, LegacyKeychain{
+			Path:            pth,
+			ConvertedPath:   convertedPth,
+			ConvertedExists: convertedExists,
+			InSearchList:    sliceutil.IsStringInSlice(pth, searchList),
+		})
+	}
+
+	return legacyKeychains, nil
+}
+
+// RepairLegacyKeychain converts the legacy keychain to the .keychain-db format, and points the search list to the converted file.
+// A stale legacy file (the converted keychain already exists) is moved aside with a .codesigndoc-backup extension,
+// a not converted keychain is copied to the .keychain-db path and the legacy file is kept as backup.
+func RepairLegacyKeychain(keychain LegacyKeychain) error {
+	if err := CheckPlatform(); err != nil {
+		return err
+	}
+	defer InvalidateIdentityCache()
+	if legacyExists, err := pathutil.IsPathExists(keychain.Path); err != nil {
+		return err
+	} else if legacyExists {
+		backupPth := keychain.Path + legacyBackupExt
+		if keychain.ConvertedExists {
+			if err := os.Rename(keychain.Path, backupPth); err != nil {
+				return fmt.Errorf("failed to move stale keychain aside, error: %s", err)
+			}
+		} else {
+			if out, err := command.New("cp", "-p", keychain.Path, keychain.ConvertedPath).RunAndReturnTrimmedCombinedOutput(); err != nil {
+				return fmt.Errorf("failed to convert keychain, output: %s, error: %s", out, err)
+			}
+			if err := os.Rename(keychain.Path, backupPth); err != nil {
+				return fmt.Errorf("failed to back up legacy keychain, error: %s", err)
+			}
+		}
+	}
+
+	if !keychain.InSearchList {
+		return nil
+	}
+
+	searchList, err := SearchList()
+	if err != nil {
+		return err
+	}
+	var updated []string
+	for _, pth := range searchList {
+		if pth == keychain.Path {
+			pth = keychain.ConvertedPath
+		}
+		if !sliceutil.IsStringInSlice(pth, updated) {
+			updated = append(updated, pth)
+		}
+	}
+	return SetSearchList(updated)
+}
@@ -0,0 +1,66 @@
+//go:build darwin
+// +build darwin
+
+package osxkeychain
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+*/
+import "C"
+
+// ManagedIdentities owns the keychain references of the identities, Release releases them.
+// The references are released by the garbage collector too, if Release is never called,
+// but the owner should not rely on it: the keychain items stay locked until the next collection.
+type ManagedIdentities struct {
+	Identities []IdentityWithRefModel
+
+	released bool
+	mux      sync.Mutex
+}
+
+// Manage takes over the release of the identities, e.g. the result of FindIdentities,
+// the identities must not be released with ReleaseIdentityWithRefList afterwards.
+func Manage(identities []IdentityWithRefModel) *ManagedIdentities {
+	managed := &ManagedIdentities{Identities: identities}
+	runtime.SetFinalizer(managed, (*ManagedIdentities).finalize)
+	return managed
+}
+
+// Release releases the identities, it is safe to call it multiple times
+func (managed *ManagedIdentities) Release() {
+	managed.mux.Lock()
+	defer managed.mux.Unlock()
+	if managed.released {
+		return
+	}
+	managed.released = true
+	runtime.SetFinalizer(managed, nil)
+	ReleaseIdentityWithRefList(managed.Identities)
+}
+
+func (managed *ManagedIdentities) finalize() {
+	if managed.released {
+		return
+	}
+	for _, identity := range managed.Identities {
+		releaseFinalized(identity.KeychainRef)
+	}
+}
+
+// releaseFinalized releases a reference from a finalizer, the reference was not released by its owner
+func releaseFinalized(ref C.CFTypeRef) {
+	if Audit {
+		log.Debugf("Releasing a leaked identity reference from a finalizer")
+	}
+	auditRelease(ref, true)
+	C.CFRelease(ref)
+}
@@ -0,0 +1,224 @@
+package osxkeychain
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/keychain"
+	"github.com/bitrise-io/go-utils/sliceutil"
+)
+
+// ExportFormat is the file format of the exported items
+type ExportFormat int
+
+const (
+	// ExportFormatPKCS12 is a .p12 bundle, as exported by Keychain Access.app
+	ExportFormatPKCS12 ExportFormat = iota
+	// ExportFormatPEM is a PEM armoured sequence of the certificates and the passphrase protected private keys
+	ExportFormatPEM
+	// ExportFormatPKCS8 is a passphrase encrypted PKCS#8 PEM of a private key, see ExportPrivateKeysContext
+	ExportFormatPKCS8
+)
+
+// IdentityWithRefModel ...
+type IdentityWithRefModel struct {
+	KeychainRef CFTypeRef
+	Label       string
+
+	// the metadata of the identity's certificate, empty if the certificate could not be read
+	TeamID            string
+	Serial            string
+	SHA1Fingerprint   string
+	SHA256Fingerprint string
+	NotAfter          time.Time
+	// KeychainPath is the keychain file the identity is stored in, empty if it could not be read
+	KeychainPath string
+
+	// certificate is the parsed certificate of the identity, so the validation does not parse it again
+	certificate *x509.Certificate
+}
+
+// setCertificate fills the metadata of the identity from its certificate
+func (identity *IdentityWithRefModel) setCertificate(certificate *x509.Certificate) {
+	identity.TeamID = strings.Join(certificate.Subject.OrganizationalUnit, " ")
+	identity.Serial = certificate.SerialNumber.String()
+	identity.SHA1Fingerprint = fmt.Sprintf("%x", sha1.Sum(certificate.Raw))
+	identity.SHA256Fingerprint = fmt.Sprintf("%x", sha256.Sum256(certificate.Raw))
+	identity.NotAfter = certificate.NotAfter
+	identity.certificate = certificate
+}
+
+// String describes the identity for the logs
+func (identity IdentityWithRefModel) String() string {
+	if identity.Serial == "" {
+		return identity.Label
+	}
+	description := fmt.Sprintf("%s (team: %s, serial: %s, SHA-1: %s, expires: %s", identity.Label, identity.TeamID, identity.Serial, identity.SHA1Fingerprint, identity.NotAfter.Format(time.RFC3339))
+	if identity.KeychainPath != "" {
+		description += ", keychain: " + identity.KeychainPath
+	}
+	return description + ")"
+}
+
+// SkippedIdentity is an identity left out by the validation
+type SkippedIdentity struct {
+	Label           string
+	SHA1Fingerprint string
+	// KeychainPath is the keychain file the skipped identity is stored in
+	KeychainPath string
+	// Reason is why the identity was skipped, e.g. expired on 2019-01-02
+	Reason string
+}
+
+// inKeychain describes the source keychain of an identity for the logs
+func inKeychain(keychainPath string) string {
+	if keychainPath == "" {
+		return ""
+	}
+	return " in " + keychainPath
+}
+
+// ExpectedIdentityQueryDuration is the performance envelope of FindIdentity.
+// The query is scoped to the identity class and the label, so its duration depends on the number of matching identities,
+// not on the (non-identity) items of the keychains, e.g. WiFi passwords and web form data.
+// Verified by the large keychain benchmarks of the testharness package against keychains with 10k non-identity items.
+const ExpectedIdentityQueryDuration = 500 * time.Millisecond
+
+// CertificateType is the type of a code signing certificate, the prefix of its subject common name
+type CertificateType string
+
+// CertificateTypes ...
+const (
+	IPhoneDeveloper         CertificateType = "iPhone Developer"
+	IPhoneDistribution      CertificateType = "iPhone Distribution"
+	AppleDevelopment        CertificateType = "Apple Development"
+	AppleDistribution       CertificateType = "Apple Distribution"
+	MacDeveloper            CertificateType = "Mac Developer"
+	MacDeveloperApplication CertificateType = "3rd Party Mac Developer Application"
+	MacDeveloperInstaller   CertificateType = "3rd Party Mac Developer Installer"
+	DeveloperIDApplication  CertificateType = "Developer ID Application"
+	DeveloperIDInstaller    CertificateType = "Developer ID Installer"
+
+	ApplePushServices               CertificateType = "Apple Push Services"
+	AppleDevelopmentIOSPushServices CertificateType = "Apple Development IOS Push Services"
+	AppleProductionIOSPushServices  CertificateType = "Apple Production IOS Push Services"
+)
+
+var (
+	// DevelopmentCertificateTypes are the types used for development (debug) builds
+	DevelopmentCertificateTypes = []CertificateType{IPhoneDeveloper, AppleDevelopment, MacDeveloper}
+	// DistributionCertificateTypes are the types used for App Store, Ad Hoc, Enterprise and Developer ID builds
+	DistributionCertificateTypes = []CertificateType{IPhoneDistribution, AppleDistribution, MacDeveloperApplication, MacDeveloperInstaller, DeveloperIDApplication, DeveloperIDInstaller}
+	// DeveloperIDCertificateTypes are the types used for the macOS apps distributed outside of the Mac App Store
+	DeveloperIDCertificateTypes = []CertificateType{DeveloperIDApplication, DeveloperIDInstaller}
+	// PushCertificateTypes are the APNs (push notification) certificates, they are not used for code signing
+	PushCertificateTypes = []CertificateType{ApplePushServices, AppleDevelopmentIOSPushServices, AppleProductionIOSPushServices}
+)
+
+// IdentityQuery selects the identities matching any of the Labels, any of the TeamIDs, any of the Types and any of the Fingerprints,
+// an empty list matches every identity.
+type IdentityQuery struct {
+	Labels  []string
+	TeamIDs []string
+	Types   []CertificateType
+	// Fingerprints are the SHA-1 or SHA-256 fingerprints of the certificates, for the identities with the same label
+	Fingerprints []string
+	// Keychains selects the identities stored in any of the keychain files, or of the keychain names (e.g. login)
+	Keychains []string
+	// IncludeExpired selects the expired and not yet valid identities too, if there is no valid one, e.g. for archival
+	IncludeExpired bool
+}
+
+// keychainQuery returns the query of the keychain package, which implements the matching
+func (query IdentityQuery) keychainQuery() keychain.Query {
+	converted := keychain.Query{Labels: query.Labels, TeamIDs: query.TeamIDs, Fingerprints: query.Fingerprints, Keychains: query.Keychains, IncludeExpired: query.IncludeExpired}
+	for _, t := range query.Types {
+		converted.Types = append(converted.Types, string(t))
+	}
+	return converted
+}
+
+// IdentitySelection is the result of SelectIdentities
+type IdentitySelection struct {
+	// Selected is the latest valid identity of each label of the query
+	Selected map[string]IdentityWithRefModel
+	// Skipped lists the other identities found, with the reason
+	Skipped []SkippedIdentity
+}
+
+// CodesignPath is the tool signing the builds with the private keys of the identities
+const CodesignPath = "/usr/bin/codesign"
+
+// KeyAccess lists the applications which can sign with a private key without a Keychain prompt
+type KeyAccess struct {
+	// AnyApplication is set if the access control entry of the key allows every application
+	AnyApplication bool
+	Applications   []string
+}
+
+// Allows returns true if the application at the path can sign with the key without a Keychain prompt
+func (access KeyAccess) Allows(pth string) bool {
+	return access.AnyApplication || sliceutil.IsStringInSlice(pth, access.Applications)
+}
+
+// Missing returns the applications of pths which would get a Keychain prompt
+func (access KeyAccess) Missing(pths []string) []string {
+	var missing []string
+	for _, pth := range pths {
+		if !access.Allows(pth) {
+			missing = append(missing, pth)
+		}
+	}
+	return missing
+}
+
+// String describes the access for the logs
+func (access KeyAccess) String() string {
+	if access.AnyApplication {
+		return "any application"
+	}
+	if len(access.Applications) == 0 {
+		return "no application without a Keychain prompt"
+	}
+	return strings.Join(access.Applications, ", ")
+}
+
+// KeyPartitions are the partitions allowed to use the imported private keys without a Keychain prompt, as set for codesign on CI
+const KeyPartitions = "apple-tool:,apple:,codesign:"
+
+// SystemKeychainPath is the keychain shared by the users of the machine, e.g. Developer ID and enterprise identities
+// are installed here, its private keys can be exported by an admin only.
+const SystemKeychainPath = "/Library/Keychains/System.keychain"
+
+// ParseWorkers is the maximum number of identities parsed and validated concurrently,
+// the identities are processed one by one if it is 1.
+var ParseWorkers = runtime.NumCPU()
+
+// AuditEnvKey enables the CF object audit, if set to true
+const AuditEnvKey = "CODESIGNDOC_CF_AUDIT"
+
+// Audit tracks the retained and released identity and certificate references, ReportLeaks lists the references never released.
+// It records the call site of every retain, so it is meant for debugging only.
+var Audit = os.Getenv(AuditEnvKey) == "true"
+
+// AuditLeak is a reference of Kind (identity or certificate) retained at Site and not released, Count times
+type AuditLeak struct {
+	Kind  string
+	Site  string
+	Count int
+}
+
+// AuditReport is the summary of the CF object audit
+type AuditReport struct {
+	Retained int
+	Released int
+	// Finalized is the number of references released by a finalizer, instead of the owner
+	Finalized int
+	Leaks     []AuditLeak
+}
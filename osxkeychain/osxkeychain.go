@@ -1,13 +1,19 @@
+//go:build darwin
+// +build darwin
+
 package osxkeychain
 
 import (
+	"context"
 	"crypto/x509"
 	"errors"
 	"fmt"
+	"os"
+	"time"
 	"unsafe"
 
+	"github.com/bitrise-io/codesigndoc/progress"
 	"github.com/bitrise-io/go-utils/log"
-	"github.com/bitrise-io/go-xcode/certificateutil"
 )
 
 /*
@@ -19,9 +25,42 @@ import (
 */
 import "C"
 
+// CFTypeRef is a CoreFoundation reference of a keychain item
+type CFTypeRef = C.CFTypeRef
+
 // ExportFromKeychain ...
 func ExportFromKeychain(itemRefsToExport []C.CFTypeRef, isAskForPassword bool) ([]byte, error) {
-	passphraseCString := C.CString("")
+	return ExportFromKeychainInFormat(itemRefsToExport, ExportFormatPKCS12, isAskForPassword, "")
+}
+
+// ExportFromKeychainWithPassphrase exports the items protected by the given passphrase, without any Keychain prompt
+func ExportFromKeychainWithPassphrase(itemRefsToExport []C.CFTypeRef, passphrase string) ([]byte, error) {
+	return ExportFromKeychainInFormat(itemRefsToExport, ExportFormatPKCS12, false, passphrase)
+}
+
+// ExportFromKeychainInFormat exports the items in the given format,
+// protected by the passphrase, or by the one typed in the Keychain prompt if isAskForPassword is set.
+func ExportFromKeychainInFormat(itemRefsToExport []C.CFTypeRef, format ExportFormat, isAskForPassword bool, passphrase string) ([]byte, error) {
+	exportedData, err := secItemExport(itemRefsToExport, format, isAskForPassword, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	// exportedData now contains your PKCS12 data
+	//  make sure it'll be released properly!
+	defer C.CFRelease(C.CFTypeRef(exportedData))
+
+	dataBytes := convertCFDataRefToGoBytes(exportedData)
+	if dataBytes == nil || len(dataBytes) < 1 {
+		return nil, errors.New("ExportFromKeychain: failed to convert export data - nil or empty")
+	}
+	log.Debugf("Export - success")
+
+	return dataBytes, nil
+}
+
+// secItemExport exports the items with SecItemExport, the returned data has to be released by the caller
+func secItemExport(itemRefsToExport []C.CFTypeRef, format ExportFormat, isAskForPassword bool, passphrase string) (C.CFDataRef, error) {
+	passphraseCString := C.CString(passphrase)
 	defer C.free(unsafe.Pointer(passphraseCString))
 
 	var exportedData C.CFDataRef
@@ -36,10 +75,18 @@ func ExportFromKeychain(itemRefsToExport []C.CFTypeRef, isAskForPassword bool) (
 
 		promptText := C.CString("Enter a password which will be used to protect the exported items")
 		defer C.free(unsafe.Pointer(promptText))
-		exportParams.alertPrompt = convertCStringToCFString(promptText)
+		alertPrompt := convertCStringToCFString(promptText)
+		if alertPrompt != 0 {
+			defer C.CFRelease(C.CFTypeRef(alertPrompt))
+		}
+		exportParams.alertPrompt = alertPrompt
 	} else {
+		passphraseRef := convertCStringToCFString(passphraseCString)
+		if passphraseRef != 0 {
+			defer C.CFRelease(C.CFTypeRef(passphraseRef))
+		}
 		exportParams.flags = 0
-		exportParams.passphrase = (C.CFTypeRef)(convertCStringToCFString(passphraseCString))
+		exportParams.passphrase = (C.CFTypeRef)(passphraseRef)
 		exportParams.alertTitle = 0
 		exportParams.alertPrompt = 0
 	}
@@ -51,28 +98,35 @@ func ExportFromKeychain(itemRefsToExport []C.CFTypeRef, isAskForPassword bool) (
 		ptr,
 		C.CFIndex(len(itemRefsToExport)),
 		&C.kCFTypeArrayCallBacks)
+	if cfArrayForExport == 0 {
+		return 0, errors.New("failed to create the array of the items to export")
+	}
+	defer C.CFRelease(C.CFTypeRef(cfArrayForExport))
+
+	// the .p12 generated by Keychain Access.app does NOT have PEM armour
+	secFormat, secFlags := C.SecExternalFormat(C.kSecFormatPKCS12), C.SecItemImportExportFlags(0)
+	if format == ExportFormatPEM {
+		secFormat, secFlags = C.SecExternalFormat(C.kSecFormatPEMSequence), C.SecItemImportExportFlags(C.kSecItemPemArmour)
+	} else if format == ExportFormatPKCS8 {
+		secFormat, secFlags = C.SecExternalFormat(C.kSecFormatWrappedPKCS8), C.SecItemImportExportFlags(C.kSecItemPemArmour)
+	}
 
 	// do the export!
 	status := C.SecItemExport(C.CFTypeRef(cfArrayForExport),
-		C.kSecFormatPKCS12,
-		0, //C.kSecItemPemArmour, // Use kSecItemPemArmour to add PEM armour - the .p12 generated by Keychain Access.app does NOT have PEM armour
+		secFormat,
+		secFlags,
 		&exportParams,
 		&exportedData)
 
 	if status != C.errSecSuccess {
-		return nil, fmt.Errorf("SecItemExport: error (OSStatus): %d", status)
+		return 0, newStatusError("SecItemExport", status)
 	}
-	// exportedData now contains your PKCS12 data
-	//  make sure it'll be released properly!
-	defer C.CFRelease(C.CFTypeRef(exportedData))
-
-	dataBytes := convertCFDataRefToGoBytes(exportedData)
-	if dataBytes == nil || len(dataBytes) < 1 {
-		return nil, errors.New("ExportFromKeychain: failed to convert export data - nil or empty")
-	}
-	log.Debugf("Export - success")
+	return exportedData, nil
+}
 
-	return dataBytes, nil
+// ExportEachFromKeychain exports every item into a separate file content, in the order of the items
+func ExportEachFromKeychain(itemRefsToExport []C.CFTypeRef, format ExportFormat, isAskForPassword bool, passphrase string) ([][]byte, error) {
+	return ExportEachFromKeychainContext(context.Background(), itemRefsToExport, format, isAskForPassword, passphrase)
 }
 
 func convertCFDataRefToGoBytes(cfdata C.CFDataRef) []byte {
@@ -81,6 +135,7 @@ func convertCFDataRefToGoBytes(cfdata C.CFDataRef) []byte {
 
 // ReleaseRef ...
 func ReleaseRef(refItem C.CFTypeRef) {
+	auditRelease(refItem, false)
 	C.CFRelease(refItem)
 }
 
@@ -105,12 +160,11 @@ func CreateEmptyCFTypeRefSlice() []C.CFTypeRef {
 
 // GetCertificateDataFromIdentityRef ...
 func GetCertificateDataFromIdentityRef(identityRef C.CFTypeRef) (*x509.Certificate, error) {
-	secIdentityRef := C.SecIdentityRef(identityRef)
-	var secCertificateRef C.SecCertificateRef
-	osStatusCode := C.SecIdentityCopyCertificate(secIdentityRef, &secCertificateRef)
-	if osStatusCode != C.errSecSuccess {
-		return nil, fmt.Errorf("Failed to call SecItemCopyMatch - OSStatus: %d", osStatusCode)
+	secCertificateRef, err := copyIdentityCertificate(identityRef)
+	if err != nil {
+		return nil, err
 	}
+	defer releaseCertificate(secCertificateRef)
 
 	certificateCFData := C.SecCertificateCopyData(secCertificateRef)
 	if certificateCFData == 0 {
@@ -123,10 +177,86 @@ func GetCertificateDataFromIdentityRef(identityRef C.CFTypeRef) (*x509.Certifica
 	return x509.ParseCertificate(certData)
 }
 
-// IdentityWithRefModel ...
-type IdentityWithRefModel struct {
-	KeychainRef C.CFTypeRef
-	Label       string
+// copyIdentityCertificate returns the certificate of the identity, release it with releaseCertificate
+func copyIdentityCertificate(identityRef C.CFTypeRef) (C.SecCertificateRef, error) {
+	var secCertificateRef C.SecCertificateRef
+	osStatusCode := C.SecIdentityCopyCertificate(C.SecIdentityRef(identityRef), &secCertificateRef)
+	if osStatusCode != C.errSecSuccess {
+		return secCertificateRef, newStatusError("SecIdentityCopyCertificate", osStatusCode)
+	}
+	auditRetain(C.CFTypeRef(secCertificateRef), auditKindCertificate)
+	return secCertificateRef, nil
+}
+
+// releaseCertificate releases a certificate returned by copyIdentityCertificate
+func releaseCertificate(secCertificateRef C.SecCertificateRef) {
+	auditRelease(C.CFTypeRef(secCertificateRef), false)
+	C.CFRelease(C.CFTypeRef(secCertificateRef))
+}
+
+// CheckExportable checks the private key attributes of the identities before calling SecItemExport,
+// returns a NotExportableError if any of the private keys is non-extractable, stored in the Secure Enclave or on a hardware token.
+func CheckExportable(identities []IdentityWithRefModel) error {
+	notExportable := NotExportableError{Reasons: map[string]string{}, HardwareBound: map[string]string{}}
+	for _, identity := range identities {
+		reason, tokenID, err := privateKeyExportProblem(identity.KeychainRef)
+		if err != nil {
+			return fmt.Errorf("Failed to check the private key of %s, error: %s", identity.Label, err)
+		}
+		if reason != "" {
+			notExportable.Reasons[identity.Label] = reason
+		}
+		if tokenID != "" {
+			notExportable.HardwareBound[identity.Label] = tokenID
+		}
+	}
+	if len(notExportable.Reasons) > 0 {
+		return notExportable
+	}
+	return nil
+}
+
+// copyPrivateKey returns the private key of the identity, it has to be released by the caller
+func copyPrivateKey(identityRef C.CFTypeRef) (C.SecKeyRef, error) {
+	var secKeyRef C.SecKeyRef
+	osStatusCode := C.SecIdentityCopyPrivateKey(C.SecIdentityRef(identityRef), &secKeyRef)
+	if osStatusCode != C.errSecSuccess {
+		return secKeyRef, newStatusError("SecIdentityCopyPrivateKey", osStatusCode)
+	}
+	return secKeyRef, nil
+}
+
+// privateKeyExportProblem returns why the private key of the identity can not be exported, empty if it is exportable,
+// and the ID of the hardware token (other than the Secure Enclave) storing the private key, empty if it is not on a token
+func privateKeyExportProblem(identityRef C.CFTypeRef) (string, string, error) {
+	secKeyRef, err := copyPrivateKey(identityRef)
+	if err != nil {
+		return "", "", err
+	}
+	defer C.CFRelease(C.CFTypeRef(secKeyRef))
+
+	attributes := C.SecKeyCopyAttributes(secKeyRef)
+	if attributes == 0 {
+		return "", "", errors.New("Failed to call SecKeyCopyAttributes")
+	}
+	defer C.CFRelease(C.CFTypeRef(attributes))
+
+	if tokenID := C.CFDictionaryGetValue(attributes, unsafe.Pointer(C.kSecAttrTokenID)); tokenID != nil {
+		if C.CFEqual(C.CFTypeRef(tokenID), C.CFTypeRef(C.kSecAttrTokenIDSecureEnclave)) != 0 {
+			return "the private key is stored in the Secure Enclave", "", nil
+		}
+		// the keys of the smart cards and the PIV tokens (e.g. YubiKey) are listed through their CryptoTokenKit driver
+		id, err := convertCFStringToGoString(C.CFStringRef(tokenID))
+		if err != nil || id == "" {
+			id = "unknown token"
+		}
+		return fmt.Sprintf("the private key is stored on the hardware token %s (e.g. a smart card or a YubiKey)", id), id, nil
+	}
+	if extractable := C.CFDictionaryGetValue(attributes, unsafe.Pointer(C.kSecAttrIsExtractable)); extractable != nil &&
+		C.CFBooleanGetValue(C.CFBooleanRef(extractable)) == 0 {
+		return "the private key is marked as non-extractable", "", nil
+	}
+	return "", "", nil
 }
 
 // FindAndValidateIdentity ...
@@ -135,100 +265,343 @@ type IdentityWithRefModel struct {
 func FindAndValidateIdentity(identityLabel string) (*IdentityWithRefModel, error) {
 	foundIdentityRefs, err := FindIdentity(identityLabel)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to find Identity, error: %s", err)
+		return nil, fmt.Errorf("Failed to find Identity, error: %w", err)
 	}
-	if len(foundIdentityRefs) < 1 {
-		return nil, nil
+	return latestValidIdentity(foundIdentityRefs)
+}
+
+// FindAndValidateIdentityInKeychain is FindAndValidateIdentity restricted to the given keychain file
+//  IMPORTANT: you have to C.CFRelease the returned items (one-by-one)!!
+func FindAndValidateIdentityInKeychain(keychainPath, identityLabel string) (*IdentityWithRefModel, error) {
+	foundIdentityRefs, err := FindIdentityInKeychain(keychainPath, identityLabel)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find Identity, error: %w", err)
+	}
+	return latestValidIdentity(foundIdentityRefs)
+}
+
+// FindAndValidateIdentityOfTypes is FindAndValidateIdentity restricted to the given certificate types,
+// e.g. DistributionCertificateTypes, parsed from the certificate subject
+//  IMPORTANT: you have to C.CFRelease the returned items (one-by-one)!!
+func FindAndValidateIdentityOfTypes(identityLabel string, types []CertificateType) (*IdentityWithRefModel, error) {
+	foundIdentityRefs, err := findIdentities(IdentityQuery{Labels: []string{identityLabel}, Types: types}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find Identity, error: %w", err)
+	}
+	return latestValidIdentity(foundIdentityRefs)
+}
+
+// latestValidIdentity selects the identity with the latest expiring valid certificate, and logs the skipped ones
+func latestValidIdentity(foundIdentityRefs []IdentityWithRefModel) (*IdentityWithRefModel, error) {
+	latest, skipped, err := selectIdentity(foundIdentityRefs, false)
+	for _, identity := range skipped {
+		log.Warnf("Skipping identity %s%s: %s", identity.Label, inKeychain(identity.KeychainPath), identity.Reason)
 	}
+	return latest, err
+}
 
-	// check validity
+// selectIdentity selects the identity with the latest expiring certificate, a valid one is preferred if includeExpired is set,
+// the other identities are returned as skipped with the reason.
+func selectIdentity(foundIdentityRefs []IdentityWithRefModel, includeExpired bool) (*IdentityWithRefModel, []SkippedIdentity, error) {
 	var latestIdentityRef *IdentityWithRefModel
 	var latestCertificate x509.Certificate
+	var latestValid bool
+	var skipped []SkippedIdentity
 
-	for _, aIdentityRef := range foundIdentityRefs {
-		cert, err := GetCertificateDataFromIdentityRef(aIdentityRef.KeychainRef)
-		if err != nil {
-			return nil, fmt.Errorf("Failed to read certificate data, error: %s", err)
+	checks := checkIdentities(foundIdentityRefs)
+
+	// the same certificate is found once for every keychain it is installed in
+	fingerprints := map[string]string{}
+	for i := range foundIdentityRefs {
+		aIdentityRef := &foundIdentityRefs[i]
+		skip := func(reason string) {
+			skipped = append(skipped, SkippedIdentity{Label: aIdentityRef.Label, SHA1Fingerprint: aIdentityRef.SHA1Fingerprint, KeychainPath: aIdentityRef.KeychainPath, Reason: reason})
 		}
 
-		if err := certificateutil.CheckValidity(*cert); err != nil {
-			log.Warnf("Certificate is not valid, skipping: %s", err)
+		if first, ok := fingerprints[aIdentityRef.SHA1Fingerprint]; ok && aIdentityRef.SHA1Fingerprint != "" {
+			skip(fmt.Sprintf("duplicate, the identity is installed in multiple keychains, it is also found%s", inKeychain(first)))
+			continue
+		}
+		fingerprints[aIdentityRef.SHA1Fingerprint] = aIdentityRef.KeychainPath
+
+		cert, err := checks[i].certificate, checks[i].certificateErr
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to read certificate data, error: %w", err)
+		}
+		if err := checks[i].privateKeyErr; err != nil {
+			skip(fmt.Sprintf("missing private key: %s", err))
 			continue
 		}
 
-		if latestIdentityRef == nil || cert.NotAfter.After(latestCertificate.NotAfter) {
-			latestIdentityRef = &aIdentityRef
-			latestCertificate = *cert
+		valid := true
+		if now := time.Now(); now.After(cert.NotAfter) {
+			valid = false
+			if !includeExpired {
+				skip(fmt.Sprintf("expired on %s", cert.NotAfter.Format("2006-01-02")))
+				continue
+			}
+		} else if now.Before(cert.NotBefore) {
+			valid = false
+			if !includeExpired {
+				skip(fmt.Sprintf("not valid until %s", cert.NotBefore.Format("2006-01-02")))
+				continue
+			}
 		}
+
+		reason := "a later expiring identity with the same name is selected"
+		if valid != latestValid {
+			reason = "a valid identity with the same name is selected"
+		}
+		if latestIdentityRef != nil && !(valid && !latestValid || valid == latestValid && cert.NotAfter.After(latestCertificate.NotAfter)) {
+			skip(reason)
+			continue
+		}
+		if latestIdentityRef != nil {
+			skipped = append(skipped, SkippedIdentity{Label: latestIdentityRef.Label, SHA1Fingerprint: latestIdentityRef.SHA1Fingerprint, KeychainPath: latestIdentityRef.KeychainPath, Reason: reason})
+		}
+		latestIdentityRef, latestCertificate, latestValid = aIdentityRef, *cert, valid
 	}
 
-	return latestIdentityRef, nil
+	return latestIdentityRef, skipped, nil
 }
 
 // FindIdentity ...
 //  IMPORTANT: you have to C.CFRelease the returned items (one-by-one)!!
 //             you can use the ReleaseIdentityWithRefList method to do that
 func FindIdentity(identityLabel string) ([]IdentityWithRefModel, error) {
+	return findIdentities(IdentityQuery{Labels: []string{identityLabel}}, 0)
+}
+
+// FindIdentities lists the identities of the query in a single keychain pass,
+// instead of calling FindIdentity for every label
+//  IMPORTANT: you have to C.CFRelease the returned items (one-by-one)!!
+//             you can use the ReleaseIdentityWithRefList method to do that
+func FindIdentities(query IdentityQuery) ([]IdentityWithRefModel, error) {
+	return findIdentities(query, 0)
+}
+
+// FindAndValidateIdentities returns the latest valid identity for each label of the query, found in a single keychain pass,
+// the labels without valid identity are missing from the returned map.
+//  IMPORTANT: you have to C.CFRelease the returned items (one-by-one)!!
+func FindAndValidateIdentities(query IdentityQuery) (map[string]IdentityWithRefModel, error) {
+	selection, err := SelectIdentities(query)
+	if err != nil {
+		return nil, err
+	}
+	for _, identity := range selection.Skipped {
+		log.Warnf("Skipping identity %s%s: %s", identity.Label, inKeychain(identity.KeychainPath), identity.Reason)
+	}
+	return selection.Selected, nil
+}
+
+// SelectIdentities returns the latest valid identity for each label of the query, and the skipped identities with the reason,
+// the expired and not yet valid identities are selected too if the query's IncludeExpired is set.
+//  IMPORTANT: you have to C.CFRelease the selected items (one-by-one)!!
+func SelectIdentities(query IdentityQuery) (IdentitySelection, error) {
+	return selectIdentities(query, 0)
+}
+
+// selectIdentities is SelectIdentities in the keychains of searchList, or in the default search list if searchList is 0
+func selectIdentities(query IdentityQuery, searchList C.CFArrayRef) (IdentitySelection, error) {
+	foundIdentityRefs, err := findIdentities(query, searchList)
+	if err != nil {
+		return IdentitySelection{}, fmt.Errorf("Failed to find Identities, error: %w", err)
+	}
+
+	byLabel := map[string][]IdentityWithRefModel{}
+	for _, identityRef := range foundIdentityRefs {
+		byLabel[identityRef.Label] = append(byLabel[identityRef.Label], identityRef)
+	}
+
+	selection := IdentitySelection{Selected: map[string]IdentityWithRefModel{}}
+	var selectErr error
+	for label, identityRefs := range byLabel {
+		latest, skipped, err := selectIdentity(identityRefs, query.IncludeExpired)
+		if err != nil {
+			selectErr = err
+			break
+		}
+		if latest != nil {
+			selection.Selected[label] = *latest
+		}
+		selection.Skipped = append(selection.Skipped, skipped...)
+	}
+
+	for _, identityRef := range foundIdentityRefs {
+		if selected, ok := selection.Selected[identityRef.Label]; selectErr != nil || !ok || selected.KeychainRef != identityRef.KeychainRef {
+			ReleaseRef(identityRef.KeychainRef)
+		}
+	}
+	if selectErr != nil {
+		return IdentitySelection{}, selectErr
+	}
+	return selection, nil
+}
+
+// FindIdentityInKeychain searches the identity only in the given .keychain or .keychain-db file,
+// the keychain does not have to be in the user's search list, but it has to be unlocked.
+//  IMPORTANT: you have to C.CFRelease the returned items (one-by-one)!!
+//             you can use the ReleaseIdentityWithRefList method to do that
+func FindIdentityInKeychain(keychainPath, identityLabel string) ([]IdentityWithRefModel, error) {
+	searchList, err := keychainSearchList(keychainPath)
+	if err != nil {
+		return nil, err
+	}
+	defer C.CFRelease(C.CFTypeRef(searchList))
+
+	return findIdentities(IdentityQuery{Labels: []string{identityLabel}}, searchList)
+}
+
+// keychainSearchList returns a search list of the single .keychain or .keychain-db file, it has to be released with CFRelease
+func keychainSearchList(keychainPath string) (C.CFArrayRef, error) {
+	// SecKeychainOpen succeeds for non existing files too
+	if _, err := os.Stat(keychainPath); err != nil {
+		return 0, fmt.Errorf("Failed to open keychain (%s), error: %s", keychainPath, err)
+	}
+
+	keychainPathCString := C.CString(keychainPath)
+	defer C.free(unsafe.Pointer(keychainPathCString))
+	var keychainRef C.SecKeychainRef
+	osStatusCode := C.SecKeychainOpen(keychainPathCString, &keychainRef)
+	if osStatusCode != C.errSecSuccess {
+		return 0, newStatusError("SecKeychainOpen", osStatusCode)
+	}
+	defer C.CFRelease(C.CFTypeRef(keychainRef))
+
+	return C.CFArrayCreate(C.kCFAllocatorDefault, (*unsafe.Pointer)(unsafe.Pointer(&keychainRef)), 1, &C.kCFTypeArrayCallBacks), nil
+}
+
+// findIdentities queries the identities of the query, in the keychains of searchList,
+// or in the default search list if searchList is 0, the results are cached until InvalidateIdentityCache
+func findIdentities(query IdentityQuery, searchList C.CFArrayRef) ([]IdentityWithRefModel, error) {
+	return cachedFindIdentities(query, searchList, func() ([]IdentityWithRefModel, error) {
+		return queryIdentities(query, searchList)
+	})
+}
 
+// queryIdentities queries the identities of the query with SecItemCopyMatching, see findIdentities
+func queryIdentities(query IdentityQuery, searchList C.CFArrayRef) ([]IdentityWithRefModel, error) {
 	queryDict := C.CFDictionaryCreateMutable(C.kCFAllocatorDefault, 0, nil, nil)
 	defer C.CFRelease(C.CFTypeRef(queryDict))
 	C.CFDictionaryAddValue(queryDict, unsafe.Pointer(C.kSecClass), unsafe.Pointer(C.kSecClassIdentity))
+	if len(query.Labels) == 1 {
+		identityLabelCString := C.CString(query.Labels[0])
+		defer C.free(unsafe.Pointer(identityLabelCString))
+		identityLabelCFString := convertCStringToCFString(identityLabelCString)
+		defer C.CFRelease(C.CFTypeRef(identityLabelCFString))
+
+		// scope the query by label, instead of listing every identity of the keychains,
+		// the query matches a single label, so multiple labels are filtered after listing the identities
+		C.CFDictionaryAddValue(queryDict, unsafe.Pointer(C.kSecAttrLabel), unsafe.Pointer(identityLabelCFString))
+	}
 	C.CFDictionaryAddValue(queryDict, unsafe.Pointer(C.kSecMatchLimit), unsafe.Pointer(C.kSecMatchLimitAll))
+	if searchList != 0 {
+		C.CFDictionaryAddValue(queryDict, unsafe.Pointer(C.kSecMatchSearchList), unsafe.Pointer(searchList))
+	}
 	C.CFDictionaryAddValue(queryDict, unsafe.Pointer(C.kSecReturnAttributes), unsafe.Pointer(C.kCFBooleanTrue))
 	C.CFDictionaryAddValue(queryDict, unsafe.Pointer(C.kSecReturnRef), unsafe.Pointer(C.kCFBooleanTrue))
 
 	var resultRefs C.CFTypeRef
-	osStatusCode := C.SecItemCopyMatching((C.CFDictionaryRef)(queryDict), &resultRefs)
+	var osStatusCode C.OSStatus
+	progress.Run("Listing the keychain identities", func() {
+		osStatusCode = C.SecItemCopyMatching((C.CFDictionaryRef)(queryDict), &resultRefs)
+	})
+	if osStatusCode == C.errSecItemNotFound {
+		return nil, nil
+	}
 	if osStatusCode != C.errSecSuccess {
-		return nil, fmt.Errorf("Failed to call SecItemCopyMatch - OSStatus: %d", osStatusCode)
+		return nil, newStatusError("SecItemCopyMatching", osStatusCode)
 	}
 	defer C.CFRelease(C.CFTypeRef(resultRefs))
 
 	identitiesArrRef := C.CFArrayRef(resultRefs)
 	identitiesCount := C.CFArrayGetCount(identitiesArrRef)
-	if identitiesCount < 1 {
-		return nil, fmt.Errorf("No Identity (certificate + related private key) found in your Keychain")
-	}
 	log.Debugf("identitiesCount: %d", identitiesCount)
 
-	// filter the identities, by label
-	retIdentityRefs := []IdentityWithRefModel{}
+	lablCSting := C.CString("labl")
+	defer C.free(unsafe.Pointer(lablCSting))
+	lablCFString := convertCStringToCFString(lablCSting)
+	defer C.CFRelease(C.CFTypeRef(lablCFString))
+	vrefCSting := C.CString("v_Ref")
+	defer C.free(unsafe.Pointer(vrefCSting))
+	vrefCFString := convertCStringToCFString(vrefCSting)
+	defer C.CFRelease(C.CFTypeRef(vrefCFString))
+
+	// the label match of the query is not guaranteed to be exact (e.g. case insensitive), filter the identities by label
+	matcher := query.keychainQuery()
+	candidates := []IdentityWithRefModel{}
 	for i := C.CFIndex(0); i < identitiesCount; i++ {
 		aIdentityRef := C.CFArrayGetValueAtIndex(identitiesArrRef, i)
 		log.Debugf("aIdentityRef: %#v", aIdentityRef)
 		aIdentityDictRef := C.CFDictionaryRef(aIdentityRef)
 		log.Debugf("aIdentityDictRef: %#v", aIdentityDictRef)
 
-		lablCSting := C.CString("labl")
-		defer C.free(unsafe.Pointer(lablCSting))
-		vrefCSting := C.CString("v_Ref")
-		defer C.free(unsafe.Pointer(vrefCSting))
-
-		labl, err := getCFDictValueUTF8String(aIdentityDictRef, C.CFTypeRef(convertCStringToCFString(lablCSting)))
+		labl, err := getCFDictValueUTF8String(aIdentityDictRef, C.CFTypeRef(lablCFString))
 		if err != nil {
 			log.Warnf("FindIdentity: failed to get 'labl' property: %s", err)
 			continue
 		}
 		log.Debugf("labl: %#v", labl)
-		if labl != identityLabel {
+		if !matcher.MatchesLabel(labl) {
 			continue
 		}
 		log.Debugf("Found identity with label: %s", labl)
 
-		vrefRef, err := getCFDictValueRef(aIdentityDictRef, C.CFTypeRef(convertCStringToCFString(vrefCSting)))
+		vrefRef, err := getCFDictValueRef(aIdentityDictRef, C.CFTypeRef(vrefCFString))
 		if err != nil {
 			log.Warnf("FindIdentity: failed to get 'v_Ref' property: %s", err)
 			continue
 		}
 		log.Debugf("vrefRef: %#v", vrefRef)
 
-		// retain the pointer
-		vrefRef = C.CFRetain(vrefRef)
+		// retain the pointer, it is released below if the identity does not match the query
+		candidates = append(candidates, IdentityWithRefModel{Label: labl, KeychainRef: C.CFRetain(vrefRef)})
+		auditRetain(vrefRef, auditKindIdentity)
+	}
+
+	// parse the certificates concurrently, the references are retained until the workers finish
+	type parsed struct {
+		certificate     *x509.Certificate
+		certificateErr  error
+		keychainPath    string
+		keychainPathErr error
+	}
+	results := make([]parsed, len(candidates))
+	parsing := progress.Start("Parsing the certificates", len(candidates))
+	forEachParallel(len(candidates), func(i int) {
+		results[i].certificate, results[i].certificateErr = GetCertificateDataFromIdentityRef(candidates[i].KeychainRef)
+		results[i].keychainPath, results[i].keychainPathErr = identityKeychainPath(candidates[i].KeychainRef)
+		parsing.Add(1)
+	})
+	parsing.Done()
+
+	retIdentityRefs := []IdentityWithRefModel{}
+	for i, identity := range candidates {
+		result := results[i]
+		if result.certificateErr != nil {
+			log.Warnf("FindIdentity: failed to read the certificate of %s: %s", identity.Label, result.certificateErr)
+			if len(query.TeamIDs) > 0 || len(query.Types) > 0 || len(query.Fingerprints) > 0 {
+				ReleaseRef(identity.KeychainRef)
+				continue
+			}
+		} else if !matcher.MatchesCertificate(result.certificate) {
+			ReleaseRef(identity.KeychainRef)
+			continue
+		} else {
+			identity.setCertificate(result.certificate)
+		}
+
+		if result.keychainPathErr != nil {
+			log.Debugf("FindIdentity: failed to get the keychain of %s: %s", identity.Label, result.keychainPathErr)
+		} else {
+			identity.KeychainPath = result.keychainPath
+		}
+		if !matcher.MatchesKeychain(identity.KeychainPath) {
+			ReleaseRef(identity.KeychainRef)
+			continue
+		}
 		// store it
-		retIdentityRefs = append(retIdentityRefs, IdentityWithRefModel{
-			KeychainRef: vrefRef,
-			Label:       labl,
-		})
+		retIdentityRefs = append(retIdentityRefs, identity)
 	}
 
 	return retIdentityRefs, nil
@@ -279,7 +652,10 @@ func getCFDictValueUTF8String(dict C.CFDictionaryRef, key C.CFTypeRef) (string,
 	if valCFStringRef == 0 {
 		return "", errors.New("getCFDictValueUTF8String: Nil value")
 	}
+	return convertCFStringToGoString(valCFStringRef)
+}
 
+func convertCFStringToGoString(valCFStringRef C.CFStringRef) (string, error) {
 	strLen := C.CFStringGetLength(valCFStringRef)
 	log.Debugf("strLen: %d", strLen)
 	charUTF8Len := C.CFStringGetMaximumSizeForEncoding(strLen, C.kCFStringEncodingUTF8) + 1
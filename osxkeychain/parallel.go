@@ -0,0 +1,75 @@
+//go:build darwin
+// +build darwin
+
+package osxkeychain
+
+import (
+	"crypto/x509"
+	"sync"
+)
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+*/
+import "C"
+
+// forEachParallel calls fn with every index of [0, n) on at most ParseWorkers goroutines, and waits for the calls to return
+func forEachParallel(n int, fn func(i int)) {
+	workers := ParseWorkers
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// identityCheck is the result of validating an identity's certificate and private key
+type identityCheck struct {
+	certificate    *x509.Certificate
+	certificateErr error
+	privateKeyErr  error
+}
+
+// checkIdentities parses the certificates (unless already parsed by the search) and looks up the private keys
+// of the identities concurrently, the identities have to be retained until it returns.
+func checkIdentities(identities []IdentityWithRefModel) []identityCheck {
+	checks := make([]identityCheck, len(identities))
+	forEachParallel(len(identities), func(i int) {
+		check := &checks[i]
+		if check.certificate = identities[i].certificate; check.certificate == nil {
+			if check.certificate, check.certificateErr = GetCertificateDataFromIdentityRef(identities[i].KeychainRef); check.certificateErr != nil {
+				return
+			}
+		}
+		secKeyRef, err := copyPrivateKey(identities[i].KeychainRef)
+		if err != nil {
+			check.privateKeyErr = err
+			return
+		}
+		C.CFRelease(C.CFTypeRef(secKeyRef))
+	})
+	return checks
+}
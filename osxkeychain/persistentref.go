@@ -0,0 +1,100 @@
+//go:build darwin
+// +build darwin
+
+package osxkeychain
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+#include <stdlib.h>
+*/
+import "C"
+
+// PersistentRef returns the persistent reference of the identity: an opaque handle which can be stored,
+// and resolved with FindIdentityByPersistentRef in a later run, without searching the identity by its label again
+func (identity IdentityWithRefModel) PersistentRef() ([]byte, error) {
+	queryDict := C.CFDictionaryCreateMutable(C.kCFAllocatorDefault, 0, nil, nil)
+	defer C.CFRelease(C.CFTypeRef(queryDict))
+	C.CFDictionaryAddValue(queryDict, unsafe.Pointer(C.kSecClass), unsafe.Pointer(C.kSecClassIdentity))
+	C.CFDictionaryAddValue(queryDict, unsafe.Pointer(C.kSecValueRef), unsafe.Pointer(identity.KeychainRef))
+	C.CFDictionaryAddValue(queryDict, unsafe.Pointer(C.kSecReturnPersistentRef), unsafe.Pointer(C.kCFBooleanTrue))
+
+	var result C.CFTypeRef
+	if status := C.SecItemCopyMatching((C.CFDictionaryRef)(queryDict), &result); status != C.errSecSuccess {
+		return nil, newStatusError("SecItemCopyMatching", status)
+	}
+	defer C.CFRelease(result)
+
+	if C.CFGetTypeID(result) != C.CFDataGetTypeID() {
+		return nil, errors.New("the persistent reference of the identity is not a data")
+	}
+	return convertCFDataRefToGoBytes(C.CFDataRef(result)), nil
+}
+
+// FindIdentityByPersistentRef resolves a persistent reference returned by PersistentRef,
+// the identity has the metadata of its certificate like the ones found by FindIdentities.
+// IMPORTANT: you have to C.CFRelease the returned item!!
+func FindIdentityByPersistentRef(persistentRef []byte) (IdentityWithRefModel, error) {
+	if len(persistentRef) == 0 {
+		return IdentityWithRefModel{}, errors.New("empty persistent reference")
+	}
+	data := C.CFDataCreate(C.kCFAllocatorDefault, (*C.UInt8)(unsafe.Pointer(&persistentRef[0])), C.CFIndex(len(persistentRef)))
+	defer C.CFRelease(C.CFTypeRef(data))
+
+	queryDict := C.CFDictionaryCreateMutable(C.kCFAllocatorDefault, 0, nil, nil)
+	defer C.CFRelease(C.CFTypeRef(queryDict))
+	C.CFDictionaryAddValue(queryDict, unsafe.Pointer(C.kSecClass), unsafe.Pointer(C.kSecClassIdentity))
+	C.CFDictionaryAddValue(queryDict, unsafe.Pointer(C.kSecValuePersistentRef), unsafe.Pointer(data))
+	C.CFDictionaryAddValue(queryDict, unsafe.Pointer(C.kSecReturnAttributes), unsafe.Pointer(C.kCFBooleanTrue))
+	C.CFDictionaryAddValue(queryDict, unsafe.Pointer(C.kSecReturnRef), unsafe.Pointer(C.kCFBooleanTrue))
+
+	var result C.CFTypeRef
+	status := C.SecItemCopyMatching((C.CFDictionaryRef)(queryDict), &result)
+	if status == C.errSecItemNotFound {
+		return IdentityWithRefModel{}, ErrPersistentRefNotFound
+	}
+	if status != C.errSecSuccess {
+		return IdentityWithRefModel{}, newStatusError("SecItemCopyMatching", status)
+	}
+	defer C.CFRelease(result)
+
+	lablCSting := C.CString("labl")
+	defer C.free(unsafe.Pointer(lablCSting))
+	lablCFString := convertCStringToCFString(lablCSting)
+	defer C.CFRelease(C.CFTypeRef(lablCFString))
+	vrefCSting := C.CString("v_Ref")
+	defer C.free(unsafe.Pointer(vrefCSting))
+	vrefCFString := convertCStringToCFString(vrefCSting)
+	defer C.CFRelease(C.CFTypeRef(vrefCFString))
+
+	attributes := C.CFDictionaryRef(result)
+	label, err := getCFDictValueUTF8String(attributes, C.CFTypeRef(lablCFString))
+	if err != nil {
+		return IdentityWithRefModel{}, fmt.Errorf("failed to get the label of the identity, error: %s", err)
+	}
+	vrefRef, err := getCFDictValueRef(attributes, C.CFTypeRef(vrefCFString))
+	if err != nil {
+		return IdentityWithRefModel{}, fmt.Errorf("failed to get the reference of the identity (%s), error: %s", label, err)
+	}
+
+	identity := IdentityWithRefModel{Label: label, KeychainRef: C.CFRetain(vrefRef)}
+	auditRetain(identity.KeychainRef, auditKindIdentity)
+
+	certificate, err := GetCertificateDataFromIdentityRef(identity.KeychainRef)
+	if err != nil {
+		ReleaseRef(identity.KeychainRef)
+		return IdentityWithRefModel{}, fmt.Errorf("failed to read the certificate of %s, error: %s", label, err)
+	}
+	identity.setCertificate(certificate)
+	if keychainPath, err := identityKeychainPath(identity.KeychainRef); err == nil {
+		identity.KeychainPath = keychainPath
+	}
+	return identity, nil
+}
@@ -0,0 +1,46 @@
+//go:build darwin
+// +build darwin
+
+package osxkeychain
+
+import (
+	"context"
+	"fmt"
+)
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+*/
+import "C"
+
+// ExportPrivateKeysContext exports the private key of every identity into a separate passphrase encrypted PKCS#8 PEM,
+// in the order of the identities, for the signing systems which can not import a .p12 (e.g. HSM backed or cloud signing).
+// The context is checked before every key.
+func ExportPrivateKeysContext(ctx context.Context, identityRefs []C.CFTypeRef, isAskForPassword bool, passphrase string) ([][]byte, error) {
+	var keys [][]byte
+	for i, identityRef := range identityRefs {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("keychain operation canceled: %w", err)
+		}
+		key, err := copyPrivateKey(identityRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export private key #%d, error: %w", i+1, err)
+		}
+
+		// the copied key is released in the background once the export returns, even if the call is abandoned
+		var content []byte
+		if ctxErr := inBackground(ctx, func() {
+			defer C.CFRelease(C.CFTypeRef(key))
+			content, err = ExportFromKeychainInFormat([]C.CFTypeRef{C.CFTypeRef(key)}, ExportFormatPKCS8, isAskForPassword, passphrase)
+		}, func() {}); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to export private key #%d, error: %w", i+1, err)
+		}
+		keys = append(keys, content)
+	}
+	return keys, nil
+}
@@ -0,0 +1,78 @@
+//go:build darwin
+// +build darwin
+
+package osxkeychain
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <stdlib.h>
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+*/
+import "C"
+
+// CopySearchList returns the keychain paths of the search list of the Security framework (SecKeychainCopySearchList),
+// in the order the keychains are searched
+func CopySearchList() ([]string, error) {
+	var pths []string
+	err := forEachSearchListKeychain(func(pth string, _ C.SecKeychainRef) error {
+		pths = append(pths, pth)
+		return nil
+	})
+	return pths, err
+}
+
+// FindIdentitiesByKeychain runs the identity discovery of the query separately in every keychain of the search list,
+// and maps the keychain paths to the identities found in them, so it can be shown where each identity lives.
+// The keychains without matching identity are in the map too, with an empty list.
+//  IMPORTANT: you have to C.CFRelease the returned items (one-by-one)!!
+//             you can use the ReleaseIdentityWithRefList method to do that
+func FindIdentitiesByKeychain(query IdentityQuery) (map[string][]IdentityWithRefModel, error) {
+	byKeychain := map[string][]IdentityWithRefModel{}
+	err := forEachSearchListKeychain(func(pth string, keychainRef C.SecKeychainRef) error {
+		searchList := C.CFArrayCreate(C.kCFAllocatorDefault, (*unsafe.Pointer)(unsafe.Pointer(&keychainRef)), 1, &C.kCFTypeArrayCallBacks)
+		defer C.CFRelease(C.CFTypeRef(searchList))
+
+		identities, err := findIdentities(query, searchList)
+		if err != nil {
+			return fmt.Errorf("failed to find identities in %s, error: %w", pth, err)
+		}
+		byKeychain[pth] = append(byKeychain[pth], identities...)
+		return nil
+	})
+	if err != nil {
+		for _, identities := range byKeychain {
+			ReleaseIdentityWithRefList(identities)
+		}
+		return nil, err
+	}
+	return byKeychain, nil
+}
+
+// forEachSearchListKeychain calls fn with the path and the reference of every keychain of the search list,
+// the reference is valid during the call only
+func forEachSearchListKeychain(fn func(pth string, keychainRef C.SecKeychainRef) error) error {
+	var searchList C.CFArrayRef
+	if status := C.SecKeychainCopySearchList(&searchList); status != C.errSecSuccess {
+		return newStatusError("SecKeychainCopySearchList", status)
+	}
+	defer C.CFRelease(C.CFTypeRef(searchList))
+
+	count := C.CFArrayGetCount(searchList)
+	for i := C.CFIndex(0); i < count; i++ {
+		keychainRef := C.SecKeychainRef(C.CFArrayGetValueAtIndex(searchList, i))
+		pth, err := keychainRefPath(keychainRef)
+		if err != nil {
+			return fmt.Errorf("failed to get the path of keychain #%d of the search list, error: %s", i, err)
+		}
+		if err := fn(pth, keychainRef); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+//go:build darwin
+// +build darwin
+
+package osxkeychain
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+*/
+import "C"
+
+var statusErrors = map[int]error{
+	C.errSecUserCanceled:          ErrUserCanceled,
+	C.errSecAuthFailed:            ErrAuthFailed,
+	C.errSecPkcs12VerifyFailure:   ErrAuthFailed,
+	C.errSecItemNotFound:          ErrItemNotFound,
+	C.errSecDuplicateItem:         ErrDuplicateItem,
+	C.errSecNoSuchKeychain:        ErrNoSuchKeychain,
+	C.errSecInteractionNotAllowed: ErrInteractionNotAllowed,
+	C.errSecPassphraseRequired:    ErrPassphraseRequired,
+	C.errSecNoAccessForItem:       ErrAccessDenied,
+}
+
+// newStatusError returns the StatusError of the OSStatus returned by the function
+func newStatusError(function string, status C.OSStatus) error {
+	statusErr := StatusError{Function: function, Status: int(status)}
+
+	messageRef := C.SecCopyErrorMessageString(status, nil)
+	if messageRef != 0 {
+		defer C.CFRelease(C.CFTypeRef(messageRef))
+		if message, err := convertCFStringToGoString(messageRef); err == nil {
+			statusErr.Message = message
+		}
+	}
+	return statusErr
+}
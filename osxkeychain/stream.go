@@ -0,0 +1,80 @@
+//go:build darwin
+// +build darwin
+
+package osxkeychain
+
+import (
+	"context"
+	"errors"
+	"io"
+	"unsafe"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+*/
+import "C"
+
+// exportChunkSize is the size of the chunks the exported data is copied to the writer in
+const exportChunkSize = 64 * 1024
+
+// ExportFromKeychainTo exports the items in the given format, like ExportFromKeychainInFormat, and writes the exported data to w.
+// The data is copied from the Security framework's buffer in chunks, so the export is not materialized in the Go heap,
+// e.g. it can be piped into an uploader or an encryptor. Returns the number of bytes written.
+func ExportFromKeychainTo(w io.Writer, itemRefsToExport []C.CFTypeRef, format ExportFormat, isAskForPassword bool, passphrase string) (int64, error) {
+	return ExportFromKeychainToContext(context.Background(), w, itemRefsToExport, format, isAskForPassword, passphrase)
+}
+
+// ExportFromKeychainToContext is ExportFromKeychainTo, returning with the context's error
+// if it is done before the export finishes, nothing is written to w in this case.
+func ExportFromKeychainToContext(ctx context.Context, w io.Writer, itemRefsToExport []C.CFTypeRef, format ExportFormat, isAskForPassword bool, passphrase string) (int64, error) {
+	var exportedData C.CFDataRef
+	var err error
+	if ctxErr := withRetainedRefs(ctx, itemRefsToExport, func() {
+		exportedData, err = secItemExport(itemRefsToExport, format, isAskForPassword, passphrase)
+	}, func() {
+		if err == nil {
+			C.CFRelease(C.CFTypeRef(exportedData))
+		}
+	}); ctxErr != nil {
+		return 0, ctxErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer C.CFRelease(C.CFTypeRef(exportedData))
+
+	written, err := writeCFData(w, exportedData)
+	if err != nil {
+		return written, err
+	}
+	log.Debugf("Export - success, %d bytes written", written)
+	return written, nil
+}
+
+// writeCFData copies the content of the data to w in exportChunkSize chunks
+func writeCFData(w io.Writer, data C.CFDataRef) (int64, error) {
+	length := int(C.CFDataGetLength(data))
+	if length < 1 {
+		return 0, errors.New("ExportFromKeychain: export data is empty")
+	}
+	start := uintptr(unsafe.Pointer(C.CFDataGetBytePtr(data)))
+
+	var written int64
+	for offset := 0; offset < length; offset += exportChunkSize {
+		size := exportChunkSize
+		if offset+size > length {
+			size = length - offset
+		}
+		n, err := w.Write(C.GoBytes(unsafe.Pointer(start+uintptr(offset)), C.int(size)))
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
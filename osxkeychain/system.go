@@ -0,0 +1,65 @@
+//go:build darwin
+// +build darwin
+
+package osxkeychain
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/bitrise-io/go-utils/sliceutil"
+)
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <stdlib.h>
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+*/
+import "C"
+
+// AddToSearchList appends the keychain to the user's search list, if it is not in the list yet,
+// the returned restore function sets back the original search list.
+func AddToSearchList(keychainPath string) (func() error, error) {
+	pths, err := SearchList()
+	if err != nil {
+		return nil, err
+	}
+	if sliceutil.IsStringInSlice(keychainPath, pths) {
+		return func() error { return nil }, nil
+	}
+	if err := SetSearchList(append(append([]string{}, pths...), keychainPath)); err != nil {
+		return nil, err
+	}
+	return func() error { return SetSearchList(pths) }, nil
+}
+
+// identityKeychainPath returns the path of the keychain file the identity is stored in
+func identityKeychainPath(identityRef C.CFTypeRef) (string, error) {
+	certificateRef, err := copyIdentityCertificate(identityRef)
+	if err != nil {
+		return "", err
+	}
+	defer releaseCertificate(certificateRef)
+
+	var keychainRef C.SecKeychainRef
+	if status := C.SecKeychainItemCopyKeychain(C.SecKeychainItemRef(certificateRef), &keychainRef); status != C.errSecSuccess {
+		return "", newStatusError("SecKeychainItemCopyKeychain", status)
+	}
+	defer C.CFRelease(C.CFTypeRef(keychainRef))
+
+	return keychainRefPath(keychainRef)
+}
+
+// keychainRefPath returns the path of the keychain file of the reference
+func keychainRefPath(keychainRef C.SecKeychainRef) (string, error) {
+	buf := make([]byte, 1024)
+	length := C.UInt32(len(buf))
+	if status := C.SecKeychainGetPath(keychainRef, &length, (*C.char)(unsafe.Pointer(&buf[0]))); status != C.errSecSuccess {
+		return "", newStatusError("SecKeychainGetPath", status)
+	}
+	if int(length) > len(buf) {
+		return "", fmt.Errorf("keychain path is longer than %d bytes", len(buf))
+	}
+	return string(buf[:length]), nil
+}
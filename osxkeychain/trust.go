@@ -0,0 +1,126 @@
+package osxkeychain
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// DefaultKeychain returns the path of the user's default keychain
+func DefaultKeychain() (string, error) {
+	if err := CheckPlatform(); err != nil {
+		return "", err
+	}
+	out, err := command.New("security", "default-keychain", "-d", "user").RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to get default keychain, output: %s, error: %s", out, err)
+	}
+	if pths := parseKeychainList(out); len(pths) > 0 {
+		return pths[0], nil
+	}
+	return "", nil
+}
+
+// VerifyCodesignTrust evaluates the code signing trust of the certificate with the keychain search list and the system trust settings,
+// returns the reason if the certificate is not trusted (e.g. the intermediate certificate is missing)
+func VerifyCodesignTrust(certificate x509.Certificate) (bool, string, error) {
+	return verifyCert(certificate)
+}
+
+// CheckRevocation checks online with the OCSP responder of the issuer (Apple) whether the certificate is revoked,
+// e.g. in the developer portal, while it is still date-valid. Returns the reason if it is revoked.
+func CheckRevocation(certificate x509.Certificate) (bool, string, error) {
+	if len(certificate.OCSPServer) == 0 {
+		return false, "", fmt.Errorf("the certificate has no OCSP responder")
+	}
+
+	trusted, problem, err := verifyCert(certificate, "-R", "ocsp")
+	if err != nil || trusted {
+		return false, "", err
+	}
+	revoked, err := classifyRevocation(problem)
+	if err != nil || !revoked {
+		return false, "", err
+	}
+	return true, problem, nil
+}
+
+// classifyRevocation classifies the problem of the failed OCSP verification: the certificate is revoked,
+// or the check itself failed (e.g. the responder is not reachable), the other trust problems are reported by VerifyCodesignTrust
+func classifyRevocation(problem string) (bool, error) {
+	lower := strings.ToLower(problem)
+	switch {
+	case strings.Contains(lower, "revoked"):
+		return true, nil
+	case strings.Contains(lower, "network"), strings.Contains(lower, "ocsp"), strings.Contains(lower, "timed out"), strings.Contains(lower, "offline"):
+		return false, fmt.Errorf("the OCSP responder could not be checked: %s", problem)
+	}
+	return false, nil
+}
+
+// verifyCert evaluates the code signing policy of the certificate with the security tool,
+// returns the last line of its output as the problem if the evaluation fails
+func verifyCert(certificate x509.Certificate, args ...string) (bool, string, error) {
+	if err := CheckPlatform(); err != nil {
+		return false, "", err
+	}
+	tmpDir, err := ioutil.TempDir("", "codesigndoc-trust")
+	if err != nil {
+		return false, "", err
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			log.Warnf("Failed to remove temporary directory (%s), error: %s", tmpDir, err)
+		}
+	}()
+
+	pth := filepath.Join(tmpDir, "certificate.pem")
+	if err := ioutil.WriteFile(pth, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw}), 0600); err != nil {
+		return false, "", err
+	}
+
+	out, err := command.New("security", append([]string{"verify-cert", "-c", pth, "-p", "codeSign"}, args...)...).RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		lines := strings.Split(out, "\n")
+		return false, strings.TrimSpace(lines[len(lines)-1]), nil
+	}
+	return true, "", nil
+}
+
+// FindIssuer returns the intermediate certificate (e.g. Apple WWDR G3) which issued the certificate, from the keychain search list,
+// nil if it is not installed
+func FindIssuer(certificate x509.Certificate) (*x509.Certificate, error) {
+	if err := CheckPlatform(); err != nil {
+		return nil, err
+	}
+	out, err := command.New("security", "find-certificate", "-a", "-p", "-c", certificate.Issuer.CommonName).RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		if strings.Contains(out, "could not be found") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find certificate (%s), output: %s, error: %s", certificate.Issuer.CommonName, out, err)
+	}
+
+	for rest := []byte(out); ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		candidate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			log.Debugf("Failed to parse certificate: %s", err)
+			continue
+		}
+		if certificate.CheckSignatureFrom(candidate) == nil {
+			return candidate, nil
+		}
+	}
+	return nil, nil
+}
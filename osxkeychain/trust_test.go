@@ -0,0 +1,35 @@
+package osxkeychain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyRevocation(t *testing.T) {
+	tests := []struct {
+		name        string
+		problem     string
+		wantRevoked bool
+		wantErr     string
+	}{
+		{name: "revoked", problem: "Cert Verify Result: CSSMERR_TP_CERT_REVOKED", wantRevoked: true},
+		{name: "network failure", problem: "Cert Verify Result: CSSMERR_APPLETP_NETWORK_FAILURE", wantErr: "the OCSP responder could not be checked: Cert Verify Result: CSSMERR_APPLETP_NETWORK_FAILURE"},
+		{name: "responder unavailable", problem: "Cert Verify Result: CSSMERR_APPLETP_OCSP_UNAVAILABLE", wantErr: "the OCSP responder could not be checked: Cert Verify Result: CSSMERR_APPLETP_OCSP_UNAVAILABLE"},
+		{name: "timeout", problem: "The request timed out.", wantErr: "the OCSP responder could not be checked: The request timed out."},
+		{name: "other trust problem", problem: "Cert Verify Result: CSSMERR_TP_NOT_TRUSTED"},
+		{name: "expired", problem: "Cert Verify Result: CSSMERR_TP_CERT_EXPIRED"},
+		{name: "no output", problem: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked, err := classifyRevocation(tt.problem)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantRevoked, revoked)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+//go:build darwin
+// +build darwin
+
+package osxkeychain
+
+import (
+	"fmt"
+	"os"
+	"unsafe"
+)
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <stdlib.h>
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+*/
+import "C"
+
+// openKeychain opens the keychain file, or the default (login) keychain if the path is empty,
+// the returned keychain has to be released with CFRelease.
+func openKeychain(keychainPath string) (C.SecKeychainRef, error) {
+	var keychainRef C.SecKeychainRef
+	if keychainPath == "" {
+		if status := C.SecKeychainCopyDefault(&keychainRef); status != C.errSecSuccess {
+			return keychainRef, newStatusError("SecKeychainCopyDefault", status)
+		}
+		return keychainRef, nil
+	}
+
+	// SecKeychainOpen succeeds for non existing files too
+	if _, err := os.Stat(keychainPath); err != nil {
+		return keychainRef, fmt.Errorf("failed to open keychain (%s), error: %s", keychainPath, err)
+	}
+	keychainPathCString := C.CString(keychainPath)
+	defer C.free(unsafe.Pointer(keychainPathCString))
+	if status := C.SecKeychainOpen(keychainPathCString, &keychainRef); status != C.errSecSuccess {
+		return keychainRef, newStatusError("SecKeychainOpen", status)
+	}
+	return keychainRef, nil
+}
+
+// IsKeychainLocked returns true if the keychain, or the default keychain if the path is empty, is locked
+func IsKeychainLocked(keychainPath string) (bool, error) {
+	keychainRef, err := openKeychain(keychainPath)
+	if err != nil {
+		return false, err
+	}
+	defer C.CFRelease(C.CFTypeRef(keychainRef))
+
+	var status C.SecKeychainStatus
+	if osStatusCode := C.SecKeychainGetStatus(keychainRef, &status); osStatusCode != C.errSecSuccess {
+		return false, newStatusError("SecKeychainGetStatus", osStatusCode)
+	}
+	return status&C.kSecUnlockStateStatus == 0, nil
+}
+
+// UnlockKeychain unlocks the keychain, or the default keychain if the path is empty, with the password,
+// without any GUI prompt, so the identities can be queried and exported in non-interactive sessions, e.g. on CI.
+// A wrong password fails with ErrAuthFailed.
+func UnlockKeychain(keychainPath, password string) error {
+	// the identities of a locked keychain can be missing from the cached results
+	defer InvalidateIdentityCache()
+	keychainRef, err := openKeychain(keychainPath)
+	if err != nil {
+		return err
+	}
+	defer C.CFRelease(C.CFTypeRef(keychainRef))
+
+	passwordCString := C.CString(password)
+	defer C.free(unsafe.Pointer(passwordCString))
+	if status := C.SecKeychainUnlock(keychainRef, C.uint32_t(len(password)), unsafe.Pointer(passwordCString), C.Boolean(1)); status != C.errSecSuccess {
+		return newStatusError("SecKeychainUnlock", status)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+//go:build !darwin
+// +build !darwin
+
+package osxkeychain
+
+import (
+	"context"
+	"crypto/x509"
+	"io"
+	"unsafe"
+
+	"github.com/bitrise-io/codesigndoc/keychain"
+)
+
+// The Security framework is only available on macOS, the keychain operations return ErrUnsupportedPlatform
+// on the other platforms, so the tool still builds there for the profile, report and upload features.
+
+// CFTypeRef is a CoreFoundation reference of a keychain item
+type CFTypeRef unsafe.Pointer
+
+var statusErrors = map[int]error{}
+
+// Keychain implements keychain.Keychain, every operation returns ErrUnsupportedPlatform
+type Keychain struct {
+	// Path is the .keychain or .keychain-db file to search, the user's search list is searched if empty
+	Path string
+}
+
+// NewKeychain returns the Keychain of the keychain file, or of the user's search list if the path is empty
+func NewKeychain(keychainPath string) *Keychain {
+	return &Keychain{Path: keychainPath}
+}
+
+var _ keychain.Keychain = (*Keychain)(nil)
+
+// FindIdentities ...
+func (kc *Keychain) FindIdentities(ctx context.Context, query keychain.Query) ([]*keychain.Identity, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// CopyCertificate ...
+func (kc *Keychain) CopyCertificate(identity *keychain.Identity) (*x509.Certificate, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// SelectIdentities ...
+func (kc *Keychain) SelectIdentities(ctx context.Context, query keychain.Query) ([]*keychain.Identity, []keychain.Skipped, error) {
+	return nil, nil, ErrUnsupportedPlatform
+}
+
+// Export ...
+func (kc *Keychain) Export(ctx context.Context, identities []*keychain.Identity, options keychain.ExportOptions) ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// ExportTo ...
+func (kc *Keychain) ExportTo(ctx context.Context, w io.Writer, identities []*keychain.Identity, options keychain.ExportOptions) (int64, error) {
+	return 0, ErrUnsupportedPlatform
+}
+
+// ExportEach ...
+func (kc *Keychain) ExportEach(ctx context.Context, identities []*keychain.Identity, options keychain.ExportOptions) ([][]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// WrapIdentity ...
+func WrapIdentity(identity IdentityWithRefModel) *keychain.Identity {
+	return keychain.NewIdentity(identity.Label, identity.certificate, identity.KeychainPath, nil)
+}
+
+// UnwrapIdentity ...
+func UnwrapIdentity(identity *keychain.Identity) (IdentityWithRefModel, error) {
+	return IdentityWithRefModel{}, ErrUnsupportedPlatform
+}
+
+// ManagedIdentities owns the keychain references of the identities, Release releases them
+type ManagedIdentities struct {
+	Identities []IdentityWithRefModel
+}
+
+// Manage takes over the release of the identities
+func Manage(identities []IdentityWithRefModel) *ManagedIdentities {
+	return &ManagedIdentities{Identities: identities}
+}
+
+// Release ...
+func (managed *ManagedIdentities) Release() {}
+
+// Report returns the current state of the CF object audit, nothing is tracked without the keychain
+func Report() AuditReport {
+	return AuditReport{}
+}
+
+// ReportLeaks ...
+func ReportLeaks() {}
+
+// InvalidateIdentityCache ...
+func InvalidateIdentityCache() {}
+
+// InspectKeyAccess ...
+func InspectKeyAccess(identity IdentityWithRefModel) (KeyAccess, error) {
+	return KeyAccess{}, ErrUnsupportedPlatform
+}
+
+// AllowApplications ...
+func AllowApplications(identity IdentityWithRefModel, pths []string) error {
+	return ErrUnsupportedPlatform
+}
+
+// FindIdentityContext ...
+func FindIdentityContext(ctx context.Context, identityLabel string) ([]IdentityWithRefModel, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// SelectIdentitiesContext ...
+func SelectIdentitiesContext(ctx context.Context, query IdentityQuery) (IdentitySelection, error) {
+	return IdentitySelection{}, ErrUnsupportedPlatform
+}
+
+// ExportFromKeychainInFormatContext ...
+func ExportFromKeychainInFormatContext(ctx context.Context, itemRefsToExport []CFTypeRef, format ExportFormat, isAskForPassword bool, passphrase string) ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// ExportEachFromKeychainContext ...
+func ExportEachFromKeychainContext(ctx context.Context, itemRefsToExport []CFTypeRef, format ExportFormat, isAskForPassword bool, passphrase string) ([][]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// ImportPKCS12 ...
+func ImportPKCS12(content []byte, passphrase, keychainPath string, applications []string) (int, error) {
+	return 0, ErrUnsupportedPlatform
+}
+
+// SetKeyPartitionList ...
+func SetKeyPartitionList(keychainPath, password string) error {
+	return ErrUnsupportedPlatform
+}
+
+// ExportFromKeychain ...
+func ExportFromKeychain(itemRefsToExport []CFTypeRef, isAskForPassword bool) ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// ExportFromKeychainWithPassphrase ...
+func ExportFromKeychainWithPassphrase(itemRefsToExport []CFTypeRef, passphrase string) ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// ExportFromKeychainInFormat ...
+func ExportFromKeychainInFormat(itemRefsToExport []CFTypeRef, format ExportFormat, isAskForPassword bool, passphrase string) ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// ExportEachFromKeychain ...
+func ExportEachFromKeychain(itemRefsToExport []CFTypeRef, format ExportFormat, isAskForPassword bool, passphrase string) ([][]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// ExportFromKeychainTo ...
+func ExportFromKeychainTo(w io.Writer, itemRefsToExport []CFTypeRef, format ExportFormat, isAskForPassword bool, passphrase string) (int64, error) {
+	return 0, ErrUnsupportedPlatform
+}
+
+// ExportFromKeychainToContext ...
+func ExportFromKeychainToContext(ctx context.Context, w io.Writer, itemRefsToExport []CFTypeRef, format ExportFormat, isAskForPassword bool, passphrase string) (int64, error) {
+	return 0, ErrUnsupportedPlatform
+}
+
+// ExportPrivateKeysContext ...
+func ExportPrivateKeysContext(ctx context.Context, identityRefs []CFTypeRef, isAskForPassword bool, passphrase string) ([][]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// ReleaseRef ...
+func ReleaseRef(refItem CFTypeRef) {}
+
+// ReleaseRefList ...
+func ReleaseRefList(refItems []CFTypeRef) {}
+
+// ReleaseIdentityWithRefList ...
+func ReleaseIdentityWithRefList(refItems []IdentityWithRefModel) {}
+
+// CreateEmptyCFTypeRefSlice ...
+func CreateEmptyCFTypeRefSlice() []CFTypeRef {
+	return []CFTypeRef{}
+}
+
+// GetCertificateDataFromIdentityRef ...
+func GetCertificateDataFromIdentityRef(identityRef CFTypeRef) (*x509.Certificate, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// CheckExportable ...
+func CheckExportable(identities []IdentityWithRefModel) error {
+	return ErrUnsupportedPlatform
+}
+
+// FindAndValidateIdentity ...
+func FindAndValidateIdentity(identityLabel string) (*IdentityWithRefModel, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// FindAndValidateIdentityInKeychain ...
+func FindAndValidateIdentityInKeychain(keychainPath, identityLabel string) (*IdentityWithRefModel, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// FindAndValidateIdentityOfTypes ...
+func FindAndValidateIdentityOfTypes(identityLabel string, types []CertificateType) (*IdentityWithRefModel, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// FindIdentity ...
+func FindIdentity(identityLabel string) ([]IdentityWithRefModel, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// FindIdentities ...
+func FindIdentities(query IdentityQuery) ([]IdentityWithRefModel, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// FindAndValidateIdentities ...
+func FindAndValidateIdentities(query IdentityQuery) (map[string]IdentityWithRefModel, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// SelectIdentities ...
+func SelectIdentities(query IdentityQuery) (IdentitySelection, error) {
+	return IdentitySelection{}, ErrUnsupportedPlatform
+}
+
+// FindIdentityInKeychain ...
+func FindIdentityInKeychain(keychainPath, identityLabel string) ([]IdentityWithRefModel, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// FindIdentitiesByKeychain ...
+func FindIdentitiesByKeychain(query IdentityQuery) (map[string][]IdentityWithRefModel, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// PersistentRef ...
+func (identity IdentityWithRefModel) PersistentRef() ([]byte, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// FindIdentityByPersistentRef ...
+func FindIdentityByPersistentRef(persistentRef []byte) (IdentityWithRefModel, error) {
+	return IdentityWithRefModel{}, ErrUnsupportedPlatform
+}
+
+// CopySearchList ...
+func CopySearchList() ([]string, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// AddToSearchList ...
+func AddToSearchList(keychainPath string) (func() error, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// IsKeychainLocked ...
+func IsKeychainLocked(keychainPath string) (bool, error) {
+	return false, ErrUnsupportedPlatform
+}
+
+// UnlockKeychain ...
+func UnlockKeychain(keychainPath, password string) error {
+	return ErrUnsupportedPlatform
+}
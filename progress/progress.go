@@ -0,0 +1,181 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// clearLine moves the cursor to the start of the line and erases it
+const clearLine = "\r\x1b[K"
+
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+var (
+	interactive bool
+	// SpinnerInterval is the redraw period of the spinner, the steps finishing earlier do not show it
+	SpinnerInterval = 100 * time.Millisecond
+	// LogInterval is the period of the log lines in the non interactive mode, the steps finishing earlier do not print any
+	LogInterval = 15 * time.Second
+)
+
+// Stdout is the standard output, the messages written to it while a spinner is shown are printed above the spinner
+var Stdout = NewWriter(os.Stdout)
+
+// SetInteractive enables the spinner, otherwise the progress is reported by periodic log lines
+func SetInteractive(enable bool) {
+	interactive = enable
+}
+
+// IsTerminal reports whether the standard output is a terminal
+func IsTerminal() bool {
+	return terminal.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// Writer keeps the spinner line at the bottom of the output
+type Writer struct {
+	out  io.Writer
+	line string
+	mux  sync.Mutex
+}
+
+// NewWriter ...
+func NewWriter(out io.Writer) *Writer {
+	return &Writer{out: out}
+}
+
+// Write prints p in place of the spinner line, and redraws the spinner below it
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if w.line != "" {
+		if _, err := io.WriteString(w.out, clearLine); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.out.Write(p)
+	if err == nil && w.line != "" && strings.HasSuffix(string(p), "\n") {
+		_, err = io.WriteString(w.out, w.line)
+	}
+	return n, err
+}
+
+// draw replaces the spinner line
+func (w *Writer) draw(line string) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	w.line = line
+	if _, err := io.WriteString(w.out, clearLine+line); err != nil {
+		log.Debugf("Failed to draw the progress: %s", err)
+	}
+}
+
+// clear removes the spinner line
+func (w *Writer) clear() {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if w.line == "" {
+		return
+	}
+	w.line = ""
+	if _, err := io.WriteString(w.out, clearLine); err != nil {
+		log.Debugf("Failed to clear the progress: %s", err)
+	}
+}
+
+// Task reports the progress of a long running step until Done is called
+type Task struct {
+	message string
+	total   int
+	current int64
+	started time.Time
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// Start starts reporting the progress of the step, total is the number of its items, 0 if it is not known
+func Start(message string, total int) *Task {
+	task := &Task{message: message, total: total, started: time.Now(), done: make(chan struct{})}
+	task.wg.Add(1)
+	if interactive {
+		go task.spin()
+	} else {
+		go task.report()
+	}
+	return task
+}
+
+// Run runs the action while reporting its progress, for the steps without countable items (e.g. xcodebuild)
+func Run(message string, action func()) {
+	task := Start(message, 0)
+	defer task.Done()
+	action()
+}
+
+// Add marks n items of the step finished, it is safe to call from multiple goroutines
+func (task *Task) Add(n int) {
+	atomic.AddInt64(&task.current, int64(n))
+}
+
+// Done stops reporting the progress
+func (task *Task) Done() {
+	close(task.done)
+	task.wg.Wait()
+}
+
+func (task *Task) spin() {
+	defer task.wg.Done()
+	ticker := time.NewTicker(SpinnerInterval)
+	defer ticker.Stop()
+
+	for frame := 0; ; frame++ {
+		select {
+		case <-task.done:
+			Stdout.clear()
+			return
+		case <-ticker.C:
+			Stdout.draw(spinnerFrames[frame%len(spinnerFrames)] + " " + task.status(time.Now()))
+		}
+	}
+}
+
+func (task *Task) report() {
+	defer task.wg.Done()
+	ticker := time.NewTicker(LogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-task.done:
+			return
+		case <-ticker.C:
+			log.Printf("%s", task.status(time.Now()))
+		}
+	}
+}
+
+func (task *Task) status(now time.Time) string {
+	return formatStatus(task.message, int(atomic.LoadInt64(&task.current)), task.total, now.Sub(task.started))
+}
+
+// formatStatus returns the progress line of the step, e.g. "Reading the installed profiles... 12/40 (30%) 8s"
+func formatStatus(message string, current, total int, elapsed time.Duration) string {
+	status := message + "..."
+	if total > 0 {
+		if current > total {
+			current = total
+		}
+		status += fmt.Sprintf(" %d/%d (%d%%)", current, total, current*100/total)
+	}
+	return status + " " + elapsed.Truncate(time.Second).String()
+}
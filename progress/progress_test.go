@@ -0,0 +1,38 @@
+package progress
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatStatus(t *testing.T) {
+	require.Equal(t, "Archiving... 1m5s", formatStatus("Archiving", 0, 0, 65*time.Second+300*time.Millisecond))
+	require.Equal(t, "Reading the installed profiles... 12/40 (30%) 8s", formatStatus("Reading the installed profiles", 12, 40, 8*time.Second))
+	require.Equal(t, "Uploading... 3/3 (100%) 0s", formatStatus("Uploading", 5, 3, 0))
+}
+
+func TestWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out)
+
+	_, err := w.Write([]byte("before\n"))
+	require.NoError(t, err)
+	w.draw("| Archiving... 1s")
+	_, err = w.Write([]byte("message\n"))
+	require.NoError(t, err)
+	w.clear()
+	w.clear()
+
+	require.Equal(t, "before\n"+clearLine+"| Archiving... 1s"+clearLine+"message\n| Archiving... 1s"+clearLine, out.String())
+}
+
+func TestTask(t *testing.T) {
+	task := Start("Counting", 2)
+	task.Add(1)
+	task.Add(1)
+	require.Equal(t, "Counting... 2/2 (100%) 0s", task.status(task.started))
+	task.Done()
+}
@@ -0,0 +1,154 @@
+package projectconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+)
+
+// FileName is the config file looked up in the working directory
+const FileName = ".codesigndoc.yml"
+
+// Config is the per-project configuration of the scans, its values are the defaults of the matching flags
+type Config struct {
+	// Project is the Xcode project or workspace, or the Xamarin solution (--file)
+	Project string `yaml:"project"`
+	// Schemes are the Xcode schemes to archive (--scheme)
+	Schemes []string `yaml:"schemes"`
+	// Configurations are the build configurations of the Xcode schemes (--configuration), or the Xamarin configuration (--config)
+	Configurations []string `yaml:"configurations"`
+	// TeamIDs filter the exported identities and profiles (--team-id)
+	TeamIDs []string `yaml:"team_ids"`
+	Output  Output   `yaml:"output"`
+	Upload  Upload   `yaml:"upload"`
+}
+
+// Output are the settings of the written files and the scan result
+type Output struct {
+	Dir            string `yaml:"dir"`
+	WriteFiles     string `yaml:"write_files"`
+	Format         string `yaml:"format"`
+	FormatOutput   string `yaml:"format_output"`
+	HTMLReport     string `yaml:"html_report"`
+	PEM            *bool  `yaml:"pem"`
+	P12PerIdentity *bool  `yaml:"p12_per_identity"`
+	IncludeChain   *bool  `yaml:"include_chain"`
+	PerTeam        *bool  `yaml:"per_team"`
+	RequirePairing *bool  `yaml:"require_pairing"`
+}
+
+// Upload are the destinations of the exported files, the credentials are read from the env vars
+type Upload struct {
+	// AppSlug is the Bitrise app, the auth token has to be given by the --auth-token flag
+	AppSlug     string `yaml:"app_slug"`
+	To          string `yaml:"to"`
+	Region      string `yaml:"region"`
+	Endpoint    string `yaml:"endpoint"`
+	SecretStore string `yaml:"secret_store"`
+}
+
+// Flag is a flag value of the config, the slice flags have multiple values
+type Flag struct {
+	Name   string
+	Values []string
+	// Destination marks the upload and secret store flags, which send the exported files off the machine
+	Destination bool
+}
+
+// NewFromFile reads the config file, its relative paths are resolved against the directory of the file
+func NewFromFile(pth string) (Config, error) {
+	content, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config file, error: %s", err)
+	}
+	config, err := New(content)
+	if err != nil {
+		return Config{}, err
+	}
+	config.resolvePaths(filepath.Dir(pth))
+	return config, nil
+}
+
+// resolvePaths joins the relative project and output paths to dir
+func (config *Config) resolvePaths(dir string) {
+	for _, pth := range []*string{&config.Project, &config.Output.Dir, &config.Output.FormatOutput, &config.Output.HTMLReport} {
+		if *pth != "" && !filepath.IsAbs(*pth) {
+			*pth = filepath.Join(dir, *pth)
+		}
+	}
+}
+
+// New parses the config, the unknown keys are reported as errors
+func New(content []byte) (Config, error) {
+	var config Config
+	if err := yaml.UnmarshalStrict(content, &config); err != nil {
+		return Config{}, fmt.Errorf("failed to parse config, error: %s", err)
+	}
+	return config, nil
+}
+
+// Flags returns the flag values of the config for the scan subcommand (e.g. xcode), the empty values are left out
+func (config Config) Flags(command string) ([]Flag, error) {
+	var flags []Flag
+	add := func(name string, values ...string) {
+		var nonEmpty []string
+		for _, value := range values {
+			if value != "" {
+				nonEmpty = append(nonEmpty, value)
+			}
+		}
+		if len(nonEmpty) > 0 {
+			flags = append(flags, Flag{Name: name, Values: nonEmpty})
+		}
+	}
+	addBool := func(name string, value *bool) {
+		if value != nil {
+			add(name, strconv.FormatBool(*value))
+		}
+	}
+	addDestination := func(name string, value string) {
+		if value != "" {
+			flags = append(flags, Flag{Name: name, Values: []string{value}, Destination: true})
+		}
+	}
+
+	switch command {
+	case "xcode":
+		add("file", config.Project)
+		add("scheme", config.Schemes...)
+		add("configuration", config.Configurations...)
+	case "xcodeuitests":
+		if len(config.Schemes) > 1 {
+			return nil, fmt.Errorf("the xcodeuitests scan supports a single scheme, the config declares %d", len(config.Schemes))
+		}
+		add("file", config.Project)
+		add("scheme", config.Schemes...)
+	case "xamarin":
+		if len(config.Configurations) > 1 {
+			return nil, fmt.Errorf("the xamarin scan supports a single configuration, the config declares %d", len(config.Configurations))
+		}
+		add("file", config.Project)
+		add("config", config.Configurations...)
+	}
+
+	add("team-id", config.TeamIDs...)
+	add("output-dir", config.Output.Dir)
+	add("write-files", config.Output.WriteFiles)
+	add("format", config.Output.Format)
+	add("format-output", config.Output.FormatOutput)
+	add("html-report", config.Output.HTMLReport)
+	addBool("pem", config.Output.PEM)
+	addBool("p12-per-identity", config.Output.P12PerIdentity)
+	addBool("include-chain", config.Output.IncludeChain)
+	addBool("per-team", config.Output.PerTeam)
+	addBool("require-pairing", config.Output.RequirePairing)
+	addDestination("app-slug", config.Upload.AppSlug)
+	addDestination("upload-to", config.Upload.To)
+	addDestination("upload-region", config.Upload.Region)
+	addDestination("upload-endpoint", config.Upload.Endpoint)
+	addDestination("secret-store", config.Upload.SecretStore)
+	return flags, nil
+}
@@ -0,0 +1,82 @@
+package projectconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `project: ios/App.xcworkspace
+schemes:
+- App
+- App Extension
+configurations: [Release]
+team_ids: [ABCD123456]
+output:
+  dir: ./signing
+  write_files: fallback
+  pem: true
+  p12_per_identity: false
+upload:
+  to: s3://signing-assets/ios
+  secret_store: vault://secret/ios/signing
+`
+
+func TestFlags(t *testing.T) {
+	config, err := New([]byte(testConfig))
+	require.NoError(t, err)
+
+	flags, err := config.Flags("xcode")
+	require.NoError(t, err)
+	require.Equal(t, []Flag{
+		{Name: "file", Values: []string{"ios/App.xcworkspace"}},
+		{Name: "scheme", Values: []string{"App", "App Extension"}},
+		{Name: "configuration", Values: []string{"Release"}},
+		{Name: "team-id", Values: []string{"ABCD123456"}},
+		{Name: "output-dir", Values: []string{"./signing"}},
+		{Name: "write-files", Values: []string{"fallback"}},
+		{Name: "pem", Values: []string{"true"}},
+		{Name: "p12-per-identity", Values: []string{"false"}},
+		{Name: "upload-to", Values: []string{"s3://signing-assets/ios"}, Destination: true},
+		{Name: "secret-store", Values: []string{"vault://secret/ios/signing"}, Destination: true},
+	}, flags)
+
+	flags, err = config.Flags("xamarin")
+	require.NoError(t, err)
+	require.Equal(t, Flag{Name: "config", Values: []string{"Release"}}, flags[1])
+
+	_, err = config.Flags("xcodeuitests")
+	require.Error(t, err, "the multiple schemes are not supported")
+
+	flags, err = config.Flags("push")
+	require.NoError(t, err)
+	require.Equal(t, "team-id", flags[0].Name, "the project flags are not used")
+}
+
+func TestNewFromFile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "projectconfig")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tmpDir))
+	}()
+
+	pth := filepath.Join(tmpDir, FileName)
+	require.NoError(t, ioutil.WriteFile(pth, []byte(strings.Replace(testConfig, "  pem: true\n", "  format_output: /tmp/scan.json\n  html_report: report.html\n", 1)), 0600))
+
+	config, err := NewFromFile(pth)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(tmpDir, "ios", "App.xcworkspace"), config.Project, "the relative paths are resolved against the directory of the config file")
+	require.Equal(t, filepath.Join(tmpDir, "signing"), config.Output.Dir)
+	require.Equal(t, filepath.Join(tmpDir, "report.html"), config.Output.HTMLReport)
+	require.Equal(t, "/tmp/scan.json", config.Output.FormatOutput, "the absolute paths are kept")
+	require.Equal(t, "s3://signing-assets/ios", config.Upload.To)
+}
+
+func TestNewUnknownKey(t *testing.T) {
+	_, err := New([]byte("scheme: App\n"))
+	require.Error(t, err)
+}
@@ -0,0 +1,34 @@
+//go:build !windows
+// +build !windows
+
+package prompt
+
+import (
+	"io"
+
+	"github.com/bitrise-io/goinp/goinp"
+)
+
+func askForBoolWithDefault(question string, defaultValue bool) (bool, error) {
+	return goinp.AskForBoolWithDefault(question, defaultValue)
+}
+
+func askForBoolFromReader(question string, input io.Reader) (bool, error) {
+	return goinp.AskForBoolFromReader(question, input)
+}
+
+func askForStringFromReader(question string, input io.Reader) (string, error) {
+	return goinp.AskForStringFromReader(question, input)
+}
+
+func askForStringFromReaderWithDefault(question, defaultValue string, input io.Reader) (string, error) {
+	return goinp.AskForStringFromReaderWithDefault(question, defaultValue, input)
+}
+
+func askForPath(question string) (string, error) {
+	return goinp.AskForPath(question)
+}
+
+func selectFromStringsWithDefault(question string, defaultValue int, options []string) (string, error) {
+	return goinp.SelectFromStringsWithDefault(question, defaultValue, options)
+}
@@ -0,0 +1,106 @@
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// goinp writes the default answers into the terminal input buffer with an ioctl which is not available on Windows,
+// the questions read the answers line by line here instead.
+
+func askForBoolWithDefault(question string, defaultValue bool) (bool, error) {
+	keywordYes, keywordNo := "yes", "NO"
+	if defaultValue {
+		keywordYes, keywordNo = "YES", "no"
+	}
+	fmt.Printf("%s [%s/%s]: ", question, keywordYes, keywordNo)
+	answer, err := readLine(os.Stdin)
+	if err != nil {
+		return false, err
+	}
+	if answer == "" {
+		return defaultValue, nil
+	}
+	return parseBool(answer)
+}
+
+func askForBoolFromReader(question string, input io.Reader) (bool, error) {
+	answer, err := askForStringFromReader(question+" [yes/no]", input)
+	if err != nil {
+		return false, err
+	}
+	return parseBool(answer)
+}
+
+func askForStringFromReader(question string, input io.Reader) (string, error) {
+	return askForStringFromReaderWithDefault(question, "", input)
+}
+
+func askForStringFromReaderWithDefault(question, defaultValue string, input io.Reader) (string, error) {
+	if defaultValue == "" {
+		fmt.Printf("%s : ", question)
+	} else {
+		fmt.Printf("%s [%s] : ", question, defaultValue)
+	}
+
+	answer, err := readLine(input)
+	if err != nil {
+		return "", err
+	}
+	if answer == "" {
+		if defaultValue != "" {
+			return defaultValue, nil
+		}
+		return "", errors.New("failed to get input, no answer")
+	}
+	return answer, nil
+}
+
+func askForPath(question string) (string, error) {
+	// the backslashes are the path separators on Windows, they are not removed as escapes
+	return askForStringFromReader(question, os.Stdin)
+}
+
+func selectFromStringsWithDefault(question string, defaultValue int, options []string) (string, error) {
+	fmt.Println(question)
+	fmt.Println("Please select from the list:")
+	for idx, option := range options {
+		fmt.Printf("[%d] : %s\n", idx+1, option)
+	}
+	answer, err := askForStringFromReaderWithDefault("(type in the option's number, then hit Enter)", strconv.Itoa(defaultValue), os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	num, err := strconv.Atoi(answer)
+	if err != nil || num < 1 || num > len(options) {
+		return "", fmt.Errorf("invalid option: %s", answer)
+	}
+	return options[num-1], nil
+}
+
+func readLine(input io.Reader) (string, error) {
+	scanner := bufio.NewScanner(input)
+	answer := ""
+	if scanner.Scan() {
+		answer = strings.TrimSpace(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to get input, error: %s", err)
+	}
+	return answer, nil
+}
+
+func parseBool(answer string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "yes", "y":
+		return true, nil
+	case "no", "n":
+		return false, nil
+	}
+	return strconv.ParseBool(answer)
+}
@@ -0,0 +1,314 @@
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// pickerMaxOptions is the number of options shown at once, the list scrolls with the cursor
+const pickerMaxOptions = 10
+
+// errInterrupted is returned if the picker is closed with Ctrl+C
+var errInterrupted = errors.New("interrupted")
+
+type keyKind int
+
+const (
+	keyRune keyKind = iota
+	keyUp
+	keyDown
+	keySpace
+	keyBackspace
+	keyEnter
+	keyInterrupt
+	keyIgnored
+)
+
+type key struct {
+	kind keyKind
+	r    rune
+}
+
+// picker is the state of the interactive list, kept apart from the terminal handling
+type picker struct {
+	options  []string
+	multi    bool
+	query    string
+	cursor   int
+	selected map[int]bool
+}
+
+func newPicker(options []string, multi bool, cursor int) *picker {
+	if cursor < 0 || cursor >= len(options) {
+		cursor = 0
+	}
+	return &picker{options: options, multi: multi, cursor: cursor, selected: map[int]bool{}}
+}
+
+// visible returns the indexes of the options containing every word of the search query
+func (p *picker) visible() []int {
+	terms := strings.Fields(strings.ToLower(p.query))
+	var indexes []int
+	for idx, option := range p.options {
+		lower := strings.ToLower(option)
+		matches := true
+		for _, term := range terms {
+			if !strings.Contains(lower, term) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			indexes = append(indexes, idx)
+		}
+	}
+	return indexes
+}
+
+// handle applies the key, returns true when the selection is done
+func (p *picker) handle(k key) (bool, error) {
+	visible := p.visible()
+	switch k.kind {
+	case keyUp:
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case keyDown:
+		if p.cursor < len(visible)-1 {
+			p.cursor++
+		}
+	case keySpace:
+		if !p.multi {
+			p.setQuery(p.query + " ")
+		} else if len(visible) > 0 {
+			idx := visible[p.cursor]
+			p.selected[idx] = !p.selected[idx]
+		}
+	case keyBackspace:
+		if runes := []rune(p.query); len(runes) > 0 {
+			p.setQuery(string(runes[:len(runes)-1]))
+		}
+	case keyRune:
+		p.setQuery(p.query + string(k.r))
+	case keyEnter:
+		if len(visible) == 0 {
+			return false, nil
+		}
+		if p.multi && len(p.result()) == 0 {
+			p.selected[visible[p.cursor]] = true
+		}
+		return true, nil
+	case keyInterrupt:
+		return false, errInterrupted
+	}
+	return false, nil
+}
+
+func (p *picker) setQuery(query string) {
+	p.query = query
+	p.cursor = 0
+}
+
+// result returns the option under the cursor, or the selected options in their original order
+func (p *picker) result() []string {
+	if !p.multi {
+		if visible := p.visible(); len(visible) > 0 {
+			return []string{p.options[visible[p.cursor]]}
+		}
+		return nil
+	}
+
+	var indexes []int
+	for idx, selected := range p.selected {
+		if selected {
+			indexes = append(indexes, idx)
+		}
+	}
+	sort.Ints(indexes)
+	var result []string
+	for _, idx := range indexes {
+		result = append(result, p.options[idx])
+	}
+	return result
+}
+
+// lines renders the question, the search query and the visible window of the options
+func (p *picker) lines(question string) []string {
+	help := "↑/↓ to move, type to search, Enter to select"
+	if p.multi {
+		help = "↑/↓ to move, Space to select, type to search, Enter to finish"
+	}
+	lines := []string{question, fmt.Sprintf("(%s) search: %s", help, p.query)}
+
+	visible := p.visible()
+	if len(visible) == 0 {
+		return append(lines, "  no matching option")
+	}
+	start := 0
+	if p.cursor >= pickerMaxOptions {
+		start = p.cursor - pickerMaxOptions + 1
+	}
+	for i := start; i < len(visible) && i < start+pickerMaxOptions; i++ {
+		line := "  "
+		if i == p.cursor {
+			line = "> "
+		}
+		if p.multi {
+			if p.selected[visible[i]] {
+				line += "[x] "
+			} else {
+				line += "[ ] "
+			}
+		}
+		lines = append(lines, line+p.options[visible[i]])
+	}
+	if len(visible) > pickerMaxOptions {
+		lines = append(lines, fmt.Sprintf("  (%d/%d)", p.cursor+1, len(visible)))
+	}
+	return lines
+}
+
+func readKey(reader *bufio.Reader) (key, error) {
+	r, _, err := reader.ReadRune()
+	if err != nil {
+		return key{}, err
+	}
+	switch r {
+	case 3, 4:
+		return key{kind: keyInterrupt}, nil
+	case '\r', '\n':
+		return key{kind: keyEnter}, nil
+	case 127, 8:
+		return key{kind: keyBackspace}, nil
+	case ' ':
+		return key{kind: keySpace}, nil
+	case 27:
+		if next, err := reader.ReadByte(); err != nil || next != '[' && next != 'O' {
+			return key{kind: keyIgnored}, err
+		}
+		code, err := reader.ReadByte()
+		if err != nil {
+			return key{}, err
+		}
+		switch code {
+		case 'A':
+			return key{kind: keyUp}, nil
+		case 'B':
+			return key{kind: keyDown}, nil
+		}
+		return key{kind: keyIgnored}, nil
+	}
+	if r < 32 {
+		return key{kind: keyIgnored}, nil
+	}
+	return key{kind: keyRune, r: r}, nil
+}
+
+// isTerminal returns true if the picker can be shown instead of the numbered list
+func isTerminal() bool {
+	return terminal.IsTerminal(int(os.Stdin.Fd())) && terminal.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// runPicker shows the picker until the selection is done, the terminal is in raw mode meanwhile
+func runPicker(question string, p *picker) ([]string, error) {
+	fd := int(os.Stdin.Fd())
+	state, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up the terminal, error: %s", err)
+	}
+	defer func() {
+		if err := terminal.Restore(fd, state); err != nil {
+			fmt.Printf("Failed to restore the terminal: %s\n", err)
+		}
+	}()
+
+	reader := bufio.NewReader(os.Stdin)
+	drawn := 0
+	for {
+		drawn = redraw(os.Stdout, drawn, p.lines(question))
+
+		k, err := readKey(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input, error: %s", err)
+		}
+		done, err := p.handle(k)
+		if err != nil {
+			redraw(os.Stdout, drawn, nil)
+			return nil, err
+		}
+		if done {
+			break
+		}
+	}
+
+	result := p.result()
+	redraw(os.Stdout, drawn, []string{fmt.Sprintf("%s %s", question, strings.Join(result, ", "))})
+	return result, nil
+}
+
+// redraw replaces the previously drawn lines, returns the number of the drawn lines
+func redraw(w io.Writer, previous int, lines []string) int {
+	if previous > 0 {
+		fmt.Fprintf(w, "\r\033[%dA\033[J", previous)
+	}
+	for _, line := range lines {
+		fmt.Fprint(w, line+"\r\n")
+	}
+	return len(lines)
+}
+
+// MultiSelect asks for any number (at least one) of the options, with the interactive picker on a terminal
+func MultiSelect(question string, options []string) ([]string, error) {
+	if err := Check(question); err != nil {
+		return nil, err
+	}
+	if len(options) == 0 {
+		return nil, errors.New("no options to select from")
+	}
+	if isTerminal() {
+		return runPicker(question, newPicker(options, true, 0))
+	}
+
+	fmt.Println(question)
+	fmt.Println("Please select from the list:")
+	for idx, option := range options {
+		fmt.Printf("[%d] : %s\n", idx+1, option)
+	}
+	answer, err := askForStringFromReaderWithDefault("(type in the options' numbers separated by commas, then hit Enter)", "1", os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	return parseMultiSelectAnswer(answer, options)
+}
+
+// parseMultiSelectAnswer returns the options of the comma separated, 1 based numbers
+func parseMultiSelectAnswer(answer string, options []string) ([]string, error) {
+	seen := map[int]bool{}
+	var selected []string
+	for _, field := range strings.Split(answer, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		num, err := strconv.Atoi(field)
+		if err != nil || num < 1 || num > len(options) {
+			return nil, fmt.Errorf("invalid option: %s", field)
+		}
+		if !seen[num] {
+			seen[num] = true
+			selected = append(selected, options[num-1])
+		}
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("no option selected")
+	}
+	return selected, nil
+}
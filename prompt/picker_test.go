@@ -0,0 +1,78 @@
+package prompt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPicker(t *testing.T) {
+	options := []string{
+		"iPhone Developer: Dev (ABC) [1] - development team: 72SA8V3WYL",
+		"iPhone Distribution: Company [2] - development team: 72SA8V3WYL",
+		"iPhone Distribution: Other [3] - development team: 9NS44DLTN7",
+	}
+
+	p := newPicker(options, false, 1)
+	require.Equal(t, []string{options[1]}, p.result())
+
+	for _, r := range "distri 9ns" {
+		_, err := p.handle(key{kind: keyRune, r: r})
+		require.NoError(t, err)
+	}
+	require.Equal(t, []int{2}, p.visible())
+
+	_, err := p.handle(key{kind: keyRune, r: 'x'})
+	require.NoError(t, err)
+	done, err := p.handle(key{kind: keyEnter})
+	require.NoError(t, err)
+	require.False(t, done)
+
+	_, err = p.handle(key{kind: keyBackspace})
+	require.NoError(t, err)
+	done, err = p.handle(key{kind: keyEnter})
+	require.NoError(t, err)
+	require.True(t, done)
+	require.Equal(t, []string{options[2]}, p.result())
+
+	_, err = p.handle(key{kind: keyInterrupt})
+	require.Equal(t, errInterrupted, err)
+}
+
+func TestMultiPicker(t *testing.T) {
+	options := []string{"Development", "App Store", "Ad Hoc"}
+
+	p := newPicker(options, true, 0)
+	for _, k := range []key{{kind: keyDown}, {kind: keyDown}, {kind: keySpace}, {kind: keyUp}, {kind: keyUp}, {kind: keySpace}, {kind: keyDown}, {kind: keyDown}, {kind: keyDown}} {
+		_, err := p.handle(k)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, p.cursor)
+	require.Equal(t, []string{"> [x] Ad Hoc"}, p.lines("Select")[4:])
+
+	done, err := p.handle(key{kind: keyEnter})
+	require.NoError(t, err)
+	require.True(t, done)
+	require.Equal(t, []string{"Development", "Ad Hoc"}, p.result())
+
+	p = newPicker(options, true, 0)
+	_, err = p.handle(key{kind: keyDown})
+	require.NoError(t, err)
+	_, err = p.handle(key{kind: keyEnter})
+	require.NoError(t, err)
+	require.Equal(t, []string{"App Store"}, p.result())
+}
+
+func TestParseMultiSelectAnswer(t *testing.T) {
+	options := []string{"Development", "App Store", "Ad Hoc"}
+
+	selected, err := parseMultiSelectAnswer("3, 1,3", options)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Ad Hoc", "Development"}, selected)
+
+	_, err = parseMultiSelectAnswer("4", options)
+	require.EqualError(t, err, "invalid option: 4")
+
+	_, err = parseMultiSelectAnswer(" , ", options)
+	require.EqualError(t, err, "no option selected")
+}
@@ -0,0 +1,85 @@
+package prompt
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// NonInteractiveEnvKey enables the non-interactive mode, as the --non-interactive flag
+const NonInteractiveEnvKey = "CODESIGNDOC_NON_INTERACTIVE"
+
+// NonInteractive makes every question fail instead of waiting for the user's input,
+// the answers have to be given by flags or env vars.
+var NonInteractive = os.Getenv(NonInteractiveEnvKey) == "true"
+
+// NonInteractiveError is returned instead of asking a question in non-interactive mode
+type NonInteractiveError struct {
+	Question string
+}
+
+// Error ...
+func (e NonInteractiveError) Error() string {
+	return fmt.Sprintf("input is required in non-interactive mode: %s", firstLine(e.Question))
+}
+
+// Check returns a NonInteractiveError in non-interactive mode
+func Check(question string) error {
+	if NonInteractive {
+		return NonInteractiveError{Question: question}
+	}
+	return nil
+}
+
+func firstLine(s string) string {
+	return strings.TrimSpace(strings.SplitN(strings.TrimSpace(s), "\n", 2)[0])
+}
+
+// AskForBoolWithDefault ...
+func AskForBoolWithDefault(question string, defaultValue bool) (bool, error) {
+	if err := Check(question); err != nil {
+		return false, err
+	}
+	return askForBoolWithDefault(question, defaultValue)
+}
+
+// AskForBoolFromReader ...
+func AskForBoolFromReader(question string, input io.Reader) (bool, error) {
+	if err := Check(question); err != nil {
+		return false, err
+	}
+	return askForBoolFromReader(question, input)
+}
+
+// AskForStringFromReader ...
+func AskForStringFromReader(question string, input io.Reader) (string, error) {
+	if err := Check(question); err != nil {
+		return "", err
+	}
+	return askForStringFromReader(question, input)
+}
+
+// AskForPath ...
+func AskForPath(question string) (string, error) {
+	if err := Check(question); err != nil {
+		return "", err
+	}
+	return askForPath(question)
+}
+
+// SelectFromStringsWithDefault asks for one of the options, with the interactive picker on a terminal
+func SelectFromStringsWithDefault(question string, defaultValue int, options []string) (string, error) {
+	if err := Check(question); err != nil {
+		return "", err
+	}
+	if !isTerminal() || len(options) == 0 {
+		return selectFromStringsWithDefault(question, defaultValue, options)
+	}
+
+	result, err := runPicker(question, newPicker(options, false, defaultValue-1))
+	if err != nil {
+		return "", err
+	}
+	return result[0], nil
+}
@@ -0,0 +1,22 @@
+package prompt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	defer func(value bool) { NonInteractive = value }(NonInteractive)
+
+	NonInteractive = false
+	require.NoError(t, Check("Select the Scheme"))
+
+	NonInteractive = true
+	err := Check(`Do you want to repair these keychains?
+(The legacy files will be kept.)`)
+	require.EqualError(t, err, "input is required in non-interactive mode: Do you want to repair these keychains?")
+
+	_, err = SelectFromStringsWithDefault("Select the Scheme", 1, []string{"App"})
+	require.Equal(t, NonInteractiveError{Question: "Select the Scheme"}, err)
+}
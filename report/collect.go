@@ -0,0 +1,159 @@
+package report
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/bitrise-io/codesigndoc/utility"
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-utils/sliceutil"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	xcodeutility "github.com/bitrise-io/go-xcode/utility"
+)
+
+// Collect creates a report of the code signing setup of this machine
+func Collect() (Report, error) {
+	report := Report{Version: FormatVersion, GeneratedAt: time.Now()}
+
+	if hostname, err := os.Hostname(); err == nil {
+		report.Hostname = hostname
+	}
+	if out, err := command.New("sw_vers", "-productVersion").RunAndReturnTrimmedCombinedOutput(); err == nil {
+		report.OSVersion = out
+	}
+	if xcodeVersion, err := xcodeutility.GetXcodeVersion(); err == nil {
+		report.XcodeVersion = xcodeVersion.Version + " (" + xcodeVersion.BuildVersion + ")"
+	}
+
+	certificates, err := certificateutil.InstalledCodesigningCertificateInfos()
+	if err != nil {
+		return Report{}, err
+	}
+	installerCertificates, err := certificateutil.InstalledInstallerCertificateInfos()
+	if err != nil {
+		return Report{}, err
+	}
+	for _, certificate := range append(certificates, installerCertificates...) {
+		identity := Identity{
+			CommonName:      certificate.CommonName,
+			SHA1Fingerprint: certificate.SHA1Fingerprint,
+			Serial:          certificate.Serial,
+			TeamID:          certificate.TeamID,
+			NotAfter:        certificate.EndDate,
+			Valid:           certificate.CheckValidity() == nil,
+		}
+		trusted, problem, err := osxkeychain.VerifyCodesignTrust(certificate.Certificate)
+		if err != nil {
+			log.Warnf("Failed to evaluate the trust of %s: %s", certificate.CommonName, err)
+		}
+		identity.Trusted, identity.TrustProblem = trusted, problem
+		if identity.Valid {
+			revoked, reason, err := osxkeychain.CheckRevocation(certificate.Certificate)
+			if err != nil {
+				log.Warnf("Failed to check the revocation of %s: %s", certificate.CommonName, err)
+			}
+			identity.Revoked = revoked
+			if revoked {
+				identity.TrustProblem = reason
+			}
+		}
+		report.Identities = append(report.Identities, identity)
+	}
+
+	for _, profileType := range []profileutil.ProfileType{profileutil.ProfileTypeIos, profileutil.ProfileTypeMacOs} {
+		profiles, err := utility.InstalledProfileInfos(profileType)
+		if err != nil {
+			return Report{}, err
+		}
+		for _, profile := range profiles {
+			var serials []string
+			for _, certificate := range profile.DeveloperCertificates {
+				serials = append(serials, certificate.Serial)
+			}
+			sort.Strings(serials)
+
+			report.Profiles = append(report.Profiles, Profile{
+				UUID:               profile.UUID,
+				Name:               profile.Name,
+				BundleID:           profile.BundleID,
+				TeamID:             profile.TeamID,
+				ExportType:         string(profile.ExportType),
+				Type:               string(profileType),
+				ExpirationDate:     profile.ExpirationDate,
+				CertificateSerials: serials,
+			})
+		}
+	}
+
+	if report.Keychains.Default, err = osxkeychain.DefaultKeychain(); err != nil {
+		return Report{}, err
+	}
+	if report.Keychains.SearchList, err = osxkeychain.SearchList(); err != nil {
+		return Report{}, err
+	}
+	legacyKeychains, err := osxkeychain.FindLegacyKeychains()
+	if err != nil {
+		return Report{}, err
+	}
+	for _, keychain := range legacyKeychains {
+		report.Keychains.Legacy = append(report.Keychains.Legacy, keychain.Path)
+	}
+
+	// the keychain paths are compared across machines, so the user specific home directory is replaced
+	report.Keychains.Default = homeRelative(report.Keychains.Default)
+	for i, pth := range report.Keychains.SearchList {
+		report.Keychains.SearchList[i] = homeRelative(pth)
+	}
+	for i, pth := range report.Keychains.Legacy {
+		report.Keychains.Legacy[i] = homeRelative(pth)
+	}
+
+	return report, nil
+}
+
+// CollectScanResult describes the installed identities and profiles of the teams as a scan result,
+// to compare the current state of the machine against a stored scan result, every team is included if teamIDs is empty
+func CollectScanResult(teamIDs []string) (ScanResult, error) {
+	inTeams := func(teamID string) bool {
+		return len(teamIDs) == 0 || sliceutil.IsStringInSlice(teamID, teamIDs)
+	}
+
+	installedCertificates, err := certificateutil.InstalledCodesigningCertificateInfos()
+	if err != nil {
+		return ScanResult{}, err
+	}
+	var certificates []certificateutil.CertificateInfoModel
+	for _, certificate := range installedCertificates {
+		if inTeams(certificate.TeamID) {
+			certificates = append(certificates, certificate)
+		}
+	}
+
+	var profiles []profileutil.ProvisioningProfileInfoModel
+	for _, profileType := range []profileutil.ProfileType{profileutil.ProfileTypeIos, profileutil.ProfileTypeMacOs} {
+		installedProfiles, err := utility.InstalledProfileInfos(profileType)
+		if err != nil {
+			return ScanResult{}, err
+		}
+		for _, profile := range installedProfiles {
+			if inTeams(profile.TeamID) {
+				profiles = append(profiles, profile)
+			}
+		}
+	}
+	return NewScanResult(certificates, profiles, nil), nil
+}
+
+func homeRelative(pth string) string {
+	home := pathutil.UserHomeDir()
+	if home != "" && strings.HasPrefix(pth, home+"/") {
+		return "~" + strings.TrimPrefix(pth, home)
+	}
+	return pth
+}
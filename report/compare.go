@@ -0,0 +1,204 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Section groups the differences of two reports
+type Section string
+
+// Sections ...
+const (
+	SectionEnvironment Section = "environment"
+	SectionIdentities  Section = "identities"
+	SectionProfiles    Section = "profiles"
+	SectionKeychains   Section = "keychains"
+)
+
+// Difference is a code signing relevant difference of two machines
+type Difference struct {
+	Section Section
+	Message string
+}
+
+// Compare returns the differences of the two reports, the names are used to refer to the machines in the messages
+func Compare(a, b Report, nameA, nameB string) []Difference {
+	var differences []Difference
+	add := func(section Section, format string, v ...interface{}) {
+		differences = append(differences, Difference{Section: section, Message: fmt.Sprintf(format, v...)})
+	}
+
+	// Environment
+	if a.XcodeVersion != b.XcodeVersion {
+		add(SectionEnvironment, "Xcode version: %s on %s, %s on %s", orNone(a.XcodeVersion), nameA, orNone(b.XcodeVersion), nameB)
+	}
+	if a.OSVersion != b.OSVersion {
+		add(SectionEnvironment, "macOS version: %s on %s, %s on %s", orNone(a.OSVersion), nameA, orNone(b.OSVersion), nameB)
+	}
+
+	// Identities
+	identitiesA, identitiesB := identitiesByFingerprint(a), identitiesByFingerprint(b)
+	for _, fingerprint := range identityFingerprints(identitiesA, identitiesB) {
+		identityA, inA := identitiesA[fingerprint]
+		identityB, inB := identitiesB[fingerprint]
+		switch {
+		case !inB:
+			add(SectionIdentities, "%s [%s] is only installed on %s", identityA.CommonName, fingerprint, nameA)
+		case !inA:
+			add(SectionIdentities, "%s [%s] is only installed on %s", identityB.CommonName, fingerprint, nameB)
+		default:
+			if identityA.Valid != identityB.Valid {
+				add(SectionIdentities, "%s [%s] is %s on %s, %s on %s", identityA.CommonName, fingerprint, validity(identityA.Valid), nameA, validity(identityB.Valid), nameB)
+			}
+			if identityA.Trusted != identityB.Trusted || identityA.Revoked != identityB.Revoked {
+				add(SectionIdentities, "%s [%s] is %s on %s, %s on %s", identityA.CommonName, fingerprint, trust(identityA), nameA, trust(identityB), nameB)
+			}
+		}
+	}
+
+	// Profiles
+	profilesA, profilesB := profilesByUUID(a), profilesByUUID(b)
+	for _, uuid := range profileUUIDs(profilesA, profilesB) {
+		profileA, inA := profilesA[uuid]
+		profileB, inB := profilesB[uuid]
+		if !inB {
+			add(SectionProfiles, "%s (%s, %s) is only installed on %s", profileA.Name, profileA.BundleID, uuid, nameA)
+		} else if !inA {
+			add(SectionProfiles, "%s (%s, %s) is only installed on %s", profileB.Name, profileB.BundleID, uuid, nameB)
+		}
+	}
+	for _, machine := range []struct {
+		name       string
+		profiles   map[string]Profile
+		identities map[string]Identity
+	}{{nameA, profilesA, identitiesA}, {nameB, profilesB, identitiesB}} {
+		serials := map[string]bool{}
+		for _, identity := range machine.identities {
+			serials[identity.Serial] = true
+		}
+		for _, uuid := range profileUUIDs(machine.profiles) {
+			profile := machine.profiles[uuid]
+			if !hasAnySerial(profile.CertificateSerials, serials) {
+				add(SectionProfiles, "%s (%s, %s) is installed on %s, but none of its certificates are", profile.Name, profile.BundleID, uuid, machine.name)
+			}
+		}
+	}
+
+	// Keychains
+	if a.Keychains.Default != b.Keychains.Default {
+		add(SectionKeychains, "default keychain: %s on %s, %s on %s", orNone(a.Keychains.Default), nameA, orNone(b.Keychains.Default), nameB)
+	}
+	for _, pth := range onlyIn(a.Keychains.SearchList, b.Keychains.SearchList) {
+		add(SectionKeychains, "%s is only in the keychain search list of %s", pth, nameA)
+	}
+	for _, pth := range onlyIn(b.Keychains.SearchList, a.Keychains.SearchList) {
+		add(SectionKeychains, "%s is only in the keychain search list of %s", pth, nameB)
+	}
+	for _, machine := range []struct {
+		name   string
+		legacy []string
+	}{{nameA, a.Keychains.Legacy}, {nameB, b.Keychains.Legacy}} {
+		for _, pth := range machine.legacy {
+			add(SectionKeychains, "%s on %s is in the legacy .keychain format", pth, machine.name)
+		}
+	}
+
+	return differences
+}
+
+func identitiesByFingerprint(report Report) map[string]Identity {
+	identities := map[string]Identity{}
+	for _, identity := range report.Identities {
+		identities[identity.SHA1Fingerprint] = identity
+	}
+	return identities
+}
+
+func profilesByUUID(report Report) map[string]Profile {
+	profiles := map[string]Profile{}
+	for _, profile := range report.Profiles {
+		profiles[profile.UUID] = profile
+	}
+	return profiles
+}
+
+func identityFingerprints(maps ...map[string]Identity) []string {
+	keySet := map[string]bool{}
+	for _, m := range maps {
+		for key := range m {
+			keySet[key] = true
+		}
+	}
+	return sortedSet(keySet)
+}
+
+func profileUUIDs(maps ...map[string]Profile) []string {
+	keySet := map[string]bool{}
+	for _, m := range maps {
+		for key := range m {
+			keySet[key] = true
+		}
+	}
+	return sortedSet(keySet)
+}
+
+func sortedSet(set map[string]bool) []string {
+	var keys []string
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func onlyIn(list, other []string) []string {
+	otherSet := map[string]bool{}
+	for _, item := range other {
+		otherSet[item] = true
+	}
+
+	var only []string
+	for _, item := range list {
+		if !otherSet[item] {
+			only = append(only, item)
+		}
+	}
+	return only
+}
+
+func hasAnySerial(serials []string, installed map[string]bool) bool {
+	for _, serial := range serials {
+		if installed[serial] {
+			return true
+		}
+	}
+	return false
+}
+
+func validity(valid bool) string {
+	if valid {
+		return "valid"
+	}
+	return "expired"
+}
+
+func trust(identity Identity) string {
+	if identity.Revoked {
+		return "revoked (" + identity.TrustProblem + ")"
+	}
+	if identity.Trusted {
+		return "trusted"
+	}
+	if identity.TrustProblem != "" {
+		return "not trusted (" + identity.TrustProblem + ")"
+	}
+	return "not trusted"
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
@@ -0,0 +1,40 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	a := Report{
+		XcodeVersion: "11.3 (11C29)",
+		Identities: []Identity{
+			{CommonName: "iPhone Distribution: Company", SHA1Fingerprint: "AA", Serial: "1", Valid: true, Trusted: true},
+			{CommonName: "iPhone Developer: Alice", SHA1Fingerprint: "BB", Serial: "2", Valid: true, Trusted: true},
+		},
+		Profiles: []Profile{
+			{UUID: "p1", Name: "App Store", BundleID: "com.company.app", CertificateSerials: []string{"1"}},
+		},
+		Keychains: Keychains{Default: "~/Library/Keychains/login.keychain-db", SearchList: []string{"~/Library/Keychains/login.keychain-db"}},
+	}
+	b := Report{
+		XcodeVersion: "11.3 (11C29)",
+		Identities: []Identity{
+			{CommonName: "iPhone Distribution: Company", SHA1Fingerprint: "AA", Serial: "1", Valid: true, Trusted: false, TrustProblem: "CSSMERR_TP_NOT_TRUSTED"},
+		},
+		Profiles: []Profile{
+			{UUID: "p1", Name: "App Store", BundleID: "com.company.app", CertificateSerials: []string{"1"}},
+			{UUID: "p2", Name: "Dev", BundleID: "com.company.app", CertificateSerials: []string{"2"}},
+		},
+		Keychains: Keychains{Default: "~/Library/Keychains/login.keychain-db", SearchList: []string{"~/Library/Keychains/login.keychain-db", "/tmp/ci.keychain-db"}},
+	}
+
+	require.Equal(t, []Difference{
+		{SectionIdentities, "iPhone Distribution: Company [AA] is trusted on alice, not trusted (CSSMERR_TP_NOT_TRUSTED) on ci"},
+		{SectionIdentities, "iPhone Developer: Alice [BB] is only installed on alice"},
+		{SectionProfiles, "Dev (com.company.app, p2) is only installed on ci"},
+		{SectionProfiles, "Dev (com.company.app, p2) is installed on ci, but none of its certificates are"},
+		{SectionKeychains, "/tmp/ci.keychain-db is only in the keychain search list of ci"},
+	}, Compare(a, b, "alice", "ci"))
+}
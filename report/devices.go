@@ -0,0 +1,84 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ProfileDevices are the devices provisioned by an ad-hoc profile
+type ProfileDevices struct {
+	Count int      `json:"count"`
+	UDIDs []string `json:"udids"`
+	// MissingTestDevices are the test devices of the project which the profile does not provision
+	MissingTestDevices []string `json:"missing_test_devices,omitempty"`
+}
+
+// ParseTestDevices reads the UDIDs of the test devices file: a UDID per line, or the device list file of the Apple Developer Portal
+// (a tab separated UDID and name per line, with a header line). The empty lines and the lines starting with # are skipped.
+func ParseTestDevices(content string) []string {
+	var udids []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		udid := strings.Fields(line)[0]
+		if strings.EqualFold(udid, "Device") || strings.EqualFold(udid, "deviceIdentifier") {
+			// header line
+			continue
+		}
+		udids = append(udids, udid)
+	}
+	return udids
+}
+
+// AddProfileDevices checks the devices of the ad-hoc profiles against the test devices,
+// and replaces the UDIDs with their redacted form (see RedactUDID) if redact is set
+func AddProfileDevices(profiles []ScanProfile, testDevices []string, redact bool) {
+	for _, profile := range profiles {
+		devices := profile.Devices
+		if devices == nil {
+			continue
+		}
+
+		provisioned := map[string]bool{}
+		for _, udid := range devices.UDIDs {
+			provisioned[strings.ToLower(udid)] = true
+		}
+		devices.MissingTestDevices = nil
+		for _, udid := range testDevices {
+			if !provisioned[strings.ToLower(udid)] {
+				devices.MissingTestDevices = append(devices.MissingTestDevices, udid)
+			}
+		}
+
+		if redact {
+			for i, udid := range devices.UDIDs {
+				devices.UDIDs[i] = RedactUDID(udid)
+			}
+			for i, udid := range devices.MissingTestDevices {
+				devices.MissingTestDevices[i] = RedactUDID(udid)
+			}
+		}
+	}
+}
+
+// RedactUDID keeps the first 5 and the last 4 characters of the UDID, enough to tell the devices apart
+func RedactUDID(udid string) string {
+	if len(udid) <= 9 {
+		return strings.Repeat("*", len(udid))
+	}
+	return udid[:5] + "..." + udid[len(udid)-4:]
+}
+
+// DeviceWarnings returns a warning for every ad-hoc profile not provisioning some of the test devices
+func DeviceWarnings(profiles []ScanProfile) []string {
+	var warnings []string
+	for _, profile := range profiles {
+		if profile.Devices == nil || len(profile.Devices.MissingTestDevices) == 0 {
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("Profile %s (%s) does not provision %d test device(s): %s", profile.Name, profile.UUID, len(profile.Devices.MissingTestDevices), strings.Join(profile.Devices.MissingTestDevices, ", ")))
+	}
+	return warnings
+}
@@ -0,0 +1,28 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTestDevices(t *testing.T) {
+	content := "Device ID\tDevice Name\tDevice Platform\n00008030-001A2B3C4D5E6F70\tiPhone 11\tIOS\n\n# retired\nabcdef0123456789abcdef0123456789abcdef01\n"
+	require.Equal(t, []string{"00008030-001A2B3C4D5E6F70", "abcdef0123456789abcdef0123456789abcdef01"}, ParseTestDevices(content))
+}
+
+func TestAddProfileDevices(t *testing.T) {
+	profiles := []profileutil.ProvisioningProfileInfoModel{
+		{UUID: "ad-hoc", Name: "Ad Hoc", ExportType: exportoptions.MethodAdHoc, ProvisionedDevices: []string{"00008030-001A2B3C4D5E6F70"}},
+		{UUID: "app-store", Name: "App Store", ExportType: exportoptions.MethodAppStore},
+	}
+	result := NewScanResult(nil, profiles, nil)
+	require.Equal(t, &ProfileDevices{Count: 1, UDIDs: []string{"00008030-001A2B3C4D5E6F70"}}, result.Profiles[0].Devices)
+	require.Nil(t, result.Profiles[1].Devices)
+
+	AddProfileDevices(result.Profiles, []string{"00008030-001a2b3c4d5e6f70", "abcdef0123456789abcdef0123456789abcdef01"}, true)
+	require.Equal(t, &ProfileDevices{Count: 1, UDIDs: []string{"00008...6F70"}, MissingTestDevices: []string{"abcde...ef01"}}, result.Profiles[0].Devices)
+	require.Equal(t, []string{"Profile Ad Hoc (ad-hoc) does not provision 1 test device(s): abcde...ef01"}, DeviceWarnings(result.Profiles))
+}
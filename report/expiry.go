@@ -0,0 +1,90 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultExpiryWarningDays is the default period before the expiry, in days, in which an identity or profile is reported as expiring
+const DefaultExpiryWarningDays = 30
+
+// ExpiryWarning is an identity or profile which is expired, or expires within the warning period
+type ExpiryWarning struct {
+	// Kind is identity or profile
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// ID is the SHA-1 fingerprint of the identity, or the UUID of the profile
+	ID        string    `json:"id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	DaysLeft  int       `json:"days_left"`
+	Expired   bool      `json:"expired"`
+	// Remediation is how to replace the file before the builds using it fail
+	Remediation string `json:"remediation"`
+}
+
+// String describes the warning for the logs
+func (warning ExpiryWarning) String() string {
+	kind := strings.ToUpper(warning.Kind[:1]) + warning.Kind[1:]
+	if warning.Expired {
+		return fmt.Sprintf("%s %s expired on %s", kind, warning.Name, warning.ExpiresAt.Format("2006-01-02"))
+	}
+	return fmt.Sprintf("%s %s expires in %d day(s), on %s", kind, warning.Name, warning.DaysLeft, warning.ExpiresAt.Format("2006-01-02"))
+}
+
+const (
+	identityRemediation = "create a new certificate of the same type in the Apple Developer Portal (or Xcode > Settings > Accounts > Manage Certificates), " +
+		"regenerate the profiles containing the old one, install both, then run codesigndoc again and replace the uploaded files"
+	profileRemediation = "regenerate the profile in the Apple Developer Portal (edit and save it), or let Xcode renew it if it is Xcode managed, " +
+		"install it, then run codesigndoc again and replace the uploaded profile"
+)
+
+// ExpiryWarnings returns the identities and profiles which are expired or expire within the given number of days, ordered by their expiry
+func ExpiryWarnings(identities []ScanIdentity, profiles []ScanProfile, now time.Time, days int) []ExpiryWarning {
+	var warnings []ExpiryWarning
+	add := func(kind, name, id string, expiresAt time.Time, remediation string) {
+		if expiresAt.IsZero() {
+			return
+		}
+		warning := ExpiryWarning{Kind: kind, Name: name, ID: id, ExpiresAt: expiresAt, Remediation: remediation}
+		if !expiresAt.After(now) {
+			warning.Expired = true
+		} else if warning.DaysLeft = daysLeft(expiresAt, now); warning.DaysLeft >= days {
+			return
+		}
+		warnings = append(warnings, warning)
+	}
+
+	for _, identity := range identities {
+		add("identity", identity.CommonName, identity.SHA1Fingerprint, identity.NotAfter, identityRemediation)
+	}
+	for _, profile := range profiles {
+		add("profile", profile.Name, profile.UUID, profile.ExpirationDate, profileRemediation)
+	}
+	sort.SliceStable(warnings, func(i, j int) bool { return warnings[i].ExpiresAt.Before(warnings[j].ExpiresAt) })
+	return warnings
+}
+
+// AddExpiryWarnings lists the identities and profiles of the result expiring within the given number of days
+func (result *ScanResult) AddExpiryWarnings(days int, now time.Time) {
+	result.ExpiryWarningDays = days
+	result.Expiring = ExpiryWarnings(result.Identities, result.Profiles, now, days)
+}
+
+// AddExpiryWarnings lists the identities and profiles of the dry run expiring within the given number of days
+func (result *DryRunResult) AddExpiryWarnings(days int, now time.Time) {
+	result.ExpiryWarningDays = days
+	result.Expiring = ExpiryWarnings(result.Identities, result.Profiles, now, days)
+}
+
+func (result ScanResult) expiryWarningDays() int {
+	if result.ExpiryWarningDays == 0 {
+		return DefaultExpiryWarningDays
+	}
+	return result.ExpiryWarningDays
+}
+
+func daysLeft(expiresAt, now time.Time) int {
+	return int(expiresAt.Sub(now).Hours() / 24)
+}
@@ -0,0 +1,29 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiryWarnings(t *testing.T) {
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	result := ScanResult{
+		Identities: []ScanIdentity{{CommonName: "Apple Distribution: Company", SHA1Fingerprint: "aa", NotAfter: now.Add(45 * 24 * time.Hour)}},
+		Profiles: []ScanProfile{
+			{UUID: "p1", Name: "App Store", ExpirationDate: now.Add(-24 * time.Hour)},
+			{UUID: "p2", Name: "Ad Hoc", ExpirationDate: now.Add(100 * 24 * time.Hour)},
+			{UUID: "p3", Name: "Without expiry"},
+		},
+	}
+
+	result.AddExpiryWarnings(DefaultExpiryWarningDays, now)
+	require.Equal(t, 30, result.ExpiryWarningDays)
+	require.Equal(t, []ExpiryWarning{{Kind: "profile", Name: "App Store", ID: "p1", ExpiresAt: now.Add(-24 * time.Hour), Expired: true, Remediation: profileRemediation}}, result.Expiring)
+
+	result.AddExpiryWarnings(60, now)
+	require.Equal(t, 2, len(result.Expiring))
+	require.Equal(t, ExpiryWarning{Kind: "identity", Name: "Apple Distribution: Company", ID: "aa", ExpiresAt: now.Add(45 * 24 * time.Hour), DaysLeft: 45, Remediation: identityRemediation}, result.Expiring[1])
+	require.Equal(t, "Identity Apple Distribution: Company expires in 45 day(s), on 2030-02-15", result.Expiring[1].String())
+}
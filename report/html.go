@@ -0,0 +1,241 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+)
+
+// timelinePeriod is the full width of the expiry timeline bars
+const timelinePeriod = 365 * 24 * time.Hour
+
+// timelineEntry is an identity or profile on the expiry timeline
+type timelineEntry struct {
+	Kind      string
+	Name      string
+	ExpiresAt time.Time
+	DaysLeft  int
+	// Percent is the width of the bar, the remaining time relative to a year
+	Percent int
+	State   string
+}
+
+// targetMapping is a bundle ID signed by a profile of the scan
+type targetMapping struct {
+	BundleID    string
+	ProfileName string
+	ProfileUUID string
+	ExportType  string
+	Kind        string
+	Reason      string
+}
+
+type htmlReport struct {
+	Result   ScanResult
+	Now      time.Time
+	Timeline []timelineEntry
+	Targets  []targetMapping
+	Warnings []string
+	// Teams is the per team section of a scan with the files of multiple teams
+	Teams []teamGroup
+}
+
+// ScanWarnings returns the problems of the exported identities and profiles a reviewer should know about:
+// the expired and soon expiring files, the ad-hoc profiles missing test devices, and the profiles without an exported identity
+func ScanWarnings(result ScanResult, now time.Time) []string {
+	var warnings []string
+	for _, expiring := range ExpiryWarnings(result.Identities, result.Profiles, now, result.expiryWarningDays()) {
+		warnings = append(warnings, expiring.String())
+	}
+	warnings = append(warnings, DeviceWarnings(result.Profiles)...)
+
+	if len(result.Identities) == 0 {
+		return warnings
+	}
+	serials := map[string]bool{}
+	for _, identity := range result.Identities {
+		serials[identity.Serial] = true
+	}
+	for _, profile := range result.Profiles {
+		found := false
+		for _, serial := range profile.CertificateSerials {
+			if serials[serial] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			warnings = append(warnings, fmt.Sprintf("Profile %s (%s) does not contain any of the exported identities", profile.Name, profile.UUID))
+		}
+	}
+	return warnings
+}
+
+// timeline returns the identities and profiles ordered by their expiry
+func timeline(result ScanResult, now time.Time) []timelineEntry {
+	var entries []timelineEntry
+	add := func(kind, name string, expiresAt time.Time) {
+		if expiresAt.IsZero() {
+			return
+		}
+		entry := timelineEntry{Kind: kind, Name: name, ExpiresAt: expiresAt, DaysLeft: daysLeft(expiresAt, now), State: "ok"}
+		switch {
+		case !expiresAt.After(now):
+			entry.State = "expired"
+			entry.DaysLeft = 0
+		case entry.DaysLeft < result.expiryWarningDays():
+			entry.State = "expiring"
+		}
+		entry.Percent = int(100 * expiresAt.Sub(now) / timelinePeriod)
+		if entry.Percent < 0 {
+			entry.Percent = 0
+		} else if entry.Percent > 100 {
+			entry.Percent = 100
+		}
+		entries = append(entries, entry)
+	}
+	for _, identity := range result.Identities {
+		add("Identity", identity.CommonName, identity.NotAfter)
+	}
+	for _, profile := range result.Profiles {
+		add("Profile", profile.Name, profile.ExpirationDate)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].ExpiresAt.Before(entries[j].ExpiresAt) })
+	return entries
+}
+
+// targetMappings returns the bundle IDs the profiles were chosen for, the profile's own bundle ID if there is no recorded match
+func targetMappings(result ScanResult) []targetMapping {
+	var mappings []targetMapping
+	for _, profile := range result.Profiles {
+		if len(profile.Matches) == 0 {
+			mappings = append(mappings, targetMapping{BundleID: profile.BundleID, ProfileName: profile.Name, ProfileUUID: profile.UUID, ExportType: profile.ExportType})
+			continue
+		}
+		for _, match := range profile.Matches {
+			mappings = append(mappings, targetMapping{
+				BundleID:    match.BundleID,
+				ProfileName: profile.Name,
+				ProfileUUID: profile.UUID,
+				ExportType:  profile.ExportType,
+				Kind:        match.Kind,
+				Reason:      match.Reason,
+			})
+		}
+	}
+	sort.SliceStable(mappings, func(i, j int) bool { return mappings[i].BundleID < mappings[j].BundleID })
+	return mappings
+}
+
+// htmlTeams returns the teams of the per team breakdown with their identities and profiles
+func htmlTeams(result ScanResult) []teamGroup {
+	if len(result.Teams) == 0 {
+		return nil
+	}
+	outputDirs := map[string]string{}
+	for _, team := range result.Teams {
+		if team.OutputDir != "" {
+			outputDirs[team.TeamID] = team.OutputDir
+		}
+	}
+	return groupTeams(result.Identities, result.Profiles, outputDirs)
+}
+
+// RenderHTML writes the self contained HTML summary of the scan, with the warnings of the scan appended to ScanWarnings
+func RenderHTML(w io.Writer, result ScanResult, warnings []string, now time.Time) error {
+	return htmlTemplate.Execute(w, htmlReport{
+		Result:   result,
+		Now:      now,
+		Timeline: timeline(result, now),
+		Targets:  targetMappings(result),
+		Warnings: append(ScanWarnings(result, now), warnings...),
+		Teams:    htmlTeams(result),
+	})
+}
+
+// WriteHTMLReport writes the HTML summary of the scan to the file, it contains no secret so it can be shared
+func WriteHTMLReport(result ScanResult, warnings []string, pth string) error {
+	var b bytes.Buffer
+	if err := RenderHTML(&b, result, warnings, time.Now()); err != nil {
+		return fmt.Errorf("failed to render the HTML report, error: %s", err)
+	}
+	if err := ioutil.WriteFile(pth, b.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write the HTML report, error: %s", err)
+	}
+	return nil
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format("2006-01-02")
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>codesigndoc export summary</title>
+<style>
+body { font-family: -apple-system, Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; width: 100%; }
+th, td { border: 1px solid #ddd; padding: 6px 8px; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+code { font-size: 0.9em; }
+.warnings li { color: #a15c00; }
+.bar { height: 12px; background: #3c9f40; }
+.expiring .bar { background: #e59400; }
+.expired .bar { background: #c62828; }
+.expired td { color: #c62828; }
+</style>
+</head>
+<body>
+<h1>codesigndoc export summary</h1>
+<p>Generated at {{.Now.Format "2006-01-02 15:04 MST"}}.
+{{- if .Result.CertificatesUploaded}} The identities are uploaded to bitrise.io.{{end}}
+{{- if .Result.ProfilesUploaded}} The profiles are uploaded to bitrise.io.{{end}}</p>
+
+<h2>Warnings</h2>
+{{if .Warnings}}<ul class="warnings">
+{{range .Warnings}}<li>{{.}}</li>
+{{end}}</ul>{{else}}<p>No warnings.</p>{{end}}
+
+<h2>Certificates</h2>
+{{if .Result.Identities}}<table>
+<tr><th>Common name</th><th>Team</th><th>Serial</th><th>SHA-1 fingerprint</th><th>Keychain</th><th>Expires</th></tr>
+{{range .Result.Identities}}<tr><td>{{.CommonName}}</td><td>{{.TeamName}} ({{.TeamID}})</td><td><code>{{.Serial}}</code></td><td><code>{{.SHA1Fingerprint}}</code></td><td><code>{{.Keychain}}</code></td><td>{{date .NotAfter}}</td></tr>
+{{end}}</table>{{else}}<p>No certificate was exported.</p>{{end}}
+
+<h2>Expiry timeline</h2>
+{{if .Timeline}}<table>
+<tr><th>Expires</th><th>Days left</th><th>Kind</th><th>Name</th><th style="width: 30%">Remaining (of a year)</th></tr>
+{{range .Timeline}}<tr class="{{.State}}"><td>{{date .ExpiresAt}}</td><td>{{.DaysLeft}}</td><td>{{.Kind}}</td><td>{{.Name}}</td><td><div class="bar" style="width: {{.Percent}}%"></div></td></tr>
+{{end}}</table>{{else}}<p>Nothing to show.</p>{{end}}
+
+<h2>Profiles and targets</h2>
+{{if .Targets}}<table>
+<tr><th>Bundle ID</th><th>Profile</th><th>UUID</th><th>Export method</th><th>Match</th></tr>
+{{range .Targets}}<tr><td>{{.BundleID}}</td><td>{{.ProfileName}}</td><td><code>{{.ProfileUUID}}</code></td><td>{{.ExportType}}</td><td>{{.Kind}}{{if .Reason}}: {{.Reason}}{{end}}</td></tr>
+{{end}}</table>{{else}}<p>No provisioning profile was exported.</p>{{end}}
+
+{{if .Teams}}<h2>Teams</h2>
+{{range .Teams}}<h3>{{if .TeamName}}{{.TeamName}} {{end}}({{if .TeamID}}{{.TeamID}}{{else}}no team{{end}})</h3>
+{{if .OutputDir}}<p>Exported to <code>{{.OutputDir}}</code></p>
+{{end}}<ul>
+{{range .IdentityList}}<li>Certificate {{.CommonName}}, expires {{date .NotAfter}}</li>
+{{end}}{{range .ProfileList}}<li>Profile {{.Name}} ({{.BundleID}}, {{.ExportType}}), expires {{date .ExpirationDate}}</li>
+{{end}}</ul>
+{{end}}{{end}}
+
+{{if .Result.Files}}<h2>Files</h2>
+<ul>
+{{range .Result.Files}}<li><code>{{.}}</code></li>
+{{end}}</ul>{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,45 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderHTML(t *testing.T) {
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	result := ScanResult{
+		Identities: []ScanIdentity{
+			{CommonName: "iPhone Distribution: <Company>", TeamID: "TEAM", Serial: "1", NotAfter: now.Add(10 * 24 * time.Hour)},
+			{CommonName: "iPhone Developer: Dev", TeamID: "TEAM", Serial: "2", NotAfter: now.Add(-time.Hour)},
+		},
+		Profiles: []ScanProfile{
+			{UUID: "p1", Name: "App Store", BundleID: "com.company.*", ExportType: "app-store", ExpirationDate: now.Add(200 * 24 * time.Hour), CertificateSerials: []string{"1"},
+				Matches: []ProfileMatch{{BundleID: "com.company.app", Kind: "wildcard", Reason: "no exact profile"}}},
+			{UUID: "p2", Name: "Other", BundleID: "com.other", ExportType: "development", CertificateSerials: []string{"3"}},
+		},
+	}
+
+	require.Equal(t, []string{
+		"Identity iPhone Developer: Dev expired on 2029-12-31",
+		"Identity iPhone Distribution: <Company> expires in 10 day(s), on 2030-01-11",
+		"Profile Other (p2) does not contain any of the exported identities",
+	}, ScanWarnings(result, now))
+
+	entries := timeline(result, now)
+	require.Equal(t, 3, len(entries))
+	require.Equal(t, "expired", entries[0].State)
+	require.Equal(t, "expiring", entries[1].State)
+	require.Equal(t, timelineEntry{Kind: "Profile", Name: "App Store", ExpiresAt: now.Add(200 * 24 * time.Hour), DaysLeft: 200, Percent: 54, State: "ok"}, entries[2])
+
+	mappings := targetMappings(result)
+	require.Equal(t, []string{"com.company.app", "com.other"}, []string{mappings[0].BundleID, mappings[1].BundleID})
+
+	var b bytes.Buffer
+	require.NoError(t, RenderHTML(&b, result, []string{"not uploaded"}, now))
+	require.Contains(t, b.String(), "iPhone Distribution: &lt;Company&gt;")
+	require.Contains(t, b.String(), "<li>not uploaded</li>")
+	require.Contains(t, b.String(), `style="width: 54%"`)
+}
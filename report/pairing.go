@@ -0,0 +1,123 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/bitrise-io/go-utils/sliceutil"
+)
+
+// PairingKind is the kind of a pairing problem of the exported files
+type PairingKind string
+
+// The pairing problems
+const (
+	// PairingCertificateWithoutProfile: none of the exported profiles contains the certificate
+	PairingCertificateWithoutProfile PairingKind = "certificate_without_profile"
+	// PairingProfileWithoutCertificate: none of the certificates of the profile is exported
+	PairingProfileWithoutCertificate PairingKind = "profile_without_certificate"
+	// PairingProfileExpiresFirst: the profile expires before its exported certificate, it has to be renewed earlier
+	PairingProfileExpiresFirst PairingKind = "profile_expires_before_certificate"
+)
+
+// unpairedCertificateTypes are the certificates which are used without a provisioning profile
+var unpairedCertificateTypes = append([]osxkeychain.CertificateType{osxkeychain.MacDeveloperInstaller, osxkeychain.DeveloperIDInstaller}, osxkeychain.PushCertificateTypes...)
+
+// PairingProblem is an exported identity or profile without its pair in the exported files
+type PairingProblem struct {
+	Kind PairingKind `json:"kind"`
+	Name string      `json:"name"`
+	// ID is the SHA-1 fingerprint of an identity, the UUID of a profile
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+// Incomplete tells whether the problem makes the exported files incomplete for the signing
+func (problem PairingProblem) Incomplete() bool {
+	return problem.Kind != PairingProfileExpiresFirst
+}
+
+func (problem PairingProblem) String() string {
+	return fmt.Sprintf("%s (%s): %s", problem.Name, problem.ID, problem.Message)
+}
+
+// CheckPairing validates the exported identities and profiles as a set: every identity has to be in an exported profile
+// (except the installer and push certificates) and every profile has to have one of its certificates exported
+func CheckPairing(identities []ScanIdentity, profiles []ScanProfile) []PairingProblem {
+	var problems []PairingProblem
+	for _, identity := range identities {
+		if !needsProfile(identity.CommonName) {
+			continue
+		}
+		paired := false
+		for _, profile := range profiles {
+			if sliceutil.IsStringInSlice(identity.Serial, profile.CertificateSerials) {
+				paired = true
+				break
+			}
+		}
+		if !paired {
+			problems = append(problems, PairingProblem{
+				Kind:    PairingCertificateWithoutProfile,
+				Name:    identity.CommonName,
+				ID:      identity.SHA1Fingerprint,
+				Message: "none of the exported profiles contains the certificate, it can not sign on CI",
+			})
+		}
+	}
+
+	for _, profile := range profiles {
+		var paired []ScanIdentity
+		for _, identity := range identities {
+			if sliceutil.IsStringInSlice(identity.Serial, profile.CertificateSerials) {
+				paired = append(paired, identity)
+			}
+		}
+		if len(paired) == 0 {
+			problems = append(problems, PairingProblem{
+				Kind:    PairingProfileWithoutCertificate,
+				Name:    profile.Name,
+				ID:      profile.UUID,
+				Message: fmt.Sprintf("none of its %d certificate(s) is exported, the profile can not be used on CI", len(profile.CertificateSerials)),
+			})
+			continue
+		}
+
+		latest := paired[0]
+		for _, identity := range paired[1:] {
+			if identity.NotAfter.After(latest.NotAfter) {
+				latest = identity
+			}
+		}
+		if profile.ExpirationDate.Before(latest.NotAfter) {
+			problems = append(problems, PairingProblem{
+				Kind:    PairingProfileExpiresFirst,
+				Name:    profile.Name,
+				ID:      profile.UUID,
+				Message: fmt.Sprintf("expires at %s, before its certificate %s (%s), regenerate the profile then", profile.ExpirationDate.Format("2006-01-02"), latest.CommonName, latest.NotAfter.Format("2006-01-02")),
+			})
+		}
+	}
+	return problems
+}
+
+// AddPairingProblems validates the exported identities and profiles of the result as a set
+func (result *ScanResult) AddPairingProblems() {
+	result.PairingProblems = CheckPairing(result.Identities, result.Profiles)
+}
+
+// AddPairingProblems validates the identities and profiles of the dry run as a set
+func (result *DryRunResult) AddPairingProblems() {
+	result.PairingProblems = CheckPairing(result.Identities, result.Profiles)
+}
+
+// needsProfile tells whether the certificate is used with a provisioning profile
+func needsProfile(commonName string) bool {
+	for _, certificateType := range unpairedCertificateTypes {
+		if strings.HasPrefix(commonName, string(certificateType)+":") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,33 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckPairing(t *testing.T) {
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	identities := []ScanIdentity{
+		{CommonName: "Apple Distribution: Company", SHA1Fingerprint: "aa", Serial: "1", NotAfter: now.AddDate(1, 0, 0)},
+		{CommonName: "Apple Development: Developer", SHA1Fingerprint: "bb", Serial: "2", NotAfter: now.AddDate(1, 0, 0)},
+		{CommonName: "Apple Push Services: io.bitrise.app", SHA1Fingerprint: "cc", Serial: "3", NotAfter: now.AddDate(1, 0, 0)},
+	}
+	profiles := []ScanProfile{
+		{UUID: "p1", Name: "App Store", ExpirationDate: now.AddDate(2, 0, 0), CertificateSerials: []string{"1"}},
+		{UUID: "p2", Name: "Ad Hoc", ExpirationDate: now.AddDate(0, 6, 0), CertificateSerials: []string{"1", "9"}},
+		{UUID: "p3", Name: "Development", ExpirationDate: now.AddDate(2, 0, 0), CertificateSerials: []string{"8", "9"}},
+	}
+
+	problems := CheckPairing(identities, profiles)
+	require.Equal(t, 3, len(problems))
+	require.Equal(t, PairingProblem{Kind: PairingCertificateWithoutProfile, Name: "Apple Development: Developer", ID: "bb", Message: "none of the exported profiles contains the certificate, it can not sign on CI"}, problems[0])
+	require.True(t, problems[0].Incomplete())
+	require.Equal(t, PairingProfileExpiresFirst, problems[1].Kind)
+	require.Equal(t, "p2", problems[1].ID)
+	require.False(t, problems[1].Incomplete())
+	require.Equal(t, PairingProblem{Kind: PairingProfileWithoutCertificate, Name: "Development", ID: "p3", Message: "none of its 2 certificate(s) is exported, the profile can not be used on CI"}, problems[2])
+
+	require.Empty(t, CheckPairing(identities[:1], profiles[:1]))
+}
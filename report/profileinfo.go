@@ -0,0 +1,91 @@
+package report
+
+import (
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// ProfileInfo is the decoded content of a provisioning profile file
+type ProfileInfo struct {
+	Path         string    `json:"path"`
+	UUID         string    `json:"uuid"`
+	Name         string    `json:"name"`
+	AppID        string    `json:"app_id"`
+	BundleID     string    `json:"bundle_id"`
+	TeamID       string    `json:"team_id"`
+	TeamName     string    `json:"team_name"`
+	Platform     string    `json:"platform"`
+	ExportType   string    `json:"export_type"`
+	XcodeManaged bool      `json:"xcode_managed"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Expired      bool      `json:"expired"`
+	// DaysLeft is the number of days until the expiry, 0 if expired
+	DaysLeft             int                    `json:"days_left"`
+	Entitlements         map[string]interface{} `json:"entitlements"`
+	Certificates         []ProfileCertificate   `json:"certificates"`
+	ProvisionsAllDevices bool                   `json:"provisions_all_devices,omitempty"`
+	Devices              []string               `json:"devices,omitempty"`
+}
+
+// ProfileCertificate is a developer certificate of a provisioning profile
+type ProfileCertificate struct {
+	CommonName      string    `json:"common_name"`
+	Serial          string    `json:"serial"`
+	SHA1Fingerprint string    `json:"sha1_fingerprint"`
+	TeamID          string    `json:"team_id"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	Expired         bool      `json:"expired"`
+	// InKeychain tells whether the identity of the certificate is in the local keychains, it is missing if the keychain is not available
+	InKeychain *bool `json:"in_keychain,omitempty"`
+}
+
+// NewProfileInfo describes the profile read from the file, installed maps the lowercase SHA-1 fingerprints of the identities
+// found in the keychains, it is nil if the keychains could not be searched
+func NewProfileInfo(pth string, profile profileutil.ProvisioningProfileInfoModel, appID string, installed map[string]bool, now time.Time) ProfileInfo {
+	info := ProfileInfo{
+		Path:                 pth,
+		UUID:                 profile.UUID,
+		Name:                 profile.Name,
+		AppID:                appID,
+		BundleID:             profile.BundleID,
+		TeamID:               profile.TeamID,
+		TeamName:             profile.TeamName,
+		Platform:             string(profile.Type),
+		ExportType:           string(profile.ExportType),
+		XcodeManaged:         profile.IsXcodeManaged(),
+		CreatedAt:            profile.CreationDate,
+		ExpiresAt:            profile.ExpirationDate,
+		Expired:              !profile.ExpirationDate.After(now),
+		Entitlements:         map[string]interface{}(profile.Entitlements),
+		Certificates:         []ProfileCertificate{},
+		ProvisionsAllDevices: profile.ProvisionsAllDevices,
+		Devices:              profile.ProvisionedDevices,
+	}
+	if !info.Expired {
+		info.DaysLeft = daysLeft(profile.ExpirationDate, now)
+	}
+	for _, certificate := range profile.DeveloperCertificates {
+		profileCertificate := ProfileCertificate{
+			CommonName:      certificate.CommonName,
+			Serial:          certificate.Serial,
+			SHA1Fingerprint: certificate.SHA1Fingerprint,
+			TeamID:          certificate.TeamID,
+			ExpiresAt:       certificate.EndDate,
+			Expired:         !certificate.EndDate.After(now),
+		}
+		if installed != nil {
+			inKeychain := installed[strings.ToLower(certificate.SHA1Fingerprint)]
+			profileCertificate.InKeychain = &inKeychain
+		}
+		info.Certificates = append(info.Certificates, profileCertificate)
+	}
+	return info
+}
+
+// WriteProfileInfos writes the profiles as indented JSON to the file, or to the standard output if pth is empty
+func WriteProfileInfos(infos []ProfileInfo, pth string) error {
+	return writeJSON(infos, pth)
+}
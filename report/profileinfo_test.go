@@ -0,0 +1,39 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProfileInfo(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	profile := profileutil.ProvisioningProfileInfoModel{
+		UUID:           "uuid",
+		Name:           "App Store: io.bitrise.app",
+		BundleID:       "io.bitrise.app",
+		TeamID:         "ABCDE12345",
+		ExpirationDate: now.AddDate(0, 0, 10),
+		DeveloperCertificates: []certificateutil.CertificateInfoModel{
+			{CommonName: "Apple Distribution: Bitrise", SHA1Fingerprint: "AA11", EndDate: now.AddDate(1, 0, 0)},
+			{CommonName: "Apple Distribution: Bitrise", SHA1Fingerprint: "bb22", EndDate: now.AddDate(0, 0, -1)},
+		},
+	}
+
+	info := NewProfileInfo("app.mobileprovision", profile, "ABCDE12345.io.bitrise.app", map[string]bool{"aa11": true}, now)
+	require.Equal(t, "ABCDE12345.io.bitrise.app", info.AppID)
+	require.False(t, info.Expired)
+	require.Equal(t, 10, info.DaysLeft)
+	require.Len(t, info.Certificates, 2)
+	require.True(t, *info.Certificates[0].InKeychain)
+	require.False(t, *info.Certificates[1].InKeychain)
+	require.True(t, info.Certificates[1].Expired)
+
+	info = NewProfileInfo("app.mobileprovision", profile, "", nil, now.AddDate(0, 0, 11))
+	require.True(t, info.Expired)
+	require.Zero(t, info.DaysLeft)
+	require.Nil(t, info.Certificates[0].InKeychain)
+}
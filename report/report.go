@@ -0,0 +1,85 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// FormatVersion is the version of the report file format
+const FormatVersion = 1
+
+// Identity is a code signing identity installed on the machine
+type Identity struct {
+	CommonName      string    `json:"common_name"`
+	SHA1Fingerprint string    `json:"sha1_fingerprint"`
+	Serial          string    `json:"serial"`
+	TeamID          string    `json:"team_id"`
+	NotAfter        time.Time `json:"not_after"`
+	Valid           bool      `json:"valid"`
+	Trusted         bool      `json:"trusted"`
+	TrustProblem    string    `json:"trust_problem,omitempty"`
+	// Revoked is set if the OCSP responder of the issuer reports the certificate as revoked
+	Revoked bool `json:"revoked,omitempty"`
+}
+
+// Profile is a provisioning profile installed on the machine
+type Profile struct {
+	UUID               string    `json:"uuid"`
+	Name               string    `json:"name"`
+	BundleID           string    `json:"bundle_id"`
+	TeamID             string    `json:"team_id"`
+	ExportType         string    `json:"export_type"`
+	Type               string    `json:"type"`
+	ExpirationDate     time.Time `json:"expiration_date"`
+	CertificateSerials []string  `json:"certificate_serials"`
+}
+
+// Keychains describes the keychain layout of the machine
+type Keychains struct {
+	Default    string   `json:"default"`
+	SearchList []string `json:"search_list"`
+	Legacy     []string `json:"legacy,omitempty"`
+}
+
+// Report is a snapshot of the code signing setup of a machine
+type Report struct {
+	Version      int        `json:"version"`
+	GeneratedAt  time.Time  `json:"generated_at"`
+	Hostname     string     `json:"hostname"`
+	OSVersion    string     `json:"os_version"`
+	XcodeVersion string     `json:"xcode_version,omitempty"`
+	Identities   []Identity `json:"identities"`
+	Profiles     []Profile  `json:"profiles"`
+	Keychains    Keychains  `json:"keychains"`
+}
+
+// ReadFile reads a report file
+func ReadFile(pth string) (Report, error) {
+	content, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to read report, error: %s", err)
+	}
+
+	var report Report
+	if err := json.Unmarshal(content, &report); err != nil {
+		return Report{}, fmt.Errorf("failed to parse report (%s), error: %s", pth, err)
+	}
+	if report.Version != FormatVersion {
+		return Report{}, fmt.Errorf("unsupported report version (%d) in %s, supported: %d", report.Version, pth, FormatVersion)
+	}
+	return report, nil
+}
+
+// WriteFile writes the report as indented JSON
+func WriteFile(report Report, pth string) error {
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(pth, content, 0600); err != nil {
+		return fmt.Errorf("failed to write report, error: %s", err)
+	}
+	return nil
+}
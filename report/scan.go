@@ -0,0 +1,242 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
+)
+
+// ScanResultVersion is the version of the scan result format
+const ScanResultVersion = 1
+
+// ScanIdentity is an identity exported by a scan
+type ScanIdentity struct {
+	CommonName        string    `json:"common_name"`
+	SHA1Fingerprint   string    `json:"sha1_fingerprint"`
+	SHA256Fingerprint string    `json:"sha256_fingerprint"`
+	Serial            string    `json:"serial"`
+	TeamID            string    `json:"team_id"`
+	TeamName          string    `json:"team_name"`
+	NotAfter          time.Time `json:"not_after"`
+	// Keychain is the keychain file the identity is exported from
+	Keychain string `json:"keychain,omitempty"`
+}
+
+// ScanProfile is a provisioning profile exported by a scan
+type ScanProfile struct {
+	UUID               string    `json:"uuid"`
+	Name               string    `json:"name"`
+	BundleID           string    `json:"bundle_id"`
+	TeamID             string    `json:"team_id"`
+	ExportType         string    `json:"export_type"`
+	ExpirationDate     time.Time `json:"expiration_date"`
+	CertificateSerials []string  `json:"certificate_serials"`
+	// Matches explain why the profile was chosen for the targets
+	Matches []ProfileMatch `json:"matches,omitempty"`
+	// Devices are the provisioned devices of an ad-hoc profile
+	Devices *ProfileDevices `json:"devices,omitempty"`
+}
+
+// ProfileMatch is the rank of a profile among the installed profiles which can sign a target
+type ProfileMatch struct {
+	ProfileUUID string `json:"-"`
+	BundleID    string `json:"bundle_id"`
+	// Kind is exact or wildcard
+	Kind       string `json:"kind"`
+	Rank       int    `json:"rank"`
+	Candidates int    `json:"candidates"`
+	Reason     string `json:"reason"`
+}
+
+// AddProfileMatches attaches the matches to the profiles of the same UUID
+func AddProfileMatches(profiles []ScanProfile, matches []ProfileMatch) {
+	for i := range profiles {
+		for _, match := range matches {
+			if match.ProfileUUID == profiles[i].UUID {
+				profiles[i].Matches = append(profiles[i].Matches, match)
+			}
+		}
+	}
+}
+
+// AddIdentityKeychains sets the source keychain of the identities, keychains are the keychain paths by SHA-1 fingerprint
+func AddIdentityKeychains(identities []ScanIdentity, keychains map[string]string) {
+	for i := range identities {
+		identities[i].Keychain = keychains[identities[i].SHA1Fingerprint]
+	}
+}
+
+// SchemeFiles are the identities and profiles needed by one of the schemes (in one of the configurations) of a multi-scheme scan
+type SchemeFiles struct {
+	Scheme string `json:"scheme"`
+	// Configuration is the build configuration the scheme was archived with, empty for the archive configuration of the scheme
+	Configuration string `json:"configuration,omitempty"`
+	// Identities are the SHA-1 fingerprints of the identities
+	Identities []string `json:"identities"`
+	// Profiles are the UUIDs of the profiles
+	Profiles []string `json:"profiles"`
+	// Error is set if the scheme could not be scanned, its files are not part of the result
+	Error string `json:"error,omitempty"`
+}
+
+// NewSchemeFiles lists the identities and profiles needed by the scheme
+func NewSchemeFiles(scheme string, certificates []certificateutil.CertificateInfoModel, profiles []profileutil.ProvisioningProfileInfoModel) SchemeFiles {
+	files := SchemeFiles{Scheme: scheme, Identities: []string{}, Profiles: []string{}}
+	for _, certificate := range certificates {
+		files.Identities = append(files.Identities, certificate.SHA1Fingerprint)
+	}
+	for _, profile := range profiles {
+		files.Profiles = append(files.Profiles, profile.UUID)
+	}
+	return files
+}
+
+// Label names the scheme with its configuration for the logs
+func (files SchemeFiles) Label() string {
+	if files.Configuration == "" {
+		return files.Scheme
+	}
+	return fmt.Sprintf("%s (%s)", files.Scheme, files.Configuration)
+}
+
+// ScanResult is the machine readable result of a scan
+type ScanResult struct {
+	Version              int            `json:"version"`
+	GeneratedAt          time.Time      `json:"generated_at"`
+	Identities           []ScanIdentity `json:"identities"`
+	Profiles             []ScanProfile  `json:"profiles"`
+	Files                []string       `json:"files"`
+	CertificatesUploaded bool           `json:"certificates_uploaded"`
+	ProfilesUploaded     bool           `json:"profiles_uploaded"`
+	// BitriseYMLSnippet is the bitrise.yml steps fragment installing the exported files
+	BitriseYMLSnippet string `json:"bitrise_yml_snippet,omitempty"`
+	// Expiring lists the identities and profiles expiring within ExpiryWarningDays
+	Expiring          []ExpiryWarning `json:"expiring,omitempty"`
+	ExpiryWarningDays int             `json:"expiry_warning_days,omitempty"`
+	// PairingProblems lists the exported identities without an exported profile and the other way around
+	PairingProblems []PairingProblem `json:"pairing_problems,omitempty"`
+	// Schemes is the per scheme breakdown of a multi-scheme scan
+	Schemes []SchemeFiles `json:"schemes,omitempty"`
+	// Teams is the per team breakdown of a scan with the files of multiple teams
+	Teams []TeamFiles `json:"teams,omitempty"`
+}
+
+// NewScanResult describes the exported identities and profiles, files are the paths of the written files
+func NewScanResult(certificates []certificateutil.CertificateInfoModel, profiles []profileutil.ProvisioningProfileInfoModel, files []string) ScanResult {
+	result := ScanResult{
+		Version:     ScanResultVersion,
+		GeneratedAt: time.Now(),
+		Identities:  []ScanIdentity{},
+		Profiles:    []ScanProfile{},
+		Files:       append([]string{}, files...),
+	}
+
+	for _, certificate := range certificates {
+		identity := ScanIdentity{
+			CommonName:      certificate.CommonName,
+			SHA1Fingerprint: certificate.SHA1Fingerprint,
+			Serial:          certificate.Serial,
+			TeamID:          certificate.TeamID,
+			TeamName:        certificate.TeamName,
+			NotAfter:        certificate.EndDate,
+		}
+		if len(certificate.Certificate.Raw) > 0 {
+			identity.SHA256Fingerprint = fmt.Sprintf("%x", sha256.Sum256(certificate.Certificate.Raw))
+		}
+		result.Identities = append(result.Identities, identity)
+	}
+
+	for _, profile := range profiles {
+		serials := []string{}
+		for _, certificate := range profile.DeveloperCertificates {
+			serials = append(serials, certificate.Serial)
+		}
+		scanProfile := ScanProfile{
+			UUID:               profile.UUID,
+			Name:               profile.Name,
+			BundleID:           profile.BundleID,
+			TeamID:             profile.TeamID,
+			ExportType:         string(profile.ExportType),
+			ExpirationDate:     profile.ExpirationDate,
+			CertificateSerials: serials,
+		}
+		if profile.ExportType == exportoptions.MethodAdHoc {
+			scanProfile.Devices = &ProfileDevices{Count: len(profile.ProvisionedDevices), UDIDs: append([]string{}, profile.ProvisionedDevices...)}
+		}
+		result.Profiles = append(result.Profiles, scanProfile)
+	}
+	return result
+}
+
+// PlannedFile is a file which a dry run would write
+type PlannedFile struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size,omitempty"`
+	Estimated bool   `json:"estimated,omitempty"`
+}
+
+// DryRunResult is the machine readable result of a dry run scan
+type DryRunResult struct {
+	Version     int            `json:"version"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	DryRun      bool           `json:"dry_run"`
+	Identities  []ScanIdentity `json:"identities"`
+	Profiles    []ScanProfile  `json:"profiles"`
+	Files       []PlannedFile  `json:"files"`
+	Upload      bool           `json:"upload"`
+	// Expiring lists the identities and profiles expiring within ExpiryWarningDays
+	Expiring          []ExpiryWarning `json:"expiring,omitempty"`
+	ExpiryWarningDays int             `json:"expiry_warning_days,omitempty"`
+	// PairingProblems lists the exported identities without an exported profile and the other way around
+	PairingProblems []PairingProblem `json:"pairing_problems,omitempty"`
+	// Schemes is the per scheme breakdown of a multi-scheme scan
+	Schemes []SchemeFiles `json:"schemes,omitempty"`
+	// Teams is the per team breakdown of a scan with the files of multiple teams
+	Teams []TeamFiles `json:"teams,omitempty"`
+}
+
+// NewDryRunResult describes the identities and profiles which would be exported, and the files which would be written
+func NewDryRunResult(certificates []certificateutil.CertificateInfoModel, profiles []profileutil.ProvisioningProfileInfoModel, files []PlannedFile, upload bool) DryRunResult {
+	scan := NewScanResult(certificates, profiles, nil)
+	return DryRunResult{
+		Version:     ScanResultVersion,
+		GeneratedAt: scan.GeneratedAt,
+		DryRun:      true,
+		Identities:  scan.Identities,
+		Profiles:    scan.Profiles,
+		Files:       append([]PlannedFile{}, files...),
+		Upload:      upload,
+	}
+}
+
+// WriteScanResult writes the result as indented JSON to the file, or to the standard output if pth is empty
+func WriteScanResult(result ScanResult, pth string) error {
+	return writeJSON(result, pth)
+}
+
+// WriteDryRunResult writes the result as indented JSON to the file, or to the standard output if pth is empty
+func WriteDryRunResult(result DryRunResult, pth string) error {
+	return writeJSON(result, pth)
+}
+
+func writeJSON(result interface{}, pth string) error {
+	content, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	if pth == "" {
+		_, err := fmt.Fprintln(os.Stdout, string(content))
+		return err
+	}
+	if err := ioutil.WriteFile(pth, content, 0600); err != nil {
+		return fmt.Errorf("failed to write scan result, error: %s", err)
+	}
+	return nil
+}
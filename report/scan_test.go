@@ -0,0 +1,62 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewScanResult(t *testing.T) {
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	certificate := certificateutil.CertificateInfoModel{CommonName: "iPhone Distribution: Company", TeamID: "TEAM", SHA1Fingerprint: "aa", Serial: "1", EndDate: notAfter}
+	profile := profileutil.ProvisioningProfileInfoModel{UUID: "p1", Name: "App Store", BundleID: "com.company.app", TeamID: "TEAM", ExportType: exportoptions.MethodAppStore, DeveloperCertificates: []certificateutil.CertificateInfoModel{certificate}}
+
+	result := NewScanResult([]certificateutil.CertificateInfoModel{certificate}, []profileutil.ProvisioningProfileInfoModel{profile}, []string{"/exports/Identities.p12"})
+	require.Equal(t, ScanResultVersion, result.Version)
+	require.Equal(t, []ScanIdentity{{CommonName: "iPhone Distribution: Company", SHA1Fingerprint: "aa", Serial: "1", TeamID: "TEAM", NotAfter: notAfter}}, result.Identities)
+	require.Equal(t, []ScanProfile{{UUID: "p1", Name: "App Store", BundleID: "com.company.app", TeamID: "TEAM", ExportType: "app-store", CertificateSerials: []string{"1"}}}, result.Profiles)
+	require.Equal(t, []string{"/exports/Identities.p12"}, result.Files)
+
+	match := ProfileMatch{ProfileUUID: "p1", BundleID: "com.company.app", Kind: "exact", Rank: 1, Candidates: 2, Reason: "ranked first"}
+	AddProfileMatches(result.Profiles, []ProfileMatch{match, {ProfileUUID: "other"}})
+	require.Equal(t, []ProfileMatch{match}, result.Profiles[0].Matches)
+
+	AddIdentityKeychains(result.Identities, map[string]string{"aa": "/Users/vagrant/Library/Keychains/ci.keychain-db"})
+	require.Equal(t, "/Users/vagrant/Library/Keychains/ci.keychain-db", result.Identities[0].Keychain)
+
+	empty := NewScanResult(nil, nil, nil)
+	require.NotNil(t, empty.Identities)
+	require.NotNil(t, empty.Profiles)
+}
+
+func TestNewDryRunResult(t *testing.T) {
+	certificate := certificateutil.CertificateInfoModel{CommonName: "iPhone Distribution: Company", TeamID: "TEAM", SHA1Fingerprint: "aa", Serial: "1"}
+
+	result := NewDryRunResult([]certificateutil.CertificateInfoModel{certificate}, nil, []PlannedFile{{Path: "/exports/Identities.p12", Size: 3200, Estimated: true}}, false)
+	require.True(t, result.DryRun)
+	require.Equal(t, ScanResultVersion, result.Version)
+	require.Equal(t, "iPhone Distribution: Company", result.Identities[0].CommonName)
+	require.NotNil(t, result.Profiles)
+	require.Equal(t, []PlannedFile{{Path: "/exports/Identities.p12", Size: 3200, Estimated: true}}, result.Files)
+}
+
+func TestNewSchemeFiles(t *testing.T) {
+	certificate := certificateutil.CertificateInfoModel{SHA1Fingerprint: "aa"}
+	profile := profileutil.ProvisioningProfileInfoModel{UUID: "p1"}
+
+	files := NewSchemeFiles("App", []certificateutil.CertificateInfoModel{certificate}, []profileutil.ProvisioningProfileInfoModel{profile})
+	require.Equal(t, SchemeFiles{Scheme: "App", Identities: []string{"aa"}, Profiles: []string{"p1"}}, files)
+
+	empty := NewSchemeFiles("Framework", nil, nil)
+	require.NotNil(t, empty.Identities)
+	require.NotNil(t, empty.Profiles)
+}
+
+func TestSchemeFilesLabel(t *testing.T) {
+	require.Equal(t, "App", SchemeFiles{Scheme: "App"}.Label())
+	require.Equal(t, "App (Debug)", SchemeFiles{Scheme: "App", Configuration: "Debug"}.Label())
+}
@@ -0,0 +1,238 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+)
+
+// ChangeKind is the kind of a difference of two scan results
+type ChangeKind string
+
+// ChangeKinds ...
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	// ChangeRenewed is an identity or profile replaced by a new one of the same name, e.g. after a certificate rotation
+	ChangeRenewed ChangeKind = "renewed"
+)
+
+// IdentityChange is an identity added, removed or renewed since the baseline
+type IdentityChange struct {
+	Kind ChangeKind `json:"kind"`
+	// Old is the identity of the baseline, nil if it is added
+	Old *ScanIdentity `json:"old,omitempty"`
+	// New is the current identity, nil if it is removed
+	New *ScanIdentity `json:"new,omitempty"`
+}
+
+// ProfileChange is a profile added, removed or renewed since the baseline
+type ProfileChange struct {
+	Kind ChangeKind   `json:"kind"`
+	Old  *ScanProfile `json:"old,omitempty"`
+	New  *ScanProfile `json:"new,omitempty"`
+}
+
+// ScanDiff is the difference of two scan results
+type ScanDiff struct {
+	Identities []IdentityChange `json:"identities"`
+	Profiles   []ProfileChange  `json:"profiles"`
+}
+
+// Empty reports whether nothing changed
+func (diff ScanDiff) Empty() bool {
+	return len(diff.Identities) == 0 && len(diff.Profiles) == 0
+}
+
+// ReadScanResult reads the identities and profiles of a scan result or a dry run result file (--format json)
+func ReadScanResult(pth string) (ScanResult, error) {
+	content, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("failed to read scan result, error: %s", err)
+	}
+
+	// the files of a dry run are not paths, they are left out
+	var result struct {
+		Version     int            `json:"version"`
+		GeneratedAt time.Time      `json:"generated_at"`
+		Identities  []ScanIdentity `json:"identities"`
+		Profiles    []ScanProfile  `json:"profiles"`
+	}
+	if err := json.Unmarshal(content, &result); err != nil {
+		return ScanResult{}, fmt.Errorf("failed to parse scan result (%s), error: %s", pth, err)
+	}
+	if result.Version != ScanResultVersion {
+		return ScanResult{}, fmt.Errorf("unsupported scan result version (%d) in %s, supported: %d", result.Version, pth, ScanResultVersion)
+	}
+	return ScanResult{Version: result.Version, GeneratedAt: result.GeneratedAt, Identities: result.Identities, Profiles: result.Profiles}, nil
+}
+
+// DiffScanResults returns the identities and profiles added, removed or renewed since the baseline.
+// An identity is renewed if a removed one has the same common name and team, a profile if a removed one
+// has the same name, bundle ID, team and export type.
+func DiffScanResults(baseline, current ScanResult) ScanDiff {
+	diff := ScanDiff{Identities: []IdentityChange{}, Profiles: []ProfileChange{}}
+
+	// Identities
+	oldIdentities, newIdentities := map[string]ScanIdentity{}, map[string]ScanIdentity{}
+	for _, identity := range baseline.Identities {
+		oldIdentities[identity.SHA1Fingerprint] = identity
+	}
+	for _, identity := range current.Identities {
+		newIdentities[identity.SHA1Fingerprint] = identity
+	}
+	removedIdentities := map[string][]ScanIdentity{}
+	for _, fingerprint := range sortedIdentityKeys(oldIdentities) {
+		if _, ok := newIdentities[fingerprint]; !ok {
+			identity := oldIdentities[fingerprint]
+			key := identity.CommonName + "|" + identity.TeamID
+			removedIdentities[key] = append(removedIdentities[key], identity)
+		}
+	}
+	for _, fingerprint := range sortedIdentityKeys(newIdentities) {
+		if _, ok := oldIdentities[fingerprint]; ok {
+			continue
+		}
+		identity := newIdentities[fingerprint]
+		key := identity.CommonName + "|" + identity.TeamID
+		if removed := removedIdentities[key]; len(removed) > 0 {
+			old := removed[0]
+			removedIdentities[key] = removed[1:]
+			diff.Identities = append(diff.Identities, IdentityChange{Kind: ChangeRenewed, Old: &old, New: &identity})
+		} else {
+			diff.Identities = append(diff.Identities, IdentityChange{Kind: ChangeAdded, New: &identity})
+		}
+	}
+	for _, key := range sortedIdentityGroupKeys(removedIdentities) {
+		for i := range removedIdentities[key] {
+			diff.Identities = append(diff.Identities, IdentityChange{Kind: ChangeRemoved, Old: &removedIdentities[key][i]})
+		}
+	}
+
+	// Profiles
+	oldProfiles, newProfiles := map[string]ScanProfile{}, map[string]ScanProfile{}
+	for _, profile := range baseline.Profiles {
+		oldProfiles[profile.UUID] = profile
+	}
+	for _, profile := range current.Profiles {
+		newProfiles[profile.UUID] = profile
+	}
+	removedProfiles := map[string][]ScanProfile{}
+	for _, uuid := range sortedProfileKeys(oldProfiles) {
+		if _, ok := newProfiles[uuid]; !ok {
+			profile := oldProfiles[uuid]
+			key := profileRenewalKey(profile)
+			removedProfiles[key] = append(removedProfiles[key], profile)
+		}
+	}
+	for _, uuid := range sortedProfileKeys(newProfiles) {
+		if _, ok := oldProfiles[uuid]; ok {
+			continue
+		}
+		profile := newProfiles[uuid]
+		key := profileRenewalKey(profile)
+		if removed := removedProfiles[key]; len(removed) > 0 {
+			old := removed[0]
+			removedProfiles[key] = removed[1:]
+			diff.Profiles = append(diff.Profiles, ProfileChange{Kind: ChangeRenewed, Old: &old, New: &profile})
+		} else {
+			diff.Profiles = append(diff.Profiles, ProfileChange{Kind: ChangeAdded, New: &profile})
+		}
+	}
+	for _, key := range sortedProfileGroupKeys(removedProfiles) {
+		for i := range removedProfiles[key] {
+			diff.Profiles = append(diff.Profiles, ProfileChange{Kind: ChangeRemoved, Old: &removedProfiles[key][i]})
+		}
+	}
+
+	return diff
+}
+
+// Message describes the change for the logs
+func (change IdentityChange) Message() string {
+	switch change.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("%s [%s] added (expiring %s)", change.New.CommonName, change.New.SHA1Fingerprint, formatDate(change.New.NotAfter))
+	case ChangeRemoved:
+		return fmt.Sprintf("%s [%s] removed (expiring %s)", change.Old.CommonName, change.Old.SHA1Fingerprint, formatDate(change.Old.NotAfter))
+	}
+	return fmt.Sprintf("%s renewed: [%s] expiring %s replaced by [%s] expiring %s", change.New.CommonName,
+		change.Old.SHA1Fingerprint, formatDate(change.Old.NotAfter), change.New.SHA1Fingerprint, formatDate(change.New.NotAfter))
+}
+
+// Message describes the change for the logs
+func (change ProfileChange) Message() string {
+	switch change.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("%s (%s, %s) added (expiring %s)", change.New.Name, change.New.BundleID, change.New.UUID, formatDate(change.New.ExpirationDate))
+	case ChangeRemoved:
+		return fmt.Sprintf("%s (%s, %s) removed (expiring %s)", change.Old.Name, change.Old.BundleID, change.Old.UUID, formatDate(change.Old.ExpirationDate))
+	}
+	return fmt.Sprintf("%s (%s) renewed: %s expiring %s replaced by %s expiring %s", change.New.Name, change.New.BundleID,
+		change.Old.UUID, formatDate(change.Old.ExpirationDate), change.New.UUID, formatDate(change.New.ExpirationDate))
+}
+
+func profileRenewalKey(profile ScanProfile) string {
+	return profile.Name + "|" + profile.BundleID + "|" + profile.TeamID + "|" + profile.ExportType
+}
+
+func formatDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+func sortedIdentityKeys(m map[string]ScanIdentity) []string {
+	keySet := map[string]bool{}
+	for key := range m {
+		keySet[key] = true
+	}
+	return sortedSet(keySet)
+}
+
+func sortedProfileKeys(m map[string]ScanProfile) []string {
+	keySet := map[string]bool{}
+	for key := range m {
+		keySet[key] = true
+	}
+	return sortedSet(keySet)
+}
+
+func sortedIdentityGroupKeys(m map[string][]ScanIdentity) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedProfileGroupKeys(m map[string][]ScanProfile) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// TeamIDs returns the teams of the identities and profiles of the result
+func (result ScanResult) TeamIDs() []string {
+	teams := map[string]bool{}
+	for _, identity := range result.Identities {
+		if identity.TeamID != "" {
+			teams[identity.TeamID] = true
+		}
+	}
+	for _, profile := range result.Profiles {
+		if profile.TeamID != "" {
+			teams[profile.TeamID] = true
+		}
+	}
+	return sortedSet(teams)
+}
+
+// WriteScanDiff writes the diff as indented JSON to the file, or to the standard output if pth is empty
+func WriteScanDiff(diff ScanDiff, pth string) error {
+	return writeJSON(diff, pth)
+}
@@ -0,0 +1,69 @@
+package report
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffScanResults(t *testing.T) {
+	oldExpiry := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	newExpiry := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	baseline := ScanResult{
+		Identities: []ScanIdentity{
+			{CommonName: "iPhone Distribution: Company (TEAM)", SHA1Fingerprint: "AA", TeamID: "TEAM", NotAfter: oldExpiry},
+			{CommonName: "iPhone Developer: Alice (TEAM)", SHA1Fingerprint: "BB", TeamID: "TEAM", NotAfter: oldExpiry},
+		},
+		Profiles: []ScanProfile{
+			{UUID: "p1", Name: "App Store", BundleID: "com.company.app", TeamID: "TEAM", ExportType: "app-store", ExpirationDate: oldExpiry},
+			{UUID: "p2", Name: "Development", BundleID: "com.company.app", TeamID: "TEAM", ExportType: "development", ExpirationDate: oldExpiry},
+		},
+	}
+	current := ScanResult{
+		Identities: []ScanIdentity{
+			{CommonName: "iPhone Distribution: Company (TEAM)", SHA1Fingerprint: "CC", TeamID: "TEAM", NotAfter: newExpiry},
+			{CommonName: "iPhone Developer: Bob (TEAM)", SHA1Fingerprint: "DD", TeamID: "TEAM", NotAfter: newExpiry},
+		},
+		Profiles: []ScanProfile{
+			{UUID: "p2", Name: "Development", BundleID: "com.company.app", TeamID: "TEAM", ExportType: "development", ExpirationDate: oldExpiry},
+			{UUID: "p3", Name: "App Store", BundleID: "com.company.app", TeamID: "TEAM", ExportType: "app-store", ExpirationDate: newExpiry},
+		},
+	}
+
+	diff := DiffScanResults(baseline, current)
+
+	var messages []string
+	for _, change := range diff.Identities {
+		messages = append(messages, change.Message())
+	}
+	for _, change := range diff.Profiles {
+		messages = append(messages, change.Message())
+	}
+	require.Equal(t, []string{
+		"iPhone Distribution: Company (TEAM) renewed: [AA] expiring 2024-03-01 replaced by [CC] expiring 2025-03-01",
+		"iPhone Developer: Bob (TEAM) [DD] added (expiring 2025-03-01)",
+		"iPhone Developer: Alice (TEAM) [BB] removed (expiring 2024-03-01)",
+		"App Store (com.company.app) renewed: p1 expiring 2024-03-01 replaced by p3 expiring 2025-03-01",
+	}, messages)
+	require.Equal(t, ChangeRenewed, diff.Profiles[0].Kind)
+
+	require.True(t, DiffScanResults(current, current).Empty())
+	require.Equal(t, []string{"TEAM"}, current.TeamIDs())
+}
+
+func TestReadScanResult(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), "dryrun.json")
+	content := `{"version": 1, "dry_run": true, "identities": [{"sha1_fingerprint": "AA"}], "profiles": [], "files": [{"path": "a.p12"}]}`
+	require.NoError(t, ioutil.WriteFile(pth, []byte(content), 0600))
+
+	result, err := ReadScanResult(pth)
+	require.NoError(t, err)
+	require.Equal(t, "AA", result.Identities[0].SHA1Fingerprint)
+
+	require.NoError(t, ioutil.WriteFile(pth, []byte(`{"version": 2}`), 0600))
+	_, err = ReadScanResult(pth)
+	require.EqualError(t, err, "unsupported scan result version (2) in "+pth+", supported: 1")
+}
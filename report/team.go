@@ -0,0 +1,80 @@
+package report
+
+import "sort"
+
+// TeamFiles are the identities and profiles of one of the Apple Developer teams of a scan,
+// for the machines with the files of multiple clients
+type TeamFiles struct {
+	TeamID   string `json:"team_id"`
+	TeamName string `json:"team_name,omitempty"`
+	// OutputDir is the directory of the team's files, set if the teams are exported separately (--per-team)
+	OutputDir string `json:"output_dir,omitempty"`
+	// Identities are the SHA-1 fingerprints of the identities
+	Identities []string `json:"identities"`
+	// Profiles are the UUIDs of the profiles
+	Profiles []string `json:"profiles"`
+}
+
+// teamGroup is a team with its identities and profiles
+type teamGroup struct {
+	TeamFiles
+	IdentityList []ScanIdentity
+	ProfileList  []ScanProfile
+}
+
+// groupTeams groups the identities and profiles by their team ID, ordered by the team ID,
+// outputDirs are the output directories of the teams by team ID
+func groupTeams(identities []ScanIdentity, profiles []ScanProfile, outputDirs map[string]string) []teamGroup {
+	byTeam := map[string]*teamGroup{}
+	team := func(teamID, teamName string) *teamGroup {
+		group, ok := byTeam[teamID]
+		if !ok {
+			group = &teamGroup{TeamFiles: TeamFiles{TeamID: teamID, OutputDir: outputDirs[teamID], Identities: []string{}, Profiles: []string{}}}
+			byTeam[teamID] = group
+		}
+		if group.TeamName == "" {
+			group.TeamName = teamName
+		}
+		return group
+	}
+	for _, identity := range identities {
+		group := team(identity.TeamID, identity.TeamName)
+		group.Identities = append(group.Identities, identity.SHA1Fingerprint)
+		group.IdentityList = append(group.IdentityList, identity)
+	}
+	for _, profile := range profiles {
+		group := team(profile.TeamID, "")
+		group.Profiles = append(group.Profiles, profile.UUID)
+		group.ProfileList = append(group.ProfileList, profile)
+	}
+
+	var groups []teamGroup
+	for _, group := range byTeam {
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].TeamID < groups[j].TeamID })
+	return groups
+}
+
+func teamFilesOf(groups []teamGroup) []TeamFiles {
+	var teams []TeamFiles
+	for _, group := range groups {
+		teams = append(teams, group.TeamFiles)
+	}
+	return teams
+}
+
+// AddTeams sets the per team breakdown of the result if its files belong to multiple teams or the teams are exported separately,
+// outputDirs are the output directories of the teams by team ID
+func (result *ScanResult) AddTeams(outputDirs map[string]string) {
+	if groups := groupTeams(result.Identities, result.Profiles, outputDirs); len(groups) > 1 || len(outputDirs) > 0 {
+		result.Teams = teamFilesOf(groups)
+	}
+}
+
+// AddTeams sets the per team breakdown of the result like ScanResult.AddTeams
+func (result *DryRunResult) AddTeams(outputDirs map[string]string) {
+	if groups := groupTeams(result.Identities, result.Profiles, outputDirs); len(groups) > 1 || len(outputDirs) > 0 {
+		result.Teams = teamFilesOf(groups)
+	}
+}
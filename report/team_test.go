@@ -0,0 +1,34 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddTeams(t *testing.T) {
+	result := ScanResult{
+		Identities: []ScanIdentity{{CommonName: "Apple Distribution: Client B", SHA1Fingerprint: "bb", TeamID: "BBBBBBBBBB", TeamName: "Client B"}},
+		Profiles:   []ScanProfile{{UUID: "p1", TeamID: "BBBBBBBBBB"}},
+	}
+	result.AddTeams(nil)
+	require.Nil(t, result.Teams)
+
+	result.AddTeams(map[string]string{"BBBBBBBBBB": "/exports/BBBBBBBBBB"})
+	require.Equal(t, []TeamFiles{{TeamID: "BBBBBBBBBB", TeamName: "Client B", OutputDir: "/exports/BBBBBBBBBB", Identities: []string{"bb"}, Profiles: []string{"p1"}}}, result.Teams)
+
+	result.Identities = append(result.Identities, ScanIdentity{CommonName: "Apple Distribution: Client A", SHA1Fingerprint: "aa", TeamID: "AAAAAAAAAA"})
+	result.Profiles = append(result.Profiles, ScanProfile{UUID: "p2", Name: "Client A App Store", TeamID: "AAAAAAAAAA", ExpirationDate: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)})
+	result.AddTeams(nil)
+	require.Equal(t, []TeamFiles{
+		{TeamID: "AAAAAAAAAA", Identities: []string{"aa"}, Profiles: []string{"p2"}},
+		{TeamID: "BBBBBBBBBB", TeamName: "Client B", Identities: []string{"bb"}, Profiles: []string{"p1"}},
+	}, result.Teams)
+
+	var b bytes.Buffer
+	require.NoError(t, RenderHTML(&b, result, nil, time.Now()))
+	require.Contains(t, b.String(), "<h3>Client B (BBBBBBBBBB)</h3>")
+	require.Contains(t, b.String(), "<li>Profile Client A App Store (, ), expires 2030-01-01</li>")
+}
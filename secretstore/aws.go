@@ -0,0 +1,76 @@
+package secretstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/cloudstorage"
+)
+
+// awsSecretsManagerStore writes the secrets as the JSON SecretString of an AWS Secrets Manager secret
+type awsSecretsManagerStore struct {
+	loc      Location
+	config   cloudstorage.Config
+	endpoint string
+	client   *http.Client
+}
+
+func newAWSSecretsManagerStore(loc Location, config cloudstorage.Config, client *http.Client) (*awsSecretsManagerStore, error) {
+	config, err := config.WithAWSDefaults("the AWS Secrets Manager secret store")
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", config.AWSRegion)
+	if config.Endpoint != "" {
+		endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	}
+	return &awsSecretsManagerStore{loc: loc, config: config, endpoint: endpoint, client: client}, nil
+}
+
+// Location ...
+func (store awsSecretsManagerStore) Location() string {
+	return fmt.Sprintf("%s (AWS Secrets Manager, %s)", store.loc.Path, store.config.AWSRegion)
+}
+
+// Write puts a new version of the secret, or creates the secret if it does not exist yet
+func (store awsSecretsManagerStore) Write(fields map[string]string) error {
+	secret, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	status, body, err := store.call("PutSecretValue", map[string]string{"SecretId": store.loc.Path, "SecretString": string(secret)})
+	if err != nil {
+		return err
+	}
+	if status == http.StatusBadRequest && bytes.Contains(body, []byte("ResourceNotFoundException")) {
+		status, body, err = store.call("CreateSecret", map[string]string{"Name": store.loc.Path, "SecretString": string(secret)})
+		if err != nil {
+			return err
+		}
+	}
+	return statusError("AWS Secrets Manager write", status, body)
+}
+
+// call runs the action of the Secrets Manager JSON API
+func (store awsSecretsManagerStore) call(action string, input map[string]string) (int, []byte, error) {
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return 0, nil, err
+	}
+	request, err := http.NewRequest(http.MethodPost, store.endpoint+"/", bytes.NewReader(payload))
+	if err != nil {
+		return 0, nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	request.Header.Set("X-Amz-Target", "secretsmanager."+action)
+	if store.config.AWSSessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", store.config.AWSSessionToken)
+	}
+	cloudstorage.SignAWSV4(request, payload, store.config, "secretsmanager", time.Now())
+	return doRequest(store.client, request)
+}
@@ -0,0 +1,73 @@
+package secretstore
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/cloudstorage"
+)
+
+// googleSecretManagerStore writes the secrets as the JSON payload of a GCP Secret Manager secret version
+type googleSecretManagerStore struct {
+	loc         Location
+	endpoint    string
+	accessToken string
+	client      *http.Client
+}
+
+func newGoogleSecretManagerStore(loc Location, config cloudstorage.Config, client *http.Client) (*googleSecretManagerStore, error) {
+	accessToken, err := cloudstorage.GoogleAccessToken(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate to GCP Secret Manager, error: %s", err)
+	}
+	endpoint := "https://secretmanager.googleapis.com"
+	if config.Endpoint != "" {
+		endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	}
+	return &googleSecretManagerStore{loc: loc, endpoint: endpoint, accessToken: accessToken, client: client}, nil
+}
+
+// Location ...
+func (store googleSecretManagerStore) Location() string {
+	return fmt.Sprintf("projects/%s/secrets/%s", store.loc.Mount, store.loc.Path)
+}
+
+// Write adds a new version to the secret, the secret is created with automatic replication if it does not exist yet
+func (store googleSecretManagerStore) Write(fields map[string]string) error {
+	secret, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	version, err := json.Marshal(map[string]interface{}{"payload": map[string]string{"data": base64.StdEncoding.EncodeToString(secret)}})
+	if err != nil {
+		return err
+	}
+
+	status, body, err := store.post(store.Location()+":addVersion", version)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusNotFound {
+		createURL := fmt.Sprintf("projects/%s/secrets?secretId=%s", store.loc.Mount, url.QueryEscape(store.loc.Path))
+		status, body, err = store.post(createURL, []byte(`{"replication":{"automatic":{}}}`))
+		if err != nil {
+			return err
+		}
+		if err := statusError("GCP Secret Manager create", status, body); err != nil {
+			return err
+		}
+		if status, body, err = store.post(store.Location()+":addVersion", version); err != nil {
+			return err
+		}
+	}
+	return statusError("GCP Secret Manager write", status, body)
+}
+
+func (store googleSecretManagerStore) post(resource string, body []byte) (int, []byte, error) {
+	headers := map[string]string{"Authorization": "Bearer " + store.accessToken, "Content-Type": "application/json"}
+	return do(store.client, http.MethodPost, store.endpoint+"/v1/"+resource, headers, body)
+}
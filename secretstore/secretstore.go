@@ -0,0 +1,151 @@
+package secretstore
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/cloudstorage"
+	"github.com/bitrise-io/codesigndoc/logging"
+	"github.com/bitrise-io/codesigndoc/network"
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// Feature names of the secret stores
+const (
+	VaultFeature               = "destination/vault"
+	AWSSecretsManagerFeature   = "destination/aws-secrets-manager"
+	GoogleSecretManagerFeature = "destination/gcp-secret-manager"
+)
+
+// Env vars of the Vault settings
+const (
+	VaultAddrEnvKey      = "VAULT_ADDR"
+	VaultTokenEnvKey     = "VAULT_TOKEN"
+	VaultNamespaceEnvKey = "VAULT_NAMESPACE"
+)
+
+// Field names of the identities secret
+const (
+	P12Field         = "p12_base64"
+	P12PasswordField = "p12_password"
+)
+
+// Store writes the secrets into a secret manager
+type Store interface {
+	// Write stores the fields as a new version of the secret
+	Write(fields map[string]string) error
+	// Location describes the secret, for the logs
+	Location() string
+}
+
+// Location is a parsed secret URL: vault://<mount>/<path> (KV version 2), aws-sm://<secret name> or gcp-sm://<project>/<secret ID>
+type Location struct {
+	Scheme string
+	// Mount is the KV secrets engine mount of Vault, and the project of GCP Secret Manager
+	Mount string
+	Path  string
+}
+
+// ParseLocation parses the secret URL
+func ParseLocation(location string) (Location, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return Location{}, fmt.Errorf("invalid secret URL (%s), error: %s", location, err)
+	}
+
+	loc := Location{Scheme: u.Scheme, Mount: u.Host, Path: strings.Trim(u.Path, "/")}
+	switch u.Scheme {
+	case "vault", "gcp-sm":
+	case "aws-sm":
+		// the secret name may contain slashes, e.g. ios/signing
+		loc.Mount, loc.Path = "", strings.Trim(u.Host+"/"+loc.Path, "/")
+	default:
+		return Location{}, fmt.Errorf("invalid secret URL (%s), valid formats: vault://<mount>/<path>, aws-sm://<secret name>, gcp-sm://<project>/<secret ID>", location)
+	}
+	if loc.Path == "" || (u.Scheme != "aws-sm" && loc.Mount == "") {
+		return Location{}, fmt.Errorf("invalid secret URL (%s), the path of the secret is missing", location)
+	}
+	if u.Scheme == "gcp-sm" && strings.Contains(loc.Path, "/") {
+		return Location{}, fmt.Errorf("invalid secret URL (%s), the secret ID of GCP Secret Manager can not contain slashes", location)
+	}
+	return loc, nil
+}
+
+// Feature returns the feature name of the secret store
+func (loc Location) Feature() string {
+	switch loc.Scheme {
+	case "vault":
+		return VaultFeature
+	case "aws-sm":
+		return AWSSecretsManagerFeature
+	}
+	return GoogleSecretManagerFeature
+}
+
+// New returns the store of the location, the credentials of AWS and GCP are read from the config, the ones of Vault from the env vars
+func New(loc Location, config cloudstorage.Config) (Store, error) {
+	client, err := network.NewHTTPClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client, error: %s", err)
+	}
+
+	switch loc.Scheme {
+	case "vault":
+		return newVaultStore(loc, os.Getenv(VaultAddrEnvKey), os.Getenv(VaultTokenEnvKey), os.Getenv(VaultNamespaceEnvKey), client)
+	case "aws-sm":
+		return newAWSSecretsManagerStore(loc, config, client)
+	}
+	return newGoogleSecretManagerStore(loc, config, client)
+}
+
+// P12Fields returns the fields of the identities secret: the base64 encoded .p12 and its password
+func P12Fields(content []byte, passphrase string) map[string]string {
+	return map[string]string{
+		P12Field:         base64.StdEncoding.EncodeToString(content),
+		P12PasswordField: passphrase,
+	}
+}
+
+// do runs the request, and returns the status and the body of the response
+func do(client *http.Client, method, requestURL string, headers map[string]string, body []byte) (int, []byte, error) {
+	request, err := http.NewRequest(method, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	return doRequest(client, request)
+}
+
+func doRequest(client *http.Client, request *http.Request) (int, []byte, error) {
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, nil, network.DescribeError(err)
+	}
+	defer func() {
+		if err := response.Body.Close(); err != nil {
+			log.Warnf("Failed to close response body: %s", err)
+		}
+	}()
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return response.StatusCode, nil, fmt.Errorf("failed to read the response body, error: %s", err)
+	}
+	return response.StatusCode, responseBody, nil
+}
+
+// statusError returns the error of a not 2xx response, nil otherwise
+func statusError(request string, status int, body []byte) error {
+	if status >= 200 && status <= 299 {
+		return nil
+	}
+	return fmt.Errorf("%s failed, status: %d, response: %s", request, status, logging.Redact(strings.TrimSpace(string(body))))
+}
@@ -0,0 +1,97 @@
+package secretstore
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bitrise-io/codesigndoc/cloudstorage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLocation(t *testing.T) {
+	for location, want := range map[string]Location{
+		"vault://secret/ios/signing":      {Scheme: "vault", Mount: "secret", Path: "ios/signing"},
+		"aws-sm://ios/signing":            {Scheme: "aws-sm", Path: "ios/signing"},
+		"aws-sm://signing":                {Scheme: "aws-sm", Path: "signing"},
+		"gcp-sm://my-project/ios-signing": {Scheme: "gcp-sm", Mount: "my-project", Path: "ios-signing"},
+	} {
+		got, err := ParseLocation(location)
+		require.NoError(t, err, location)
+		require.Equal(t, want, got, location)
+	}
+
+	for _, invalid := range []string{"gcp-sm://my-project/ios/signing", "vault://secret", "s3://bucket/path"} {
+		_, err := ParseLocation(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestVaultStoreWrite(t *testing.T) {
+	var body map[string]map[string]string
+	var decodeErr error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/ios/signing" || r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		decodeErr = json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer server.Close()
+
+	store, err := newVaultStore(Location{Scheme: "vault", Mount: "secret", Path: "ios/signing"}, server.URL, "token", "", server.Client())
+	require.NoError(t, err)
+	require.NoError(t, store.Write(P12Fields([]byte("p12"), "pass")))
+	require.NoError(t, decodeErr)
+	require.Equal(t, map[string]string{P12Field: "cDEy", P12PasswordField: "pass"}, body["data"])
+}
+
+func TestAWSSecretsManagerStoreCreatesMissingSecret(t *testing.T) {
+	var actions []string
+	var readErr error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		action := r.Header.Get("X-Amz-Target")
+		actions = append(actions, action)
+		if action == "secretsmanager.PutSecretValue" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"Secrets Manager can't find the specified secret."}`))
+			return
+		}
+		_, readErr = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	config := cloudstorage.Config{AWSAccessKeyID: "id", AWSSecretAccessKey: "secret", Endpoint: server.URL}
+	store, err := newAWSSecretsManagerStore(Location{Scheme: "aws-sm", Path: "ios/signing"}, config, server.Client())
+	require.NoError(t, err)
+	require.NoError(t, store.Write(P12Fields([]byte("p12"), "pass")))
+	require.NoError(t, readErr)
+	require.Equal(t, []string{"secretsmanager.PutSecretValue", "secretsmanager.CreateSecret"}, actions)
+}
+
+func TestGoogleSecretManagerStoreCreatesMissingSecret(t *testing.T) {
+	var requests []string
+	created := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		switch r.URL.Path {
+		case "/v1/projects/project/secrets":
+			created = true
+		case "/v1/projects/project/secrets/signing:addVersion":
+			if !created {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	config := cloudstorage.Config{GCSAccessToken: "token", Endpoint: server.URL}
+	store, err := newGoogleSecretManagerStore(Location{Scheme: "gcp-sm", Mount: "project", Path: "signing"}, config, server.Client())
+	require.NoError(t, err)
+	require.NoError(t, store.Write(P12Fields([]byte("p12"), "pass")))
+	require.Equal(t, []string{"/v1/projects/project/secrets/signing:addVersion", "/v1/projects/project/secrets?secretId=signing", "/v1/projects/project/secrets/signing:addVersion"}, requests)
+}
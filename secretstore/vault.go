@@ -0,0 +1,47 @@
+package secretstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// vaultStore writes the secrets into a KV version 2 secrets engine of HashiCorp Vault
+type vaultStore struct {
+	loc       Location
+	addr      string
+	token     string
+	namespace string
+	client    *http.Client
+}
+
+func newVaultStore(loc Location, addr, token, namespace string, client *http.Client) (*vaultStore, error) {
+	if addr == "" || token == "" {
+		return nil, fmt.Errorf("the Vault secret store requires the %s and %s env vars", VaultAddrEnvKey, VaultTokenEnvKey)
+	}
+	return &vaultStore{loc: loc, addr: strings.TrimSuffix(addr, "/"), token: token, namespace: namespace, client: client}, nil
+}
+
+// Location ...
+func (store vaultStore) Location() string {
+	return fmt.Sprintf("%s/v1/%s/data/%s", store.addr, store.loc.Mount, store.loc.Path)
+}
+
+// Write ...
+func (store vaultStore) Write(fields map[string]string) error {
+	body, err := json.Marshal(map[string]interface{}{"data": fields})
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{"X-Vault-Token": store.token, "Content-Type": "application/json"}
+	if store.namespace != "" {
+		headers["X-Vault-Namespace"] = store.namespace
+	}
+	status, responseBody, err := do(store.client, http.MethodPost, store.Location(), headers, body)
+	if err != nil {
+		return err
+	}
+	return statusError("Vault write", status, responseBody)
+}
@@ -0,0 +1,205 @@
+package selfupdate
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const (
+	// LatestReleaseURL is the GitHub API endpoint of the latest codesigndoc release
+	LatestReleaseURL = "https://api.github.com/repos/bitrise-io/codesigndoc/releases/latest"
+	// AssetName is the name of the released binary
+	AssetName = "codesigndoc-Darwin-x86_64"
+	// ChecksumAssetName is the name of the shasum -a 256 output of the released binary
+	ChecksumAssetName = AssetName + ".sha256"
+)
+
+// Asset is a downloadable file of a release
+type Asset struct {
+	Name        string `json:"name"`
+	DownloadURL string `json:"browser_download_url"`
+}
+
+// Release is a GitHub release
+type Release struct {
+	TagName string  `json:"tag_name"`
+	HTMLURL string  `json:"html_url"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Version returns the tag of the release without the v prefix
+func (release Release) Version() string {
+	return strings.TrimPrefix(release.TagName, "v")
+}
+
+// Asset returns the asset of the name
+func (release Release) Asset(name string) (Asset, bool) {
+	for _, asset := range release.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return Asset{}, false
+}
+
+// LatestRelease fetches the latest release from the GitHub API url
+func LatestRelease(client *http.Client, url string) (Release, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return Release{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Release{}, err
+	}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return Release{}, fmt.Errorf("failed to fetch the latest release, status: %s", resp.Status)
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return Release{}, fmt.Errorf("failed to parse the latest release, error: %s", err)
+	}
+	if release.TagName == "" {
+		return Release{}, fmt.Errorf("the latest release has no tag")
+	}
+	return release, nil
+}
+
+// IsNewer returns true if the latest version (e.g. 2.5.0) is greater than the current one,
+// the dot separated numeric parts are compared, a missing part counts as 0
+func IsNewer(current, latest string) (bool, error) {
+	currentParts, err := versionParts(current)
+	if err != nil {
+		return false, err
+	}
+	latestParts, err := versionParts(latest)
+	if err != nil {
+		return false, err
+	}
+	for i := 0; i < len(currentParts) || i < len(latestParts); i++ {
+		var c, l int
+		if i < len(currentParts) {
+			c = currentParts[i]
+		}
+		if i < len(latestParts) {
+			l = latestParts[i]
+		}
+		if c != l {
+			return l > c, nil
+		}
+	}
+	return false, nil
+}
+
+func versionParts(version string) ([]int, error) {
+	var parts []int
+	for _, part := range strings.Split(strings.TrimPrefix(version, "v"), ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version (%s)", version)
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
+
+// ParseChecksum returns the SHA-256 checksum of the file from the shasum -a 256 output
+func ParseChecksum(content, fileName string) (string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		// shasum prefixes the file name with * in binary mode
+		if filepath.Base(strings.TrimPrefix(fields[1], "*")) == fileName {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("no checksum of %s found", fileName)
+}
+
+// Download writes the content of the url to a new file in dir and returns its path and its SHA-256 checksum
+func Download(client *http.Client, url, dir string) (string, string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", "", err
+	}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("failed to download %s, status: %s", url, resp.Status)
+	}
+
+	file, err := ioutil.TempFile(dir, ".codesigndoc-update")
+	if err != nil {
+		return "", "", err
+	}
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(file, hash), resp.Body)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		if rerr := os.Remove(file.Name()); rerr != nil {
+			log.Warnf("Failed to remove %s: %s", file.Name(), rerr)
+		}
+		return "", "", fmt.Errorf("failed to download %s, error: %s", url, err)
+	}
+	return file.Name(), hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// DownloadString returns the content of the url
+func DownloadString(client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download %s, status: %s", url, resp.Status)
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// Replace makes the downloaded binary executable and moves it over the binary at exePath,
+// the downloaded file has to be in the same directory, so the rename is atomic
+func Replace(exePath, downloadedPath string) error {
+	if err := os.Chmod(downloadedPath, 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(downloadedPath, exePath); err != nil {
+		return fmt.Errorf("failed to replace %s, error: %s", exePath, err)
+	}
+	return nil
+}
+
+// IsHomebrewInstall returns true if the binary is installed by Homebrew, it has to be updated with brew upgrade
+func IsHomebrewInstall(exePath string) bool {
+	return strings.Contains(filepath.ToSlash(exePath), "/Cellar/codesigndoc/")
+}
+
+func closeBody(resp *http.Response) {
+	if err := resp.Body.Close(); err != nil {
+		log.Warnf("Failed to close response body: %s", err)
+	}
+}
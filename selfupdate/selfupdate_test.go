@@ -0,0 +1,96 @@
+package selfupdate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsNewer(t *testing.T) {
+	for _, tc := range []struct {
+		current, latest string
+		newer           bool
+	}{
+		{"2.4.1", "2.5.0", true},
+		{"2.4.1", "2.4.1", false},
+		{"2.4.1", "2.4", false},
+		{"2.4", "2.4.1", true},
+		{"2.10.0", "2.9.0", false},
+		{"2.4.1", "v3.0.0", true},
+	} {
+		newer, err := IsNewer(tc.current, tc.latest)
+		require.NoError(t, err)
+		require.Equal(t, tc.newer, newer, "%s -> %s", tc.current, tc.latest)
+	}
+
+	_, err := IsNewer("2.4.1", "2.5.0-beta")
+	require.Error(t, err)
+}
+
+func TestParseChecksum(t *testing.T) {
+	content := "0A1B2C  ./_bin/codesigndoc-Darwin-x86_64\n"
+	checksum, err := ParseChecksum(content, AssetName)
+	require.NoError(t, err)
+	require.Equal(t, "0a1b2c", checksum)
+
+	checksum, err = ParseChecksum("0a1b2c *codesigndoc-Darwin-x86_64", AssetName)
+	require.NoError(t, err)
+	require.Equal(t, "0a1b2c", checksum)
+
+	_, err = ParseChecksum("0a1b2c  other", AssetName)
+	require.Error(t, err)
+}
+
+func TestDownloadAndReplace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/releases/latest":
+			_, err := fmt.Fprintf(w, `{"tag_name": "2.5.0", "assets": [{"name": %q, "browser_download_url": %q}]}`, AssetName, "http://"+r.Host+"/"+AssetName)
+			require.NoError(t, err)
+		case "/" + AssetName:
+			_, err := w.Write([]byte("binary"))
+			require.NoError(t, err)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	release, err := LatestRelease(server.Client(), server.URL+"/releases/latest")
+	require.NoError(t, err)
+	require.Equal(t, "2.5.0", release.Version())
+	asset, ok := release.Asset(AssetName)
+	require.True(t, ok)
+
+	tmpDir, err := ioutil.TempDir("", "selfupdate")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tmpDir))
+	}()
+	exePath := filepath.Join(tmpDir, "codesigndoc")
+	require.NoError(t, ioutil.WriteFile(exePath, []byte("old"), 0755))
+
+	downloaded, checksum, err := Download(server.Client(), asset.DownloadURL, tmpDir)
+	require.NoError(t, err)
+	// sha256 of "binary"
+	require.Equal(t, "9a3a45d01531a20e89ac6ae10b0b0beb0492acd7216a368aa062d1a5fecaf9cd", checksum)
+	require.NoError(t, Replace(exePath, downloaded))
+
+	content, err := ioutil.ReadFile(exePath)
+	require.NoError(t, err)
+	require.Equal(t, "binary", string(content))
+
+	_, err = LatestRelease(server.Client(), server.URL+"/missing")
+	require.Error(t, err)
+}
+
+func TestIsHomebrewInstall(t *testing.T) {
+	require.True(t, IsHomebrewInstall("/opt/homebrew/Cellar/codesigndoc/2.4.1/bin/codesigndoc"))
+	require.False(t, IsHomebrewInstall("/usr/local/bin/codesigndoc"))
+}
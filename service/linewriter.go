@@ -0,0 +1,62 @@
+package service
+
+import (
+	"bytes"
+	"sync"
+)
+
+// LineWriter keeps the last lines written to it, for the log of a scan
+type LineWriter struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial []byte
+}
+
+// NewLineWriter returns a writer keeping the last max lines
+func NewLineWriter(max int) *LineWriter {
+	return &LineWriter{max: max}
+}
+
+// Write splits the output into lines, the last line is kept until it is terminated
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.add(string(bytes.TrimSuffix(w.partial[:i], []byte("\r"))))
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// WriteLine adds a line
+func (w *LineWriter) WriteLine(line string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.add(line)
+}
+
+// Lines returns the kept lines, with the not terminated last line
+func (w *LineWriter) Lines() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	lines := append([]string{}, w.lines...)
+	if len(w.partial) > 0 {
+		lines = append(lines, string(w.partial))
+	}
+	return lines
+}
+
+func (w *LineWriter) add(line string) {
+	w.lines = append(w.lines, line)
+	if len(w.lines) > w.max {
+		w.lines = w.lines[len(w.lines)-w.max:]
+	}
+}
@@ -0,0 +1,82 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/cmd"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/logging"
+	"github.com/bitrise-io/codesigndoc/service"
+	"github.com/bitrise-io/codesigndoc/state"
+	"github.com/stretchr/testify/require"
+)
+
+// runCodesigndocEnvKey makes the test binary run codesigndoc instead of the tests, for the scans of the test runner
+const runCodesigndocEnvKey = "CODESIGNDOC_SERVICE_TEST_RUN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runCodesigndocEnvKey) == "true" {
+		cmd.Execute()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestJobLog(t *testing.T) {
+	stateDir := t.TempDir()
+	runner := func(ctx context.Context, run service.Run) (int, error) {
+		scan := exec.CommandContext(ctx, os.Args[0], run.Args...)
+		// the scan fails after its flags are checked, without touching the keychain or the project
+		scan.Env = append(os.Environ(), run.Env...)
+		scan.Env = append(scan.Env, runCodesigndocEnvKey+"=true", features.DisabledFeaturesEnvKey+"=scan/xcode", state.DirEnvKey+"="+stateDir)
+		scan.Stdout = run.Log
+		scan.Stderr = run.Log
+		err := scan.Run()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return 0, err
+	}
+	server := httptest.NewServer(service.New("secret", []string{"xcode"}, "PASSPHRASE", runner).Handler())
+	defer server.Close()
+
+	request := func(method, path, body string) service.Job {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var job service.Job
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&job))
+		return job
+	}
+
+	job := request(http.MethodPost, "/v1/scans", `{"scanner":"xcode","args":["--verbose"]}`)
+	require.Eventually(t, func() bool {
+		job = request(http.MethodGet, "/v1/scans/"+job.ID, "")
+		return job.State != service.JobRunning
+	}, 30*time.Second, 50*time.Millisecond)
+	require.Equal(t, service.JobFailed, job.State)
+
+	require.NotEmpty(t, job.Log)
+	for _, line := range job.Log {
+		var entry logging.Entry
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		require.NotEmpty(t, entry.Severity, line)
+	}
+	var last logging.Entry
+	require.NoError(t, json.Unmarshal([]byte(job.Log[len(job.Log)-1]), &last))
+	require.Equal(t, "error", last.Severity)
+	require.Contains(t, last.Message, "scan/xcode")
+}
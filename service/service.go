@@ -0,0 +1,390 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/logging"
+)
+
+// TokenEnvKey is the env var of the API token, a random token is generated if it is not set
+const TokenEnvKey = "CODESIGNDOC_SERVE_TOKEN"
+
+// DefaultAddress is the listen address of the service
+const DefaultAddress = "127.0.0.1:7767"
+
+// maxLogLines is the number of the last log lines kept per job
+const maxLogLines = 2000
+
+// reservedFlags are set by the service, or would wait for an input the service can not give
+var reservedFlags = []string{"--format", "--format-output", "--ask-pass", "--pass-stdin", "--non-interactive"}
+
+// JobState is the state of a scan run by the service
+type JobState string
+
+// JobStates ...
+const (
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed"
+	JobCancelled JobState = "cancelled"
+)
+
+// ScanRequest is the body of POST /v1/scans
+type ScanRequest struct {
+	// Scanner is the scan subcommand, e.g. xcode
+	Scanner string `json:"scanner"`
+	// Args are the flags of the scan, e.g. ["--file", "App.xcworkspace", "--scheme", "App"]
+	Args []string `json:"args"`
+	// Passphrase protects the exported .p12, it is passed to the scan in an env var and never stored
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+// Job is a scan run by the service
+type Job struct {
+	ID         string     `json:"id"`
+	Scanner    string     `json:"scanner"`
+	Args       []string   `json:"args"`
+	State      JobState   `json:"state"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	ExitCode   int        `json:"exit_code"`
+	Error      string     `json:"error,omitempty"`
+	// Result is the json scan result (--format json) of the scan
+	Result json.RawMessage `json:"result,omitempty"`
+	// Log are the last log lines of the scan, in the json log format
+	Log []string `json:"log"`
+
+	cancel    context.CancelFunc
+	logWriter *LineWriter
+}
+
+// Run is a scan started by the Runner
+type Run struct {
+	// Args are the arguments of codesigndoc, e.g. scan xcode --format json ...
+	Args []string
+	// Env are the additional env vars of the scan
+	Env []string
+	// Log receives the output of the scan
+	Log *LineWriter
+}
+
+// Runner runs codesigndoc with the arguments of the run, until it exits or the context is done,
+// and returns its exit code
+type Runner func(ctx context.Context, run Run) (int, error)
+
+// Server exposes the scans over a localhost HTTP API, every request has to carry the token
+// in an Authorization: Bearer header
+type Server struct {
+	token    string
+	scanners []string
+	runner   Runner
+	// passphraseEnvKey is the env var the .p12 passphrase of a request is passed in
+	passphraseEnvKey string
+
+	mu     sync.Mutex
+	jobs   map[string]*Job
+	order  []string
+	active string
+}
+
+// New returns a server running the scans of the scanners with the runner
+func New(token string, scanners []string, passphraseEnvKey string, runner Runner) *Server {
+	return &Server{
+		token:            token,
+		scanners:         append([]string{}, scanners...),
+		runner:           runner,
+		passphraseEnvKey: passphraseEnvKey,
+		jobs:             map[string]*Job{},
+	}
+}
+
+// NewToken returns a random API token
+func NewToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token, error: %s", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CheckLocalAddress returns an error if the listen address is not a loopback address,
+// the API can export the identities of the machine so it must not be reachable from the network
+func CheckLocalAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid listen address (%s), error: %s", address, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("the listen address (%s) is not a loopback address, use 127.0.0.1 or localhost", address)
+}
+
+// Handler returns the HTTP handler of the API. GET /v1/scanners lists the available scanners,
+// POST /v1/scans starts a scan (one scan runs at a time), GET /v1/scans lists the scans,
+// GET /v1/scans/<id> returns the state, the log and the result of a scan and DELETE /v1/scans/<id> cancels it.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/scanners", s.handleScanners)
+	mux.HandleFunc("/v1/scans", s.handleScans)
+	mux.HandleFunc("/v1/scans/", s.handleScan)
+	return s.authorize(mux)
+}
+
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(header, "Bearer ")
+		if token == header || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleScanners(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{"scanners": s.scanners})
+}
+
+func (s *Server) handleScans(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		jobs := []Job{}
+		for _, id := range s.order {
+			jobs = append(jobs, s.jobs[id].summary())
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string][]Job{"scans": jobs})
+	case http.MethodPost:
+		var request ScanRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("failed to parse request, error: %s", err))
+			return
+		}
+		if err := s.checkRequest(request); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		job, err := s.start(request)
+		if err != nil {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, job)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+	}
+}
+
+func (s *Server) handleScan(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/v1/scans/")
+	s.mu.Lock()
+	job, ok := s.jobs[id]
+	var snapshot Job
+	if ok {
+		snapshot = job.snapshot()
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("scan (%s) not found", id))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, snapshot)
+	case http.MethodDelete:
+		if snapshot.State != JobRunning {
+			writeError(w, http.StatusConflict, fmt.Errorf("scan (%s) is not running", id))
+			return
+		}
+		job.cancel()
+		writeJSON(w, http.StatusAccepted, snapshot)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+	}
+}
+
+// Cancel cancels the running scan, e.g. when the service stops
+func (s *Server) Cancel() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, ok := s.jobs[s.active]; ok {
+		job.cancel()
+	}
+}
+
+// checkRequest returns an error if the scanner is not available or a flag is set by the service
+func (s *Server) checkRequest(request ScanRequest) error {
+	found := false
+	for _, scanner := range s.scanners {
+		if scanner == request.Scanner {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("unknown scanner (%s), available: %s", request.Scanner, strings.Join(s.scanners, ", "))
+	}
+	for _, arg := range request.Args {
+		for _, reserved := range reservedFlags {
+			if arg == reserved || strings.HasPrefix(arg, reserved+"=") {
+				return fmt.Errorf("the %s flag can not be set by a request", reserved)
+			}
+		}
+	}
+	return nil
+}
+
+// start runs the scan of the request in the background, an error is returned if another scan is running:
+// the scans share the keychain prompts and the output directory
+func (s *Server) start(request ScanRequest) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active != "" {
+		return Job{}, fmt.Errorf("scan (%s) is running, wait for it to finish or cancel it", s.active)
+	}
+	id, err := newJobID()
+	if err != nil {
+		return Job{}, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &Job{ID: id, Scanner: request.Scanner, Args: append([]string{}, request.Args...), State: JobRunning, StartedAt: time.Now(), cancel: cancel, logWriter: NewLineWriter(maxLogLines)}
+	s.jobs[id] = job
+	s.order = append(s.order, id)
+	s.active = id
+
+	go s.run(ctx, job, request)
+	return job.snapshot(), nil
+}
+
+func (s *Server) run(ctx context.Context, job *Job, request ScanRequest) {
+	exitCode, result, err := s.runScan(ctx, request, job.logWriter)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	job.ExitCode = exitCode
+	job.Result = result
+	switch {
+	case ctx.Err() != nil:
+		job.State = JobCancelled
+	case err != nil:
+		job.State = JobFailed
+		job.Error = err.Error()
+	case exitCode != 0:
+		job.State = JobFailed
+		job.Error = fmt.Sprintf("the scan exited with code %d", exitCode)
+	default:
+		job.State = JobSucceeded
+	}
+	job.cancel()
+	s.active = ""
+}
+
+// logError adds an error message of the service to the log of the scan, in the json log format of the scan's messages
+func logError(logWriter *LineWriter, message string) {
+	entry := logging.Entry{Time: time.Now().UTC().Format(time.RFC3339), Severity: "error", Message: message}
+	if line, err := json.Marshal(entry); err == nil {
+		logWriter.WriteLine(string(line))
+	}
+}
+
+// runScan runs the scan with the json result written into a temporary file, and returns the result
+func (s *Server) runScan(ctx context.Context, request ScanRequest, logWriter *LineWriter) (int, json.RawMessage, error) {
+	tmpDir, err := ioutil.TempDir("", "codesigndoc-serve")
+	if err != nil {
+		return -1, nil, fmt.Errorf("failed to create temporary directory, error: %s", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			logError(logWriter, fmt.Sprintf("failed to remove temporary directory, error: %s", err))
+		}
+	}()
+	resultPath := filepath.Join(tmpDir, "result.json")
+
+	run := Run{
+		Args: append(append([]string{"scan", request.Scanner}, request.Args...), "--format", "json", "--format-output", resultPath, "--non-interactive", "--log-format", "json"),
+		Log:  logWriter,
+	}
+	if request.Passphrase != "" {
+		run.Env = []string{s.passphraseEnvKey + "=" + request.Passphrase}
+	}
+	exitCode, err := s.runner(ctx, run)
+	if err != nil {
+		return exitCode, nil, err
+	}
+
+	content, err := ioutil.ReadFile(resultPath)
+	if os.IsNotExist(err) {
+		return exitCode, nil, nil
+	} else if err != nil {
+		return exitCode, nil, fmt.Errorf("failed to read scan result, error: %s", err)
+	}
+	if !json.Valid(content) {
+		return exitCode, nil, errors.New("the scan result is not valid json")
+	}
+	return exitCode, json.RawMessage(content), nil
+}
+
+// snapshot copies the job for a response, it has to be called with the server locked
+func (job *Job) snapshot() Job {
+	snapshot := *job
+	snapshot.Args = append([]string{}, job.Args...)
+	snapshot.Log = job.logWriter.Lines()
+	return snapshot
+}
+
+// summary is the job without its log and result, for the list of the jobs
+func (job *Job) summary() Job {
+	summary := job.snapshot()
+	summary.Log = nil
+	summary.Result = nil
+	return summary
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate scan ID, error: %s", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write response, error: %s\n", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	release := make(chan bool)
+	var runs []Run
+	runner := func(ctx context.Context, run Run) (int, error) {
+		runs = append(runs, run)
+		fmt.Fprint(run.Log, "{\"message\":\"scanning\"}\n")
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return -1, nil
+		}
+		for i, arg := range run.Args {
+			if arg == "--format-output" {
+				if err := ioutil.WriteFile(run.Args[i+1], []byte(`{"version":1}`), 0600); err != nil {
+					return 1, err
+				}
+			}
+		}
+		return 0, nil
+	}
+	server := httptest.NewServer(New("secret", []string{"xcode"}, "PASSPHRASE", runner).Handler())
+	defer server.Close()
+
+	request := func(method, path, token, body string) (int, map[string]interface{}) {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var decoded map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+		return resp.StatusCode, decoded
+	}
+
+	status, _ := request(http.MethodGet, "/v1/scanners", "", "")
+	require.Equal(t, http.StatusUnauthorized, status)
+	status, _ = request(http.MethodGet, "/v1/scanners", "wrong", "")
+	require.Equal(t, http.StatusUnauthorized, status)
+	status, body := request(http.MethodGet, "/v1/scanners", "secret", "")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, []interface{}{"xcode"}, body["scanners"])
+
+	status, _ = request(http.MethodPost, "/v1/scans", "secret", `{"scanner":"android"}`)
+	require.Equal(t, http.StatusBadRequest, status)
+	status, _ = request(http.MethodPost, "/v1/scans", "secret", `{"scanner":"xcode","args":["--format=text"]}`)
+	require.Equal(t, http.StatusBadRequest, status)
+
+	status, body = request(http.MethodPost, "/v1/scans", "secret", `{"scanner":"xcode","args":["--scheme","App"],"passphrase":"p12"}`)
+	require.Equal(t, http.StatusAccepted, status)
+	require.Equal(t, "running", body["state"])
+	id := body["id"].(string)
+
+	status, _ = request(http.MethodPost, "/v1/scans", "secret", `{"scanner":"xcode"}`)
+	require.Equal(t, http.StatusConflict, status)
+
+	close(release)
+	require.Eventually(t, func() bool {
+		_, body = request(http.MethodGet, "/v1/scans/"+id, "secret", "")
+		return body["state"] != "running"
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, "succeeded", body["state"])
+	require.Equal(t, map[string]interface{}{"version": float64(1)}, body["result"])
+	require.Equal(t, []interface{}{`{"message":"scanning"}`}, body["log"])
+	require.Equal(t, []string{"scan", "xcode", "--scheme", "App", "--format", "json"}, runs[0].Args[:6])
+	require.Equal(t, []string{"PASSPHRASE=p12"}, runs[0].Env)
+
+	status, _ = request(http.MethodDelete, "/v1/scans/"+id, "secret", "")
+	require.Equal(t, http.StatusConflict, status)
+	status, _ = request(http.MethodGet, "/v1/scans/unknown", "secret", "")
+	require.Equal(t, http.StatusNotFound, status)
+}
+
+func TestCheckLocalAddress(t *testing.T) {
+	require.NoError(t, CheckLocalAddress("127.0.0.1:7767"))
+	require.NoError(t, CheckLocalAddress("localhost:7767"))
+	require.NoError(t, CheckLocalAddress("[::1]:7767"))
+	require.Error(t, CheckLocalAddress("0.0.0.0:7767"))
+	require.Error(t, CheckLocalAddress(":7767"))
+	require.Error(t, CheckLocalAddress("127.0.0.1"))
+}
+
+func TestLineWriter(t *testing.T) {
+	w := NewLineWriter(2)
+	_, err := w.Write([]byte("first\nsecond\r\nthi"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"first", "second", "thi"}, w.Lines())
+
+	_, err = bytes.NewBufferString("rd\n").WriteTo(w)
+	require.NoError(t, err)
+	require.Equal(t, []string{"second", "third"}, w.Lines())
+}
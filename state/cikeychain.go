@@ -0,0 +1,91 @@
+package state
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const ciKeychainsFileName = "ci-keychains.yml"
+
+// CIKeychain is a keychain created by keychain create-ci, with the provisioning profiles installed for it
+type CIKeychain struct {
+	Name string `yaml:"name"`
+	Path string `yaml:"path"`
+	// Profiles are the installed profile files, which were not installed before
+	Profiles  []string  `yaml:"profiles,omitempty"`
+	CreatedAt time.Time `yaml:"created_at"`
+}
+
+// CIKeychainList is the list of the CI keychains to tear down, stored in the ci-keychains.yml of the state directory
+type CIKeychainList struct {
+	Keychains []CIKeychain `yaml:"keychains"`
+}
+
+// ReadCIKeychainList reads the CI keychains, returns an empty list if it does not exist
+func ReadCIKeychainList() (CIKeychainList, error) {
+	content, err := ioutil.ReadFile(Path(ciKeychainsFileName))
+	if os.IsNotExist(err) {
+		return CIKeychainList{}, nil
+	} else if err != nil {
+		return CIKeychainList{}, fmt.Errorf("failed to read CI keychain list, error: %s", err)
+	}
+
+	var list CIKeychainList
+	if err := yaml.Unmarshal(content, &list); err != nil {
+		return CIKeychainList{}, fmt.Errorf("failed to parse CI keychain list (%s), run 'codesigndoc state doctor', error: %s", Path(ciKeychainsFileName), err)
+	}
+	return list, nil
+}
+
+// WriteCIKeychainList writes the CI keychains to the state directory
+func WriteCIKeychainList(list CIKeychainList) error {
+	if err := EnsureDir(); err != nil {
+		return err
+	}
+
+	content, err := yaml.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return writeFile(ciKeychainsFileName, content)
+}
+
+// Find returns the CI keychain with the given name
+func (list CIKeychainList) Find(name string) (CIKeychain, bool) {
+	if idx := list.find(name); idx >= 0 {
+		return list.Keychains[idx], true
+	}
+	return CIKeychain{}, false
+}
+
+// Add adds the keychain to the list, a keychain with the same name is replaced
+func (list *CIKeychainList) Add(keychain CIKeychain) {
+	if idx := list.find(keychain.Name); idx >= 0 {
+		list.Keychains[idx] = keychain
+		return
+	}
+	list.Keychains = append(list.Keychains, keychain)
+}
+
+// Remove removes the keychain with the given name, returns false if it was not listed
+func (list *CIKeychainList) Remove(name string) bool {
+	idx := list.find(name)
+	if idx < 0 {
+		return false
+	}
+	list.Keychains = append(list.Keychains[:idx], list.Keychains[idx+1:]...)
+	return true
+}
+
+func (list CIKeychainList) find(name string) int {
+	for idx, keychain := range list.Keychains {
+		if keychain.Name == name {
+			return idx
+		}
+	}
+	return -1
+}
@@ -0,0 +1,23 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCIKeychainList(t *testing.T) {
+	list := CIKeychainList{}
+	list.Add(CIKeychain{Name: "codesigndoc-ci", Path: "/Users/ci/Library/Keychains/codesigndoc-ci.keychain-db"})
+	list.Add(CIKeychain{Name: "codesigndoc-ci", Path: "/Users/ci/Library/Keychains/codesigndoc-ci.keychain-db", Profiles: []string{"a.mobileprovision"}})
+
+	require.Equal(t, 1, len(list.Keychains))
+	keychain, ok := list.Find("codesigndoc-ci")
+	require.True(t, ok)
+	require.Equal(t, []string{"a.mobileprovision"}, keychain.Profiles)
+
+	require.True(t, list.Remove("codesigndoc-ci"))
+	require.False(t, list.Remove("codesigndoc-ci"))
+	_, ok = list.Find("codesigndoc-ci")
+	require.False(t, ok)
+}
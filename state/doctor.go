@@ -0,0 +1,189 @@
+package state
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Problem is an issue of the state directory found by Diagnose
+type Problem struct {
+	File        string
+	Description string
+	// Repair describes the fix, empty if the problem has to be fixed manually
+	Repair string
+	fix    func() error
+}
+
+// Repairable returns true if the problem can be fixed by Fix
+func (problem Problem) Repairable() bool {
+	return problem.fix != nil
+}
+
+// Fix repairs the problem
+func (problem Problem) Fix() error {
+	if problem.fix == nil {
+		return fmt.Errorf("%s has to be fixed manually", problem.File)
+	}
+	return problem.fix()
+}
+
+// Diagnose checks the state directory for corrupt files, pending migrations and unsafe permissions
+func Diagnose() ([]Problem, error) {
+	info, err := os.Stat(Dir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []Problem{{File: Dir(), Description: "the state directory path is not a directory"}}, nil
+	}
+
+	var problems []Problem
+	if info.Mode().Perm()&0077 != 0 {
+		problems = append(problems, Problem{
+			File:        Dir(),
+			Description: fmt.Sprintf("the state directory is accessible by other users (%s)", info.Mode().Perm()),
+			Repair:      "restrict the permissions to the owner",
+			fix:         func() error { return os.Chmod(Dir(), 0700) },
+		})
+	}
+
+	problems = append(problems, diagnoseFile(configFileName, "the external scanners are not available", func(content []byte) error {
+		var config Config
+		if err := yaml.UnmarshalStrict(content, &config); err != nil {
+			return err
+		}
+		return validateConfig(config)
+	})...)
+	problems = append(problems, diagnoseFile(neverExportFileName, "the never export list is not applied until it is recreated", func(content []byte) error {
+		var list NeverExportList
+		return yaml.UnmarshalStrict(content, &list)
+	})...)
+	problems = append(problems, diagnoseFile(ciKeychainsFileName, "the CI keychains can not be torn down by keychain delete-ci until it is recreated", func(content []byte) error {
+		var list CIKeychainList
+		return yaml.UnmarshalStrict(content, &list)
+	})...)
+
+	// the migrations read the state files, so they are repaired after the files
+	problems = append(problems, diagnoseSchema()...)
+
+	tmpFiles, err := filepath.Glob(filepath.Join(Dir(), ".*.tmp*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, pth := range tmpFiles {
+		pth := pth
+		problems = append(problems, Problem{
+			File:        pth,
+			Description: "leftover of an interrupted write",
+			Repair:      "remove the file",
+			fix:         func() error { return os.Remove(pth) },
+		})
+	}
+
+	return problems, nil
+}
+
+func diagnoseSchema() []Problem {
+	if _, err := ReadSchema(); err != nil {
+		return []Problem{{
+			File:        Path(schemaFileName),
+			Description: err.Error(),
+			Repair:      "move the file aside and rerun the migrations",
+			fix: func() error {
+				if err := moveAside(schemaFileName); err != nil {
+					return err
+				}
+				_, err := Migrate()
+				return err
+			},
+		}}
+	}
+
+	pending, err := PendingMigrations()
+	if err != nil {
+		return []Problem{{File: Path(schemaFileName), Description: err.Error()}}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	var descriptions []string
+	for _, migration := range pending {
+		descriptions = append(descriptions, migration.Description)
+	}
+	return []Problem{{
+		File:        Path(schemaFileName),
+		Description: fmt.Sprintf("%d pending migration(s): %s", len(pending), strings.Join(descriptions, ", ")),
+		Repair:      "apply the migrations",
+		fix: func() error {
+			_, err := Migrate()
+			return err
+		},
+	}}
+}
+
+// diagnoseFile checks a state file, a corrupt file is moved aside, impact describes the consequence of the repair
+func diagnoseFile(name, impact string, validate func([]byte) error) []Problem {
+	pth := Path(name)
+	info, err := os.Stat(pth)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return []Problem{{File: pth, Description: err.Error()}}
+	}
+
+	var problems []Problem
+	if info.Mode().Perm()&0077 != 0 {
+		problems = append(problems, Problem{
+			File:        pth,
+			Description: fmt.Sprintf("the file is accessible by other users (%s)", info.Mode().Perm()),
+			Repair:      "restrict the permissions to the owner",
+			fix:         func() error { return os.Chmod(pth, 0600) },
+		})
+	}
+
+	content, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return append(problems, Problem{File: pth, Description: err.Error()})
+	}
+	if err := validate(content); err != nil {
+		problems = append(problems, Problem{
+			File:        pth,
+			Description: fmt.Sprintf("invalid content: %s", err),
+			Repair:      fmt.Sprintf("move the file aside (%s)", impact),
+			fix:         func() error { return moveAside(name) },
+		})
+	}
+	return problems
+}
+
+func validateConfig(config Config) error {
+	names := map[string]bool{}
+	for i, scanner := range config.Scanners {
+		if scanner.Name == "" || scanner.Path == "" {
+			return fmt.Errorf("scanner #%d: name and path are required", i+1)
+		}
+		if names[scanner.Name] {
+			return fmt.Errorf("scanner #%d: duplicated name: %s", i+1, scanner.Name)
+		}
+		names[scanner.Name] = true
+	}
+	return nil
+}
+
+// moveAside renames the state file with a .corrupt-<timestamp> suffix, so it can be inspected later
+func moveAside(name string) error {
+	backupPth := fmt.Sprintf("%s.corrupt-%s", Path(name), time.Now().Format("20060102150405"))
+	if err := os.Rename(Path(name), backupPth); err != nil {
+		return fmt.Errorf("failed to move %s aside, error: %s", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package state
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const neverExportFileName = "never-export.yml"
+
+// NeverExportEntry is an identity which must not be offered or exported on this machine
+type NeverExportEntry struct {
+	SHA1Fingerprint string    `yaml:"sha1_fingerprint"`
+	CommonName      string    `yaml:"common_name,omitempty"`
+	Reason          string    `yaml:"reason,omitempty"`
+	AddedAt         time.Time `yaml:"added_at"`
+}
+
+// NeverExportList is the local denylist of identities, stored in the never-export.yml of the state directory
+type NeverExportList struct {
+	Identities []NeverExportEntry `yaml:"identities"`
+}
+
+// ReadNeverExportList reads the denylist, returns an empty list if it does not exist
+func ReadNeverExportList() (NeverExportList, error) {
+	content, err := ioutil.ReadFile(Path(neverExportFileName))
+	if os.IsNotExist(err) {
+		return NeverExportList{}, nil
+	} else if err != nil {
+		return NeverExportList{}, fmt.Errorf("failed to read never export list, error: %s", err)
+	}
+
+	var list NeverExportList
+	if err := yaml.Unmarshal(content, &list); err != nil {
+		return NeverExportList{}, fmt.Errorf("failed to parse never export list (%s), run 'codesigndoc state doctor', error: %s", Path(neverExportFileName), err)
+	}
+	return list, nil
+}
+
+// WriteNeverExportList writes the denylist to the state directory
+func WriteNeverExportList(list NeverExportList) error {
+	if err := EnsureDir(); err != nil {
+		return err
+	}
+
+	content, err := yaml.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return writeFile(neverExportFileName, content)
+}
+
+// Contains returns true if the identity with the given SHA1 fingerprint is denylisted
+func (list NeverExportList) Contains(sha1Fingerprint string) bool {
+	_, ok := list.find(sha1Fingerprint)
+	return ok
+}
+
+// Add adds the entry to the list, an already listed fingerprint is updated
+func (list *NeverExportList) Add(entry NeverExportEntry) {
+	entry.SHA1Fingerprint = NormalizeFingerprint(entry.SHA1Fingerprint)
+	if idx, ok := list.find(entry.SHA1Fingerprint); ok {
+		list.Identities[idx] = entry
+		return
+	}
+	list.Identities = append(list.Identities, entry)
+}
+
+// Remove removes the fingerprint from the list, returns false if it was not listed
+func (list *NeverExportList) Remove(sha1Fingerprint string) bool {
+	idx, ok := list.find(sha1Fingerprint)
+	if !ok {
+		return false
+	}
+	list.Identities = append(list.Identities[:idx], list.Identities[idx+1:]...)
+	return true
+}
+
+func (list NeverExportList) find(sha1Fingerprint string) (int, bool) {
+	sha1Fingerprint = NormalizeFingerprint(sha1Fingerprint)
+	for idx, entry := range list.Identities {
+		if NormalizeFingerprint(entry.SHA1Fingerprint) == sha1Fingerprint {
+			return idx, true
+		}
+	}
+	return -1, false
+}
+
+// NormalizeFingerprint returns the lower case hex fingerprint, it accepts the fingerprint in the Keychain Access (spaces) and openssl (colons) formats too
+func NormalizeFingerprint(sha1Fingerprint string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", ":", "").Replace(sha1Fingerprint))
+}
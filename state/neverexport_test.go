@@ -0,0 +1,22 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNeverExportList(t *testing.T) {
+	list := NeverExportList{}
+	list.Add(NeverExportEntry{SHA1Fingerprint: "AB:CD:EF", CommonName: "iPhone Developer: Personal"})
+	list.Add(NeverExportEntry{SHA1Fingerprint: "abcdef", Reason: "personal"})
+
+	require.Equal(t, 1, len(list.Identities))
+	require.Equal(t, "personal", list.Identities[0].Reason)
+	require.True(t, list.Contains("AB CD EF"))
+	require.False(t, list.Contains("123456"))
+
+	require.True(t, list.Remove("ABCDEF"))
+	require.False(t, list.Remove("ABCDEF"))
+	require.False(t, list.Contains("abcdef"))
+}
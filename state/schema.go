@@ -0,0 +1,153 @@
+package state
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"gopkg.in/yaml.v2"
+)
+
+// SchemaVersion is the version of the state directory layout written by this codesigndoc version
+const SchemaVersion = 1
+
+const schemaFileName = "schema.yml"
+
+// Schema records the version of the state directory, stored in the schema.yml of the state directory.
+// A state directory without schema.yml was written before the state was versioned (version 0).
+type Schema struct {
+	Version    int       `yaml:"version"`
+	MigratedAt time.Time `yaml:"migrated_at,omitempty"`
+}
+
+// Migration upgrades the state directory from the From version to the next one
+type Migration struct {
+	From        int
+	Description string
+	Apply       func() error
+}
+
+// migrations are applied in order, the migration of every version below SchemaVersion has to be listed
+var migrations = []Migration{
+	{From: 0, Description: "normalize and deduplicate the never export list fingerprints", Apply: migrateNeverExportFingerprints},
+}
+
+// ReadSchema reads the schema version of the state directory
+func ReadSchema() (Schema, error) {
+	content, err := ioutil.ReadFile(Path(schemaFileName))
+	if os.IsNotExist(err) {
+		return Schema{}, nil
+	} else if err != nil {
+		return Schema{}, fmt.Errorf("failed to read state schema, error: %s", err)
+	}
+
+	var schema Schema
+	if err := yaml.Unmarshal(content, &schema); err != nil {
+		return Schema{}, fmt.Errorf("failed to parse state schema (%s), error: %s", Path(schemaFileName), err)
+	}
+	return schema, nil
+}
+
+func writeSchema(schema Schema) error {
+	content, err := yaml.Marshal(schema)
+	if err != nil {
+		return err
+	}
+	return writeFile(schemaFileName, content)
+}
+
+// writeFile replaces the file in the state directory atomically, so an interrupted write does not leave a corrupt file behind
+func writeFile(name string, content []byte) error {
+	tmp, err := ioutil.TempFile(Dir(), "."+name+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write %s, error: %s", name, err)
+	}
+	defer func() {
+		if err := os.Remove(tmp.Name()); err != nil && !os.IsNotExist(err) {
+			log.Warnf("Failed to remove temporary file (%s): %s", tmp.Name(), err)
+		}
+	}()
+
+	if _, err := tmp.Write(content); err != nil {
+		return fmt.Errorf("failed to write %s, error: %s", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s, error: %s", name, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), Path(name)); err != nil {
+		return fmt.Errorf("failed to write %s, error: %s", name, err)
+	}
+	return nil
+}
+
+// PendingMigrations returns the migrations required to bring the state directory to the current schema version
+func PendingMigrations() ([]Migration, error) {
+	if exists, err := pathutil.IsDirExists(Dir()); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, nil
+	}
+
+	schema, err := ReadSchema()
+	if err != nil {
+		return nil, err
+	}
+	if schema.Version > SchemaVersion {
+		return nil, fmt.Errorf("the state directory (%s) was written by a newer codesigndoc (schema version: %d, supported: %d), upgrade codesigndoc", Dir(), schema.Version, SchemaVersion)
+	}
+
+	var pending []Migration
+	for _, migration := range migrations {
+		if migration.From >= schema.Version {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate applies the pending migrations, the schema version is recorded after each migration,
+// so a failed migration is retried from where it stopped. Returns the applied migrations.
+func Migrate() ([]Migration, error) {
+	pending, err := PendingMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range pending {
+		if err := migration.Apply(); err != nil {
+			return applied, fmt.Errorf("failed to migrate the state directory from version %d (%s), error: %s", migration.From, migration.Description, err)
+		}
+		if err := writeSchema(Schema{Version: migration.From + 1, MigratedAt: time.Now().UTC()}); err != nil {
+			return applied, err
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+func migrateNeverExportFingerprints() error {
+	if exists, err := pathutil.IsPathExists(Path(neverExportFileName)); err != nil {
+		return err
+	} else if !exists {
+		return nil
+	}
+
+	list, err := ReadNeverExportList()
+	if err != nil {
+		return err
+	}
+
+	// Add normalizes the fingerprint and merges the duplicates
+	var migrated NeverExportList
+	for _, entry := range list.Identities {
+		migrated.Add(entry)
+	}
+	return WriteNeverExportList(migrated)
+}
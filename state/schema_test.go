@@ -0,0 +1,86 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setupStateDir(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "codesigndoc-state")
+	require.NoError(t, err)
+	require.NoError(t, os.Setenv(DirEnvKey, dir))
+	return func() {
+		require.NoError(t, os.Unsetenv(DirEnvKey))
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	defer setupStateDir(t)()
+
+	// unversioned state directory with not normalized fingerprints
+	require.NoError(t, ioutil.WriteFile(Path(neverExportFileName), []byte(`identities:
+- sha1_fingerprint: "AB:CD:EF"
+  reason: old
+- sha1_fingerprint: "ab cd ef"
+  reason: new
+`), 0600))
+
+	pending, err := PendingMigrations()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(pending))
+
+	applied, err := Migrate()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(applied))
+
+	schema, err := ReadSchema()
+	require.NoError(t, err)
+	require.Equal(t, SchemaVersion, schema.Version)
+
+	list, err := ReadNeverExportList()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(list.Identities))
+	require.Equal(t, "abcdef", list.Identities[0].SHA1Fingerprint)
+	require.Equal(t, "new", list.Identities[0].Reason)
+
+	applied, err = Migrate()
+	require.NoError(t, err)
+	require.Equal(t, 0, len(applied))
+
+	require.NoError(t, writeSchema(Schema{Version: SchemaVersion + 1}))
+	_, err = Migrate()
+	require.Error(t, err)
+}
+
+func TestDiagnose(t *testing.T) {
+	defer setupStateDir(t)()
+	require.NoError(t, writeSchema(Schema{Version: SchemaVersion}))
+
+	problems, err := Diagnose()
+	require.NoError(t, err)
+	require.Equal(t, 0, len(problems))
+
+	require.NoError(t, ioutil.WriteFile(Path(configFileName), []byte("scanners: [\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(Path(".never-export.yml.tmp123"), nil, 0600))
+
+	problems, err = Diagnose()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(problems))
+	for _, problem := range problems {
+		require.True(t, problem.Repairable(), problem.Description)
+		require.NoError(t, problem.Fix())
+	}
+
+	problems, err = Diagnose()
+	require.NoError(t, err)
+	require.Equal(t, 0, len(problems))
+
+	backups, err := filepath.Glob(Path(configFileName + ".corrupt-*"))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(backups))
+}
@@ -0,0 +1,82 @@
+package state
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/bitrise-io/go-utils/pathutil"
+	"gopkg.in/yaml.v2"
+)
+
+// DirEnvKey overrides the location of the state directory
+const DirEnvKey = "CODESIGNDOC_STATE_DIR"
+
+const configFileName = "config.yml"
+
+// Dir returns the directory of the machine local codesigndoc configuration and state
+func Dir() string {
+	if dir := os.Getenv(DirEnvKey); dir != "" {
+		return dir
+	}
+	return filepath.Join(pathutil.UserHomeDir(), ".codesigndoc")
+}
+
+// Path returns the path of the given file in the state directory
+func Path(name string) string {
+	return filepath.Join(Dir(), name)
+}
+
+// EnsureDir creates the state directory if it does not exist, a new directory is created with the current schema version
+func EnsureDir() error {
+	if exists, err := pathutil.IsDirExists(Dir()); err != nil {
+		return err
+	} else if exists {
+		return nil
+	}
+
+	if err := os.MkdirAll(Dir(), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory, error: %s", err)
+	}
+	return writeSchema(Schema{Version: SchemaVersion})
+}
+
+// ScannerConfig registers an external project scanner executable
+type ScannerConfig struct {
+	Name        string   `yaml:"name"`
+	Path        string   `yaml:"path"`
+	Args        []string `yaml:"args,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+}
+
+// Config is the machine local codesigndoc configuration, stored in the config.yml of the state directory
+type Config struct {
+	Scanners []ScannerConfig `yaml:"scanners,omitempty"`
+}
+
+// ReadConfig reads the configuration file, returns an empty configuration if it does not exist
+func ReadConfig() (Config, error) {
+	content, err := ioutil.ReadFile(Path(configFileName))
+	if os.IsNotExist(err) {
+		return Config{}, nil
+	} else if err != nil {
+		return Config{}, fmt.Errorf("failed to read config file, error: %s", err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return Config{}, fmt.Errorf("failed to parse config file (%s), run 'codesigndoc state doctor', error: %s", Path(configFileName), err)
+	}
+	return config, nil
+}
+
+// Scanner returns the external scanner registered with the given name
+func (config Config) Scanner(name string) (ScannerConfig, bool) {
+	for _, scanner := range config.Scanners {
+		if scanner.Name == name {
+			return scanner, true
+		}
+	}
+	return ScannerConfig{}, false
+}
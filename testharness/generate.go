@@ -0,0 +1,270 @@
+package testharness
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"howett.net/plist"
+)
+
+// IdentitySpec describes a generated code signing identity
+type IdentitySpec struct {
+	// CommonName has to start with a known certificate type, e.g. "iPhone Distribution: Harness (ABCD123456)"
+	CommonName string
+	TeamID     string
+	TeamName   string
+	// Validity defaults to a year from now
+	Validity time.Duration
+}
+
+// ProfileSpec describes a generated (not Apple signed) provisioning profile
+type ProfileSpec struct {
+	Name     string
+	BundleID string
+	TeamID   string
+	TeamName string
+	// Platform is "ios" (default) or "macos"
+	Platform     string
+	ExportMethod exportoptions.Method
+	// Identities are the common names of the generated identities the profile contains
+	Identities []string
+	// Validity defaults to a year from now
+	Validity time.Duration
+}
+
+// Identity is a generated certificate and its private key
+type Identity struct {
+	Certificate *x509.Certificate
+	PrivateKey  *rsa.PrivateKey
+}
+
+// Profile is a generated provisioning profile
+type Profile struct {
+	UUID    string
+	Spec    ProfileSpec
+	Content []byte
+}
+
+// Ext returns the file extension of the profile on its platform
+func (profile Profile) Ext() string {
+	if profile.Spec.Platform == "macos" {
+		return ".provisionprofile"
+	}
+	return ".mobileprovision"
+}
+
+const defaultValidity = 365 * 24 * time.Hour
+
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+}
+
+// GenerateCA creates the self-signed root the generated identities are issued by
+func GenerateCA(name string) (Identity, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return Identity{}, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return Identity{}, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(defaultValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to create CA certificate, error: %s", err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return Identity{}, err
+	}
+	return Identity{Certificate: certificate, PrivateKey: key}, nil
+}
+
+// GenerateIdentity creates a code signing identity issued by the given CA
+func GenerateIdentity(spec IdentitySpec, ca Identity) (Identity, error) {
+	if spec.CommonName == "" {
+		return Identity{}, fmt.Errorf("identity common name is required")
+	}
+	validity := spec.Validity
+	if validity == 0 {
+		validity = defaultValidity
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return Identity{}, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return Identity{}, err
+	}
+
+	subject := pkix.Name{CommonName: spec.CommonName}
+	if spec.TeamID != "" {
+		subject.OrganizationalUnit = []string{spec.TeamID}
+	}
+	if spec.TeamName != "" {
+		subject.Organization = []string{spec.TeamName}
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Certificate, &key.PublicKey, ca.PrivateKey)
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to create certificate (%s), error: %s", spec.CommonName, err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return Identity{}, err
+	}
+	return Identity{Certificate: certificate, PrivateKey: key}, nil
+}
+
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return strings.ToUpper(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])), nil
+}
+
+// GenerateProfile creates a provisioning profile containing the given certificates.
+// The profile is wrapped in a PKCS#7 signed data without signers, it is parsed as any installed profile,
+// but it is not accepted by Xcode.
+func GenerateProfile(spec ProfileSpec, certificates []*x509.Certificate) (Profile, error) {
+	if spec.Name == "" || spec.BundleID == "" || spec.TeamID == "" {
+		return Profile{}, fmt.Errorf("profile name, bundle ID and team ID are required")
+	}
+	validity := spec.Validity
+	if validity == 0 {
+		validity = defaultValidity
+	}
+	exportMethod := spec.ExportMethod
+	if exportMethod == "" {
+		exportMethod = exportoptions.MethodDevelopment
+	}
+
+	uuid, err := newUUID()
+	if err != nil {
+		return Profile{}, err
+	}
+
+	platform := "iOS"
+	if spec.Platform == "macos" {
+		platform = "OSX"
+	}
+
+	var developerCertificates [][]byte
+	for _, certificate := range certificates {
+		developerCertificates = append(developerCertificates, certificate.Raw)
+	}
+
+	entitlements := map[string]interface{}{
+		"application-identifier":              spec.TeamID + "." + spec.BundleID,
+		"com.apple.developer.team-identifier": spec.TeamID,
+	}
+	data := map[string]interface{}{
+		"AppIDName":                   spec.Name,
+		"ApplicationIdentifierPrefix": []string{spec.TeamID},
+		"CreationDate":                time.Now().Add(-time.Hour).UTC(),
+		"ExpirationDate":              time.Now().Add(validity).UTC(),
+		"DeveloperCertificates":       developerCertificates,
+		"Entitlements":                entitlements,
+		"Name":                        spec.Name,
+		"Platform":                    []string{platform},
+		"TeamIdentifier":              []string{spec.TeamID},
+		"TeamName":                    spec.TeamName,
+		"TimeToLive":                  int(validity.Hours() / 24),
+		"UUID":                        uuid,
+		"Version":                     1,
+	}
+
+	// the export method is derived from the devices and the get-task-allow entitlement
+	devices := []string{"00008030-000000000000000E"}
+	switch exportMethod {
+	case exportoptions.MethodDevelopment:
+		data["ProvisionedDevices"] = devices
+		entitlements["get-task-allow"] = true
+	case exportoptions.MethodAdHoc:
+		data["ProvisionedDevices"] = devices
+		entitlements["get-task-allow"] = false
+	case exportoptions.MethodEnterprise, exportoptions.MethodDeveloperID:
+		data["ProvisionsAllDevices"] = true
+	case exportoptions.MethodAppStore:
+	default:
+		return Profile{}, fmt.Errorf("unsupported export method: %s", exportMethod)
+	}
+
+	content, err := plist.Marshal(data, plist.XMLFormat)
+	if err != nil {
+		return Profile{}, fmt.Errorf("failed to marshal profile, error: %s", err)
+	}
+	wrapped, err := wrapPKCS7(content)
+	if err != nil {
+		return Profile{}, err
+	}
+	return Profile{UUID: uuid, Spec: spec, Content: wrapped}, nil
+}
+
+var (
+	oidData       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+)
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+type signedData struct {
+	Version                    int
+	DigestAlgorithmIdentifiers []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo                contentInfo
+	SignerInfos                []asn1.RawValue `asn1:"set"`
+}
+
+// wrapPKCS7 wraps the content in a PKCS#7 signed data structure without signers
+func wrapPKCS7(content []byte) ([]byte, error) {
+	octets, err := asn1.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := asn1.Marshal(signedData{
+		Version:     1,
+		ContentInfo: contentInfo{ContentType: oidData, Content: explicitContent(octets)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{ContentType: oidSignedData, Content: explicitContent(signed)})
+}
+
+// explicitContent tags the DER value as the explicit [0] content of a content info
+func explicitContent(der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+}
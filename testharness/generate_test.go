@@ -0,0 +1,49 @@
+package testharness
+
+import (
+	"crypto/x509"
+	"testing"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateProfile(t *testing.T) {
+	ca, err := GenerateCA("codesigndoc harness CA")
+	require.NoError(t, err)
+
+	identity, err := GenerateIdentity(IdentitySpec{CommonName: "iPhone Distribution: Harness (ABCD123456)", TeamID: "ABCD123456", TeamName: "Harness"}, ca)
+	require.NoError(t, err)
+	require.NoError(t, identity.Certificate.CheckSignatureFrom(ca.Certificate))
+
+	certificate := certificateutil.NewCertificateInfo(*identity.Certificate, identity.PrivateKey)
+	require.Equal(t, "ABCD123456", certificate.TeamID)
+	require.NoError(t, certificate.CheckValidity())
+
+	for _, method := range []exportoptions.Method{exportoptions.MethodDevelopment, exportoptions.MethodAdHoc, exportoptions.MethodAppStore, exportoptions.MethodEnterprise} {
+		profile, err := GenerateProfile(ProfileSpec{
+			Name:         "Harness " + string(method),
+			BundleID:     "io.bitrise.harness",
+			TeamID:       "ABCD123456",
+			TeamName:     "Harness",
+			ExportMethod: method,
+		}, []*x509.Certificate{identity.Certificate})
+		require.NoError(t, err)
+
+		p7, err := profileutil.ProvisioningProfileFromContent(profile.Content)
+		require.NoError(t, err)
+		info, err := profileutil.NewProvisioningProfileInfo(*p7)
+		require.NoError(t, err)
+
+		require.Equal(t, profile.UUID, info.UUID)
+		require.Equal(t, "io.bitrise.harness", info.BundleID)
+		require.Equal(t, "ABCD123456", info.TeamID)
+		require.Equal(t, method, info.ExportType)
+		require.Equal(t, profileutil.ProfileTypeIos, info.Type)
+		require.NoError(t, info.CheckValidity())
+		require.Equal(t, 1, len(info.DeveloperCertificates))
+		require.Equal(t, certificate.Serial, info.DeveloperCertificates[0].Serial)
+	}
+}
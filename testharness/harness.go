@@ -0,0 +1,376 @@
+// Package testharness provisions a disposable macOS code signing environment:
+// a dedicated keychain with generated identities and generated provisioning profiles,
+// so the complete codesigndoc pipeline can be run end-to-end and torn down afterwards.
+//
+// The harness changes the user's keychain search list, trust settings and installed profiles while it is open,
+// run it on CI machines or disposable user accounts only.
+package testharness
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/external"
+	"github.com/bitrise-io/codesigndoc/features"
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/bitrise-io/codesigndoc/state"
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-utils/pkcs12"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"gopkg.in/yaml.v2"
+)
+
+// Options configures the disposable environment
+type Options struct {
+	Identities []IdentitySpec
+	Profiles   []ProfileSpec
+	// FillerItems is the number of non-identity items (generic passwords like WiFi networks and internet passwords like web form data)
+	// added to the keychain, to simulate a cluttered keychain
+	FillerItems int
+	// AdminTrust trusts the generated CA in the admin domain (sudo -n security add-trusted-cert -d),
+	// for machines where changing the user trust settings requires interaction.
+	AdminTrust bool
+}
+
+// Env is a provisioned disposable environment, Close tears it down
+type Env struct {
+	// Dir is the temporary root directory of the environment
+	Dir string
+	// WorkDir is the working directory of the commands run by Run, the files are exported to its codesigndoc_exports directory
+	WorkDir string
+	// StateDir is used as the codesigndoc state directory
+	StateDir         string
+	KeychainPath     string
+	KeychainPassword string
+
+	CA         Identity
+	Identities map[string]Identity
+	Profiles   []Profile
+
+	options            Options
+	originalSearchList []string
+	caPath             string
+	profilePaths       []string
+}
+
+// New provisions the environment, on failure the already created parts are torn down
+func New(options Options) (env *Env, err error) {
+	dir, err := ioutil.TempDir("", "codesigndoc-harness")
+	if err != nil {
+		return nil, err
+	}
+	password, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	env = &Env{
+		Dir:              dir,
+		WorkDir:          filepath.Join(dir, "work"),
+		StateDir:         filepath.Join(dir, "state"),
+		KeychainPath:     filepath.Join(dir, "harness.keychain-db"),
+		KeychainPassword: password,
+		Identities:       map[string]Identity{},
+		options:          options,
+	}
+	defer func() {
+		if err != nil {
+			if closeErr := env.Close(); closeErr != nil {
+				err = fmt.Errorf("%s, teardown failed: %s", err, closeErr)
+			}
+			env = nil
+		}
+	}()
+
+	for _, pth := range []string{env.WorkDir, env.StateDir} {
+		if err := os.MkdirAll(pth, 0700); err != nil {
+			return env, err
+		}
+	}
+
+	if err := env.createKeychain(); err != nil {
+		return env, err
+	}
+
+	if err := env.addFillerItems(options.FillerItems); err != nil {
+		return env, err
+	}
+
+	if env.CA, err = GenerateCA("codesigndoc harness CA " + filepath.Base(dir)); err != nil {
+		return env, err
+	}
+	if err := env.trustCA(); err != nil {
+		return env, err
+	}
+
+	for _, spec := range options.Identities {
+		identity, err := GenerateIdentity(spec, env.CA)
+		if err != nil {
+			return env, err
+		}
+		if err := env.importIdentity(spec.CommonName, identity); err != nil {
+			return env, err
+		}
+		env.Identities[spec.CommonName] = identity
+	}
+	if err := security("set-key-partition-list", "-S", "apple-tool:,apple:,codesign:,unsigned:", "-s", "-k", env.KeychainPassword, env.KeychainPath); err != nil {
+		return env, err
+	}
+
+	for _, spec := range options.Profiles {
+		if err := env.installProfile(spec); err != nil {
+			return env, err
+		}
+	}
+
+	return env, nil
+}
+
+func randomPassword() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", b), nil
+}
+
+func security(args ...string) error {
+	cmd := command.New("security", args...)
+	if out, err := cmd.RunAndReturnTrimmedCombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed, output: %s, error: %s", cmd.PrintableCommandArgs(), out, err)
+	}
+	return nil
+}
+
+func searchList() ([]string, error) {
+	out, err := command.New("security", "list-keychains", "-d", "user").RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keychains, output: %s, error: %s", out, err)
+	}
+	var pths []string
+	for _, line := range strings.Split(out, "\n") {
+		if pth := strings.Trim(strings.TrimSpace(line), `"`); pth != "" {
+			pths = append(pths, pth)
+		}
+	}
+	return pths, nil
+}
+
+func (env *Env) createKeychain() error {
+	if err := security("create-keychain", "-p", env.KeychainPassword, env.KeychainPath); err != nil {
+		return err
+	}
+	// no auto-lock, so long running pipelines do not hit a locked keychain
+	if err := security("set-keychain-settings", env.KeychainPath); err != nil {
+		return err
+	}
+	if err := security("unlock-keychain", "-p", env.KeychainPassword, env.KeychainPath); err != nil {
+		return err
+	}
+
+	original, err := searchList()
+	if err != nil {
+		return err
+	}
+	if err := security(append([]string{"list-keychains", "-d", "user", "-s", env.KeychainPath}, original...)...); err != nil {
+		return err
+	}
+	env.originalSearchList = original
+	return nil
+}
+
+// addFillerItems adds the items in a single interactive security session, a command per item would take minutes for 10k items
+func (env *Env) addFillerItems(count int) error {
+	if count <= 0 {
+		return nil
+	}
+
+	var commands bytes.Buffer
+	for i := 0; i < count; i++ {
+		if i%2 == 0 {
+			fmt.Fprintf(&commands, "add-generic-password -a harness -s wifi-network-%d -D AirPort -w filler-%d %s\n", i, i, env.KeychainPath)
+		} else {
+			fmt.Fprintf(&commands, "add-internet-password -a user-%d -s form-%d.example.com -r htps -w filler-%d %s\n", i, i, i, env.KeychainPath)
+		}
+	}
+	commands.WriteString("quit\n")
+
+	var out bytes.Buffer
+	cmd := command.New("security", "-i").SetStdin(&commands).SetStdout(&out).SetStderr(&out)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to add filler items, output: %s, error: %s", out.String(), err)
+	}
+	return nil
+}
+
+func (env *Env) trustCA() error {
+	env.caPath = filepath.Join(env.Dir, "ca.pem")
+	if err := ioutil.WriteFile(env.caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: env.CA.Certificate.Raw}), 0600); err != nil {
+		return err
+	}
+
+	if env.options.AdminTrust {
+		cmd := command.New("sudo", "-n", "security", "add-trusted-cert", "-d", "-r", "trustRoot", "-p", "codeSign", "-k", osxkeychain.SystemKeychainPath, env.caPath)
+		if out, err := cmd.RunAndReturnTrimmedCombinedOutput(); err != nil {
+			return fmt.Errorf("failed to trust the harness CA, output: %s, error: %s", out, err)
+		}
+		return nil
+	}
+	return security("add-trusted-cert", "-r", "trustRoot", "-p", "codeSign", "-k", env.KeychainPath, env.caPath)
+}
+
+func (env *Env) importIdentity(name string, identity Identity) error {
+	p12, err := pkcs12.Encode(rand.Reader, identity.PrivateKey, identity.Certificate, nil, env.KeychainPassword)
+	if err != nil {
+		return fmt.Errorf("failed to encode identity (%s), error: %s", name, err)
+	}
+	pth := filepath.Join(env.Dir, fmt.Sprintf("identity-%d.p12", len(env.Identities)))
+	if err := ioutil.WriteFile(pth, p12, 0600); err != nil {
+		return err
+	}
+	// -A: the generated keys can be used by any application without an access prompt
+	return security("import", pth, "-k", env.KeychainPath, "-P", env.KeychainPassword, "-f", "pkcs12", "-A")
+}
+
+func (env *Env) installProfile(spec ProfileSpec) error {
+	var certificates []*x509.Certificate
+	for _, name := range spec.Identities {
+		identity, ok := env.Identities[name]
+		if !ok {
+			return fmt.Errorf("profile (%s) references an unknown identity: %s", spec.Name, name)
+		}
+		certificates = append(certificates, identity.Certificate)
+	}
+
+	profile, err := GenerateProfile(spec, certificates)
+	if err != nil {
+		return err
+	}
+
+	profilesDir, err := pathutil.AbsPath(profileutil.ProvProfileSystemDirPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(profilesDir, 0700); err != nil {
+		return err
+	}
+	pth := filepath.Join(profilesDir, profile.UUID+profile.Ext())
+	if err := ioutil.WriteFile(pth, profile.Content, 0600); err != nil {
+		return err
+	}
+	env.profilePaths = append(env.profilePaths, pth)
+	env.Profiles = append(env.Profiles, profile)
+	return nil
+}
+
+// RegisterScanner registers an external scanner in the state directory config, which reports the given result
+func (env *Env) RegisterScanner(name string, result external.Result) error {
+	result.Version = external.ProtocolVersion
+	output, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	resultPth := filepath.Join(env.Dir, name+"-result.json")
+	if err := ioutil.WriteFile(resultPth, output, 0600); err != nil {
+		return err
+	}
+	scannerPth := filepath.Join(env.Dir, name+"-scanner.sh")
+	script := fmt.Sprintf("#!/bin/sh\ncat > /dev/null\ncat %q\n", resultPth)
+	if err := ioutil.WriteFile(scannerPth, []byte(script), 0700); err != nil {
+		return err
+	}
+
+	config := state.Config{}
+	configPth := filepath.Join(env.StateDir, "config.yml")
+	if content, err := ioutil.ReadFile(configPth); err == nil {
+		if err := yaml.Unmarshal(content, &config); err != nil {
+			return err
+		}
+	}
+	config.Scanners = append(config.Scanners, state.ScannerConfig{Name: name, Path: scannerPth})
+	content, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(configPth, content, 0600)
+}
+
+// Environ returns the environment of the commands run in the harness:
+// the harness state directory is used and the interactive Bitrise upload is disabled.
+func (env *Env) Environ() []string {
+	return append(os.Environ(),
+		state.DirEnvKey+"="+env.StateDir,
+		features.DisabledFeaturesEnvKey+"="+codesign.BitriseDestinationFeature,
+	)
+}
+
+// Run runs the codesigndoc binary in the environment's working directory
+func (env *Env) Run(binaryPth string, args ...string) (string, error) {
+	cmd := command.New(binaryPth, args...).SetDir(env.WorkDir).SetEnvs(env.Environ()...)
+	return cmd.RunAndReturnTrimmedCombinedOutput()
+}
+
+// ExportDir is the directory the pipeline run by Run writes the code signing files to
+func (env *Env) ExportDir() string {
+	return filepath.Join(env.WorkDir, "codesigndoc_exports")
+}
+
+// Close removes the keychain, the trust setting and the installed profiles, and restores the keychain search list
+func (env *Env) Close() error {
+	var errs []string
+
+	for _, pth := range env.profilePaths {
+		if err := os.Remove(pth); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err.Error())
+		}
+	}
+	env.profilePaths = nil
+
+	if env.options.AdminTrust && env.caPath != "" {
+		if out, err := command.New("sudo", "-n", "security", "remove-trusted-cert", "-d", env.caPath).RunAndReturnTrimmedCombinedOutput(); err != nil {
+			errs = append(errs, fmt.Sprintf("failed to remove the harness CA trust, output: %s, error: %s", out, err))
+		}
+		// the CA is added to the System keychain by add-trusted-cert -k, remove-trusted-cert only removes its trust setting
+		fingerprint := fmt.Sprintf("%X", sha1.Sum(env.CA.Certificate.Raw))
+		if out, err := command.New("sudo", "-n", "security", "delete-certificate", "-Z", fingerprint, osxkeychain.SystemKeychainPath).RunAndReturnTrimmedCombinedOutput(); err != nil {
+			errs = append(errs, fmt.Sprintf("failed to remove the harness CA from the System keychain, output: %s, error: %s", out, err))
+		}
+	} else if env.caPath != "" {
+		// the user domain trust setting is stored in the user's trust settings, deleting the keychain leaves it in place
+		if err := security("remove-trusted-cert", env.caPath); err != nil {
+			errs = append(errs, fmt.Sprintf("failed to remove the harness CA trust, error: %s", err))
+		}
+	}
+
+	if env.originalSearchList != nil {
+		if err := security(append([]string{"list-keychains", "-d", "user", "-s"}, env.originalSearchList...)...); err != nil {
+			errs = append(errs, err.Error())
+		}
+		env.originalSearchList = nil
+	}
+	if exists, err := pathutil.IsPathExists(env.KeychainPath); err == nil && exists {
+		if err := security("delete-keychain", env.KeychainPath); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if err := os.RemoveAll(env.Dir); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to tear down the harness: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
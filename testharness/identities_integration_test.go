@@ -0,0 +1,123 @@
+//go:build integration && darwin
+// +build integration,darwin
+
+package testharness
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/stretchr/testify/require"
+)
+
+const manyIdentitiesCount = 200
+
+var (
+	manyIdentitiesOnce sync.Once
+	manyIdentitiesEnv  *Env
+	manyIdentitiesErr  error
+)
+
+// manyIdentities returns the shared environment with a keychain of manyIdentitiesCount identities, created on first use
+func manyIdentities(tb testing.TB) *Env {
+	manyIdentitiesOnce.Do(func() {
+		var specs []IdentitySpec
+		for i := 0; i < manyIdentitiesCount; i++ {
+			specs = append(specs, IdentitySpec{CommonName: fmt.Sprintf("iPhone Developer: codesigndoc identity %03d (HARNESS123)", i), TeamID: harnessTeamID})
+		}
+		manyIdentitiesEnv, manyIdentitiesErr = New(Options{
+			Identities: specs,
+			AdminTrust: os.Getenv("CODESIGNDOC_HARNESS_ADMIN_TRUST") == "true",
+		})
+		if manyIdentitiesErr == nil {
+			sharedEnvs = append(sharedEnvs, manyIdentitiesEnv)
+		}
+	})
+	require.NoError(tb, manyIdentitiesErr)
+	return manyIdentitiesEnv
+}
+
+// TestSelectIdentitiesConcurrently verifies that the concurrent parsing selects the same identities as the serial one
+func TestSelectIdentitiesConcurrently(t *testing.T) {
+	manyIdentities(t)
+	defer func(workers int) { osxkeychain.ParseWorkers = workers }(osxkeychain.ParseWorkers)
+
+	selected := map[int]map[string]string{}
+	for _, workers := range []int{1, 8} {
+		osxkeychain.ParseWorkers = workers
+		selection, err := osxkeychain.SelectIdentities(osxkeychain.IdentityQuery{TeamIDs: []string{harnessTeamID}})
+		require.NoError(t, err)
+
+		selected[workers] = map[string]string{}
+		for label, identity := range selection.Selected {
+			selected[workers][label] = identity.SHA1Fingerprint
+			osxkeychain.ReleaseRef(identity.KeychainRef)
+		}
+	}
+	require.Equal(t, manyIdentitiesCount, len(selected[1]))
+	require.Equal(t, selected[1], selected[8])
+}
+
+// TestSelectIdentitiesAudit verifies that the selection releases the skipped references and the copied certificates, and the CF object audit reports the selected ones until released
+func TestSelectIdentitiesAudit(t *testing.T) {
+	manyIdentities(t)
+	defer func(audit bool) { osxkeychain.Audit = audit }(osxkeychain.Audit)
+	osxkeychain.Audit = true
+
+	identities, err := osxkeychain.FindIdentities(osxkeychain.IdentityQuery{TeamIDs: []string{harnessTeamID}})
+	require.NoError(t, err)
+	managed := osxkeychain.Manage(identities)
+
+	// the certificates copied while parsing the identities are released right away, only the identities are retained
+	leaked := 0
+	for _, leak := range osxkeychain.Report().Leaks {
+		require.Equal(t, "identity", leak.Kind)
+		leaked += leak.Count
+	}
+	require.Equal(t, len(identities), leaked)
+
+	managed.Release()
+	managed.Release()
+	require.Empty(t, osxkeychain.Report().Leaks)
+}
+
+func BenchmarkSelectIdentitiesManyIdentities(b *testing.B) {
+	manyIdentities(b)
+	defer func(workers int) { osxkeychain.ParseWorkers = workers }(osxkeychain.ParseWorkers)
+
+	for _, workers := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			osxkeychain.ParseWorkers = workers
+			for i := 0; i < b.N; i++ {
+				selection, err := osxkeychain.SelectIdentities(osxkeychain.IdentityQuery{TeamIDs: []string{harnessTeamID}})
+				if err != nil {
+					b.Fatal(err)
+				}
+				for _, identity := range selection.Selected {
+					osxkeychain.ReleaseRef(identity.KeychainRef)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFindAndValidateIdentityManyIdentities(b *testing.B) {
+	manyIdentities(b)
+	defer func(workers int) { osxkeychain.ParseWorkers = workers }(osxkeychain.ParseWorkers)
+
+	for _, workers := range []int{1, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			osxkeychain.ParseWorkers = workers
+			for i := 0; i < b.N; i++ {
+				identity, err := osxkeychain.FindAndValidateIdentity("iPhone Developer: codesigndoc identity 000 (HARNESS123)")
+				if err != nil {
+					b.Fatal(err)
+				}
+				osxkeychain.ReleaseRef(identity.KeychainRef)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+//go:build integration && darwin
+// +build integration,darwin
+
+package testharness
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/codesigndoc/osxkeychain"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	largeKeychainItems    = 10000
+	largeKeychainIdentity = "iPhone Developer: codesigndoc large keychain (HARNESS123)"
+	// installedCertificatesEnvelope is the expected duration of listing the installed certificates with 10k non-identity items,
+	// the listing shells out to the security tool a few times, so it is slower than the scoped identity query.
+	installedCertificatesEnvelope = 3 * time.Second
+)
+
+var (
+	largeKeychainOnce sync.Once
+	largeKeychainEnv  *Env
+	largeKeychainErr  error
+
+	// sharedEnvs are the environments shared by the tests and benchmarks, closed in reverse order by TestMain
+	sharedEnvs []*Env
+)
+
+// largeKeychain returns the shared environment with a keychain of 10k non-identity items, created on first use
+func largeKeychain(tb testing.TB) *Env {
+	largeKeychainOnce.Do(func() {
+		largeKeychainEnv, largeKeychainErr = New(Options{
+			Identities:  []IdentitySpec{{CommonName: largeKeychainIdentity, TeamID: harnessTeamID}},
+			FillerItems: largeKeychainItems,
+			AdminTrust:  os.Getenv("CODESIGNDOC_HARNESS_ADMIN_TRUST") == "true",
+		})
+		if largeKeychainErr == nil {
+			sharedEnvs = append(sharedEnvs, largeKeychainEnv)
+		}
+	})
+	require.NoError(tb, largeKeychainErr)
+	return largeKeychainEnv
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	// every environment restores the search list it found, so the last one is closed first
+	for i := len(sharedEnvs) - 1; i >= 0; i-- {
+		if err := sharedEnvs[i].Close(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
+
+func findLargeKeychainIdentity(tb testing.TB) {
+	identities, err := osxkeychain.FindIdentity(largeKeychainIdentity)
+	require.NoError(tb, err)
+	require.Equal(tb, 1, len(identities))
+	osxkeychain.ReleaseIdentityWithRefList(identities)
+}
+
+// TestLargeKeychainEnvelope verifies that the queries stay in the documented performance envelope on a cluttered keychain
+func TestLargeKeychainEnvelope(t *testing.T) {
+	largeKeychain(t)
+
+	start := time.Now()
+	findLargeKeychainIdentity(t)
+	require.True(t, time.Since(start) < osxkeychain.ExpectedIdentityQueryDuration, "FindIdentity took %s, expected: < %s", time.Since(start), osxkeychain.ExpectedIdentityQueryDuration)
+
+	missing, err := osxkeychain.FindIdentity("iPhone Developer: not installed")
+	require.NoError(t, err)
+	require.Equal(t, 0, len(missing))
+
+	start = time.Now()
+	certificates, err := codesign.InstalledCertificates(codesign.IOSCertificate)
+	require.NoError(t, err)
+	require.True(t, time.Since(start) < installedCertificatesEnvelope, "InstalledCertificates took %s, expected: < %s", time.Since(start), installedCertificatesEnvelope)
+
+	found := false
+	for _, certificate := range certificates {
+		if certificate.CommonName == largeKeychainIdentity {
+			found = true
+		}
+	}
+	require.True(t, found)
+}
+
+// TestFindIdentityInKeychain verifies the lookup restricted to the harness keychain file
+func TestFindIdentityInKeychain(t *testing.T) {
+	env := largeKeychain(t)
+
+	scoped, err := osxkeychain.FindIdentityInKeychain(env.KeychainPath, largeKeychainIdentity)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(scoped))
+	osxkeychain.ReleaseIdentityWithRefList(scoped)
+
+	_, err = osxkeychain.FindIdentityInKeychain(env.KeychainPath+".missing", largeKeychainIdentity)
+	require.Error(t, err)
+}
+
+func BenchmarkFindIdentityLargeKeychain(b *testing.B) {
+	largeKeychain(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		findLargeKeychainIdentity(b)
+	}
+}
+
+func BenchmarkInstalledCertificatesLargeKeychain(b *testing.B) {
+	largeKeychain(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := codesign.InstalledCertificates(codesign.IOSCertificate); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
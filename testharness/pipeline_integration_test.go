@@ -0,0 +1,73 @@
+//go:build integration && darwin
+// +build integration,darwin
+
+package testharness
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/codesigndoc/external"
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	harnessTeamID   = "HARNESS123"
+	harnessIdentity = "iPhone Distribution: codesigndoc harness (HARNESS123)"
+	harnessBundleID = "io.bitrise.codesigndoc.harness"
+)
+
+// TestScanPipeline runs the complete scan, export and write pipeline of the codesigndoc binary
+// against a disposable keychain: go test -tags integration ./testharness/...
+func TestScanPipeline(t *testing.T) {
+	env, err := New(Options{
+		Identities: []IdentitySpec{{CommonName: harnessIdentity, TeamID: harnessTeamID, TeamName: "codesigndoc harness"}},
+		Profiles: []ProfileSpec{{
+			Name:         "codesigndoc harness app-store",
+			BundleID:     harnessBundleID,
+			TeamID:       harnessTeamID,
+			ExportMethod: exportoptions.MethodAppStore,
+			Identities:   []string{harnessIdentity},
+		}},
+		AdminTrust: os.Getenv("CODESIGNDOC_HARNESS_ADMIN_TRUST") == "true",
+	})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, env.Close())
+	}()
+
+	binaryPth := filepath.Join(env.Dir, "codesigndoc")
+	out, err := command.New("go", "build", "-o", binaryPth, "github.com/bitrise-io/codesigndoc").RunAndReturnTrimmedCombinedOutput()
+	require.NoError(t, err, out)
+
+	require.NoError(t, env.RegisterScanner("harness", external.Result{
+		Identities: []external.IdentityRequirement{{CommonName: harnessIdentity}},
+		Profiles:   []external.ProfileRequirement{{BundleID: harnessBundleID, ExportMethod: exportoptions.MethodAppStore}},
+	}))
+
+	out, err = env.Run(binaryPth, "scan", "--skip-keychain-check", "--write-files", "always", "external", "--name", "harness")
+	require.NoError(t, err, out)
+
+	p12Pths, err := filepath.Glob(filepath.Join(env.ExportDir(), "*.p12"))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(p12Pths), out)
+	content, err := ioutil.ReadFile(p12Pths[0])
+	require.NoError(t, err)
+	certificates, err := certificateutil.CertificatesFromPKCS12Content(content, "")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(certificates))
+	require.Equal(t, env.Identities[harnessIdentity].Certificate.SerialNumber.String(), certificates[0].Serial)
+
+	profilePths, err := filepath.Glob(filepath.Join(env.ExportDir(), "*.mobileprovision"))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(profilePths), out)
+	profile, err := profileutil.NewProvisioningProfileInfoFromFile(profilePths[0])
+	require.NoError(t, err)
+	require.Equal(t, env.Profiles[0].UUID, profile.UUID)
+}
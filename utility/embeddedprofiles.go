@@ -0,0 +1,103 @@
+package utility
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	xcodeutility "github.com/bitrise-io/go-xcode/utility"
+)
+
+// EmbeddedProfileDirPaths are the directories of the Xcode build products and archives,
+// the profiles embedded in them are used if a required profile is no longer installed.
+var EmbeddedProfileDirPaths = []string{"~/Library/Developer/Xcode/DerivedData", "~/Library/Developer/Xcode/Archives"}
+
+// embeddedProfilePatterns are the locations of the embedded profiles relative to an EmbeddedProfileDirPaths entry
+var embeddedProfilePatterns = []string{
+	// DerivedData/<project>/Build/Products/<configuration>/<app>
+	"*/Build/Products/*/*.app",
+	// DerivedData/<project>/Build/Intermediates.noindex/ArchiveIntermediates/<scheme>/InstallationBuildProductsLocation/Applications/<app>
+	"*/Build/Intermediates.noindex/ArchiveIntermediates/*/InstallationBuildProductsLocation/Applications/*.app",
+	// Archives/<date>/<archive>/Products/Applications/<app>
+	"*/*.xcarchive/Products/Applications/*.app",
+}
+
+// embeddedProfileBundlePaths are the embedded profiles relative to an app, of the app, its extensions and watch app
+var embeddedProfileBundlePaths = []string{
+	"embedded.mobileprovision",
+	"PlugIns/*.appex/embedded.mobileprovision",
+	"Watch/*.app/embedded.mobileprovision",
+	"Watch/*.app/PlugIns/*.appex/embedded.mobileprovision",
+	"AppClips/*.app/embedded.mobileprovision",
+	"Contents/embedded.provisionprofile",
+	"Contents/PlugIns/*.appex/Contents/embedded.provisionprofile",
+}
+
+// FindEmbeddedProfileInfo returns the most recently built copy of the profile embedded in the build products or archives,
+// and its path, it fails if the profile is not found.
+func FindEmbeddedProfileInfo(uuid string) (profileutil.ProvisioningProfileInfoModel, string, error) {
+	candidates, err := embeddedProfileCandidates(EmbeddedProfileDirPaths, uuid)
+	if err != nil {
+		return profileutil.ProvisioningProfileInfoModel{}, "", err
+	}
+
+	for _, pth := range candidates {
+		profile, err := profileutil.ProvisioningProfileFromFile(pth)
+		if err != nil {
+			continue
+		}
+		info, err := profileutil.NewProvisioningProfileInfo(*profile)
+		if err != nil || info.UUID != uuid {
+			continue
+		}
+		return info, pth, nil
+	}
+	return profileutil.ProvisioningProfileInfoModel{}, "", fmt.Errorf("profile (%s) is not embedded in any of the build products or archives of: %v", uuid, EmbeddedProfileDirPaths)
+}
+
+// embeddedProfileCandidates returns the embedded profiles containing the UUID, the most recently modified first
+func embeddedProfileCandidates(dirs []string, uuid string) ([]string, error) {
+	type candidate struct {
+		pth     string
+		modTime int64
+	}
+	var candidates []candidate
+	for _, dir := range dirs {
+		absDir, err := pathutil.AbsPath(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, appPattern := range embeddedProfilePatterns {
+			for _, bundlePath := range embeddedProfileBundlePaths {
+				pths, err := filepath.Glob(filepath.Join(xcodeutility.EscapeGlobPath(absDir), appPattern, bundlePath))
+				if err != nil {
+					return nil, err
+				}
+				for _, pth := range pths {
+					// the profile's plist is not encrypted, skip the other profiles without parsing them
+					content, err := ioutil.ReadFile(pth)
+					if err != nil || !bytes.Contains(content, []byte(uuid)) {
+						continue
+					}
+					info, err := os.Stat(pth)
+					if err != nil {
+						continue
+					}
+					candidates = append(candidates, candidate{pth: pth, modTime: info.ModTime().UnixNano()})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].modTime > candidates[j].modTime })
+	var pths []string
+	for _, c := range candidates {
+		pths = append(pths, c.pth)
+	}
+	return pths, nil
+}
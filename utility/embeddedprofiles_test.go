@@ -0,0 +1,38 @@
+package utility
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedProfileCandidates(t *testing.T) {
+	derivedData, err := ioutil.TempDir("", "DerivedData")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(derivedData))
+	}()
+
+	const uuid = "8F3E1A52-7C1B-4F5E-9AB1-0C2D3E4F5A6B"
+	write := func(pth, content string, modTime time.Time) string {
+		pth = filepath.Join(derivedData, pth)
+		require.NoError(t, os.MkdirAll(filepath.Dir(pth), 0700))
+		require.NoError(t, ioutil.WriteFile(pth, []byte(content), 0600))
+		require.NoError(t, os.Chtimes(pth, modTime, modTime))
+		return pth
+	}
+
+	now := time.Now()
+	older := write("App-abc/Build/Products/Release-iphoneos/App.app/embedded.mobileprovision", "<string>"+uuid+"</string>", now.Add(-time.Hour))
+	newer := write("App-abc/Build/Products/Debug-iphoneos/App.app/PlugIns/Widget.appex/embedded.mobileprovision", "<string>"+uuid+"</string>", now)
+	write("App-abc/Build/Products/Debug-iphoneos/Other.app/embedded.mobileprovision", "<string>other</string>", now)
+	write("App-abc/Build/Products/Debug-iphoneos/App.app/Frameworks/Lib.framework/embedded.mobileprovision", "<string>"+uuid+"</string>", now)
+
+	candidates, err := embeddedProfileCandidates([]string{derivedData}, uuid)
+	require.NoError(t, err)
+	require.Equal(t, []string{newer, older}, candidates)
+}
@@ -0,0 +1,102 @@
+package utility
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bitrise-io/codesigndoc/progress"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	xcodeutility "github.com/bitrise-io/go-xcode/utility"
+)
+
+// XcodeProfilesDirPath is the directory of the provisioning profiles installed by Xcode 16 and later
+const XcodeProfilesDirPath = "~/Library/Developer/Xcode/UserData/Provisioning Profiles"
+
+// ProfileDirPaths are the directories of the installed provisioning profiles, in the order of precedence
+var ProfileDirPaths = []string{profileutil.ProvProfileSystemDirPath, XcodeProfilesDirPath}
+
+// profileExtension returns the file extension of the profile type
+func profileExtension(profileType profileutil.ProfileType) string {
+	if profileType == profileutil.ProfileTypeMacOs {
+		return ".provisionprofile"
+	}
+	return ".mobileprovision"
+}
+
+// InstalledProfileInfos returns the profiles of the type installed in any of the ProfileDirPaths,
+// a profile installed in multiple directories is listed once.
+func InstalledProfileInfos(profileType profileutil.ProfileType) ([]profileutil.ProvisioningProfileInfoModel, error) {
+	var pths []string
+	for _, dir := range ProfileDirPaths {
+		absDir, err := pathutil.AbsPath(dir)
+		if err != nil {
+			return nil, err
+		}
+		dirPths, err := filepath.Glob(filepath.Join(xcodeutility.EscapeGlobPath(absDir), "*"+profileExtension(profileType)))
+		if err != nil {
+			return nil, err
+		}
+		pths = append(pths, dirPths...)
+	}
+
+	scanning := progress.Start("Reading the installed profiles", len(pths))
+	defer scanning.Done()
+
+	seen := map[string]bool{}
+	infos := []profileutil.ProvisioningProfileInfoModel{}
+	for _, pth := range pths {
+		profile, err := profileutil.ProvisioningProfileFromFile(pth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read profile (%s), error: %s", pth, err)
+		}
+		info, err := profileutil.NewProvisioningProfileInfo(*profile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse profile (%s), error: %s", pth, err)
+		}
+		if !seen[info.UUID] {
+			seen[info.UUID] = true
+			infos = append(infos, info)
+		}
+		scanning.Add(1)
+	}
+	return infos, nil
+}
+
+// FindProfileInfo returns the installed profile of the UUID and its path,
+// it falls back to the copy embedded in the Xcode build products and archives if the profile is no longer installed.
+func FindProfileInfo(uuid string) (profileutil.ProvisioningProfileInfoModel, string, error) {
+	for _, dir := range ProfileDirPaths {
+		absDir, err := pathutil.AbsPath(dir)
+		if err != nil {
+			return profileutil.ProvisioningProfileInfoModel{}, "", err
+		}
+		for _, profileType := range []profileutil.ProfileType{profileutil.ProfileTypeIos, profileutil.ProfileTypeMacOs} {
+			pth := filepath.Join(absDir, uuid+profileExtension(profileType))
+			if _, err := os.Stat(pth); os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return profileutil.ProvisioningProfileInfoModel{}, "", err
+			}
+
+			profile, err := profileutil.ProvisioningProfileFromFile(pth)
+			if err != nil {
+				return profileutil.ProvisioningProfileInfoModel{}, "", fmt.Errorf("failed to read profile (%s), error: %s", pth, err)
+			}
+			info, err := profileutil.NewProvisioningProfileInfo(*profile)
+			if err != nil {
+				return profileutil.ProvisioningProfileInfoModel{}, "", fmt.Errorf("failed to parse profile (%s), error: %s", pth, err)
+			}
+			return info, pth, nil
+		}
+	}
+
+	info, pth, err := FindEmbeddedProfileInfo(uuid)
+	if err != nil {
+		return profileutil.ProvisioningProfileInfoModel{}, "", fmt.Errorf("profile (%s) is not installed in any of: %v, and %s", uuid, ProfileDirPaths, err)
+	}
+	log.Warnf("Profile %s (%s) is not installed, using its copy embedded in: %s", info.Name, uuid, pth)
+	return info, pth, nil
+}
@@ -0,0 +1,151 @@
+package whitelabel
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/codesigndoc/codesign"
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"gopkg.in/yaml.v2"
+)
+
+// Brand is a white-label variant of the app, built from the same codebase with its own bundle IDs
+type Brand struct {
+	Name      string   `yaml:"name"`
+	BundleIDs []string `yaml:"bundle_ids"`
+}
+
+// Mapping lists the brands of a white-label project
+type Mapping struct {
+	Brands []Brand `yaml:"brands"`
+}
+
+// NewMappingFromFile reads the brand mapping file
+func NewMappingFromFile(pth string) (Mapping, error) {
+	content, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return Mapping{}, fmt.Errorf("failed to read mapping file, error: %s", err)
+	}
+	return NewMapping(content)
+}
+
+// NewMapping parses and validates the brand mapping
+func NewMapping(content []byte) (Mapping, error) {
+	var mapping Mapping
+	if err := yaml.Unmarshal(content, &mapping); err != nil {
+		return Mapping{}, fmt.Errorf("failed to parse mapping, error: %s", err)
+	}
+
+	if len(mapping.Brands) == 0 {
+		return Mapping{}, fmt.Errorf("no brands defined in the mapping")
+	}
+
+	names := map[string]bool{}
+	for _, brand := range mapping.Brands {
+		if brand.Name == "" {
+			return Mapping{}, fmt.Errorf("brand without name found in the mapping")
+		}
+		if brand.Name == "." || brand.Name == ".." || strings.ContainsAny(brand.Name, `/\`) || filepath.Base(brand.Name) != brand.Name {
+			return Mapping{}, fmt.Errorf("invalid brand name (%s), it is used as a directory name", brand.Name)
+		}
+		if names[brand.Name] {
+			return Mapping{}, fmt.Errorf("brand (%s) is defined multiple times", brand.Name)
+		}
+		names[brand.Name] = true
+
+		if len(brand.BundleIDs) == 0 {
+			return Mapping{}, fmt.Errorf("brand (%s) has no bundle IDs", brand.Name)
+		}
+	}
+
+	return mapping, nil
+}
+
+// BrandAssets are the code signing files selected for a brand
+type BrandAssets struct {
+	Brand        Brand
+	Certificates []certificateutil.CertificateInfoModel
+	Profiles     []profileutil.ProvisioningProfileInfoModel
+	// MissingBundleIDs are the bundle IDs of the brand without a matching provisioning profile
+	MissingBundleIDs []string
+}
+
+// MatchBrand selects the valid provisioning profiles of the brand's bundle IDs and the certificates included in them.
+// Explicit bundle ID profiles are preferred over wildcard ones, if no export method is given every method is selected.
+func MatchBrand(brand Brand, profiles []profileutil.ProvisioningProfileInfoModel, certificates []certificateutil.CertificateInfoModel, exportMethods ...exportoptions.Method) BrandAssets {
+	assets := BrandAssets{Brand: brand}
+
+	var candidates []profileutil.ProvisioningProfileInfoModel
+	for _, profile := range profiles {
+		if err := profile.CheckValidity(); err != nil {
+			continue
+		}
+		if !profile.HasInstalledCertificate(certificates) {
+			continue
+		}
+		if len(exportMethods) > 0 && !containsMethod(exportMethods, profile.ExportType) {
+			continue
+		}
+		candidates = append(candidates, profile)
+	}
+
+	selectedProfiles := map[string]profileutil.ProvisioningProfileInfoModel{}
+	for _, bundleID := range brand.BundleIDs {
+		var matching []profileutil.ProvisioningProfileInfoModel
+		for _, profile := range candidates {
+			if profile.BundleID == bundleID {
+				matching = append(matching, profile)
+			}
+		}
+		if len(matching) == 0 {
+			for _, profile := range candidates {
+				if codesign.IsWildcardBundleIDMatch(profile.BundleID, bundleID) {
+					matching = append(matching, profile)
+				}
+			}
+		}
+
+		if len(matching) == 0 {
+			assets.MissingBundleIDs = append(assets.MissingBundleIDs, bundleID)
+			continue
+		}
+		for _, profile := range codesign.FilterLatestProfiles(matching) {
+			selectedProfiles[profile.UUID] = profile
+		}
+	}
+
+	for _, profile := range selectedProfiles {
+		assets.Profiles = append(assets.Profiles, profile)
+	}
+	sort.Slice(assets.Profiles, func(i, j int) bool {
+		if assets.Profiles[i].BundleID != assets.Profiles[j].BundleID {
+			return assets.Profiles[i].BundleID < assets.Profiles[j].BundleID
+		}
+		return assets.Profiles[i].Name < assets.Profiles[j].Name
+	})
+
+	for _, certificate := range certificates {
+		for _, profile := range assets.Profiles {
+			if profile.HasInstalledCertificate([]certificateutil.CertificateInfoModel{certificate}) {
+				assets.Certificates = append(assets.Certificates, certificate)
+				break
+			}
+		}
+	}
+
+	return assets
+}
+
+func containsMethod(methods []exportoptions.Method, method exportoptions.Method) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
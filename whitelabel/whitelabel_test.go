@@ -0,0 +1,69 @@
+package whitelabel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-xcode/certificateutil"
+	"github.com/bitrise-io/go-xcode/exportoptions"
+	"github.com/bitrise-io/go-xcode/profileutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMapping(t *testing.T) {
+	mapping, err := NewMapping([]byte(`brands:
+- name: brand-a
+  bundle_ids:
+  - com.company.a
+  - com.company.a.widget
+- name: brand-b
+  bundle_ids:
+  - com.company.b
+`))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(mapping.Brands))
+	require.Equal(t, []string{"com.company.a", "com.company.a.widget"}, mapping.Brands[0].BundleIDs)
+
+	_, err = NewMapping([]byte(`brands:
+- name: brand-a
+  bundle_ids: [com.company.a]
+- name: brand-a
+  bundle_ids: [com.company.b]
+`))
+	require.Error(t, err)
+
+	_, err = NewMapping([]byte(`brands:
+- name: brand-a
+`))
+	require.Error(t, err)
+
+	for _, name := range []string{`""`, ".", "..", "brand/a", `'brand\a'`} {
+		_, err = NewMapping([]byte(`brands:
+- name: ` + name + `
+  bundle_ids: [com.company.a]
+`))
+		require.Error(t, err, name)
+	}
+}
+
+func TestMatchBrand(t *testing.T) {
+	certificate := certificateutil.CertificateInfoModel{CommonName: "iPhone Distribution: Company", Serial: "1"}
+	otherCertificate := certificateutil.CertificateInfoModel{CommonName: "iPhone Developer: Company", Serial: "2"}
+	expire := time.Now().Add(24 * time.Hour)
+
+	profiles := []profileutil.ProvisioningProfileInfoModel{
+		{UUID: "1", Name: "A", BundleID: "com.company.a", ExportType: exportoptions.MethodAppStore, ExpirationDate: expire, DeveloperCertificates: []certificateutil.CertificateInfoModel{certificate}},
+		{UUID: "2", Name: "A old", BundleID: "com.company.a", ExportType: exportoptions.MethodAppStore, ExpirationDate: time.Now().Add(-time.Hour), DeveloperCertificates: []certificateutil.CertificateInfoModel{certificate}},
+		{UUID: "3", Name: "Wildcard", BundleID: "com.company.*", ExportType: exportoptions.MethodAppStore, ExpirationDate: expire, DeveloperCertificates: []certificateutil.CertificateInfoModel{certificate}},
+		{UUID: "4", Name: "A dev", BundleID: "com.company.a", ExportType: exportoptions.MethodDevelopment, ExpirationDate: expire, DeveloperCertificates: []certificateutil.CertificateInfoModel{otherCertificate}},
+	}
+
+	brand := Brand{Name: "brand-a", BundleIDs: []string{"com.company.a", "com.company.a.widget", "org.other.a"}}
+	assets := MatchBrand(brand, profiles, []certificateutil.CertificateInfoModel{certificate, otherCertificate}, exportoptions.MethodAppStore)
+
+	require.Equal(t, 2, len(assets.Profiles))
+	require.Equal(t, "3", assets.Profiles[0].UUID)
+	require.Equal(t, "1", assets.Profiles[1].UUID)
+	require.Equal(t, []certificateutil.CertificateInfoModel{certificate}, assets.Certificates)
+	require.Equal(t, []string{"org.other.a"}, assets.MissingBundleIDs)
+}
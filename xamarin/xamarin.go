@@ -2,16 +2,16 @@ package xamarin
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
+	"github.com/bitrise-io/codesigndoc/progress"
 	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-io/go-utils/pathutil"
-	"github.com/bitrise-io/go-utils/progress"
 	"github.com/bitrise-io/go-xamarin/builder"
 	"github.com/bitrise-io/go-xamarin/constants"
 	"github.com/bitrise-io/go-xamarin/tools/buildtools"
@@ -38,16 +38,19 @@ func (xamarinCmd *CommandModel) SetConfigurationPlatformCombination(configPlatfo
 	return nil
 }
 
-// GenerateArchive ...
-func (xamarinCmd CommandModel) GenerateArchive() (string, string, error) {
+// GenerateArchive archives the solution, the build is not started if the context is done
+// (the go-xamarin builder can not interrupt a running msbuild)
+func (xamarinCmd CommandModel) GenerateArchive(ctx context.Context) (string, string, error) {
 	cmdOut := ""
 	archivePth := ""
 	var err error
+	if err := ctx.Err(); err != nil {
+		return "", "", fmt.Errorf("xamarin build canceled: %w", err)
+	}
 
-	progress.SimpleProgress(".", 1*time.Second, func() {
+	progress.Run("Archiving the project", func() {
 		archivePth, cmdOut, err = xamarinCmd.RunBuildCommand()
 	})
-	fmt.Println()
 
 	if err != nil {
 		return "", cmdOut, err
@@ -0,0 +1,96 @@
+package xcode
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TargetCodeSignSettings are the code signing related build settings of a target in a build configuration
+type TargetCodeSignSettings struct {
+	Target                       string
+	Configuration                string
+	BundleID                     string
+	CodeSignStyle                string
+	CodeSignIdentity             string
+	ProvisioningProfileSpecifier string
+	DevelopmentTeam              string
+}
+
+// IsAutomatic returns true if the target uses "Automatically manage signing", Xcode selects or creates its profile
+func (settings TargetCodeSignSettings) IsAutomatic() bool {
+	return settings.CodeSignStyle == "Automatic"
+}
+
+// AutomaticSigningTargets returns the targets using "Automatically manage signing"
+func AutomaticSigningTargets(settings []TargetCodeSignSettings) []TargetCodeSignSettings {
+	var automatic []TargetCodeSignSettings
+	for _, target := range settings {
+		if target.IsAutomatic() {
+			automatic = append(automatic, target)
+		}
+	}
+	return automatic
+}
+
+// ShowCodeSignSettings runs xcodebuild -showBuildSettings for the scheme in the given configuration
+// (the Configuration of the command, or the scheme's archive configuration if empty), and returns the code signing settings of its targets.
+func (xccmd CommandModel) ShowCodeSignSettings(configuration string) ([]TargetCodeSignSettings, error) {
+	args := []string{"-showBuildSettings"}
+	if configuration != "" {
+		xccmd.Configuration = configuration
+	}
+
+	xcoutput, err := xccmd.RunXcodebuildCommand(args...)
+	if err != nil {
+		return nil, fmt.Errorf("error: %s | xcodebuild output: %s", err, xcoutput)
+	}
+	return parseCodeSignSettings(xcoutput), nil
+}
+
+var (
+	targetBuildSettingsPattern = regexp.MustCompile(`^Build settings for action \w+ and target "?(.+?)"?:$`)
+	buildSettingPattern        = regexp.MustCompile(`^\s+([A-Z0-9_]+) = (.*)$`)
+)
+
+func parseCodeSignSettings(xcodeOutput string) []TargetCodeSignSettings {
+	var targets []TargetCodeSignSettings
+	var current *TargetCodeSignSettings
+
+	scanner := bufio.NewScanner(strings.NewReader(xcodeOutput))
+	// some build settings (e.g. HEADER_SEARCH_PATHS) can be longer than the default limit
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := targetBuildSettingsPattern.FindStringSubmatch(line); match != nil {
+			targets = append(targets, TargetCodeSignSettings{Target: match[1]})
+			current = &targets[len(targets)-1]
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		match := buildSettingPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		value := strings.TrimSpace(match[2])
+		switch match[1] {
+		case "CONFIGURATION":
+			current.Configuration = value
+		case "PRODUCT_BUNDLE_IDENTIFIER":
+			current.BundleID = value
+		case "CODE_SIGN_STYLE":
+			current.CodeSignStyle = value
+		case "CODE_SIGN_IDENTITY":
+			current.CodeSignIdentity = value
+		case "PROVISIONING_PROFILE_SPECIFIER":
+			current.ProvisioningProfileSpecifier = value
+		case "DEVELOPMENT_TEAM":
+			current.DevelopmentTeam = value
+		}
+	}
+	return targets
+}
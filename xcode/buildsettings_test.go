@@ -0,0 +1,59 @@
+package xcode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseCodeSignSettings(t *testing.T) {
+	xcout := `Command line invocation:
+    /Applications/Xcode.app/Contents/Developer/usr/bin/xcodebuild -project SampleApp.xcodeproj -scheme SampleApp -showBuildSettings
+
+Build settings for action build and target SampleApp:
+    ACTION = build
+    CODE_SIGN_IDENTITY = iPhone Developer
+    CODE_SIGN_STYLE = Manual
+    CONFIGURATION = Release
+    DEVELOPMENT_TEAM = 72SA8V3WYL
+    PRODUCT_BUNDLE_IDENTIFIER = io.bitrise.sample
+    PROVISIONING_PROFILE_SPECIFIER = Sample Development
+
+Build settings for action build and target "Sample Widget":
+    CODE_SIGN_IDENTITY = Apple Development
+    CODE_SIGN_STYLE = Automatic
+    CONFIGURATION = Release
+    DEVELOPMENT_TEAM = 72SA8V3WYL
+    PRODUCT_BUNDLE_IDENTIFIER = io.bitrise.sample.widget
+    PROVISIONING_PROFILE_SPECIFIER = 
+`
+	require.Equal(t, []TargetCodeSignSettings{
+		{
+			Target:                       "SampleApp",
+			Configuration:                "Release",
+			BundleID:                     "io.bitrise.sample",
+			CodeSignStyle:                "Manual",
+			CodeSignIdentity:             "iPhone Developer",
+			ProvisioningProfileSpecifier: "Sample Development",
+			DevelopmentTeam:              "72SA8V3WYL",
+		},
+		{
+			Target:           "Sample Widget",
+			Configuration:    "Release",
+			BundleID:         "io.bitrise.sample.widget",
+			CodeSignStyle:    "Automatic",
+			CodeSignIdentity: "Apple Development",
+			DevelopmentTeam:  "72SA8V3WYL",
+		},
+	}, parseCodeSignSettings(xcout))
+}
+
+func TestAutomaticSigningTargets(t *testing.T) {
+	settings := []TargetCodeSignSettings{
+		{Target: "SampleApp", CodeSignStyle: "Manual"},
+		{Target: "Sample Widget", CodeSignStyle: "Automatic"},
+		{Target: "Legacy"},
+	}
+	require.Equal(t, []TargetCodeSignSettings{{Target: "Sample Widget", CodeSignStyle: "Automatic"}}, AutomaticSigningTargets(settings))
+	require.Nil(t, AutomaticSigningTargets(settings[:1]))
+}
@@ -0,0 +1,82 @@
+package xcode
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/bitrise-io/codesigndoc/progress"
+)
+
+// CocoaPodsWorkspace returns the workspace generated by pod install for the project, if the project uses CocoaPods:
+// the project has to be opened and built through the workspace, else the Pods are not built.
+// The returned bool is false if there is no Podfile next to the project, the returned path is empty if the Pods are not installed.
+func CocoaPodsWorkspace(projectPath string) (string, bool, error) {
+	if filepath.Ext(projectPath) != ".xcodeproj" {
+		return "", false, nil
+	}
+	dir := filepath.Dir(projectPath)
+	if _, err := os.Stat(filepath.Join(dir, "Podfile")); os.IsNotExist(err) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+
+	workspaces, err := filepath.Glob(filepath.Join(dir, "*.xcworkspace"))
+	if err != nil {
+		return "", true, err
+	}
+	absProjectPath, err := filepath.Abs(projectPath)
+	if err != nil {
+		return "", true, err
+	}
+	for _, workspace := range workspaces {
+		projects, err := workspaceProjects(workspace)
+		if err != nil {
+			return "", true, err
+		}
+		for _, project := range projects {
+			if absProject, err := filepath.Abs(project); err == nil && absProject == absProjectPath {
+				return workspace, true, nil
+			}
+		}
+	}
+	return "", true, nil
+}
+
+// UsesSwiftPackages returns true if the project, or a project of the workspace, depends on Swift packages
+func UsesSwiftPackages(projectPath string) (bool, error) {
+	projects := []string{projectPath}
+	if filepath.Ext(projectPath) == ".xcworkspace" {
+		var err error
+		if projects, err = workspaceProjects(projectPath); err != nil {
+			return false, err
+		}
+	}
+
+	for _, project := range projects {
+		content, err := ioutil.ReadFile(filepath.Join(project, "project.pbxproj"))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return false, fmt.Errorf("failed to read the project (%s), error: %s", project, err)
+		}
+		if bytes.Contains(content, []byte("XCRemoteSwiftPackageReference")) || bytes.Contains(content, []byte("XCLocalSwiftPackageReference")) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ResolvePackageDependencies resolves (downloads) the Swift packages of the project,
+// before the build settings are read, which fail on a missing package
+func (xccmd CommandModel) ResolvePackageDependencies() (string, error) {
+	var xcoutput string
+	var err error
+	progress.Run("Resolving the Swift packages", func() {
+		xcoutput, err = xccmd.RunXcodebuildCommand("-resolvePackageDependencies")
+	})
+	return xcoutput, err
+}
@@ -0,0 +1,50 @@
+package xcode
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCocoaPodsWorkspace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pods")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	project := filepath.Join(dir, "App.xcodeproj")
+	require.NoError(t, os.MkdirAll(project, 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(project, "project.pbxproj"), []byte(`isa = XCRemoteSwiftPackageReference;`), 0600))
+
+	workspace, usesPods, err := CocoaPodsWorkspace(project)
+	require.NoError(t, err)
+	require.False(t, usesPods)
+	require.Equal(t, "", workspace)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "Podfile"), nil, 0600))
+	workspace, usesPods, err = CocoaPodsWorkspace(project)
+	require.NoError(t, err)
+	require.True(t, usesPods)
+	require.Equal(t, "", workspace)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "App.xcworkspace"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "App.xcworkspace", "contents.xcworkspacedata"), []byte(`<Workspace version = "1.0">
+   <FileRef location = "group:App.xcodeproj"></FileRef>
+   <FileRef location = "group:Pods/Pods.xcodeproj"></FileRef>
+</Workspace>`), 0600))
+	workspace, usesPods, err = CocoaPodsWorkspace(project)
+	require.NoError(t, err)
+	require.True(t, usesPods)
+	require.Equal(t, filepath.Join(dir, "App.xcworkspace"), workspace)
+
+	usesPackages, err := UsesSwiftPackages(workspace)
+	require.NoError(t, err)
+	require.True(t, usesPackages)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(project, "project.pbxproj"), nil, 0600))
+	usesPackages, err = UsesSwiftPackages(project)
+	require.NoError(t, err)
+	require.False(t, usesPackages)
+}
@@ -0,0 +1,99 @@
+package xcode
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/bitrise-io/go-utils/pathutil"
+)
+
+// The cross-platform frameworks of which the iOS project is found in the ios subdirectory of the repository
+const (
+	FrameworkFlutter     = "Flutter"
+	FrameworkReactNative = "React Native"
+)
+
+// CrossPlatformProject is the iOS project of a Flutter or React Native repository
+type CrossPlatformProject struct {
+	Framework string
+	// ProjectPath is the workspace of the ios directory, or its project if the workspace is not generated yet
+	ProjectPath string
+	// Hint tells how to generate the missing files the build of the iOS project requires, empty if nothing is missing
+	Hint string
+}
+
+// DetectCrossPlatformProject looks for the Flutter (ios/Runner.xcworkspace) and React Native (a workspace in ios/) layouts in the directory,
+// the returned bool is false if the directory is not the root of a Flutter or React Native app
+func DetectCrossPlatformProject(dir string) (CrossPlatformProject, bool, error) {
+	iosDir := filepath.Join(dir, "ios")
+	if exist, err := pathutil.IsDirExists(iosDir); err != nil || !exist {
+		return CrossPlatformProject{}, false, err
+	}
+
+	if exist, err := pathutil.IsPathExists(filepath.Join(dir, "pubspec.yaml")); err != nil {
+		return CrossPlatformProject{}, false, err
+	} else if exist {
+		project := CrossPlatformProject{Framework: FrameworkFlutter, ProjectPath: filepath.Join(iosDir, "Runner.xcworkspace")}
+		if exist, err := pathutil.IsPathExists(project.ProjectPath); err != nil {
+			return CrossPlatformProject{}, false, err
+		} else if !exist {
+			return CrossPlatformProject{}, false, nil
+		}
+		if exist, err := pathutil.IsPathExists(filepath.Join(iosDir, "Flutter", "Generated.xcconfig")); err != nil {
+			return CrossPlatformProject{}, false, err
+		} else if !exist {
+			project.Hint = "run flutter pub get (or flutter build ios --config-only) first, the build fails without ios/Flutter/Generated.xcconfig"
+		}
+		return project, true, nil
+	}
+
+	if isReactNative, err := dependsOnReactNative(filepath.Join(dir, "package.json")); err != nil || !isReactNative {
+		return CrossPlatformProject{}, false, err
+	}
+	project := CrossPlatformProject{Framework: FrameworkReactNative}
+	workspaces, err := filepath.Glob(filepath.Join(iosDir, "*.xcworkspace"))
+	if err != nil {
+		return CrossPlatformProject{}, false, err
+	}
+	if len(workspaces) == 1 {
+		project.ProjectPath = workspaces[0]
+		return project, true, nil
+	}
+	projects, err := filepath.Glob(filepath.Join(iosDir, "*.xcodeproj"))
+	if err != nil {
+		return CrossPlatformProject{}, false, err
+	}
+	if len(workspaces) > 1 || len(projects) != 1 {
+		// ambiguous, the caller lists the candidates
+		return CrossPlatformProject{}, false, nil
+	}
+	project.ProjectPath = projects[0]
+	project.Hint = "run npm install (or yarn) and pod install in the ios directory first, the build fails without the Pods workspace"
+	return project, true, nil
+}
+
+// dependsOnReactNative returns true if the package.json lists react-native as a dependency
+func dependsOnReactNative(packageJSONPath string) (bool, error) {
+	content, err := ioutil.ReadFile(packageJSONPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	var packageJSON struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal(content, &packageJSON); err != nil {
+		return false, fmt.Errorf("failed to parse %s, error: %s", packageJSONPath, err)
+	}
+	_, ok := packageJSON.Dependencies["react-native"]
+	if !ok {
+		_, ok = packageJSON.DevDependencies["react-native"]
+	}
+	return ok, nil
+}
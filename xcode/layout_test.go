@@ -0,0 +1,59 @@
+package xcode
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectCrossPlatformProject(t *testing.T) {
+	dir, err := ioutil.TempDir("", "layout")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	create := func(pth, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, pth)), 0700))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, pth), []byte(content), 0600))
+	}
+
+	_, found, err := DetectCrossPlatformProject(dir)
+	require.NoError(t, err)
+	require.False(t, found)
+
+	flutter := filepath.Join(dir, "flutter")
+	create("flutter/pubspec.yaml", "name: app")
+	create("flutter/ios/Runner.xcworkspace/contents.xcworkspacedata", "")
+	project, found, err := DetectCrossPlatformProject(flutter)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, FrameworkFlutter, project.Framework)
+	require.Equal(t, filepath.Join(flutter, "ios", "Runner.xcworkspace"), project.ProjectPath)
+	require.NotEmpty(t, project.Hint)
+
+	create("flutter/ios/Flutter/Generated.xcconfig", "")
+	project, _, err = DetectCrossPlatformProject(flutter)
+	require.NoError(t, err)
+	require.Empty(t, project.Hint)
+
+	reactNative := filepath.Join(dir, "rn")
+	create("rn/package.json", `{"dependencies": {"react": "18.2.0", "react-native": "0.72.0"}}`)
+	create("rn/ios/App.xcodeproj/project.pbxproj", "")
+	project, found, err = DetectCrossPlatformProject(reactNative)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, CrossPlatformProject{Framework: FrameworkReactNative, ProjectPath: filepath.Join(reactNative, "ios", "App.xcodeproj"), Hint: project.Hint}, project)
+	require.NotEmpty(t, project.Hint)
+
+	create("rn/ios/App.xcworkspace/contents.xcworkspacedata", "")
+	project, _, err = DetectCrossPlatformProject(reactNative)
+	require.NoError(t, err)
+	require.Equal(t, CrossPlatformProject{Framework: FrameworkReactNative, ProjectPath: filepath.Join(reactNative, "ios", "App.xcworkspace")}, project)
+
+	create("node/package.json", `{"dependencies": {"express": "4.0.0"}}`)
+	create("node/ios/Other.xcodeproj/project.pbxproj", "")
+	_, found, err = DetectCrossPlatformProject(filepath.Join(dir, "node"))
+	require.NoError(t, err)
+	require.False(t, found)
+}
@@ -0,0 +1,165 @@
+package xcode
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Scheme is a scheme listed by xcodebuild -list
+type Scheme struct {
+	Name string
+	// Shared is set if the scheme is stored in an xcshareddata directory, only the shared schemes are committed and available on CI;
+	// the other schemes are stored in the xcuserdata directory of the user, or are autocreated by Xcode
+	Shared bool
+}
+
+// String describes the scheme for the scheme selection
+func (scheme Scheme) String() string {
+	if scheme.Shared {
+		return scheme.Name + " (shared)"
+	}
+	return scheme.Name + " (user)"
+}
+
+// ListSchemes lists the schemes of the project or workspace with xcodebuild -list,
+// and reads from the .xcscheme files of the project (and the projects of the workspace) which of them are shared.
+func (xccmd CommandModel) ListSchemes() ([]Scheme, error) {
+	names, err := xccmd.ScanSchemes()
+	if err != nil {
+		return nil, err
+	}
+	shared, err := SharedSchemeNames(xccmd.ProjectFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var schemes []Scheme
+	for _, name := range names {
+		schemes = append(schemes, Scheme{Name: name, Shared: shared[name]})
+	}
+	return schemes, nil
+}
+
+// SharedSchemeNames returns the names of the shared schemes of the project or workspace,
+// including the shared schemes of the projects referenced by the workspace
+func SharedSchemeNames(projectPath string) (map[string]bool, error) {
+	containers := []string{projectPath}
+	if filepath.Ext(projectPath) == ".xcworkspace" {
+		projects, err := workspaceProjects(projectPath)
+		if err != nil {
+			return nil, err
+		}
+		containers = append(containers, projects...)
+	}
+
+	shared := map[string]bool{}
+	for _, container := range containers {
+		pths, err := filepath.Glob(filepath.Join(container, "xcshareddata", "xcschemes", "*.xcscheme"))
+		if err != nil {
+			return nil, err
+		}
+		for _, pth := range pths {
+			shared[strings.TrimSuffix(filepath.Base(pth), ".xcscheme")] = true
+		}
+	}
+	return shared, nil
+}
+
+// workspaceProjects returns the paths of the .xcodeproj files referenced by the workspace
+func workspaceProjects(workspacePath string) ([]string, error) {
+	content, err := ioutil.ReadFile(filepath.Join(workspacePath, "contents.xcworkspacedata"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read the workspace, error: %s", err)
+	}
+	return parseWorkspaceProjects(workspacePath, content)
+}
+
+// parseWorkspaceProjects returns the project references of the contents.xcworkspacedata of the workspace, including the ones in groups
+func parseWorkspaceProjects(workspacePath string, content []byte) ([]string, error) {
+	var projects []string
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	var groups []string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse the workspace, error: %s", err)
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			location := ""
+			for _, attr := range element.Attr {
+				if attr.Name.Local == "location" {
+					location = attr.Value
+				}
+			}
+			if element.Name.Local == "Group" {
+				groups = append(groups, location)
+			} else if element.Name.Local == "FileRef" {
+				if pth := workspaceLocationPath(workspacePath, groups, location); filepath.Ext(pth) == ".xcodeproj" {
+					projects = append(projects, pth)
+				}
+			}
+		case xml.EndElement:
+			if element.Name.Local == "Group" && len(groups) > 0 {
+				groups = groups[:len(groups)-1]
+			}
+		}
+	}
+	sort.Strings(projects)
+	return projects, nil
+}
+
+// workspaceLocationPath resolves the location attribute (e.g. group:App/App.xcodeproj) of a workspace file reference
+func workspaceLocationPath(workspacePath string, groups []string, location string) string {
+	kind, pth := "", location
+	if i := strings.Index(location, ":"); i >= 0 {
+		kind, pth = location[:i], location[i+1:]
+	}
+	switch kind {
+	case "absolute":
+		return pth
+	case "container":
+		return filepath.Join(filepath.Dir(workspacePath), pth)
+	}
+	// group locations are relative to the enclosing groups
+	dir := filepath.Dir(workspacePath)
+	for _, group := range groups {
+		if i := strings.Index(group, ":"); i >= 0 {
+			group = group[i+1:]
+		}
+		dir = filepath.Join(dir, group)
+	}
+	return filepath.Join(dir, pth)
+}
+
+// DefaultSchemeIndex returns the index of the scheme to offer by default: the one named after the project or workspace,
+// or else the first shared scheme, or else the first one
+func DefaultSchemeIndex(projectPath string, schemes []Scheme) int {
+	for i, scheme := range schemes {
+		if scheme.Name == ProjectName(projectPath) {
+			return i
+		}
+	}
+	for i, scheme := range schemes {
+		if scheme.Shared {
+			return i
+		}
+	}
+	return 0
+}
+
+// ProjectName returns the name of the project or workspace file, e.g. App of App.xcworkspace
+func ProjectName(projectPath string) string {
+	return strings.TrimSuffix(filepath.Base(projectPath), filepath.Ext(projectPath))
+}
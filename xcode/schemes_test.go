@@ -0,0 +1,45 @@
+package xcode
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedSchemeNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schemes")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, pth := range []string{
+		"App.xcworkspace/xcshareddata/xcschemes/Workspace.xcscheme",
+		"App/App.xcodeproj/xcshareddata/xcschemes/App.xcscheme",
+		"App/App.xcodeproj/xcuserdata/me.xcuserdatad/xcschemes/Private.xcscheme",
+		"Pods/Pods.xcodeproj/xcshareddata/xcschemes/Pods.xcscheme",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, pth)), 0700))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, pth), nil, 0600))
+	}
+	workspace := filepath.Join(dir, "App.xcworkspace")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(workspace, "contents.xcworkspacedata"), []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<Workspace version = "1.0">
+   <Group location = "group:App" name = "App">
+      <FileRef location = "group:App.xcodeproj"></FileRef>
+   </Group>
+   <FileRef location = "container:Pods/Pods.xcodeproj"></FileRef>
+   <FileRef location = "group:README.md"></FileRef>
+</Workspace>`), 0600))
+
+	shared, err := SharedSchemeNames(workspace)
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"Workspace": true, "App": true, "Pods": true}, shared)
+
+	schemes := []Scheme{{Name: "Pods", Shared: true}, {Name: "Private"}, {Name: "App", Shared: true}}
+	require.Equal(t, 2, DefaultSchemeIndex(workspace, schemes))
+	require.Equal(t, 0, DefaultSchemeIndex(filepath.Join(dir, "Other.xcodeproj"), schemes))
+	require.Equal(t, 0, DefaultSchemeIndex(workspace, []Scheme{{Name: "Private"}}))
+	require.Equal(t, "Private (user)", schemes[1].String())
+}
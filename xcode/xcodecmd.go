@@ -2,16 +2,17 @@ package xcode
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
-	"time"
 
+	"github.com/bitrise-io/codesigndoc/progress"
 	"github.com/bitrise-io/go-utils/command"
 	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-io/go-utils/pathutil"
-	"github.com/bitrise-io/go-utils/progress"
 )
 
 // CommandModel ...
@@ -27,6 +28,10 @@ type CommandModel struct {
 	// Only passed to xcodebuild if not empty!
 	Scheme string
 
+	// Configuration will be passed to xcodebuild as the -configuration flag's value,
+	// the scheme's configuration of the action is used if empty.
+	Configuration string
+
 	// CodeSignIdentity will be passed to xcodebuild as an CODE_SIGN_IDENTITY= argument.
 	// Only passed to xcodebuild if not empty!
 	CodeSignIdentity string
@@ -37,8 +42,8 @@ type CommandModel struct {
 	SDK string
 }
 
-// GenerateArchive : generates the archive for subsequent "Scan"
-func (xccmd CommandModel) GenerateArchive() (string, string, error) {
+// GenerateArchive : generates the archive for subsequent "Scan", xcodebuild is killed if the context is done
+func (xccmd CommandModel) GenerateArchive(ctx context.Context) (string, string, error) {
 	xcoutput := ""
 	var err error
 
@@ -46,12 +51,15 @@ func (xccmd CommandModel) GenerateArchive() (string, string, error) {
 	if err != nil {
 		return "", "", fmt.Errorf("failed to create temp dir for archives, error: %s", err)
 	}
-	tmpArchivePath := filepath.Join(tmpDir, xccmd.Scheme+".xcarchive")
+	archiveName := xccmd.Scheme
+	if xccmd.Configuration != "" {
+		archiveName += "-" + xccmd.Configuration
+	}
+	tmpArchivePath := filepath.Join(tmpDir, archiveName+".xcarchive")
 
-	progress.SimpleProgress(".", 1*time.Second, func() {
-		xcoutput, err = xccmd.RunXcodebuildCommand("clean", "archive", "-archivePath", tmpArchivePath)
+	progress.Run("Archiving the project", func() {
+		xcoutput, err = xccmd.RunXcodebuildCommandContext(ctx, "clean", "archive", "-archivePath", tmpArchivePath)
 	})
-	fmt.Println()
 
 	if err != nil {
 		return "", xcoutput, err
@@ -59,6 +67,30 @@ func (xccmd CommandModel) GenerateArchive() (string, string, error) {
 	return tmpArchivePath, xcoutput, nil
 }
 
+// ExportArchive exports the archive with the export options plist into a temp dir, and returns the export dir.
+// Xcode is allowed to create and download the profiles of the automatically signed export (-allowProvisioningUpdates).
+// xcodebuild is killed if the context is done.
+func ExportArchive(ctx context.Context, archivePath, exportOptionsPath string) (string, string, error) {
+	tmpDir, err := pathutil.NormalizedOSTempDirPath("__codesigndoc_export__")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create temp dir for the export, error: %s", err)
+	}
+
+	args := []string{"-exportArchive", "-archivePath", archivePath, "-exportPath", tmpDir, "-exportOptionsPlist", exportOptionsPath, "-allowProvisioningUpdates"}
+	log.Infof("$ xcodebuild %s", command.PrintableCommandArgs(true, args))
+
+	var xcoutput string
+	progress.Run("Exporting the archive", func() {
+		xcoutput, err = runXcodebuild(ctx, args)
+	})
+
+	log.Debugf("xcoutput: %s", xcoutput)
+	if err != nil {
+		return "", xcoutput, err
+	}
+	return tmpDir, xcoutput, nil
+}
+
 func (xccmd CommandModel) xcodeProjectOrWorkspaceParam() (string, error) {
 	if strings.HasSuffix(xccmd.ProjectFilePath, "xcworkspace") {
 		return "-workspace", nil
@@ -78,6 +110,9 @@ func (xccmd CommandModel) transformToXcodebuildParams(xcodebuildActionArgs ...st
 	if xccmd.Scheme != "" {
 		baseArgs = append(baseArgs, "-scheme", xccmd.Scheme)
 	}
+	if xccmd.Configuration != "" {
+		baseArgs = append(baseArgs, "-configuration", xccmd.Configuration)
+	}
 
 	if xccmd.SDK != "" {
 		baseArgs = append(baseArgs, "-sdk", xccmd.SDK)
@@ -91,21 +126,38 @@ func (xccmd CommandModel) transformToXcodebuildParams(xcodebuildActionArgs ...st
 
 // RunXcodebuildCommand ...
 func (xccmd CommandModel) RunXcodebuildCommand(xcodebuildActionArgs ...string) (string, error) {
+	return xccmd.RunXcodebuildCommandContext(context.Background(), xcodebuildActionArgs...)
+}
+
+// RunXcodebuildCommandContext is RunXcodebuildCommand, xcodebuild is killed if the context is done
+func (xccmd CommandModel) RunXcodebuildCommandContext(ctx context.Context, xcodebuildActionArgs ...string) (string, error) {
 	xcodeCmdParamsToRun, err := xccmd.transformToXcodebuildParams(xcodebuildActionArgs...)
 	if err != nil {
 		return "", err
 	}
 
 	log.Infof("$ xcodebuild %s", command.PrintableCommandArgs(true, xcodeCmdParamsToRun))
-	xcoutput, err := command.RunCommandAndReturnCombinedStdoutAndStderr("xcodebuild", xcodeCmdParamsToRun...)
+	xcoutput, err := runXcodebuild(ctx, xcodeCmdParamsToRun)
 	if err != nil {
-		return xcoutput, fmt.Errorf("failed to run xcodebuild command, error: %s", err)
+		return xcoutput, err
 	}
 
 	log.Debugf("xcoutput: %s", xcoutput)
 	return xcoutput, nil
 }
 
+// runXcodebuild runs xcodebuild with the args and returns its combined output
+func runXcodebuild(ctx context.Context, args []string) (string, error) {
+	xcoutput, err := command.RunCmdAndReturnTrimmedCombinedOutput(exec.CommandContext(ctx, "xcodebuild", args...))
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return xcoutput, fmt.Errorf("xcodebuild command canceled, raise --timeout for the slow builds: %w", ctxErr)
+	}
+	if err != nil {
+		return xcoutput, fmt.Errorf("failed to run xcodebuild command, error: %s", err)
+	}
+	return xcoutput, nil
+}
+
 // ScanSchemes ...
 func (xccmd CommandModel) ScanSchemes() ([]string, error) {
 	xcoutput, err := xccmd.RunXcodebuildCommand("-list")
@@ -124,7 +176,7 @@ func parseSchemesFromXcodeOutput(xcodeOutput string) []string {
 	isSchemeDelimiterFound := false
 	for scanner.Scan() {
 		line := scanner.Text()
-		if isSchemeDelimiterFound {
+		if isSchemeDelimiterFound && strings.TrimSpace(line) != "" {
 			foundSchemes = append(foundSchemes, strings.TrimSpace(line))
 		}
 		if regexp.MustCompile(`^[[:space:]]*Schemes:$`).MatchString(line) {
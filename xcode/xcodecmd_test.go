@@ -1,6 +1,8 @@
 package xcode
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -23,3 +25,25 @@ func Test_parseSchemesFromXcodeOutput(t *testing.T) {
 	parsedSchemes := parseSchemesFromXcodeOutput(xcout)
 	require.Equal(t, []string{"SampleAppWithCocoapods"}, parsedSchemes)
 }
+
+func TestTransformToXcodebuildParams(t *testing.T) {
+	xccmd := CommandModel{ProjectFilePath: "App.xcworkspace", Scheme: "App", Configuration: "Debug"}
+	params, err := xccmd.transformToXcodebuildParams("archive")
+	require.NoError(t, err)
+	require.Equal(t, []string{"-workspace", "App.xcworkspace", "-scheme", "App", "-configuration", "Debug", "archive"}, params)
+
+	xccmd.Configuration = ""
+	params, err = xccmd.transformToXcodebuildParams("archive")
+	require.NoError(t, err)
+	require.Equal(t, []string{"-workspace", "App.xcworkspace", "-scheme", "App", "archive"}, params)
+}
+
+func TestRunXcodebuildCommandContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	xccmd := CommandModel{ProjectFilePath: "App.xcodeproj", Scheme: "App"}
+	_, err := xccmd.RunXcodebuildCommandContext(ctx, "archive")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, context.Canceled), err.Error())
+}
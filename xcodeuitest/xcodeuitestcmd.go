@@ -1,16 +1,17 @@
 package xcodeuitest
 
 import (
+	"context"
 	"fmt"
+	"os/exec"
 	"path"
 	"path/filepath"
 	"strings"
-	"time"
 
+	"github.com/bitrise-io/codesigndoc/progress"
 	"github.com/bitrise-io/go-utils/command"
 	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-io/go-utils/pathutil"
-	"github.com/bitrise-io/go-utils/progress"
 	"github.com/bitrise-io/xcode-project"
 	"github.com/bitrise-io/xcode-project/xcodeproj"
 	"github.com/bitrise-io/xcode-project/xcscheme"
@@ -36,8 +37,8 @@ type CommandModel struct {
 	SDK string
 }
 
-// RunBuildForTesting runs the build-for-tesing xcode command
-func (xcuitestcmd CommandModel) RunBuildForTesting() (string, string, error) {
+// RunBuildForTesting runs the build-for-tesing xcode command, xcodebuild is killed if the context is done
+func (xcuitestcmd CommandModel) RunBuildForTesting(ctx context.Context) (string, string, error) {
 	xcoutput := ""
 	var err error
 
@@ -47,10 +48,9 @@ func (xcuitestcmd CommandModel) RunBuildForTesting() (string, string, error) {
 	}
 	tmpBuildPath := filepath.Join(tmpDir, xcuitestcmd.Scheme)
 
-	progress.SimpleProgress(".", 1*time.Second, func() {
-		xcoutput, err = xcuitestcmd.RunXcodebuildCommand("clean", "build-for-testing", "CONFIGURATION_BUILD_DIR="+tmpBuildPath)
+	progress.Run("Building the project for testing", func() {
+		xcoutput, err = xcuitestcmd.RunXcodebuildCommandContext(ctx, "clean", "build-for-testing", "CONFIGURATION_BUILD_DIR="+tmpBuildPath)
 	})
-	fmt.Println()
 
 	if err != nil {
 		return "", xcoutput, err
@@ -87,13 +87,21 @@ func (xcuitestcmd CommandModel) transformToXcodebuildParams(xcodebuildActionArgs
 
 // RunXcodebuildCommand TODO comment
 func (xcuitestcmd CommandModel) RunXcodebuildCommand(xcodebuildActionArgs ...string) (string, error) {
+	return xcuitestcmd.RunXcodebuildCommandContext(context.Background(), xcodebuildActionArgs...)
+}
+
+// RunXcodebuildCommandContext is RunXcodebuildCommand, xcodebuild is killed if the context is done
+func (xcuitestcmd CommandModel) RunXcodebuildCommandContext(ctx context.Context, xcodebuildActionArgs ...string) (string, error) {
 	xcodeCmdParamsToRun, err := xcuitestcmd.transformToXcodebuildParams(xcodebuildActionArgs...)
 	if err != nil {
 		return "", err
 	}
 
 	log.Infof("$ xcodebuild %s", command.PrintableCommandArgs(true, xcodeCmdParamsToRun))
-	xcoutput, err := command.RunCommandAndReturnCombinedStdoutAndStderr("xcodebuild", xcodeCmdParamsToRun...)
+	xcoutput, err := command.RunCmdAndReturnTrimmedCombinedOutput(exec.CommandContext(ctx, "xcodebuild", xcodeCmdParamsToRun...))
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return xcoutput, fmt.Errorf("xcodebuild command canceled, raise --timeout for the slow builds: %w", ctxErr)
+	}
 	if err != nil {
 		return xcoutput, fmt.Errorf("failed to run xcodebuild command, error: %s", err)
 	}